@@ -0,0 +1,87 @@
+// Command gogdbllm-cli is a terminal client of a gogdbllm server: GDB in one pane and the
+// assistant in the other, for debugging without a browser.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/tui"
+)
+
+func main() {
+	serverURL := flag.String("url", "http://localhost:8080", "URL of the server")
+	token := flag.String("token", os.Getenv("GOGDBLLM_AUTH_TOKEN"), "Authentication token (auth.mode: token), or set GOGDBLLM_AUTH_TOKEN")
+	user := flag.String("user", "", "User to sign in as (auth.mode: password); the password is read from GOGDBLLM_PASSWORD or asked for")
+	session := flag.String("session", "", "Join the debugging session of an upload's session token instead of uploading")
+	model := flag.String("model", "", "Model the assistant uses instead of your settings' model")
+	terminalLines := flag.Int("terminal-lines", 50, "Lines of GDB output sent with each question")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm-cli [flags] [executable]\n\n")
+		fmt.Fprintf(os.Stderr, "Uploads the executable, starts GDB on it and shows GDB and the assistant side by side.\n")
+		fmt.Fprintf(os.Stderr, "The executable's path may also be piped in: echo ./crash | gogdbllm-cli\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+
+	executable := flag.Arg(0)
+	in := os.Stdin
+	if !isTerminal(os.Stdin) {
+		// A piped path; keys then come from the terminal itself
+		if executable == "" && *session == "" {
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			executable = strings.TrimSpace(line)
+		}
+		tty, err := openTerminal()
+		if err != nil {
+			log.Fatalf("Opening the terminal: %v", err)
+		}
+		defer tty.Close()
+		in = tty
+	}
+	if executable == "" && *session == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := tui.NewClient(*serverURL, *token)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *user != "" {
+		password := os.Getenv("GOGDBLLM_PASSWORD")
+		if password == "" {
+			if password, err = tui.ReadPassword(in, os.Stderr, "Password for "+*user+": "); err != nil {
+				log.Fatalf("Reading the password: %v", err)
+			}
+		}
+		if err := client.Login(*user, password); err != nil {
+			log.Fatalf("Signing in: %v", err)
+		}
+	}
+
+	opts := tui.Options{Executable: executable, SessionToken: *session, Model: *model, TerminalLines: *terminalLines}
+	if err := tui.Run(client, opts, in, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// openTerminal opens the controlling terminal for reading keys
+func openTerminal() (*os.File, error) {
+	if runtime.GOOS == "windows" {
+		return os.Open("CONIN$")
+	}
+	return os.Open("/dev/tty")
+}
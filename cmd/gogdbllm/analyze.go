@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/analyze"
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/di"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// analyzeProgram runs the assistant's agent loop on a program without the web server and
+// prints its report, e.g. to triage a failing test in CI
+func analyzeProgram(args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	binary := flags.String("binary", "", "Program to analyze")
+	programArgs := flags.String("args", "", "Arguments the program is run with")
+	corePath := flags.String("core", "", "Core dump of the program to inspect instead of running it")
+	question := flags.String("question", "", "What to ask the assistant (default: find out why the program crashes)")
+	provider := flags.String("provider", "", "LLM provider, overriding the environment, saved settings and config file")
+	model := flags.String("model", "", "LLM model, overriding the environment, saved settings and config file")
+	profile := flags.String("profile", "", "Prompt profile, e.g. triage to only inspect the program's state")
+	turns := flags.Int("turns", 5, "Turns in which the assistant may run GDB commands before it reports")
+	output := flags.String("output", "", "Also save the report to this file; a .json name saves JSON")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Give up after this long")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm analyze -binary <program> [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *binary == "" || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	executable, err := filepath.Abs(*binary)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(executable); err != nil || info.IsDir() {
+		return fmt.Errorf("%s is not a program", *binary)
+	}
+
+	core := ""
+	if *corePath != "" {
+		if core, err = filepath.Abs(*corePath); err != nil {
+			return err
+		}
+		if *question == "" {
+			*question = analyze.CoreQuestion
+		}
+	}
+	if *question == "" {
+		*question = analyze.DefaultQuestion
+	}
+
+	container := di.NewContainer()
+	if err := container.Configure(*configPath, config.Overrides{Provider: *provider, Model: *model}); err != nil {
+		return fmt.Errorf("failed to configure container: %w", err)
+	}
+
+	var report *analyze.Report
+	err = container.Invoke(func(
+		cfg *config.Config,
+		hub *websocket.Hub,
+		settingsManager *settings.Manager,
+		featureManager *features.Manager,
+		responseCache *api.ResponseCache,
+		promptEngine *prompts.Engine,
+	) error {
+		if err := cfg.Chat.Envelope.Validate(); err != nil {
+			return err
+		}
+		if err := api.ValidatePostProcessors(cfg.Chat.PostProcessors); err != nil {
+			return err
+		}
+		if err := cfg.Chat.Retrieval.Validate(); err != nil {
+			return err
+		}
+		if _, err := promptEngine.Profile(*profile); err != nil {
+			return err
+		}
+		// Nobody subscribes, but GDB's output is broadcast all the same
+		go hub.Run()
+
+		session, err := analyze.StartSession(cfg, hub, analyze.SessionOptions{
+			Executable: executable,
+			Core:       core,
+			Args:       *programArgs,
+			Kind:       "analyze",
+			Features:   featureManager,
+			Metadata: map[string]interface{}{
+				"session.analyze": *question,
+				"session.core":    core,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		processor := api.NewChatProcessor(settingsManager, session.Logs, session.GDB, featureManager, cfg.Chat, responseCache, promptEngine)
+		report, err = analyze.Run(ctx, processor, analyze.Options{Question: *question, MaxTurns: *turns, Profile: *profile})
+		report.Executable, report.Session = executable, session.ID
+		return err
+	})
+	if report == nil {
+		return err
+	}
+
+	write := report.WriteText
+	if *asJSON {
+		write = report.WriteJSON
+	}
+	if writeErr := write(os.Stdout); writeErr != nil {
+		return writeErr
+	}
+	if *output != "" {
+		if saveErr := saveReport(report, *output); saveErr != nil {
+			return saveErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return report.Validate()
+}
+
+// saveReport writes a report to a file, as JSON when the name ends in .json
+func saveReport(report *analyze.Report, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = report.WriteJSON(file)
+	} else {
+		err = report.WriteText(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
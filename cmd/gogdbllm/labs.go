@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/labs"
+)
+
+// stringList collects a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// labAdd adds or replaces a lab target in the catalog, so a lab can be brought up from a
+// script before the server starts
+func labAdd(args []string) error {
+	flags := flag.NewFlagSet("lab-add", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	id := flags.String("id", "", "Target ID: lowercase letters, digits, '-' and '_'")
+	name := flags.String("name", "", "Target name shown in the catalog")
+	description := flags.String("description", "", "What the student should find or do")
+	var hints, tags stringList
+	flags.Var(&hints, "hint", "A hint; repeat for several, in the order they should be read")
+	flags.Var(&tags, "tag", "A tag, e.g. a topic or difficulty; repeat for several")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm lab-add -id <id> -name <name> [flags] <executable>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || *id == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	catalog, err := labs.NewCatalog(cfg)
+	if err != nil {
+		return err
+	}
+
+	binary, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer binary.Close()
+
+	target := labs.Target{ID: *id, Name: *name, Description: *description, Hints: hints, Tags: tags}
+	if err := catalog.Put(target, binary); err != nil {
+		return err
+	}
+	fmt.Printf("Lab %s added to %s\n", *id, cfg.Labs.Directory)
+	return nil
+}
@@ -1,149 +1,124 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"path/filepath"
-	"syscall"
-	"time"
+	"runtime/debug"
+	"strings"
 
-	"github.com/gorilla/mux"
-	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/auth"
 	"github.com/yourusername/gogdbllm/internal/config"
-	"github.com/yourusername/gogdbllm/internal/di"
-	"github.com/yourusername/gogdbllm/internal/handlers"
-	"github.com/yourusername/gogdbllm/internal/websocket"
 )
 
-var diContainer *di.Container
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// command is a gogdbllm subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage text
+var commands = []command{
+	{"serve", "Start the web server (default)", serve},
+	{"gen-config", "Write the default configuration file to a path", genConfig},
+	{"hash-password", "Print a password hash for auth.users in the configuration", hashPassword},
+	{"lab-add", "Add or replace a lab target in the lab catalog", labAdd},
+	{"replay", "Re-run the GDB commands of a logged session", replaySession},
+	{"analyze", "Ask the assistant about a program without the web server and print its report", analyzeProgram},
+	{"mcp", "Connect an MCP client on standard input and output to a running server", mcpBridge},
+	{"version", "Print the version", printVersion},
+}
 
 func main() {
-	// Parse command line flags
-	configPath := flag.String("config", "", "Path to configuration file")
-	genConfig := flag.String("gen-config", "", "Generate default configuration file at specified path and exit")
-	flag.Parse()
+	// Running without a subcommand, or with only flags, starts the server
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	// Generate config file if requested
-	if *genConfig != "" {
-		if err := config.WriteDefaultConfig(*genConfig); err != nil {
-			log.Fatalf("Failed to generate configuration file: %v", err)
-		}
-		fmt.Printf("Default configuration written to %s\n", *genConfig)
+	if name == "help" {
+		usage()
 		return
 	}
-
-	// Create DI container
-	diContainer = di.NewContainer()
-	if err := diContainer.Configure(*configPath); err != nil {
-		log.Fatalf("Failed to configure container: %v", err)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			return
+		}
 	}
 
-	// Run application with DI container
-	if err := diContainer.Invoke(run); err != nil {
-		log.Fatalf("Failed to run application: %v", err)
-	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
 }
 
-// run is the main application function that gets invoked with dependencies
-func run(cfg *config.Config) error {
-	// Create uploads directory if it doesn't exist
-	uploadsDir := cfg.Uploads.Directory
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create uploads directory: %v", err)
+// usage prints the available subcommands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gogdbllm [command] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun 'gogdbllm <command> -h' for the flags of a command.\n")
+}
 
-	// Initialize router
-	router := mux.NewRouter()
-
-	// Setup routes and handlers using dependency injection
-	if err := setupRoutes(router); err != nil {
-		return fmt.Errorf("failed to setup routes: %v", err)
+// genConfig writes the default configuration file
+func genConfig(args []string) error {
+	flags := flag.NewFlagSet("gen-config", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm gen-config <path>\n")
 	}
-
-	// Configure and start the HTTP server
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
-	server := &http.Server{
-		Addr:         addr,
-		Handler:      router,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
 	}
 
-	// Channel to listen for errors coming from the server
-	serverErrors := make(chan error, 1)
-
-	// Start the server in a goroutine
-	go func() {
-		fmt.Printf("Server started on http://localhost%s\n", addr)
-		serverErrors <- server.ListenAndServe()
-	}()
-
-	// Channel to listen for interrupt/terminate signals
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-
-	// Block until we receive a signal or an error
-	select {
-	case err := <-serverErrors:
-		return fmt.Errorf("server error: %w", err)
-	case <-shutdown:
-		fmt.Println("\nShutting down gracefully...")
-
-		// Create a context with a timeout to tell the server how long to wait
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		// Attempt to gracefully shutdown the server
-		if err := server.Shutdown(ctx); err != nil {
-			// Force shutdown if graceful shutdown fails
-			server.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
-		}
+	path := flags.Arg(0)
+	if err := config.WriteDefaultConfig(path); err != nil {
+		return fmt.Errorf("failed to generate configuration file: %w", err)
 	}
-
+	fmt.Printf("Default configuration written to %s\n", path)
 	return nil
 }
 
-// setupRoutes configures all the routes for the application
-func setupRoutes(router *mux.Router) error {
-	// This will be automatically invoked by the DI container with all required dependencies
-	return diContainer.Invoke(func(
-		fileHandler *handlers.FileHandler,
-		gdbHandler *handlers.GDBHandler,
-		settingsHandler *handlers.SettingsHandler,
-		chatHandler *api.SimpleChatHandler,
-		wsHub *websocket.Hub,
-	) {
-		// Register API routes
-		router.HandleFunc("/upload", fileHandler.HandleUpload).Methods("POST")
-		router.HandleFunc("/ws", websocket.ServeWs(wsHub, gdbHandler))
-		router.HandleFunc("/start-gdb", gdbHandler.HandleStartGDB).Methods("POST")
-		router.HandleFunc("/api/chat", chatHandler.HandleChat).Methods("POST")
-		router.HandleFunc("/api/settings", settingsHandler.GetSettings).Methods("GET")
-		router.HandleFunc("/save-settings", settingsHandler.SaveSettings).Methods("POST")
-		router.HandleFunc("/test-connection", settingsHandler.TestConnection).Methods("POST")
-
-		// Serve static files
-		fs := http.FileServer(http.Dir("./web/static"))
-		router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
-
-		// Serve index page
-		router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			http.ServeFile(w, r, filepath.Join("web/templates", "index.html"))
-		})
+// hashPassword prints a password hash for password authentication
+func hashPassword(args []string) error {
+	flags := flag.NewFlagSet("hash-password", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm hash-password <password>\n")
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
 
-		// Health check endpoint
-		router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Server is working"))
-		})
+	hash, err := auth.HashPassword(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	fmt.Println(hash)
+	return nil
+}
 
-		// Start WebSocket hub
-		go wsHub.Run()
-	})
+// printVersion prints the build version, falling back to the VCS revision Go embeds
+// when the version was not set at build time
+func printVersion(args []string) error {
+	v := version
+	if info, ok := debug.ReadBuildInfo(); ok && v == "dev" {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				v += " (" + setting.Value[:12] + ")"
+			}
+		}
+	}
+	fmt.Printf("gogdbllm %s\n", v)
+	return nil
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yourusername/gogdbllm/internal/mcp"
+)
+
+// mcpBridge connects an MCP client that launches servers as processes, like Claude
+// Desktop, to a running server's MCP endpoint
+func mcpBridge(args []string) error {
+	flags := flag.NewFlagSet("mcp", flag.ExitOnError)
+	url := flags.String("url", "http://localhost:8080", "URL of the running server")
+	token := flags.String("token", os.Getenv("GOGDBLLM_AUTH_TOKEN"), "Authentication token (auth.mode: token), or set GOGDBLLM_AUTH_TOKEN")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm mcp [flags]\n\nSpeaks MCP on standard input and output, passing messages to the server's /mcp endpoint.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	endpoint := strings.TrimSuffix(*url, "/") + "/mcp"
+	return mcp.Bridge(ctx, os.Stdin, os.Stdout, endpoint, *token, http.DefaultClient)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/replay"
+	"github.com/yourusername/gogdbllm/internal/transcript"
+)
+
+// replaySession re-runs the GDB commands of a logged session against a new GDB and prints
+// what each one output, without calling the LLM
+func replaySession(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	executable := flags.String("executable", "", "Program to debug; by default the session's upload")
+	userOnly := flags.Bool("user-only", false, "Skip the commands the assistant ran")
+	timeout := flags.Duration("timeout", 0, "How long to collect each command's output (default 2s)")
+	limit := flags.Int("limit", 0, "Replay at most this many steps")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogdbllm replay [flags] <session ID or log file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	path, sessionID := flags.Arg(0), flags.Arg(0)
+	if strings.ContainsRune(path, filepath.Separator) || strings.HasSuffix(path, ".log") {
+		sessionID = strings.TrimSuffix(filepath.Base(path), ".log")
+	} else {
+		path = logsession.LogFilePath(sessionID)
+	}
+	session, err := transcript.LoadSession(sessionID, path)
+	if err != nil {
+		return err
+	}
+
+	if *executable == "" {
+		if *executable, err = sessionExecutable(cfg, session); err != nil {
+			return err
+		}
+	}
+
+	service := gdb.NewGDBService(cfg)
+	if err := service.StartGDB(*executable); err != nil {
+		return err
+	}
+	defer service.StopGDB()
+	// Output is collected by each command; drain the stream so GDB never blocks on it
+	go func() {
+		for range service.GetOutputChannel() {
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := replay.Run(ctx, service, session, replay.Options{UserOnly: *userOnly, Timeout: *timeout, Limit: *limit})
+	report.Executable = *executable
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d steps failed", report.Failed, len(report.Steps))
+	}
+	return nil
+}
+
+// sessionExecutable finds the program a session debugged among the uploads, where the
+// server keeps each user's files
+func sessionExecutable(cfg *config.Config, session *transcript.Session) (string, error) {
+	filename := session.Metadata("session.filename")
+	if filename == "" {
+		return "", errors.New("the session log does not name its executable; pass -executable")
+	}
+
+	candidates := []string{filepath.Join(cfg.Uploads.Directory, filepath.Base(filename))}
+	if owner := session.Metadata("session.owner"); owner != "" {
+		candidates = append([]string{filepath.Join(cfg.Uploads.Directory, "users", filepath.Base(owner), filepath.Base(filename))}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("executable %s of the session was not found in %s; pass -executable", filename, cfg.Uploads.Directory)
+}
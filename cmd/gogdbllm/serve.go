@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/dap"
+	"github.com/yourusername/gogdbllm/internal/di"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/grpcapi"
+	"github.com/yourusername/gogdbllm/internal/handlers"
+	"github.com/yourusername/gogdbllm/internal/health"
+	"github.com/yourusername/gogdbllm/internal/mcp"
+	"github.com/yourusername/gogdbllm/internal/middleware"
+	"github.com/yourusername/gogdbllm/internal/openapi"
+	"github.com/yourusername/gogdbllm/internal/reload"
+	"github.com/yourusername/gogdbllm/internal/tlscert"
+	"github.com/yourusername/gogdbllm/internal/tracing"
+	"github.com/yourusername/gogdbllm/internal/triage"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+	"github.com/yourusername/gogdbllm/web"
+)
+
+var diContainer *di.Container
+
+// uiFiles are the web UI's pages, scripts and styles
+var uiFiles fs.FS
+
+// serve starts the web server
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	provider := flags.String("provider", "", "LLM provider, overriding the environment, saved settings and config file")
+	model := flags.String("model", "", "LLM model, overriding the environment, saved settings and config file")
+	dev := flags.Bool("dev", false, "Serve the web UI from ./web on disk instead of the copy built into the binary, so edits show on reload")
+	flags.Parse(args)
+
+	uiFiles = web.Files("")
+	if *dev {
+		uiFiles = web.Files("web")
+	}
+
+	// Create DI container
+	diContainer = di.NewContainer()
+	if err := diContainer.Configure(*configPath, config.Overrides{Provider: *provider, Model: *model}); err != nil {
+		return fmt.Errorf("failed to configure container: %w", err)
+	}
+
+	// Run application with DI container
+	if err := diContainer.Invoke(run); err != nil {
+		return fmt.Errorf("failed to run application: %w", err)
+	}
+	return nil
+}
+
+// run is the main application function that gets invoked with dependencies
+func run(
+	cfg *config.Config,
+	tracer *tracing.Tracer,
+	responseCache *api.ResponseCache,
+	gdbHandler *handlers.GDBHandler,
+	wsHub *websocket.Hub,
+	authenticator *auth.Authenticator,
+	chatHandler *api.SimpleChatHandler,
+	healthChecker *health.Checker,
+	reloadWatcher *reload.Watcher,
+) error {
+	// Create uploads directory if it doesn't exist
+	uploadsDir := cfg.Uploads.Directory
+	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create uploads directory: %v", err)
+	}
+
+	if err := cfg.GRPC.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Server.Validate(); err != nil {
+		return err
+	}
+	trustedProxies, _ := config.ParseCIDRs(cfg.Server.TrustedProxies)
+
+	// Initialize router
+	router := mux.NewRouter()
+
+	// Setup routes and handlers using dependency injection
+	if err := setupRoutes(router); err != nil {
+		return fmt.Errorf("failed to setup routes: %v", err)
+	}
+
+	// Every endpoint is also served under /api/v1, for the routes registered elsewhere
+	routes := func(r *http.Request) bool {
+		var match mux.RouteMatch
+		return router.Match(r, &match) || match.MatchErr == mux.ErrMethodMismatch
+	}
+
+	// Configure and start the HTTP server, behind a proxy under its base path
+	addr := cfg.Server.ListenAddress()
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      middleware.ProxyHeaders(trustedProxies)(middleware.BasePath(cfg.Server.BasePath)(middleware.APIVersion(routes)(router))),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	scheme := "http"
+	if cfg.Server.TLS() {
+		certs, err := tlscert.New(cfg.Server.CertFile, cfg.Server.KeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = certs.TLSConfig()
+		certs.Start(context.Background(), cfg.Server.CertReloadInterval)
+		scheme = "https"
+	}
+
+	// Channel to listen for errors coming from the server
+	serverErrors := make(chan error, 1)
+
+	// Start the server in a goroutine
+	go func() {
+		fmt.Printf("Server started on %s://%s%s/\n", scheme, displayAddress(addr), cfg.Server.BasePath)
+		if cfg.Server.TLS() {
+			// The certificate comes from TLSConfig, so no files are given here
+			serverErrors <- server.ListenAndServeTLS("", "")
+			return
+		}
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	// Serve the gRPC API on its own port. Streams stay open as long as the client wants,
+	// so the HTTP server's timeouts do not apply.
+	var grpcServer *http.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.GRPC.Port),
+			Handler: authenticator.Middleware(grpcapi.NewServer(gdbHandler, wsHub, router)),
+		}
+		if cfg.GRPC.Plaintext {
+			// gRPC clients speak HTTP/2 without TLS from their first byte (h2c)
+			grpcServer.Protocols = new(http.Protocols)
+			grpcServer.Protocols.SetUnencryptedHTTP2(true)
+		}
+		go func() {
+			fmt.Printf("gRPC API started on localhost%s\n", grpcServer.Addr)
+			if cfg.GRPC.Plaintext {
+				serverErrors <- grpcServer.ListenAndServe()
+				return
+			}
+			serverErrors <- grpcServer.ListenAndServeTLS(cfg.GRPC.CertFile, cfg.GRPC.KeyFile)
+		}()
+	}
+
+	// Serve the Debug Adapter Protocol for editors
+	var dapServer *dap.Server
+	if cfg.DAP.Enabled {
+		listener, err := net.Listen("tcp", cfg.DAP.Address)
+		if err != nil {
+			return fmt.Errorf("failed to listen for DAP clients: %w", err)
+		}
+		dapServer = dap.NewServer(gdbHandler, wsHub, authenticator, router)
+		go func() {
+			fmt.Printf("Debug adapter listening on %s\n", listener.Addr())
+			if err := dapServer.Serve(listener); err != nil {
+				serverErrors <- err
+			}
+		}()
+	}
+
+	// Apply changes to the config file on SIGHUP, or when it is saved
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go reloadWatcher.Start(watchCtx, cfg.Server.ReloadInterval)
+
+	// Channel to listen for interrupt/terminate signals
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+	// Block until we receive a signal or an error
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("server error: %w", err)
+	case <-shutdown:
+		fmt.Println("\nShutting down gracefully...")
+
+		// Fail readiness probes and refuse new debugging sessions
+		healthChecker.Drain()
+		gdbHandler.Drain()
+
+		// Stop accepting connections while chat requests wait for their LLM responses, up
+		// to server.shutdown_timeout, and give cancelled ones a moment to respond
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancelDrain()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout+5*time.Second)
+		defer cancel()
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- server.Shutdown(ctx) }()
+		if cancelled := chatHandler.Drain(drainCtx); cancelled > 0 {
+			log.Printf("Cancelled %d chat requests still waiting for the LLM", cancelled)
+		}
+
+		// Keep the chat metrics of the last interval
+		if err := chatHandler.FlushMetricsHistory(); err != nil {
+			log.Printf("Writing the chat metrics history failed: %v", err)
+		}
+
+		// Record the session's state and close its log, whether or not the server stops cleanly
+		defer gdbHandler.Shutdown()
+
+		// Attempt to gracefully shutdown the server
+		if err := <-shutdownErr; err != nil {
+			// Force shutdown if graceful shutdown fails
+			server.Close()
+			return fmt.Errorf("could not stop server gracefully: %w", err)
+		}
+
+		// Open gRPC streams and debug adapter clients are cut rather than waited for
+		if grpcServer != nil {
+			grpcServer.Close()
+		}
+		if dapServer != nil {
+			dapServer.Close()
+		}
+
+		// Export the spans of the last requests
+		if err := tracer.Shutdown(ctx); err != nil {
+			log.Printf("Exporting remaining spans failed: %v", err)
+		}
+
+		// Close the connection to a Redis response cache
+		if err := responseCache.Close(); err != nil {
+			log.Printf("Closing the response cache failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// displayAddress returns a listen address as a browser would reach it, e.g. ":8080" as
+// "localhost:8080"
+func displayAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// setupRoutes configures all the routes for the application
+func setupRoutes(router *mux.Router) error {
+	// This will be automatically invoked by the DI container with all required dependencies
+	return diContainer.Invoke(func(
+		cfg *config.Config,
+		fileHandler *handlers.FileHandler,
+		gdbHandler *handlers.GDBHandler,
+		settingsHandler *handlers.SettingsHandler,
+		memoryHandler *handlers.MemoryHandler,
+		breakpointHandler *handlers.BreakpointHandler,
+		runHandler *handlers.RunHandler,
+		checkpointHandler *handlers.CheckpointHandler,
+		scriptHandler *handlers.ScriptHandler,
+		binaryHandler *handlers.BinaryHandler,
+		capabilitiesHandler *handlers.CapabilitiesHandler,
+		compileHandler *handlers.CompileHandler,
+		authenticator *auth.Authenticator,
+		exportHandler *handlers.ExportHandler,
+		logHandler *handlers.LogHandler,
+		adminHandler *handlers.AdminHandler,
+		labHandler *handlers.LabHandler,
+		auditHandler *handlers.AuditHandler,
+		chatHandler *api.SimpleChatHandler,
+		featureManager *features.Manager,
+		wsHub *websocket.Hub,
+		tracer *tracing.Tracer,
+		mcpHandler *mcp.Handler,
+		triageHandler *triage.Handler,
+		healthChecker *health.Checker,
+	) {
+		// Require authentication for everything except the UI shell and login endpoints
+		if !authenticator.Enabled() {
+			log.Println("WARNING: authentication is disabled (auth.mode: none); anyone who can reach the server can run GDB")
+		}
+		router.Use(middleware.RequestLogger)
+		router.Use(tracer.Middleware)
+		if cfg.Server.CSRF {
+			router.Use(middleware.CSRF(cfg.Server.AllowedOrigins))
+		}
+		router.Use(authenticator.Middleware)
+		router.HandleFunc("/auth/login", authenticator.HandleLogin).Methods("POST")
+		router.HandleFunc("/auth/logout", authenticator.HandleLogout).Methods("POST")
+		router.HandleFunc("/auth/status", authenticator.HandleStatus).Methods("GET")
+
+		// Register API routes
+		router.HandleFunc("/upload", fileHandler.HandleUpload).Methods("POST")
+		router.HandleFunc("/api/v1/uploads", fileHandler.HandleChunkedCreate).Methods("POST")
+		router.HandleFunc("/api/v1/uploads/{id}", fileHandler.HandleChunkedStatus).Methods("GET")
+		router.HandleFunc("/api/v1/uploads/{id}", fileHandler.HandleChunkedWrite).Methods("PATCH")
+		router.HandleFunc("/api/v1/uploads/{id}", fileHandler.HandleChunkedCancel).Methods("DELETE")
+		router.HandleFunc("/api/v1/uploads/{id}/complete", fileHandler.HandleChunkedComplete).Methods("POST")
+		router.HandleFunc("/ws", websocket.ServeWs(wsHub, gdbHandler, websocket.LimitsFromConfig(cfg.WebSocket), cfg.Server.AllowedOrigins))
+		router.HandleFunc("/api/ws/metrics", wsHub.HandleMetrics).Methods("GET")
+		router.HandleFunc("/start-gdb", gdbHandler.HandleStartGDB).Methods("POST")
+		router.HandleFunc("/stop-gdb", gdbHandler.HandleStopGDB).Methods("POST")
+		router.HandleFunc("/api/compile", compileHandler.HandleCompile).Methods("POST")
+		router.HandleFunc("/api/gdb/annotate", gdbHandler.HandleAnnotateAddress).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/memory", memoryHandler.HandleRead).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/memory", memoryHandler.HandleWrite).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/breakpoints", breakpointHandler.HandleList).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/breakpoints", breakpointHandler.HandleSet).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/run-until", runHandler.HandleRunUntil).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/checkpoints", checkpointHandler.HandleList).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/checkpoints", checkpointHandler.HandleCreate).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/checkpoints/{id}/restore", checkpointHandler.HandleRestore).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/checkpoints/{id}", checkpointHandler.HandleDelete).Methods("DELETE")
+		router.HandleFunc("/api/v1/debugger/registers", gdbHandler.HandleRegisters).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/threads", gdbHandler.HandleThreads).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/threads/{id}/select", gdbHandler.HandleSelectThread).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/goroutines", gdbHandler.HandleGoroutines).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/variables", gdbHandler.HandleVariables).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/pretty-printers", gdbHandler.HandlePrettyPrinters).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/pretty-printers", gdbHandler.HandleEnablePrettyPrinters).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/hooks", gdbHandler.HandleHooks).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/hooks", gdbHandler.HandleSetHooks).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/scripts", scriptHandler.HandleList).Methods("GET")
+		router.HandleFunc("/api/v1/debugger/scripts", scriptHandler.HandleUpload).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/scripts/{name}/run", scriptHandler.HandleRun).Methods("POST")
+		router.HandleFunc("/api/v1/debugger/scripts/{name}", scriptHandler.HandleDelete).Methods("DELETE")
+		router.HandleFunc("/api/v1/binaries", fileHandler.HandleListBinaries).Methods("GET")
+		router.HandleFunc("/api/v1/audit", auditHandler.HandleQuery).Methods("GET")
+		router.HandleFunc("/api/v1/audit/verify", auditHandler.HandleVerify).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/sections", binaryHandler.HandleSections).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/symbols", binaryHandler.HandleSymbols).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/imports", binaryHandler.HandleImports).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/strings", binaryHandler.HandleStrings).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/summary", binaryHandler.HandleSummary).Methods("GET")
+		router.HandleFunc("/api/v1/binaries/{filename}/decompile", binaryHandler.HandleDecompile).Methods("GET")
+		router.HandleFunc("/api/gdb/observe", gdbHandler.HandleObserve).Methods("POST")
+		router.HandleFunc("/api/gdb/output", gdbHandler.HandleOutput).Methods("GET")
+		router.HandleFunc("/api/sessions/metrics", gdbHandler.HandleSessionMetrics).Methods("GET")
+		router.HandleFunc("/api/sessions/members", gdbHandler.HandleSessionMembers).Methods("GET")
+		router.HandleFunc("/api/sessions/join", gdbHandler.HandleJoinSession).Methods("POST")
+		router.HandleFunc("/api/sessions/leave", gdbHandler.HandleLeaveSession).Methods("POST")
+		router.HandleFunc("/api/sessions/share", gdbHandler.HandleShareSession).Methods("POST")
+		router.HandleFunc("/api/sessions/share/{token}", gdbHandler.HandleRevokeShare).Methods("DELETE")
+		router.HandleFunc("/api/chat", chatHandler.HandleChat).Methods("POST")
+		router.HandleFunc("/api/chat/metrics", chatHandler.HandleMetrics).Methods("GET")
+		router.HandleFunc("/api/chat/metrics/history", chatHandler.HandleMetricsHistory).Methods("GET")
+		router.HandleFunc("/api/metrics/cost", chatHandler.HandleCost).Methods("GET")
+		router.HandleFunc("/api/chat/prompt", chatHandler.HandlePromptPreview).Methods("POST")
+		router.HandleFunc("/api/prompts", chatHandler.HandlePromptTemplates).Methods("GET")
+		router.HandleFunc("/api/prompts/preview", chatHandler.HandlePromptRender).Methods("POST")
+		router.HandleFunc("/api/prompts/profiles", chatHandler.HandlePromptProfiles).Methods("GET")
+		router.HandleFunc("/api/chat/observe", chatHandler.HandleObserve).Methods("POST")
+		router.HandleFunc("/api/chat/cancel", chatHandler.HandleCancel).Methods("POST")
+		router.HandleFunc("/api/chat/pages/{token}", chatHandler.HandlePage).Methods("GET")
+		router.HandleFunc("/api/v1/chat/attachments", chatHandler.HandleAttachmentList).Methods("GET")
+		router.HandleFunc("/api/v1/chat/attachments", chatHandler.HandleAttachmentUpload).Methods("POST")
+		router.HandleFunc("/api/v1/chat/attachments/{id}", chatHandler.HandleAttachmentGet).Methods("GET")
+		router.HandleFunc("/api/v1/chat/attachments/{id}", chatHandler.HandleAttachmentDelete).Methods("DELETE")
+		router.HandleFunc("/api/chat/branches", chatHandler.HandleBranchList).Methods("GET")
+		router.HandleFunc("/api/chat/branches", chatHandler.HandleBranchCreate).Methods("POST")
+		router.HandleFunc("/api/chat/branches/{id}", chatHandler.HandleBranchGet).Methods("GET")
+		router.HandleFunc("/api/chat/branches/{id}", chatHandler.HandleBranchDelete).Methods("DELETE")
+		router.HandleFunc("/api/chat/branches/{id}/messages", chatHandler.HandleBranchMessage).Methods("POST")
+		router.HandleFunc("/api/settings", settingsHandler.GetSettings).Methods("GET")
+		router.HandleFunc("/save-settings", settingsHandler.SaveSettings).Methods("POST")
+		router.HandleFunc("/test-connection", settingsHandler.TestConnection).Methods("POST")
+		router.HandleFunc("/api/v1/providers/{name}/models", settingsHandler.HandleProviderModels).Methods("GET")
+		router.HandleFunc("/api/capabilities", capabilitiesHandler.HandleCapabilities).Methods("GET")
+		router.HandleFunc("/api/sessions/{id}/export", exportHandler.HandleExport).Methods("GET")
+		router.HandleFunc("/api/logs", logHandler.HandleList).Methods("GET")
+		router.HandleFunc("/api/logs/{id}", logHandler.HandleEntries).Methods("GET")
+		router.HandleFunc("/api/logs/{id}/download", logHandler.HandleDownload).Methods("GET")
+		router.HandleFunc("/api/admin/config", adminHandler.HandleEffectiveConfig).Methods("GET")
+		router.HandleFunc("/api/admin/cache", chatHandler.HandleCacheList).Methods("GET")
+		router.HandleFunc("/api/admin/cache", chatHandler.HandleCacheInvalidate).Methods("DELETE")
+		router.HandleFunc("/api/admin/cache/warm", chatHandler.HandleCacheWarm).Methods("POST")
+		router.HandleFunc("/api/admin/cache/warm", chatHandler.HandleCacheWarmStatus).Methods("GET")
+		router.HandleFunc("/api/labs", labHandler.HandleCatalog).Methods("GET")
+		router.HandleFunc("/api/labs/{id}/start", labHandler.HandleStart).Methods("POST")
+		router.HandleFunc("/api/admin/labs", labHandler.HandleAdminPut).Methods("POST")
+		router.HandleFunc("/api/admin/labs/{id}", labHandler.HandleAdminDelete).Methods("DELETE")
+		if cfg.MCP.Enabled {
+			router.HandleFunc("/mcp", mcpHandler.HandleMCP).Methods("POST")
+		}
+		if cfg.Triage.Enabled {
+			router.HandleFunc("/api/triage", triageHandler.HandleSubmit).Methods("POST")
+			router.HandleFunc("/api/triage/{id}", triageHandler.HandleStatus).Methods("GET")
+		}
+
+		// Serve static files, embedded in the binary unless serve -dev reads them from ./web
+		static, _ := fs.Sub(uiFiles, "static")
+		router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+
+		// Describe the API as an OpenAPI document, with Swagger UI to try it
+		router.HandleFunc("/api/v1/openapi.json", openapi.Handler(openapi.Info{
+			Title:       "GoGDBLLM",
+			Version:     version,
+			Description: "Debug programs with GDB and an LLM assistant. The web UI calls the same endpoints at their unversioned paths, e.g. /api/chat and /start-gdb, which keep working.",
+		}, cfg.Server.BasePath, router)).Methods("GET")
+		router.HandleFunc("/api/v1/docs", handlers.PageHandler(uiFiles, "templates/api_docs.html", cfg.Server.BasePath)).Methods("GET")
+
+		// Serve the dashboard charting the chat metrics history
+		router.HandleFunc("/dashboard/metrics", handlers.PageHandler(uiFiles, "templates/metrics.html", cfg.Server.BasePath)).Methods("GET")
+
+		// Serve index page
+		router.HandleFunc("/", handlers.PageHandler(uiFiles, "templates/index.html", cfg.Server.BasePath))
+
+		// Health check endpoints: every component's status, liveness and readiness
+		router.HandleFunc("/health", healthChecker.HandleHealth).Methods("GET")
+		router.HandleFunc("/healthz", healthChecker.HandleLive).Methods("GET")
+		router.HandleFunc("/readyz", healthChecker.HandleReady).Methods("GET")
+
+		// Start WebSocket hub
+		go wsHub.Run()
+
+		// Keep remote feature flags up to date (no-op without a remote URL)
+		featureManager.StartRemoteRefresh(context.Background())
+
+		// End sessions left idle (no-op without sessions.idle_ttl)
+		gdbHandler.StartReaper(context.Background())
+
+		// Write the chat metrics history (no-op without chat.metrics.history.enabled)
+		chatHandler.StartMetricsHistory(context.Background())
+	})
+}
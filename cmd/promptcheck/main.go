@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/promptcheck"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+func main() {
+	// Parse command line flags
+	fixturesDir := flag.String("fixtures", "./fixtures/prompts", "Directory containing prompt fixtures")
+	providers := flag.String("providers", "", "Comma-separated provider:model pairs to test (e.g. anthropic:claude-3-haiku-20240307)")
+	replay := flag.Bool("replay", true, "Score the recorded responses stored in the fixtures")
+	reportPath := flag.String("report", "", "Write a JSON report to this path")
+	timeout := flag.Duration("timeout", 5*time.Minute, "Overall timeout for the run")
+	configPath := flag.String("config", "", "Path to configuration file, for secrets.keychain and llm.api_key")
+	flag.Parse()
+
+	fixtures, err := promptcheck.LoadFixtures(*fixturesDir)
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		log.Fatalf("No fixtures found in %s", *fixturesDir)
+	}
+
+	var targets []promptcheck.Target
+	if *replay {
+		targets = append(targets, promptcheck.ReplayTarget{})
+	}
+	if *providers != "" {
+		providerTargets, err := buildProviderTargets(*configPath, *providers)
+		if err != nil {
+			log.Fatalf("Invalid providers: %v", err)
+		}
+		targets = append(targets, providerTargets...)
+	}
+	if len(targets) == 0 {
+		log.Fatalf("Nothing to run: enable -replay or pass -providers")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report := promptcheck.Run(ctx, fixtures, targets)
+	fmt.Print(report.Summary())
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+// buildProviderTargets parses provider:model pairs, looking up API keys as the server
+// does: GOGDBLLM_<PROVIDER>_KEY, the OS keychain when secrets.keychain is on, then the
+// saved settings and the config file for the provider they name
+func buildProviderTargets(configPath, spec string) ([]promptcheck.Target, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	manager, err := settings.NewManagerFromConfig("", cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	var targets []promptcheck.Target
+	for _, pair := range strings.Split(spec, ",") {
+		provider, model, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || provider == "" || model == "" {
+			return nil, fmt.Errorf("expected provider:model, got %q", pair)
+		}
+
+		apiKey := manager.APIKeyFor("", provider)
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key for provider %s; set %s", provider, settings.ProviderKeyEnv(provider))
+		}
+
+		targets = append(targets, promptcheck.NewProviderTarget(settings.Settings{
+			Provider: provider,
+			Model:    model,
+			APIKey:   apiKey,
+		}))
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,196 @@
+// Package analyze runs the assistant's agent loop on a program without the web UI: it
+// asks a question, lets the assistant run GDB commands and read their output for a few
+// turns, and reports the final answer. The analyze command uses it to triage crashes in
+// CI.
+package analyze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/api"
+)
+
+// DefaultQuestion is asked when the options name none
+const DefaultQuestion = "Run the program and find out why it crashes or misbehaves."
+
+// CoreQuestion is asked instead when a core dump is loaded
+const CoreQuestion = "The program crashed and its core dump is loaded in GDB. Find out why it crashed from the state it left."
+
+// continuePrompt asks for the next turn of the analysis
+const continuePrompt = "Continue the analysis with the GDB output above. Run more commands if you need them; " +
+	"once you know the cause, reply without GDB commands."
+
+// reportPrompt asks for the final report once the turns are spent or the assistant is done
+const reportPrompt = "Write the final report without running more GDB commands: the cause of the problem, " +
+	"the evidence for it from the GDB output (stack, registers, variables), and a suggested fix."
+
+// Options control an analysis
+type Options struct {
+	Question      string
+	MaxTurns      int    // Turns in which the assistant may run commands; 5 by default
+	Profile       string // Prompt profile, limiting the commands the assistant runs
+	TerminalLines int    // Lines of terminal output sent with each request; 200 by default
+}
+
+// Processor runs one request through the agent loop; *api.ChatProcessor implements it
+type Processor interface {
+	ProcessChat(ctx context.Context, req *api.ChatRequest) (*api.ProcessingResult, error)
+}
+
+// Turn is one request of an analysis
+type Turn struct {
+	Message   string   `json:"message"`
+	Response  string   `json:"response"`
+	Commands  []string `json:"commands,omitempty"`  // Commands the assistant ran
+	Suggested []string `json:"suggested,omitempty"` // Commands it offered without running them
+	GDBOutput string   `json:"gdbOutput,omitempty"`
+}
+
+// Report is the outcome of an analysis
+type Report struct {
+	Executable string         `json:"executable"`
+	Question   string         `json:"question"`
+	Model      string         `json:"model,omitempty"`
+	Session    string         `json:"session,omitempty"` // ID of the session log, for replay
+	Started    time.Time      `json:"started"`
+	Duration   time.Duration  `json:"durationNs"`
+	Turns      []Turn         `json:"turns"`
+	Answer     string         `json:"answer"`
+	Usage      api.TokenUsage `json:"usage"`
+	Cost       float64        `json:"cost"` // US dollars
+	Cancelled  bool           `json:"cancelled,omitempty"`
+}
+
+// Run asks the question and carries on while the assistant runs commands, for at most
+// MaxTurns turns, then asks for a final report unless the last answer ran nothing. GDB
+// must be running on the program.
+func Run(ctx context.Context, processor Processor, opts Options) (*Report, error) {
+	if opts.Question == "" {
+		opts.Question = DefaultQuestion
+	}
+	if opts.MaxTurns <= 0 {
+		opts.MaxTurns = 5
+	}
+	if opts.TerminalLines <= 0 {
+		opts.TerminalLines = 200
+	}
+
+	report := &Report{Question: opts.Question, Started: time.Now()}
+	defer func() { report.Duration = time.Since(report.Started) }()
+
+	var history []api.ChatMessage
+	message := opts.Question
+	for turn := 0; ; turn++ {
+		final := turn == opts.MaxTurns
+		if final {
+			message = reportPrompt
+		}
+
+		result, err := processor.ProcessChat(ctx, &api.ChatRequest{
+			Message:       message,
+			History:       history,
+			Profile:       opts.Profile,
+			TerminalLines: opts.TerminalLines,
+		})
+		if err == nil && result.Error != nil {
+			err = result.Error
+		}
+		if err != nil {
+			return report, fmt.Errorf("turn %d: %w", turn+1, err)
+		}
+
+		report.Turns = append(report.Turns, Turn{
+			Message:   message,
+			Response:  result.FinalText,
+			Commands:  result.ExecutedCmds,
+			Suggested: result.SuggestedCmds,
+			GDBOutput: result.GDBOutput,
+		})
+		report.Model = result.Model
+		report.Usage = report.Usage.Add(result.Usage)
+		report.Cost += result.Cost
+		report.Answer = result.FinalText
+		if result.Cancelled || ctx.Err() != nil {
+			report.Cancelled = true
+			return report, nil
+		}
+
+		// Done when the assistant answered without running anything
+		if final || len(result.ExecutedCmds) == 0 {
+			return report, nil
+		}
+
+		history = append(history,
+			api.ChatMessage{Role: "user", Content: message},
+			api.ChatMessage{Role: "assistant", Content: result.FinalText})
+		message = continuePrompt
+	}
+}
+
+// Validate fails when the analysis was cancelled or ended without an answer
+func (r *Report) Validate() error {
+	if r.Cancelled {
+		return errors.New("the analysis was cancelled")
+	}
+	if strings.TrimSpace(r.Answer) == "" {
+		return errors.New("the assistant gave no answer")
+	}
+	return nil
+}
+
+// WriteText writes the report as Markdown: the answer first, then the commands and output
+// of each turn
+func (r *Report) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Analysis of %s\n\n", r.Executable)
+	fmt.Fprintf(&sb, "**Question:** %s\n\n", r.Question)
+	sb.WriteString(strings.TrimSpace(r.Answer))
+	sb.WriteString("\n")
+	if r.Cancelled {
+		sb.WriteString("\n(The analysis was cancelled before it finished.)\n")
+	}
+
+	sb.WriteString("\n## Steps\n")
+	for i, turn := range r.Turns {
+		fmt.Fprintf(&sb, "\n### Turn %d\n\n", i+1)
+		if len(turn.Commands) == 0 {
+			sb.WriteString("No GDB commands run.\n")
+		} else {
+			sb.WriteString("```\n")
+			for _, command := range turn.Commands {
+				fmt.Fprintf(&sb, "(gdb) %s\n", command)
+			}
+			if output := strings.TrimRight(turn.GDBOutput, "\n"); output != "" {
+				sb.WriteString(output + "\n")
+			}
+			sb.WriteString("```\n")
+		}
+		if len(turn.Suggested) > 0 {
+			fmt.Fprintf(&sb, "\nSuggested but not run: `%s`\n", strings.Join(turn.Suggested, "`, `"))
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n---\nModel %s, %d turns, %s", r.Model, len(r.Turns), r.Duration.Round(time.Second))
+	if r.Cost > 0 {
+		fmt.Fprintf(&sb, ", $%.4f", r.Cost)
+	}
+	if r.Session != "" {
+		fmt.Fprintf(&sb, "; replay with `gogdbllm replay -executable %s %s`", r.Executable, r.Session)
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
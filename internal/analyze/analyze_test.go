@@ -0,0 +1,84 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/api"
+)
+
+// fakeProcessor answers with canned results in order and records the requests
+type fakeProcessor struct {
+	results  []*api.ProcessingResult
+	requests []*api.ChatRequest
+}
+
+func (f *fakeProcessor) ProcessChat(ctx context.Context, req *api.ChatRequest) (*api.ProcessingResult, error) {
+	f.requests = append(f.requests, req)
+	if len(f.results) == 0 {
+		return nil, errors.New("no more results")
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	return result, nil
+}
+
+func TestRunUntilAnswered(t *testing.T) {
+	processor := &fakeProcessor{results: []*api.ProcessingResult{
+		{FinalText: "Let me run it.", Model: "m", ExecutedCmds: []string{"run"}, GDBOutput: "SIGSEGV in parse ()", Cost: 0.01},
+		{FinalText: "Checking the stack.", Model: "m", ExecutedCmds: []string{"bt", "info locals"}, GDBOutput: "#0 parse (s=0x0)", Cost: 0.01},
+		{FinalText: "parse dereferences a NULL s. Check the argument in main.", Model: "m", Cost: 0.01},
+	}}
+
+	report, err := Run(context.Background(), processor, Options{Question: "why does this segfault", Profile: "triage"})
+	require.NoError(t, err)
+	require.NoError(t, report.Validate())
+	assert.Equal(t, "parse dereferences a NULL s. Check the argument in main.", report.Answer)
+	assert.Len(t, report.Turns, 3)
+	assert.InDelta(t, 0.03, report.Cost, 1e-9)
+
+	require.Len(t, processor.requests, 3)
+	assert.Equal(t, "why does this segfault", processor.requests[0].Message)
+	assert.Equal(t, "triage", processor.requests[0].Profile)
+	assert.Equal(t, 200, processor.requests[0].TerminalLines)
+	assert.Equal(t, continuePrompt, processor.requests[2].Message)
+	assert.Len(t, processor.requests[2].History, 4, "each turn sees the earlier ones")
+
+	report.Executable = "/ci/crash"
+	var text strings.Builder
+	require.NoError(t, report.WriteText(&text))
+	assert.True(t, strings.HasPrefix(text.String(), "# Analysis of /ci/crash\n\n**Question:** why does this segfault\n\nparse dereferences"))
+	assert.Contains(t, text.String(), "```\n(gdb) bt\n(gdb) info locals\n#0 parse (s=0x0)\n```")
+}
+
+func TestRunAsksForReportWhenTurnsAreSpent(t *testing.T) {
+	processor := &fakeProcessor{results: []*api.ProcessingResult{
+		{FinalText: "Running.", ExecutedCmds: []string{"run"}},
+		{FinalText: "More.", ExecutedCmds: []string{"bt"}},
+		{FinalText: "The report."},
+	}}
+
+	report, err := Run(context.Background(), processor, Options{MaxTurns: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "The report.", report.Answer)
+	assert.Equal(t, DefaultQuestion, processor.requests[0].Message)
+	assert.Equal(t, reportPrompt, processor.requests[2].Message)
+}
+
+func TestRunFails(t *testing.T) {
+	processor := &fakeProcessor{results: []*api.ProcessingResult{
+		{FinalText: "Running.", ExecutedCmds: []string{"run"}},
+		{Error: errors.New("rate limited")},
+	}}
+
+	report, err := Run(context.Background(), processor, Options{})
+	assert.EqualError(t, err, "turn 2: rate limited")
+	assert.Len(t, report.Turns, 1, "the report keeps the turns that ran")
+
+	assert.Error(t, (&Report{}).Validate())
+	assert.Error(t, (&Report{Answer: "partial", Cancelled: true}).Validate())
+}
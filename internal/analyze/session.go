@@ -0,0 +1,97 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/handlers"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// SessionOptions name the program an analysis runs on
+type SessionOptions struct {
+	Executable string                 // Absolute path of the program
+	Core       string                 // Core dump loaded into GDB, optional
+	Args       string                 // Arguments the program is run with
+	Kind       string                 // Part of the session ID, e.g. "analyze"
+	Metadata   map[string]interface{} // Logged with the session's metadata
+	Features   *features.Manager      // Records the session's feature flags when set
+}
+
+// Session is a GDB session of an analysis's own, apart from the web UI's, logged like a
+// session of the web UI so it can be replayed
+type Session struct {
+	ID   string
+	GDB  *handlers.GDBHandler
+	Logs *logsession.LoggerHolderImpl
+}
+
+// StartSession starts GDB on the program where it is rather than among the uploads. Core
+// dumps can only be loaded with the local backend, which sees the host's files.
+func StartSession(cfg *config.Config, hub *websocket.Hub, opts SessionOptions) (*Session, error) {
+	if strings.ContainsAny(opts.Args, "\r\n") {
+		return nil, errors.New("the program's arguments must be a single line")
+	}
+	if opts.Core != "" && cfg.GDB.Backend != config.BackendLocal {
+		return nil, fmt.Errorf("core dumps need gdb.backend: %s", config.BackendLocal)
+	}
+
+	local := *cfg
+	local.Uploads.Directory = filepath.Dir(opts.Executable)
+	if err := local.GDB.Validate(); err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Base(opts.Executable)
+	session := &Session{
+		ID:   fmt.Sprintf("%s_%s_%s", time.Now().Format("20060102_150405"), opts.Kind, filename),
+		Logs: logsession.NewLoggerHolder(),
+	}
+	logger, err := logsession.NewSessionLogger(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]interface{}{"session.filename": filename}
+	for key, value := range opts.Metadata {
+		metadata[key] = value
+	}
+	if opts.Features != nil {
+		metadata["session.features"] = opts.Features.ForSession(session.ID)
+		session.Logs.OnSessionEnd(opts.Features.ReleaseSession)
+	}
+	logger.LogSessionMetadata(metadata)
+	session.Logs.Set(logger)
+	session.GDB = handlers.NewGDBHandler(hub, session.Logs, &local)
+
+	if err := session.GDB.StartSession("", filename); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("starting GDB: %w", err)
+	}
+	if opts.Core != "" {
+		if _, err := session.GDB.ExecuteCommandWithOutput("core-file " + opts.Core); err != nil {
+			session.Close()
+			return nil, fmt.Errorf("loading the core dump: %w", err)
+		}
+	}
+	if opts.Args != "" {
+		if err := session.GDB.HandleCommand("set args " + opts.Args); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// Close stops GDB and ends the session, closing its log
+func (s *Session) Close() {
+	if s.GDB != nil {
+		s.GDB.StopSession("")
+	}
+	s.Logs.Set(nil)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// ResponsePage is one page of a chat response. NextPage is empty on the last page.
+type ResponsePage struct {
+	Text     string `json:"text"`
+	NextPage string `json:"nextPage,omitempty"` // Token for the following page
+	Offset   int    `json:"offset"`             // Byte offset of Text in the full response
+	Total    int    `json:"total"`              // Size of the full response in bytes
+}
+
+// Truncated reports whether the page is not the whole response
+func (p ResponsePage) Truncated() bool {
+	return p.Offset > 0 || p.NextPage != ""
+}
+
+// ArtifactStore keeps responses larger than a page on disk, one directory per user, so
+// they can be read a page at a time
+type ArtifactStore struct {
+	dir      string
+	pageSize int
+	ttl      time.Duration
+}
+
+// artifactIDPattern matches the IDs generated by Save
+var artifactIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// NewArtifactStore creates an artifact store from the chat output configuration
+func NewArtifactStore(cfg config.OutputConfig) *ArtifactStore {
+	return &ArtifactStore{dir: cfg.ArtifactDir, pageSize: cfg.MaxResponseSize, ttl: cfg.ArtifactTTL}
+}
+
+// Paginate returns the first page of text. Text longer than a page is stored as an
+// artifact owned by owner, and the page carries the token for the next one. A store with
+// no page size returns text unchanged.
+func (s *ArtifactStore) Paginate(owner, text string) (ResponsePage, error) {
+	if s == nil || s.pageSize <= 0 || len(text) <= s.pageSize {
+		return ResponsePage{Text: text, Total: len(text)}, nil
+	}
+
+	id, err := s.save(owner, text)
+	if err != nil {
+		return ResponsePage{}, err
+	}
+	return s.page(id, text, 0), nil
+}
+
+// Page returns the page of a stored response named by a token from a previous page
+func (s *ArtifactStore) Page(owner, token string) (ResponsePage, error) {
+	id, offset, err := decodePageToken(token)
+	if err != nil {
+		return ResponsePage{}, err
+	}
+
+	data, err := os.ReadFile(s.path(owner, id))
+	if os.IsNotExist(err) {
+		return ResponsePage{}, fmt.Errorf("response %s: %w", id, appErrors.ErrNotFound)
+	}
+	if err != nil {
+		return ResponsePage{}, fmt.Errorf("failed to read response %s: %w", id, err)
+	}
+	if offset > len(data) {
+		return ResponsePage{}, fmt.Errorf("page offset %d beyond response: %w", offset, appErrors.ErrBadRequest)
+	}
+	return s.page(id, string(data), offset), nil
+}
+
+// page cuts the page of text starting at offset, ending at the last line break in the
+// second half of the page or, failing that, at a character boundary
+func (s *ArtifactStore) page(id, text string, offset int) ResponsePage {
+	end := offset + s.pageSize
+	if end >= len(text) {
+		return ResponsePage{Text: text[offset:], Offset: offset, Total: len(text)}
+	}
+	if newline := strings.LastIndexByte(text[offset:end], '\n'); newline >= s.pageSize/2 {
+		end = offset + newline + 1
+	} else {
+		for end > offset+1 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+	return ResponsePage{
+		Text:     text[offset:end],
+		NextPage: encodePageToken(id, end),
+		Offset:   offset,
+		Total:    len(text),
+	}
+}
+
+// save writes text to a new artifact and removes the owner's expired artifacts
+func (s *ArtifactStore) save(owner, text string) (string, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", fmt.Errorf("failed to generate artifact id: %w", err)
+	}
+	id := hex.EncodeToString(raw[:])
+
+	path := s.path(owner, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	s.reap(filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		return "", fmt.Errorf("failed to store response: %w", err)
+	}
+	return id, nil
+}
+
+// reap removes artifacts in dir older than the store's TTL
+func (s *ArtifactStore) reap(dir string) {
+	if s.ttl <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.ttl)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// path returns the file of an artifact. Owners are hashed so user names never reach the
+// file system.
+func (s *ArtifactStore) path(owner, id string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]), id+".txt")
+}
+
+// encodePageToken and decodePageToken convert between a page token and the artifact ID
+// and byte offset it names
+func encodePageToken(id string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id + ":" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid page token: %w", appErrors.ErrBadRequest)
+	}
+	id, offsetText, ok := strings.Cut(string(raw), ":")
+	offset, err := strconv.Atoi(offsetText)
+	if !ok || err != nil || offset < 0 || !artifactIDPattern.MatchString(id) {
+		return "", 0, fmt.Errorf("invalid page token: %w", appErrors.ErrBadRequest)
+	}
+	return id, offset, nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestArtifactStorePagination(t *testing.T) {
+	store := NewArtifactStore(config.OutputConfig{MaxResponseSize: 64, ArtifactDir: t.TempDir()})
+
+	short, err := store.Paginate("alice", "short answer")
+	require.NoError(t, err)
+	assert.Equal(t, "short answer", short.Text)
+	assert.False(t, short.Truncated())
+
+	text := strings.Repeat("line of output\n", 20) + strings.Repeat("é", 100)
+	page, err := store.Paginate("alice", text)
+	require.NoError(t, err)
+	require.True(t, page.Truncated())
+	assert.Equal(t, len(text), page.Total)
+	assert.True(t, strings.HasSuffix(page.Text, "\n"), "pages end at a line break when possible")
+
+	// Reading every page reassembles the response without splitting characters
+	full := page.Text
+	for page.NextPage != "" {
+		page, err = store.Page("alice", page.NextPage)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page.Text), 64)
+		full += page.Text
+	}
+	assert.Equal(t, text, full)
+
+	// Tokens are scoped to the user who received them
+	first, err := store.Paginate("alice", text)
+	require.NoError(t, err)
+	_, err = store.Page("bob", first.NextPage)
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	_, err = store.Page("alice", "not-a-token")
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+}
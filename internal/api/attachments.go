@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// attachmentIDPattern matches the IDs generated by Save
+var attachmentIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// textMediaType is the media type of text attachments
+const textMediaType = "text/plain; charset=utf-8"
+
+// Attachment is a file uploaded for chat requests to attach by ID instead of pasting it
+// into the message: text, e.g. a log, a header or a linker map, attached as context, or an
+// image, e.g. a screenshot, sent to models that accept images
+type Attachment struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	MediaType string    `json:"mediaType"` // textMediaType or an image's, e.g. "image/png"
+	Size      int       `json:"size"`      // Bytes
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsImage reports whether the attachment is an image
+func (a *Attachment) IsImage() bool {
+	return imageMediaTypes[a.MediaType]
+}
+
+// file returns the name of the attachment's content in its session's directory
+func (a *Attachment) file() string {
+	if a.IsImage() {
+		return a.ID + ".img"
+	}
+	return a.ID + ".txt"
+}
+
+// AttachmentStore keeps attachments on disk, one directory per debugging session, each as
+// its content and a JSON description
+type AttachmentStore struct {
+	dir           string
+	maxSize       int64
+	maxImageSize  int64
+	maxPerSession int
+	ttl           time.Duration
+	mutex         sync.Mutex
+}
+
+// NewAttachmentStore creates an attachment store from the chat attachments configuration
+func NewAttachmentStore(cfg config.AttachmentsConfig) *AttachmentStore {
+	return &AttachmentStore{dir: cfg.Directory, maxSize: cfg.MaxSize, maxImageSize: cfg.MaxImageSize, maxPerSession: cfg.MaxPerSession, ttl: cfg.TTL}
+}
+
+// Save stores a session's text file or image under its name and returns its description.
+// Other files, e.g. executables, are refused.
+func (s *AttachmentStore) Save(session, name string, content []byte) (*Attachment, error) {
+	mediaType := imageMediaType(content)
+	switch {
+	case mediaType != "":
+		if s.maxImageSize > 0 && int64(len(content)) > s.maxImageSize {
+			return nil, fmt.Errorf("%w: images are limited to %d bytes", appErrors.ErrBadRequest, s.maxImageSize)
+		}
+	case s.maxSize > 0 && int64(len(content)) > s.maxSize:
+		return nil, fmt.Errorf("%w: attachments are limited to %d bytes", appErrors.ErrBadRequest, s.maxSize)
+	case len(content) == 0 || !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0:
+		return nil, fmt.Errorf("%w: attachments must be text files or PNG, JPEG, GIF or WebP images", appErrors.ErrBadRequest)
+	default:
+		mediaType = textMediaType
+	}
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "attachment.txt"
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dir := s.sessionDir(session)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	s.reap(dir)
+	if existing := s.list(dir); s.maxPerSession > 0 && len(existing) >= s.maxPerSession {
+		return nil, fmt.Errorf("%w: the session has %d attachments; delete some first", appErrors.ErrBadRequest, s.maxPerSession)
+	}
+
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate attachment id: %w", err)
+	}
+	attachment := &Attachment{ID: hex.EncodeToString(raw[:]), Name: name, MediaType: mediaType, Size: len(content), CreatedAt: time.Now()}
+	description, _ := json.Marshal(attachment)
+	if err := os.WriteFile(filepath.Join(dir, attachment.file()), content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, attachment.ID+".json"), description, 0600); err != nil {
+		os.Remove(filepath.Join(dir, attachment.file()))
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// List returns a session's attachments, oldest first
+func (s *AttachmentStore) List(session string) []Attachment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dir := s.sessionDir(session)
+	s.reap(dir)
+	return s.list(dir)
+}
+
+// Get returns a session's attachment and its content
+func (s *AttachmentStore) Get(session, id string) (*Attachment, []byte, error) {
+	if !attachmentIDPattern.MatchString(id) {
+		return nil, nil, fmt.Errorf("attachment %q: %w", id, appErrors.ErrNotFound)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dir := s.sessionDir(session)
+	attachment, err := readAttachment(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("attachment %q: %w", id, appErrors.ErrNotFound)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, attachment.file()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("attachment %q: %w", id, appErrors.ErrNotFound)
+	}
+	return attachment, content, nil
+}
+
+// Delete removes a session's attachment
+func (s *AttachmentStore) Delete(session, id string) error {
+	if !attachmentIDPattern.MatchString(id) {
+		return fmt.Errorf("attachment %q: %w", id, appErrors.ErrNotFound)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dir := s.sessionDir(session)
+	attachment, err := readAttachment(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return fmt.Errorf("attachment %q: %w", id, appErrors.ErrNotFound)
+	}
+	removeAttachment(dir, attachment)
+	return nil
+}
+
+// Release removes all of a session's attachments, once it has ended
+func (s *AttachmentStore) Release(session string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	os.RemoveAll(s.sessionDir(session))
+}
+
+// list reads the attachments in a session's directory; the caller holds the lock
+func (s *AttachmentStore) list(dir string) []Attachment {
+	attachments := []Attachment{}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range paths {
+		if attachment, err := readAttachment(path); err == nil {
+			attachments = append(attachments, *attachment)
+		}
+	}
+	sort.SliceStable(attachments, func(i, j int) bool { return attachments[i].CreatedAt.Before(attachments[j].CreatedAt) })
+	return attachments
+}
+
+// reap removes a session's attachments older than the store's TTL; the caller holds the
+// lock
+func (s *AttachmentStore) reap(dir string) {
+	if s.ttl <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.ttl)
+	for _, attachment := range s.list(dir) {
+		if attachment.CreatedAt.Before(cutoff) {
+			removeAttachment(dir, &attachment)
+		}
+	}
+}
+
+// sessionDir returns the directory of a session's attachments. Sessions are hashed so
+// user names never reach the file system.
+func (s *AttachmentStore) sessionDir(session string) string {
+	sum := sha256.Sum256([]byte(session))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]))
+}
+
+// removeAttachment removes an attachment's description and content
+func removeAttachment(dir string, attachment *Attachment) {
+	os.Remove(filepath.Join(dir, attachment.ID+".json"))
+	os.Remove(filepath.Join(dir, attachment.file()))
+}
+
+// readAttachment reads an attachment's description. Attachments stored before images
+// were accepted have no media type and are text.
+func readAttachment(path string) (*Attachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var attachment Attachment
+	if err := json.Unmarshal(data, &attachment); err != nil {
+		return nil, err
+	}
+	if attachment.MediaType == "" {
+		attachment.MediaType = textMediaType
+	}
+	return &attachment, nil
+}
+
+// ResolveAttachments adds the text attachments a request names to its context and the
+// images to its images, so they are part of its cache key, and clears the names so they
+// are attached once. Requests with images are refused unless the user's model, or the one
+// the request chose, accepts images.
+func (cp *ChatProcessor) ResolveAttachments(ctx context.Context, req *ChatRequest) error {
+	session := cp.session(ctx)
+	for _, id := range req.Attachments {
+		attachment, content, err := cp.attachments.Get(session, id)
+		if err != nil {
+			return err
+		}
+		if attachment.IsImage() {
+			req.Images = append(req.Images, Image{Name: attachment.Name, MediaType: attachment.MediaType, Data: content})
+			continue
+		}
+		req.SentContext = append(req.SentContext, ContextItem{
+			Type:        "attachment",
+			Description: attachment.Name,
+			Content:     string(content),
+		})
+	}
+	req.Attachments = nil
+
+	if len(req.Images) == 0 {
+		return nil
+	}
+	settings := withOverrides(cp.settingsManager.GetUserSettings(userFromContext(ctx)), req)
+	return req.ValidateImages(settings.Provider, settings.Model, cp.attachments.maxImageSize)
+}
+
+// HandleAttachmentUpload stores a text file or image for the current session's chat
+// requests, sent as the "file" field of a multipart form, and returns its description
+// with its ID
+func (sch *SimpleChatHandler) HandleAttachmentUpload(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	store := sch.processor.attachments
+	if store.maxSize > 0 && store.maxImageSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, max(store.maxSize, store.maxImageSize)+(1<<20))
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "A file is required in the \"file\" field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Reading the file failed", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := store.Save(sch.queueSession(r.Context()), header.Filename, content)
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	if logger := sch.processor.requestLogger(r.Context()); logger != nil {
+		logger.LogEvent("INFO", "chat.attachment", "Chat attachment uploaded", map[string]interface{}{
+			"attachment.id":   attachment.ID,
+			"attachment.name": attachment.Name,
+			"attachment.type": attachment.MediaType,
+			"attachment.size": attachment.Size,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// HandleAttachmentList lists the current session's attachments
+func (sch *SimpleChatHandler) HandleAttachmentList(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachments": sch.processor.attachments.List(sch.queueSession(r.Context()))})
+}
+
+// HandleAttachmentGet returns one of the current session's attachments as it was uploaded
+func (sch *SimpleChatHandler) HandleAttachmentGet(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	attachment, content, err := sch.processor.attachments.Get(sch.queueSession(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	w.Header().Set("Content-Type", attachment.MediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(content)
+}
+
+// HandleAttachmentDelete deletes one of the current session's attachments
+func (sch *SimpleChatHandler) HandleAttachmentDelete(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	if err := sch.processor.attachments.Delete(sch.queueSession(r.Context()), mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
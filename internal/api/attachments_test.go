@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// noSession is a LoggerHolder before any debugging session has started
+type noSession struct{}
+
+func (noSession) Set(*logsession.SessionLogger)  {}
+func (noSession) Get() *logsession.SessionLogger { return nil }
+func (noSession) OnSessionEnd(func(string))      {}
+
+func TestAttachmentStore(t *testing.T) {
+	store := NewAttachmentStore(config.AttachmentsConfig{Directory: t.TempDir(), MaxSize: 64, MaxPerSession: 2, TTL: time.Hour})
+
+	log, err := store.Save("session:1", "../../build/link.map", []byte("0x401000 main\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "link.map", log.Name, "directories are dropped from names")
+	assert.False(t, log.IsImage())
+	assert.Equal(t, 14, log.Size)
+
+	attachment, content, err := store.Get("session:1", log.ID)
+	require.NoError(t, err)
+	assert.Equal(t, log.Name, attachment.Name)
+	assert.Equal(t, "0x401000 main\n", string(content))
+
+	// Attachments belong to their session
+	_, _, err = store.Get("session:2", log.ID)
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	assert.Empty(t, store.List("session:2"))
+
+	// Only text within the limits is kept
+	_, err = store.Save("session:1", "a.out", []byte("\x7fELF\x00\x00"))
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+	_, err = store.Save("session:1", "big.log", []byte(strings.Repeat("x", 65)))
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+	header, err := store.Save("session:1", "types.h", []byte("struct s;\n"))
+	require.NoError(t, err)
+	_, err = store.Save("session:1", "third.log", []byte("x"))
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+
+	listed := store.List("session:1")
+	require.Len(t, listed, 2)
+	assert.Equal(t, []string{log.ID, header.ID}, []string{listed[0].ID, listed[1].ID})
+
+	require.NoError(t, store.Delete("session:1", log.ID))
+	assert.ErrorIs(t, store.Delete("session:1", log.ID), appErrors.ErrNotFound)
+	assert.ErrorIs(t, store.Delete("session:1", "../../etc"), appErrors.ErrNotFound)
+	assert.Len(t, store.List("session:1"), 1)
+}
+
+func TestAttachmentStoreImages(t *testing.T) {
+	store := NewAttachmentStore(config.AttachmentsConfig{Directory: t.TempDir(), MaxSize: 16, MaxImageSize: 1024})
+	screenshot := testPNG(t, 8, 8)
+
+	attachment, err := store.Save("session:1", "gui.png", screenshot)
+	require.NoError(t, err, "images are not held to the text limit")
+	assert.Equal(t, "image/png", attachment.MediaType)
+	assert.True(t, attachment.IsImage())
+	_, content, err := store.Get("session:1", attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, screenshot, content)
+
+	_, err = store.Save("session:1", "huge.png", append(testPNG(t, 8, 8), make([]byte, 1024)...))
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+
+	require.NoError(t, store.Delete("session:1", attachment.ID))
+	_, err = os.Stat(filepath.Join(store.sessionDir("session:1"), attachment.ID+".img"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAttachmentStoreExpires(t *testing.T) {
+	store := NewAttachmentStore(config.AttachmentsConfig{Directory: t.TempDir(), TTL: time.Hour})
+	old, err := store.Save("session:1", "old.log", []byte("old"))
+	require.NoError(t, err)
+
+	// Backdate the attachment past the TTL
+	old.CreatedAt = time.Now().Add(-2 * time.Hour)
+	description, err := json.Marshal(old)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(store.sessionDir("session:1"), old.ID+".json"), description, 0600))
+
+	assert.Empty(t, store.List("session:1"))
+	_, err = os.Stat(filepath.Join(store.sessionDir("session:1"), old.ID+".txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResolveAttachments(t *testing.T) {
+	processor := &ChatProcessor{
+		loggerHolder: noSession{},
+		attachments:  NewAttachmentStore(config.AttachmentsConfig{Directory: t.TempDir()}),
+	}
+	log, err := processor.attachments.Save("user:", "crash.log", []byte("Segmentation fault\n"))
+	require.NoError(t, err)
+
+	req := &ChatRequest{Message: "Why?", Attachments: []string{log.ID}}
+	require.NoError(t, processor.ResolveAttachments(context.Background(), req))
+	assert.Equal(t, []ContextItem{{Type: "attachment", Description: "crash.log", Content: "Segmentation fault\n"}}, req.SentContext)
+	assert.Empty(t, req.Attachments)
+
+	req = &ChatRequest{Message: "Why?", Attachments: []string{"0123456789abcdef0123456789abcdef"}}
+	assert.ErrorIs(t, processor.ResolveAttachments(context.Background(), req), appErrors.ErrNotFound)
+}
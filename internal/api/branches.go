@@ -0,0 +1,432 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// maxBranchesPerSession caps the conversation branches kept for a session
+const maxBranchesPerSession = 50
+
+// Branch is a conversation forked at one of its turns and continued with another provider
+// or model, so models can be compared on the same GDB state. Branches are kept with the
+// debugging session; their GDB commands are suggested, never run, so they leave the
+// session as the main conversation found it.
+type Branch struct {
+	ID          string          `json:"id"`
+	Parent      string          `json:"parent,omitempty"` // Branch forked from; empty for the chat window's conversation
+	At          int             `json:"at"`               // Messages of the parent the branch starts with
+	Provider    string          `json:"provider"`
+	Model       string          `json:"model"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxTokens   int             `json:"maxTokens,omitempty"`
+	Profile     string          `json:"profile,omitempty"`
+	Messages    []BranchMessage `json:"messages"`
+	Usage       TokenUsage      `json:"usage"`
+	Cost        float64         `json:"cost"` // US dollars
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// BranchMessage is a message of a branch
+type BranchMessage struct {
+	ChatMessage
+	SuggestedCommands []string `json:"suggestedCommands,omitempty"` // GDB commands the model proposed
+}
+
+// history returns the branch's messages up to at as chat history
+func (b *Branch) history(at int) []ChatMessage {
+	history := make([]ChatMessage, at)
+	for i := range history {
+		history[i] = b.Messages[i].ChatMessage
+	}
+	return history
+}
+
+// BranchStore keeps the conversation branches of each session in memory until the
+// session ends
+type BranchStore struct {
+	sessions map[string][]*Branch
+	next     int
+	mutex    sync.Mutex
+}
+
+// NewBranchStore creates an empty branch store
+func NewBranchStore() *BranchStore {
+	return &BranchStore{sessions: make(map[string][]*Branch)}
+}
+
+// Add stores a new branch of a session and assigns its ID
+func (bs *BranchStore) Add(session string, branch *Branch) error {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	if len(bs.sessions[session]) >= maxBranchesPerSession {
+		return fmt.Errorf("%w: the session has %d branches; delete some first", appErrors.ErrBadRequest, maxBranchesPerSession)
+	}
+	bs.next++
+	branch.ID = fmt.Sprintf("b%d", bs.next)
+	bs.sessions[session] = append(bs.sessions[session], branch)
+	return nil
+}
+
+// Get returns a copy of a session's branch
+func (bs *BranchStore) Get(session, id string) (*Branch, error) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	branch := bs.find(session, id)
+	if branch == nil {
+		return nil, fmt.Errorf("branch %q: %w", id, appErrors.ErrNotFound)
+	}
+	return branch.copy(), nil
+}
+
+// List returns copies of a session's branches, oldest first
+func (bs *BranchStore) List(session string) []*Branch {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	branches := make([]*Branch, len(bs.sessions[session]))
+	for i, branch := range bs.sessions[session] {
+		branches[i] = branch.copy()
+	}
+	sort.SliceStable(branches, func(i, j int) bool { return branches[i].CreatedAt.Before(branches[j].CreatedAt) })
+	return branches
+}
+
+// Append adds a turn to a session's branch and returns a copy of the branch
+func (bs *BranchStore) Append(session, id string, messages []BranchMessage, usage TokenUsage, cost float64) (*Branch, error) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	branch := bs.find(session, id)
+	if branch == nil {
+		return nil, fmt.Errorf("branch %q: %w", id, appErrors.ErrNotFound)
+	}
+	branch.Messages = append(branch.Messages, messages...)
+	branch.Usage = branch.Usage.Add(usage)
+	branch.Cost += cost
+	branch.UpdatedAt = time.Now()
+	return branch.copy(), nil
+}
+
+// Delete removes a session's branch
+func (bs *BranchStore) Delete(session, id string) error {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	branches := bs.sessions[session]
+	for i, branch := range branches {
+		if branch.ID == id {
+			bs.sessions[session] = append(branches[:i:i], branches[i+1:]...)
+			if len(bs.sessions[session]) == 0 {
+				delete(bs.sessions, session)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("branch %q: %w", id, appErrors.ErrNotFound)
+}
+
+// Release drops every branch of a session, once it has ended
+func (bs *BranchStore) Release(session string) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	delete(bs.sessions, session)
+}
+
+// find returns a session's branch; the caller holds the lock
+func (bs *BranchStore) find(session, id string) *Branch {
+	for _, branch := range bs.sessions[session] {
+		if branch.ID == id {
+			return branch
+		}
+	}
+	return nil
+}
+
+// copy returns a copy of the branch that shares no messages with it
+func (b *Branch) copy() *Branch {
+	c := *b
+	c.Messages = append([]BranchMessage(nil), b.Messages...)
+	return &c
+}
+
+// BranchRequest is the body of a request forking a conversation
+type BranchRequest struct {
+	From        string        `json:"from,omitempty"`    // Branch to fork; empty forks History
+	History     []ChatMessage `json:"history,omitempty"` // The chat window's conversation
+	At          int           `json:"at"`                // Messages kept from the forked conversation
+	Message     string        `json:"message,omitempty"` // Defaults to re-asking the user message at At
+	Provider    string        `json:"provider,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"maxTokens,omitempty"`
+	Profile     string        `json:"profile,omitempty"`
+	RequestID   string        `json:"requestId,omitempty"` // Chosen by the client to cancel the request with
+}
+
+// BranchMessageRequest is the body of a request continuing a branch
+type BranchMessageRequest struct {
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// HandleBranchCreate forks a conversation at a turn and asks the turn's question, or a
+// new one, with the provider and model given in the body
+func (sch *SimpleChatHandler) HandleBranchCreate(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	var req BranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session := sch.queueSession(r.Context())
+	source := &Branch{Messages: make([]BranchMessage, len(req.History))}
+	for i, msg := range req.History {
+		source.Messages[i] = BranchMessage{ChatMessage: msg}
+	}
+	if req.From != "" {
+		parent, err := sch.branches.Get(session, req.From)
+		if err != nil {
+			http.Error(w, err.Error(), appErrors.StatusCode(err))
+			return
+		}
+		source = parent
+	}
+	if req.At < 0 || req.At > len(source.Messages) {
+		http.Error(w, fmt.Sprintf("at must be between 0 and %d", len(source.Messages)), http.StatusBadRequest)
+		return
+	}
+	if req.Message == "" && req.At < len(source.Messages) && source.Messages[req.At].Role == "user" {
+		req.Message = source.Messages[req.At].Content
+	}
+	if req.Message == "" {
+		http.Error(w, "message is required unless at points to a user message", http.StatusBadRequest)
+		return
+	}
+
+	user := userFromContext(r.Context())
+	branchSettings, err := sch.branchSettings(user, req.Provider, req.Model)
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	branch := &Branch{
+		Parent:      req.From,
+		At:          req.At,
+		Provider:    branchSettings.Provider,
+		Model:       branchSettings.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Profile:     req.Profile,
+		Messages:    append([]BranchMessage(nil), source.Messages[:req.At]...),
+		CreatedAt:   time.Now(),
+	}
+	result, turn, ok := sch.askBranch(w, r, branch, branchSettings, req.Message, req.RequestID)
+	if !ok {
+		return
+	}
+	branch.Messages = append(branch.Messages, turn...)
+	branch.Usage, branch.Cost, branch.UpdatedAt = result.Usage, result.Cost, time.Now()
+	if err := sch.branches.Add(session, branch); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	if logger := sch.processor.requestLogger(r.Context()); logger != nil {
+		logger.LogEvent("INFO", "chat.branch", "Conversation branched", map[string]interface{}{
+			"branch.id":       branch.ID,
+			"branch.parent":   branch.Parent,
+			"branch.at":       branch.At,
+			"branch.provider": branch.Provider,
+			"branch.model":    branch.Model,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(branch)
+}
+
+// HandleBranchMessage asks a new question in a branch with the branch's provider and model
+func (sch *SimpleChatHandler) HandleBranchMessage(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	var req BranchMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Message == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session := sch.queueSession(r.Context())
+	branch, err := sch.branches.Get(session, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	branchSettings, err := sch.branchSettings(userFromContext(r.Context()), branch.Provider, branch.Model)
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	result, turn, ok := sch.askBranch(w, r, branch, branchSettings, req.Message, req.RequestID)
+	if !ok {
+		return
+	}
+	branch, err = sch.branches.Append(session, branch.ID, turn, result.Usage, result.Cost)
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(branch)
+}
+
+// HandleBranchList lists the current session's branches
+func (sch *SimpleChatHandler) HandleBranchList(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"branches": sch.branches.List(sch.queueSession(r.Context()))})
+}
+
+// HandleBranchGet returns one of the current session's branches
+func (sch *SimpleChatHandler) HandleBranchGet(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	branch, err := sch.branches.Get(sch.queueSession(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(branch)
+}
+
+// HandleBranchDelete deletes one of the current session's branches
+func (sch *SimpleChatHandler) HandleBranchDelete(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+	if err := sch.branches.Delete(sch.queueSession(r.Context()), mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// branchSettings returns the user's settings switched to a branch's provider and model.
+// Another provider needs an API key of the user's for it and a model.
+func (sch *SimpleChatHandler) branchSettings(user, provider, model string) (settings.Settings, error) {
+	s := sch.processor.settingsManager.GetUserSettings(user)
+	if provider != "" && provider != s.Provider {
+		if model == "" {
+			return s, fmt.Errorf("%w: a model is required to branch to %s", appErrors.ErrBadRequest, provider)
+		}
+		s.Provider = provider
+		s.APIKey = sch.processor.settingsManager.APIKeyFor(user, provider)
+		if s.APIKey == "" {
+			return s, fmt.Errorf("%w: no API key is configured for %s", appErrors.ErrBadRequest, provider)
+		}
+	}
+	if model != "" {
+		s.Model = model
+	}
+	return s, nil
+}
+
+// askBranch asks message after a branch's messages and returns the answer and the turn
+// to add to the branch. Failures are written to w.
+func (sch *SimpleChatHandler) askBranch(w http.ResponseWriter, r *http.Request, branch *Branch, branchSettings settings.Settings, message, requestID string) (*ProcessingResult, []BranchMessage, bool) {
+	req := &ChatRequest{
+		Message:     message,
+		History:     branch.history(len(branch.Messages)),
+		RequestID:   requestID,
+		Profile:     branch.Profile,
+		Model:       branch.Model,
+		Temperature: branch.Temperature,
+		MaxTokens:   branch.MaxTokens,
+	}
+	if err := req.ValidateOverrides(branch.Provider); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return nil, nil, false
+	}
+	if _, err := sch.processor.prompts.Profile(req.Profile); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return nil, nil, false
+	}
+
+	ctx, done := sch.inflight.start(r.Context(), userFromContext(r.Context()), requestID)
+	defer done()
+	result, err := sch.processor.AnswerBranch(ctx, branchSettings, req)
+	if cancelled(ctx) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ChatResponse{Cancelled: true})
+		return nil, nil, false
+	}
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrBudgetExceeded) {
+			status = appErrors.StatusCode(err)
+		}
+		http.Error(w, err.Error(), status)
+		return nil, nil, false
+	}
+
+	turn := []BranchMessage{
+		{ChatMessage: ChatMessage{Role: "user", Content: message}},
+		{ChatMessage: ChatMessage{Role: "assistant", Content: result.FinalText}, SuggestedCommands: result.SuggestedCmds},
+	}
+	return result, turn, true
+}
+
+// AnswerBranch asks a branch's question with the branch's settings. The model's GDB
+// commands are returned as suggestions instead of run, so the answer is based on the
+// GDB state the branch was forked in.
+func (cp *ChatProcessor) AnswerBranch(ctx context.Context, branchSettings settings.Settings, req *ChatRequest) (*ProcessingResult, error) {
+	procCtx := &ProcessingContext{
+		RequestID:   cp.generateRequestID(),
+		OriginalReq: req,
+		Settings:    branchSettings,
+		Envelope:    cp.envelope().ModeFor(branchSettings.Model),
+		Logger:      cp.requestLogger(ctx),
+	}
+	procCtx.Profile = cp.resolveProfile(procCtx, req)
+	cp.logStep(procCtx, fmt.Sprintf("Answering branch question with %s %s", branchSettings.Provider, branchSettings.Model))
+
+	response, err := cp.initialResponse(ctx, procCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := cp.parseResponse(ctx, procCtx, req, response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", appErrors.ErrInvalidLLMResponse, err)
+	}
+	if parsed.Refused {
+		cp.metrics.RecordRefusal(branchSettings.Provider)
+	}
+
+	return &ProcessingResult{
+		FinalText:     parsed.Text,
+		Model:         branchSettings.Model,
+		Envelope:      procCtx.Envelope,
+		SuggestedCmds: append(parsed.SuggestedCommands, parsed.GDBCommands...),
+		Refused:       parsed.Refused,
+		Usage:         procCtx.Usage,
+		Cost:          procCtx.Cost,
+		ProcessingLog: procCtx.ProcessingLog,
+	}, nil
+}
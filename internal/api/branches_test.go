@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestBranchStore(t *testing.T) {
+	store := NewBranchStore()
+	question := BranchMessage{ChatMessage: ChatMessage{Role: "user", Content: "why did it crash?"}}
+	branch := &Branch{Provider: "openai", Model: "gpt-4o", Messages: []BranchMessage{question}}
+	require.NoError(t, store.Add("session:1", branch))
+	require.NotEmpty(t, branch.ID)
+
+	answer := BranchMessage{ChatMessage: ChatMessage{Role: "assistant", Content: "a null pointer"}, SuggestedCommands: []string{"bt"}}
+	updated, err := store.Append("session:1", branch.ID, []BranchMessage{answer}, TokenUsage{InputTokens: 10, OutputTokens: 5}, 0.01)
+	require.NoError(t, err)
+	assert.Len(t, updated.Messages, 2)
+	assert.Equal(t, []ChatMessage{question.ChatMessage}, updated.history(1))
+
+	// Copies don't change the stored branch
+	updated.Messages[0].Content = "changed"
+	stored, err := store.Get("session:1", branch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "why did it crash?", stored.Messages[0].Content)
+	assert.Equal(t, 15, stored.Usage.InputTokens+stored.Usage.OutputTokens)
+
+	// Branches belong to their session
+	_, err = store.Get("session:2", branch.ID)
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	assert.Empty(t, store.List("session:2"))
+
+	require.NoError(t, store.Delete("session:1", branch.ID))
+	assert.Empty(t, store.List("session:1"))
+	assert.ErrorIs(t, store.Delete("session:1", branch.ID), appErrors.ErrNotFound)
+
+	for i := 0; i < maxBranchesPerSession; i++ {
+		require.NoError(t, store.Add("session:1", &Branch{}))
+	}
+	assert.ErrorIs(t, store.Add("session:1", &Branch{}), appErrors.ErrBadRequest)
+
+	store.Release("session:1")
+	assert.Empty(t, store.sessions, "an ended session's branches are dropped")
+}
@@ -0,0 +1,382 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/cache/store"
+)
+
+// maxWarmPrompts bounds the prompts in one warm request, since each one is an LLM call
+const maxWarmPrompts = 500
+
+// maxWarmBodySize bounds the prompt file of a warm request
+const maxWarmBodySize = 10 << 20
+
+// CacheFilter selects cache entries; empty fields match every entry
+type CacheFilter struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Prefix   string `json:"prefix,omitempty"` // Of the key, provider:model:hash
+}
+
+// CacheFilterFromQuery reads a filter from the provider, model and prefix query parameters
+func CacheFilterFromQuery(query url.Values) CacheFilter {
+	return CacheFilter{
+		Provider: query.Get("provider"),
+		Model:    query.Get("model"),
+		Prefix:   query.Get("prefix"),
+	}
+}
+
+// Matches reports whether an entry is selected by the filter
+func (f CacheFilter) Matches(entry *store.Entry) bool {
+	return (f.Provider == "" || strings.EqualFold(f.Provider, entry.Provider)) &&
+		(f.Model == "" || strings.EqualFold(f.Model, entry.Model)) &&
+		strings.HasPrefix(entry.Key, f.Prefix)
+}
+
+// CachedResponse describes a cache entry without the response itself, which may hold
+// details of another user's session
+type CachedResponse struct {
+	Key          string    `json:"key"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Size         int       `json:"size"` // Bytes
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	LastAccessed time.Time `json:"lastAccessed"`
+	AccessCount  int       `json:"accessCount"`
+}
+
+// List returns the unexpired entries selected by filter, most recently used first
+func (rc *ResponseCache) List(filter CacheFilter) ([]CachedResponse, error) {
+	entries, err := rc.backend.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	listed := []CachedResponse{}
+	for _, entry := range entries {
+		if entry.Expired(now) || !filter.Matches(entry) {
+			continue
+		}
+		listed = append(listed, CachedResponse{
+			Key:          entry.Key,
+			Provider:     entry.Provider,
+			Model:        entry.Model,
+			Size:         len(entry.Value),
+			CreatedAt:    entry.CreatedAt,
+			ExpiresAt:    entry.ExpiresAt,
+			LastAccessed: entry.LastAccessed,
+			AccessCount:  entry.AccessCount,
+		})
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].LastAccessed.After(listed[j].LastAccessed)
+	})
+	return listed, nil
+}
+
+// Invalidate removes the entries selected by filter and returns how many were removed
+func (rc *ResponseCache) Invalidate(filter CacheFilter) (int, error) {
+	if filter == (CacheFilter{}) {
+		entries, err := rc.backend.List()
+		if err != nil {
+			return 0, err
+		}
+		return len(entries), rc.backend.Clear()
+	}
+
+	entries, err := rc.backend.List()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !filter.Matches(entry) {
+			continue
+		}
+		if err := rc.backend.Delete(entry.Key); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// WarmStatus reports the progress of the last cache warm-up
+type WarmStatus struct {
+	Running    bool      `json:"running"`
+	User       string    `json:"user,omitempty"` // Whose provider settings are used
+	Total      int       `json:"total"`
+	Done       int       `json:"done"`
+	Warmed     int       `json:"warmed"`  // Answered by the model and cached
+	Skipped    int       `json:"skipped"` // Already cached, or refused by the model
+	Failed     int       `json:"failed"`
+	Errors     []string  `json:"errors,omitempty"`
+	StartedAt  time.Time `json:"startedAt,omitempty"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// maxWarmErrors bounds the errors kept in WarmStatus
+const maxWarmErrors = 20
+
+// cacheWarmer runs one warm-up at a time in the background
+type cacheWarmer struct {
+	mutex  sync.Mutex
+	status WarmStatus
+}
+
+// start begins a warm-up unless one is running
+func (cw *cacheWarmer) start(user string, total int) bool {
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+
+	if cw.status.Running {
+		return false
+	}
+	cw.status = WarmStatus{Running: true, User: user, Total: total, StartedAt: time.Now()}
+	return true
+}
+
+// record adds the outcome of one prompt
+func (cw *cacheWarmer) record(index int, outcome string, err error) {
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+
+	cw.status.Done++
+	switch {
+	case err != nil:
+		cw.status.Failed++
+		if len(cw.status.Errors) < maxWarmErrors {
+			cw.status.Errors = append(cw.status.Errors, fmt.Sprintf("prompt %d: %v", index+1, err))
+		}
+	case outcome == "warmed":
+		cw.status.Warmed++
+	default:
+		cw.status.Skipped++
+	}
+}
+
+// finish marks the warm-up done
+func (cw *cacheWarmer) finish() WarmStatus {
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+
+	cw.status.Running = false
+	cw.status.FinishedAt = time.Now()
+	return cw.status
+}
+
+// get returns a copy of the current status
+func (cw *cacheWarmer) get() WarmStatus {
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+
+	status := cw.status
+	status.Errors = append([]string(nil), cw.status.Errors...)
+	return status
+}
+
+// WarmCache asks the model each prompt that has no cached response yet, with the
+// requesting user's provider settings, and caches the responses. They are cached for the
+// current state of the program being debugged.
+func (cp *ChatProcessor) WarmCache(ctx context.Context, prompts []ChatRequest, progress func(index int, outcome string, err error)) {
+	userSettings := cp.settingsManager.GetUserSettings(userFromContext(ctx))
+	for i := range prompts {
+		if ctx.Err() != nil {
+			progress(i, "", ctx.Err())
+			continue
+		}
+		if err := prompts[i].ValidateOverrides(userSettings.Provider); err != nil {
+			progress(i, "", err)
+			continue
+		}
+		settings := withOverrides(userSettings, &prompts[i])
+
+		procCtx := &ProcessingContext{
+			RequestID: cp.generateRequestID(),
+			Settings:  settings,
+			Envelope:  cp.envelope().ModeFor(settings.Model),
+		}
+		procCtx.Profile = cp.resolveProfile(procCtx, &prompts[i])
+		prompts[i].GDBState = cp.stateFingerprint()
+		if response, _ := cp.cache.Get(ctx, &prompts[i], settings.Provider, settings.Model); response != "" {
+			progress(i, "cached", nil)
+			continue
+		}
+
+		response, err := cp.sendPrompt(ctx, procCtx, cp.buildPrompt(procCtx, &prompts[i]), nil)
+		if err != nil {
+			progress(i, "", err)
+			continue
+		}
+		if _, refused := DetectRefusal(response); refused {
+			progress(i, "refused", nil)
+			continue
+		}
+		cp.cache.Set(&prompts[i], settings.Provider, settings.Model, response)
+		progress(i, "warmed", nil)
+	}
+}
+
+// decodeWarmPrompts reads the prompts of a warm request: chat request bodies as a JSON
+// array or as JSON Lines
+func decodeWarmPrompts(body io.Reader) ([]ChatRequest, error) {
+	var prompts []ChatRequest
+	decoder := json.NewDecoder(body)
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid prompt file: %w", err)
+		}
+
+		var batch []ChatRequest
+		if strings.HasPrefix(strings.TrimSpace(string(value)), "[") {
+			if err := json.Unmarshal(value, &batch); err != nil {
+				return nil, fmt.Errorf("invalid prompt file: %w", err)
+			}
+		} else {
+			var prompt ChatRequest
+			if err := json.Unmarshal(value, &prompt); err != nil {
+				return nil, fmt.Errorf("invalid prompt file: %w", err)
+			}
+			batch = []ChatRequest{prompt}
+		}
+		for _, prompt := range batch {
+			if strings.TrimSpace(prompt.Message) == "" {
+				return nil, fmt.Errorf("invalid prompt file: prompt %d has no message", len(prompts)+1)
+			}
+			prompts = append(prompts, prompt)
+		}
+	}
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("the prompt file holds no prompts")
+	}
+	if len(prompts) > maxWarmPrompts {
+		return nil, fmt.Errorf("the prompt file holds %d prompts; at most %d may be warmed at once", len(prompts), maxWarmPrompts)
+	}
+	return prompts, nil
+}
+
+// authorizeCacheAdmin checks that the requesting user may manage the response cache:
+// they must be listed in chat.cache.admins, or with authentication disabled the list
+// must be empty
+func (sch *SimpleChatHandler) authorizeCacheAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := userFromContext(r.Context())
+	sch.adminsMutex.RLock()
+	admins := sch.cacheAdmins
+	sch.adminsMutex.RUnlock()
+	if admins[user] || (user == "" && len(admins) == 0) {
+		return true
+	}
+	http.Error(w, "Only cache admins may manage the response cache", http.StatusForbidden)
+	return false
+}
+
+// HandleCacheList lists the cached responses with their metadata, e.g.
+// GET /api/admin/cache?provider=openai&model=gpt-4o&prefix=openai:gpt-4o:3f
+func (sch *SimpleChatHandler) HandleCacheList(w http.ResponseWriter, r *http.Request) {
+	if !sch.authorizeCacheAdmin(w, r) {
+		return
+	}
+
+	cache := sch.processor.cache
+	entries, err := cache.List(CacheFilterFromQuery(r.URL.Query()))
+	if err != nil {
+		http.Error(w, "Listing the cache failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":   cache.GetStats(),
+		"entries": entries,
+	})
+}
+
+// HandleCacheInvalidate removes the cached responses matching the provider, model and
+// prefix query parameters, or every cached response without any, e.g.
+// DELETE /api/admin/cache?provider=anthropic
+func (sch *SimpleChatHandler) HandleCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	if !sch.authorizeCacheAdmin(w, r) {
+		return
+	}
+
+	filter := CacheFilterFromQuery(r.URL.Query())
+	removed, err := sch.processor.cache.Invalidate(filter)
+	if err != nil {
+		http.Error(w, "Invalidating the cache failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("Cache admin %q removed %d cached responses (filter %+v)", userFromContext(r.Context()), removed, filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed": removed,
+		"filter":  filter,
+	})
+}
+
+// HandleCacheWarm starts caching the responses to a file of common prompts, e.g.
+// POST /api/admin/cache/warm with chat request bodies ({"message", "history",
+// "sentContext"}) as a JSON array or JSON Lines. Prompts are sent with the requesting
+// user's provider settings in the background; HandleCacheWarmStatus reports progress.
+func (sch *SimpleChatHandler) HandleCacheWarm(w http.ResponseWriter, r *http.Request) {
+	if !sch.authorizeCacheAdmin(w, r) {
+		return
+	}
+	if !sch.processor.cache.Enabled() {
+		http.Error(w, "Response caching is disabled (chat.cache.enabled)", http.StatusConflict)
+		return
+	}
+
+	prompts, err := decodeWarmPrompts(http.MaxBytesReader(w, r.Body, maxWarmBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := userFromContext(r.Context())
+	if !sch.warmer.start(user, len(prompts)) {
+		http.Error(w, "A cache warm-up is already running", http.StatusConflict)
+		return
+	}
+
+	// The warm-up outlives the request, but keeps the user for their provider settings
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		sch.processor.WarmCache(ctx, prompts, sch.warmer.record)
+		status := sch.warmer.finish()
+		log.Printf("Cache warm-up by %q finished: %d warmed, %d skipped, %d failed", user, status.Warmed, status.Skipped, status.Failed)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(sch.warmer.get())
+}
+
+// HandleCacheWarmStatus reports the progress of the last cache warm-up, e.g.
+// GET /api/admin/cache/warm
+func (sch *SimpleChatHandler) HandleCacheWarmStatus(w http.ResponseWriter, r *http.Request) {
+	if !sch.authorizeCacheAdmin(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sch.warmer.get())
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestResponseCacheListAndInvalidate(t *testing.T) {
+	cache, err := NewResponseCacheFromConfig(config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10})
+	require.NoError(t, err)
+
+	cache.Set(&ChatRequest{Message: "why did it crash?"}, "anthropic", "claude-3-haiku", "a segfault")
+	cache.Set(&ChatRequest{Message: "why did it crash?"}, "openai", "gpt-4o", "a segfault")
+	cache.Set(&ChatRequest{Message: "what is rbp?"}, "openai", "gpt-4o-mini", "the frame pointer")
+	response, _ := cache.Get(context.Background(), &ChatRequest{Message: "why did it crash?"}, "openai", "gpt-4o")
+	assert.Equal(t, "a segfault", response)
+
+	entries, err := cache.List(CacheFilter{Provider: "openai"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "gpt-4o", entries[0].Model, "the most recently used entry comes first")
+	assert.Equal(t, 2, entries[0].AccessCount)
+	assert.Equal(t, len("a segfault"), entries[0].Size)
+
+	entries, err = cache.List(CacheFilter{Prefix: "openai:gpt-4o-mini:"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	removed, err := cache.Invalidate(CacheFilter{Provider: "openai", Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	response, _ = cache.Get(context.Background(), &ChatRequest{Message: "why did it crash?"}, "openai", "gpt-4o")
+	assert.Empty(t, response)
+
+	removed, err = cache.Invalidate(CacheFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	entries, err = cache.List(CacheFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDecodeWarmPrompts(t *testing.T) {
+	prompts, err := decodeWarmPrompts(strings.NewReader(`[{"message": "What does SIGSEGV mean?"}, {"message": "How do I set a watchpoint?"}]`))
+	require.NoError(t, err)
+	assert.Len(t, prompts, 2)
+
+	prompts, err = decodeWarmPrompts(strings.NewReader("{\"message\": \"bt\"}\n{\"message\": \"info registers\", \"history\": [{\"role\": \"user\", \"content\": \"hi\"}]}\n"))
+	require.NoError(t, err)
+	require.Len(t, prompts, 2)
+	assert.Len(t, prompts[1].History, 1)
+
+	_, err = decodeWarmPrompts(strings.NewReader(`[{"message": ""}]`))
+	assert.Error(t, err)
+	_, err = decodeWarmPrompts(strings.NewReader(""))
+	assert.Error(t, err)
+}
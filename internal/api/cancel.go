@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// chatTimeout bounds a chat request, including the GDB commands and follow-up it runs
+const chatTimeout = 120 * time.Second
+
+// CancelChatRequest names the chat request to cancel. Without a RequestID every chat
+// request of the user is cancelled.
+type CancelChatRequest struct {
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// inflightChat is a running chat request
+type inflightChat struct {
+	user   string
+	id     string // Client-chosen; may be empty
+	cancel context.CancelFunc
+}
+
+// inflightChats tracks the running chat requests so their users can cancel them. Users
+// can only cancel their own requests.
+type inflightChats struct {
+	mutex    sync.Mutex
+	requests map[*inflightChat]struct{}
+}
+
+// newInflightChats creates an empty registry
+func newInflightChats() *inflightChats {
+	return &inflightChats{requests: make(map[*inflightChat]struct{})}
+}
+
+// start registers a chat request and returns the context to process it with and a
+// function to call once it is done. Requests without an ID can only be cancelled by
+// cancelling all of the user's requests.
+func (f *inflightChats) start(parent context.Context, user, id string) (context.Context, func()) {
+	ctx, cancel := context.WithTimeout(parent, chatTimeout)
+	chat := &inflightChat{user: user, id: id, cancel: cancel}
+
+	f.mutex.Lock()
+	f.requests[chat] = struct{}{}
+	f.mutex.Unlock()
+
+	return ctx, func() {
+		cancel()
+		f.mutex.Lock()
+		delete(f.requests, chat)
+		f.mutex.Unlock()
+	}
+}
+
+// cancel cancels the user's requests with the given ID, or all of the user's requests if
+// id is empty, and returns how many were cancelled
+func (f *inflightChats) cancel(user, id string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	cancelled := 0
+	for chat := range f.requests {
+		if chat.user != user || (id != "" && chat.id != id) {
+			continue
+		}
+		chat.cancel()
+		delete(f.requests, chat)
+		cancelled++
+	}
+	return cancelled
+}
+
+// drain waits until no chat request runs or ctx is done, then cancels the requests still
+// running and returns how many it cancelled
+func (f *inflightChats) drain(ctx context.Context) int {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		f.mutex.Lock()
+		running := len(f.requests)
+		if running == 0 || ctx.Err() != nil {
+			for chat := range f.requests {
+				chat.cancel()
+				delete(f.requests, chat)
+			}
+			f.mutex.Unlock()
+			return running
+		}
+		f.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain lets the running chat requests finish, for a server shutting down: it waits for
+// them until ctx is done, then cancels the rest, which return responses marked cancelled.
+// It returns how many were cancelled.
+func (sch *SimpleChatHandler) Drain(ctx context.Context) int {
+	return sch.inflight.drain(ctx)
+}
+
+// HandleCancel cancels a running chat request of the requesting user, e.g.
+// POST /api/chat/cancel {"requestId": "..."}. The LLM call, GDB commands and follow-up of
+// the request are abandoned, and the request returns a response marked cancelled.
+func (sch *SimpleChatHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
+	var req CancelChatRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	cancelled := sch.inflight.cancel(userFromContext(r.Context()), req.RequestID)
+	if logger := sch.processor.requestLogger(r.Context()); logger != nil && cancelled > 0 {
+		logger.LogEvent("INFO", "chat.cancel", "Chat request cancelled by the user", map[string]interface{}{
+			"chat.request_id": req.RequestID,
+			"chat.cancelled":  cancelled,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cancelled": cancelled,
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInflightChatsCancel(t *testing.T) {
+	inflight := newInflightChats()
+
+	first, doneFirst := inflight.start(context.Background(), "alice", "req-1")
+	defer doneFirst()
+	second, doneSecond := inflight.start(context.Background(), "alice", "")
+	defer doneSecond()
+	other, doneOther := inflight.start(context.Background(), "bob", "req-1")
+	defer doneOther()
+
+	// Users can only cancel their own requests
+	assert.Equal(t, 1, inflight.cancel("alice", "req-1"))
+	assert.ErrorIs(t, first.Err(), context.Canceled)
+	assert.NoError(t, second.Err())
+	assert.NoError(t, other.Err())
+	assert.Equal(t, 0, inflight.cancel("alice", "req-1"), "a cancelled request is no longer in flight")
+
+	// Without an ID every request of the user is cancelled
+	assert.Equal(t, 1, inflight.cancel("alice", ""))
+	assert.ErrorIs(t, second.Err(), context.Canceled)
+	assert.NoError(t, other.Err())
+
+	doneOther()
+	assert.Equal(t, 0, inflight.cancel("bob", ""), "finished requests are forgotten")
+}
+
+func TestInflightChatsDrain(t *testing.T) {
+	inflight := newInflightChats()
+	assert.Equal(t, 0, inflight.drain(context.Background()), "nothing to wait for")
+
+	_, doneFinished := inflight.start(context.Background(), "alice", "req-1")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		doneFinished()
+	}()
+	assert.Equal(t, 0, inflight.drain(context.Background()), "requests finishing in time are waited for")
+
+	stuck, doneStuck := inflight.start(context.Background(), "bob", "")
+	defer doneStuck()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, 1, inflight.drain(ctx), "requests outlasting the deadline are cancelled")
+	assert.ErrorIs(t, stuck.Err(), context.Canceled)
+}
@@ -2,11 +2,24 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/chat/providers"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/gdb"
 	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/retrieval"
 	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/tracing"
 )
 
 // ChatProcessor handles the complete chat processing pipeline
@@ -17,13 +30,37 @@ type ChatProcessor struct {
 	responseParser  *ResponseParser
 	gdbExecutor     *GDBExecutor
 	llmClient       *LLMClient
+	features        *features.Manager
+	metrics         *MetricsCollector
+	costs           *CostTracker
+	cache           *ResponseCache
+	attachments     *AttachmentStore
+	prompts         *prompts.Engine
+	contextCfg      config.ContextConfig
+	envelopeCfg     config.EnvelopeConfig
+	retriever       *retrieval.Retriever // Finds context for each message; nil when retrieval is disabled
+	retrievalCfg    config.RetrievalConfig
+	pipeline        LLMHandler       // Sends the LLM calls of every chat route
+	postProcessors  postProcessChain // Rewrite parsed responses before they are returned or run
+	cfgMutex        sync.RWMutex     // Guards contextCfg, envelopeCfg, pipeline and postProcessors, which reloads change
+
+	// When the LLM was last told GDB was restarted
+	restartNoted time.Time
+	restartMutex sync.Mutex
 }
 
 // ProcessingResult contains the final result of chat processing
 type ProcessingResult struct {
 	FinalText     string
+	Model         string // Model that answered: the user's or the request's override
+	Envelope      string
 	ExecutedCmds  []string
+	SuggestedCmds []string // Plain envelope mode: commands offered to the user instead of executed
 	GDBOutput     string
+	Refused       bool
+	Cancelled     bool // The user cancelled the request; FinalText holds what was ready
+	Usage         TokenUsage
+	Cost          float64 // US dollars
 	Error         error
 	ProcessingLog []string
 }
@@ -33,7 +70,12 @@ type ProcessingContext struct {
 	RequestID     string
 	OriginalReq   *ChatRequest
 	Settings      settings.Settings
+	Envelope      string
+	Profile       prompts.Profile
 	Logger        *logsession.SessionLogger
+	Features      features.Assignments
+	Usage         TokenUsage // Summed over the request's LLM calls
+	Cost          float64
 	ProcessingLog []string
 }
 
@@ -42,15 +84,50 @@ func NewChatProcessor(
 	settingsManager *settings.Manager,
 	loggerHolder LoggerHolder,
 	gdbHandler GDBCommandHandler,
+	featureManager *features.Manager,
+	chatCfg config.ChatConfig,
+	responseCache *ResponseCache,
+	promptEngine *prompts.Engine,
 ) *ChatProcessor {
-	return &ChatProcessor{
+	if responseCache == nil {
+		responseCache = NewResponseCache(config.CacheConfig{})
+	}
+	if promptEngine == nil {
+		promptEngine = prompts.Builtin()
+	}
+	cp := &ChatProcessor{
 		settingsManager: settingsManager,
 		loggerHolder:    loggerHolder,
 		gdbHandler:      gdbHandler,
 		responseParser:  NewResponseParser(),
 		gdbExecutor:     NewGDBExecutor(gdbHandler),
 		llmClient:       NewLLMClient(settingsManager),
+		features:        featureManager,
+		metrics:         NewMetricsCollector(),
+		costs:           NewCostTracker(chatCfg.Cost),
+		cache:           responseCache,
+		attachments:     NewAttachmentStore(chatCfg.Attachments),
+		prompts:         promptEngine,
+		contextCfg:      chatCfg.Context,
+		envelopeCfg:     chatCfg.Envelope,
+		retriever:       newRetriever(chatCfg.Retrieval),
+		retrievalCfg:    chatCfg.Retrieval,
 	}
+	if settingsManager != nil {
+		responseCache.useAPIKeys(func(ctx context.Context) string {
+			return settingsManager.APIKeyFor(userFromContext(ctx), "openai")
+		})
+	}
+	cp.pipeline = cp.newPipeline(chatCfg)
+	// Invalid entries are rejected by ValidatePostProcessors before the processor is created
+	cp.postProcessors, _ = newPostProcessChain(chatCfg.PostProcessors)
+	if loggerHolder != nil {
+		loggerHolder.OnSessionEnd(func(sessionID string) {
+			cp.costs.Release(sessionID)
+			cp.attachments.Release(sessionKey(sessionID))
+		})
+	}
+	return cp
 }
 
 // ProcessChat handles the complete chat processing pipeline
@@ -59,26 +136,74 @@ func (cp *ChatProcessor) ProcessChat(ctx context.Context, req *ChatRequest) (*Pr
 	procCtx := &ProcessingContext{
 		RequestID:     cp.generateRequestID(),
 		OriginalReq:   req,
-		Settings:      cp.settingsManager.GetSettings(),
-		Logger:        cp.loggerHolder.Get(),
+		Settings:      withOverrides(cp.settingsManager.GetUserSettings(userFromContext(ctx)), req),
+		Logger:        cp.requestLogger(ctx),
 		ProcessingLog: []string{},
 	}
+	procCtx.Envelope = cp.envelope().ModeFor(procCtx.Settings.Model)
+	procCtx.Profile = cp.resolveProfile(procCtx, req)
+	cp.attachTerminalOutput(procCtx, req)
+	cp.attachBinaryMetadata(procCtx, req)
+	cp.attachBinarySummary(procCtx, req)
+	cp.attachScriptResults(procCtx, req)
+	cp.attachVariables(procCtx, req)
+	cp.attachStopLocation(procCtx, req)
+	cp.attachFunction(ctx, procCtx, req)
+	cp.attachRetrieved(ctx, procCtx, req)
+	cp.attachRestart(procCtx, req)
 
-	cp.logStep(procCtx, fmt.Sprintf("Starting chat processing - RequestID: %s", procCtx.RequestID))
+	if cp.features != nil {
+		sessionID := ""
+		if procCtx.Logger != nil {
+			sessionID = procCtx.Logger.SessionID()
+		}
+		procCtx.Features = cp.features.ForSession(sessionID)
+	}
+
+	cp.logStep(procCtx, fmt.Sprintf("Starting chat processing - RequestID: %s, Features: %v, Envelope: %s, Profile: %s",
+		procCtx.RequestID, procCtx.Features, procCtx.Envelope, procCtx.Profile.Name))
+
+	ctx, span := tracing.Start(ctx, "chat.process",
+		tracing.Attr("chat.request_id", procCtx.RequestID),
+		tracing.Attr("chat.envelope", procCtx.Envelope),
+		tracing.Attr("chat.profile", procCtx.Profile.Name),
+		tracing.Attr("gen_ai.system", procCtx.Settings.Provider),
+		tracing.Attr("gen_ai.request.model", procCtx.Settings.Model))
+	defer span.End()
 
-	// Step 1: Get initial LLM response
-	initialResponse, err := cp.llmClient.SendRequest(ctx, req, procCtx.Settings, procCtx.Logger)
+	// Step 1: Get initial LLM response, from the cache when the same request was answered
+	// before in the same program state
+	initialResponse, err := cp.initialResponse(ctx, procCtx, req)
 	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, ErrBudgetExceeded) {
+			return &ProcessingResult{Envelope: procCtx.Envelope, Error: err, ProcessingLog: procCtx.ProcessingLog}, nil
+		}
+		if cancelled(ctx) {
+			cp.logStep(procCtx, "Cancelled during the initial LLM request")
+			return &ProcessingResult{Envelope: procCtx.Envelope, Cancelled: true, ProcessingLog: procCtx.ProcessingLog}, nil
+		}
 		return &ProcessingResult{Error: fmt.Errorf("initial LLM request failed: %w", err)}, nil
 	}
 
 	cp.logStep(procCtx, fmt.Sprintf("Received initial LLM response: %d chars", len(initialResponse)))
 
-	// Step 2: Parse the response
-	parsedResponse, err := cp.responseParser.ParseResponse(initialResponse, procCtx.Logger)
+	// Step 2: Parse the response, asking for a reformat if it does not match the schema.
+	// Plain responses are never executed, only suggested.
+	parseCtx, parseSpan := tracing.Start(ctx, "chat.parse")
+	parsedResponse, err := cp.parseResponse(parseCtx, procCtx, req, initialResponse)
 	if err != nil {
+		parseSpan.RecordError(err)
+		parseSpan.End()
+		span.RecordError(err)
 		return &ProcessingResult{Error: fmt.Errorf("response parsing failed: %w", err)}, nil
 	}
+	parseSpan.SetAttributes(
+		tracing.Attr("chat.parse_method", parsedResponse.ParseMethod),
+		tracing.Attr("chat.commands", len(parsedResponse.GDBCommands)),
+		tracing.Attr("chat.wait_for_output", parsedResponse.WaitForOutput),
+		tracing.Attr("chat.refused", parsedResponse.Refused))
+	parseSpan.End()
 
 	cp.logStep(procCtx, fmt.Sprintf("Parsed response - Text: %d chars, Commands: %d, WaitForOutput: %v",
 		len(parsedResponse.Text), len(parsedResponse.GDBCommands), parsedResponse.WaitForOutput))
@@ -86,28 +211,62 @@ func (cp *ChatProcessor) ProcessChat(ctx context.Context, req *ChatRequest) (*Pr
 	// Step 3: Execute GDB commands if present
 	result := &ProcessingResult{
 		FinalText:     parsedResponse.Text,
+		Model:         procCtx.Settings.Model,
+		Envelope:      procCtx.Envelope,
 		ExecutedCmds:  parsedResponse.GDBCommands,
+		SuggestedCmds: parsedResponse.SuggestedCommands,
+		Refused:       parsedResponse.Refused,
 		ProcessingLog: procCtx.ProcessingLog,
 	}
 
+	if parsedResponse.Refused {
+		cp.metrics.RecordRefusal(procCtx.Settings.Provider)
+		cp.logStep(procCtx, "Model refused the request")
+	}
+
+	// Commands the profile does not allow are offered to the user instead of run
+	if denied := cp.filterCommands(procCtx, parsedResponse); len(denied) > 0 {
+		result.ExecutedCmds = parsedResponse.GDBCommands
+		result.SuggestedCmds = append(result.SuggestedCmds, denied...)
+		result.FinalText += fmt.Sprintf("\n\n(Note: the %s profile does not run %s; run them yourself if needed)",
+			procCtx.Profile.Name, strings.Join(denied, ", "))
+	}
+
 	if len(parsedResponse.GDBCommands) > 0 && cp.gdbHandler != nil && cp.gdbHandler.IsRunning() {
 		gdbResult, err := cp.gdbExecutor.ExecuteCommands(ctx, parsedResponse.GDBCommands, procCtx.Logger)
-		if err != nil {
+		if cancelled(ctx) {
+			// Keep the initial text but skip the follow-up
+			cp.logStep(procCtx, "Cancelled while executing GDB commands")
+			result.Cancelled = true
+		} else if err != nil {
 			cp.logStep(procCtx, fmt.Sprintf("GDB execution failed: %v", err))
 			// Don't fail the whole request, just log the error
 		} else {
-			result.GDBOutput = gdbResult.CombinedOutput
-			cp.logStep(procCtx, fmt.Sprintf("GDB commands executed - Output: %d chars", len(gdbResult.CombinedOutput)))
+			// Annotate faulting addresses so the LLM sees module/symbol/permissions for a crash
+			gdbOutput := annotateCrashOutput(cp.gdbHandler, gdbResult.CombinedOutput, procCtx.Logger)
+			gdbOutput = annotateRegisterChanges(cp.gdbHandler, gdbResult, gdbOutput)
+			result.GDBOutput = gdbOutput
+			cp.logStep(procCtx, fmt.Sprintf("GDB commands executed - Output: %d chars", len(gdbOutput)))
 
-			// Step 4: Send follow-up request if waitForOutput is true
-			if parsedResponse.WaitForOutput && gdbResult.CombinedOutput != "" {
-				followupText, err := cp.processFollowup(ctx, procCtx, gdbResult.CombinedOutput)
-				if err != nil {
+			// Step 4: Send follow-up request if waitForOutput is true, unless the session's
+			// agent loop is off
+			if parsedResponse.WaitForOutput && gdbOutput != "" && agentRounds(procCtx.Features) > 0 {
+				followup, err := cp.processFollowup(ctx, procCtx, gdbOutput)
+				if cancelled(ctx) {
+					cp.logStep(procCtx, "Cancelled during the follow-up LLM request")
+					result.Cancelled = true
+				} else if errors.Is(err, ErrBudgetExceeded) {
+					result.FinalText += "\n\n(Note: this session's LLM budget is spent, so the GDB output was not analysed)"
+				} else if err != nil {
 					cp.logStep(procCtx, fmt.Sprintf("Follow-up processing failed: %v", err))
 					// Keep original text if follow-up fails
 				} else {
-					result.FinalText = followupText
-					cp.logStep(procCtx, fmt.Sprintf("Using follow-up response: %d chars", len(followupText)))
+					result.FinalText = followup.text
+					result.ExecutedCmds = append(result.ExecutedCmds, followup.commands...)
+					if followup.output != "" {
+						result.GDBOutput += "\n" + followup.output
+					}
+					cp.logStep(procCtx, fmt.Sprintf("Using follow-up response: %d chars", len(followup.text)))
 				}
 			}
 		}
@@ -117,38 +276,579 @@ func (cp *ChatProcessor) ProcessChat(ctx context.Context, req *ChatRequest) (*Pr
 	}
 
 	cp.logStep(procCtx, "Chat processing completed successfully")
+	span.SetAttributes(
+		tracing.Attr("gen_ai.usage.input_tokens", procCtx.Usage.InputTokens),
+		tracing.Attr("gen_ai.usage.output_tokens", procCtx.Usage.OutputTokens),
+		tracing.Attr("chat.cost", procCtx.Cost),
+		tracing.Attr("chat.cancelled", result.Cancelled))
+	result.Usage = procCtx.Usage
+	result.Cost = procCtx.Cost
 	result.ProcessingLog = procCtx.ProcessingLog
 	return result, nil
 }
 
-// processFollowup handles the follow-up request with GDB output
-func (cp *ChatProcessor) processFollowup(ctx context.Context, procCtx *ProcessingContext, gdbOutput string) (string, error) {
+// initialResponse returns the model's first response to a request, which is cached
+// under the request when caching is enabled; the follow-up is never cached because it
+// depends on the GDB output of the moment
+func (cp *ChatProcessor) initialResponse(ctx context.Context, procCtx *ProcessingContext, req *ChatRequest) (string, error) {
+	req.GDBState = cp.stateFingerprint()
+	return cp.sendPrompt(ctx, procCtx, cp.buildPrompt(procCtx, req), req)
+}
+
+// stateFingerprint returns the fingerprint of the debugged program's state that cached
+// responses are keyed by, or "" when the GDB handler cannot tell it
+func (cp *ChatProcessor) stateFingerprint() string {
+	if fingerprinter, ok := cp.gdbHandler.(StateFingerprinter); ok {
+		return fingerprinter.StateFingerprint()
+	}
+	return ""
+}
+
+// sendPrompt sends a prompt through the pipeline, caching the response under cacheKey
+// unless it is nil, and adds the tokens used to the request's usage and cost. With the
+// streaming feature on, the response is passed on to the user as it arrives; a stream
+// that breaks off is resumed by the pipeline's retries without repeating any of it.
+func (cp *ChatProcessor) sendPrompt(ctx context.Context, procCtx *ProcessingContext, prompt *Prompt, cacheKey *ChatRequest) (string, error) {
+	session := ""
+	if procCtx.Logger != nil {
+		session = procCtx.Logger.SessionID()
+	}
+	var stream *providers.Stream
+	if send := cp.chatStream(ctx, procCtx); send != nil {
+		stream = providers.NewStream(func(delta string) { send(delta, false) })
+		defer send("", true)
+	}
+	cp.cfgMutex.RLock()
+	pipeline := cp.pipeline
+	cp.cfgMutex.RUnlock()
+
+	result, err := pipeline(ctx, &LLMCall{
+		Prompt:   prompt,
+		Settings: procCtx.Settings,
+		Logger:   procCtx.Logger,
+		Session:  session,
+		CacheKey: cacheKey,
+		Stream:   stream,
+	})
+	if result.Attempts > 1 {
+		cp.logStep(procCtx, fmt.Sprintf("Sent the LLM request %d times", result.Attempts))
+	}
+	if stream != nil && stream.Resumes() > 0 {
+		cp.logStep(procCtx, fmt.Sprintf("Resumed the streamed LLM response %d times", stream.Resumes()))
+	}
+	if err != nil {
+		if errors.Is(err, ErrBudgetExceeded) {
+			cp.logStep(procCtx, err.Error())
+		}
+		return "", err
+	}
+	switch result.Cache {
+	case cacheHit:
+		cp.logStep(procCtx, "Using cached LLM response")
+	case cacheSimilar:
+		cp.logStep(procCtx, "Using the cached LLM response to a similar request")
+	}
+	procCtx.Usage = procCtx.Usage.Add(result.Usage)
+	procCtx.Cost += result.Cost
+	return result.Response, nil
+}
+
+// maxAgentRounds is how many follow-ups a request sends at most in the v2 agent loop
+const maxAgentRounds = 3
+
+// followup is the answer of the follow-ups to a request, with the commands they ran in
+// the v2 agent loop and their output
+type followup struct {
+	text     string
+	commands []string
+	output   string
+}
+
+// agentRounds returns how many follow-ups with GDB output a request may send under the
+// session's agent_loop flag: none when it is off, one in v1 and up to maxAgentRounds in
+// v2. Sessions without the flag, or with an unknown version, get v1.
+func agentRounds(assignments features.Assignments) int {
+	version, ok := assignments[features.FlagAgentLoop]
+	switch {
+	case !ok:
+		return 1
+	case version == features.ValueOff:
+		return 0
+	case version == features.AgentLoopV2:
+		return maxAgentRounds
+	default:
+		return 1
+	}
+}
+
+// processFollowup sends the GDB output of a response's commands back to the model and
+// returns its answer. In the v2 agent loop, the commands a follow-up asks to see the
+// output of are run as well and sent back in the next follow-up.
+func (cp *ChatProcessor) processFollowup(ctx context.Context, procCtx *ProcessingContext, gdbOutput string) (*followup, error) {
 	cp.logStep(procCtx, "Processing follow-up request with GDB output")
+	ctx, span := tracing.Start(ctx, "chat.followup", tracing.Attr("gdb.output_length", len(gdbOutput)))
+	defer span.End()
 
-	// Create follow-up request with GDB output as context
+	result := &followup{}
+	rounds := agentRounds(procCtx.Features)
 	followupReq := *procCtx.OriginalReq
-	followupReq.SentContext = append(followupReq.SentContext, ContextItem{
-		Type:        "command_output",
-		Description: "GDB Command Output",
-		Content:     gdbOutput,
+	followupReq.SentContext = slices.Clip(followupReq.SentContext)
+	for round := 1; ; round++ {
+		// Create follow-up request with GDB output as context
+		followupReq.SentContext = append(followupReq.SentContext, ContextItem{
+			Type:        "command_output",
+			Description: "GDB Command Output",
+			Content:     gdbOutput,
+		})
+
+		// Send follow-up request
+		followupResponse, err := cp.sendPrompt(ctx, procCtx, cp.buildPrompt(procCtx, &followupReq), nil)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("follow-up LLM request failed: %w", err)
+		}
+
+		cp.logStep(procCtx, fmt.Sprintf("Received follow-up response: %d chars", len(followupResponse)))
+
+		// Parse follow-up response
+		parsedFollowup, err := cp.parseResponse(ctx, procCtx, &followupReq, followupResponse)
+		if err != nil {
+			cp.logStep(procCtx, fmt.Sprintf("Follow-up parsing failed, using raw response: %v", err))
+			result.text = followupResponse // Use raw response if parsing fails
+			return result, nil
+		}
+
+		if parsedFollowup.Refused {
+			cp.metrics.RecordRefusal(procCtx.Settings.Provider)
+			cp.logStep(procCtx, "Model refused the follow-up request")
+		}
+		result.text = parsedFollowup.Text
+
+		// The v2 agent loop runs what the follow-up wants to see and follows up again
+		if round >= rounds || !parsedFollowup.WaitForOutput || len(parsedFollowup.GDBCommands) == 0 {
+			return result, nil
+		}
+		cp.filterCommands(procCtx, parsedFollowup)
+		if len(parsedFollowup.GDBCommands) == 0 || !cp.gdbHandler.IsRunning() {
+			return result, nil
+		}
+		gdbResult, err := cp.gdbExecutor.ExecuteCommands(ctx, parsedFollowup.GDBCommands, procCtx.Logger)
+		if err != nil {
+			cp.logStep(procCtx, fmt.Sprintf("GDB execution of the follow-up's commands failed: %v", err))
+			return result, nil
+		}
+		gdbOutput = annotateCrashOutput(cp.gdbHandler, gdbResult.CombinedOutput, procCtx.Logger)
+		gdbOutput = annotateRegisterChanges(cp.gdbHandler, gdbResult, gdbOutput)
+		result.commands = append(result.commands, parsedFollowup.GDBCommands...)
+		result.output = strings.TrimPrefix(result.output+"\n"+gdbOutput, "\n")
+		cp.logStep(procCtx, fmt.Sprintf("Agent loop round %d: ran %d commands - Output: %d chars",
+			round+1, len(parsedFollowup.GDBCommands), len(gdbOutput)))
+		if gdbOutput == "" {
+			return result, nil
+		}
+	}
+}
+
+// parseResponse parses a response to a request in the request's envelope mode and runs
+// the post-processors on it
+func (cp *ChatProcessor) parseResponse(ctx context.Context, procCtx *ProcessingContext, req *ChatRequest, response string) (*ParsedResponse, error) {
+	parsed, err := cp.decodeResponse(ctx, procCtx, req, response)
+	if err != nil {
+		return nil, err
+	}
+	cp.cfgMutex.RLock()
+	postProcessors := cp.postProcessors
+	cp.cfgMutex.RUnlock()
+	postProcessors.process(parsed, func(message string) { cp.logStep(procCtx, message) })
+	return parsed, nil
+}
+
+// decodeResponse parses a response in the request's envelope mode. A JSON response that
+// does not match the response schema is sent back to the model with the schema's
+// complaint, up to chat.envelope.reformat_attempts times, and the first reformatted reply
+// that matches it is used instead.
+func (cp *ChatProcessor) decodeResponse(ctx context.Context, procCtx *ProcessingContext, req *ChatRequest, response string) (*ParsedResponse, error) {
+	if procCtx.Envelope == config.EnvelopePlain {
+		return cp.responseParser.ParsePlainResponse(response, procCtx.Logger), nil
+	}
+	parsed, err := cp.responseParser.ParseResponse(response, procCtx.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := cp.envelope().ReformatAttempts
+	for attempt := 1; parsed.ValidationError != "" && attempt <= attempts; attempt++ {
+		cp.logStep(procCtx, fmt.Sprintf("Response does not match the schema (%s); asking for a reformat, attempt %d of %d",
+			parsed.ValidationError, attempt, attempts))
+
+		// The model sees its own reply followed by what is wrong with it
+		reformatReq := ChatRequest{
+			Message: cp.reformatMessage(procCtx, parsed.ValidationError),
+			History: append(append([]ChatMessage{}, req.History...),
+				ChatMessage{Role: "user", Content: req.Message},
+				ChatMessage{Role: "assistant", Content: response}),
+			SentContext: req.SentContext,
+			Profile:     req.Profile,
+		}
+		reformatted, err := cp.sendPrompt(ctx, procCtx, cp.buildPrompt(procCtx, &reformatReq), nil)
+		if err != nil {
+			cp.logStep(procCtx, fmt.Sprintf("Reformat request failed: %v", err))
+			break
+		}
+		next, err := cp.responseParser.ParseResponse(reformatted, procCtx.Logger)
+		if err != nil {
+			return nil, err
+		}
+		if next.ValidationError == "" {
+			cp.logStep(procCtx, "Using reformatted response")
+			next.ParseMethod = "reformatted_" + next.ParseMethod
+			return next, nil
+		}
+		// Keep the first reply's text to fall back on, but complain about the latest
+		response, parsed.ValidationError = reformatted, next.ValidationError
+	}
+	return parsed, nil
+}
+
+// reformatMessage returns the request asking the model to reformat a reply that failed
+// the response schema
+func (cp *ChatProcessor) reformatMessage(procCtx *ProcessingContext, validationError string) string {
+	message, err := cp.prompts.Render(prompts.Reformat, cp.promptVars(procCtx.Logger, procCtx.Settings, procCtx.Profile.Name))
+	if err != nil {
+		message = renderBuiltinPrompt(prompts.Reformat)
+	}
+	return message + "\n\nYour response did not match the required schema: " + validationError
+}
+
+// PreviewPrompt returns the composition of the prompt that would be sent for a request,
+// using the requesting user's provider and model, without calling the LLM
+func (cp *ChatProcessor) PreviewPrompt(ctx context.Context, req *ChatRequest) PromptComposition {
+	settings := withOverrides(cp.settingsManager.GetUserSettings(userFromContext(ctx)), req)
+	procCtx := &ProcessingContext{Settings: settings}
+	cp.resolveProfile(procCtx, req)
+	cp.attachTerminalOutput(procCtx, req)
+	cp.attachBinaryMetadata(procCtx, req)
+	cp.attachBinarySummary(procCtx, req)
+	cp.attachScriptResults(procCtx, req)
+	cp.attachVariables(procCtx, req)
+	cp.attachStopLocation(procCtx, req)
+
+	contextCfg := cp.contextLimits()
+	composition := BuildPrompt(req, contextCfg).
+		WithEnvelope(cp.envelope().ModeFor(settings.Model)).
+		WithTemplates(cp.prompts, cp.promptVars(cp.loggerHolder.Get(), settings, req.Profile)).
+		Composition()
+	composition.Provider = settings.Provider
+	composition.Model = settings.Model
+	if contextCfg.Enabled {
+		composition.MaxTokens = contextCfg.MaxTokens
+	}
+	return composition
+}
+
+// buildPrompt builds the prompt for a request in the envelope mode of the session's model
+func (cp *ChatProcessor) buildPrompt(procCtx *ProcessingContext, req *ChatRequest) *Prompt {
+	return BuildPrompt(req, cp.contextLimits()).
+		WithEnvelope(procCtx.Envelope).
+		WithTemplates(cp.prompts, cp.promptVars(procCtx.Logger, procCtx.Settings, req.Profile))
+}
+
+// withOverrides returns the user's settings with the model the request chose for itself.
+// The request's temperature and maxTokens travel in its prompt.
+func withOverrides(s settings.Settings, req *ChatRequest) settings.Settings {
+	if req.Model != "" {
+		s.Model = req.Model
+	}
+	return s
+}
+
+// attachTerminalOutput adds the last req.TerminalLines lines of the session's terminal
+// output to the request's context. The request keeps them, so they are part of its cache
+// key, and they are attached once.
+func (cp *ChatProcessor) attachTerminalOutput(procCtx *ProcessingContext, req *ChatRequest) {
+	if req.TerminalLines <= 0 || cp.gdbHandler == nil {
+		return
+	}
+	output := cp.gdbHandler.RecentOutput(req.TerminalLines)
+	if output == "" {
+		cp.logStep(procCtx, "No terminal output to attach")
+		return
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "terminal_output",
+		Description: fmt.Sprintf("Last %d lines of terminal output", req.TerminalLines),
+		Content:     output,
+	})
+	req.TerminalLines = 0
+	cp.logStep(procCtx, fmt.Sprintf("Attached %d chars of terminal output", len(output)))
+}
+
+// attachFunction adds the code of the function req.Function names, or of one the message
+// asks about, to the request's context. The request keeps the function, so it is part of
+// its cache key, and it is attached once. A decompiler can take minutes, so previews of
+// the prompt leave the code out.
+func (cp *ChatProcessor) attachFunction(ctx context.Context, procCtx *ProcessingContext, req *ChatRequest) {
+	decompiler, ok := cp.gdbHandler.(FunctionDecompiler)
+	if !ok {
+		return
+	}
+	function := req.Function
+	if function == "" {
+		if function = decompiler.MentionedFunction(req.Message); function == "" {
+			return
+		}
+	}
+	description := "Code of " + function
+	for _, item := range req.SentContext {
+		if item.Type == "decompiled_function" && item.Description == description {
+			return
+		}
+	}
+	result, err := decompiler.DecompileFunction(ctx, function)
+	if err != nil {
+		cp.logStep(procCtx, fmt.Sprintf("Could not decompile %s: %v", function, err))
+		return
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "decompiled_function",
+		Description: description,
+		Content:     result.String(),
+	})
+	cp.logStep(procCtx, fmt.Sprintf("Attached %s of %s by %s", result.Language, function, result.Backend))
+}
+
+// attachStopLocation adds where the program last stopped, with the source around it, to
+// the request's context, from GDB's report of the stop rather than another command. It is
+// attached once.
+func (cp *ChatProcessor) attachStopLocation(procCtx *ProcessingContext, req *ChatRequest) {
+	locator, ok := cp.gdbHandler.(StopLocator)
+	if !ok {
+		return
+	}
+	stop := locator.LastStop()
+	if stop == nil || stop.Reason == gdb.StopExited || stop.Function == "" && stop.File == "" {
+		return
+	}
+	for _, item := range req.SentContext {
+		if item.Type == "stop_location" {
+			return
+		}
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "stop_location",
+		Description: "Where the program stopped",
+		Content:     stop.String(),
+	})
+	cp.logStep(procCtx, fmt.Sprintf("Attached the stop in %s with %d lines of source", stop.Function, len(stop.Source)))
+}
+
+// attachBinaryMetadata adds what the executable being debugged runs on and whether it
+// has debug information to the request's context, so answers fit the architecture and
+// do not suggest source-level commands for a binary without any. It is attached once.
+func (cp *ChatProcessor) attachBinaryMetadata(procCtx *ProcessingContext, req *ChatRequest) {
+	describer, ok := cp.gdbHandler.(BinaryDescriber)
+	if !ok {
+		return
+	}
+	for _, item := range req.SentContext {
+		if item.Type == "binary_metadata" {
+			return
+		}
+	}
+	metadata, err := describer.BinaryMetadata()
+	if err != nil {
+		return
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "binary_metadata",
+		Description: "The executable being debugged",
+		Content:     metadata,
+	})
+	cp.logStep(procCtx, "Attached the executable's metadata")
+}
+
+// attachBinarySummary adds a summary of the executable being debugged to the request's
+// context if req.BinaryContext asks for it, once, like attachTerminalOutput
+func (cp *ChatProcessor) attachBinarySummary(procCtx *ProcessingContext, req *ChatRequest) {
+	describer, ok := cp.gdbHandler.(BinaryDescriber)
+	if !req.BinaryContext || !ok {
+		return
+	}
+	req.BinaryContext = false
+	summary, err := describer.BinarySummary()
+	if err != nil {
+		cp.logStep(procCtx, fmt.Sprintf("No binary summary to attach: %v", err))
+		return
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "binary_summary",
+		Description: "Static analysis of the executable being debugged",
+		Content:     summary,
+	})
+	cp.logStep(procCtx, fmt.Sprintf("Attached %d chars of binary summary", len(summary)))
+}
+
+// attachScriptResults adds the session's recent script runs to the request's context if
+// req.ScriptResults asks for it, once, like attachTerminalOutput
+func (cp *ChatProcessor) attachScriptResults(procCtx *ProcessingContext, req *ChatRequest) {
+	reporter, ok := cp.gdbHandler.(ScriptReporter)
+	if !req.ScriptResults || !ok {
+		return
+	}
+	req.ScriptResults = false
+	results, err := reporter.ScriptResults()
+	if err != nil {
+		cp.logStep(procCtx, fmt.Sprintf("No script results to attach: %v", err))
+		return
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "script_results",
+		Description: "Recent GDB Python script runs and their output",
+		Content:     results,
 	})
+	cp.logStep(procCtx, fmt.Sprintf("Attached %d chars of script results", len(results)))
+}
+
+// maxVariablesContext limits the variables attached as context, in bytes
+const maxVariablesContext = 8000
 
-	// Send follow-up request
-	followupResponse, err := cp.llmClient.SendRequest(ctx, &followupReq, procCtx.Settings, procCtx.Logger)
+// attachVariables adds the innermost frame's variables to the request's context if
+// req.Variables asks for it, once, like attachTerminalOutput
+func (cp *ChatProcessor) attachVariables(procCtx *ProcessingContext, req *ChatRequest) {
+	reader, ok := cp.gdbHandler.(VariableReader)
+	if !req.Variables || !ok {
+		return
+	}
+	req.Variables = false
+	variables, err := reader.FrameVariables(0)
 	if err != nil {
-		return "", fmt.Errorf("follow-up LLM request failed: %w", err)
+		cp.logStep(procCtx, fmt.Sprintf("No variables to attach: %v", err))
+		return
 	}
+	content := variables.String()
+	if content == "" {
+		cp.logStep(procCtx, "No variables to attach: the frame has none")
+		return
+	}
+	if len(content) > maxVariablesContext {
+		content = strings.ToValidUTF8(content[:maxVariablesContext], "") + "\n..."
+	}
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "variables",
+		Description: "The arguments and locals of the innermost frame",
+		Content:     content,
+	})
+	cp.logStep(procCtx, fmt.Sprintf("Attached %d variables", len(variables.Args)+len(variables.Locals)))
+}
 
-	cp.logStep(procCtx, fmt.Sprintf("Received follow-up response: %d chars", len(followupResponse)))
+// attachRestart tells the LLM, in the first request after GDB was restarted, that GDB
+// exited and the program is no longer running, so it does not rely on earlier state
+func (cp *ChatProcessor) attachRestart(procCtx *ProcessingContext, req *ChatRequest) {
+	if cp.gdbHandler == nil {
+		return
+	}
+	restart := cp.gdbHandler.LastRestart()
+	if restart == nil {
+		return
+	}
+	cp.restartMutex.Lock()
+	defer cp.restartMutex.Unlock()
+	if !restart.Time.After(cp.restartNoted) {
+		return
+	}
+	cp.restartNoted = restart.Time
 
-	// Parse follow-up response
-	parsedFollowup, err := cp.responseParser.ParseResponse(followupResponse, procCtx.Logger)
+	req.SentContext = append(req.SentContext, ContextItem{
+		Type:        "gdb_restart",
+		Description: "GDB was restarted",
+		Content: fmt.Sprintf("GDB exited (%s) at %s and was restarted automatically on the same executable. "+
+			"%d breakpoints were set again, possibly with new numbers. The program is not running: "+
+			"earlier frames, variables and addresses are gone until it is run again.",
+			restart.Reason, restart.Time.Format(time.RFC3339), restart.Breakpoints),
+	})
+	cp.logStep(procCtx, "Attached the notice of GDB's restart")
+}
+
+// resolveProfile returns the prompt profile of a request: the one it names, or the
+// user's. The request's profile is checked by the handler; a saved profile that is no
+// longer configured falls back to the default behaviour. The request is updated with the
+// profile's name, so responses are cached per profile.
+func (cp *ChatProcessor) resolveProfile(procCtx *ProcessingContext, req *ChatRequest) prompts.Profile {
+	name := req.Profile
+	if name == "" {
+		name = procCtx.Settings.Profile
+	}
+	profile, err := cp.prompts.Profile(name)
 	if err != nil {
-		cp.logStep(procCtx, fmt.Sprintf("Follow-up parsing failed, using raw response: %v", err))
-		return followupResponse, nil // Use raw response if parsing fails
+		cp.logStep(procCtx, fmt.Sprintf("Ignoring prompt profile: %v", err))
+	}
+	req.Profile = profile.Name
+	return profile
+}
+
+// filterCommands removes the commands the request's profile does not allow from a
+// parsed response and returns them
+func (cp *ChatProcessor) filterCommands(procCtx *ProcessingContext, parsed *ParsedResponse) []string {
+	if procCtx.Profile.Name == "" {
+		return nil
+	}
+
+	var allowed, denied []string
+	for _, command := range parsed.GDBCommands {
+		if procCtx.Profile.Allows(command) {
+			allowed = append(allowed, command)
+		} else {
+			denied = append(denied, command)
+		}
 	}
+	if len(denied) > 0 {
+		cp.logStep(procCtx, fmt.Sprintf("Profile %s does not allow: %s", procCtx.Profile.Name, strings.Join(denied, ", ")))
+		parsed.GDBCommands = allowed
+	}
+	return denied
+}
 
-	return parsedFollowup.Text, nil
+// promptVars returns the values prompt templates are rendered with for the session
+// logged by logger and a prompt profile
+func (cp *ChatProcessor) promptVars(logger *logsession.SessionLogger, settings settings.Settings, profile string) prompts.Vars {
+	vars := prompts.Vars{
+		DebuggerBackend: "GDB",
+		Envelope:        cp.envelope().ModeFor(settings.Model),
+		Provider:        settings.Provider,
+		Model:           settings.Model,
+		Profile:         profile,
+	}
+	if cp.gdbHandler != nil {
+		vars.DebuggerBackend = cp.gdbHandler.Debugger()
+	}
+	if logger == nil {
+		return vars
+	}
+	if filename, ok := logger.Metadata("session.filename").(string); ok {
+		vars.Executable = filepath.Base(filename)
+	}
+	if compiled, ok := logger.Metadata("session.compiled").(map[string]interface{}); ok {
+		language, _ := compiled["language"].(string)
+		vars.Language = languageName(language)
+	}
+	return vars
+}
+
+// languageName returns the display name of a compile request's language
+func languageName(language string) string {
+	switch language {
+	case "cpp":
+		return "C++"
+	case "", "c":
+		return "C"
+	default:
+		return language
+	}
+}
+
+// GetMetrics returns per-provider request, error and refusal counts
+func (cp *ChatProcessor) GetMetrics() map[string]*ProviderMetrics {
+	return cp.metrics.GetAllMetrics()
 }
 
 // logStep adds a step to the processing log
@@ -162,6 +862,31 @@ func (cp *ChatProcessor) logStep(ctx *ProcessingContext, message string) {
 	}
 }
 
+// userFromContext returns the authenticated user of a request, or "" for the shared settings
+func userFromContext(ctx context.Context) string {
+	user, _ := auth.UserFromContext(ctx)
+	return user
+}
+
+// cancelled reports whether ctx was cancelled rather than having timed out
+func cancelled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// session returns the debugging session a chat request belongs to, or the user before
+// any session has started
+func (cp *ChatProcessor) session(ctx context.Context) string {
+	if logger := cp.loggerHolder.Get(); logger != nil {
+		return sessionKey(logger.SessionID())
+	}
+	return "user:" + userFromContext(ctx)
+}
+
+// sessionKey returns the key of a debugging session in the stores kept per session
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
 // generateRequestID generates a unique request ID
 func (cp *ChatProcessor) generateRequestID() string {
 	return fmt.Sprintf("req_%d", time.Now().UnixNano())
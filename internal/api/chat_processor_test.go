@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+// scriptedGDB is a running GDB answering each command with its output in outputs
+type scriptedGDB struct {
+	GDBCommandHandler
+	outputs map[string]string
+	ran     []string
+}
+
+func (g *scriptedGDB) IsRunning() bool { return true }
+
+func (g *scriptedGDB) Debugger() string { return "GDB" }
+
+func (g *scriptedGDB) ExecuteCommandWithOutput(cmd string) (string, error) {
+	g.ran = append(g.ran, cmd)
+	return g.outputs[cmd], nil
+}
+
+func TestAgentRounds(t *testing.T) {
+	assert.Equal(t, 1, agentRounds(nil), "sessions without the flag get v1")
+	assert.Equal(t, 0, agentRounds(features.Assignments{features.FlagAgentLoop: features.ValueOff}))
+	assert.Equal(t, 1, agentRounds(features.Assignments{features.FlagAgentLoop: features.AgentLoopV1}))
+	assert.Equal(t, maxAgentRounds, agentRounds(features.Assignments{features.FlagAgentLoop: features.AgentLoopV2}))
+}
+
+func TestProcessFollowupAgentLoop(t *testing.T) {
+	newProcessor := func(gdb *scriptedGDB, replies ...string) (*ChatProcessor, *[]*LLMCall) {
+		var calls []*LLMCall
+		return &ChatProcessor{
+			responseParser: NewResponseParser(),
+			gdbHandler:     gdb,
+			gdbExecutor:    NewGDBExecutor(gdb),
+			prompts:        prompts.Builtin(),
+			pipeline: func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+				calls = append(calls, call)
+				reply := replies[0]
+				replies = replies[1:]
+				return LLMResult{Response: reply, Attempts: 1}, nil
+			},
+		}, &calls
+	}
+	replies := []string{
+		`{"text": "Let me look at the frame.", "gdbCommands": ["info frame"], "waitForOutput": true}`,
+		`{"text": "p is NULL.", "gdbCommands": [], "waitForOutput": false}`,
+	}
+
+	// v1 answers with the first follow-up, running none of its commands
+	gdb := &scriptedGDB{outputs: map[string]string{"info frame": "Stack level 0, frame at 0x7ffe"}}
+	processor, calls := newProcessor(gdb, replies...)
+	procCtx := &ProcessingContext{
+		Envelope:    config.EnvelopeJSON,
+		OriginalReq: &ChatRequest{Message: "why did it crash?"},
+		Features:    features.Assignments{features.FlagAgentLoop: features.AgentLoopV1},
+	}
+	result, err := processor.processFollowup(context.Background(), procCtx, "Program received signal SIGSEGV")
+	require.NoError(t, err)
+	assert.Equal(t, "Let me look at the frame.", result.text)
+	assert.Empty(t, gdb.ran)
+	assert.Len(t, *calls, 1)
+
+	// v2 runs what the follow-up asked for and sends its output back
+	gdb = &scriptedGDB{outputs: map[string]string{"info frame": "Stack level 0, frame at 0x7ffe"}}
+	processor, calls = newProcessor(gdb, replies...)
+	procCtx.Features = features.Assignments{features.FlagAgentLoop: features.AgentLoopV2}
+	result, err = processor.processFollowup(context.Background(), procCtx, "Program received signal SIGSEGV")
+	require.NoError(t, err)
+	assert.Equal(t, "p is NULL.", result.text)
+	assert.Equal(t, []string{"info frame"}, gdb.ran)
+	assert.Equal(t, []string{"info frame"}, result.commands)
+	assert.Equal(t, "Stack level 0, frame at 0x7ffe", result.output)
+	require.Len(t, *calls, 2)
+	sent := (*calls)[1].Prompt.Context
+	require.Len(t, sent, 2, "the output of every round is sent")
+	assert.Equal(t, "Stack level 0, frame at 0x7ffe", sent[1].Content)
+	assert.Empty(t, procCtx.OriginalReq.SentContext, "the request itself is unchanged")
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/events"
+)
+
+// ErrBudgetExceeded is returned instead of calling the LLM once a session has spent its budget
+var ErrBudgetExceeded = errors.New("session budget exceeded")
+
+// TokenUsage is the number of tokens a provider reports for a request
+type TokenUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// Add returns the sum of two usages
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{InputTokens: u.InputTokens + other.InputTokens, OutputTokens: u.OutputTokens + other.OutputTokens}
+}
+
+// ModelCost is the usage and cost of one model within a session
+type ModelCost struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	Cost         float64 `json:"cost"`               // US dollars
+	Unpriced     bool    `json:"unpriced,omitempty"` // No price is configured for the model
+}
+
+// SessionCost is the usage and cost of a debugging session
+type SessionCost struct {
+	Session      string                `json:"session"`
+	Requests     int                   `json:"requests"`
+	InputTokens  int64                 `json:"inputTokens"`
+	OutputTokens int64                 `json:"outputTokens"`
+	Cost         float64               `json:"cost"`                // US dollars
+	Budget       float64               `json:"budget,omitempty"`    // US dollars; 0 for no limit
+	Remaining    float64               `json:"remaining,omitempty"` // Set when there is a budget
+	LastUsed     time.Time             `json:"lastUsed"`
+	Models       map[string]*ModelCost `json:"models"` // Keyed by provider/model
+}
+
+// CostTracker accumulates the tokens and cost of LLM calls per debugging session and
+// enforces the per-session budget. A session's totals are kept until it ends.
+type CostTracker struct {
+	budget   float64
+	prices   []config.PriceConfig
+	mutex    sync.Mutex
+	sessions map[string]*SessionCost
+	events   *events.Bus // Told when a session spends its budget; nil publishes nothing
+}
+
+// NewCostTracker creates a cost tracker with the configured prices and budget
+func NewCostTracker(cfg config.CostConfig) *CostTracker {
+	return &CostTracker{
+		budget:   cfg.SessionBudget,
+		prices:   cfg.Prices,
+		sessions: make(map[string]*SessionCost),
+	}
+}
+
+// Price returns the price of a model: an exact match, or else the longest matching prefix
+func (t *CostTracker) Price(model string) (config.PriceConfig, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var best config.PriceConfig
+	bestLen := -1
+	for _, price := range t.prices {
+		if price.Model == model {
+			return price, true
+		}
+		if prefix, ok := strings.CutSuffix(price.Model, "*"); ok && strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = price, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Check returns an error wrapping ErrBudgetExceeded if the session has spent its budget
+func (t *CostTracker) Check(session string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.budget <= 0 {
+		return nil
+	}
+
+	s, ok := t.sessions[session]
+	if !ok || s.Cost < t.budget {
+		return nil
+	}
+	return appErrors.NewAppError(ErrBudgetExceeded, appErrors.CodePaymentRequired,
+		fmt.Sprintf("This session has spent $%.4f of its $%.2f LLM budget; start a new session to continue", s.Cost, t.budget),
+		"CostTracker.Check", "warn")
+}
+
+// Record adds a request's usage to the session and returns its cost in US dollars
+func (t *CostTracker) Record(session, provider, model string, usage TokenUsage) float64 {
+	price, priced := t.Price(model)
+	cost := (float64(usage.InputTokens)*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	s, ok := t.sessions[session]
+	if !ok {
+		s = &SessionCost{Session: session, Models: make(map[string]*ModelCost)}
+		t.sessions[session] = s
+	}
+	s.Requests++
+	s.InputTokens += int64(usage.InputTokens)
+	s.OutputTokens += int64(usage.OutputTokens)
+	s.Cost += cost
+	s.LastUsed = time.Now()
+
+	key := provider + "/" + model
+	m, ok := s.Models[key]
+	if !ok {
+		m = &ModelCost{Unpriced: !priced}
+		s.Models[key] = m
+	}
+	m.Requests++
+	m.InputTokens += int64(usage.InputTokens)
+	m.OutputTokens += int64(usage.OutputTokens)
+	m.Cost += cost
+	return cost
+}
+
+// Release forgets the usage of a session, once it has ended
+func (t *CostTracker) Release(session string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.sessions, session)
+}
+
+// Sessions returns a copy of every session's usage, most recently used first
+func (t *CostTracker) Sessions() []SessionCost {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	sessions := make([]SessionCost, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		sessions = append(sessions, t.copyLocked(s))
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsed.After(sessions[j].LastUsed) })
+	return sessions
+}
+
+// copyLocked copies a session's usage with its budget filled in. The caller holds the mutex.
+func (t *CostTracker) copyLocked(s *SessionCost) SessionCost {
+	c := *s
+	c.Models = make(map[string]*ModelCost, len(s.Models))
+	for key, m := range s.Models {
+		model := *m
+		c.Models[key] = &model
+	}
+	if t.budget > 0 {
+		c.Budget = t.budget
+		c.Remaining = max(t.budget-s.Cost, 0)
+	}
+	return c
+}
+
+// HandleCost returns the token usage and cost of each debugging session, e.g.
+// GET /api/metrics/cost, or of one session with ?session=<id>
+func (sch *SimpleChatHandler) HandleCost(w http.ResponseWriter, r *http.Request) {
+	sessions := sch.processor.costs.Sessions()
+	if session := r.URL.Query().Get("session"); session != "" {
+		filtered := sessions[:0]
+		for _, s := range sessions {
+			if s.Session == session {
+				filtered = append(filtered, s)
+			}
+		}
+		sessions = filtered
+	}
+
+	var total float64
+	for _, s := range sessions {
+		total += s.Cost
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"timestamp":     time.Now(),
+		"sessionBudget": sch.processor.costs.budget,
+		"totalCost":     total,
+		"sessions":      sessions,
+	})
+}
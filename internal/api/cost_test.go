@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+func TestCostTrackerBudget(t *testing.T) {
+	tracker := NewCostTracker(config.CostConfig{
+		SessionBudget: 0.01,
+		Prices: []config.PriceConfig{
+			{Model: "gpt-4o*", Input: 2.5, Output: 10},
+			{Model: "gpt-4o-mini*", Input: 0.15, Output: 0.6},
+		},
+	})
+
+	price, ok := tracker.Price("gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, 0.15, price.Input, "the longest matching prefix wins")
+	_, ok = tracker.Price("llama3:8b")
+	assert.False(t, ok)
+
+	cost := tracker.Record("s1", "openai", "gpt-4o", TokenUsage{InputTokens: 2000, OutputTokens: 400})
+	assert.InDelta(t, 0.009, cost, 1e-9)
+	assert.NoError(t, tracker.Check("s1"))
+
+	tracker.Record("s1", "ollama", "llama3:8b", TokenUsage{InputTokens: 100, OutputTokens: 100})
+	tracker.Record("s1", "openai", "gpt-4o", TokenUsage{InputTokens: 1000})
+	err := tracker.Check("s1")
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 402, appErrors.StatusCode(err))
+	assert.NoError(t, tracker.Check("s2"), "budgets are per session")
+
+	sessions := tracker.Sessions()
+	require.Len(t, sessions, 1)
+	assert.Equal(t, 3, sessions[0].Requests)
+	assert.Equal(t, int64(3100), sessions[0].InputTokens)
+	assert.Equal(t, 0.0, sessions[0].Remaining)
+	assert.True(t, sessions[0].Models["ollama/llama3:8b"].Unpriced)
+	assert.Equal(t, 2, sessions[0].Models["openai/gpt-4o"].Requests)
+
+	tracker.Release("s1")
+	assert.Empty(t, tracker.Sessions(), "an ended session's usage is dropped")
+}
+
+func TestBudgetExceededEvent(t *testing.T) {
+	costs := NewCostTracker(config.CostConfig{
+		SessionBudget: 0.005,
+		Prices:        []config.PriceConfig{{Model: "gpt-4o", Input: 2.5, Output: 10}},
+	})
+	bus := events.NewBus()
+	published := make(chan events.Event, 10)
+	bus.Subscribe(func(e events.Event) { published <- e })
+	costs.events = bus
+
+	provider := &fakeProvider{}
+	pipeline := Chain(provider.send, withBudget(costs))
+	call := &LLMCall{Settings: settings.Settings{Provider: "openai", Model: "gpt-4o"}, Session: "session:abc"}
+	for i := 0; i < 3; i++ {
+		pipeline(context.Background(), call)
+	}
+
+	select {
+	case event := <-published:
+		assert.Equal(t, events.BudgetExceeded, event.Type)
+		assert.Equal(t, "abc", event.Session)
+		assert.Equal(t, 0.005, event.Data["budget"])
+	case <-time.After(time.Second):
+		t.Fatal("no event published")
+	}
+	assert.Equal(t, 2, provider.calls, "calls after the budget was spent are refused")
+	select {
+	case <-published:
+		t.Fatal("the budget was reported spent twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
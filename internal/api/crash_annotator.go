@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// AddressAnnotator is implemented by GDB handlers that can resolve addresses in the inferior
+type AddressAnnotator interface {
+	AnnotateAddress(addr uint64) (*gdb.AddressAnnotation, error)
+}
+
+// ThreadBacktracer is implemented by GDB handlers that can list the backtraces of all
+// threads; ThreadBacktraces returns "" for a program with a single thread
+type ThreadBacktracer interface {
+	ThreadBacktraces() (string, error)
+}
+
+// StopLocator is implemented by GDB handlers that follow where the program stopped, from
+// GDB's own reports of its stops
+type StopLocator interface {
+	LastStop() *gdb.StopLocation
+}
+
+// annotateCrashOutput appends address annotations for any faulting addresses found in GDB
+// output and, for a multithreaded program, the backtraces of all its threads, since the
+// thread that crashed is often not the one that caused the crash. The output is returned
+// unchanged when no crash is detected or the handler cannot annotate.
+func annotateCrashOutput(gdbHandler GDBCommandHandler, output string, logger *logsession.SessionLogger) string {
+	if !gdb.ReportsCrash(output) {
+		return output
+	}
+	annotated := output
+
+	if annotator, ok := gdbHandler.(AddressAnnotator); ok {
+		var lines []string
+		for _, addr := range gdb.FindCrashAddresses(output) {
+			annotation, err := annotator.AnnotateAddress(addr)
+			if err != nil {
+				if logger != nil {
+					logger.LogError(err, fmt.Sprintf("Annotating crash address 0x%x", addr))
+				}
+				continue
+			}
+			lines = append(lines, annotation.String())
+		}
+		if len(lines) > 0 {
+			annotated += "\n\n--- Faulting Address Annotations ---\n" + strings.Join(lines, "\n")
+		}
+	}
+
+	if backtracer, ok := gdbHandler.(ThreadBacktracer); ok {
+		backtraces, err := backtracer.ThreadBacktraces()
+		if err != nil && logger != nil {
+			logger.LogError(err, "Listing thread backtraces of a crash")
+		}
+		if backtraces != "" {
+			annotated += "\n\n--- Backtraces of All Threads ---\n" + backtraces
+		}
+	}
+
+	return annotated
+}
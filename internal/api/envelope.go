@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// respondToolName is the tool models call in tools envelope mode
+const respondToolName = "respond"
+
+// respondToolDescription describes the respond tool to the model
+const respondToolDescription = "Reply to the user, optionally running GDB commands"
+
+// respondToolSchema is the JSON Schema of the respond tool's input, matching LLMResponse
+var respondToolSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "text": {"type": "string", "description": "Your explanation or message to the user"},
+    "gdbCommands": {"type": "array", "items": {"type": "string"}, "description": "GDB commands to execute, in order"},
+    "breakpoints": {
+      "type": "array",
+      "description": "Breakpoints to set before the GDB commands run",
+      "items": {
+        "type": "object",
+        "properties": {
+          "location": {"type": "string", "description": "Function, file:line or *address"},
+          "condition": {"type": "string", "description": "Only stop when this expression is true"},
+          "ignoreCount": {"type": "integer", "description": "Pass the breakpoint this many times before stopping"},
+          "commands": {"type": "array", "items": {"type": "string"}, "description": "GDB commands to run at each stop, e.g. [\"silent\", \"print x\", \"continue\"] to collect x without stopping"},
+          "temporary": {"type": "boolean", "description": "Delete the breakpoint when it is first hit"}
+        },
+        "required": ["location"]
+      }
+    },
+    "waitForOutput": {"type": "boolean", "description": "Whether to see the command output before answering"}
+  },
+  "required": ["text", "gdbCommands", "waitForOutput"]
+}`)
+
+// ParsePlainResponse handles a free-text response from a model in plain envelope mode.
+// Nothing in it is executed: GDB commands are extracted only as suggestions for the user.
+func (rp *ResponseParser) ParsePlainResponse(response string, logger *logsession.SessionLogger) *ParsedResponse {
+	parsed := &ParsedResponse{
+		Text:              response,
+		GDBCommands:       []string{},
+		SuggestedCommands: extractSuggestedCommands(response),
+		RawResponse:       response,
+		ParseMethod:       "plain",
+	}
+	if refusal, ok := DetectRefusal(response); ok {
+		parsed.Text = refusalMessage(refusal)
+		parsed.SuggestedCommands = nil
+		parsed.Refused = true
+	}
+
+	if logger != nil {
+		logger.LogTerminalOutput(fmt.Sprintf("=== PLAIN RESPONSE ===\nText: %d chars, Suggested commands: %d",
+			len(parsed.Text), len(parsed.SuggestedCommands)))
+	}
+	return parsed
+}
+
+// extractSuggestedCommands collects the commands in ```gdb code blocks and the commands
+// typed at a "(gdb) " prompt, skipping comments and duplicates. A gdb block that shows a
+// session transcript contributes only its prompt lines, not the output between them.
+func extractSuggestedCommands(text string) []string {
+	var commands []string
+	seen := make(map[string]bool)
+	add := func(command string) {
+		command = strings.TrimSpace(command)
+		if command == "" || strings.HasPrefix(command, "#") || seen[command] {
+			return
+		}
+		seen[command] = true
+		commands = append(commands, command)
+	}
+
+	var block []string
+	inBlock, gdbBlock := false, false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		trimmed := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(trimmed, "```") {
+			if inBlock && gdbBlock {
+				for _, command := range blockCommands(block) {
+					add(command)
+				}
+			}
+			if inBlock {
+				inBlock, gdbBlock, block = false, false, nil
+			} else {
+				inBlock = true
+				gdbBlock = strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(trimmed, "```")), "gdb")
+			}
+			continue
+		}
+
+		switch {
+		case inBlock:
+			block = append(block, trimmed)
+		case strings.HasPrefix(trimmed, gdbPrompt):
+			add(strings.TrimPrefix(trimmed, gdbPrompt))
+		}
+	}
+	return commands
+}
+
+// gdbPrompt precedes commands in a pasted GDB session
+const gdbPrompt = "(gdb) "
+
+// blockCommands returns the commands of a gdb code block: its prompt lines if it is a
+// transcript, otherwise every line
+func blockCommands(lines []string) []string {
+	var prompted []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, gdbPrompt) {
+			prompted = append(prompted, strings.TrimPrefix(line, gdbPrompt))
+		}
+	}
+	if len(prompted) > 0 {
+		return prompted
+	}
+	return lines
+}
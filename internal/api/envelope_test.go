@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/llm"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+func TestParsePlainResponse(t *testing.T) {
+	response := "The crash is in parse(). Try:\n\n" +
+		"```gdb\nbreak parse\n# stop on entry\nrun\n```\n\n" +
+		"Earlier you ran:\n(gdb) bt\n#0  parse () at main.c:12\n\n" +
+		"```gdb\n(gdb) info locals\np = 0x0\n(gdb) run\n```\n\n" +
+		"```c\nint *p = NULL;\n```\n"
+
+	parsed := NewResponseParser().ParsePlainResponse(response, nil)
+	assert.Equal(t, response, parsed.Text)
+	assert.Empty(t, parsed.GDBCommands, "plain responses are never executed")
+	assert.Equal(t, []string{"break parse", "run", "bt", "info locals"}, parsed.SuggestedCommands)
+	assert.Equal(t, "plain", parsed.ParseMethod)
+}
+
+func TestWithEnvelope(t *testing.T) {
+	req := &ChatRequest{Message: "why does it crash?"}
+
+	assert.Equal(t, config.EnvelopeJSON, BuildPrompt(req, config.ContextConfig{}).Envelope)
+
+	prompt := BuildPrompt(req, config.ContextConfig{}).WithEnvelope(config.EnvelopePlain)
+	assert.Contains(t, prompt.System, "fenced code block marked gdb")
+	assert.Equal(t, config.EnvelopePlain, prompt.Composition().Envelope)
+
+	envelopes := config.EnvelopeConfig{
+		Default: config.EnvelopeJSON,
+		Models:  []config.ModelEnvelope{{Model: "llama3:8b", Mode: config.EnvelopePlain}},
+	}
+	assert.Equal(t, config.EnvelopePlain, envelopes.ModeFor("Llama3:8B"))
+	assert.Equal(t, config.EnvelopeJSON, envelopes.ModeFor("gpt-4.1"))
+	assert.NoError(t, envelopes.Validate())
+	assert.Error(t, config.EnvelopeConfig{Default: "xml"}.Validate())
+
+	// The envelope decides how providers are asked for the reply
+	jsonReq := BuildPrompt(req, config.ContextConfig{}).request("gpt-4o")
+	assert.Equal(t, &llm.ResponseFormat{Type: llm.FormatJSONObject}, jsonReq.ResponseFormat)
+	assert.Nil(t, jsonReq.Tool)
+	toolsReq := BuildPrompt(req, config.ContextConfig{}).WithEnvelope(config.EnvelopeTools).request("gpt-4o")
+	assert.Equal(t, respondToolName, toolsReq.Tool.Name)
+	assert.Nil(t, toolsReq.ResponseFormat)
+	schemaReq := BuildPrompt(req, config.ContextConfig{}).WithEnvelope(config.EnvelopeSchema).request("gpt-4o")
+	assert.Equal(t, llm.FormatJSONSchema, schemaReq.ResponseFormat.Type)
+	assert.JSONEq(t, string(respondToolSchema), string(schemaReq.ResponseFormat.Schema))
+	plainReq := prompt.request("llama3:8b")
+	assert.Nil(t, plainReq.Tool)
+	assert.Nil(t, plainReq.ResponseFormat)
+	assert.Equal(t, "user", plainReq.Messages[len(plainReq.Messages)-1].Role)
+}
+
+func TestParseResponseBreakpoints(t *testing.T) {
+	response := `{"text": "Logging len at each call.", "gdbCommands": ["run"], "waitForOutput": true,
+		"breakpoints": [
+			{"location": "parse.c:42", "condition": "len > 64", "ignoreCount": 1, "commands": ["silent", "print len", "continue"]},
+			{"location": ""}
+		]}`
+
+	parsed, err := NewResponseParser().ParseResponse(response, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"break parse.c:42 if len > 64\nignore $bpnum 1\ncommands\nsilent\nprint len\ncontinue\nend",
+		"run",
+	}, parsed.GDBCommands, "breakpoints are set first; invalid ones are skipped")
+	assert.True(t, json.Valid(respondToolSchema))
+}
+
+func TestParseResponseSchema(t *testing.T) {
+	parser := NewResponseParser()
+
+	// Braces inside strings do not confuse the extraction
+	parsed, err := parser.ParseResponse(`Here you go: {"text": "The } in main() is fine", "gdbCommands": ["bt"], "waitForOutput": true} Hope that helps.`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "extracted_json", parsed.ParseMethod)
+	assert.Equal(t, "The } in main() is fine", parsed.Text)
+	assert.Equal(t, []string{"bt"}, parsed.GDBCommands)
+
+	parsed, err = parser.ParseResponse("```json\n{\"text\": \"Step in.\", \"gdbCommands\": [\"step\"], \"waitForOutput\": false}\n```", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fenced_json", parsed.ParseMethod)
+
+	// Objects that do not match the schema are not the reply, however JSON they are
+	parsed, err = parser.ParseResponse(`The config is {"level": 3}.`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback_text", parsed.ParseMethod)
+	assert.Equal(t, `missing required property "text"`, parsed.ValidationError)
+
+	parsed, err = parser.ParseResponse(`{"text": "Run it.", "gdbCommands": "run", "waitForOutput": false}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback_text", parsed.ParseMethod)
+	assert.Equal(t, "/gdbCommands: expected array, got string", parsed.ValidationError)
+
+	parsed, err = parser.ParseResponse(`{"text": " ", "gdbCommands": [], "waitForOutput": false}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/text: must not be empty", parsed.ValidationError)
+}
+
+func TestParseResponseReformat(t *testing.T) {
+	var calls []*LLMCall
+	replies := []string{`{"text": "Run it.", "gdbCommands": ["run"], "waitForOutput": true}`}
+	processor := &ChatProcessor{
+		responseParser: NewResponseParser(),
+		prompts:        prompts.Builtin(),
+		envelopeCfg:    config.EnvelopeConfig{ReformatAttempts: 1},
+		pipeline: func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			calls = append(calls, call)
+			reply := replies[0]
+			replies = replies[1:]
+			return LLMResult{Response: reply, Attempts: 1}, nil
+		},
+	}
+	procCtx := &ProcessingContext{Envelope: config.EnvelopeJSON}
+	req := &ChatRequest{Message: "what next?"}
+
+	parsed, err := processor.parseResponse(context.Background(), procCtx, req, `{"text": "Run it.", "gdbCommands": "run"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "reformatted_full_json", parsed.ParseMethod)
+	assert.Equal(t, []string{"run"}, parsed.GDBCommands)
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].Prompt.Message, `Your response did not match the required schema: missing required property "waitForOutput"`)
+	assert.Equal(t, []ChatMessage{
+		{Role: "user", Content: "what next?"},
+		{Role: "assistant", Content: `{"text": "Run it.", "gdbCommands": "run"}`},
+	}, calls[0].Prompt.History)
+
+	// Without attempts left the first reply falls back to text
+	processor.envelopeCfg.ReformatAttempts = 0
+	parsed, err = processor.parseResponse(context.Background(), procCtx, req, "Just run it.")
+	require.NoError(t, err)
+	assert.Equal(t, "fallback_text", parsed.ParseMethod)
+	assert.Len(t, calls, 1)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/events"
+)
+
+// maxEventQuestion limits how much of the question a chat.completed event carries
+const maxEventQuestion = 200
+
+// SetEventBus makes the handler publish when the assistant answers and when a session
+// spends its budget
+func (sch *SimpleChatHandler) SetEventBus(bus *events.Bus) {
+	sch.events = bus
+	sch.processor.costs.events = bus
+}
+
+// publishChat publishes that the assistant answered a chat request
+func (sch *SimpleChatHandler) publishChat(ctx context.Context, req *ChatRequest, result *ProcessingResult) {
+	if sch.events == nil {
+		return
+	}
+	question := req.Message
+	if len(question) > maxEventQuestion {
+		question = strings.ToValidUTF8(question[:maxEventQuestion], "") + "..."
+	}
+	sch.events.Publish(events.Event{
+		Type:    events.ChatCompleted,
+		User:    userFromContext(ctx),
+		Session: eventSession(sch.processor.session(ctx)),
+		Message: "The assistant answered: " + question,
+		Data: map[string]interface{}{
+			"requestId":        req.RequestID,
+			"model":            result.Model,
+			"refused":          result.Refused,
+			"executedCommands": len(result.ExecutedCmds),
+			"inputTokens":      result.Usage.InputTokens,
+			"outputTokens":     result.Usage.OutputTokens,
+			"cost":             result.Cost,
+		},
+	})
+}
+
+// publishExceeded publishes that the session of user, keyed as ChatProcessor.session
+// keys it, spent its budget
+func (t *CostTracker) publishExceeded(user, session string) {
+	if t.events == nil {
+		return
+	}
+	t.mutex.Lock()
+	cost, budget := t.sessions[session].Cost, t.budget
+	t.mutex.Unlock()
+	t.events.Publish(events.Event{
+		Type:    events.BudgetExceeded,
+		User:    user,
+		Session: eventSession(session),
+		Message: fmt.Sprintf("The session spent $%.4f of its $%.2f LLM budget", cost, budget),
+		Data:    map[string]interface{}{"cost": cost, "budget": budget},
+	})
+}
+
+// eventSession returns the debugging session of a session key, or "" for the key of a
+// user without one
+func eventSession(key string) string {
+	if id, ok := strings.CutPrefix(key, "session:"); ok {
+		return id
+	}
+	return ""
+}
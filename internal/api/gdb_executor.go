@@ -7,7 +7,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/decompile"
+	"github.com/yourusername/gogdbllm/internal/gdb"
 	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/tracing"
 )
 
 // GDBExecutor handles execution of GDB commands
@@ -16,6 +20,51 @@ type GDBExecutor struct {
 	mutex      sync.Mutex
 }
 
+// ProgramRunner is implemented by GDB handlers that can let the program run until it stops
+// or a timeout passes, so a continue from the model can never hang the chat
+type ProgramRunner interface {
+	RunUntil(opts gdb.RunUntilOptions) (*gdb.StopReason, error)
+}
+
+// BinaryDescriber is implemented by GDB handlers that can summarize the executable being
+// debugged from the file itself
+type BinaryDescriber interface {
+	BinarySummary() (string, error)
+	BinaryMetadata() (string, error)
+}
+
+// ScriptReporter is implemented by GDB handlers that run GDB Python scripts in the session
+// and keep what they printed
+type ScriptReporter interface {
+	ScriptResults() (string, error)
+}
+
+// VariableReader is implemented by GDB handlers that can read the variables of a frame
+type VariableReader interface {
+	FrameVariables(frame int) (*gdb.Variables, error)
+}
+
+// FunctionDecompiler is implemented by GDB handlers that can decompile the functions of
+// the executable being debugged
+type FunctionDecompiler interface {
+	DecompileFunction(ctx context.Context, function string) (*decompile.Result, error)
+	MentionedFunction(message string) string
+}
+
+// CommandAuditor is implemented by GDB handlers that keep an audit trail of the commands
+// run in the session
+type CommandAuditor interface {
+	// AuditCommand records that actor ran command for user while serving the HTTP request
+	// requestID, and why it failed
+	AuditCommand(actor, user, requestID, command string, err error)
+}
+
+// CommandHooker is implemented by GDB handlers that run the session's hooks before each
+// command the assistant runs
+type CommandHooker interface {
+	BeforeLLMCommand(command string)
+}
+
 // GDBExecutionResult contains the results of GDB command execution
 type GDBExecutionResult struct {
 	Commands       []string
@@ -49,6 +98,9 @@ func (ge *GDBExecutor) ExecuteCommands(ctx context.Context, commands []string, l
 	ge.mutex.Lock()
 	defer ge.mutex.Unlock()
 
+	ctx, span := tracing.Start(ctx, "gdb.execute", tracing.Attr("gdb.command_count", len(commands)))
+	defer span.End()
+
 	startTime := time.Now()
 	result := &GDBExecutionResult{
 		Commands: commands,
@@ -74,8 +126,22 @@ func (ge *GDBExecutor) ExecuteCommands(ctx context.Context, commands []string, l
 		default:
 		}
 
+		if hooker, ok := ge.gdbHandler.(CommandHooker); ok {
+			hooker.BeforeLLMCommand(cmd)
+		}
+
 		// Execute command with timeout
+		_, cmdSpan := tracing.Start(ctx, "gdb.command", tracing.Attr("gdb.command", cmd))
 		output, err := ge.executeCommandWithTimeout(ctx, cmd, 30*time.Second)
+		cmdSpan.SetAttributes(tracing.Attr("gdb.output_length", len(output)))
+		cmdSpan.RecordError(err)
+		cmdSpan.End()
+		ge.audit(ctx, cmd, err, logger)
+		if ctx.Err() != nil {
+			span.RecordError(ctx.Err())
+			// Cancelled or timed out mid-command; the remaining commands are not run
+			return nil, ctx.Err()
+		}
 
 		result.Outputs[i] = output
 		result.Errors[i] = err
@@ -101,6 +167,7 @@ func (ge *GDBExecutor) ExecuteCommands(ctx context.Context, commands []string, l
 
 	result.CombinedOutput = combinedOutput.String()
 	result.ExecutionTime = time.Since(startTime)
+	span.SetAttributes(tracing.Attr("gdb.output_length", len(result.CombinedOutput)))
 
 	if logger != nil {
 		logger.LogTerminalOutput(fmt.Sprintf("=== GDB EXECUTION COMPLETED ===\nTotal time: %v\nCombined output: %d chars",
@@ -110,6 +177,19 @@ func (ge *GDBExecutor) ExecuteCommands(ctx context.Context, commands []string, l
 	return result, nil
 }
 
+// audit records a command run for the LLM in the GDB handler's audit trail, if it keeps one
+func (ge *GDBExecutor) audit(ctx context.Context, cmd string, err error, logger *logsession.SessionLogger) {
+	auditor, ok := ge.gdbHandler.(CommandAuditor)
+	if !ok {
+		return
+	}
+	requestID := ""
+	if logger != nil {
+		requestID = logger.RequestID()
+	}
+	auditor.AuditCommand(audit.ActorLLM, userFromContext(ctx), requestID, cmd, err)
+}
+
 // executeCommandWithTimeout executes a single command with timeout
 func (ge *GDBExecutor) executeCommandWithTimeout(ctx context.Context, cmd string, timeout time.Duration) (string, error) {
 	// Create a context with timeout
@@ -124,7 +204,7 @@ func (ge *GDBExecutor) executeCommandWithTimeout(ctx context.Context, cmd string
 
 	// Execute command in goroutine
 	go func() {
-		output, err := ge.gdbHandler.ExecuteCommandWithOutput(cmd)
+		output, err := ge.runCommand(cmd)
 		resultChan <- struct {
 			output string
 			err    error
@@ -140,6 +220,20 @@ func (ge *GDBExecutor) executeCommandWithTimeout(ctx context.Context, cmd string
 	}
 }
 
+// runCommand runs a command. Commands letting the program run are run until it stops or
+// the run-until timeout passes, and why it stopped is appended to their output.
+func (ge *GDBExecutor) runCommand(cmd string) (string, error) {
+	runner, ok := ge.gdbHandler.(ProgramRunner)
+	if !ok || !gdb.IsContinueCommand(cmd) {
+		return ge.gdbHandler.ExecuteCommandWithOutput(cmd)
+	}
+	stop, err := runner.RunUntil(gdb.RunUntilOptions{Command: cmd})
+	if err != nil {
+		return "", err
+	}
+	return stop.Output + "\n\n--- Stop Reason ---\n" + stop.String(), nil
+}
+
 // truncateForLog truncates output for logging purposes
 func (ge *GDBExecutor) truncateForLog(text string, maxLen int) string {
 	if len(text) <= maxLen {
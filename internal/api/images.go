@@ -0,0 +1,122 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// maxRequestImages is the most images one chat request may carry
+const maxRequestImages = 20
+
+// imageMediaTypes are the image formats both Anthropic and OpenAI accept
+var imageMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// visionModels are prefixes of the models that accept images, by provider
+var visionModels = map[string][]string{
+	"anthropic": {"claude-3", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4"},
+	"openai":    {"gpt-4o", "gpt-4-turbo", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"},
+}
+
+// nonVisionModels are prefixes of models matching visionModels that do not accept images
+var nonVisionModels = []string{"o1-mini", "o3-mini"}
+
+// Image is an image sent with a chat request, e.g. a screenshot of a GUI bug or of a
+// waveform, for models that accept images
+type Image struct {
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"mediaType"` // "image/png", "image/jpeg", "image/gif" or "image/webp"
+	Data      []byte `json:"data"`      // Base64 in JSON
+}
+
+// imageMediaType returns the media type of an image in a supported format, or "" if the
+// content is not one
+func imageMediaType(content []byte) string {
+	mediaType := http.DetectContentType(content)
+	if !imageMediaTypes[mediaType] {
+		return ""
+	}
+	return mediaType
+}
+
+// SupportsImages reports whether a provider's model accepts images
+func SupportsImages(provider, model string) bool {
+	model = strings.ToLower(model)
+	for _, prefix := range nonVisionModels {
+		if strings.HasPrefix(model, prefix) {
+			return false
+		}
+	}
+	for _, prefix := range visionModels[provider] {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateImages checks the images a request carries: their number, size and format, and
+// that the model it is sent to accepts images. maxSize is the most bytes an image may
+// have; 0 does not limit it.
+func (r *ChatRequest) ValidateImages(provider, model string, maxSize int64) error {
+	if len(r.Images) == 0 {
+		return nil
+	}
+	if len(r.Images) > maxRequestImages {
+		return fmt.Errorf("%w: a request may carry at most %d images", appErrors.ErrBadRequest, maxRequestImages)
+	}
+	for i, img := range r.Images {
+		if len(img.Data) == 0 {
+			return fmt.Errorf("%w: image %d is empty", appErrors.ErrBadRequest, i+1)
+		}
+		if maxSize > 0 && int64(len(img.Data)) > maxSize {
+			return fmt.Errorf("%w: images are limited to %d bytes", appErrors.ErrBadRequest, maxSize)
+		}
+		detected := imageMediaType(img.Data)
+		if detected == "" {
+			return fmt.Errorf("%w: image %d is not a PNG, JPEG, GIF or WebP image", appErrors.ErrBadRequest, i+1)
+		}
+		// The detected format wins over a mislabelled one, which providers reject
+		r.Images[i].MediaType = detected
+	}
+	if !SupportsImages(provider, model) {
+		return fmt.Errorf("%w: %s model %s does not accept images", appErrors.ErrBadRequest, provider, model)
+	}
+	return nil
+}
+
+// ImageTokens approximates the tokens an image costs
+func ImageTokens(img Image) int {
+	return llm.ImageTokens(img.Data)
+}
+
+// imageDescription names an image in prompt compositions and logs
+func imageDescription(img Image) string {
+	name := img.Name
+	if name == "" {
+		name = "image"
+	}
+	return fmt.Sprintf("%s (%s, %d bytes)", name, img.MediaType, len(img.Data))
+}
+
+// userMessage returns the final user turn: its text alone, or the prompt's images
+// followed by the text, the order Anthropic recommends
+func (p *Prompt) userMessage() llm.Message {
+	message := llm.Message{Role: "user", Content: p.UserMessage()}
+	if len(p.Images) == 0 {
+		return message
+	}
+	for _, img := range p.Images {
+		message.Parts = append(message.Parts, llm.ContentPart{Type: llm.ContentImage, MediaType: img.MediaType, Data: img.Data})
+	}
+	message.Parts = append(message.Parts, llm.ContentPart{Type: llm.ContentText, Text: p.UserMessage()})
+	return message
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// testPNG encodes a blank PNG image of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestSupportsImages(t *testing.T) {
+	assert.True(t, SupportsImages("anthropic", "claude-3-5-sonnet-20240620"))
+	assert.True(t, SupportsImages("anthropic", "claude-sonnet-4-20250514"))
+	assert.False(t, SupportsImages("anthropic", "claude-2.1"))
+	assert.True(t, SupportsImages("openai", "gpt-4o-mini"))
+	assert.True(t, SupportsImages("openai", "o4-mini"))
+	assert.False(t, SupportsImages("openai", "o3-mini"))
+	assert.False(t, SupportsImages("openai", "gpt-3.5-turbo"))
+	assert.False(t, SupportsImages("openrouter", "gpt-4o"))
+}
+
+func TestValidateImages(t *testing.T) {
+	screenshot := testPNG(t, 4, 4)
+
+	req := &ChatRequest{Images: []Image{{MediaType: "image/jpeg", Data: screenshot}}}
+	require.NoError(t, req.ValidateImages("anthropic", "claude-3-haiku-20240307", 0))
+	assert.Equal(t, "image/png", req.Images[0].MediaType, "the detected format replaces a wrong label")
+
+	assert.ErrorIs(t, req.ValidateImages("openai", "gpt-3.5-turbo", 0), appErrors.ErrBadRequest)
+	assert.ErrorIs(t, req.ValidateImages("openai", "gpt-4o", 16), appErrors.ErrBadRequest)
+
+	req = &ChatRequest{Images: []Image{{MediaType: "image/png", Data: []byte("not an image")}}}
+	assert.ErrorIs(t, req.ValidateImages("openai", "gpt-4o", 0), appErrors.ErrBadRequest)
+
+	assert.NoError(t, (&ChatRequest{}).ValidateImages("openai", "gpt-3.5-turbo", 0), "requests without images go to any model")
+}
+
+func TestImageTokens(t *testing.T) {
+	assert.Equal(t, 1, ImageTokens(Image{Data: testPNG(t, 10, 10)}))
+	assert.Equal(t, 342, ImageTokens(Image{Data: testPNG(t, 640, 400)}))
+	assert.Equal(t, llm.MaxImageTokens, ImageTokens(Image{Data: testPNG(t, 4000, 3000)}))
+	assert.Equal(t, llm.MaxImageTokens, ImageTokens(Image{Data: []byte("RIFF....WEBP")}), "sizes that cannot be read count as the largest")
+}
+
+func TestPromptImageContent(t *testing.T) {
+	screenshot := testPNG(t, 4, 4)
+	prompt := BuildPrompt(&ChatRequest{Message: "What is wrong with this window?"}, config.ContextConfig{})
+	assert.Equal(t, llm.Message{Role: "user", Content: prompt.UserMessage()}, prompt.userMessage())
+
+	prompt.Images = []Image{{Name: "gui.png", MediaType: "image/png", Data: screenshot}}
+	assert.Equal(t, []llm.ContentPart{
+		{Type: llm.ContentImage, MediaType: "image/png", Data: screenshot},
+		{Type: llm.ContentText, Text: prompt.UserMessage()},
+	}, prompt.userMessage().Parts)
+
+	composition := prompt.Composition()
+	var images *PromptSegment
+	for i := range composition.Segments {
+		if composition.Segments[i].Name == "images" {
+			images = &composition.Segments[i]
+		}
+	}
+	require.NotNil(t, images)
+	require.Len(t, images.Items, 1)
+	assert.Equal(t, fmt.Sprintf("gui.png (image/png, %d bytes)", len(screenshot)), images.Items[0].Description)
+	assert.Equal(t, 1, images.Tokens)
+}
@@ -1,243 +1,127 @@
 package api
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"time"
 
+	"github.com/yourusername/gogdbllm/internal/chat/providers"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/llm"
 	"github.com/yourusername/gogdbllm/internal/logsession"
 	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/tracing"
 )
 
-// LLMClient handles communication with LLM providers
+// llmTimeout limits how long a provider may take to answer a prompt
+const llmTimeout = 60 * time.Second
+
+// LLMClient sends prompts to the LLM providers, through the providers package
 type LLMClient struct {
 	settingsManager *settings.Manager
-	httpClient      *http.Client
 }
 
 // NewLLMClient creates a new LLM client
 func NewLLMClient(settingsManager *settings.Manager) *LLMClient {
-	return &LLMClient{
-		settingsManager: settingsManager,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}
+	return &LLMClient{settingsManager: settingsManager}
 }
 
-// SendRequest sends a request to the configured LLM provider
+// SendRequest sends a chat request, without history trimming, to the configured LLM provider
 func (lc *LLMClient) SendRequest(ctx context.Context, req *ChatRequest, settings settings.Settings, logger *logsession.SessionLogger) (string, error) {
-	if logger != nil {
-		logger.LogTerminalOutput(fmt.Sprintf("=== LLM REQUEST ===\nProvider: %s\nModel: %s\nMessage length: %d\nContext items: %d",
-			settings.Provider, settings.Model, len(req.Message), len(req.SentContext)))
-	}
-
-	var response string
-	var err error
-
-	switch settings.Provider {
-	case "anthropic":
-		response, err = lc.sendAnthropicRequest(ctx, req, settings, logger)
-	case "openai":
-		response, err = lc.sendOpenAIRequest(ctx, req, settings, logger)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", settings.Provider)
-	}
-
-	if err != nil {
-		if logger != nil {
-			logger.LogTerminalOutput(fmt.Sprintf("=== LLM REQUEST FAILED ===\nError: %v", err))
-		}
-		return "", err
-	}
-
-	if logger != nil {
-		logger.LogTerminalOutput(fmt.Sprintf("=== LLM RESPONSE RECEIVED ===\nLength: %d chars", len(response)))
-	}
-
-	return response, nil
+	response, _, err := lc.SendPrompt(ctx, BuildPrompt(req, config.ContextConfig{}), settings, logger)
+	return response, err
 }
 
-// sendAnthropicRequest sends a request to Anthropic API
-func (lc *LLMClient) sendAnthropicRequest(ctx context.Context, req *ChatRequest, settings settings.Settings, logger *logsession.SessionLogger) (string, error) {
-	systemMessage := `You are an AI assistant that helps with programming and debugging.
-
-YOU MUST RESPOND IN VALID JSON FORMAT according to this structure:
-{
-  "text": "Your explanation or message to the user",
-  "gdbCommands": ["command1", "command2", "..."],
-  "waitForOutput": true/false
+// SendPrompt sends a prompt built by BuildPrompt to the configured LLM provider and
+// returns the response with the tokens the provider reports having used
+func (lc *LLMClient) SendPrompt(ctx context.Context, prompt *Prompt, settings settings.Settings, logger *logsession.SessionLogger) (string, TokenUsage, error) {
+	return lc.StreamPrompt(ctx, prompt, settings, logger, nil)
 }
 
-Do not include any text outside the JSON structure. Your entire response must be a single JSON object.`
-
-	// Build user message with context
-	userMessage := req.Message
-	if len(req.SentContext) > 0 {
-		contextPrefix := "\n\n--- Provided Context ---\n"
-		for _, item := range req.SentContext {
-			contextPrefix += fmt.Sprintf("Type: %s\nDescription: %s\n", item.Type, item.Description)
-			if item.Content != "" {
-				contextPrefix += fmt.Sprintf("Content:\n```\n%s\n```\n", item.Content)
-			}
-			contextPrefix += "---\n"
-		}
-		userMessage = contextPrefix + userMessage
-	}
-
-	// Build messages array
-	messages := []AnthropicMessage{}
-	for _, msg := range req.History {
-		messages = append(messages, AnthropicMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-	messages = append(messages, AnthropicMessage{
-		Role:    "user",
-		Content: userMessage,
-	})
-
-	// Create request
-	apiReq := AnthropicRequest{
-		Model:     settings.Model,
-		Messages:  messages,
-		MaxTokens: 4096,
-		System:    systemMessage,
-	}
-
-	reqBody, err := json.Marshal(apiReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal Anthropic request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create Anthropic HTTP request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", settings.APIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := lc.httpClient.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("Anthropic API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read Anthropic response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Anthropic API error (status %d): %s", resp.StatusCode, respBody)
-	}
-
-	var apiResp AnthropicResponse
-	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse Anthropic response: %w", err)
-	}
-
-	if len(apiResp.Content) > 0 {
-		return apiResp.Content[0].Text, nil
+// StreamPrompt sends a prompt like SendPrompt, streaming the response into stream unless
+// it is nil. Sent again with the same stream after the response broke off, the prompt
+// resumes it.
+func (lc *LLMClient) StreamPrompt(ctx context.Context, prompt *Prompt, settings settings.Settings, logger *logsession.SessionLogger, stream *providers.Stream) (string, TokenUsage, error) {
+	if logger != nil {
+		logger.LogTerminalOutput(fmt.Sprintf("=== LLM REQUEST ===\nProvider: %s\nModel: %s\nEnvelope: %s\nMessage length: %d\nContext items: %d\nImages: %d\nHistory messages: %d (%d trimmed)",
+			settings.Provider, settings.Model, prompt.Envelope, len(prompt.Message), len(prompt.Context), len(prompt.Images), len(prompt.History), prompt.TrimmedMessages))
 	}
 
-	return "", fmt.Errorf("no content in Anthropic response")
-}
-
-// sendOpenAIRequest sends a request to OpenAI API
-func (lc *LLMClient) sendOpenAIRequest(ctx context.Context, req *ChatRequest, settings settings.Settings, logger *logsession.SessionLogger) (string, error) {
-	systemMessage := `You are an AI assistant that helps with programming and debugging.
-
-YOU MUST RESPOND IN VALID JSON FORMAT according to this structure:
-{
-  "text": "Your explanation or message to the user",
-  "gdbCommands": ["command1", "command2", "..."],
-  "waitForOutput": true/false
-}
-
-Do not include any text outside the JSON structure. Your entire response must be a single JSON object.`
-
-	// Build user message with context
-	userMessage := req.Message
-	if len(req.SentContext) > 0 {
-		contextPrefix := "\n\n--- Provided Context ---\n"
-		for _, item := range req.SentContext {
-			contextPrefix += fmt.Sprintf("Type: %s\nDescription: %s\n", item.Type, item.Description)
-			if item.Content != "" {
-				contextPrefix += fmt.Sprintf("Content:\n```\n%s\n```\n", item.Content)
-			}
-			contextPrefix += "---\n"
-		}
-		userMessage = contextPrefix + userMessage
-	}
+	ctx, span := tracing.StartKind(ctx, "llm.call", tracing.KindClient,
+		tracing.Attr("gen_ai.system", settings.Provider),
+		tracing.Attr("gen_ai.request.model", settings.Model),
+		tracing.Attr("llm.envelope", prompt.Envelope),
+		tracing.Attr("llm.history_messages", len(prompt.History)))
+	defer span.End()
 
-	// Build messages array
-	messages := []OpenAIMessage{
-		{Role: "system", Content: systemMessage},
-	}
-	for _, msg := range req.History {
-		messages = append(messages, OpenAIMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-	messages = append(messages, OpenAIMessage{
-		Role:    "user",
-		Content: userMessage,
+	provider, err := providers.New(settings.Provider, &providers.ProviderConfig{
+		Name:    settings.Provider,
+		APIKey:  settings.APIKey,
+		Timeout: llmTimeout,
 	})
-
-	// Create request
-	apiReq := OpenAIRequest{
-		Model:    settings.Model,
-		Messages: messages,
-		ResponseFormat: &ResponseFormat{
-			Type: "json_object",
-		},
-	}
-
-	reqBody, err := json.Marshal(apiReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal OpenAI request: %w", err)
+		return "", TokenUsage{}, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create OpenAI HTTP request: %w", err)
+	var resp *llm.Response
+	if streamer, ok := provider.(providers.StreamingProvider); ok && stream != nil {
+		resp, err = stream.Send(ctx, streamer, prompt.request(settings.Model))
+		span.SetAttributes(tracing.Attr("llm.stream_resumes", stream.Resumes()))
+	} else {
+		resp, err = provider.SendRequest(ctx, prompt.request(settings.Model))
 	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+settings.APIKey)
-
-	resp, err := lc.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API request failed: %w", err)
+		if logger != nil {
+			logger.LogTerminalOutput(fmt.Sprintf("=== LLM REQUEST FAILED ===\nError: %v", err))
+		}
+		span.RecordError(err)
+		return "", TokenUsage{}, err
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read OpenAI response: %w", err)
+	response := resp.Content
+	var usage TokenUsage
+	if resp.Metadata != nil {
+		usage = TokenUsage{InputTokens: resp.Metadata.PromptTokens, OutputTokens: resp.Metadata.ResponseTokens}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, respBody)
+	span.SetAttributes(
+		tracing.Attr("gen_ai.usage.input_tokens", usage.InputTokens),
+		tracing.Attr("gen_ai.usage.output_tokens", usage.OutputTokens),
+		tracing.Attr("llm.response_length", len(response)))
+	if logger != nil {
+		logger.LogTerminalOutput(fmt.Sprintf("=== LLM RESPONSE RECEIVED ===\nLength: %d chars\nTokens: %d in, %d out", len(response), usage.InputTokens, usage.OutputTokens))
 	}
 
-	var apiResp OpenAIResponse
-	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse OpenAI response: %w", err)
-	}
+	return response, usage, nil
+}
 
-	if len(apiResp.Choices) > 0 {
-		return apiResp.Choices[0].Message.Content, nil
+// request converts the prompt to a provider request for a model. The envelope mode
+// decides how the reply is asked for: through the respond tool, as a JSON object, held to
+// the respond tool's schema in schema mode, or as free text.
+func (p *Prompt) request(model string) *llm.Request {
+	req := &llm.Request{
+		Model:        model,
+		SystemPrompt: p.System,
+		Temperature:  p.Temperature,
+	}
+	for _, msg := range p.History {
+		req.Messages = append(req.Messages, llm.Message{Role: msg.Role, Content: msg.Content})
+	}
+	req.Messages = append(req.Messages, p.userMessage())
+	if p.MaxTokens > 0 {
+		maxTokens := p.MaxTokens
+		req.MaxTokens = &maxTokens
+	}
+
+	switch p.Envelope {
+	case config.EnvelopeTools:
+		req.Tool = &llm.Tool{Name: respondToolName, Description: respondToolDescription, Schema: respondToolSchema}
+	case config.EnvelopeSchema:
+		req.ResponseFormat = &llm.ResponseFormat{Type: llm.FormatJSONSchema, Name: respondToolName, Schema: respondToolSchema}
+	case config.EnvelopePlain:
+		// Free text: no response format is requested
+	default:
+		req.ResponseFormat = &llm.ResponseFormat{Type: llm.FormatJSONObject}
 	}
-
-	return "", fmt.Errorf("no content in OpenAI response")
+	return req
 }
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/metricstore"
+)
+
+// MetricsCollector collects performance metrics
+type MetricsCollector struct {
+	providerMetrics map[string]*ProviderMetrics
+	sampled         map[string]ProviderMetrics // The counts when Sample was last called
+	mutex           sync.RWMutex
+}
+
+// ProviderMetrics counts a provider's chat LLM calls and their outcomes
+type ProviderMetrics struct {
+	RequestCount    int64         `json:"request_count"`
+	ErrorCount      int64         `json:"error_count"`
+	CacheHits       int64         `json:"cache_hits"`
+	CacheMisses     int64         `json:"cache_misses"`
+	RetryAttempts   int64         `json:"retry_attempts"`
+	RefusalCount    int64         `json:"refusal_count"`
+	AvgResponseTime time.Duration `json:"avg_response_time"`
+	TotalCost       float64       `json:"total_cost"`
+
+	// Successful responses and their summed latency, for the metrics history
+	ResponseCount     int64         `json:"-"`
+	TotalResponseTime time.Duration `json:"-"`
+}
+
+// NewMetricsCollector creates a new metrics collector
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		providerMetrics: make(map[string]*ProviderMetrics),
+		sampled:         make(map[string]ProviderMetrics),
+	}
+}
+
+func (mc *MetricsCollector) RecordRequest(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].RequestCount++
+}
+
+func (mc *MetricsCollector) RecordError(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].ErrorCount++
+}
+
+func (mc *MetricsCollector) RecordCacheHit(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].CacheHits++
+}
+
+func (mc *MetricsCollector) RecordCacheMiss(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].CacheMisses++
+}
+
+func (mc *MetricsCollector) RecordRetry(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].RetryAttempts++
+}
+
+func (mc *MetricsCollector) RecordRefusal(provider string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].RefusalCount++
+}
+
+func (mc *MetricsCollector) RecordCost(provider string, cost float64) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+	mc.providerMetrics[provider].TotalCost += cost
+}
+
+func (mc *MetricsCollector) RecordResponse(provider string, responseTime time.Duration) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, exists := mc.providerMetrics[provider]; !exists {
+		mc.providerMetrics[provider] = &ProviderMetrics{}
+	}
+
+	metrics := mc.providerMetrics[provider]
+	metrics.ResponseCount++
+	metrics.TotalResponseTime += responseTime
+	// Simple running average
+	if metrics.RequestCount > 0 {
+		metrics.AvgResponseTime = time.Duration(
+			(int64(metrics.AvgResponseTime) + int64(responseTime)) / 2,
+		)
+	} else {
+		metrics.AvgResponseTime = responseTime
+	}
+}
+
+func (mc *MetricsCollector) GetAllMetrics() map[string]*ProviderMetrics {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	result := make(map[string]*ProviderMetrics)
+	for k, v := range mc.providerMetrics {
+		// Create a copy to avoid data races
+		copy := *v
+		result[k] = &copy
+	}
+	return result
+}
+
+// Sample returns what each provider did since the last call, as samples ending at now,
+// leaving out providers that did nothing. With peek, the next call still includes it.
+func (mc *MetricsCollector) Sample(now time.Time, peek bool) []metricstore.Sample {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	var samples []metricstore.Sample
+	for provider, current := range mc.providerMetrics {
+		last := mc.sampled[provider]
+		sample := metricstore.Sample{
+			Time:         now,
+			Provider:     provider,
+			Requests:     current.RequestCount - last.RequestCount,
+			Errors:       current.ErrorCount - last.ErrorCount,
+			CacheHits:    current.CacheHits - last.CacheHits,
+			CacheMisses:  current.CacheMisses - last.CacheMisses,
+			Retries:      current.RetryAttempts - last.RetryAttempts,
+			Refusals:     current.RefusalCount - last.RefusalCount,
+			Responses:    current.ResponseCount - last.ResponseCount,
+			ResponseTime: float64(current.TotalResponseTime-last.TotalResponseTime) / float64(time.Millisecond),
+			Cost:         current.TotalCost - last.TotalCost,
+		}
+		if sample.Empty() {
+			continue
+		}
+		samples = append(samples, sample)
+		if !peek {
+			mc.sampled[provider] = *current
+		}
+	}
+	return samples
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/metricstore"
+)
+
+// defaultHistoryRange is the time range a history query covers without from
+const defaultHistoryRange = 24 * time.Hour
+
+// metricsHistory writes what each provider did to a store every interval, so the
+// metrics outlive the server
+type metricsHistory struct {
+	store    *metricstore.Store
+	metrics  *MetricsCollector
+	interval time.Duration
+}
+
+// newMetricsHistory creates the history chat.metrics.history configures, or returns nil
+// when it is disabled
+func newMetricsHistory(cfg config.MetricsHistoryConfig, metrics *MetricsCollector) *metricsHistory {
+	if !cfg.Enabled {
+		return nil
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &metricsHistory{store: metricstore.New(cfg.Directory, cfg.Retention), metrics: metrics, interval: interval}
+}
+
+// flush writes what the providers did since the last flush and drops samples past the
+// retention
+func (h *metricsHistory) flush(now time.Time) error {
+	if samples := h.metrics.Sample(now, false); len(samples) > 0 {
+		if err := h.store.Append(samples); err != nil {
+			return err
+		}
+	}
+	return h.store.Prune(now)
+}
+
+// StartMetricsHistory writes the chat metrics every chat.metrics.history.interval until
+// ctx is done (no-op while the history is disabled)
+func (sch *SimpleChatHandler) StartMetricsHistory(ctx context.Context) {
+	if sch.history == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(sch.history.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := sch.history.flush(now); err != nil {
+					log.Printf("Writing the chat metrics history failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// FlushMetricsHistory writes what the providers did since the last write, e.g. before the
+// server stops
+func (sch *SimpleChatHandler) FlushMetricsHistory() error {
+	if sch.history == nil {
+		return nil
+	}
+	return sch.history.flush(time.Now())
+}
+
+// HandleMetricsHistory returns the chat metrics of a time range added up per provider
+// into buckets, e.g. GET /api/chat/metrics/history?from=2024-05-01T00:00:00Z&step=1h.
+// from and to are RFC 3339 times, by default the last 24 hours, step a duration, by
+// default an hour, and provider limits the series to one provider.
+func (sch *SimpleChatHandler) HandleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	if sch.history == nil {
+		http.Error(w, "the metrics history is disabled (chat.metrics.history.enabled)", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now()
+	to, err := queryTime(query.Get("to"), now)
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	from, err := queryTime(query.Get("from"), to.Add(-defaultHistoryRange))
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	step := time.Hour
+	if value := query.Get("step"); value != "" {
+		if step, err = time.ParseDuration(value); err != nil {
+			http.Error(w, "invalid step: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// What was not written yet is included, so the last bucket is current
+	series, err := sch.history.store.Query(from, to, step, query.Get("provider"), sch.history.metrics.Sample(now, true))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"step":   step.String(),
+		"series": series,
+	})
+}
+
+// queryTime parses an RFC 3339 query parameter, returning fallback when it is empty
+func queryTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q, expected RFC 3339", appErrors.ErrBadRequest, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestMetricsHistoryFlush(t *testing.T) {
+	metrics := NewMetricsCollector()
+	assert.Nil(t, newMetricsHistory(config.MetricsHistoryConfig{}, metrics))
+	history := newMetricsHistory(config.MetricsHistoryConfig{Enabled: true, Directory: t.TempDir()}, metrics)
+	require.NotNil(t, history)
+	now := time.Now()
+
+	metrics.RecordRequest("openai")
+	metrics.RecordResponse("openai", 200*time.Millisecond)
+	metrics.RecordRequest("ollama")
+	metrics.RecordError("ollama")
+
+	peeked := metrics.Sample(now, true)
+	assert.Len(t, peeked, 2)
+	assert.Len(t, metrics.Sample(now, true), 2, "peeking leaves the samples to flush")
+	require.NoError(t, history.flush(now))
+	assert.Empty(t, metrics.Sample(now, true), "a flush consumes the samples")
+
+	metrics.RecordRequest("openai")
+	metrics.RecordResponse("openai", 400*time.Millisecond)
+	series, err := history.store.Query(now.Add(-time.Hour), now.Add(time.Hour), 2*time.Hour, "", metrics.Sample(now, true))
+	require.NoError(t, err)
+	require.Len(t, series["openai"], 1)
+	assert.EqualValues(t, 2, series["openai"][0].Requests)
+	assert.InDelta(t, 300, series["openai"][0].AvgResponseTime, 1e-6)
+	require.Len(t, series["ollama"], 1)
+	assert.InDelta(t, 1, series["ollama"][0].ErrorRate, 1e-9)
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// catalogTimeout bounds the requests for one provider's model list
+const catalogTimeout = 10 * time.Second
+
+// maxCatalogPages caps how many pages of a paginated model list are fetched
+const maxCatalogPages = 10
+
+// modelEndpoints are the providers' model list endpoints
+var modelEndpoints = map[string]string{
+	"anthropic":  "https://api.anthropic.com/v1/models",
+	"openai":     "https://api.openai.com/v1/models",
+	"openrouter": "https://openrouter.ai/api/v1/models",
+}
+
+// openAINonChatModels are substrings of OpenAI model IDs that cannot be used for chat
+var openAINonChatModels = []string{"embedding", "whisper", "tts", "dall-e", "moderation", "davinci", "babbage", "transcribe", "image", "realtime", "audio", "search"}
+
+// CatalogModel is a model offered by a provider
+type CatalogModel struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	Created       time.Time `json:"created,omitempty"`
+	ContextLength int       `json:"contextLength,omitempty"`
+}
+
+// ModelList is a provider's models as last fetched
+type ModelList struct {
+	Provider  string         `json:"provider"`
+	Models    []CatalogModel `json:"models"`
+	FetchedAt time.Time      `json:"fetchedAt"`
+	Cached    bool           `json:"cached"`
+	Stale     bool           `json:"stale,omitempty"` // The provider could not be reached, so these are the models fetched before
+	Error     string         `json:"error,omitempty"` // Why the list could not be refreshed
+}
+
+// ModelCatalog lists the models the LLM providers currently offer, from their models
+// endpoints. Lists are cached per provider and API key for llm.models_cache_ttl, and a
+// cached list is served, marked stale, when the provider cannot be reached.
+type ModelCatalog struct {
+	client    *http.Client
+	ttl       time.Duration
+	endpoints map[string]string
+	lists     map[string]*ModelList
+	mutex     sync.Mutex
+}
+
+// NewModelCatalog creates a model catalog
+func NewModelCatalog(cfg *config.Config) *ModelCatalog {
+	return &ModelCatalog{
+		client:    &http.Client{Timeout: catalogTimeout},
+		ttl:       cfg.LLM.ModelsCacheTTL,
+		endpoints: modelEndpoints,
+		lists:     make(map[string]*ModelList),
+	}
+}
+
+// Models returns a provider's models, fetching them when the cached list has expired or
+// refresh is set
+func (mc *ModelCatalog) Models(ctx context.Context, provider, apiKey string, refresh bool) (*ModelList, error) {
+	endpoint, ok := mc.endpoints[provider]
+	if !ok {
+		return nil, fmt.Errorf("provider %q: %w", provider, appErrors.ErrNotFound)
+	}
+	if apiKey == "" && provider != "openrouter" {
+		return nil, fmt.Errorf("%w: no API key is configured for %s", appErrors.ErrBadRequest, provider)
+	}
+
+	key := catalogKey(provider, apiKey)
+	mc.mutex.Lock()
+	cached := mc.lists[key]
+	mc.mutex.Unlock()
+	if cached != nil && !refresh && time.Since(cached.FetchedAt) < mc.ttl {
+		list := *cached
+		list.Cached = true
+		return &list, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, catalogTimeout)
+	defer cancel()
+	models, err := mc.fetch(ctx, provider, endpoint, apiKey)
+	if err != nil {
+		err = fmt.Errorf("%w: listing %s models: %v", appErrors.ErrLLMAPICall, provider, err)
+		if cached == nil {
+			return nil, err
+		}
+		list := *cached
+		list.Cached, list.Stale, list.Error = true, true, err.Error()
+		return &list, nil
+	}
+
+	list := &ModelList{Provider: provider, Models: models, FetchedAt: time.Now()}
+	mc.mutex.Lock()
+	mc.lists[key] = list
+	mc.mutex.Unlock()
+	result := *list
+	return &result, nil
+}
+
+// catalogKey identifies a cached list. The key is hashed: keys may see different models,
+// but the cache need not hold them.
+func catalogKey(provider, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return provider + ":" + hex.EncodeToString(sum[:8])
+}
+
+// fetch requests a provider's model list, following pagination, and returns the models
+// newest first
+func (mc *ModelCatalog) fetch(ctx context.Context, provider, endpoint, apiKey string) ([]CatalogModel, error) {
+	var models []CatalogModel
+	afterID := ""
+	for page := 0; page < maxCatalogPages; page++ {
+		pageURL := endpoint
+		if provider == "anthropic" {
+			query := url.Values{"limit": {"1000"}}
+			if afterID != "" {
+				query.Set("after_id", afterID)
+			}
+			pageURL += "?" + query.Encode()
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		authorize(req, provider, apiKey)
+
+		var body modelListResponse
+		if err := mc.getJSON(req, &body); err != nil {
+			return nil, err
+		}
+		for _, m := range body.Data {
+			if provider == "openai" && !openAIChatModel(m.ID) {
+				continue
+			}
+			models = append(models, m.catalogModel())
+		}
+		if !body.HasMore || body.LastID == "" {
+			break
+		}
+		afterID = body.LastID
+	}
+
+	sort.SliceStable(models, func(i, j int) bool {
+		if !models[i].Created.Equal(models[j].Created) {
+			return models[i].Created.After(models[j].Created)
+		}
+		return models[i].ID < models[j].ID
+	})
+	return models, nil
+}
+
+// Ping checks that a provider answers and accepts an API key by requesting the first
+// page of its model list, which costs no tokens
+func (mc *ModelCatalog) Ping(ctx context.Context, provider, apiKey string) error {
+	endpoint, ok := mc.endpoints[provider]
+	if !ok {
+		return fmt.Errorf("provider %q: %w", provider, appErrors.ErrNotFound)
+	}
+	if provider == "anthropic" {
+		endpoint += "?limit=1"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	authorize(req, provider, apiKey)
+	var body modelListResponse
+	if err := mc.getJSON(req, &body); err != nil {
+		return fmt.Errorf("%w: %s: %v", appErrors.ErrLLMAPICall, provider, err)
+	}
+	return nil
+}
+
+// authorize sets a provider's authentication headers on a request
+func authorize(req *http.Request, provider, apiKey string) {
+	switch provider {
+	case "anthropic":
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	default:
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+}
+
+// getJSON sends a request and decodes its JSON response
+func (mc *ModelCatalog) getJSON(req *http.Request, v interface{}) error {
+	resp, err := mc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid model list: %w", err)
+	}
+	return nil
+}
+
+// modelListResponse is the shape shared by the providers' model lists: Anthropic's with
+// display names and pagination, OpenAI's with Unix creation times and OpenRouter's with
+// names and context lengths
+type modelListResponse struct {
+	Data    []providerModel `json:"data"`
+	HasMore bool            `json:"has_more"`
+	LastID  string          `json:"last_id"`
+}
+
+// providerModel is a model in a provider's list
+type providerModel struct {
+	ID            string          `json:"id"`
+	DisplayName   string          `json:"display_name"`
+	Name          string          `json:"name"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Created       json.RawMessage `json:"created"`
+	ContextLength int             `json:"context_length"`
+}
+
+// catalogModel converts a provider's model description
+func (m providerModel) catalogModel() CatalogModel {
+	model := CatalogModel{ID: m.ID, Name: m.DisplayName, Created: m.CreatedAt, ContextLength: m.ContextLength}
+	if model.Name == "" {
+		model.Name = m.Name
+	}
+	var unix float64
+	if model.Created.IsZero() && json.Unmarshal(m.Created, &unix) == nil && unix > 0 {
+		model.Created = time.Unix(int64(unix), 0).UTC()
+	}
+	return model
+}
+
+// openAIChatModel reports whether an OpenAI model can be used for chat
+func openAIChatModel(id string) bool {
+	for _, s := range openAINonChatModels {
+		if strings.Contains(id, s) {
+			return false
+		}
+	}
+	return true
+}
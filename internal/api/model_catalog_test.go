@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func newTestCatalog(endpoints map[string]string) *ModelCatalog {
+	return &ModelCatalog{client: http.DefaultClient, ttl: time.Hour, endpoints: endpoints, lists: make(map[string]*ModelList)}
+}
+
+func TestModelCatalogCachesAndServesStale(t *testing.T) {
+	requests, fail := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"data": [
+			{"id": "gpt-4o", "created": 1715367049},
+			{"id": "text-embedding-3-small", "created": 1705948997},
+			{"id": "o3-mini", "created": 1737146383}
+		]}`))
+	}))
+	defer server.Close()
+	catalog := newTestCatalog(map[string]string{"openai": server.URL})
+
+	list, err := catalog.Models(context.Background(), "openai", "sk-test", false)
+	require.NoError(t, err)
+	require.Len(t, list.Models, 2, "non-chat models are left out")
+	assert.Equal(t, "o3-mini", list.Models[0].ID, "newest first")
+	assert.False(t, list.Cached)
+
+	list, err = catalog.Models(context.Background(), "openai", "sk-test", false)
+	require.NoError(t, err)
+	assert.True(t, list.Cached)
+	assert.Equal(t, 1, requests)
+
+	fail = true
+	list, err = catalog.Models(context.Background(), "openai", "sk-test", true)
+	require.NoError(t, err)
+	assert.True(t, list.Stale)
+	assert.Len(t, list.Models, 2)
+	assert.NotEmpty(t, list.Error)
+
+	_, err = catalog.Models(context.Background(), "openai", "sk-other", false)
+	assert.ErrorIs(t, err, appErrors.ErrLLMAPICall, "lists are cached per key")
+}
+
+func TestModelCatalogFollowsAnthropicPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sk-ant", r.Header.Get("x-api-key"))
+		if r.URL.Query().Get("after_id") == "" {
+			w.Write([]byte(`{"data": [{"id": "claude-3-7-sonnet-20250219", "display_name": "Claude 3.7 Sonnet", "created_at": "2025-02-19T00:00:00Z"}], "has_more": true, "last_id": "claude-3-7-sonnet-20250219"}`))
+			return
+		}
+		w.Write([]byte(`{"data": [{"id": "claude-3-haiku-20240307", "display_name": "Claude 3 Haiku", "created_at": "2024-03-07T00:00:00Z"}], "has_more": false}`))
+	}))
+	defer server.Close()
+	catalog := newTestCatalog(map[string]string{"anthropic": server.URL})
+
+	list, err := catalog.Models(context.Background(), "anthropic", "sk-ant", false)
+	require.NoError(t, err)
+	require.Len(t, list.Models, 2)
+	assert.Equal(t, "Claude 3.7 Sonnet", list.Models[0].Name)
+	assert.Equal(t, "claude-3-haiku-20240307", list.Models[1].ID)
+
+	_, err = catalog.Models(context.Background(), "anthropic", "", false)
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+	_, err = catalog.Models(context.Background(), "bard", "key", false)
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+}
+
+func TestModelCatalogPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "sk-ant-test" {
+			http.Error(w, `{"error": "invalid x-api-key"}`, http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"data": [{"id": "claude-sonnet-4-20250514"}], "has_more": true}`))
+	}))
+	defer server.Close()
+	catalog := newTestCatalog(map[string]string{"anthropic": server.URL})
+
+	assert.NoError(t, catalog.Ping(context.Background(), "anthropic", "sk-ant-test"))
+	assert.ErrorIs(t, catalog.Ping(context.Background(), "anthropic", "sk-ant-revoked"), appErrors.ErrLLMAPICall)
+	assert.ErrorIs(t, catalog.Ping(context.Background(), "mistral", "key"), appErrors.ErrNotFound)
+}
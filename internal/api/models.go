@@ -1,5 +1,17 @@
 package api
 
+import (
+	"fmt"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// maxRequestTokens caps the maxTokens a chat request may ask for
+const maxRequestTokens = 200000
+
 // ChatMessage represents a message in the chat history
 type ChatMessage struct {
 	Role        string        `json:"role"`
@@ -19,88 +31,101 @@ type ChatRequest struct {
 	Message     string        `json:"message"`
 	History     []ChatMessage `json:"history"`
 	SentContext []ContextItem `json:"sentContext,omitempty"`
+	RequestID   string        `json:"requestId,omitempty"` // Chosen by the client to cancel the request with
+	Profile     string        `json:"profile,omitempty"`   // Prompt profile for this request instead of the user's
+
+	// TerminalLines attaches the last lines of the session's terminal output as context
+	TerminalLines int `json:"terminalLines,omitempty"`
+	// BinaryContext attaches a summary of the executable being debugged as context: its
+	// sections, libraries, imports and interesting strings
+	BinaryContext bool `json:"binaryContext,omitempty"`
+	// ScriptResults attaches the session's recent GDB Python script runs and what they
+	// printed as context
+	ScriptResults bool `json:"scriptResults,omitempty"`
+	// Variables attaches the arguments and locals of the innermost frame as context, with
+	// containers split into their elements as the pretty-printers show them
+	Variables bool `json:"variables,omitempty"`
+	// Function attaches the code of a function of the executable being debugged, named by
+	// its symbol or address, from the configured decompiler. Without it, a function the
+	// message asks about is attached when the executable has no debug information.
+	Function string `json:"function,omitempty"`
+	// Attachments attaches files uploaded to /api/v1/chat/attachments, by ID
+	Attachments []string `json:"attachments,omitempty"`
+	// Images are sent with the message to models that accept images. Uploaded images
+	// named in Attachments are added to them.
+	Images []Image `json:"images,omitempty"`
+
+	// Overrides of the user's settings for this request only, e.g. a cheaper model for a
+	// trivial question
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"` // Response size limit; 0 uses the provider's default
+
+	// GDBState fingerprints the state of the program the request was answered in, so
+	// cached answers are only reused in the same state. The server sets it.
+	GDBState string `json:"-"`
+}
+
+// ValidateOverrides checks the model, temperature and maxTokens a request sets for a
+// provider
+func (r *ChatRequest) ValidateOverrides(provider string) error {
+	if len(r.Model) > 200 || strings.ContainsAny(r.Model, " \t\r\n") {
+		return fmt.Errorf("%w: invalid model %q", appErrors.ErrBadRequest, r.Model)
+	}
+	if r.Temperature != nil {
+		maxTemperature := 2.0
+		if provider == "anthropic" {
+			maxTemperature = 1.0
+		}
+		if *r.Temperature < 0 || *r.Temperature > maxTemperature {
+			return fmt.Errorf("%w: temperature must be between 0 and %g for %s", appErrors.ErrBadRequest, maxTemperature, provider)
+		}
+	}
+	if r.MaxTokens < 0 || r.MaxTokens > maxRequestTokens {
+		return fmt.Errorf("%w: maxTokens must be between 1 and %d", appErrors.ErrBadRequest, maxRequestTokens)
+	}
+	return nil
 }
 
 // ChatResponse represents a response from the chat API
 type ChatResponse struct {
-	Response string `json:"response"`
+	Response          string      `json:"response"`
+	Model             string      `json:"model,omitempty"`   // Model that answered
+	Refused           bool        `json:"refused,omitempty"` // The model declined the request; Response explains why
+	Envelope          string      `json:"envelope,omitempty"`
+	SuggestedCommands []string    `json:"suggestedCommands,omitempty"` // Commands the user may run; never executed automatically
+	NextPage          string      `json:"nextPage,omitempty"`          // Set when Response is the first page of a longer response
+	TotalSize         int         `json:"totalSize,omitempty"`         // Size of the full response in bytes when paginated
+	Cancelled         bool        `json:"cancelled,omitempty"`         // The user cancelled the request; Response holds what was ready
+	QueuedMs          int64       `json:"queuedMs,omitempty"`          // Time spent waiting for earlier requests of the session
+	Usage             *TokenUsage `json:"usage,omitempty"`             // Tokens used by the request's LLM calls
+	Cost              float64     `json:"cost,omitempty"`              // US dollars
 }
 
 // LLMResponse represents a structured response from the LLM
 type LLMResponse struct {
-	Text          string   `json:"text"`          // Text to display to the user
-	GDBCommands   []string `json:"gdbCommands"`   // Array of GDB commands to execute
-	WaitForOutput bool     `json:"waitForOutput"` // Whether to wait for output before continuing
-}
-
-// --- LLM Provider Specific Structs ---
-
-// AnthropicMessage represents a message for Anthropic API
-type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// AnthropicRequest represents a request to the Anthropic API
-type AnthropicRequest struct {
-	Model     string             `json:"model"`
-	Messages  []AnthropicMessage `json:"messages"`
-	MaxTokens int                `json:"max_tokens"`
-	System    string             `json:"system,omitempty"`
-}
-
-// AnthropicResponse represents a response from the Anthropic API
-type AnthropicResponse struct {
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-}
-
-// OpenAIMessage represents a message for OpenAI API
-type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// OpenAIRequest represents a request to the OpenAI API
-type OpenAIRequest struct {
-	Model          string          `json:"model"`
-	Messages       []OpenAIMessage `json:"messages"`
-	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
-}
-
-// ResponseFormat specifies the format for OpenAI API responses
-type ResponseFormat struct {
-	Type string `json:"type"`
-}
-
-// OpenAIResponse represents a response from the OpenAI API
-type OpenAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
-
-// OpenRouterMessage represents a message for OpenRouter API
-type OpenRouterMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// OpenRouterRequest represents a request to the OpenRouter API
-type OpenRouterRequest struct {
-	Model    string              `json:"model"`
-	Messages []OpenRouterMessage `json:"messages"`
+	Text          string               `json:"text"`                  // Text to display to the user
+	GDBCommands   []string             `json:"gdbCommands"`           // Array of GDB commands to execute
+	Breakpoints   []gdb.BreakpointSpec `json:"breakpoints,omitempty"` // Breakpoints to set before the commands run
+	WaitForOutput bool                 `json:"waitForOutput"`         // Whether to wait for output before continuing
 }
 
-// OpenRouterResponse represents a response from the OpenRouter API
-type OpenRouterResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
+// Commands returns the commands setting the response's breakpoints followed by its GDB
+// commands. Breakpoints that cannot be set are skipped and logged.
+func (r *LLMResponse) Commands(logger *logsession.SessionLogger) []string {
+	if len(r.Breakpoints) == 0 {
+		return r.GDBCommands
+	}
+	commands := make([]string, 0, len(r.Breakpoints)+len(r.GDBCommands))
+	for _, spec := range r.Breakpoints {
+		command, err := spec.Command()
+		if err != nil {
+			if logger != nil {
+				logger.LogError(err, "Skipping breakpoint at "+spec.Location)
+			}
+			continue
+		}
+		commands = append(commands, command)
+	}
+	return append(commands, r.GDBCommands...)
 }
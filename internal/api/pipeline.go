@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/providers"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/llm"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// Outcomes of an LLMCall in the response cache
+const (
+	cacheHit     = "hit"
+	cacheSimilar = "similar" // Answered with the response to a reworded request
+	cacheMiss    = "miss"
+)
+
+// LLMCall is a prompt sent to the LLM by a chat route
+type LLMCall struct {
+	Prompt   *Prompt
+	Settings settings.Settings
+	Logger   *logsession.SessionLogger
+	Session  string       // Debugging session whose budget pays for the call
+	CacheKey *ChatRequest // Request the response is cached under; nil for calls never cached, like follow-ups
+	// Stream receives the response as it arrives; nil to receive it whole. A retry after
+	// the stream broke off resumes the response rather than starting it over.
+	Stream *providers.Stream
+}
+
+// LLMResult is the LLM's response to a call and how the pipeline got it
+type LLMResult struct {
+	Response string
+	Usage    TokenUsage
+	Cost     float64 // US dollars
+	Cache    string  // cacheHit, cacheSimilar or cacheMiss when the call may be cached and caching is enabled
+	Attempts int     // Requests sent to the provider; 0 when none was
+}
+
+// LLMHandler sends a call to the LLM
+type LLMHandler func(ctx context.Context, call *LLMCall) (LLMResult, error)
+
+// LLMMiddleware adds a feature, like caching or retries, to a handler
+type LLMMiddleware func(next LLMHandler) LLMHandler
+
+// Chain returns handler with the middleware added, the first outermost
+func Chain(handler LLMHandler, middleware ...LLMMiddleware) LLMHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// newPipeline builds the handler every chat route sends its LLM calls with: metrics,
+// the response cache, the session budget, retries and the circuit breaker, outermost
+// first, around the provider client. The features turned off in the chat configuration
+// are left out; the cache is skipped while chat.cache.enabled is false.
+func (cp *ChatProcessor) newPipeline(chatCfg config.ChatConfig) LLMHandler {
+	var middleware []LLMMiddleware
+	if chatCfg.Metrics.Enabled {
+		middleware = append(middleware, withMetrics(cp.metrics))
+	}
+	middleware = append(middleware, withCache(cp.cache), withBudget(cp.costs))
+	if chatCfg.Retry.Enabled && chatCfg.Retry.MaxAttempts > 1 {
+		middleware = append(middleware, withRetry(chatCfg.Retry))
+	}
+	if chatCfg.CircuitBreaker.Enabled && chatCfg.CircuitBreaker.FailureThreshold > 0 {
+		middleware = append(middleware, withCircuitBreaker(chatCfg.CircuitBreaker))
+	}
+	return Chain(cp.callLLM, middleware...)
+}
+
+// callLLM sends a call to its provider, at the end of the pipeline
+func (cp *ChatProcessor) callLLM(ctx context.Context, call *LLMCall) (LLMResult, error) {
+	response, usage, err := cp.llmClient.StreamPrompt(ctx, call.Prompt, call.Settings, call.Logger, call.Stream)
+	return LLMResult{Response: response, Usage: usage, Attempts: 1}, err
+}
+
+// withMetrics records each call's provider request, retries, errors, response time,
+// cost and cache outcome. Calls refused by the budget or answered from the cache are not
+// requests.
+func withMetrics(metrics *MetricsCollector) LLMMiddleware {
+	return func(next LLMHandler) LLMHandler {
+		return func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			provider := call.Settings.Provider
+			start := time.Now()
+			result, err := next(ctx, call)
+			switch result.Cache {
+			case cacheHit, cacheSimilar:
+				metrics.RecordCacheHit(provider)
+				return result, err
+			case cacheMiss:
+				metrics.RecordCacheMiss(provider)
+			}
+			if errors.Is(err, ErrBudgetExceeded) {
+				return result, err
+			}
+
+			metrics.RecordRequest(provider)
+			for i := 1; i < result.Attempts; i++ {
+				metrics.RecordRetry(provider)
+			}
+			if err != nil {
+				if !cancelled(ctx) {
+					metrics.RecordError(provider)
+				}
+				return result, err
+			}
+			metrics.RecordResponse(provider, time.Since(start))
+			metrics.RecordCost(provider, result.Cost)
+			return result, nil
+		}
+	}
+}
+
+// withCache answers calls that may be cached from the response cache, with the response
+// to the same request or, with semantic matching, a reworded one, and caches the
+// responses to the others. Refusals are not cached so a rephrased retry reaches the model.
+func withCache(cache *ResponseCache) LLMMiddleware {
+	return func(next LLMHandler) LLMHandler {
+		return func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			if call.CacheKey == nil || !cache.Enabled() {
+				return next(ctx, call)
+			}
+			provider, model := call.Settings.Provider, call.Settings.Model
+			if response, similar := cache.Get(ctx, call.CacheKey, provider, model); response != "" {
+				if similar {
+					return LLMResult{Response: response, Cache: cacheSimilar}, nil
+				}
+				return LLMResult{Response: response, Cache: cacheHit}, nil
+			}
+
+			result, err := next(ctx, call)
+			result.Cache = cacheMiss
+			if _, refused := DetectRefusal(result.Response); err == nil && !refused {
+				cache.Set(call.CacheKey, provider, model, result.Response)
+			}
+			return result, err
+		}
+	}
+}
+
+// withBudget refuses calls once the session has spent its budget, and adds the tokens
+// used by the others to the session's cost
+func withBudget(costs *CostTracker) LLMMiddleware {
+	return func(next LLMHandler) LLMHandler {
+		return func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			if err := costs.Check(call.Session); err != nil {
+				return LLMResult{}, err
+			}
+			result, err := next(ctx, call)
+			if err != nil {
+				return result, err
+			}
+			result.Cost = costs.Record(call.Session, call.Settings.Provider, call.Settings.Model, result.Usage)
+			// Later calls are refused above, so only the call that spends the budget tells
+			if costs.Check(call.Session) != nil {
+				costs.publishExceeded(userFromContext(ctx), call.Session)
+			}
+			return result, nil
+		}
+	}
+}
+
+// withRetry sends a call again, after a backoff, while it fails for a reason that may
+// pass, up to cfg.MaxAttempts times
+func withRetry(cfg config.RetryConfig) LLMMiddleware {
+	return func(next LLMHandler) LLMHandler {
+		return func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			attempts := 0
+			for attempt := 1; ; attempt++ {
+				result, err := next(ctx, call)
+				attempts += result.Attempts
+				result.Attempts = attempts
+				if err == nil || attempt >= cfg.MaxAttempts || !transient(ctx, err) {
+					return result, err
+				}
+
+				delay := retryDelay(cfg, attempt)
+				if call.Logger != nil {
+					call.Logger.LogTerminalOutput(fmt.Sprintf("=== LLM RETRY %d/%d ===\nDelay: %v\nError: %v", attempt+1, cfg.MaxAttempts, delay, err))
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return result, err
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+// retryDelay returns the backoff before the retry following an attempt:
+// cfg.BaseDelay multiplied by cfg.BackoffMultiplier for each earlier retry, up to
+// cfg.MaxDelay, plus up to a quarter more with jitter
+func retryDelay(cfg config.RetryConfig, attempt int) time.Duration {
+	multiplier := cfg.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(cfg.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if cfg.MaxDelay > 0 && delay > float64(cfg.MaxDelay) {
+		delay = float64(cfg.MaxDelay)
+	}
+	if cfg.Jitter {
+		delay += delay * 0.25 * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// transient reports whether a call failed for a reason that may pass: a rate limit, a
+// failing or overloaded provider or a network error. Calls whose context is done are
+// not retried.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var providerErr *llm.ProviderError
+	return errors.As(err, &providerErr) && providerErr.Retryable
+}
+
+// withCircuitBreaker fails calls to a provider at once, without sending them, after
+// cfg.FailureThreshold transient failures in a row, until cfg.RecoveryTimeout has passed
+func withCircuitBreaker(cfg config.CircuitBreakerConfig) LLMMiddleware {
+	var mutex sync.Mutex
+	breakers := make(map[string]*CircuitBreaker)
+	breaker := func(provider string) *CircuitBreaker {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if breakers[provider] == nil {
+			breakers[provider] = &CircuitBreaker{threshold: cfg.FailureThreshold, timeout: cfg.RecoveryTimeout}
+		}
+		return breakers[provider]
+	}
+
+	return func(next LLMHandler) LLMHandler {
+		return func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+			cb := breaker(call.Settings.Provider)
+			if !cb.CanExecute() {
+				return LLMResult{}, fmt.Errorf("%w: %s failed %d times in a row; calls resume within %v",
+					appErrors.ErrUnavailable, call.Settings.Provider, cfg.FailureThreshold, cfg.RecoveryTimeout)
+			}
+			result, err := next(ctx, call)
+			switch {
+			case err == nil:
+				cb.RecordSuccess()
+			case transient(ctx, err):
+				cb.RecordFailure()
+			}
+			return result, err
+		}
+	}
+}
+
+// CircuitBreaker counts a provider's failures in a row. It opens after threshold of
+// them, then lets a call through once timeout has passed, closing again if it succeeds.
+type CircuitBreaker struct {
+	failureCount    int
+	lastFailureTime time.Time
+	state           CircuitBreakerState
+	threshold       int
+	timeout         time.Duration
+	mutex           sync.Mutex
+}
+
+// CircuitBreakerState is whether a circuit breaker lets calls through
+type CircuitBreakerState int
+
+const (
+	CircuitClosed CircuitBreakerState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+// CanExecute reports whether a call may be made now
+func (cb *CircuitBreaker) CanExecute() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(cb.lastFailureTime) > cb.timeout {
+			cb.state = CircuitHalfOpen
+			return true
+		}
+		return false
+	case CircuitHalfOpen:
+		return true
+	default:
+		return false
+	}
+}
+
+// RecordSuccess closes the breaker
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failureCount = 0
+	if cb.state == CircuitHalfOpen {
+		cb.state = CircuitClosed
+	}
+}
+
+// RecordFailure counts a failure, opening the breaker at the threshold
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failureCount++
+	cb.lastFailureTime = time.Now()
+
+	if cb.failureCount >= cb.threshold {
+		cb.state = CircuitOpen
+	}
+}
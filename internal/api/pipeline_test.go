@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/chat/providers"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/llm"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// fakeProvider answers calls with its errors in turn, then with "fixed"
+type fakeProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *fakeProvider) send(ctx context.Context, call *LLMCall) (LLMResult, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return LLMResult{Attempts: 1}, err
+	}
+	return LLMResult{Response: "fixed", Usage: TokenUsage{InputTokens: 1000, OutputTokens: 100}, Attempts: 1}, nil
+}
+
+func TestPipeline(t *testing.T) {
+	overloaded := &llm.ProviderError{Provider: "anthropic", Code: 529, Message: "overloaded", Retryable: true}
+	call := func() *LLMCall {
+		return &LLMCall{
+			Settings: settings.Settings{Provider: "anthropic", Model: "claude-3-haiku"},
+			Session:  "s1",
+			CacheKey: &ChatRequest{Message: "why did it crash?"},
+		}
+	}
+	retry := config.RetryConfig{Enabled: true, MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	cache := NewResponseCache(config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10})
+	costs := NewCostTracker(config.CostConfig{Prices: []config.PriceConfig{{Model: "claude-3-haiku", Input: 1, Output: 10}}})
+	metrics := NewMetricsCollector()
+	provider := &fakeProvider{errs: []error{overloaded}}
+	pipeline := Chain(provider.send, withMetrics(metrics), withCache(cache), withBudget(costs), withRetry(retry))
+
+	result, err := pipeline(context.Background(), call())
+	require.NoError(t, err)
+	assert.Equal(t, "fixed", result.Response)
+	assert.Equal(t, 2, result.Attempts, "the overloaded provider was retried")
+	assert.Equal(t, cacheMiss, result.Cache)
+	assert.InDelta(t, 0.002, result.Cost, 1e-9)
+
+	result, err = pipeline(context.Background(), call())
+	require.NoError(t, err)
+	assert.Equal(t, cacheHit, result.Cache)
+	assert.Equal(t, 2, provider.calls)
+
+	providerMetrics := metrics.GetAllMetrics()["anthropic"]
+	require.NotNil(t, providerMetrics)
+	assert.Equal(t, int64(1), providerMetrics.RequestCount)
+	assert.Equal(t, int64(1), providerMetrics.RetryAttempts)
+	assert.Equal(t, int64(1), providerMetrics.CacheHits)
+	assert.Equal(t, int64(1), providerMetrics.CacheMisses)
+	assert.Zero(t, providerMetrics.ErrorCount)
+
+	// Rejected requests are not retried
+	rejected := &llm.ProviderError{Provider: "anthropic", Code: 400, Message: "bad request"}
+	provider = &fakeProvider{errs: []error{rejected}}
+	_, err = Chain(provider.send, withRetry(retry))(context.Background(), call())
+	assert.ErrorIs(t, err, rejected)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	unavailable := &llm.ProviderError{Provider: "openai", Code: 503, Message: "unavailable", Retryable: true}
+	provider := &fakeProvider{errs: []error{unavailable, unavailable}}
+	pipeline := Chain(provider.send, withCircuitBreaker(config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, RecoveryTimeout: 50 * time.Millisecond}))
+	call := &LLMCall{Settings: settings.Settings{Provider: "openai"}}
+
+	for i := 0; i < 2; i++ {
+		_, err := pipeline(context.Background(), call)
+		assert.ErrorIs(t, err, unavailable)
+	}
+	_, err := pipeline(context.Background(), call)
+	assert.ErrorIs(t, err, appErrors.ErrUnavailable, "the breaker is open")
+	assert.Equal(t, 2, provider.calls)
+
+	_, err = pipeline(context.Background(), &LLMCall{Settings: settings.Settings{Provider: "anthropic"}})
+	assert.NoError(t, err, "each provider has its own breaker")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = pipeline(context.Background(), call)
+	assert.NoError(t, err, "a call is let through after the timeout")
+}
+
+func TestTransient(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, transient(ctx, &llm.ProviderError{Code: 429, ErrorType: llm.ErrorTypeRateLimit, Retryable: true}))
+	assert.True(t, transient(ctx, fmt.Errorf("chat: %w", &llm.ProviderError{ErrorType: llm.ErrorTypeNetwork, Retryable: true})))
+	assert.False(t, transient(ctx, &llm.ProviderError{Code: 401, ErrorType: llm.ErrorTypeAuth}))
+	assert.False(t, transient(ctx, errors.New("unsupported provider: ollama")))
+	assert.False(t, transient(ctx, ErrBudgetExceeded))
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, transient(cancelledCtx, &llm.ProviderError{Code: 503, Retryable: true}))
+}
+
+func TestRetryDelay(t *testing.T) {
+	cfg := config.RetryConfig{BaseDelay: time.Second, MaxDelay: 3 * time.Second, BackoffMultiplier: 2}
+	assert.Equal(t, time.Second, retryDelay(cfg, 1))
+	assert.Equal(t, 2*time.Second, retryDelay(cfg, 2))
+	assert.Equal(t, 3*time.Second, retryDelay(cfg, 3), "capped at max_delay")
+
+	cfg.Jitter = true
+	delay := retryDelay(cfg, 1)
+	assert.GreaterOrEqual(t, delay, time.Second)
+	assert.LessOrEqual(t, delay, 1250*time.Millisecond)
+}
+
+// brokenStreamer streams a response that breaks off once, then its continuation
+type brokenStreamer struct {
+	providers.Provider
+	prefills []string
+}
+
+func (p *brokenStreamer) StreamRequest(ctx context.Context, req *llm.Request, onDelta func(delta string)) (*llm.Response, error) {
+	p.prefills = append(p.prefills, req.Prefill)
+	if req.Prefill == "" {
+		onDelta("Step into ")
+		return nil, &llm.ProviderError{Provider: "anthropic", Message: "stream ended", Retryable: true}
+	}
+	onDelta("parse().")
+	return &llm.Response{Content: "parse()."}, nil
+}
+
+func TestPipelineResumesStream(t *testing.T) {
+	streamer := &brokenStreamer{}
+	send := func(ctx context.Context, call *LLMCall) (LLMResult, error) {
+		resp, err := call.Stream.Send(ctx, streamer, &llm.Request{Messages: []llm.Message{{Role: "user", Content: "what next?"}}})
+		if err != nil {
+			return LLMResult{Attempts: 1}, err
+		}
+		return LLMResult{Response: resp.Content, Attempts: 1}, nil
+	}
+	var deltas []string
+	stream := providers.NewStream(func(delta string) { deltas = append(deltas, delta) })
+	retry := config.RetryConfig{Enabled: true, MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	result, err := Chain(send, withRetry(retry))(context.Background(), &LLMCall{Settings: settings.Settings{Provider: "anthropic"}, Stream: stream})
+	require.NoError(t, err)
+	assert.Equal(t, "Step into parse().", result.Response)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, []string{"", "Step into "}, streamer.prefills, "the retry continues the text received")
+	assert.Equal(t, []string{"Step into ", "parse()."}, deltas)
+	assert.Equal(t, 1, stream.Resumes())
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// PostProcessor rewrites a parsed LLM response before it is returned to the user or its
+// GDB commands run
+type PostProcessor interface {
+	// Process changes the response in place and describes what it changed, or returns ""
+	// when it left the response alone
+	Process(resp *ParsedResponse) string
+}
+
+// PostProcessorFactory creates a post-processor from its entry in chat.post_processors
+type PostProcessorFactory func(cfg config.PostProcessorConfig) (PostProcessor, error)
+
+var (
+	postProcessorsMutex    sync.RWMutex
+	postProcessorFactories = map[string]PostProcessorFactory{
+		"command_sanitizer": newCommandSanitizer,
+		"pii_filter":        newPIIFilter,
+		"profanity_filter":  newProfanityFilter,
+		"markdown":          newMarkdownNormalizer,
+		"source_links":      newSourceLinker,
+	}
+)
+
+// RegisterPostProcessor makes a post-processor available to chat.post_processors under a
+// name, replacing any registered before under it
+func RegisterPostProcessor(name string, factory PostProcessorFactory) {
+	postProcessorsMutex.Lock()
+	defer postProcessorsMutex.Unlock()
+	postProcessorFactories[name] = factory
+}
+
+// postProcessStep is a post-processor of the chain and the name it was configured by
+type postProcessStep struct {
+	name      string
+	processor PostProcessor
+}
+
+// postProcessChain runs its post-processors on a response in order
+type postProcessChain []postProcessStep
+
+// newPostProcessChain creates the post-processors configured in chat.post_processors,
+// failing on unknown names and invalid settings
+func newPostProcessChain(cfgs []config.PostProcessorConfig) (postProcessChain, error) {
+	postProcessorsMutex.RLock()
+	defer postProcessorsMutex.RUnlock()
+
+	chain := make(postProcessChain, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		factory, ok := postProcessorFactories[cfg.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown chat.post_processors entry %q (expected one of %s)", cfg.Name, strings.Join(postProcessorNames(), ", "))
+		}
+		processor, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("chat.post_processors entry %q: %w", cfg.Name, err)
+		}
+		chain = append(chain, postProcessStep{name: cfg.Name, processor: processor})
+	}
+	return chain, nil
+}
+
+// ValidatePostProcessors checks that chat.post_processors names registered
+// post-processors with valid settings
+func ValidatePostProcessors(cfgs []config.PostProcessorConfig) error {
+	_, err := newPostProcessChain(cfgs)
+	return err
+}
+
+// postProcessorNames returns the registered names, sorted; the caller holds
+// postProcessorsMutex
+func postProcessorNames() []string {
+	names := make([]string, 0, len(postProcessorFactories))
+	for name := range postProcessorFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// process runs the chain on a response, calling logStep with each change made
+func (c postProcessChain) process(resp *ParsedResponse, logStep func(message string)) {
+	for _, step := range c {
+		if change := step.processor.Process(resp); change != "" {
+			logStep(fmt.Sprintf("Post-processor %s: %s", step.name, change))
+		}
+	}
+}
+
+// shellCommands leave GDB for the shell, so the command sanitizer offers them to the user
+// instead of running them
+var shellCommands = []string{"shell", "!", "pipe", "|"}
+
+// commandSanitizer cleans up the GDB commands of a response: it strips pasted prompts and
+// code quoting, drops empty and repeated commands, and turns shell escapes into
+// suggestions
+type commandSanitizer struct{}
+
+func newCommandSanitizer(config.PostProcessorConfig) (PostProcessor, error) {
+	return commandSanitizer{}, nil
+}
+
+func (commandSanitizer) Process(resp *ParsedResponse) string {
+	var kept, escaped []string
+	seen := make(map[string]bool)
+	for _, command := range resp.GDBCommands {
+		command = strings.TrimSpace(command)
+		command = strings.TrimSpace(strings.TrimPrefix(command, gdbPrompt))
+		if len(command) > 1 && strings.HasPrefix(command, "`") && strings.HasSuffix(command, "`") {
+			command = strings.TrimSpace(strings.Trim(command, "`"))
+		}
+		if command == "" || seen[command] {
+			continue
+		}
+		seen[command] = true
+		if isShellEscape(command) {
+			escaped = append(escaped, command)
+			continue
+		}
+		kept = append(kept, command)
+	}
+
+	if slices.Equal(kept, resp.GDBCommands) {
+		return ""
+	}
+	dropped := len(resp.GDBCommands) - len(kept) - len(escaped)
+	resp.GDBCommands = append([]string{}, kept...)
+	resp.SuggestedCommands = append(resp.SuggestedCommands, escaped...)
+	return fmt.Sprintf("%d commands kept, %d dropped, %d shell escapes suggested instead", len(kept), dropped, len(escaped))
+}
+
+// isShellEscape reports whether a GDB command runs a shell command
+func isShellEscape(command string) bool {
+	for _, escape := range shellCommands {
+		if command == escape || strings.HasPrefix(command, escape+" ") || (len(escape) == 1 && strings.HasPrefix(command, escape)) {
+			return true
+		}
+	}
+	return false
+}
+
+// piiPatterns match personal data and secrets that should not be shown or logged
+var piiPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),          // Email addresses
+	regexp.MustCompile(`\b(?:sk-(?:ant-|or-)?[A-Za-z0-9_-]{16,}|AKIA[0-9A-Z]{16})\b`), // API keys
+}
+
+// redacted replaces the values the PII filter removes
+const redacted = "[redacted]"
+
+// piiFilter redacts email addresses, API keys and the configured patterns from a
+// response's text
+type piiFilter struct {
+	patterns []*regexp.Regexp
+}
+
+func newPIIFilter(cfg config.PostProcessorConfig) (PostProcessor, error) {
+	filter := &piiFilter{patterns: append([]*regexp.Regexp{}, piiPatterns...)}
+	for _, pattern := range cfg.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		filter.patterns = append(filter.patterns, compiled)
+	}
+	return filter, nil
+}
+
+func (f *piiFilter) Process(resp *ParsedResponse) string {
+	redactions := 0
+	for _, pattern := range f.patterns {
+		resp.Text = pattern.ReplaceAllStringFunc(resp.Text, func(string) string {
+			redactions++
+			return redacted
+		})
+	}
+	if redactions == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d values redacted", redactions)
+}
+
+// profanityFilter masks the configured words in a response's text, whole words only and
+// ignoring case
+type profanityFilter struct {
+	pattern *regexp.Regexp
+}
+
+func newProfanityFilter(cfg config.PostProcessorConfig) (PostProcessor, error) {
+	if len(cfg.Words) == 0 {
+		return nil, fmt.Errorf("words must list the words to mask")
+	}
+	quoted := make([]string, len(cfg.Words))
+	for i, word := range cfg.Words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return &profanityFilter{pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}, nil
+}
+
+func (f *profanityFilter) Process(resp *ParsedResponse) string {
+	masked := 0
+	resp.Text = f.pattern.ReplaceAllStringFunc(resp.Text, func(word string) string {
+		masked++
+		return strings.Repeat("*", len(word))
+	})
+	if masked == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d words masked", masked)
+}
+
+// markdownNormalizer tidies a response's Markdown: Unix line endings, no trailing spaces,
+// at most one blank line in a row outside code blocks, and code blocks that are closed
+type markdownNormalizer struct{}
+
+func newMarkdownNormalizer(config.PostProcessorConfig) (PostProcessor, error) {
+	return markdownNormalizer{}, nil
+}
+
+func (markdownNormalizer) Process(resp *ParsedResponse) string {
+	lines := strings.Split(strings.ReplaceAll(resp.Text, "\r\n", "\n"), "\n")
+	normalized := make([]string, 0, len(lines))
+	inBlock, blank := false, false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inBlock = !inBlock
+		}
+		if !inBlock {
+			line = strings.TrimRight(line, " \t")
+			if line == "" && blank {
+				continue
+			}
+			blank = line == ""
+		}
+		normalized = append(normalized, line)
+	}
+	text := strings.TrimSpace(strings.Join(normalized, "\n"))
+	if inBlock {
+		text += "\n```"
+	}
+	if text == resp.Text {
+		return ""
+	}
+	resp.Text = text
+	return "Markdown normalized"
+}
+
+// sourceReference matches a source file and line, e.g. parse.c:42 or src/main.go:7
+var sourceReference = regexp.MustCompile(`\b((?:[\w.-]+/)*[\w.-]+\.(?:c|cc|cpp|cxx|h|hh|hpp|go|rs|s|S|asm|zig|m|mm)):(\d+)\b`)
+
+// sourceLinker turns the source references in a response's text, outside code, into
+// Markdown links made from a URL template with {file} and {line}
+type sourceLinker struct {
+	template string
+}
+
+func newSourceLinker(cfg config.PostProcessorConfig) (PostProcessor, error) {
+	if !strings.Contains(cfg.URL, "{file}") {
+		return nil, fmt.Errorf("url must contain {file}")
+	}
+	return &sourceLinker{template: cfg.URL}, nil
+}
+
+func (l *sourceLinker) Process(resp *ParsedResponse) string {
+	linked := 0
+	lines := strings.Split(resp.Text, "\n")
+	inBlock := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inBlock = !inBlock
+		}
+		if inBlock {
+			continue
+		}
+		// Inline code spans alternate with prose between backticks
+		spans := strings.Split(line, "`")
+		for j := 0; j < len(spans); j += 2 {
+			spans[j] = l.link(spans[j], &linked)
+		}
+		lines[i] = strings.Join(spans, "`")
+	}
+	if linked == 0 {
+		return ""
+	}
+	resp.Text = strings.Join(lines, "\n")
+	return fmt.Sprintf("%d source references linked", linked)
+}
+
+// link links the source references in prose that are not already links
+func (l *sourceLinker) link(prose string, linked *int) string {
+	matches := sourceReference.FindAllStringSubmatchIndex(prose, -1)
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// Skip references already in a link's text or target
+		if (start > 0 && (prose[start-1] == '[' || prose[start-1] == '/' || prose[start-1] == '(')) || (end < len(prose) && prose[end] == ']') {
+			continue
+		}
+		file, line := prose[m[2]:m[3]], prose[m[4]:m[5]]
+		target := strings.NewReplacer("{file}", url.PathEscape(file), "{line}", line).Replace(l.template)
+		target = strings.ReplaceAll(target, "%2F", "/")
+		sb.WriteString(prose[last:start])
+		fmt.Fprintf(&sb, "[%s](%s)", prose[start:end], target)
+		last = end
+		*linked++
+	}
+	sb.WriteString(prose[last:])
+	return sb.String()
+}
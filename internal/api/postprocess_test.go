@@ -0,0 +1,62 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestPostProcessChain(t *testing.T) {
+	chain, err := newPostProcessChain([]config.PostProcessorConfig{
+		{Name: "command_sanitizer"},
+		{Name: "pii_filter", Patterns: []string{`\binternal-\d+\b`}},
+		{Name: "profanity_filter", Words: []string{"darn"}},
+		{Name: "markdown"},
+		{Name: "source_links", URL: "https://example.com/blob/main/{file}#L{line}"},
+	})
+	require.NoError(t, err)
+
+	resp := &ParsedResponse{
+		Text: "The darn crash is in src/parse.c:42, see `parse.c:42`.  \r\n\r\n\r\n" +
+			"Mail bob@example.com about ticket internal-7.\n```gdb\nlist parse.c:42\n",
+		GDBCommands: []string{"(gdb) bt", "`info locals`", "bt", "", "shell cat /etc/passwd", "!ls"},
+	}
+	var steps []string
+	chain.process(resp, func(message string) { steps = append(steps, message) })
+
+	assert.Equal(t, "The **** crash is in [src/parse.c:42](https://example.com/blob/main/src/parse.c#L42), see `parse.c:42`.\n\n"+
+		"Mail [redacted] about ticket [redacted].\n```gdb\nlist parse.c:42\n```", resp.Text)
+	assert.Equal(t, []string{"bt", "info locals"}, resp.GDBCommands)
+	assert.Equal(t, []string{"shell cat /etc/passwd", "!ls"}, resp.SuggestedCommands, "shell escapes are left to the user")
+	assert.Equal(t, []string{
+		"Post-processor command_sanitizer: 2 commands kept, 2 dropped, 2 shell escapes suggested instead",
+		"Post-processor pii_filter: 2 values redacted",
+		"Post-processor profanity_filter: 1 words masked",
+		"Post-processor markdown: Markdown normalized",
+		"Post-processor source_links: 1 source references linked",
+	}, steps)
+
+	// A tidy response is left alone
+	resp = &ParsedResponse{Text: "See [main.c:3](https://example.com/main.c#L3).", GDBCommands: []string{"run"}}
+	steps = nil
+	chain.process(resp, func(message string) { steps = append(steps, message) })
+	assert.Empty(t, steps)
+	assert.Equal(t, "See [main.c:3](https://example.com/main.c#L3).", resp.Text)
+}
+
+func TestValidatePostProcessors(t *testing.T) {
+	assert.NoError(t, ValidatePostProcessors(nil))
+	assert.ErrorContains(t, ValidatePostProcessors([]config.PostProcessorConfig{{Name: "spellcheck"}}), "expected one of command_sanitizer")
+	assert.Error(t, ValidatePostProcessors([]config.PostProcessorConfig{{Name: "source_links"}}), "source_links needs a url")
+	assert.Error(t, ValidatePostProcessors([]config.PostProcessorConfig{{Name: "pii_filter", Patterns: []string{"("}}}))
+
+	RegisterPostProcessor("spellcheck", func(config.PostProcessorConfig) (PostProcessor, error) { return markdownNormalizer{}, nil })
+	defer func() {
+		postProcessorsMutex.Lock()
+		delete(postProcessorFactories, "spellcheck")
+		postProcessorsMutex.Unlock()
+	}()
+	assert.NoError(t, ValidatePostProcessors([]config.PostProcessorConfig{{Name: "spellcheck"}}))
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+// contextHeader introduces the context items in the user's message
+const contextHeader = "\n\n--- Provided Context ---\n"
+
+// charsPerToken approximates how many characters make up a token for English text and code
+const charsPerToken = 4
+
+// Prompt is the prompt sent to the LLM for a chat request
+type Prompt struct {
+	Envelope        string // Envelope mode (config.EnvelopeJSON, EnvelopeSchema, EnvelopeTools or EnvelopePlain)
+	System          string
+	History         []ChatMessage
+	TrimmedMessages int // Oldest history messages dropped to fit the context budget
+	Context         []ContextItem
+	Images          []Image // Sent with the message, before it
+	Message         string
+	Temperature     *float64 // Unset uses the provider's default
+	MaxTokens       int      // Response size limit; 0 uses the default
+}
+
+// BuildPrompt composes the prompt for a chat request. When context management is enabled
+// and the request exceeds MaxTokens, only the PriorityRecentMessages most recent history
+// messages are kept.
+func BuildPrompt(req *ChatRequest, cfg config.ContextConfig) *Prompt {
+	prompt := &Prompt{
+		Envelope: config.EnvelopeJSON,
+		System:   prompts.Builtin().System(prompts.Vars{Envelope: config.EnvelopeJSON}),
+		History:  req.History,
+		Context:  req.SentContext,
+		Images:   req.Images,
+		Message:  req.Message,
+
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+
+	if cfg.Enabled && cfg.MaxTokens > 0 && prompt.estimatedTokens() > cfg.MaxTokens {
+		keep := cfg.PriorityRecentMessages
+		if keep >= 0 && keep < len(prompt.History) {
+			prompt.TrimmedMessages = len(prompt.History) - keep
+			prompt.History = prompt.History[prompt.TrimmedMessages:]
+		}
+	}
+
+	return prompt
+}
+
+// WithEnvelope switches the prompt to an envelope mode and its built-in system prompt
+func (p *Prompt) WithEnvelope(envelope string) *Prompt {
+	p.Envelope = envelope
+	p.System = prompts.Builtin().System(prompts.Vars{Envelope: envelope})
+	return p
+}
+
+// WithTemplates renders the system prompt for the prompt's envelope mode from engine's
+// templates
+func (p *Prompt) WithTemplates(engine *prompts.Engine, vars prompts.Vars) *Prompt {
+	vars.Envelope = p.Envelope
+	p.System = engine.System(vars)
+	return p
+}
+
+// renderBuiltinPrompt renders a built-in prompt template that uses no variables
+func renderBuiltinPrompt(name string) string {
+	prompt, _ := prompts.Builtin().Render(name, prompts.Vars{})
+	return prompt
+}
+
+// UserMessage returns the final user turn: the provided context followed by the message
+func (p *Prompt) UserMessage() string {
+	return p.contextBlock() + p.Message
+}
+
+// contextBlock formats the context items sent with the message
+func (p *Prompt) contextBlock() string {
+	if len(p.Context) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(contextHeader)
+	for _, item := range p.Context {
+		sb.WriteString(contextItemText(item))
+	}
+	return sb.String()
+}
+
+// contextItemText formats a single context item
+func contextItemText(item ContextItem) string {
+	text := fmt.Sprintf("Type: %s\nDescription: %s\n", item.Type, item.Description)
+	if item.Content != "" {
+		text += fmt.Sprintf("Content:\n```\n%s\n```\n", item.Content)
+	}
+	return text + "---\n"
+}
+
+// estimatedTokens approximates the size of the whole prompt
+func (p *Prompt) estimatedTokens() int {
+	tokens := EstimateTokens(p.System) + EstimateTokens(p.UserMessage())
+	for _, msg := range p.History {
+		tokens += EstimateTokens(msg.Content)
+	}
+	for _, img := range p.Images {
+		tokens += ImageTokens(img)
+	}
+	return tokens
+}
+
+// EstimateTokens approximates the number of tokens in text. Providers tokenize differently,
+// so this is a guide to relative sizes rather than an exact count.
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// PromptSegment is one part of a prompt with its size
+type PromptSegment struct {
+	Name        string          `json:"name"`
+	Role        string          `json:"role"`
+	Description string          `json:"description,omitempty"`
+	Chars       int             `json:"chars"`
+	Tokens      int             `json:"tokens"`
+	Items       []PromptSegment `json:"items,omitempty"`
+}
+
+// PromptComposition describes what the model will see for a request
+type PromptComposition struct {
+	Provider        string          `json:"provider"`
+	Model           string          `json:"model"`
+	Envelope        string          `json:"envelope"`
+	Segments        []PromptSegment `json:"segments"`
+	TotalTokens     int             `json:"totalTokens"`
+	MaxTokens       int             `json:"maxTokens,omitempty"` // Context budget when trimming is enabled
+	HistoryMessages int             `json:"historyMessages"`
+	TrimmedMessages int             `json:"trimmedMessages"`
+	TokenEstimate   string          `json:"tokenEstimate"` // How token counts were estimated
+}
+
+// Composition breaks the prompt into the system prompt, the history that survived
+// trimming, each context item and the user's message
+func (p *Prompt) Composition() PromptComposition {
+	composition := PromptComposition{
+		Envelope:        p.Envelope,
+		HistoryMessages: len(p.History),
+		TrimmedMessages: p.TrimmedMessages,
+		TokenEstimate:   fmt.Sprintf("%d characters per token", charsPerToken),
+	}
+
+	composition.Segments = append(composition.Segments, newPromptSegment("system", "system", "", p.System))
+
+	history := PromptSegment{Name: "history", Role: "history",
+		Description: fmt.Sprintf("%d messages (%d trimmed)", len(p.History), p.TrimmedMessages)}
+	for _, msg := range p.History {
+		history.Items = append(history.Items, newPromptSegment("message", msg.Role, "", msg.Content))
+	}
+	composition.Segments = append(composition.Segments, sumSegment(history))
+
+	if len(p.Context) > 0 {
+		context := PromptSegment{Name: "context", Role: "user", Description: fmt.Sprintf("%d items", len(p.Context))}
+		for _, item := range p.Context {
+			context.Items = append(context.Items, newPromptSegment(item.Type, "user", item.Description, contextItemText(item)))
+		}
+		context = sumSegment(context)
+		context.Chars += len(contextHeader)
+		context.Tokens += EstimateTokens(contextHeader)
+		composition.Segments = append(composition.Segments, context)
+	}
+
+	if len(p.Images) > 0 {
+		images := PromptSegment{Name: "images", Role: "user", Description: fmt.Sprintf("%d images", len(p.Images))}
+		for _, img := range p.Images {
+			images.Items = append(images.Items, PromptSegment{Name: "image", Role: "user", Description: imageDescription(img), Chars: len(img.Data), Tokens: ImageTokens(img)})
+		}
+		composition.Segments = append(composition.Segments, sumSegment(images))
+	}
+
+	composition.Segments = append(composition.Segments, newPromptSegment("message", "user", "", p.Message))
+
+	for _, segment := range composition.Segments {
+		composition.TotalTokens += segment.Tokens
+	}
+	return composition
+}
+
+// newPromptSegment measures a piece of prompt text
+func newPromptSegment(name, role, description, text string) PromptSegment {
+	return PromptSegment{Name: name, Role: role, Description: description, Chars: len(text), Tokens: EstimateTokens(text)}
+}
+
+// sumSegment sets a segment's size to the total of its items
+func sumSegment(segment PromptSegment) PromptSegment {
+	for _, item := range segment.Items {
+		segment.Chars += item.Chars
+		segment.Tokens += item.Tokens
+	}
+	return segment
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+// PromptRenderRequest is the body of a prompt preview request
+type PromptRenderRequest struct {
+	Name     string       `json:"name"`     // Template to render; defaults to the session's system prompt
+	Template string       `json:"template"` // Unsaved template text to render instead
+	Vars     prompts.Vars `json:"vars"`     // Overrides the current session's values
+}
+
+// promptRenderResponse is a rendered prompt
+type promptRenderResponse struct {
+	Name   string       `json:"name"`
+	Prompt string       `json:"prompt"`
+	Vars   prompts.Vars `json:"vars"`
+	Tokens int          `json:"tokens"`
+}
+
+// HandlePromptTemplates lists the prompt templates with their text and where each was
+// loaded from
+func (sch *SimpleChatHandler) HandlePromptTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"templates": sch.processor.prompts.Templates()})
+}
+
+// HandlePromptProfiles lists the prompt profiles a user or request may choose
+func (sch *SimpleChatHandler) HandlePromptProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"profiles": sch.processor.prompts.Profiles()})
+}
+
+// HandlePromptRender renders a prompt template with the current session's values, or
+// with the values and template text given in the body, without calling the LLM
+func (sch *SimpleChatHandler) HandlePromptRender(w http.ResponseWriter, r *http.Request) {
+	cp := sch.processor
+	settings := cp.settingsManager.GetUserSettings(userFromContext(r.Context()))
+	req := PromptRenderRequest{Vars: cp.promptVars(cp.loggerHolder.Get(), settings, settings.Profile)}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		req.Name = prompts.SystemName(req.Vars.Envelope)
+	}
+
+	var prompt string
+	var err error
+	if req.Template != "" {
+		prompt, err = cp.prompts.RenderText(req.Template, req.Vars)
+	} else {
+		prompt, err = cp.prompts.Render(req.Name, req.Vars)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promptRenderResponse{Name: req.Name, Prompt: prompt, Vars: req.Vars, Tokens: EstimateTokens(prompt)})
+}
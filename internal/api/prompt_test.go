@@ -0,0 +1,87 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+func TestBuildPromptTrimsHistory(t *testing.T) {
+	history := make([]ChatMessage, 6)
+	for i := range history {
+		history[i] = ChatMessage{Role: "user", Content: strings.Repeat("x", 400)}
+	}
+	req := &ChatRequest{Message: "why did it crash?", History: history}
+
+	// Trimming is off by default
+	assert.Len(t, BuildPrompt(req, config.ContextConfig{}).History, 6)
+
+	prompt := BuildPrompt(req, config.ContextConfig{Enabled: true, MaxTokens: 500, PriorityRecentMessages: 2})
+	assert.Len(t, prompt.History, 2)
+	assert.Equal(t, 4, prompt.TrimmedMessages)
+	assert.Len(t, req.History, 6, "the request is not modified")
+}
+
+func TestPromptComposition(t *testing.T) {
+	req := &ChatRequest{
+		Message: "what is rax?",
+		History: []ChatMessage{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		SentContext: []ContextItem{
+			{Type: "selection", Description: "Selected Text Snippet", Content: "rax 0x0"},
+		},
+	}
+
+	prompt := BuildPrompt(req, config.ContextConfig{})
+	composition := prompt.Composition()
+	require.Len(t, composition.Segments, 4)
+
+	names := []string{}
+	total := 0
+	for _, segment := range composition.Segments {
+		names = append(names, segment.Name)
+		total += segment.Tokens
+	}
+	assert.Equal(t, []string{"system", "history", "context", "message"}, names)
+	assert.Equal(t, total, composition.TotalTokens)
+	assert.Equal(t, EstimateTokens(prompt.System), composition.Segments[0].Tokens)
+	assert.Len(t, composition.Segments[1].Items, 2)
+	assert.Equal(t, 2, composition.HistoryMessages)
+
+	// The segments account for every character the model receives
+	chars := len(prompt.System) + len(prompt.UserMessage())
+	for _, msg := range prompt.History {
+		chars += len(msg.Content)
+	}
+	sum := 0
+	for _, segment := range composition.Segments {
+		sum += segment.Chars
+	}
+	assert.Equal(t, chars, sum)
+}
+
+func TestRequestOverrides(t *testing.T) {
+	temperature := 0.2
+	req := &ChatRequest{Message: "what is rip?", Model: "gpt-4o-mini", Temperature: &temperature, MaxTokens: 256}
+	require.NoError(t, req.ValidateOverrides("openai"))
+
+	prompt := BuildPrompt(req, config.ContextConfig{})
+	assert.Equal(t, &temperature, prompt.Temperature)
+	assert.Equal(t, 256, prompt.MaxTokens)
+	assert.Equal(t, "gpt-4o-mini", withOverrides(settings.Settings{Provider: "openai", Model: "gpt-4o"}, req).Model)
+	assert.Equal(t, "gpt-4o", withOverrides(settings.Settings{Model: "gpt-4o"}, &ChatRequest{}).Model, "settings are the fallback")
+
+	cache := &ResponseCache{}
+	assert.NotEqual(t, cache.generateKey(req, "openai", "gpt-4o-mini"), cache.generateKey(&ChatRequest{Message: req.Message}, "openai", "gpt-4o-mini"),
+		"responses are cached per temperature and size limit")
+
+	temperature = 1.5
+	assert.NoError(t, req.ValidateOverrides("openai"))
+	assert.ErrorIs(t, req.ValidateOverrides("anthropic"), appErrors.ErrBadRequest)
+	assert.Error(t, (&ChatRequest{MaxTokens: -1}).ValidateOverrides("openai"))
+	assert.Error(t, (&ChatRequest{Model: "gpt 4"}).ValidateOverrides("openai"))
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Queue errors; both map to 429 Too Many Requests
+var (
+	ErrQueueFull    = fmt.Errorf("%w: too many chat requests are waiting for this session", appErrors.ErrTooManyRequests)
+	ErrQueueTimeout = fmt.Errorf("%w: timed out waiting for earlier chat requests of this session", appErrors.ErrTooManyRequests)
+)
+
+// ChatQueue limits the chat requests processed at once per debugging session. Waiting
+// requests are admitted in arrival order.
+type ChatQueue struct {
+	maxConcurrent int
+	maxQueued     int
+	maxWait       time.Duration
+	mutex         sync.Mutex
+	sessions      map[string]*sessionQueue
+}
+
+// sessionQueue is the state of one session's queue
+type sessionQueue struct {
+	running int
+	waiting []chan struct{} // Closed to admit the waiter
+}
+
+// NewChatQueue creates a chat queue. A MaxConcurrent below 1 admits one request at a time.
+func NewChatQueue(cfg config.QueueConfig) *ChatQueue {
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &ChatQueue{
+		maxConcurrent: maxConcurrent,
+		maxQueued:     cfg.MaxQueued,
+		maxWait:       cfg.MaxWait,
+		sessions:      make(map[string]*sessionQueue),
+	}
+}
+
+// Acquire admits a request of a session, waiting for earlier requests if the session is
+// at its limit. It returns a function to call once the request is done. It fails with
+// ErrQueueFull when too many requests are waiting, ErrQueueTimeout after waiting too
+// long, or ctx's error when ctx is done first.
+func (q *ChatQueue) Acquire(ctx context.Context, session string) (release func(), err error) {
+	q.mutex.Lock()
+	s, ok := q.sessions[session]
+	if !ok {
+		s = &sessionQueue{}
+		q.sessions[session] = s
+	}
+	if s.running < q.maxConcurrent && len(s.waiting) == 0 {
+		s.running++
+		q.mutex.Unlock()
+		return q.releaser(session), nil
+	}
+	if len(s.waiting) >= q.maxQueued {
+		q.mutex.Unlock()
+		return nil, ErrQueueFull
+	}
+	admitted := make(chan struct{})
+	s.waiting = append(s.waiting, admitted)
+	maxWait := q.maxWait
+	q.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-admitted:
+		return q.releaser(session), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+
+	// Leave the queue, unless the request was admitted meanwhile and must hand its slot on
+	q.mutex.Lock()
+	for i, waiter := range s.waiting {
+		if waiter == admitted {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			q.mutex.Unlock()
+			return nil, err
+		}
+	}
+	q.mutex.Unlock()
+	q.releaser(session)()
+	return nil, err
+}
+
+// Waiting returns how many requests of a session are waiting
+func (q *ChatQueue) Waiting(session string) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if s, ok := q.sessions[session]; ok {
+		return len(s.waiting)
+	}
+	return 0
+}
+
+// releaser returns a function that frees a session's slot once, handing it to the first
+// waiting request
+func (q *ChatQueue) releaser(session string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mutex.Lock()
+			defer q.mutex.Unlock()
+
+			s := q.sessions[session]
+			if len(s.waiting) > 0 {
+				close(s.waiting[0])
+				s.waiting = s.waiting[1:]
+				return
+			}
+			s.running--
+			if s.running == 0 {
+				delete(q.sessions, session)
+			}
+		})
+	}
+}
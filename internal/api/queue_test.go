@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestChatQueueOrderAndLimits(t *testing.T) {
+	queue := NewChatQueue(config.QueueConfig{MaxConcurrent: 1, MaxQueued: 2, MaxWait: time.Second})
+	ctx := context.Background()
+
+	release, err := queue.Acquire(ctx, "s1")
+	require.NoError(t, err)
+
+	// Other sessions are not held up
+	other, err := queue.Acquire(ctx, "s2")
+	require.NoError(t, err)
+	other()
+
+	// Waiting requests are admitted in arrival order
+	admitted := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		i := i
+		go func() {
+			next, err := queue.Acquire(ctx, "s1")
+			if err == nil {
+				admitted <- i
+				next()
+			}
+		}()
+		require.Eventually(t, func() bool { return queue.Waiting("s1") == i }, time.Second, time.Millisecond)
+	}
+
+	_, err = queue.Acquire(ctx, "s1")
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, 429, appErrors.StatusCode(err))
+
+	release()
+	release() // Releasing twice frees the slot once
+	assert.Equal(t, 1, <-admitted)
+	assert.Equal(t, 2, <-admitted)
+}
+
+func TestChatQueueGivesUp(t *testing.T) {
+	queue := NewChatQueue(config.QueueConfig{MaxConcurrent: 1, MaxQueued: 2, MaxWait: 20 * time.Millisecond})
+	release, err := queue.Acquire(context.Background(), "s1")
+	require.NoError(t, err)
+
+	_, err = queue.Acquire(context.Background(), "s1")
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = queue.Acquire(ctx, "s1")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, queue.Waiting("s1"), "requests that give up leave the queue")
+
+	release()
+	next, err := queue.Acquire(context.Background(), "s1")
+	require.NoError(t, err, "the slot is free again")
+	next()
+}
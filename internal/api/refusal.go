@@ -0,0 +1,48 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+)
+
+// refusalScanLength limits refusal detection to the opening of a response, where models
+// state a refusal. Matching further in would flag explanations that merely quote one.
+const refusalScanLength = 400
+
+// refusalPatterns match the boilerplate models use when declining a request
+var refusalPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bi(?:'m| am) sorry,? but\b`),
+	regexp.MustCompile(`(?i)\bi(?: can(?:no|')t| won't| will not|'m unable to| am unable to|'m not able to| am not able to) (?:help|assist)(?: you)?(?: with\b|[.,!]|$)`),
+	regexp.MustCompile(`(?i)\bi(?: can(?:no|')t| won't| will not|'m unable to| am unable to|'m not able to| am not able to) (?:provide|comply|fulfill|do that|continue with)\b`),
+	regexp.MustCompile(`(?i)\bi must (?:decline|refuse)\b`),
+	regexp.MustCompile(`(?i)\bas an ai(?: language model| assistant)?,`),
+	regexp.MustCompile(`(?i)\b(?:violates?|against) (?:my|our|the) (?:usage |content |safety )?(?:guidelines|policies|policy)\b`),
+}
+
+// DetectRefusal reports whether a response that is not valid JSON is a model refusal
+// or safety boilerplate, returning the trimmed refusal text
+func DetectRefusal(response string) (string, bool) {
+	text := strings.TrimSpace(response)
+	if text == "" {
+		return "", false
+	}
+
+	head := text
+	if len(head) > refusalScanLength {
+		head = head[:refusalScanLength]
+	}
+
+	for _, pattern := range refusalPatterns {
+		if pattern.MatchString(head) {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// refusalMessage builds the user-facing message shown when the model declines a request
+func refusalMessage(refusal string) string {
+	return "The model declined to answer this request:\n\n> " +
+		strings.ReplaceAll(refusal, "\n", "\n> ") +
+		"\n\nTry rephrasing the question or giving more context about the debugging task."
+}
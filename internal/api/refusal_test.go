@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRefusal(t *testing.T) {
+	refusals := []string{
+		"I'm sorry, but I can't help with that request.",
+		"I cannot assist with bypassing license checks.",
+		"As an AI language model, I am not able to do that.",
+		"This request goes against my guidelines, so I must decline.",
+		"I'm unable to help with reverse engineering this binary.",
+	}
+	for _, response := range refusals {
+		text, ok := DetectRefusal(response)
+		assert.True(t, ok, response)
+		assert.Equal(t, response, text)
+	}
+
+	allowed := []string{
+		"",
+		"The segfault happens because p is NULL. Run `bt` to see the caller.",
+		"I can't help noticing the loop never terminates; try `watch i`.",
+	}
+	for _, response := range allowed {
+		_, ok := DetectRefusal(response)
+		assert.False(t, ok, response)
+	}
+}
+
+func TestParseResponseRefusal(t *testing.T) {
+	parsed, err := NewResponseParser().ParseResponse("I'm sorry, but I can't help with that.", nil)
+	assert.NoError(t, err)
+	assert.True(t, parsed.Refused)
+	assert.Equal(t, "refusal", parsed.ParseMethod)
+	assert.Contains(t, parsed.Text, "> I'm sorry, but I can't help with that.")
+	assert.Empty(t, parsed.GDBCommands)
+
+	// Valid JSON that happens to apologise is not a refusal
+	parsed, err = NewResponseParser().ParseResponse(`{"text": "I'm sorry, but the binary has no symbols.", "gdbCommands": [], "waitForOutput": false}`, nil)
+	assert.NoError(t, err)
+	assert.False(t, parsed.Refused)
+}
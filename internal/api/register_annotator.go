@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// RegisterRecorder is implemented by GDB handlers that follow registers across stops
+type RegisterRecorder interface {
+	RecordRegisters(output string) *gdb.Registers
+}
+
+// annotateRegisterChanges appends the registers that changed since the last stop when the
+// model listed registers, so it need not compare values across turns itself. The output is
+// returned unchanged when no register changed or the handler does not follow registers.
+func annotateRegisterChanges(gdbHandler GDBCommandHandler, result *GDBExecutionResult, output string) string {
+	recorder, ok := gdbHandler.(RegisterRecorder)
+	if !ok {
+		return output
+	}
+
+	var lines []string
+	for i, command := range result.Commands {
+		if i >= len(result.Outputs) || !gdb.IsRegisterCommand(command) {
+			continue
+		}
+		registers := recorder.RecordRegisters(result.Outputs[i])
+		if registers == nil {
+			continue
+		}
+		for _, register := range registers.Changed() {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", register.Name, register.Previous, register.Value))
+		}
+	}
+
+	if len(lines) == 0 {
+		return output
+	}
+
+	return output + "\n\n--- Registers Changed Since the Last Stop ---\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Reload applies the chat settings that can change while the server runs: the envelope
+// modes, context limits, prices and session budget, queue limits, whether responses are
+// cached and for how long, the cache admins, and the pipeline's retries, circuit breaker
+// and metrics, and the response post-processors. Directories and the cache's backend and
+// size need a restart. Nothing changes if the envelope modes or post-processors are invalid.
+func (sch *SimpleChatHandler) Reload(chatCfg config.ChatConfig) error {
+	if err := chatCfg.Envelope.Validate(); err != nil {
+		return err
+	}
+	postProcessors, err := newPostProcessChain(chatCfg.PostProcessors)
+	if err != nil {
+		return err
+	}
+	sch.processor.reload(chatCfg, postProcessors)
+	sch.queue.reload(chatCfg.Queue)
+
+	sch.adminsMutex.Lock()
+	sch.cacheAdmins = adminSet(chatCfg.Cache.Admins)
+	sch.adminsMutex.Unlock()
+	return nil
+}
+
+// adminSet returns a set of the users listed as admins
+func adminSet(admins []string) map[string]bool {
+	set := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		set[admin] = true
+	}
+	return set
+}
+
+// reload applies the processor's share of Reload
+func (cp *ChatProcessor) reload(chatCfg config.ChatConfig, postProcessors postProcessChain) {
+	cp.costs.reload(chatCfg.Cost)
+	cp.cache.reload(chatCfg.Cache)
+	cp.cfgMutex.Lock()
+	cp.contextCfg, cp.envelopeCfg = chatCfg.Context, chatCfg.Envelope
+	// Circuit breakers start closed again
+	cp.pipeline = cp.newPipeline(chatCfg)
+	cp.postProcessors = postProcessors
+	cp.cfgMutex.Unlock()
+}
+
+// envelope returns the envelope mode of each model
+func (cp *ChatProcessor) envelope() config.EnvelopeConfig {
+	cp.cfgMutex.RLock()
+	defer cp.cfgMutex.RUnlock()
+	return cp.envelopeCfg
+}
+
+// contextLimits returns how much of the conversation prompts may carry
+func (cp *ChatProcessor) contextLimits() config.ContextConfig {
+	cp.cfgMutex.RLock()
+	defer cp.cfgMutex.RUnlock()
+	return cp.contextCfg
+}
+
+// reload changes the prices and session budget; sessions keep what they spent
+func (t *CostTracker) reload(cfg config.CostConfig) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.budget, t.prices = cfg.SessionBudget, cfg.Prices
+}
+
+// reload changes the queue's limits. Requests already waiting keep their deadline.
+func (q *ChatQueue) reload(cfg config.QueueConfig) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.maxConcurrent, q.maxQueued, q.maxWait = max(cfg.MaxConcurrent, 1), cfg.MaxQueued, cfg.MaxWait
+}
+
+// reload turns caching on or off and changes how long new entries are kept
+func (rc *ResponseCache) reload(cfg config.CacheConfig) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.enabled, rc.ttl = cfg.Enabled, cfg.TTL
+}
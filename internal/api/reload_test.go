@@ -0,0 +1,34 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestReload(t *testing.T) {
+	cache, err := NewResponseCacheFromConfig(config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10})
+	require.NoError(t, err)
+	chatCfg := config.ChatConfig{Cache: config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10}}
+	sch := NewSimpleChatHandler(nil, nil, nil, nil, chatCfg, cache, nil)
+
+	chatCfg.Envelope = config.EnvelopeConfig{Default: config.EnvelopePlain}
+	chatCfg.Cache.Enabled = false
+	chatCfg.Cache.Admins = []string{"alice"}
+	chatCfg.Queue = config.QueueConfig{MaxConcurrent: 4}
+	require.NoError(t, sch.Reload(chatCfg))
+	assert.Equal(t, config.EnvelopePlain, sch.processor.envelope().ModeFor("gpt-4o"))
+	assert.False(t, cache.Enabled())
+	assert.True(t, sch.cacheAdmins["alice"])
+	assert.Equal(t, 4, sch.queue.maxConcurrent)
+
+	// Invalid settings change nothing
+	chatCfg.Envelope.Default = "xml"
+	chatCfg.Cache.Enabled = true
+	assert.Error(t, sch.Reload(chatCfg))
+	assert.Equal(t, config.EnvelopePlain, sch.processor.envelope().ModeFor("gpt-4o"))
+	assert.False(t, cache.Enabled())
+}
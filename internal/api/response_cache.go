@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/cache/store"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// ResponseCache caches LLM responses per provider, model and request in a storage
+// backend. Keys are hashed like cache.Cache's, so both can share a backend.
+type ResponseCache struct {
+	backend   store.Backend
+	enabled   bool
+	ttl       time.Duration
+	maxSize   int
+	mutex     sync.RWMutex // Guards enabled and ttl, which reloads change
+	evictions atomic.Int64
+	semantic  *semanticIndex // Matches reworded requests; nil when only exact ones match
+
+	// apiKey returns the key the semantic index's embedder uses for a request's user
+	apiKey func(ctx context.Context) string
+}
+
+// cacheRequest is the part of a request that identifies its response, in the shape
+// cache.Cache hashes
+type cacheRequest struct {
+	Message     string              `json:"message"`
+	History     []cacheMessage      `json:"history"`
+	SentContext []map[string]string `json:"sentContext"`
+	Images      []string            `json:"images,omitempty"` // Hashes of the images
+	Profile     string              `json:"profile,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	MaxTokens   int                 `json:"maxTokens,omitempty"`
+	State       string              `json:"state,omitempty"` // Fingerprint of the program's state
+}
+
+// cacheMessage is a history message as hashed for the cache key
+type cacheMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// StateFingerprinter is implemented by GDB handlers that can fingerprint the state of the
+// program being debugged: its executable, where and why it last stopped, and its
+// breakpoints
+type StateFingerprinter interface {
+	// StateFingerprint returns the fingerprint, or "" when there is no program
+	StateFingerprint() string
+}
+
+// NewResponseCache creates an in-memory response cache, whatever backend the cache
+// configuration selects
+func NewResponseCache(cfg config.CacheConfig) *ResponseCache {
+	return &ResponseCache{
+		backend:  store.NewMemory(cfg.MaxSize),
+		enabled:  cfg.Enabled,
+		ttl:      cfg.TTL,
+		maxSize:  cfg.MaxSize,
+		semantic: newSemanticIndex(cfg),
+	}
+}
+
+// NewResponseCacheFromConfig creates a response cache with the backend selected in the
+// cache configuration. The backend is opened even when caching is disabled, so entries
+// can still be managed.
+func NewResponseCacheFromConfig(cfg config.CacheConfig) (*ResponseCache, error) {
+	if err := cfg.Semantic.Validate(); err != nil {
+		return nil, err
+	}
+	backend, err := store.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseCache{backend: backend, enabled: cfg.Enabled, ttl: cfg.TTL, maxSize: cfg.MaxSize, semantic: newSemanticIndex(cfg)}, nil
+}
+
+// Enabled reports whether responses are cached. A nil cache is disabled.
+func (rc *ResponseCache) Enabled() bool {
+	if rc == nil {
+		return false
+	}
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+	return rc.enabled
+}
+
+// Get returns the cached response for a request, or "" if there is none. With semantic
+// matching on for the provider, a request with a reworded message gets the response to
+// the most similar one, and similar is true.
+func (rc *ResponseCache) Get(ctx context.Context, req *ChatRequest, provider, model string) (response string, similar bool) {
+	if !rc.Enabled() {
+		return "", false
+	}
+
+	if response := rc.lookup(rc.generateKey(req, provider, model)); response != "" {
+		return response, false
+	}
+	if rc.semantic == nil {
+		return "", false
+	}
+	threshold := rc.semantic.cfg.ThresholdFor(provider)
+	if threshold == 0 {
+		return "", false
+	}
+	apiKey := ""
+	if rc.apiKey != nil {
+		apiKey = rc.apiKey(ctx)
+	}
+	scope := semanticScope(req, provider, model)
+	key, _ := rc.semantic.match(ctx, apiKey, scope, req.Message, threshold)
+	if key == "" {
+		return "", false
+	}
+	if response := rc.lookup(key); response != "" {
+		return response, true
+	}
+	rc.semantic.remove(scope, key)
+	return "", false
+}
+
+// lookup returns the unexpired response cached under key, or ""
+func (rc *ResponseCache) lookup(key string) string {
+	entry, err := rc.backend.Get(key)
+	if err != nil {
+		return ""
+	}
+	if entry.Expired(time.Now()) {
+		rc.backend.Delete(key)
+		return ""
+	}
+
+	entry.AccessCount++
+	entry.LastAccessed = time.Now()
+	rc.backend.Put(entry)
+	return string(entry.Value)
+}
+
+// Set caches the response to a request. Requests Get looked up semantically are indexed
+// for later reworded ones.
+func (rc *ResponseCache) Set(req *ChatRequest, provider, model, response string) {
+	if !rc.Enabled() {
+		return
+	}
+
+	rc.mutex.RLock()
+	ttl := rc.ttl
+	rc.mutex.RUnlock()
+	now := time.Now()
+	key := rc.generateKey(req, provider, model)
+	evicted, err := rc.backend.Put(&store.Entry{
+		Key:          key,
+		Provider:     provider,
+		Model:        model,
+		Value:        []byte(response),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+		AccessCount:  1,
+		LastAccessed: now,
+	})
+	if err != nil {
+		return
+	}
+	rc.evictions.Add(int64(evicted))
+	if rc.semantic != nil {
+		rc.semantic.add(semanticScope(req, provider, model), req.Message, key, now.Add(ttl))
+	}
+}
+
+// useAPIKeys sets how the semantic index's embedder finds the API key of a request's user
+func (rc *ResponseCache) useAPIKeys(apiKey func(ctx context.Context) string) {
+	rc.apiKey = apiKey
+}
+
+// Clear removes every cached response
+func (rc *ResponseCache) Clear() {
+	rc.backend.Clear()
+	if rc.semantic != nil {
+		rc.semantic.clear()
+	}
+}
+
+// Close releases the cache's backend
+func (rc *ResponseCache) Close() error {
+	return rc.backend.Close()
+}
+
+// generateKey returns the cache key of a request
+func (rc *ResponseCache) generateKey(req *ChatRequest, provider, model string) string {
+	return store.Key(provider, model, store.Hash(cacheRequestOf(req)))
+}
+
+// cacheRequestOf returns the part of a request its cache key is hashed from
+func cacheRequestOf(req *ChatRequest) cacheRequest {
+	hashData := cacheRequest{
+		Message:     req.Message,
+		History:     make([]cacheMessage, len(req.History)),
+		SentContext: make([]map[string]string, len(req.SentContext)),
+		Profile:     req.Profile,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		State:       req.GDBState,
+	}
+	for i, msg := range req.History {
+		hashData.History[i] = cacheMessage{Role: msg.Role, Content: msg.Content}
+	}
+	for i, item := range req.SentContext {
+		hashData.SentContext[i] = map[string]string{
+			"type":        item.Type,
+			"description": item.Description,
+			"content":     item.Content,
+		}
+	}
+	for _, img := range req.Images {
+		hashData.Images = append(hashData.Images, store.Hash(img.Data))
+	}
+	return hashData
+}
+
+// GetStats returns the cache's backend, size and settings
+func (rc *ResponseCache) GetStats() map[string]interface{} {
+	rc.mutex.RLock()
+	stats := map[string]interface{}{
+		"enabled":   rc.enabled,
+		"backend":   rc.backend.Name(),
+		"max_size":  rc.maxSize,
+		"ttl":       rc.ttl.String(),
+		"evictions": rc.evictions.Load(),
+		"semantic":  rc.semantic != nil,
+	}
+	rc.mutex.RUnlock()
+
+	entries, err := rc.backend.List()
+	if err != nil {
+		stats["error"] = err.Error()
+		return stats
+	}
+	count := 0
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.Expired(now) {
+			count++
+		}
+	}
+	stats["entry_count"] = count
+	return stats
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestResponseCacheKeyIncludesState(t *testing.T) {
+	cache := NewResponseCache(config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10})
+	ctx := context.Background()
+	cache.Set(&ChatRequest{Message: "why did it crash?", GDBState: "stopped-in-parse"}, "openai", "gpt-4o", "buf is NULL")
+
+	response, _ := cache.Get(ctx, &ChatRequest{Message: "why did it crash?", GDBState: "stopped-in-parse"}, "openai", "gpt-4o")
+	assert.Equal(t, "buf is NULL", response)
+	response, _ = cache.Get(ctx, &ChatRequest{Message: "why did it crash?", GDBState: "stopped-in-main"}, "openai", "gpt-4o")
+	assert.Empty(t, response, "answers about another program state are not reused")
+	response, _ = cache.Get(ctx, &ChatRequest{Message: "why did it crash?"}, "openai", "gpt-4o")
+	assert.Empty(t, response)
+}
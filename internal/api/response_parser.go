@@ -1,23 +1,43 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/yourusername/gogdbllm/internal/jsonschema"
 	"github.com/yourusername/gogdbllm/internal/logsession"
 )
 
+// maxEmbeddedObjects caps how many JSON objects embedded in prose are checked against the
+// response schema
+const maxEmbeddedObjects = 20
+
+// responseSchema is the compiled schema every JSON and tools envelope response must match
+var responseSchema = jsonschema.MustCompile(respondToolSchema)
+
 // ResponseParser handles parsing of LLM responses
 type ResponseParser struct{}
 
 // ParsedResponse contains the parsed components of an LLM response
 type ParsedResponse struct {
-	Text          string   `json:"text"`
-	GDBCommands   []string `json:"gdbCommands"`
-	WaitForOutput bool     `json:"waitForOutput"`
-	RawResponse   string   `json:"rawResponse"`
-	ParseMethod   string   `json:"parseMethod"`
+	Text              string   `json:"text"`
+	GDBCommands       []string `json:"gdbCommands"`
+	SuggestedCommands []string `json:"suggestedCommands,omitempty"` // Plain envelope mode: commands for the user to run
+	WaitForOutput     bool     `json:"waitForOutput"`
+	RawResponse       string   `json:"rawResponse"`
+	ParseMethod       string   `json:"parseMethod"`
+	Refused           bool     `json:"refused"`
+	// ValidationError is why a response fell back to text: how it failed the response
+	// schema, to be returned to the model when asking it to reformat
+	ValidationError string `json:"validationError,omitempty"`
+}
+
+// jsonCandidate is a part of a response that may be the JSON reply
+type jsonCandidate struct {
+	document []byte
+	method   string // ParseMethod of a response parsed from it
 }
 
 // NewResponseParser creates a new response parser
@@ -25,186 +45,154 @@ func NewResponseParser() *ResponseParser {
 	return &ResponseParser{}
 }
 
-// ParseResponse attempts to parse an LLM response, handling various formats
+// ParseResponse parses an LLM response, validating it against the response schema. The
+// whole response is tried first, then the JSON in its fenced code blocks, then the JSON
+// objects embedded in its prose. A response with none that matches the schema falls back
+// to text, with the schema's complaint about the closest candidate as ValidationError.
 func (rp *ResponseParser) ParseResponse(response string, logger *logsession.SessionLogger) (*ParsedResponse, error) {
 	if logger != nil {
 		logger.LogTerminalOutput(fmt.Sprintf("=== PARSING RESPONSE ===\nLength: %d chars", len(response)))
 	}
 
-	// Try different parsing strategies in order of preference
-
-	// Strategy 1: Try full response as JSON
-	if parsed, err := rp.tryParseFullJSON(response, logger); err == nil {
-		return parsed, nil
-	}
-
-	// Strategy 2: Try extracting JSON from mixed content
-	if parsed, err := rp.tryExtractJSON(response, logger); err == nil {
-		return parsed, nil
-	}
-
-	// Strategy 3: Try reformatting and parsing
-	if parsed, err := rp.tryReformatAndParse(response, logger); err == nil {
-		return parsed, nil
-	}
-
-	// Strategy 4: Fallback to text-only response
-	if logger != nil {
-		logger.LogTerminalOutput("=== USING FALLBACK TEXT RESPONSE ===")
-	}
-
-	return &ParsedResponse{
-		Text:          response,
-		GDBCommands:   []string{},
-		WaitForOutput: false,
-		RawResponse:   response,
-		ParseMethod:   "fallback_text",
-	}, nil
-}
-
-// tryParseFullJSON attempts to parse the entire response as JSON
-func (rp *ResponseParser) tryParseFullJSON(response string, logger *logsession.SessionLogger) (*ParsedResponse, error) {
-	var llmResp LLMResponse
-	err := json.Unmarshal([]byte(response), &llmResp)
+	var validationErr error
+	for _, candidate := range jsonCandidates(response) {
+		llmResp, err := validateResponse(candidate.document)
+		if err != nil {
+			if logger != nil {
+				logger.LogTerminalOutput(fmt.Sprintf("=== SCHEMA VALIDATION FAILED (%s) ===\nError: %v", candidate.method, err))
+			}
+			// JSON that fails the schema says more than text that is not JSON at all
+			if validationErr == nil || (isSyntaxError(validationErr) && !isSyntaxError(err)) {
+				validationErr = err
+			}
+			continue
+		}
 
-	if err != nil {
 		if logger != nil {
-			logger.LogTerminalOutput(fmt.Sprintf("=== FULL JSON PARSE FAILED ===\nError: %v", err))
+			logger.LogTerminalOutput(fmt.Sprintf("=== SCHEMA VALIDATION SUCCESS (%s) ===\nText: %d chars, Commands: %d",
+				candidate.method, len(llmResp.Text), len(llmResp.GDBCommands)))
 		}
-		return nil, err
+		return &ParsedResponse{
+			Text:          llmResp.Text,
+			GDBCommands:   llmResp.Commands(logger),
+			WaitForOutput: llmResp.WaitForOutput,
+			RawResponse:   response,
+			ParseMethod:   candidate.method,
+		}, nil
 	}
 
-	if strings.TrimSpace(llmResp.Text) == "" {
+	// A refusal will not become valid JSON however it is reformatted, so surface it directly
+	if refusal, ok := DetectRefusal(response); ok {
 		if logger != nil {
-			logger.LogTerminalOutput("=== FULL JSON PARSE: EMPTY TEXT FIELD ===")
+			logger.LogTerminalOutput("=== REFUSAL DETECTED ===\nSkipping reformat")
 		}
-		return nil, fmt.Errorf("empty text field")
+		return &ParsedResponse{
+			Text:          refusalMessage(refusal),
+			GDBCommands:   []string{},
+			WaitForOutput: false,
+			RawResponse:   response,
+			ParseMethod:   "refusal",
+			Refused:       true,
+		}, nil
 	}
 
+	// Fallback to text-only response
 	if logger != nil {
-		logger.LogTerminalOutput(fmt.Sprintf("=== FULL JSON PARSE SUCCESS ===\nText: %d chars, Commands: %d",
-			len(llmResp.Text), len(llmResp.GDBCommands)))
+		logger.LogTerminalOutput("=== USING FALLBACK TEXT RESPONSE ===")
 	}
 
 	return &ParsedResponse{
-		Text:          llmResp.Text,
-		GDBCommands:   llmResp.GDBCommands,
-		WaitForOutput: llmResp.WaitForOutput,
-		RawResponse:   response,
-		ParseMethod:   "full_json",
+		Text:            response,
+		GDBCommands:     []string{},
+		WaitForOutput:   false,
+		RawResponse:     response,
+		ParseMethod:     "fallback_text",
+		ValidationError: validationErr.Error(),
 	}, nil
 }
 
-// tryExtractJSON attempts to extract JSON from mixed content
-func (rp *ResponseParser) tryExtractJSON(response string, logger *logsession.SessionLogger) (*ParsedResponse, error) {
-	jsonStr, found := rp.extractJSONFromResponse(response)
-	if !found {
-		if logger != nil {
-			logger.LogTerminalOutput("=== JSON EXTRACTION: NO JSON FOUND ===")
-		}
-		return nil, fmt.Errorf("no JSON found in response")
+// validateResponse checks a JSON document against the response schema and decodes it
+func validateResponse(document []byte) (*LLMResponse, error) {
+	if err := responseSchema.Validate(document); err != nil {
+		return nil, err
 	}
-
 	var llmResp LLMResponse
-	err := json.Unmarshal([]byte(jsonStr), &llmResp)
-	if err != nil {
-		if logger != nil {
-			logger.LogTerminalOutput(fmt.Sprintf("=== EXTRACTED JSON PARSE FAILED ===\nError: %v", err))
-		}
+	if err := json.Unmarshal(document, &llmResp); err != nil {
 		return nil, err
 	}
-
 	if strings.TrimSpace(llmResp.Text) == "" {
-		if logger != nil {
-			logger.LogTerminalOutput("=== EXTRACTED JSON: EMPTY TEXT FIELD ===")
-		}
-		return nil, fmt.Errorf("empty text field in extracted JSON")
-	}
-
-	if logger != nil {
-		logger.LogTerminalOutput(fmt.Sprintf("=== JSON EXTRACTION SUCCESS ===\nExtracted: %d chars -> Text: %d chars, Commands: %d",
-			len(jsonStr), len(llmResp.Text), len(llmResp.GDBCommands)))
+		return nil, &jsonschema.ValidationError{Path: "/text", Message: "must not be empty"}
 	}
+	return &llmResp, nil
+}
 
-	return &ParsedResponse{
-		Text:          llmResp.Text,
-		GDBCommands:   llmResp.GDBCommands,
-		WaitForOutput: llmResp.WaitForOutput,
-		RawResponse:   response,
-		ParseMethod:   "extracted_json",
-	}, nil
+// isSyntaxError reports whether a validation error is that the document is not JSON
+func isSyntaxError(err error) bool {
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	return ok && validationErr.Syntax
 }
 
-// tryReformatAndParse attempts to clean up and reparse the response
-func (rp *ResponseParser) tryReformatAndParse(response string, logger *logsession.SessionLogger) (*ParsedResponse, error) {
-	// Simple cleanup strategies
-	cleaned := strings.TrimSpace(response)
+// jsonCandidates returns the parts of a response that may be its JSON reply: the whole
+// response, the contents of its fenced code blocks and the JSON objects in its prose
+func jsonCandidates(response string) []jsonCandidate {
+	trimmed := strings.TrimSpace(response)
+	candidates := []jsonCandidate{{document: []byte(trimmed), method: "full_json"}}
 
-	// Remove common prefixes/suffixes
-	if strings.HasPrefix(cleaned, "```json") {
-		cleaned = strings.TrimPrefix(cleaned, "```json")
+	for _, block := range fencedBlocks(trimmed) {
+		candidates = append(candidates, jsonCandidate{document: []byte(block), method: "fenced_json"})
 	}
-	if strings.HasSuffix(cleaned, "```") {
-		cleaned = strings.TrimSuffix(cleaned, "```")
-	}
-
-	cleaned = strings.TrimSpace(cleaned)
 
-	var llmResp LLMResponse
-	err := json.Unmarshal([]byte(cleaned), &llmResp)
-	if err != nil {
-		if logger != nil {
-			logger.LogTerminalOutput(fmt.Sprintf("=== REFORMAT PARSE FAILED ===\nError: %v", err))
+	for _, object := range embeddedObjects(trimmed) {
+		if !bytes.Equal(object, []byte(trimmed)) {
+			candidates = append(candidates, jsonCandidate{document: object, method: "extracted_json"})
 		}
-		return nil, err
 	}
+	return candidates
+}
 
-	if strings.TrimSpace(llmResp.Text) == "" {
-		if logger != nil {
-			logger.LogTerminalOutput("=== REFORMAT PARSE: EMPTY TEXT FIELD ===")
+// fencedBlocks returns the contents of a text's ``` code blocks
+func fencedBlocks(text string) []string {
+	var blocks []string
+	for {
+		start := strings.Index(text, "```")
+		if start == -1 {
+			return blocks
 		}
-		return nil, fmt.Errorf("empty text field after reformat")
-	}
-
-	if logger != nil {
-		logger.LogTerminalOutput(fmt.Sprintf("=== REFORMAT PARSE SUCCESS ===\nText: %d chars, Commands: %d",
-			len(llmResp.Text), len(llmResp.GDBCommands)))
+		text = text[start+3:]
+		// Skip the info string, e.g. json
+		if newline := strings.IndexByte(text, '\n'); newline != -1 {
+			text = text[newline+1:]
+		} else {
+			return blocks
+		}
+		end := strings.Index(text, "```")
+		if end == -1 {
+			return append(blocks, strings.TrimSpace(text))
+		}
+		blocks = append(blocks, strings.TrimSpace(text[:end]))
+		text = text[end+3:]
 	}
-
-	return &ParsedResponse{
-		Text:          llmResp.Text,
-		GDBCommands:   llmResp.GDBCommands,
-		WaitForOutput: llmResp.WaitForOutput,
-		RawResponse:   response,
-		ParseMethod:   "reformatted",
-	}, nil
 }
 
-// extractJSONFromResponse extracts the first valid JSON object from a response
-func (rp *ResponseParser) extractJSONFromResponse(response string) (string, bool) {
-	startIdx := strings.Index(response, "{")
-	if startIdx == -1 {
-		return "", false
-	}
+// embeddedObjects returns the JSON objects in a text, decoding from each '{' that does not
+// start inside an object found before
+func embeddedObjects(text string) [][]byte {
+	var objects [][]byte
+	for offset := 0; len(objects) < maxEmbeddedObjects; {
+		start := strings.IndexByte(text[offset:], '{')
+		if start == -1 {
+			break
+		}
+		start += offset
 
-	braceCount := 0
-	for i := startIdx; i < len(response); i++ {
-		switch response[i] {
-		case '{':
-			braceCount++
-		case '}':
-			braceCount--
-			if braceCount == 0 {
-				jsonStr := response[startIdx : i+1]
-
-				// Validate JSON
-				var temp interface{}
-				if json.Unmarshal([]byte(jsonStr), &temp) == nil {
-					return jsonStr, true
-				}
-			}
+		var object json.RawMessage
+		decoder := json.NewDecoder(strings.NewReader(text[start:]))
+		if err := decoder.Decode(&object); err != nil {
+			offset = start + 1
+			continue
 		}
+		objects = append(objects, object)
+		offset = start + int(decoder.InputOffset())
 	}
-
-	return "", false
+	return objects
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/retrieval"
+)
+
+// SourceLocator is implemented by GDB handlers that know where the sources uploaded for
+// the current session were extracted
+type SourceLocator interface {
+	// SourcesDir returns the directory, or "" when no sources were uploaded
+	SourcesDir() string
+}
+
+// attachRetrieved adds the chunks of the session's sources and log most relevant to the
+// message to the request's context, when retrieval is enabled. They are attached once.
+// Previews of the prompt leave them out, as indexing can call the embeddings API.
+func (cp *ChatProcessor) attachRetrieved(ctx context.Context, procCtx *ProcessingContext, req *ChatRequest) {
+	if cp.retriever == nil || procCtx.Logger == nil {
+		return
+	}
+	for _, item := range req.SentContext {
+		if item.Type == "retrieved" {
+			return
+		}
+	}
+
+	session := retrieval.Session{ID: procCtx.Logger.SessionID()}
+	session.LogFile = logsession.LogFilePath(session.ID)
+	if locator, ok := cp.gdbHandler.(SourceLocator); ok {
+		session.SourcesDir = locator.SourcesDir()
+	}
+	apiKey := ""
+	if cp.retrievalCfg.Embedder == config.EmbedderOpenAI {
+		apiKey = cp.settingsManager.APIKeyFor(userFromContext(ctx), "openai")
+	}
+	results, err := cp.retriever.Retrieve(ctx, session, apiKey, req.Message)
+	if err != nil {
+		cp.logStep(procCtx, fmt.Sprintf("Could not retrieve context: %v", err))
+		return
+	}
+	for _, result := range results {
+		req.SentContext = append(req.SentContext, ContextItem{
+			Type:        "retrieved",
+			Description: fmt.Sprintf("%s:%d-%d (relevance %.2f)", result.Source, result.StartLine, result.EndLine, result.Score),
+			Content:     result.Text,
+		})
+	}
+	cp.logStep(procCtx, fmt.Sprintf("Attached %d retrieved chunks", len(results)))
+}
+
+// newRetriever creates the retriever chat.retrieval configures, or returns nil when it is
+// disabled
+func newRetriever(cfg config.RetrievalConfig) *retrieval.Retriever {
+	if !cfg.Enabled {
+		return nil
+	}
+	return retrieval.New(cfg, retrieval.NewEmbedder(cfg.Embedder, cfg.Model, cfg.BaseURL, cfg.Timeout))
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/cache/store"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/retrieval"
+)
+
+// maxPendingEmbeddings caps the embeddings of missed messages kept for Set to index
+const maxPendingEmbeddings = 256
+
+// semanticIndex finds cached responses to reworded requests. It keeps the embedding of
+// each cached request's message, grouped by the rest of the request, so only requests
+// asked in the same state, with the same history and context, are compared. The index
+// is in memory: entries cached before a restart are only matched exactly.
+type semanticIndex struct {
+	cfg      config.SemanticCacheConfig
+	embedder retrieval.Embedder
+	maxSize  int
+
+	mutex   sync.Mutex
+	scopes  map[string][]semanticEntry // By provider, model and the request's state
+	size    int
+	pending map[string][]float32 // Embeddings of missed messages, by scope and message, for Set
+}
+
+// semanticEntry is a cached response's key and the embedding of its message
+type semanticEntry struct {
+	key     string
+	vector  []float32
+	expires time.Time
+}
+
+// newSemanticIndex creates the index chat.cache.semantic configures, or returns nil when
+// it is disabled
+func newSemanticIndex(cfg config.CacheConfig) *semanticIndex {
+	if !cfg.Semantic.Enabled {
+		return nil
+	}
+	return &semanticIndex{
+		cfg:      cfg.Semantic,
+		embedder: retrieval.NewEmbedder(cfg.Semantic.Embedder, cfg.Semantic.Model, cfg.Semantic.BaseURL, cfg.Semantic.Timeout),
+		maxSize:  cfg.MaxSize,
+		scopes:   make(map[string][]semanticEntry),
+		pending:  make(map[string][]float32),
+	}
+}
+
+// match returns the key of the cached response to the request in scope whose message is
+// most similar to message, at least threshold, or "" when there is none. The message's
+// embedding is kept for add.
+func (si *semanticIndex) match(ctx context.Context, apiKey, scope, message string, threshold float64) (string, float64) {
+	vectors, err := si.embedder.Embed(ctx, apiKey, []string{message})
+	if err != nil || len(vectors) != 1 {
+		return "", 0
+	}
+
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	if len(si.pending) >= maxPendingEmbeddings {
+		clear(si.pending)
+	}
+	si.pending[scope+"\x00"+message] = vectors[0]
+
+	best, bestScore := "", threshold
+	now := time.Now()
+	for _, entry := range si.scopes[scope] {
+		if now.After(entry.expires) {
+			continue
+		}
+		if score := retrieval.Similarity(vectors[0], entry.vector); score >= bestScore {
+			best, bestScore = entry.key, score
+		}
+	}
+	return best, bestScore
+}
+
+// add indexes a cached response under the embedding match computed for its message. A
+// message match was not asked about is not indexed, to keep Set from calling the embedder.
+func (si *semanticIndex) add(scope, message, key string, expires time.Time) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	vector, ok := si.pending[scope+"\x00"+message]
+	if !ok {
+		return
+	}
+	delete(si.pending, scope+"\x00"+message)
+
+	entries := si.scopes[scope]
+	for i, entry := range entries {
+		if entry.key == key {
+			entries = append(entries[:i], entries[i+1:]...)
+			si.size--
+			break
+		}
+	}
+	si.scopes[scope] = append(entries, semanticEntry{key: key, vector: vector, expires: expires})
+	si.size++
+	if si.maxSize > 0 && si.size > si.maxSize {
+		si.prune(scope)
+	}
+}
+
+// remove drops the entry for a cached response that is gone
+func (si *semanticIndex) remove(scope, key string) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	entries := si.scopes[scope]
+	for i, entry := range entries {
+		if entry.key == key {
+			si.scopes[scope] = append(entries[:i], entries[i+1:]...)
+			si.size--
+			return
+		}
+	}
+}
+
+// prune drops expired entries, then the oldest of scope while the index is over its
+// size; the caller holds the mutex
+func (si *semanticIndex) prune(scope string) {
+	now := time.Now()
+	for s, entries := range si.scopes {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if now.After(entry.expires) {
+				si.size--
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		if len(kept) == 0 {
+			delete(si.scopes, s)
+			continue
+		}
+		si.scopes[s] = kept
+	}
+	for si.size > si.maxSize && len(si.scopes[scope]) > 0 {
+		si.scopes[scope] = si.scopes[scope][1:]
+		si.size--
+	}
+}
+
+// clear drops every entry
+func (si *semanticIndex) clear() {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	clear(si.scopes)
+	clear(si.pending)
+	si.size = 0
+}
+
+// semanticScope returns the scope a request's message is compared in: its provider,
+// model and everything but the message. Retrieved context is left out, as it changes
+// with the wording of the message.
+func semanticScope(req *ChatRequest, provider, model string) string {
+	state := *req
+	state.Message = ""
+	state.SentContext = nil
+	for _, item := range req.SentContext {
+		if item.Type != "retrieved" {
+			state.SentContext = append(state.SentContext, item)
+		}
+	}
+	return store.Key(provider, model, store.Hash(cacheRequestOf(&state)))
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestSemanticCache(t *testing.T) {
+	cache, err := NewResponseCacheFromConfig(config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 10, Semantic: config.SemanticCacheConfig{
+		Enabled:    true,
+		Threshold:  0.6,
+		Providers:  []string{"anthropic", "openai"},
+		Thresholds: map[string]float64{"openai": 0.99},
+		Embedder:   config.EmbedderHash,
+	}})
+	require.NoError(t, err)
+	ctx := context.Background()
+	stack := ContextItem{Type: "command_output", Description: "bt", Content: "#0 parse_header (buf=0x0)"}
+	asked := &ChatRequest{Message: "Why does parse_header crash?", SentContext: []ContextItem{stack}}
+
+	for _, provider := range []string{"anthropic", "openai", "ollama"} {
+		response, _ := cache.Get(ctx, asked, provider, "m")
+		require.Empty(t, response)
+		cache.Set(asked, provider, "m", "buf is NULL")
+	}
+
+	reworded := &ChatRequest{Message: "why is parse_header crashing", SentContext: []ContextItem{stack,
+		{Type: "retrieved", Description: "src/parse.c:1-40 (relevance 0.80)", Content: "int parse_header(char *buf)"}}}
+	response, similar := cache.Get(ctx, reworded, "anthropic", "m")
+	assert.Equal(t, "buf is NULL", response)
+	assert.True(t, similar)
+
+	response, similar = cache.Get(ctx, asked, "anthropic", "m")
+	assert.Equal(t, "buf is NULL", response)
+	assert.False(t, similar, "the same request matches exactly")
+
+	response, _ = cache.Get(ctx, reworded, "openai", "m")
+	assert.Empty(t, response, "openai's threshold is higher")
+	response, _ = cache.Get(ctx, reworded, "ollama", "m")
+	assert.Empty(t, response, "ollama is only matched exactly")
+	response, _ = cache.Get(ctx, &ChatRequest{Message: reworded.Message}, "anthropic", "m")
+	assert.Empty(t, response, "requests in another state do not match")
+	response, _ = cache.Get(ctx, &ChatRequest{Message: "what does info frame show?", SentContext: []ContextItem{stack}}, "anthropic", "m")
+	assert.Empty(t, response, "unrelated questions do not match")
+
+	cache.Clear()
+	response, _ = cache.Get(ctx, reworded, "anthropic", "m")
+	assert.Empty(t, response)
+
+	_, err = NewResponseCacheFromConfig(config.CacheConfig{Semantic: config.SemanticCacheConfig{Enabled: true, Threshold: 1.5, Embedder: config.EmbedderHash}})
+	assert.Error(t, err)
+}
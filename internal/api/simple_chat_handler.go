@@ -3,16 +3,37 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/gdb"
 	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/prompts"
 	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/tracing"
 )
 
 // SimpleChatHandler provides a clean, maintainable chat interface
 type SimpleChatHandler struct {
 	processor *ChatProcessor
+	artifacts *ArtifactStore
+	inflight  *inflightChats
+	queue     *ChatQueue
+	branches  *BranchStore
+	history   *metricsHistory // nil when the metrics history is disabled
+	events    *events.Bus     // nil publishes nothing
+
+	cacheAdmins map[string]bool
+	adminsMutex sync.RWMutex // Guards cacheAdmins, which reloads replace
+	warmer      cacheWarmer
 }
 
 // NewSimpleChatHandler creates a new simple chat handler
@@ -20,10 +41,27 @@ func NewSimpleChatHandler(
 	settingsManager *settings.Manager,
 	loggerHolder LoggerHolder,
 	gdbHandler GDBCommandHandler,
+	featureManager *features.Manager,
+	chatCfg config.ChatConfig,
+	responseCache *ResponseCache,
+	promptEngine *prompts.Engine,
 ) *SimpleChatHandler {
-	return &SimpleChatHandler{
-		processor: NewChatProcessor(settingsManager, loggerHolder, gdbHandler),
+	processor := NewChatProcessor(settingsManager, loggerHolder, gdbHandler, featureManager, chatCfg, responseCache, promptEngine)
+	sch := &SimpleChatHandler{
+		processor:   processor,
+		artifacts:   NewArtifactStore(chatCfg.Output),
+		inflight:    newInflightChats(),
+		queue:       NewChatQueue(chatCfg.Queue),
+		branches:    NewBranchStore(),
+		history:     newMetricsHistory(chatCfg.Metrics.History, processor.metrics),
+		cacheAdmins: adminSet(chatCfg.Cache.Admins),
+	}
+	if loggerHolder != nil {
+		loggerHolder.OnSessionEnd(func(sessionID string) {
+			sch.branches.Release(sessionKey(sessionID))
+		})
 	}
+	return sch
 }
 
 // HandleChat handles incoming chat requests with the new architecture
@@ -32,6 +70,9 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
 
 	// Parse request
 	var chatReq ChatRequest
@@ -39,9 +80,26 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if _, err := sch.processor.prompts.Profile(chatReq.Profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	provider := sch.processor.settingsManager.GetUserSettings(userFromContext(r.Context())).Provider
+	if err := chatReq.ValidateOverrides(provider); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	if chatReq.TerminalLines < 0 {
+		http.Error(w, "terminalLines must not be negative", http.StatusBadRequest)
+		return
+	}
+	if err := sch.processor.ResolveAttachments(r.Context(), &chatReq); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
 
 	// Log user input
-	logger := sch.processor.loggerHolder.Get()
+	logger := sch.processor.requestLogger(r.Context())
 	if logger != nil {
 		logContext := make([]logsession.ContextItem, len(chatReq.SentContext))
 		for i, apiItem := range chatReq.SentContext {
@@ -54,9 +112,32 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		logger.LogUserChat(logContext, chatReq.Message)
 	}
 
-	// Process the chat request using the new architecture
-	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second) // Extended timeout for GDB operations
-	defer cancel()
+	// Process the chat request using the new architecture; the user may cancel it meanwhile
+	ctx, done := sch.inflight.start(r.Context(), userFromContext(r.Context()), chatReq.RequestID)
+	defer done()
+
+	// Wait for earlier requests of the session, so their GDB commands and output don't interleave
+	queuedAt := time.Now()
+	_, queueSpan := tracing.Start(ctx, "chat.queue")
+	release, err := sch.queue.Acquire(ctx, sch.queueSession(r.Context()))
+	queueSpan.RecordError(err)
+	queueSpan.End()
+	if err != nil {
+		if cancelled(ctx) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ChatResponse{Cancelled: true})
+			return
+		}
+		writeQueueError(w, err)
+		return
+	}
+	defer release()
+	queued := time.Since(queuedAt)
+	if logger != nil && queued >= time.Millisecond {
+		logger.LogEvent("INFO", "chat.queued", "Chat request waited for earlier requests", map[string]interface{}{
+			"chat.queued_ms": queued.Milliseconds(),
+		})
+	}
 
 	result, err := sch.processor.ProcessChat(ctx, &chatReq)
 	if err != nil {
@@ -67,6 +148,12 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// A session over its budget gets no answer at all
+	if errors.Is(result.Error, ErrBudgetExceeded) {
+		http.Error(w, result.Error.Error(), appErrors.StatusCode(result.Error))
+		return
+	}
+
 	// Handle processing errors (non-fatal)
 	if result.Error != nil {
 		if logger != nil {
@@ -75,13 +162,43 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		// Continue with partial results
 	}
 
+	// Long responses are returned a page at a time
+	page, err := sch.artifacts.Paginate(userFromContext(r.Context()), result.FinalText)
+	if err != nil {
+		http.Error(w, "Storing chat response failed", http.StatusInternalServerError)
+		if logger != nil {
+			logger.LogError(err, "Storing chat response")
+		}
+		return
+	}
+
 	// Log the final response
 	if logger != nil {
-		logger.LogLLMResponse(result.FinalText)
+		logResponsePage(logger, page)
+	}
+	if !result.Cancelled {
+		sch.processor.shareChat(r.Context(), &chatReq, page.Text)
+		sch.publishChat(r.Context(), &chatReq, result)
 	}
 
 	// Send response
-	chatResp := ChatResponse{Response: result.FinalText}
+	chatResp := ChatResponse{
+		Response:          page.Text,
+		Model:             result.Model,
+		Refused:           result.Refused,
+		Envelope:          result.Envelope,
+		SuggestedCommands: result.SuggestedCmds,
+		Cancelled:         result.Cancelled,
+		QueuedMs:          queued.Milliseconds(),
+		Cost:              result.Cost,
+	}
+	if result.Usage != (TokenUsage{}) {
+		chatResp.Usage = &result.Usage
+	}
+	if page.Truncated() {
+		chatResp.NextPage = page.NextPage
+		chatResp.TotalSize = page.Total
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(chatResp); err != nil {
 		if logger != nil {
@@ -89,3 +206,170 @@ func (sch *SimpleChatHandler) HandleChat(w http.ResponseWriter, r *http.Request)
 		}
 	}
 }
+
+// ObserveChatRequest asks the assistant to diagnose a running process from sampled backtraces
+type ObserveChatRequest struct {
+	ChatRequest
+	PID      int     `json:"pid"`
+	Duration float64 `json:"duration"` // Seconds
+	Interval float64 `json:"interval"` // Seconds between samples
+}
+
+// defaultObserveQuestion is asked when an observe request has no message
+const defaultObserveQuestion = "Based on the sampled backtraces, where is this process spending its time? " +
+	"Is it hung, deadlocked, busy or waiting, and what should I look at next?"
+
+// HandleObserve samples a running process in observe mode and asks the LLM to diagnose a
+// hang or latency problem from the report, e.g. POST /api/chat/observe {"pid": 1234}.
+// The response holds the diagnosis and the report.
+func (sch *SimpleChatHandler) HandleObserve(w http.ResponseWriter, r *http.Request) {
+	if !authorizeChat(w, r, sch.processor.gdbHandler) {
+		return
+	}
+
+	var req ObserveChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := sch.processor.gdbHandler.Observe(r.Context(), gdb.NewObserveOptions(req.PID, req.Duration, req.Interval))
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+
+	chatReq := req.ChatRequest
+	if chatReq.Message == "" {
+		chatReq.Message = defaultObserveQuestion
+	}
+	if err := sch.processor.ResolveAttachments(r.Context(), &chatReq); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+	chatReq.SentContext = append(chatReq.SentContext, ContextItem{
+		Type:        "observe_report",
+		Description: fmt.Sprintf("Backtraces of process %d sampled %d times over %s", report.PID, report.Samples, report.Duration),
+		Content:     report.String(),
+	})
+
+	ctx, done := sch.inflight.start(r.Context(), userFromContext(r.Context()), chatReq.RequestID)
+	defer done()
+	writeCancelled := func() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"cancelled": true,
+			"report":    report,
+		})
+	}
+	release, err := sch.queue.Acquire(ctx, sch.queueSession(r.Context()))
+	if err != nil {
+		if cancelled(ctx) {
+			writeCancelled()
+			return
+		}
+		writeQueueError(w, err)
+		return
+	}
+	defer release()
+
+	result, err := sch.processor.ProcessChat(ctx, &chatReq)
+	if err == nil && result.Cancelled {
+		writeCancelled()
+		return
+	}
+	if err != nil || result.Error != nil {
+		if err == nil {
+			err = result.Error
+		}
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrBudgetExceeded) {
+			status = appErrors.StatusCode(err)
+		}
+		http.Error(w, "Diagnosis failed: "+err.Error(), status)
+		return
+	}
+
+	page, err := sch.artifacts.Paginate(userFromContext(r.Context()), result.FinalText)
+	if err != nil {
+		http.Error(w, "Storing chat response failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"response": page.Text,
+		"nextPage": page.NextPage,
+		"refused":  result.Refused,
+		"report":   report,
+	})
+}
+
+// HandlePage returns a further page of a long chat response, e.g.
+// GET /api/chat/pages/{token} with the nextPage token of the previous page
+func (sch *SimpleChatHandler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	page, err := sch.artifacts.Page(userFromContext(r.Context()), mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// queueSession returns the queue key of a chat request: the current debugging session, or
+// the user before any session has started
+func (sch *SimpleChatHandler) queueSession(ctx context.Context) string {
+	return sch.processor.session(ctx)
+}
+
+// writeQueueError rejects a request that could not be queued, asking the client to retry
+func writeQueueError(w http.ResponseWriter, err error) {
+	if errors.Is(err, appErrors.ErrTooManyRequests) {
+		w.Header().Set("Retry-After", "5")
+	}
+	http.Error(w, err.Error(), appErrors.StatusCode(err))
+}
+
+// logResponsePage logs a response, or only its first page and full size when it was
+// paginated, so session logs stay small
+func logResponsePage(logger *logsession.SessionLogger, page ResponsePage) {
+	if !page.Truncated() {
+		logger.LogLLMResponse(page.Text)
+		return
+	}
+	logger.LogEvent("INFO", "llm.response", "Received response from LLM", map[string]interface{}{
+		"llm.response.body":      page.Text,
+		"llm.response.size":      page.Total,
+		"llm.response.next_page": page.NextPage,
+	})
+}
+
+// HandlePromptPreview returns the composition of the prompt the next chat request would
+// send, with estimated token counts per segment. The body is the same as for HandleChat.
+func (sch *SimpleChatHandler) HandlePromptPreview(w http.ResponseWriter, r *http.Request) {
+	var chatReq ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&chatReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := sch.processor.ResolveAttachments(r.Context(), &chatReq); err != nil {
+		http.Error(w, err.Error(), appErrors.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sch.processor.PreviewPrompt(r.Context(), &chatReq))
+}
+
+// HandleMetrics returns per-provider request, error and refusal counts for the chat
+// pipeline, and the response cache's statistics
+func (sch *SimpleChatHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"timestamp":        time.Now(),
+		"provider_metrics": sch.processor.GetMetrics(),
+		"cache_stats":      sch.processor.cache.GetStats(),
+	})
+}
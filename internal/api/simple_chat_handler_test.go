@@ -0,0 +1,45 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+func TestSessionEndReleasesState(t *testing.T) {
+	// Session logs are written relative to the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	holder := logsession.NewLoggerHolder()
+	chatCfg := config.ChatConfig{Attachments: config.AttachmentsConfig{Directory: "attachments"}}
+	sch := NewSimpleChatHandler(nil, holder, nil, nil, chatCfg, nil, nil)
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	require.NoError(t, sch.branches.Add(sessionKey("s1"), &Branch{}))
+	sch.processor.costs.Record("s1", "openai", "gpt-4o", TokenUsage{InputTokens: 100})
+	_, err = sch.processor.attachments.Save(sessionKey("s1"), "crash.log", []byte("SIGSEGV\n"))
+	require.NoError(t, err)
+	require.Len(t, sch.processor.costs.Sessions(), 1)
+	require.DirExists(t, sch.processor.attachments.sessionDir(sessionKey("s1")))
+
+	// The next session ends this one
+	next, err := logsession.NewSessionLogger("s2")
+	require.NoError(t, err)
+	holder.Set(next)
+
+	assert.Empty(t, sch.branches.List(sessionKey("s1")))
+	assert.Empty(t, sch.branches.sessions)
+	assert.Empty(t, sch.processor.costs.Sessions())
+	assert.NoDirExists(t, sch.processor.attachments.sessionDir(sessionKey("s1")))
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+
+	"github.com/yourusername/gogdbllm/internal/features"
+)
+
+// ChatStreamer is implemented by GDB handlers that can pass a chat response on to the
+// user who asked while it is being received
+type ChatStreamer interface {
+	StreamChat(user, requestID, delta string, done bool)
+}
+
+// chatStream returns the function passing a request's responses on to the user as they
+// arrive, or nil when the streaming feature is off for the session or nothing can
+// receive the stream
+func (cp *ChatProcessor) chatStream(ctx context.Context, procCtx *ProcessingContext) func(delta string, done bool) {
+	streamer, ok := cp.gdbHandler.(ChatStreamer)
+	if !ok || !procCtx.Features.Enabled(features.FlagStreaming) {
+		return nil
+	}
+	user := userFromContext(ctx)
+	requestID := procCtx.RequestID
+	if procCtx.OriginalReq != nil && procCtx.OriginalReq.RequestID != "" {
+		requestID = procCtx.OriginalReq.RequestID
+	}
+	return func(delta string, done bool) {
+		streamer.StreamChat(user, requestID, delta, done)
+	}
+}
+
+// ChatSharer is implemented by GDB handlers that can show a chat turn to every member of
+// the debugging session, who share its chat
+type ChatSharer interface {
+	ShareChat(user, requestID, message, response string)
+}
+
+// shareChat shows a finished chat turn to the session's members
+func (cp *ChatProcessor) shareChat(ctx context.Context, req *ChatRequest, response string) {
+	if sharer, ok := cp.gdbHandler.(ChatSharer); ok {
+		sharer.ShareChat(userFromContext(ctx), req.RequestID, req.Message, response)
+	}
+}
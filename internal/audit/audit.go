@@ -0,0 +1,233 @@
+// Package audit keeps the audit trail: who ran each GDB command, the user or the LLM on
+// their behalf, and who changed which settings. Unlike the session logs, which are for
+// debugging, the trail is only ever appended to, and each entry is chained to the one
+// before by a SHA-256 hash, so editing or deleting an entry is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the trail's file in its directory
+const FileName = "audit.jsonl"
+
+// Actions audited
+const (
+	ActionCommand  = "gdb.command"     // A GDB command was run
+	ActionSettings = "settings.change" // A user's settings were saved
+)
+
+// Actors of audited actions
+const (
+	ActorUser = "user" // The user, e.g. typing in the terminal
+	ActorLLM  = "llm"  // The LLM, for the user whose chat request it answered
+	ActorHook = "hook" // A hook the user set, on an event of the session
+)
+
+// Entry is an audited action
+type Entry struct {
+	Seq       int64             `json:"seq"` // Position in the trail, from 1
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor"`
+	User      string            `json:"user,omitempty"` // "" when authentication is disabled
+	Session   string            `json:"session,omitempty"`
+	RequestID string            `json:"request_id,omitempty"` // The HTTP request's X-Request-ID
+	Command   string            `json:"command,omitempty"`
+	Changes   map[string]Change `json:"changes,omitempty"` // Settings changed, by name
+	Error     string            `json:"error,omitempty"`   // Why the action failed
+	Prev      string            `json:"prev"`              // Hash of the previous entry; "" for the first
+	Hash      string            `json:"hash"`
+}
+
+// Change is a setting's value before and after a change. Secrets are never recorded,
+// only that they changed.
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Filter selects entries; zero fields match every entry
+type Filter struct {
+	Session string
+	User    string
+	Actor   string
+	Action  string
+	From    time.Time // Entries at or after From
+	To      time.Time // Entries before To
+	Limit   int       // Keeps only the last Limit matching entries
+}
+
+// match reports whether an entry is selected by the filter
+func (f Filter) match(entry Entry) bool {
+	return (f.Session == "" || entry.Session == f.Session) &&
+		(f.User == "" || entry.User == f.User) &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.From.IsZero() || !entry.Time.Before(f.From)) &&
+		(f.To.IsZero() || entry.Time.Before(f.To))
+}
+
+// Trail is an audit trail in a directory. A nil Trail records nothing, so callers need
+// not check whether auditing is enabled.
+type Trail struct {
+	path string
+
+	mutex  sync.Mutex
+	loaded bool // Whether seq and last were read from the file
+	seq    int64
+	last   string // Hash of the last entry
+}
+
+// New returns a trail kept in dir, which is created when the first entry is recorded
+func New(dir string) *Trail {
+	return &Trail{path: filepath.Join(dir, FileName)}
+}
+
+// Record appends an entry to the trail, setting its sequence number, hash and, when it
+// is zero, its time
+func (t *Trail) Record(entry Entry) error {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.loaded {
+		if err := t.load(); err != nil {
+			return err
+		}
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	entry.Seq = t.seq + 1
+	entry.Prev = t.last
+	entry.Hash = hash(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	t.seq, t.last = entry.Seq, entry.Hash
+	return nil
+}
+
+// load reads the sequence number and hash of the last entry written before, e.g. by a
+// previous run; the caller holds the mutex
+func (t *Trail) load() error {
+	err := t.read(func(entry Entry) {
+		t.seq, t.last = entry.Seq, entry.Hash
+	})
+	if err != nil {
+		return err
+	}
+	t.loaded = true
+	return nil
+}
+
+// Query returns the entries selected by filter, oldest first
+func (t *Trail) Query(filter Filter) ([]Entry, error) {
+	if t == nil {
+		return nil, nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entries := []Entry{}
+	err := t.read(func(entry Entry) {
+		if !filter.match(entry) {
+			return
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) > filter.Limit {
+			entries = entries[1:]
+		}
+	})
+	return entries, err
+}
+
+// Verify checks that no entry was changed, removed or inserted since it was recorded,
+// returning how many entries it checked
+func (t *Trail) Verify() (int, error) {
+	if t == nil {
+		return 0, nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	count, prev := 0, ""
+	var broken error
+	err := t.read(func(entry Entry) {
+		if broken != nil {
+			return
+		}
+		count++
+		switch {
+		case entry.Seq != int64(count):
+			broken = fmt.Errorf("audit entry %d is out of sequence (expected %d)", entry.Seq, count)
+		case entry.Prev != prev || entry.Hash != hash(entry):
+			broken = fmt.Errorf("audit entry %d does not match its hash", entry.Seq)
+		}
+		prev = entry.Hash
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, broken
+}
+
+// read calls fn with each entry in the file; a missing file holds none
+func (t *Trail) read(fn func(Entry)) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("invalid audit entry on line %d: %w", line, err)
+		}
+		fn(entry)
+	}
+	return scanner.Err()
+}
+
+// hash returns the hash chaining an entry to the one before: the SHA-256 of the entry
+// encoded without its own hash
+func hash(entry Entry) string {
+	entry.Hash = ""
+	data, _ := json.Marshal(entry)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailRecordAndQuery(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "audit")
+	trail := New(dir)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	entries, err := trail.Query(Filter{})
+	require.NoError(t, err, "a trail that was never written to is empty")
+	assert.Empty(t, entries)
+
+	require.NoError(t, trail.Record(Entry{Time: start, Action: ActionCommand, Actor: ActorUser, User: "alice", Session: "s1", Command: "break main"}))
+	require.NoError(t, trail.Record(Entry{Time: start.Add(time.Minute), Action: ActionCommand, Actor: ActorLLM, User: "alice", Session: "s1", RequestID: "r1", Command: "bt"}))
+	require.NoError(t, trail.Record(Entry{Time: start.Add(2 * time.Minute), Action: ActionSettings, Actor: ActorUser, User: "bob",
+		Changes: map[string]Change{"model": {From: "gpt-4o", To: "gpt-4o-mini"}}}))
+
+	// A trail opened again, e.g. after a restart, continues the chain
+	trail = New(dir)
+	require.NoError(t, trail.Record(Entry{Time: start.Add(3 * time.Minute), Action: ActionCommand, Actor: ActorUser, User: "bob", Session: "s2", Command: "run"}))
+
+	entries, err = trail.Query(Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for i, entry := range entries {
+		assert.EqualValues(t, i+1, entry.Seq)
+		if i > 0 {
+			assert.Equal(t, entries[i-1].Hash, entry.Prev)
+		}
+	}
+
+	entries, err = trail.Query(Filter{Session: "s1", Actor: ActorLLM})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "r1", entries[0].RequestID)
+
+	entries, err = trail.Query(Filter{User: "bob", From: start.Add(2 * time.Minute), To: start.Add(3 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "gpt-4o-mini", entries[0].Changes["model"].To)
+
+	entries, err = trail.Query(Filter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "the limit keeps the last entries")
+	assert.Equal(t, "run", entries[1].Command)
+
+	count, err := trail.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	info, err := os.Stat(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestTrailVerifyDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	trail := New(dir)
+	for _, command := range []string{"break main", "run", "bt"} {
+		require.NoError(t, trail.Record(Entry{Action: ActionCommand, Actor: ActorUser, User: "alice", Command: command}))
+	}
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(strings.TrimSpace(string(data)), "\n")
+
+	// An edited entry
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), `"run"`, `"kill"`, 1)), 0600))
+	_, err = trail.Verify()
+	assert.ErrorContains(t, err, "entry 2 does not match")
+
+	// A removed entry
+	require.NoError(t, os.WriteFile(path, []byte(lines[0]+lines[2]), 0600))
+	_, err = trail.Verify()
+	assert.ErrorContains(t, err, "out of sequence")
+
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	count, err := trail.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	var nilTrail *Trail
+	assert.NoError(t, nilTrail.Record(Entry{Command: "run"}), "a nil trail records nothing")
+}
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/middleware"
+)
+
+// Authentication modes
+const (
+	ModeNone     = "none"
+	ModeToken    = "token"
+	ModePassword = "password"
+)
+
+// SessionCookieName is the cookie carrying the session token after login
+const SessionCookieName = "gogdbllm_session"
+
+// tokenUser is the user name assigned to requests authenticated with the shared token
+const tokenUser = "token"
+
+// publicPaths are reachable without authentication so the UI can load and log in
+var publicPaths = map[string]bool{
+	"/":            true,
+	"/health":      true,
+	"/healthz":     true,
+	"/readyz":      true,
+	"/auth/login":  true,
+	"/auth/logout": true,
+	"/auth/status": true,
+	"/api/triage":  true, // Checks its integrations' tokens itself, as do its job pages
+
+	// The API's description, for integrators to discover it
+	"/api/v1/openapi.json": true,
+	"/api/v1/docs":         true,
+}
+
+// publicPrefixes are path prefixes reachable without authentication
+var publicPrefixes = []string{"/static/", "/api/triage/"}
+
+type contextKey struct{}
+
+// session is an authenticated browser session
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// Authenticator guards the server with a static token or username/password logins.
+// Successful logins receive a session cookie, which also authenticates the WebSocket upgrade.
+type Authenticator struct {
+	mode         string
+	token        string
+	users        map[string]string
+	roles        map[string]string
+	defaultRole  string
+	sessionTTL   time.Duration
+	cookieSecure bool
+
+	sessions map[string]session
+	mutex    sync.Mutex
+}
+
+// NewAuthenticator creates an authenticator from the auth configuration
+func NewAuthenticator(cfg *config.Config) (*Authenticator, error) {
+	a := &Authenticator{
+		mode:         strings.ToLower(cfg.Auth.Mode),
+		token:        cfg.Auth.Token,
+		users:        cfg.Auth.Users,
+		roles:        make(map[string]string, len(cfg.Auth.Roles)),
+		defaultRole:  strings.ToLower(cfg.Auth.DefaultRole),
+		sessionTTL:   cfg.Auth.SessionTTL,
+		cookieSecure: cfg.Auth.CookieSecure,
+		sessions:     make(map[string]session),
+	}
+	if a.mode == "" {
+		a.mode = ModeNone
+	}
+	if a.sessionTTL <= 0 {
+		a.sessionTTL = 24 * time.Hour
+	}
+	if a.defaultRole == "" {
+		a.defaultRole = RoleAdmin
+	}
+	for user, role := range cfg.Auth.Roles {
+		a.roles[strings.ToLower(user)] = strings.ToLower(role)
+	}
+	if err := validateRoles(a.roles, a.defaultRole); err != nil {
+		return nil, err
+	}
+
+	switch a.mode {
+	case ModeNone:
+	case ModeToken:
+		if a.token == "" {
+			return nil, fmt.Errorf("%w: auth.mode is token but auth.token is empty", appErrors.ErrInvalidConfiguration)
+		}
+	case ModePassword:
+		if len(a.users) == 0 {
+			return nil, fmt.Errorf("%w: auth.mode is password but auth.users is empty", appErrors.ErrInvalidConfiguration)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown auth.mode %q", appErrors.ErrInvalidConfiguration, cfg.Auth.Mode)
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether requests must be authenticated
+func (a *Authenticator) Enabled() bool {
+	return a.mode != ModeNone
+}
+
+// Mode returns the configured authentication mode
+func (a *Authenticator) Mode() string {
+	return a.mode
+}
+
+// Middleware rejects unauthenticated requests to everything except the UI shell and login
+// endpoints, and requests needing a role the user lacks (see requiredRole)
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() || isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := a.authenticate(r)
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		role := a.Role(user)
+		if required := requiredRole(r); !Allows(role, required) {
+			writeJSONError(w, http.StatusForbidden, fmt.Sprintf("This requires the %s role; %s has the %s role", required, user, role))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, user)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, roleKey{}, role)))
+	})
+}
+
+// UserFromContext returns the authenticated user stored by Middleware
+func UserFromContext(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(contextKey{}).(string)
+	return user, ok
+}
+
+// LoginRequest is the body of a login request. Token mode uses Token; password mode uses
+// Username and Password.
+type LoginRequest struct {
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// HandleLogin verifies credentials and starts a cookie session
+func (a *Authenticator) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if !a.Enabled() {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, ok := a.checkCredentials(req)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Unable to create session")
+		return
+	}
+
+	expires := time.Now().Add(a.sessionTTL)
+	a.mutex.Lock()
+	a.pruneSessionsLocked()
+	a.sessions[token] = session{user: user, expires: expires}
+	a.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   a.cookieSecure || middleware.IsHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "user": user})
+}
+
+// HandleLogout ends the cookie session
+func (a *Authenticator) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		a.mutex.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mutex.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.cookieSecure || middleware.IsHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// HandleStatus reports the authentication mode and whether the caller is logged in
+func (a *Authenticator) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{
+		"enabled":       a.Enabled(),
+		"mode":          a.mode,
+		"authenticated": !a.Enabled(),
+	}
+	if a.Enabled() {
+		if user, ok := a.authenticate(r); ok {
+			status["authenticated"] = true
+			status["user"] = user
+			status["role"] = a.Role(user)
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// authenticate checks the session cookie, then an "Authorization: Bearer" token in token mode
+func (a *Authenticator) authenticate(r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if user, ok := a.sessionUser(cookie.Value); ok {
+			return user, true
+		}
+	}
+
+	if a.mode == ModeToken {
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && a.tokenMatches(bearer) {
+			return tokenUser, true
+		}
+	}
+
+	return "", false
+}
+
+// AuthenticateToken returns the user of a token presented outside HTTP, e.g. by a debug
+// adapter client: the value of a login session's cookie, or the shared token in token
+// mode. Without authentication every token is accepted for the empty user.
+func (a *Authenticator) AuthenticateToken(token string) (string, bool) {
+	if !a.Enabled() {
+		return "", true
+	}
+	if user, ok := a.sessionUser(token); ok {
+		return user, true
+	}
+	if a.mode == ModeToken && a.tokenMatches(token) {
+		return tokenUser, true
+	}
+	return "", false
+}
+
+// sessionUser returns the user of an unexpired login session
+func (a *Authenticator) sessionUser(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	s, ok := a.sessions[token]
+	if ok && time.Now().After(s.expires) {
+		delete(a.sessions, token)
+		return "", false
+	}
+	return s.user, ok
+}
+
+// checkCredentials validates a login request against the configured mode
+func (a *Authenticator) checkCredentials(req LoginRequest) (string, bool) {
+	switch a.mode {
+	case ModeToken:
+		if a.tokenMatches(req.Token) {
+			return tokenUser, true
+		}
+	case ModePassword:
+		// Viper lower-cases map keys, so usernames are case-insensitive
+		username := strings.ToLower(strings.TrimSpace(req.Username))
+		if hash, ok := a.users[username]; ok && VerifyPassword(req.Password, hash) {
+			return username, true
+		}
+	}
+	return "", false
+}
+
+// tokenMatches compares a presented token with the configured token in constant time
+func (a *Authenticator) tokenMatches(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+// pruneSessionsLocked drops expired sessions; the caller must hold the mutex
+func (a *Authenticator) pruneSessionsLocked() {
+	now := time.Now()
+	for token, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+// isPublicPath reports whether a path is reachable without authentication
+func isPublicPath(path string) bool {
+	if publicPaths[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSessionToken returns a random 256-bit session token
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeJSONError writes an error response in the shape used by the error middleware
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, appErrors.ErrorResponse{Success: false, Error: message, Code: status})
+}
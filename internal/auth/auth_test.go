@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// newTestAuthenticator builds an authenticator from an auth config
+func newTestAuthenticator(t *testing.T, authCfg config.AuthConfig) *Authenticator {
+	t.Helper()
+	a, err := NewAuthenticator(&config.Config{Auth: authCfg})
+	require.NoError(t, err)
+	return a
+}
+
+// protectedHandler echoes the authenticated user
+var protectedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	user, _ := UserFromContext(r.Context())
+	w.Write([]byte(user))
+})
+
+func TestPasswordHash(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "pbkdf2-sha256$"))
+	assert.True(t, VerifyPassword("hunter2", hash))
+	assert.False(t, VerifyPassword("hunter3", hash))
+	assert.False(t, VerifyPassword("hunter2", "plaintext"))
+}
+
+func TestNewAuthenticatorValidation(t *testing.T) {
+	_, err := NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "token"}})
+	assert.Error(t, err)
+
+	_, err = NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "password"}})
+	assert.Error(t, err)
+
+	_, err = NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "oauth"}})
+	assert.Error(t, err)
+
+	a := newTestAuthenticator(t, config.AuthConfig{})
+	assert.False(t, a.Enabled())
+}
+
+func TestMiddlewareToken(t *testing.T) {
+	a := newTestAuthenticator(t, config.AuthConfig{Mode: "token", Token: "s3cret"})
+	handler := a.Middleware(protectedHandler)
+
+	// Public paths stay reachable
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/js/app.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, path := range []string{"/api/chat", "/ws", "/upload"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chat", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/chat", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "token", rec.Body.String())
+}
+
+func TestPasswordLoginSession(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+	require.NoError(t, err)
+	a := newTestAuthenticator(t, config.AuthConfig{Mode: "password", Users: map[string]string{"alice": hash}})
+	handler := a.Middleware(protectedHandler)
+
+	rec := httptest.NewRecorder()
+	a.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"alice","password":"nope"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"Alice","password":"hunter2"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice", rec.Body.String())
+
+	// Logging out invalidates the session
+	logoutReq := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	logoutReq.AddCookie(cookies[0])
+	a.HandleLogout(httptest.NewRecorder(), logoutReq)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a := newTestAuthenticator(t, config.AuthConfig{Mode: "token", Token: "s3cret"})
+	user, ok := a.AuthenticateToken("s3cret")
+	assert.True(t, ok)
+	assert.Equal(t, tokenUser, user)
+	_, ok = a.AuthenticateToken("guess")
+	assert.False(t, ok)
+
+	// A login session's cookie value works too
+	rec := httptest.NewRecorder()
+	a.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"token":"s3cret"}`)))
+	require.Len(t, rec.Result().Cookies(), 1)
+	user, ok = a.AuthenticateToken(rec.Result().Cookies()[0].Value)
+	assert.True(t, ok)
+	assert.Equal(t, tokenUser, user)
+
+	_, ok = newTestAuthenticator(t, config.AuthConfig{}).AuthenticateToken("")
+	assert.True(t, ok)
+}
+
+func TestMiddlewareRoles(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+	require.NoError(t, err)
+	users := map[string]string{"alice": hash, "bob": hash, "carol": hash}
+	a := newTestAuthenticator(t, config.AuthConfig{Mode: "password", Users: users, DefaultRole: "debugger",
+		Roles: map[string]string{"Alice": "viewer", "carol": "admin"}})
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RoleFromContext(r.Context())))
+	}))
+
+	cookies := make(map[string]*http.Cookie)
+	for user := range users {
+		rec := httptest.NewRecorder()
+		a.HandleLogin(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"`+user+`","password":"hunter2"}`)))
+		require.Len(t, rec.Result().Cookies(), 1)
+		cookies[user] = rec.Result().Cookies()[0]
+	}
+	status := func(user, method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(cookies[user])
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Viewers watch and read
+	assert.Equal(t, http.StatusOK, status("alice", http.MethodGet, "/ws"))
+	assert.Equal(t, http.StatusOK, status("alice", http.MethodGet, "/api/gdb/output"))
+	assert.Equal(t, http.StatusOK, status("alice", http.MethodPost, "/api/chat/prompt"))
+	assert.Equal(t, http.StatusForbidden, status("alice", http.MethodPost, "/api/chat"))
+	assert.Equal(t, http.StatusForbidden, status("alice", http.MethodPost, "/api/v1/debugger/memory"))
+
+	// gRPC calls are all POSTs; viewers may only open Terminal streams to watch
+	assert.Equal(t, http.StatusOK, status("alice", http.MethodPost, "/gogdbllm.v1.Debugger/Terminal"))
+	assert.Equal(t, http.StatusForbidden, status("alice", http.MethodPost, "/gogdbllm.v1.Debugger/SendCommand"))
+	assert.Equal(t, http.StatusOK, status("bob", http.MethodPost, "/gogdbllm.v1.Debugger/SendCommand"))
+
+	// Debuggers also change the session and their own settings, but not the configuration
+	assert.Equal(t, http.StatusOK, status("bob", http.MethodPost, "/api/chat"))
+	assert.Equal(t, http.StatusOK, status("bob", http.MethodDelete, "/api/v1/debugger/checkpoints/1"))
+	assert.Equal(t, http.StatusOK, status("bob", http.MethodPost, "/save-settings"))
+	assert.Equal(t, http.StatusForbidden, status("alice", http.MethodPost, "/save-settings"))
+	assert.Equal(t, http.StatusForbidden, status("bob", http.MethodGet, "/api/admin/config"))
+
+	assert.Equal(t, http.StatusOK, status("carol", http.MethodPost, "/save-settings"))
+	assert.Equal(t, http.StatusOK, status("carol", http.MethodGet, "/api/admin/config"))
+
+	assert.Equal(t, RoleViewer, a.Role("alice"))
+	assert.ErrorContains(t, a.RequireRole("alice", RoleDebugger), "debugger role is required")
+	assert.NoError(t, a.RequireRole("bob", RoleDebugger))
+
+	// Without authentication everyone is an admin
+	assert.Equal(t, RoleAdmin, newTestAuthenticator(t, config.AuthConfig{}).Role("anyone"))
+	assert.Equal(t, RoleAdmin, RoleFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+
+	_, err = NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "token", Token: "s3cret", DefaultRole: "root"}})
+	assert.Error(t, err)
+	_, err = NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "token", Token: "s3cret", Roles: map[string]string{"token": "guest"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Roles, each allowed what the ones before it are
+const (
+	RoleViewer   = "viewer"   // Watches the terminal output and reads the chat
+	RoleDebugger = "debugger" // Also runs commands, sends chat messages and uploads programs
+	RoleAdmin    = "admin"    // Also changes the server's configuration and policies
+)
+
+// roleRanks orders the roles
+var roleRanks = map[string]int{RoleViewer: 1, RoleDebugger: 2, RoleAdmin: 3}
+
+// adminPrefixes are the paths of the server's configuration and policies, which only
+// admins may reach. A user's own LLM settings (/save-settings) are not among them: they
+// are stored per user, so debuggers choose their own provider, model and API key.
+var adminPrefixes = []string{"/api/admin/"}
+
+// viewerPosts are POST endpoints viewers may call: they change nothing but, for joining
+// and leaving a shared session, the viewer's own membership
+var viewerPosts = map[string]bool{
+	"/api/chat/prompt":     true, // Previews the prompt without calling the LLM
+	"/api/prompts/preview": true,
+	"/api/sessions/join":   true, // Viewers join shared sessions to watch them
+	"/api/sessions/leave":  true,
+}
+
+// grpcMethods are the role each method of the gRPC API (internal/grpcapi/debugger.proto)
+// needs. Every call is a POST, so they are mapped here rather than by method. A Terminal
+// stream may be opened to watch; the commands, input and resizes sent on it are checked
+// as they arrive, like a WebSocket client's.
+var grpcMethods = map[string]string{
+	"/gogdbllm.v1.Debugger/Upload":        RoleDebugger,
+	"/gogdbllm.v1.Debugger/StartDebugger": RoleDebugger,
+	"/gogdbllm.v1.Debugger/StopDebugger":  RoleDebugger,
+	"/gogdbllm.v1.Debugger/SendCommand":   RoleDebugger,
+	"/gogdbllm.v1.Debugger/Chat":          RoleDebugger,
+	"/gogdbllm.v1.Debugger/Terminal":      RoleViewer,
+}
+
+type roleKey struct{}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// Allows reports whether a user with role may do what required needs
+func Allows(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+// RoleFromContext returns the role stored by Middleware. Without authentication every
+// request is an admin's.
+func RoleFromContext(ctx context.Context) string {
+	if role, ok := ctx.Value(roleKey{}).(string); ok {
+		return role
+	}
+	return RoleAdmin
+}
+
+// Role returns a user's role: the one given in auth.roles, or auth.default_role
+func (a *Authenticator) Role(user string) string {
+	if !a.Enabled() {
+		return RoleAdmin
+	}
+	if role, ok := a.roles[strings.ToLower(user)]; ok {
+		return role
+	}
+	return a.defaultRole
+}
+
+// RequireRole returns an error unless user has at least the required role
+func (a *Authenticator) RequireRole(user, required string) error {
+	if role := a.Role(user); !Allows(role, required) {
+		return fmt.Errorf("%w: the %s role is required (%s has %s)", appErrors.ErrForbidden, required, user, role)
+	}
+	return nil
+}
+
+// requiredRole returns the role a request needs. Reading needs a viewer, changing
+// anything a debugger, and the server's configuration and policies an admin. WebSocket
+// clients and gRPC Terminal streams connect as viewers; their messages are checked as
+// they arrive.
+func requiredRole(r *http.Request) string {
+	if role, ok := grpcMethods[r.URL.Path]; ok {
+		return role
+	}
+	for _, prefix := range adminPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return RoleAdmin
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	}
+	if r.Method == http.MethodPost && viewerPosts[r.URL.Path] {
+		return RoleViewer
+	}
+	return RoleDebugger
+}
+
+// validateRoles checks the roles in the auth configuration
+func validateRoles(roles map[string]string, defaultRole string) error {
+	if !ValidRole(defaultRole) {
+		return fmt.Errorf("%w: unknown auth.default_role %q (expected viewer, debugger or admin)", appErrors.ErrInvalidConfiguration, defaultRole)
+	}
+	for user, role := range roles {
+		if !ValidRole(role) {
+			return fmt.Errorf("%w: unknown role %q for %s in auth.roles (expected viewer, debugger or admin)", appErrors.ErrInvalidConfiguration, role, user)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+// Package binfile reads static information from executables without running them: their
+// sections, symbols, imports and strings, much as readelf, nm and strings show them. It
+// gives the assistant context about a binary, such as the libraries it uses and the
+// strings it contains, before the binary is debugged. ELF, PE and Mach-O files are read
+// with the standard library's debug packages.
+package binfile
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Executable formats
+const (
+	FormatELF   = "ELF"
+	FormatPE    = "PE"
+	FormatMachO = "Mach-O"
+)
+
+// File is an open executable
+type File struct {
+	Format string
+	elf    *elf.File
+	pe     *pe.File
+	macho  *macho.File
+	closer io.Closer
+}
+
+// Section is a section of an executable
+type Section struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"` // e.g. "PROGBITS" or "code"
+	Address     uint64 `json:"address"`        // Where it is loaded; 0 if it is not
+	Offset      uint64 `json:"offset"`         // In the file
+	Size        uint64 `json:"size"`
+	Permissions string `json:"permissions"` // e.g. "r-x"; "" if it is not loaded
+}
+
+// Symbol is an entry of an executable's symbol tables
+type Symbol struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"` // "function", "object" or "other"
+	Address uint64 `json:"address,omitempty"`
+	Size    uint64 `json:"size,omitempty"`
+	Section string `json:"section,omitempty"`
+	Binding string `json:"binding,omitempty"` // ELF's "global", "local" or "weak"
+	Dynamic bool   `json:"dynamic,omitempty"` // From the dynamic symbol table, which stripping keeps
+	Defined bool   `json:"defined"`           // False for symbols imported from libraries
+}
+
+// Import is a symbol an executable imports
+type Import struct {
+	Name    string `json:"name"`
+	Library string `json:"library,omitempty"` // When the format records it
+}
+
+// Imports are the libraries an executable links and the symbols it imports from them
+type Imports struct {
+	Libraries []string `json:"libraries"`
+	Symbols   []Import `json:"symbols"`
+}
+
+// Open opens an ELF, PE or Mach-O executable
+func Open(path string) (*File, error) {
+	if f, err := elf.Open(path); err == nil {
+		return &File{Format: FormatELF, elf: f, closer: f}, nil
+	}
+	if f, err := pe.Open(path); err == nil {
+		return &File{Format: FormatPE, pe: f, closer: f}, nil
+	}
+	if f, err := macho.Open(path); err == nil {
+		return &File{Format: FormatMachO, macho: f, closer: f}, nil
+	}
+	return nil, fmt.Errorf("%w: not an ELF, PE or Mach-O executable", appErrors.ErrUnsupported)
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.closer.Close()
+}
+
+// Sections returns the executable's sections in file order
+func (f *File) Sections() []Section {
+	var sections []Section
+	switch {
+	case f.elf != nil:
+		for _, s := range f.elf.Sections {
+			if s.Type == elf.SHT_NULL {
+				continue
+			}
+			section := Section{
+				Name:   s.Name,
+				Type:   strings.TrimPrefix(s.Type.String(), "SHT_"),
+				Offset: s.Offset,
+				Size:   s.Size,
+			}
+			if s.Flags&elf.SHF_ALLOC != 0 {
+				section.Address = s.Addr
+				section.Permissions = permissions(true, s.Flags&elf.SHF_WRITE != 0, s.Flags&elf.SHF_EXECINSTR != 0)
+			}
+			sections = append(sections, section)
+		}
+	case f.pe != nil:
+		base := f.peImageBase()
+		for _, s := range f.pe.Sections {
+			c := s.Characteristics
+			sections = append(sections, Section{
+				Name:        s.Name,
+				Type:        peSectionType(c),
+				Address:     base + uint64(s.VirtualAddress),
+				Offset:      uint64(s.Offset),
+				Size:        uint64(s.VirtualSize),
+				Permissions: permissions(c&pe.IMAGE_SCN_MEM_READ != 0, c&pe.IMAGE_SCN_MEM_WRITE != 0, c&pe.IMAGE_SCN_MEM_EXECUTE != 0),
+			})
+		}
+	case f.macho != nil:
+		for _, s := range f.macho.Sections {
+			section := Section{
+				Name:    s.Seg + "," + s.Name,
+				Address: s.Addr,
+				Offset:  uint64(s.Offset),
+				Size:    s.Size,
+			}
+			if segment := f.macho.Segment(s.Seg); segment != nil {
+				section.Permissions = permissions(segment.Prot&1 != 0, segment.Prot&2 != 0, segment.Prot&4 != 0)
+			}
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// Symbols returns the executable's symbols, sorted by address then name. Stripped
+// executables only have the symbols they export or import.
+func (f *File) Symbols() []Symbol {
+	var symbols []Symbol
+	switch {
+	case f.elf != nil:
+		static, _ := f.elf.Symbols()
+		dynamic, _ := f.elf.DynamicSymbols()
+		symbols = append(f.elfSymbols(static, false), f.elfSymbols(dynamic, true)...)
+	case f.pe != nil:
+		base := f.peImageBase()
+		for _, s := range f.pe.Symbols {
+			if s.StorageClass == 103 || strings.HasPrefix(s.Name, ".") { // File names and section symbols
+				continue
+			}
+			symbol := Symbol{Name: s.Name, Kind: "object", Defined: s.SectionNumber > 0}
+			if s.Type&0x20 != 0 {
+				symbol.Kind = "function"
+			}
+			if s.SectionNumber > 0 && int(s.SectionNumber) <= len(f.pe.Sections) {
+				section := f.pe.Sections[s.SectionNumber-1]
+				symbol.Section = section.Name
+				symbol.Address = base + uint64(section.VirtualAddress) + uint64(s.Value)
+			}
+			symbols = append(symbols, symbol)
+		}
+	case f.macho != nil && f.macho.Symtab != nil:
+		for _, s := range f.macho.Symtab.Syms {
+			if s.Type&0xe0 != 0 { // Debugging entries
+				continue
+			}
+			symbol := Symbol{Name: s.Name, Kind: "other", Defined: s.Type&0x0e != 0}
+			if s.Sect > 0 && int(s.Sect) <= len(f.macho.Sections) {
+				section := f.macho.Sections[s.Sect-1]
+				symbol.Section = section.Seg + "," + section.Name
+				symbol.Address = s.Value
+				symbol.Kind = "object"
+				if section.Name == "__text" {
+					symbol.Kind = "function"
+				}
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].Address != symbols[j].Address {
+			return symbols[i].Address < symbols[j].Address
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols
+}
+
+// elfSymbols converts an ELF symbol table, leaving out file and section symbols
+func (f *File) elfSymbols(table []elf.Symbol, dynamic bool) []Symbol {
+	symbols := make([]Symbol, 0, len(table))
+	for _, s := range table {
+		kind := elf.ST_TYPE(s.Info)
+		if s.Name == "" || kind == elf.STT_FILE || kind == elf.STT_SECTION {
+			continue
+		}
+		symbol := Symbol{
+			Name:    s.Name,
+			Kind:    "other",
+			Address: s.Value,
+			Size:    s.Size,
+			Dynamic: dynamic,
+			Defined: s.Section != elf.SHN_UNDEF,
+		}
+		switch kind {
+		case elf.STT_FUNC, elf.STT_LOOS: // STT_LOOS is STT_GNU_IFUNC
+			symbol.Kind = "function"
+		case elf.STT_OBJECT, elf.STT_TLS:
+			symbol.Kind = "object"
+		}
+		switch elf.ST_BIND(s.Info) {
+		case elf.STB_GLOBAL:
+			symbol.Binding = "global"
+		case elf.STB_LOCAL:
+			symbol.Binding = "local"
+		case elf.STB_WEAK:
+			symbol.Binding = "weak"
+		}
+		if index := int(s.Section); s.Section < elf.SHN_LORESERVE && index > 0 && index < len(f.elf.Sections) {
+			symbol.Section = f.elf.Sections[index].Name
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// Stripped reports whether the executable has no symbol table besides the dynamic one
+func (f *File) Stripped() bool {
+	switch {
+	case f.elf != nil:
+		_, err := f.elf.Symbols()
+		return errors.Is(err, elf.ErrNoSymbols)
+	case f.pe != nil:
+		return len(f.pe.Symbols) == 0
+	case f.macho != nil:
+		return f.macho.Symtab == nil || len(f.macho.Symtab.Syms) == 0
+	}
+	return true
+}
+
+// DebugInfo reports whether the executable carries DWARF debug information, without which
+// GDB knows neither source lines nor variables
+func (f *File) DebugInfo() bool {
+	for _, section := range f.Sections() {
+		switch section.Name {
+		case ".debug_info", ".zdebug_info", "__DWARF,__debug_info":
+			return true
+		}
+	}
+	return false
+}
+
+// Imports returns the libraries the executable links and the symbols it imports, sorted
+func (f *File) Imports() (*Imports, error) {
+	imports := &Imports{Libraries: []string{}, Symbols: []Import{}}
+	var err error
+	switch {
+	case f.elf != nil:
+		if imports.Libraries, err = f.elf.ImportedLibraries(); err != nil {
+			return nil, err
+		}
+		symbols, err := f.elf.ImportedSymbols()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range symbols {
+			imports.Symbols = append(imports.Symbols, Import{Name: s.Name, Library: s.Library})
+		}
+	case f.pe != nil:
+		if imports.Libraries, err = f.pe.ImportedLibraries(); err != nil {
+			return nil, err
+		}
+		symbols, err := f.pe.ImportedSymbols()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range symbols {
+			// "CreateFileA:KERNEL32.dll"
+			name, library, _ := strings.Cut(s, ":")
+			imports.Symbols = append(imports.Symbols, Import{Name: name, Library: library})
+		}
+	case f.macho != nil:
+		if imports.Libraries, err = f.macho.ImportedLibraries(); err != nil {
+			return nil, err
+		}
+		symbols, err := f.macho.ImportedSymbols()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range symbols {
+			imports.Symbols = append(imports.Symbols, Import{Name: s})
+		}
+	}
+	if imports.Libraries == nil {
+		imports.Libraries = []string{}
+	}
+	sort.Strings(imports.Libraries)
+	sort.Slice(imports.Symbols, func(i, j int) bool { return imports.Symbols[i].Name < imports.Symbols[j].Name })
+	return imports, nil
+}
+
+// peImageBase returns the address a PE executable prefers to be loaded at
+func (f *File) peImageBase() uint64 {
+	switch header := f.pe.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(header.ImageBase)
+	case *pe.OptionalHeader64:
+		return header.ImageBase
+	}
+	return 0
+}
+
+// peSectionType describes the contents of a PE section
+func peSectionType(characteristics uint32) string {
+	switch {
+	case characteristics&pe.IMAGE_SCN_CNT_CODE != 0:
+		return "code"
+	case characteristics&pe.IMAGE_SCN_CNT_INITIALIZED_DATA != 0:
+		return "data"
+	case characteristics&pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA != 0:
+		return "bss"
+	}
+	return ""
+}
+
+// permissions returns memory permissions as "rwx", with "-" for those missing
+func permissions(read, write, execute bool) string {
+	p := []byte("---")
+	if read {
+		p[0] = 'r'
+	}
+	if write {
+		p[1] = 'w'
+	}
+	if execute {
+		p[2] = 'x'
+	}
+	return string(p)
+}
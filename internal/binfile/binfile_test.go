@@ -0,0 +1,178 @@
+package binfile
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+const program = `#include <stdio.h>
+#include <string.h>
+
+static const char *server = "https://api.example.com/v1/login";
+
+int check(const char *password) {
+	return strcmp(password, "hunter2-secret") == 0;
+}
+
+int main(int argc, char **argv) {
+	if (argc < 2) {
+		printf("Usage: %s <password>\n", argv[0]);
+		return 1;
+	}
+	puts(check(argv[1]) ? server : "error: access denied");
+	return 0;
+}
+`
+
+// compile builds the test program, stripped if strip is set
+func compile(t *testing.T, strip bool) string {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "login.c")
+	require.NoError(t, os.WriteFile(source, []byte(program), 0644))
+	executable := filepath.Join(dir, "login")
+	args := []string{"-o", executable, source}
+	if strip {
+		args = append(args, "-s")
+	}
+	output, err := exec.Command("gcc", args...).CombinedOutput()
+	require.NoError(t, err, string(output))
+	return executable
+}
+
+func TestELF(t *testing.T) {
+	f, err := Open(compile(t, false))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, FormatELF, f.Format)
+	assert.False(t, f.Stripped())
+	assert.False(t, f.DebugInfo())
+
+	sections := make(map[string]Section)
+	for _, section := range f.Sections() {
+		sections[section.Name] = section
+	}
+	require.Contains(t, sections, ".text")
+	assert.Equal(t, "r-x", sections[".text"].Permissions)
+	assert.Equal(t, "PROGBITS", sections[".text"].Type)
+	assert.Equal(t, "r--", sections[".rodata"].Permissions)
+	if symtab, ok := sections[".symtab"]; ok {
+		assert.Empty(t, symtab.Permissions, "not loaded")
+	}
+
+	var check *Symbol
+	symbols := f.Symbols()
+	for i := range symbols {
+		if symbols[i].Name == "check" && !symbols[i].Dynamic {
+			check = &symbols[i]
+		}
+	}
+	require.NotNil(t, check)
+	assert.Equal(t, "function", check.Kind)
+	assert.Equal(t, ".text", check.Section)
+	assert.True(t, check.Defined)
+	assert.NotZero(t, check.Address)
+
+	imports, err := f.Imports()
+	require.NoError(t, err)
+	assert.Contains(t, imports.Libraries, "libc.so.6")
+	names := make([]string, len(imports.Symbols))
+	for i, imported := range imports.Symbols {
+		names[i] = imported.Name
+	}
+	assert.Contains(t, names, "strcmp")
+	assert.Contains(t, names, "puts")
+
+	strs, total, err := f.Strings(StringOptions{Interesting: true})
+	require.NoError(t, err)
+	assert.Equal(t, len(strs), total)
+	tagged := make(map[string][]string)
+	for _, s := range strs {
+		tagged[s.Value] = s.Tags
+	}
+	assert.Equal(t, []string{"url"}, tagged["https://api.example.com/v1/login"])
+	assert.Equal(t, []string{"secret"}, tagged["hunter2-secret"])
+	assert.Equal(t, []string{"format", "secret"}, tagged["Usage: %s <password>"])
+	assert.Equal(t, []string{"error"}, tagged["error: access denied"])
+
+	strs, total, err = f.Strings(StringOptions{Filter: "USAGE"})
+	require.NoError(t, err)
+	require.Len(t, strs, 1)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, ".rodata", strs[0].Section)
+	assert.NotZero(t, strs[0].Address)
+
+	strs, total, err = f.Strings(StringOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, strs, 1)
+	assert.Greater(t, total, 1)
+}
+
+func TestSummary(t *testing.T) {
+	f, err := Open(compile(t, true))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.True(t, f.Stripped())
+
+	summary, err := f.Summary()
+	require.NoError(t, err)
+	assert.True(t, summary.Stripped)
+	assert.Zero(t, summary.Functions)
+	for _, section := range summary.Sections {
+		assert.NotEmpty(t, section.Permissions)
+	}
+
+	text := summary.String()
+	assert.Contains(t, text, "Format: ELF, stripped")
+	assert.Contains(t, text, "Libraries: libc.so.6")
+	assert.Contains(t, text, "strcmp")
+	assert.Contains(t, text, `[url] "https://api.example.com/v1/login"`)
+	assert.Contains(t, text, ".interp [path]")
+	assert.NotContains(t, text, "GLIBC", "untagged strings are left out")
+}
+
+func TestMetadata(t *testing.T) {
+	f, err := Open(compile(t, true))
+	require.NoError(t, err)
+	defer f.Close()
+
+	metadata := f.Metadata()
+	assert.Equal(t, FormatELF, metadata.Format)
+	assert.NotEmpty(t, metadata.Arch)
+	assert.Contains(t, []int{32, 64}, metadata.Bits)
+	assert.True(t, metadata.Stripped)
+	assert.False(t, metadata.DebugInfo)
+	assert.Equal(t, []string{"libc.so.6"}, metadata.Libraries)
+	assert.Regexp(t, `^[0-9a-f]{16,}$`, metadata.BuildID, "gcc links a GNU build ID")
+	assert.Contains(t, metadata.Warning(), "stripped")
+
+	text := metadata.String()
+	assert.Contains(t, text, "Format: ELF, "+metadata.Arch)
+	assert.Contains(t, text, "Debug information: none, and stripped of symbols")
+	assert.Contains(t, text, "Build ID: "+metadata.BuildID)
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("not an executable"), 0644))
+	_, err := Open(path)
+	assert.ErrorIs(t, err, appErrors.ErrUnsupported)
+}
+
+func TestTagString(t *testing.T) {
+	assert.Equal(t, []string{"path"}, TagString("/etc/ssl/certs"))
+	assert.Equal(t, []string{"path"}, TagString(`C:\Windows\System32`))
+	assert.Equal(t, []string{"ip"}, TagString("connecting to 10.0.0.1"))
+	assert.Equal(t, []string{"email"}, TagString("admin@example.com"))
+	assert.Equal(t, []string{"format", "error"}, TagString("failed to read %zu bytes"))
+	assert.Empty(t, TagString("GLIBC_2.34"))
+	assert.Empty(t, TagString("100%"))
+}
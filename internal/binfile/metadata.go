@@ -0,0 +1,191 @@
+package binfile
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// lcUUID is the Mach-O load command carrying the executable's UUID, its build ID
+const lcUUID = 0x1b
+
+// Metadata is what identifies an executable at a glance: what it runs on, what it links
+// and whether GDB will find names and source lines in it
+type Metadata struct {
+	Format    string   `json:"format"`
+	Arch      string   `json:"arch"` // e.g. "x86-64" or "aarch64"
+	Bits      int      `json:"bits"` // 32 or 64
+	Endian    string   `json:"endian"`
+	Stripped  bool     `json:"stripped"`
+	DebugInfo bool     `json:"debugInfo"`
+	Libraries []string `json:"libraries"`         // None if it is statically linked
+	BuildID   string   `json:"buildId,omitempty"` // ELF's GNU build ID or Mach-O's UUID, in hex
+}
+
+// Metadata reads the executable's metadata
+func (f *File) Metadata() *Metadata {
+	metadata := &Metadata{Format: f.Format, Stripped: f.Stripped(), DebugInfo: f.DebugInfo(), Libraries: []string{}}
+	if imports, err := f.Imports(); err == nil {
+		metadata.Libraries = imports.Libraries
+	}
+	switch {
+	case f.elf != nil:
+		metadata.Arch = elfArch(f.elf.Machine)
+		metadata.Bits = 32
+		if f.elf.Class == elf.ELFCLASS64 {
+			metadata.Bits = 64
+		}
+		metadata.Endian = endian(f.elf.Data == elf.ELFDATA2MSB)
+		metadata.BuildID = f.elfBuildID()
+	case f.pe != nil:
+		metadata.Arch = peArch(f.pe.Machine)
+		metadata.Bits = 32
+		if _, ok := f.pe.OptionalHeader.(*pe.OptionalHeader64); ok {
+			metadata.Bits = 64
+		}
+		metadata.Endian = endian(false)
+	case f.macho != nil:
+		metadata.Arch = machoArch(f.macho.Cpu)
+		metadata.Bits = 32
+		if f.macho.Magic == macho.Magic64 {
+			metadata.Bits = 64
+		}
+		metadata.Endian = endian(f.macho.ByteOrder == binary.BigEndian)
+		for _, load := range f.macho.Loads {
+			raw := load.Raw()
+			if len(raw) >= 24 && f.macho.ByteOrder.Uint32(raw) == lcUUID {
+				metadata.BuildID = hex.EncodeToString(raw[8:24])
+			}
+		}
+	}
+	return metadata
+}
+
+// Warning explains what debugging the executable lacks, or is "" if it has debug
+// information
+func (m *Metadata) Warning() string {
+	if m.DebugInfo {
+		return ""
+	}
+	if m.Stripped {
+		return "The executable is stripped and has no debug information: GDB shows neither function names nor source lines. Rebuild it with -g and without -s for source-level debugging."
+	}
+	return "The executable has no debug information: GDB shows function names but neither source lines nor local variables. Rebuild it with -g for source-level debugging."
+}
+
+// String formats the metadata as text for the assistant
+func (m *Metadata) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Format: %s, %s, %d-bit, %s endian", m.Format, m.Arch, m.Bits, m.Endian)
+	switch {
+	case m.DebugInfo:
+		sb.WriteString("\nDebug information: yes")
+	case m.Stripped:
+		sb.WriteString("\nDebug information: none, and stripped of symbols")
+	default:
+		sb.WriteString("\nDebug information: none, symbols only")
+	}
+	libraries := "none (statically linked)"
+	if len(m.Libraries) > 0 {
+		libraries = strings.Join(m.Libraries, ", ")
+	}
+	fmt.Fprintf(&sb, "\nLibraries: %s", libraries)
+	if m.BuildID != "" {
+		fmt.Fprintf(&sb, "\nBuild ID: %s", m.BuildID)
+	}
+	return sb.String()
+}
+
+// elfBuildID reads the GNU build ID note, which debuginfod and separate debug files are
+// found by, or returns "" if there is none
+func (f *File) elfBuildID() string {
+	section := f.elf.Section(".note.gnu.build-id")
+	if section == nil {
+		return ""
+	}
+	note, err := section.Data()
+	if err != nil || len(note) < 16 {
+		return ""
+	}
+	// namesz, descsz and type, then the name "GNU\0" and the ID, each padded to 4 bytes
+	order := f.elf.ByteOrder
+	nameSize, descSize, kind := order.Uint32(note), order.Uint32(note[4:]), order.Uint32(note[8:])
+	start := 12 + uint64(nameSize+3)&^3
+	if kind != 3 || start+uint64(descSize) > uint64(len(note)) { // NT_GNU_BUILD_ID
+		return ""
+	}
+	return hex.EncodeToString(note[start : start+uint64(descSize)])
+}
+
+// elfArch names an ELF machine as GDB and toolchains commonly do
+func elfArch(machine elf.Machine) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return "x86-64"
+	case elf.EM_386:
+		return "x86"
+	case elf.EM_AARCH64:
+		return "aarch64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_RISCV:
+		return "riscv"
+	case elf.EM_MIPS:
+		return "mips"
+	case elf.EM_PPC:
+		return "powerpc"
+	case elf.EM_PPC64:
+		return "powerpc64"
+	case elf.EM_S390:
+		return "s390"
+	}
+	return strings.ToLower(strings.TrimPrefix(machine.String(), "EM_"))
+}
+
+// peArch names a PE machine
+func peArch(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "x86-64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "x86"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "aarch64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT, pe.IMAGE_FILE_MACHINE_ARM:
+		return "arm"
+	case pe.IMAGE_FILE_MACHINE_RISCV64:
+		return "riscv"
+	}
+	return fmt.Sprintf("0x%x", machine)
+}
+
+// machoArch names a Mach-O CPU
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "x86-64"
+	case macho.Cpu386:
+		return "x86"
+	case macho.CpuArm64:
+		return "aarch64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc:
+		return "powerpc"
+	case macho.CpuPpc64:
+		return "powerpc64"
+	}
+	return strings.ToLower(strings.TrimPrefix(cpu.String(), "Cpu"))
+}
+
+// endian names a byte order
+func endian(big bool) string {
+	if big {
+		return "big"
+	}
+	return "little"
+}
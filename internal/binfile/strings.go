@@ -0,0 +1,181 @@
+package binfile
+
+import (
+	"debug/elf"
+	"debug/pe"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultMinString is the shortest run of printable characters taken as a string, as
+	// for strings(1)
+	DefaultMinString = 4
+	// DefaultStringLimit and MaxStringLimit bound the strings returned at once
+	DefaultStringLimit = 1000
+	MaxStringLimit     = 10000
+	// maxStringLength cuts longer strings, e.g. embedded text files
+	maxStringLength = 1024
+)
+
+// stringTags classify strings worth the assistant's attention
+var stringTags = []struct {
+	tag     string
+	pattern *regexp.Regexp
+}{
+	{"url", regexp.MustCompile(`(?i)\b(?:https?|ftp|wss?)://[^\s"']+`)},
+	{"path", regexp.MustCompile(`(?:^|\s)(?:/[\w.+-]+){2,}|\b[A-Za-z]:\\[\w\\. -]+`)},
+	{"format", regexp.MustCompile(`%[-+ #0]*(?:\d+|\*)?(?:\.\d+)?(?:hh|h|ll|l|z|j|t)?[diouxXeEfgGcsp]`)},
+	{"ip", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+	{"email", regexp.MustCompile(`\b[\w.+-]+@[\w-]+\.[\w.-]+\b`)},
+	{"secret", regexp.MustCompile(`(?i)passw(?:or)?d|secret|token|api[_-]?key|private key|BEGIN [A-Z ]*KEY`)},
+	{"error", regexp.MustCompile(`(?i)\b(?:error|fail(?:ed|ure)?|invalid|denied|overflow|corrupt)`)},
+}
+
+// String is a run of printable characters in an executable's data
+type String struct {
+	Value   string   `json:"value"`
+	Section string   `json:"section"`
+	Offset  uint64   `json:"offset"`            // In the file
+	Address uint64   `json:"address,omitempty"` // Where it is loaded; 0 if it is not
+	Tags    []string `json:"tags,omitempty"`    // e.g. "url", "path", "format" or "error"
+}
+
+// StringOptions select the strings to return
+type StringOptions struct {
+	Min         int    // Shortest string; DefaultMinString if not positive
+	Limit       int    // Most strings; DefaultStringLimit if not positive, at most MaxStringLimit
+	Filter      string // Only strings containing it, ignoring case
+	Interesting bool   // Only strings with tags
+}
+
+// stringSection is a section whose data is searched for strings
+type stringSection struct {
+	name    string
+	address uint64 // 0 if not loaded
+	offset  uint64
+	data    func() ([]byte, error)
+}
+
+// Strings returns the strings in the executable's data sections, in file order, and how
+// many matched before opts.Limit. Code, symbol tables and debug information are skipped:
+// the symbols are listed by Symbols and Imports.
+func (f *File) Strings(opts StringOptions) ([]String, int, error) {
+	if opts.Min <= 0 {
+		opts.Min = DefaultMinString
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultStringLimit
+	}
+	opts.Limit = min(opts.Limit, MaxStringLimit)
+	filter := strings.ToLower(opts.Filter)
+
+	strs := []String{}
+	total := 0
+	for _, section := range f.stringSections() {
+		data, err := section.data()
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, run := range printableRuns(data, opts.Min) {
+			value := string(data[run[0]:run[1]])
+			if filter != "" && !strings.Contains(strings.ToLower(value), filter) {
+				continue
+			}
+			tags := TagString(value)
+			if opts.Interesting && len(tags) == 0 {
+				continue
+			}
+			total++
+			if len(strs) >= opts.Limit {
+				continue
+			}
+			if len(value) > maxStringLength {
+				value = value[:maxStringLength]
+			}
+			s := String{Value: value, Section: section.name, Offset: section.offset + uint64(run[0]), Tags: tags}
+			if section.address != 0 {
+				s.Address = section.address + uint64(run[0])
+			}
+			strs = append(strs, s)
+		}
+	}
+	return strs, total, nil
+}
+
+// TagString classifies a string: "url", "path", "format", "ip", "email", "secret" or
+// "error". Untagged strings are mostly noise.
+func TagString(s string) []string {
+	var tags []string
+	for _, t := range stringTags {
+		if t.pattern.MatchString(s) {
+			tags = append(tags, t.tag)
+		}
+	}
+	return tags
+}
+
+// printableRuns returns the [start, end) offsets of the runs of at least min printable
+// ASCII characters or tabs in data
+func printableRuns(data []byte, min int) [][2]int {
+	var runs [][2]int
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && (data[i] >= 0x20 && data[i] < 0x7f || data[i] == '\t') {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= min {
+			runs = append(runs, [2]int{start, i})
+		}
+		start = -1
+	}
+	return runs
+}
+
+// stringSections returns the sections holding the executable's data
+func (f *File) stringSections() []stringSection {
+	var sections []stringSection
+	switch {
+	case f.elf != nil:
+		for _, s := range f.elf.Sections {
+			if s.Type != elf.SHT_PROGBITS || s.Flags&elf.SHF_EXECINSTR != 0 || isDebugSection(s.Name) {
+				continue
+			}
+			section := stringSection{name: s.Name, offset: s.Offset, data: s.Data}
+			if s.Flags&elf.SHF_ALLOC != 0 {
+				section.address = s.Addr
+			}
+			sections = append(sections, section)
+		}
+	case f.pe != nil:
+		base := f.peImageBase()
+		for _, s := range f.pe.Sections {
+			c := s.Characteristics
+			if c&(pe.IMAGE_SCN_CNT_CODE|pe.IMAGE_SCN_MEM_EXECUTE|pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA) != 0 || s.Name == ".reloc" || isDebugSection(s.Name) {
+				continue
+			}
+			sections = append(sections, stringSection{name: s.Name, address: base + uint64(s.VirtualAddress), offset: uint64(s.Offset), data: s.Data})
+		}
+	case f.macho != nil:
+		for _, s := range f.macho.Sections {
+			const instructions = 0x80000000 | 0x400 // S_ATTR_PURE_INSTRUCTIONS, S_ATTR_SOME_INSTRUCTIONS
+			switch s.Flags & 0xff {
+			case 0x1, 0xc, 0x12: // Zero-filled
+				continue
+			}
+			if s.Flags&instructions != 0 || s.Seg == "__DWARF" || s.Offset == 0 {
+				continue
+			}
+			sections = append(sections, stringSection{name: s.Seg + "," + s.Name, address: s.Addr, offset: uint64(s.Offset), data: s.Data})
+		}
+	}
+	return sections
+}
+
+// isDebugSection reports whether a section holds debug information
+func isDebugSection(name string) bool {
+	return strings.HasPrefix(name, ".debug") || strings.HasPrefix(name, ".zdebug") || strings.HasPrefix(name, "/")
+}
@@ -0,0 +1,111 @@
+package binfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// summaryImports and summaryStrings bound what a summary lists, to keep it short
+	// enough for the assistant's context
+	summaryImports = 200
+	summaryStrings = 50
+)
+
+// Summary is the static context of an executable for the assistant: its loaded sections,
+// libraries, imports and interesting strings
+type Summary struct {
+	Format    string    `json:"format"`
+	Stripped  bool      `json:"stripped"`
+	Functions int       `json:"functions"` // Functions the executable defines, by its symbols
+	Sections  []Section `json:"sections"`  // The loaded ones
+	Libraries []string  `json:"libraries"`
+	Imports   []Import  `json:"imports"`
+	Strings   []String  `json:"strings"` // Tagged ones
+	// Imports and strings left out of the summary
+	MoreImports int `json:"moreImports,omitempty"`
+	MoreStrings int `json:"moreStrings,omitempty"`
+}
+
+// Summary summarizes the executable
+func (f *File) Summary() (*Summary, error) {
+	summary := &Summary{Format: f.Format, Stripped: f.Stripped(), Sections: []Section{}}
+	for _, section := range f.Sections() {
+		if section.Permissions != "" && section.Address != 0 {
+			summary.Sections = append(summary.Sections, section)
+		}
+	}
+	for _, symbol := range f.Symbols() {
+		if symbol.Defined && symbol.Kind == "function" && !symbol.Dynamic {
+			summary.Functions++
+		}
+	}
+
+	imports, err := f.Imports()
+	if err != nil {
+		return nil, err
+	}
+	summary.Libraries = imports.Libraries
+	summary.Imports = imports.Symbols
+	if len(summary.Imports) > summaryImports {
+		summary.MoreImports = len(summary.Imports) - summaryImports
+		summary.Imports = summary.Imports[:summaryImports]
+	}
+
+	strs, total, err := f.Strings(StringOptions{Min: 6, Limit: summaryStrings, Interesting: true})
+	if err != nil {
+		return nil, err
+	}
+	summary.Strings = strs
+	summary.MoreStrings = total - len(strs)
+	return summary, nil
+}
+
+// String formats the summary as text for the assistant
+func (s *Summary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Format: %s", s.Format)
+	if s.Stripped {
+		sb.WriteString(", stripped: functions have no names beyond the imported and exported ones")
+	} else {
+		fmt.Fprintf(&sb, ", %d functions with symbols", s.Functions)
+	}
+
+	sb.WriteString("\nSections:")
+	for _, section := range s.Sections {
+		fmt.Fprintf(&sb, "\n  %-20s %s 0x%x (%d bytes)", section.Name, section.Permissions, section.Address, section.Size)
+	}
+
+	libraries := "none (statically linked)"
+	if len(s.Libraries) > 0 {
+		libraries = strings.Join(s.Libraries, ", ")
+	}
+	fmt.Fprintf(&sb, "\nLibraries: %s", libraries)
+
+	if len(s.Imports) > 0 {
+		names := make([]string, len(s.Imports))
+		for i, imported := range s.Imports {
+			names[i] = imported.Name
+		}
+		fmt.Fprintf(&sb, "\nImported symbols: %s", strings.Join(names, ", "))
+		if s.MoreImports > 0 {
+			fmt.Fprintf(&sb, " and %d more", s.MoreImports)
+		}
+	}
+
+	if len(s.Strings) > 0 {
+		sb.WriteString("\nInteresting strings:")
+		for _, str := range s.Strings {
+			location := str.Section
+			if str.Address != 0 {
+				location = fmt.Sprintf("0x%x %s", str.Address, str.Section)
+			}
+			fmt.Fprintf(&sb, "\n  %s [%s] %s", location, strings.Join(str.Tags, ","), strconv.Quote(str.Value))
+		}
+		if s.MoreStrings > 0 {
+			fmt.Fprintf(&sb, "\n  and %d more", s.MoreStrings)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,257 @@
+// Package binstore keeps one copy of each uploaded executable, named by the SHA-256 of its
+// content, so uploading the same binary again, by any user or under any name, reuses the
+// stored copy and what was read from it. Users' uploads are hard links to the copies.
+package binstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// defaultTTL is how long a binary nobody uploads or debugs is kept when the
+// configuration sets no uploads.binary_ttl
+const defaultTTL = 30 * 24 * time.Hour
+
+// mutex serializes changes to every store, which share their directories on disk
+var mutex sync.Mutex
+
+// Binary is a stored executable and what was read from it when it was first stored
+type Binary struct {
+	ID        string            `json:"id"` // The SHA-256 of its content
+	Size      int64             `json:"size"`
+	Format    string            `json:"format"`
+	Metadata  *binfile.Metadata `json:"metadata,omitempty"` // nil if the file could not be read
+	Names     []string          `json:"names"`              // The filenames it was uploaded as
+	Owners    []string          `json:"owners,omitempty"`   // The users who uploaded it
+	Uploads   int               `json:"uploads"`            // Times it was uploaded
+	CreatedAt time.Time         `json:"createdAt"`
+	LastUsed  time.Time         `json:"lastUsed"` // Last uploaded or debugged
+}
+
+// Store keeps binaries in a directory, each as its content and a JSON description
+type Store struct {
+	dir string
+	ttl time.Duration
+}
+
+// New creates the store of the uploads directory
+func New(cfg config.UploadsConfig) *Store {
+	ttl := cfg.BinaryTTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &Store{dir: Dir(cfg.Directory), ttl: ttl}
+}
+
+// Dir returns the directory binaries are stored in below an uploads directory
+func Dir(uploadsDir string) string {
+	return filepath.Join(uploadsDir, "binaries")
+}
+
+// Add stores the executable at path for a user under a name, and links dst to the stored
+// copy. The file at path is moved into the store, or removed when the same content is
+// stored already, in which case the stored binary's description is returned with reused
+// set.
+func (s *Store) Add(path, dst, user, name, format string) (binary *Binary, reused bool, err error) {
+	id, size, err := hashFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create binary store: %w", err)
+	}
+	s.reap()
+
+	object := s.path(id)
+	binary, err = s.read(id)
+	if err == nil {
+		if _, statErr := os.Stat(object); statErr == nil {
+			reused = true
+		}
+	}
+	if reused {
+		os.Remove(path)
+	} else {
+		binary = &Binary{ID: id, Size: size, Format: format, Names: []string{}, CreatedAt: time.Now()}
+		if f, err := binfile.Open(path); err == nil {
+			binary.Metadata = f.Metadata()
+			f.Close()
+		}
+		// Read-only, as every upload of it shares the file
+		if err := os.Chmod(path, 0555); err != nil {
+			return nil, false, fmt.Errorf("failed to store binary: %w", err)
+		}
+		if err := os.Rename(path, object); err != nil {
+			return nil, false, fmt.Errorf("failed to store binary: %w", err)
+		}
+	}
+
+	if err := link(object, dst); err != nil {
+		return nil, false, fmt.Errorf("failed to link binary: %w", err)
+	}
+	if !slices.Contains(binary.Names, name) {
+		binary.Names = append(binary.Names, name)
+	}
+	if !slices.Contains(binary.Owners, user) {
+		binary.Owners = append(binary.Owners, user)
+	}
+	binary.Uploads++
+	binary.LastUsed = time.Now()
+	if err := s.write(binary); err != nil {
+		return nil, false, err
+	}
+	return binary, reused, nil
+}
+
+// List returns the binaries a user stored, most recently used first
+func (s *Store) List(user string) []Binary {
+	mutex.Lock()
+	defer mutex.Unlock()
+	binaries := []Binary{}
+	for _, binary := range s.all() {
+		if slices.Contains(binary.Owners, user) {
+			binaries = append(binaries, binary)
+		}
+	}
+	sort.SliceStable(binaries, func(i, j int) bool { return binaries[i].LastUsed.After(binaries[j].LastUsed) })
+	return binaries
+}
+
+// Touch records that the executable at path, if it is a stored binary, was used now
+func (s *Store) Touch(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, binary := range s.all() {
+		if object, err := os.Stat(s.path(binary.ID)); err == nil && os.SameFile(info, object) {
+			binary.LastUsed = time.Now()
+			s.write(&binary)
+			return
+		}
+	}
+}
+
+// reap removes the binaries unused for the store's TTL. Uploads linked to them keep
+// their content. The caller holds the mutex.
+func (s *Store) reap() {
+	cutoff := time.Now().Add(-s.ttl)
+	for _, binary := range s.all() {
+		if binary.LastUsed.Before(cutoff) {
+			os.Remove(s.path(binary.ID) + ".json")
+			os.Remove(s.path(binary.ID))
+		}
+	}
+}
+
+// all reads every binary's description; the caller holds the mutex
+func (s *Store) all() []Binary {
+	var binaries []Binary
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	for _, path := range paths {
+		var binary Binary
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &binary) == nil {
+			binaries = append(binaries, binary)
+		}
+	}
+	return binaries
+}
+
+// read reads a binary's description
+func (s *Store) read(id string) (*Binary, error) {
+	data, err := os.ReadFile(s.path(id) + ".json")
+	if err != nil {
+		return nil, err
+	}
+	var binary Binary
+	if err := json.Unmarshal(data, &binary); err != nil {
+		return nil, err
+	}
+	return &binary, nil
+}
+
+// write writes a binary's description
+func (s *Store) write(binary *Binary) error {
+	data, _ := json.Marshal(binary)
+	tmp := s.path(binary.ID) + ".json.tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to describe binary: %w", err)
+	}
+	return os.Rename(tmp, s.path(binary.ID)+".json")
+}
+
+// path returns the path of a binary's content
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// link makes dst a hard link to object, replacing whatever dst was. Where hard links are
+// not possible, e.g. across file systems, dst is a copy.
+func link(object, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := dst + ".link"
+	os.Remove(tmp)
+	if err := os.Link(object, tmp); err != nil {
+		if err := copyFile(object, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// copyFile copies an executable
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// hashFile returns the hex SHA-256 of a file's content and its size
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash binary: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
@@ -0,0 +1,110 @@
+package binstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// upload writes content to a temporary upload file, as the upload handlers do
+func upload(t *testing.T, dir, content string) string {
+	f, err := os.CreateTemp(dir, ".upload-*")
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func TestAdd(t *testing.T) {
+	dir := t.TempDir()
+	store := New(config.UploadsConfig{Directory: dir})
+	sum := sha256.Sum256([]byte("\x7fELF program"))
+	id := hex.EncodeToString(sum[:])
+
+	alice := filepath.Join(dir, "users", "alice", "server")
+	binary, reused, err := store.Add(upload(t, dir, "\x7fELF program"), alice, "alice", "server", "ELF")
+	require.NoError(t, err)
+	assert.False(t, reused)
+	assert.Equal(t, id, binary.ID)
+	assert.Equal(t, int64(12), binary.Size)
+	content, err := os.ReadFile(alice)
+	require.NoError(t, err)
+	assert.Equal(t, "\x7fELF program", string(content))
+
+	// The same content under another name and user is the same binary
+	bob := filepath.Join(dir, "users", "bob", "server-v2")
+	binary, reused, err = store.Add(upload(t, dir, "\x7fELF program"), bob, "bob", "server-v2", "ELF")
+	require.NoError(t, err)
+	assert.True(t, reused)
+	assert.Equal(t, id, binary.ID)
+	assert.Equal(t, []string{"server", "server-v2"}, binary.Names)
+	assert.Equal(t, 2, binary.Uploads)
+	aliceInfo, err := os.Stat(alice)
+	require.NoError(t, err)
+	bobInfo, err := os.Stat(bob)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(aliceInfo, bobInfo), "uploads share the stored copy")
+
+	// A different file replaces the upload of the same name, not the stored binary
+	_, reused, err = store.Add(upload(t, dir, "\x7fELF patched"), alice, "alice", "server", "ELF")
+	require.NoError(t, err)
+	assert.False(t, reused)
+	content, err = os.ReadFile(bob)
+	require.NoError(t, err)
+	assert.Equal(t, "\x7fELF program", string(content))
+
+	temporary, _ := filepath.Glob(filepath.Join(dir, ".upload-*"))
+	assert.Empty(t, temporary)
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	store := New(config.UploadsConfig{Directory: dir})
+	first, _, err := store.Add(upload(t, dir, "first"), filepath.Join(dir, "first"), "alice", "first", "ELF")
+	require.NoError(t, err)
+	second, _, err := store.Add(upload(t, dir, "second"), filepath.Join(dir, "second"), "alice", "second", "ELF")
+	require.NoError(t, err)
+	_, _, err = store.Add(upload(t, dir, "third"), filepath.Join(dir, "users", "bob", "third"), "bob", "third", "ELF")
+	require.NoError(t, err)
+
+	binaries := store.List("alice")
+	require.Len(t, binaries, 2)
+	assert.Equal(t, second.ID, binaries[0].ID, "most recently used first")
+
+	// Debugging an upload counts as using its binary
+	store.Touch(filepath.Join(dir, "first"))
+	binaries = store.List("alice")
+	assert.Equal(t, first.ID, binaries[0].ID)
+	assert.Empty(t, store.List("carol"))
+}
+
+func TestReap(t *testing.T) {
+	dir := t.TempDir()
+	store := New(config.UploadsConfig{Directory: dir, BinaryTTL: time.Hour})
+	old, _, err := store.Add(upload(t, dir, "old"), filepath.Join(dir, "old"), "alice", "old", "ELF")
+	require.NoError(t, err)
+
+	// Binaries unused for the TTL are removed; their uploads keep their content
+	old.LastUsed = time.Now().Add(-2 * time.Hour)
+	data, err := json.Marshal(old)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), old.ID+".json"), data, 0644))
+	_, _, err = store.Add(upload(t, dir, "new"), filepath.Join(dir, "new"), "alice", "new", "ELF")
+	require.NoError(t, err)
+
+	binaries := store.List("alice")
+	require.Len(t, binaries, 1)
+	assert.NoFileExists(t, filepath.Join(Dir(dir), old.ID))
+	content, err := os.ReadFile(filepath.Join(dir, "old"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+}
@@ -1,14 +1,13 @@
 package cache
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"sync"
 	"time"
 
 	"github.com/yourusername/gogdbllm/internal/chat"
+	"github.com/yourusername/gogdbllm/internal/chat/cache/store"
+	"github.com/yourusername/gogdbllm/internal/llm"
 )
 
 // Config holds cache configuration
@@ -29,13 +28,13 @@ func DefaultConfig() *Config {
 	}
 }
 
-// Cache represents an in-memory cache for chat requests/responses
+// Cache caches chat responses in a storage backend: memory by default, or a disk
+// directory or Redis server that survives restarts
 type Cache struct {
-	config      *Config
-	entries     map[string]*chat.CacheEntry
-	accessOrder []string // For LRU eviction
-	mutex       sync.RWMutex
-	stats       *CacheStats
+	config  *Config
+	backend store.Backend
+	mutex   sync.RWMutex
+	stats   *CacheStats
 }
 
 // CacheStats holds cache performance statistics
@@ -46,20 +45,30 @@ type CacheStats struct {
 	Size        int     `json:"size"`
 	HitRate     float64 `json:"hit_rate"`
 	MemoryUsage int64   `json:"memory_usage_bytes"`
+	Backend     string  `json:"backend"`
 }
 
-// New creates a new cache instance
+// New creates a new in-memory cache instance
 func New(config *Config) *Cache {
 	if config == nil {
 		config = DefaultConfig()
 	}
+	return NewWithBackend(config, store.NewMemory(config.MaxSize))
+}
+
+// NewWithBackend creates a cache keeping its entries in backend
+func NewWithBackend(config *Config, backend store.Backend) *Cache {
+	if config == nil {
+		config = DefaultConfig()
+	}
 
-	return &Cache{
-		config:      config,
-		entries:     make(map[string]*chat.CacheEntry),
-		accessOrder: make([]string, 0),
-		stats:       &CacheStats{},
+	c := &Cache{
+		config:  config,
+		backend: backend,
+		stats:   &CacheStats{Backend: backend.Name()},
 	}
+	c.updateStats()
+	return c
 }
 
 // Get retrieves a cached response
@@ -72,18 +81,21 @@ func (c *Cache) Get(key *chat.CacheKey) (*chat.ChatResponse, bool) {
 	defer c.mutex.Unlock()
 
 	keyStr := c.keyToString(key)
-	entry, exists := c.entries[keyStr]
+	entry, err := c.backend.Get(keyStr)
+	var response chat.ChatResponse
+	if err == nil {
+		err = json.Unmarshal(entry.Value, &response)
+	}
 
-	if !exists {
+	if err != nil {
 		c.stats.Misses++
 		c.updateStats()
 		return nil, false
 	}
 
 	// Check if entry has expired
-	if time.Now().After(entry.ExpiresAt) {
-		delete(c.entries, keyStr)
-		c.removeFromAccessOrder(keyStr)
+	if entry.Expired(time.Now()) {
+		c.backend.Delete(keyStr)
 		c.stats.Misses++
 		c.updateStats()
 		return nil, false
@@ -92,13 +104,12 @@ func (c *Cache) Get(key *chat.CacheKey) (*chat.ChatResponse, bool) {
 	// Update access information
 	entry.AccessCount++
 	entry.LastAccessed = time.Now()
-	c.moveToFront(keyStr)
+	c.backend.Put(entry)
 
 	c.stats.Hits++
 	c.updateStats()
 
 	// Mark response as from cache
-	response := *entry.Response
 	response.FromCache = true
 
 	return &response, true
@@ -110,29 +121,30 @@ func (c *Cache) Set(key *chat.CacheKey, response *chat.ChatResponse) {
 		return
 	}
 
+	value, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	keyStr := c.keyToString(key)
-
 	// Create cache entry
-	entry := &chat.CacheEntry{
-		Response:     response,
-		CreatedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(c.config.TTL),
+	now := time.Now()
+	evicted, err := c.backend.Put(&store.Entry{
+		Key:          c.keyToString(key),
+		Provider:     key.Provider,
+		Model:        key.Model,
+		Value:        value,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(c.config.TTL),
 		AccessCount:  1,
-		LastAccessed: time.Now(),
-	}
-
-	// Check if we need to evict entries
-	if len(c.entries) >= c.config.MaxSize {
-		c.evictLRU()
+		LastAccessed: now,
+	})
+	if err == nil {
+		c.stats.Evictions += int64(evicted)
 	}
 
-	// Store the entry
-	c.entries[keyStr] = entry
-	c.moveToFront(keyStr)
-
 	c.updateStats()
 }
 
@@ -141,9 +153,13 @@ func (c *Cache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.entries = make(map[string]*chat.CacheEntry)
-	c.accessOrder = make([]string, 0)
-	c.stats = &CacheStats{}
+	c.backend.Clear()
+	c.stats = &CacheStats{Backend: c.backend.Name()}
+}
+
+// Close releases the cache's backend
+func (c *Cache) Close() error {
+	return c.backend.Close()
 }
 
 // GetStats returns cache statistics
@@ -152,6 +168,12 @@ func (c *Cache) GetStats() *CacheStats {
 	defer c.mutex.RUnlock()
 
 	statsCopy := *c.stats
+	if entries, err := c.backend.List(); err == nil {
+		statsCopy.Size = len(entries)
+		for _, entry := range entries {
+			statsCopy.MemoryUsage += int64(len(entry.Value))
+		}
+	}
 	return &statsCopy
 }
 
@@ -162,12 +184,7 @@ func (c *Cache) IsEnabled() bool {
 
 // keyToString converts a cache key to a string representation
 func (c *Cache) keyToString(key *chat.CacheKey) string {
-	if key.Hash != "" {
-		return fmt.Sprintf("%s:%s:%s", key.Provider, key.Model, key.Hash)
-	}
-
-	// If no hash provided, this is likely an error
-	return fmt.Sprintf("%s:%s:no-hash", key.Provider, key.Model)
+	return store.Key(key.Provider, key.Model, key.Hash)
 }
 
 // GenerateKey generates a cache key for a request
@@ -186,18 +203,23 @@ func (c *Cache) GenerateKey(provider, model string, request *chat.ChatRequest) *
 func (c *Cache) hashRequest(request *chat.ChatRequest) string {
 	// Create a simplified version of the request for hashing
 	hashData := struct {
-		Message     string                 `json:"message"`
-		History     []chat.StandardMessage `json:"history"`
-		SentContext []interface{}          `json:"sentContext"`
+		Message     string        `json:"message"`
+		History     []llm.Message `json:"history"`
+		SentContext []interface{} `json:"sentContext"`
+		Images      []string      `json:"images,omitempty"`
+		Temperature *float64      `json:"temperature,omitempty"`
+		MaxTokens   int           `json:"maxTokens,omitempty"`
 	}{
 		Message:     request.Message,
-		History:     make([]chat.StandardMessage, len(request.History)),
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		History:     make([]llm.Message, len(request.History)),
 		SentContext: make([]interface{}, len(request.SentContext)),
 	}
 
 	// Convert history to standard messages
 	for i, msg := range request.History {
-		hashData.History[i] = chat.StandardMessage{
+		hashData.History[i] = llm.Message{
 			Role:    msg.Role,
 			Content: msg.Content,
 		}
@@ -212,59 +234,24 @@ func (c *Cache) hashRequest(request *chat.ChatRequest) string {
 		}
 	}
 
-	// Marshal to JSON and hash
-	data, _ := json.Marshal(hashData)
-	hasher := sha256.New()
-	hasher.Write(data)
-	return hex.EncodeToString(hasher.Sum(nil))[:16] // Use first 16 characters
-}
-
-// evictLRU removes the least recently used entry
-func (c *Cache) evictLRU() {
-	if len(c.accessOrder) == 0 {
-		return
+	// Images by their hashes
+	for _, img := range request.Images {
+		hashData.Images = append(hashData.Images, store.Hash(img.Data))
 	}
 
-	// Remove the least recently used entry (last in access order)
-	keyToEvict := c.accessOrder[len(c.accessOrder)-1]
-	delete(c.entries, keyToEvict)
-	c.accessOrder = c.accessOrder[:len(c.accessOrder)-1]
-	c.stats.Evictions++
-}
-
-// moveToFront moves a key to the front of the access order (most recently used)
-func (c *Cache) moveToFront(key string) {
-	// Remove from current position
-	c.removeFromAccessOrder(key)
-
-	// Add to front
-	c.accessOrder = append([]string{key}, c.accessOrder...)
-}
-
-// removeFromAccessOrder removes a key from the access order slice
-func (c *Cache) removeFromAccessOrder(key string) {
-	for i, k := range c.accessOrder {
-		if k == key {
-			c.accessOrder = append(c.accessOrder[:i], c.accessOrder[i+1:]...)
-			break
-		}
-	}
+	return store.Hash(hashData)
 }
 
 // updateStats updates cache statistics
 func (c *Cache) updateStats() {
-	c.stats.Size = len(c.entries)
-
 	total := c.stats.Hits + c.stats.Misses
 	if total > 0 {
 		c.stats.HitRate = float64(c.stats.Hits) / float64(total) * 100
 	}
-
-	// Estimate memory usage (rough calculation)
-	c.stats.MemoryUsage = int64(len(c.entries) * 1024) // Rough estimate: 1KB per entry
 }
 
-// Cleanup removes expired entries
+// Cleanup removes expired entries. Backends that expire entries themselves, like Redis,
+// have none to remove.
 func (c *Cache) Cleanup() {
 	if !c.config.Enabled {
 		return
@@ -273,18 +260,16 @@ func (c *Cache) Cleanup() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	now := time.Now()
-	var keysToRemove []string
-
-	for key, entry := range c.entries {
-		if now.After(entry.ExpiresAt) {
-			keysToRemove = append(keysToRemove, key)
-		}
+	entries, err := c.backend.List()
+	if err != nil {
+		return
 	}
 
-	for _, key := range keysToRemove {
-		delete(c.entries, key)
-		c.removeFromAccessOrder(key)
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Expired(now) {
+			c.backend.Delete(entry.Key)
+		}
 	}
 
 	c.updateStats()
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Disk keeps one JSON file per entry in a directory, so cached responses survive
+// restarts. An index of keys and access times is kept in memory to evict the least
+// recently used entry once maxSize entries are stored.
+//
+// It stands in for an embedded database such as BoltDB or SQLite: the server builds
+// from the standard library and its few existing modules (SQLite would also need cgo
+// for the common driver), and a cache of at most max_size entries, each written whole
+// and read by key, needs none of a database's transactions or queries. Writes go to a
+// temporary file renamed into place, so a crash never leaves a torn entry.
+type Disk struct {
+	dir     string
+	maxSize int
+	index   map[string]time.Time // Key to last access
+	mutex   sync.Mutex
+}
+
+// OpenDisk opens the disk backend in dir, creating the directory if needed. Expired and
+// unreadable entries left from earlier runs are removed.
+func OpenDisk(dir string, maxSize int) (*Disk, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("chat.cache.directory is required for the disk backend")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	d := &Disk{dir: dir, maxSize: maxSize, index: make(map[string]time.Time)}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache directory: %w", err)
+	}
+	now := time.Now()
+	for _, file := range files {
+		entry, err := readEntry(file)
+		if err != nil || entry.Expired(now) || file != d.path(entry.Key) {
+			os.Remove(file)
+			continue
+		}
+		d.index[entry.Key] = entry.LastAccessed
+	}
+	return d, nil
+}
+
+// Name implements Backend
+func (d *Disk) Name() string { return BackendDisk }
+
+// Get implements Backend
+func (d *Disk) Get(key string) (*Entry, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.index[key]; !ok {
+		return nil, ErrNotFound
+	}
+	entry, err := readEntry(d.path(key))
+	if os.IsNotExist(err) {
+		delete(d.index, key)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.index[key] = time.Now()
+	return entry, nil
+}
+
+// Put implements Backend
+func (d *Disk) Put(entry *Entry) (int, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	evicted := 0
+	if _, exists := d.index[entry.Key]; !exists {
+		for d.maxSize > 0 && len(d.index) >= d.maxSize {
+			d.remove(d.leastRecentlyUsed())
+			evicted++
+		}
+	}
+
+	// Write to a temporary file first, so a crash never leaves a partial entry
+	tmp, err := os.CreateTemp(d.dir, ".entry-*")
+	if err != nil {
+		return evicted, fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path(entry.Key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return evicted, fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	d.index[entry.Key] = entry.LastAccessed
+	return evicted, nil
+}
+
+// Delete implements Backend
+func (d *Disk) Delete(key string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.remove(key)
+}
+
+// List implements Backend
+func (d *Disk) List() ([]*Entry, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entries := make([]*Entry, 0, len(d.index))
+	for key := range d.index {
+		entry, err := readEntry(d.path(key))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Clear implements Backend
+func (d *Disk) Clear() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key := range d.index {
+		if err := d.remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Backend
+func (d *Disk) Close() error { return nil }
+
+// path returns the file holding the entry for key. Keys contain model names, so the file
+// is named after their hash.
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// remove deletes the entry for key; the caller holds the mutex
+func (d *Disk) remove(key string) error {
+	delete(d.index, key)
+	if err := os.Remove(d.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache entry: %w", err)
+	}
+	return nil
+}
+
+// leastRecentlyUsed returns the key accessed longest ago; the caller holds the mutex
+func (d *Disk) leastRecentlyUsed() string {
+	var oldestKey string
+	var oldest time.Time
+	for key, accessed := range d.index {
+		if oldestKey == "" || accessed.Before(oldest) || (accessed.Equal(oldest) && key < oldestKey) {
+			oldestKey, oldest = key, accessed
+		}
+	}
+	return oldestKey
+}
+
+// readEntry reads an entry file
+func readEntry(file string) (*Entry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid cache entry %s: %w", filepath.Base(file), err)
+	}
+	return &entry, nil
+}
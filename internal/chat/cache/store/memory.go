@@ -0,0 +1,102 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Memory keeps entries in process memory, evicting the least recently used entry once
+// maxSize entries are stored. Entries are lost on restart.
+type Memory struct {
+	maxSize int
+	entries map[string]*list.Element // Values are *Entry
+	order   *list.List               // Most recently used first
+	mutex   sync.Mutex
+}
+
+// NewMemory creates a memory backend holding at most maxSize entries; 0 is unbounded
+func NewMemory(maxSize int) *Memory {
+	return &Memory{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Name implements Backend
+func (m *Memory) Name() string { return BackendMemory }
+
+// Get implements Backend
+func (m *Memory) Get(key string) (*Entry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	m.order.MoveToFront(elem)
+	entry := *elem.Value.(*Entry)
+	return &entry, nil
+}
+
+// Put implements Backend
+func (m *Memory) Put(entry *Entry) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stored := *entry
+	if elem, ok := m.entries[entry.Key]; ok {
+		elem.Value = &stored
+		m.order.MoveToFront(elem)
+		return 0, nil
+	}
+
+	evicted := 0
+	for m.maxSize > 0 && len(m.entries) >= m.maxSize {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*Entry).Key)
+		evicted++
+	}
+	m.entries[entry.Key] = m.order.PushFront(&stored)
+	return evicted, nil
+}
+
+// Delete implements Backend
+func (m *Memory) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// List implements Backend, returning the most recently used entries first
+func (m *Memory) List() ([]*Entry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entries := make([]*Entry, 0, len(m.entries))
+	for elem := m.order.Front(); elem != nil; elem = elem.Next() {
+		entry := *elem.Value.(*Entry)
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// Clear implements Backend
+func (m *Memory) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries = make(map[string]*list.Element)
+	m.order.Init()
+	return nil
+}
+
+// Close implements Backend
+func (m *Memory) Close() error { return nil }
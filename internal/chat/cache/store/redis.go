@@ -0,0 +1,351 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// redisScanCount is the number of keys asked for per SCAN call
+const redisScanCount = 100
+
+// Redis keeps entries in a Redis server, so several servers can share a cache and it
+// survives restarts. Expiry is left to Redis; entries are stored with their TTL and
+// evicted under the server's maxmemory policy rather than chat.cache.max_size.
+//
+// It speaks the RESP protocol itself, so the cache adds no dependencies. Each request
+// takes a connection from a pool of up to pool_size idle ones, dialling another when
+// none is free, so concurrent requests do not wait on each other.
+type Redis struct {
+	cfg    config.RedisConfig
+	idle   chan *redisConn
+	closed bool
+	mutex  sync.Mutex
+}
+
+// redisConn is a connection to the server with its reader
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// NewRedis creates a Redis backend. The server is not contacted until the first request.
+func NewRedis(cfg config.RedisConfig) *Redis {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6379"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 8
+	}
+	return &Redis{cfg: cfg, idle: make(chan *redisConn, cfg.PoolSize)}
+}
+
+// Name implements Backend
+func (r *Redis) Name() string { return BackendRedis }
+
+// Get implements Backend
+func (r *Redis) Get(key string) (*Entry, error) {
+	reply, err := r.do("GET", r.cfg.Prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid cache entry %s: %w", key, err)
+	}
+	return &entry, nil
+}
+
+// Put implements Backend
+func (r *Redis) Put(entry *Entry) (int, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	args := []string{"SET", r.cfg.Prefix + entry.Key, string(data)}
+	if !entry.ExpiresAt.IsZero() {
+		ttl := time.Until(entry.ExpiresAt).Milliseconds()
+		if ttl <= 0 {
+			return 0, r.Delete(entry.Key)
+		}
+		args = append(args, "PX", strconv.FormatInt(ttl, 10))
+	}
+	_, err = r.do(args...)
+	return 0, err
+}
+
+// Delete implements Backend
+func (r *Redis) Delete(key string) error {
+	_, err := r.do("DEL", r.cfg.Prefix+key)
+	return err
+}
+
+// List implements Backend
+func (r *Redis) List() ([]*Entry, error) {
+	keys, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for start := 0; start < len(keys); start += redisScanCount {
+		batch := keys[start:min(start+redisScanCount, len(keys))]
+		reply, err := r.do(append([]string{"MGET"}, batch...)...)
+		if err != nil {
+			return nil, err
+		}
+		values, _ := reply.([]interface{})
+		for _, value := range values {
+			data, ok := value.([]byte)
+			if !ok {
+				continue // Expired since the scan
+			}
+			var entry Entry
+			if json.Unmarshal(data, &entry) == nil {
+				entries = append(entries, &entry)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Clear implements Backend, removing only keys under the configured prefix
+func (r *Redis) Clear() error {
+	keys, err := r.scan()
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(keys); start += redisScanCount {
+		batch := keys[start:min(start+redisScanCount, len(keys))]
+		if _, err := r.do(append([]string{"DEL"}, batch...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Backend, closing the idle connections; those in use are closed when
+// their request ends
+func (r *Redis) Close() error {
+	r.mutex.Lock()
+	r.closed = true
+	r.mutex.Unlock()
+
+	for {
+		select {
+		case c := <-r.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// scan returns every Redis key under the configured prefix
+func (r *Redis) scan() ([]string, error) {
+	pattern := escapeGlob(r.cfg.Prefix) + "*"
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("redis: unexpected SCAN reply")
+		}
+		next, _ := parts[0].([]byte)
+		batch, _ := parts[1].([]interface{})
+		for _, key := range batch {
+			if key, ok := key.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+// do sends a command and returns its reply: a string for status replies, an int64, a
+// []byte or nil for bulk strings, or a []interface{} for arrays. A command that fails on
+// a stale pooled connection is retried once on a new one.
+func (r *Redis) do(args ...string) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		c, reused, err := r.get()
+		if err != nil {
+			return nil, err
+		}
+		reply, err := c.roundTrip(args, r.cfg.Timeout)
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			r.put(c)
+			return reply, err
+		}
+
+		// The connection is in an unknown state after a network error
+		c.conn.Close()
+		if !reused || attempt > 0 {
+			return nil, fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+}
+
+// get returns an idle connection, reporting that it was reused, or dials a new one
+func (r *Redis) get() (*redisConn, bool, error) {
+	select {
+	case c := <-r.idle:
+		return c, true, nil
+	default:
+	}
+	c, err := r.dial()
+	return c, false, err
+}
+
+// put returns a connection to the pool, closing it when the pool is full or closed
+func (r *Redis) put(c *redisConn) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.closed {
+		select {
+		case r.idle <- c:
+			return
+		default:
+		}
+	}
+	c.conn.Close()
+}
+
+// dial connects to the server and authenticates
+func (r *Redis) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", r.cfg.Addr, r.cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", r.cfg.Addr, err)
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	var setup [][]string
+	if r.cfg.Password != "" {
+		if r.cfg.Username != "" {
+			setup = append(setup, []string{"AUTH", r.cfg.Username, r.cfg.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", r.cfg.Password})
+		}
+	}
+	if r.cfg.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.cfg.DB)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(args, r.cfg.Timeout); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+	return c, nil
+}
+
+// roundTrip writes a command and reads its reply within timeout
+func (c *redisConn) roundTrip(args []string, timeout time.Duration) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// readReply reads one RESP reply
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", line[1:])
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(reader); err != nil {
+				var replyErr redisError
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = nil
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// escapeGlob escapes the characters special to Redis MATCH patterns
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
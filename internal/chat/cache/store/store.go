@@ -0,0 +1,94 @@
+// Package store holds the backends cached chat responses are kept in: memory, a disk
+// directory that survives restarts, and Redis, which several servers can share. Backends
+// only store entries; the caches built on them decide keys and expiry, so every backend
+// sees the same keys and TTLs.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Backend names, as set in chat.cache.backend
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+	BackendRedis  = "redis"
+)
+
+// ErrNotFound is returned by Get for a key with no entry
+var ErrNotFound = errors.New("cache entry not found")
+
+// Entry is a cached response with its bookkeeping. Value is opaque to the backend.
+type Entry struct {
+	Key          string    `json:"key"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Value        []byte    `json:"value"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	AccessCount  int       `json:"accessCount"`
+	LastAccessed time.Time `json:"lastAccessed"`
+}
+
+// Expired reports whether the entry has outlived its TTL at now
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// Backend stores cache entries. Implementations are safe for concurrent use.
+type Backend interface {
+	// Name is the backend's name in the configuration
+	Name() string
+	// Get returns the entry for key, or ErrNotFound. Expired entries may be returned;
+	// callers check Expired.
+	Get(key string) (*Entry, error)
+	// Put stores an entry under its key, replacing any previous one, and returns how many
+	// other entries were evicted to make room
+	Put(entry *Entry) (int, error)
+	// Delete removes the entry for key, if any
+	Delete(key string) error
+	// List returns every stored entry, possibly including expired ones
+	List() ([]*Entry, error)
+	// Clear removes every entry
+	Clear() error
+	// Close releases the backend's resources
+	Close() error
+}
+
+// Hash returns the hash identifying a request within a provider and model: the first 16
+// hex digits of the SHA-256 of its JSON encoding
+func Hash(request interface{}) string {
+	data, _ := json.Marshal(request)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Key returns the cache key of a request hash for a provider and model
+func Key(provider, model, hash string) string {
+	if hash == "" {
+		// If no hash provided, this is likely an error
+		hash = "no-hash"
+	}
+	return fmt.Sprintf("%s:%s:%s", provider, model, hash)
+}
+
+// Open creates the backend selected by the cache configuration
+func Open(cfg config.CacheConfig) (Backend, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return NewMemory(cfg.MaxSize), nil
+	case BackendDisk:
+		return OpenDisk(cfg.Directory, cfg.MaxSize)
+	case BackendRedis:
+		return NewRedis(cfg.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown chat.cache backend %q (expected memory, disk or redis)", cfg.Backend)
+	}
+}
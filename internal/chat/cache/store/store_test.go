@@ -0,0 +1,312 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func newEntry(key, value string, ttl time.Duration) *Entry {
+	now := time.Now()
+	return &Entry{Key: key, Value: []byte(value), CreatedAt: now, ExpiresAt: now.Add(ttl), LastAccessed: now}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	backend := NewMemory(2)
+	backend.Put(newEntry("a", "1", time.Hour))
+	backend.Put(newEntry("b", "2", time.Hour))
+	_, err := backend.Get("a")
+	require.NoError(t, err)
+
+	evicted, err := backend.Put(newEntry("c", "3", time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	_, err = backend.Get("b")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	entries, err := backend.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestDiskSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := OpenDisk(dir, 10)
+	require.NoError(t, err)
+	key := Key("anthropic", "claude-3-haiku", Hash(map[string]string{"message": "why"}))
+	_, err = backend.Put(newEntry(key, "cached", time.Hour))
+	require.NoError(t, err)
+	_, err = backend.Put(newEntry("stale", "old", -time.Minute))
+	require.NoError(t, err)
+
+	reopened, err := OpenDisk(dir, 10)
+	require.NoError(t, err)
+	entry, err := reopened.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(entry.Value))
+	_, err = reopened.Get("stale")
+	assert.ErrorIs(t, err, ErrNotFound, "expired entries are dropped on open")
+
+	require.NoError(t, reopened.Clear())
+	entries, err := reopened.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOpenRejectsUnknownBackend(t *testing.T) {
+	_, err := Open(config.CacheConfig{Backend: "sqlite"})
+	assert.Error(t, err)
+}
+
+func TestRedis(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := NewRedis(config.RedisConfig{Addr: server.addr, Password: "secret", Prefix: "test:"})
+	defer backend.Close()
+
+	_, err := backend.Put(newEntry("openai:gpt-4o:abc", "cached", time.Hour))
+	require.NoError(t, err)
+	entry, err := backend.Get("openai:gpt-4o:abc")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(entry.Value))
+	assert.Equal(t, "secret", server.password)
+	assert.NotEmpty(t, server.ttl["test:openai:gpt-4o:abc"], "entries are stored with their TTL")
+
+	_, err = backend.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	server.set("other:key", "not ours")
+	entries, err := backend.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "openai:gpt-4o:abc", entries[0].Key)
+
+	require.NoError(t, backend.Clear())
+	_, err = backend.Get("openai:gpt-4o:abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "not ours", server.data["other:key"], "Clear only removes keys under the prefix")
+}
+
+func TestRedisServesRequestsConcurrently(t *testing.T) {
+	server := newFakeRedis(t)
+	server.gate = make(chan struct{})
+	backend := NewRedis(config.RedisConfig{Addr: server.addr})
+	defer backend.Close()
+
+	// A GET of "slow" waits for the gate while another request goes through
+	done := make(chan error)
+	go func() {
+		_, err := backend.Get("slow")
+		done <- err
+	}()
+	_, err := backend.Get("other")
+	assert.ErrorIs(t, err, ErrNotFound)
+	close(server.gate)
+	assert.ErrorIs(t, <-done, ErrNotFound)
+
+	// Idle connections are reused
+	for i := 0; i < 3; i++ {
+		backend.Get("other")
+	}
+	assert.Equal(t, int32(2), server.conns.Load())
+}
+
+// TestRedisServer runs the Redis backend against a real server at $GOGDBLLM_TEST_REDIS
+// (host:port), using only keys under a prefix of its own
+func TestRedisServer(t *testing.T) {
+	addr := os.Getenv("GOGDBLLM_TEST_REDIS")
+	if addr == "" {
+		t.Skip("GOGDBLLM_TEST_REDIS not set")
+	}
+	prefix := fmt.Sprintf("gogdbllm-test:%d:", time.Now().UnixNano())
+	backend := NewRedis(config.RedisConfig{Addr: addr, Prefix: prefix, PoolSize: 4})
+	defer backend.Close()
+	defer backend.Clear()
+
+	// Values are sent as bulk strings, so line breaks and any bytes survive
+	value := "line one\r\nline two \x00\xff ünïcode"
+	_, err := backend.Put(newEntry("openai:gpt-4o:abc", value, time.Hour))
+	require.NoError(t, err)
+	entry, err := backend.Get("openai:gpt-4o:abc")
+	require.NoError(t, err)
+	assert.Equal(t, value, string(entry.Value))
+	_, err = backend.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Redis expires entries itself
+	_, err = backend.Put(newEntry("short", "soon gone", 200*time.Millisecond))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := backend.Get("short")
+		return err == ErrNotFound
+	}, 2*time.Second, 50*time.Millisecond)
+
+	// More entries than one SCAN or MGET batch, from more requests than the pool holds
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*redisScanCount)
+	for i := 0; i < 2*redisScanCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("entry:%d", i)
+			if _, err := backend.Put(newEntry(key, key, time.Hour)); err != nil {
+				errs <- err
+				return
+			}
+			if entry, err := backend.Get(key); err != nil || string(entry.Value) != key {
+				errs <- fmt.Errorf("get %s: %v %v", key, entry, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	entries, err := backend.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2*redisScanCount+1)
+	assert.LessOrEqual(t, len(backend.idle), 4, "at most pool_size connections are kept")
+
+	// An error reply fails the request but leaves its connection usable
+	_, err = backend.do("LPUSH", prefix+"list", "x")
+	require.NoError(t, err)
+	_, err = backend.Get("list")
+	var replyErr redisError
+	require.ErrorAs(t, err, &replyErr)
+	assert.Contains(t, err.Error(), "WRONGTYPE")
+	_, err = backend.Get("openai:gpt-4o:abc")
+	assert.NoError(t, err)
+
+	require.NoError(t, backend.Clear())
+	entries, err = backend.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// A server that cannot be reached is an error, not a miss
+	unreachable := NewRedis(config.RedisConfig{Addr: "127.0.0.1:1", Timeout: time.Second})
+	_, err = unreachable.Get("openai:gpt-4o:abc")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+// fakeRedis implements the few commands the Redis backend sends
+type fakeRedis struct {
+	addr     string
+	data     map[string]string
+	ttl      map[string]string
+	password string
+	gate     chan struct{} // When set, a GET of "slow" waits for it to close
+	conns    atomic.Int32
+	mutex    sync.Mutex
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{addr: listener.Addr().String(), data: map[string]string{}, ttl: map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeRedis) set(key, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data[key] = value
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	s.conns.Add(1)
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		if s.gate != nil && len(args) == 2 && args[1] == "slow" {
+			<-s.gate
+		}
+		conn.Write([]byte(s.handle(args)))
+	}
+}
+
+func (s *fakeRedis) handle(args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bulk := func(value string) string { return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n" }
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		s.password = args[len(args)-1]
+		return "+OK\r\n"
+	case "SET":
+		s.data[args[1]] = args[2]
+		if len(args) == 5 {
+			s.ttl[args[1]] = args[4]
+		}
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "MGET":
+		reply := "*" + strconv.Itoa(len(args)-1) + "\r\n"
+		for _, key := range args[1:] {
+			if value, ok := s.data[key]; ok {
+				reply += bulk(value)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(s.data, key)
+		}
+		return ":1\r\n"
+	case "SCAN":
+		pattern := strings.ReplaceAll(args[3], `\`, "")
+		var keys []string
+		for key := range s.data {
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		reply := "*2\r\n" + bulk("0") + "*" + strconv.Itoa(len(keys)) + "\r\n"
+		for _, key := range keys {
+			reply += bulk(key)
+		}
+		return reply
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/llm"
 )
 
 // ChatRequest represents an internal chat request
@@ -11,10 +12,46 @@ type ChatRequest struct {
 	Message     string            `json:"message"`
 	History     []api.ChatMessage `json:"history"`
 	SentContext []api.ContextItem `json:"sentContext,omitempty"`
+	Images      []api.Image       `json:"images,omitempty"` // Sent with the message to models that accept images
 	SessionID   string            `json:"sessionId,omitempty"`
 	UserID      string            `json:"userId,omitempty"`
 	RequestID   string            `json:"requestId"`
 	Timestamp   time.Time         `json:"timestamp"`
+
+	// Overrides of the provider's settings for this request only
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+}
+
+// FromAPIRequest converts a chat API request, with its overrides
+func FromAPIRequest(req *api.ChatRequest) *ChatRequest {
+	return &ChatRequest{
+		Message:     req.Message,
+		History:     req.History,
+		SentContext: req.SentContext,
+		Images:      req.Images,
+		RequestID:   req.RequestID,
+		Timestamp:   time.Now(),
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+}
+
+// ApplyOverrides sets the request's model, temperature and maxTokens, where it has them,
+// on a provider request
+func (r *ChatRequest) ApplyOverrides(req *llm.Request) {
+	if r.Model != "" {
+		req.Model = r.Model
+	}
+	if r.Temperature != nil {
+		req.Temperature = r.Temperature
+	}
+	if r.MaxTokens > 0 {
+		maxTokens := r.MaxTokens
+		req.MaxTokens = &maxTokens
+	}
 }
 
 // ChatResponse represents an internal chat response
@@ -38,76 +75,23 @@ type ResponseMetadata struct {
 	CacheHit       bool          `json:"cacheHit"`
 	ContextLength  int           `json:"contextLength"`
 	ContextTrimmed bool          `json:"contextTrimmed"`
+	FallbackFrom   string        `json:"fallbackFrom,omitempty"` // The primary provider, when another one served the request
 }
 
-// StandardRequest represents a standardized request to any provider
-type StandardRequest struct {
-	Model          string            `json:"model"`
-	Messages       []StandardMessage `json:"messages"`
-	MaxTokens      *int              `json:"maxTokens,omitempty"`
-	Temperature    *float64          `json:"temperature,omitempty"`
-	SystemPrompt   string            `json:"systemPrompt,omitempty"`
-	ResponseFormat *ResponseFormat   `json:"responseFormat,omitempty"`
-	RequestID      string            `json:"requestId"`
-}
-
-// StandardMessage represents a standardized message
-type StandardMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// ResponseFormat specifies the desired response format
-type ResponseFormat struct {
-	Type   string `json:"type"`
-	Schema string `json:"schema,omitempty"`
-}
-
-// StandardResponse represents a standardized response from any provider
-type StandardResponse struct {
-	Content    string            `json:"content"`
-	TokensUsed int               `json:"tokensUsed,omitempty"`
-	Model      string            `json:"model"`
-	Provider   string            `json:"provider"`
-	RequestID  string            `json:"requestId"`
-	Metadata   *ProviderMetadata `json:"metadata,omitempty"`
-}
-
-// ProviderMetadata contains provider-specific metadata
-type ProviderMetadata struct {
-	RawResponse    string        `json:"rawResponse,omitempty"`
-	FinishReason   string        `json:"finishReason,omitempty"`
-	PromptTokens   int           `json:"promptTokens,omitempty"`
-	ResponseTokens int           `json:"responseTokens,omitempty"`
-	ResponseTime   time.Duration `json:"responseTime"`
-}
-
-// ProviderError represents an error from a provider
-type ProviderError struct {
-	Provider  string `json:"provider"`
-	ErrorType string `json:"errorType"`
-	Message   string `json:"message"`
-	Code      int    `json:"code,omitempty"`
-	Retryable bool   `json:"retryable"`
-}
-
-func (e *ProviderError) Error() string {
-	return e.Message
+// UserMessage returns the request's final user turn with the given text: the text alone,
+// or the request's images and the text as parts
+func (r *ChatRequest) UserMessage(text string) llm.Message {
+	message := llm.Message{Role: "user", Content: text}
+	if len(r.Images) == 0 {
+		return message
+	}
+	for _, img := range r.Images {
+		message.Parts = append(message.Parts, llm.ContentPart{Type: llm.ContentImage, MediaType: img.MediaType, Data: img.Data})
+	}
+	message.Parts = append(message.Parts, llm.ContentPart{Type: llm.ContentText, Text: text})
+	return message
 }
 
-// ErrorType constants
-const (
-	ErrorTypeRateLimit   = "rate_limit"
-	ErrorTypeInvalidJSON = "invalid_json"
-	ErrorTypeNetwork     = "network"
-	ErrorTypeAuth        = "authentication"
-	ErrorTypeQuota       = "quota_exceeded"
-	ErrorTypeModel       = "model_error"
-	ErrorTypeValidation  = "validation"
-	ErrorTypeTimeout     = "timeout"
-	ErrorTypeInternal    = "internal"
-)
-
 // CacheKey represents a cache key for requests
 type CacheKey struct {
 	Provider string `json:"provider"`
@@ -1,15 +1,17 @@
 package providers
 
 import (
-	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/yourusername/gogdbllm/internal/chat"
+	"github.com/yourusername/gogdbllm/internal/llm"
 )
 
 // AnthropicProvider implements the Provider interface for Anthropic
@@ -18,25 +20,65 @@ type AnthropicProvider struct {
 	client *http.Client
 }
 
+// anthropicMaxTokens is the response size limit sent to Anthropic, which requires one,
+// when the request sets none
+const anthropicMaxTokens = 4096
+
+// anthropicSpace is the whitespace Anthropic refuses at the end of a prefilled reply
+const anthropicSpace = " \t\r\n"
+
 // AnthropicRequest represents a request to the Anthropic API
 type AnthropicRequest struct {
-	Model     string             `json:"model"`
-	Messages  []AnthropicMessage `json:"messages"`
-	MaxTokens int                `json:"max_tokens"`
-	System    string             `json:"system,omitempty"`
+	Model       string               `json:"model"`
+	Messages    []AnthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	System      string               `json:"system,omitempty"`
+	Tools       []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *AnthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream      bool                 `json:"stream,omitempty"`
 }
 
 // AnthropicMessage represents a message for Anthropic API
 type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // A string, or []AnthropicContentBlock for messages with parts
+}
+
+// AnthropicContentBlock is a part of a message with several, e.g. text and images
+type AnthropicContentBlock struct {
+	Type   string                `json:"type"` // "text" or "image"
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicImageSource `json:"source,omitempty"`
+}
+
+// AnthropicImageSource is the content of an image block
+type AnthropicImageSource struct {
+	Type      string `json:"type"` // Always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// AnthropicTool describes a tool the model may call
+type AnthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// AnthropicToolChoice forces the model to call a specific tool
+type AnthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 // AnthropicResponse represents a response from the Anthropic API
 type AnthropicResponse struct {
 	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Name  string          `json:"name,omitempty"`  // Tool name for tool_use blocks
+		Input json.RawMessage `json:"input,omitempty"` // Tool input for tool_use blocks
 	} `json:"content"`
 	Usage *struct {
 		InputTokens  int `json:"input_tokens"`
@@ -64,148 +106,286 @@ func NewAnthropicProvider(config *ProviderConfig) *AnthropicProvider {
 }
 
 // SendRequest sends a request to the Anthropic API
-func (ap *AnthropicProvider) SendRequest(ctx context.Context, req *chat.StandardRequest) (*chat.StandardResponse, error) {
+func (ap *AnthropicProvider) SendRequest(ctx context.Context, req *llm.Request) (*llm.Response, error) {
 	start := time.Now()
 
 	// Convert to Anthropic format
 	anthropicReq, err := ap.convertRequest(req)
 	if err != nil {
-		return nil, &chat.ProviderError{
+		return nil, &llm.ProviderError{
 			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeValidation,
+			ErrorType: llm.ErrorTypeValidation,
 			Message:   fmt.Sprintf("failed to convert request: %v", err),
 			Retryable: false,
 		}
 	}
 
-	// Marshal request
-	reqBody, err := json.Marshal(anthropicReq)
-	if err != nil {
-		return nil, &chat.ProviderError{
-			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeInternal,
-			Message:   fmt.Sprintf("failed to marshal request: %v", err),
-			Retryable: false,
-		}
-	}
-
-	// Create HTTP request
-	baseURL := "https://api.anthropic.com/v1/messages"
-	if ap.config.BaseURL != "" {
-		baseURL = ap.config.BaseURL + "/v1/messages"
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, &chat.ProviderError{
-			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeInternal,
-			Message:   fmt.Sprintf("failed to create HTTP request: %v", err),
-			Retryable: false,
-		}
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", ap.config.APIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	// Send request
-	resp, err := ap.client.Do(httpReq)
+	resp, err := postJSON(ctx, ap.client, ap.GetName(), ap.url(), ap.headers(), anthropicReq)
 	if err != nil {
-		return nil, &chat.ProviderError{
-			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeNetwork,
-			Message:   fmt.Sprintf("failed to send request: %v", err),
-			Retryable: true,
-		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, &chat.ProviderError{
+		return nil, &llm.ProviderError{
 			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeNetwork,
+			ErrorType: llm.ErrorTypeNetwork,
 			Message:   fmt.Sprintf("failed to read response: %v", err),
 			Retryable: true,
 		}
 	}
 
-	// Handle HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, ap.handleHTTPError(resp.StatusCode, respBody)
-	}
-
 	// Parse response
 	var anthropicResp AnthropicResponse
 	if err := json.Unmarshal(respBody, &anthropicResp); err != nil {
-		return nil, &chat.ProviderError{
+		return nil, &llm.ProviderError{
 			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeInternal,
+			ErrorType: llm.ErrorTypeInternal,
 			Message:   fmt.Sprintf("failed to parse response: %v", err),
 			Retryable: false,
 		}
 	}
 
 	// Convert response
-	return ap.convertResponse(&anthropicResp, req.RequestID, time.Since(start), string(respBody))
+	return ap.convertResponse(&anthropicResp, req, time.Since(start), string(respBody))
+}
+
+// anthropicStreamEvent is an event of a streamed Anthropic response. Each type of event
+// sets some of the fields.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"` // message_start
+	ContentBlock struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"content_block"` // content_block_start
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`         // text_delta
+		PartialJSON string `json:"partial_json"` // input_json_delta
+		StopReason  string `json:"stop_reason"`  // message_delta
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"` // message_delta
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// StreamRequest streams a response from the Anthropic API. With a tool, the tool's input
+// is streamed rather than text.
+func (ap *AnthropicProvider) StreamRequest(ctx context.Context, req *llm.Request, onDelta func(delta string)) (*llm.Response, error) {
+	start := time.Now()
+
+	anthropicReq, err := ap.convertRequest(req)
+	if err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  ap.GetName(),
+			ErrorType: llm.ErrorTypeValidation,
+			Message:   fmt.Sprintf("failed to convert request: %v", err),
+			Retryable: false,
+		}
+	}
+	anthropicReq.Stream = true
+
+	resp, err := postJSON(ctx, ap.client, ap.GetName(), ap.url(), ap.headers(), anthropicReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	metadata := &llm.ProviderMetadata{}
+	response := &llm.Response{Provider: ap.GetName(), RequestID: req.RequestID, Metadata: metadata}
+	var content strings.Builder
+	skipSpace := trimmedPrefill(req)
+	toolBlock := -1
+	complete := false
+
+	err = readEvents(resp.Body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("unreadable event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			response.Model = event.Message.Model
+			metadata.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_start":
+			if req.Tool != nil && event.ContentBlock.Type == "tool_use" && event.ContentBlock.Name == req.Tool.Name {
+				toolBlock = event.Index
+			}
+		case "content_block_delta":
+			text := event.Delta.Text
+			if req.Tool != nil {
+				if event.Index != toolBlock {
+					return nil
+				}
+				text = event.Delta.PartialJSON
+			}
+			if skipSpace {
+				text = strings.TrimLeft(text, anthropicSpace)
+				skipSpace = text == ""
+			}
+			if text != "" {
+				content.WriteString(text)
+				onDelta(text)
+			}
+		case "message_delta":
+			metadata.FinishReason = event.Delta.StopReason
+			metadata.ResponseTokens = event.Usage.OutputTokens
+		case "message_stop":
+			complete = true
+		case "error":
+			// Overloaded or failing mid-stream
+			return &llm.ProviderError{
+				Provider:  ap.GetName(),
+				ErrorType: llm.ErrorTypeNetwork,
+				Message:   fmt.Sprintf("%s API error (%s): %s", ap.GetName(), event.Error.Type, event.Error.Message),
+				Retryable: event.Error.Type != "invalid_request_error",
+			}
+		}
+		return nil
+	})
+	var providerErr *llm.ProviderError
+	if errors.As(err, &providerErr) {
+		return nil, err
+	}
+	if err != nil || !complete {
+		return nil, streamError(ap.GetName(), err)
+	}
+
+	response.Content = content.String()
+	response.TokensUsed = metadata.PromptTokens + metadata.ResponseTokens
+	metadata.ResponseTime = time.Since(start)
+	return response, nil
+}
+
+// url returns the messages endpoint
+func (ap *AnthropicProvider) url() string {
+	if ap.config.BaseURL != "" {
+		return ap.config.BaseURL + "/v1/messages"
+	}
+	return "https://api.anthropic.com/v1/messages"
+}
+
+// headers returns the headers sent with every request
+func (ap *AnthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         ap.config.APIKey,
+		"anthropic-version": "2023-06-01",
+	}
 }
 
 // convertRequest converts a standard request to Anthropic format
-func (ap *AnthropicProvider) convertRequest(req *chat.StandardRequest) (*AnthropicRequest, error) {
-	messages := make([]AnthropicMessage, len(req.Messages))
-	for i, msg := range req.Messages {
+func (ap *AnthropicProvider) convertRequest(req *llm.Request) (*AnthropicRequest, error) {
+	messages := make([]AnthropicMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
 		// Skip system messages as they go in the system field
 		if msg.Role == "system" {
 			continue
 		}
 
-		role := msg.Role
-		if role == "assistant" {
+		role := "user"
+		if msg.Role == "assistant" {
 			role = "assistant"
-		} else {
-			role = "user"
 		}
 
-		messages[i] = AnthropicMessage{
+		messages = append(messages, AnthropicMessage{
 			Role:    role,
-			Content: msg.Content,
-		}
+			Content: anthropicContent(msg),
+		})
+	}
+	if req.Prefill != "" {
+		// The reply continues the final assistant message
+		messages = append(messages, AnthropicMessage{Role: "assistant", Content: strings.TrimRight(req.Prefill, anthropicSpace)})
 	}
 
-	maxTokens := 4096
+	maxTokens := anthropicMaxTokens
 	if req.MaxTokens != nil && *req.MaxTokens > 0 {
 		maxTokens = *req.MaxTokens
 	}
 
-	return &AnthropicRequest{
-		Model:     req.Model,
-		Messages:  messages,
-		MaxTokens: maxTokens,
-		System:    req.SystemPrompt,
-	}, nil
+	anthropicReq := &AnthropicRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+		System:      req.SystemPrompt,
+	}
+	if req.Tool != nil {
+		anthropicReq.Tools = []AnthropicTool{{Name: req.Tool.Name, Description: req.Tool.Description, InputSchema: req.Tool.Schema}}
+		anthropicReq.ToolChoice = &AnthropicToolChoice{Type: "tool", Name: req.Tool.Name}
+	}
+	return anthropicReq, nil
+}
+
+// trimmedPrefill reports whether a request's prefill was sent without the whitespace it
+// ends with, which the reply then starts with again
+func trimmedPrefill(req *llm.Request) bool {
+	return req.Prefill != strings.TrimRight(req.Prefill, anthropicSpace)
+}
+
+// anthropicContent converts a message's content: its text, or its parts as content blocks
+func anthropicContent(msg llm.Message) interface{} {
+	if len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	blocks := make([]AnthropicContentBlock, len(msg.Parts))
+	for i, part := range msg.Parts {
+		switch part.Type {
+		case llm.ContentImage:
+			blocks[i] = AnthropicContentBlock{Type: "image", Source: &AnthropicImageSource{
+				Type:      "base64",
+				MediaType: part.MediaType,
+				Data:      base64.StdEncoding.EncodeToString(part.Data),
+			}}
+		default:
+			blocks[i] = AnthropicContentBlock{Type: "text", Text: part.Text}
+		}
+	}
+	return blocks
 }
 
 // convertResponse converts an Anthropic response to standard format
-func (ap *AnthropicProvider) convertResponse(resp *AnthropicResponse, requestID string, responseTime time.Duration, rawResp string) (*chat.StandardResponse, error) {
+func (ap *AnthropicProvider) convertResponse(resp *AnthropicResponse, req *llm.Request, responseTime time.Duration, rawResp string) (*llm.Response, error) {
 	if len(resp.Content) == 0 {
-		return nil, &chat.ProviderError{
+		return nil, &llm.ProviderError{
 			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeInternal,
+			ErrorType: llm.ErrorTypeInternal,
 			Message:   "no content in response",
 			Retryable: false,
 		}
 	}
 
+	// A forced tool's input is the reply
 	content := resp.Content[0].Text
+	if trimmedPrefill(req) {
+		content = strings.TrimLeft(content, anthropicSpace)
+	}
+	if req.Tool != nil {
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" && block.Name == req.Tool.Name {
+				content = string(block.Input)
+				break
+			}
+		}
+	}
 	tokensUsed := 0
 	if resp.Usage != nil {
 		tokensUsed = resp.Usage.InputTokens + resp.Usage.OutputTokens
 	}
 
-	metadata := &chat.ProviderMetadata{
+	metadata := &llm.ProviderMetadata{
 		RawResponse:  rawResp,
 		FinishReason: resp.StopReason,
 		ResponseTime: responseTime,
@@ -216,47 +396,16 @@ func (ap *AnthropicProvider) convertResponse(resp *AnthropicResponse, requestID
 		metadata.ResponseTokens = resp.Usage.OutputTokens
 	}
 
-	return &chat.StandardResponse{
+	return &llm.Response{
 		Content:    content,
 		TokensUsed: tokensUsed,
 		Model:      resp.Model,
 		Provider:   ap.GetName(),
-		RequestID:  requestID,
+		RequestID:  req.RequestID,
 		Metadata:   metadata,
 	}, nil
 }
 
-// handleHTTPError handles HTTP errors and converts them to provider errors
-func (ap *AnthropicProvider) handleHTTPError(statusCode int, body []byte) error {
-	message := string(body)
-
-	var errorType string
-	var retryable bool
-
-	switch statusCode {
-	case http.StatusUnauthorized:
-		errorType = chat.ErrorTypeAuth
-		retryable = false
-	case http.StatusTooManyRequests:
-		errorType = chat.ErrorTypeRateLimit
-		retryable = true
-	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-		errorType = chat.ErrorTypeNetwork
-		retryable = true
-	default:
-		errorType = chat.ErrorTypeInternal
-		retryable = false
-	}
-
-	return &chat.ProviderError{
-		Provider:  ap.GetName(),
-		ErrorType: errorType,
-		Message:   fmt.Sprintf("HTTP %d: %s", statusCode, message),
-		Code:      statusCode,
-		Retryable: retryable,
-	}
-}
-
 // GetSupportedModels returns supported Anthropic models
 func (ap *AnthropicProvider) GetSupportedModels() []ModelInfo {
 	return []ModelInfo{
@@ -312,9 +461,9 @@ func (ap *AnthropicProvider) ValidateConfig(config *ProviderConfig) error {
 	}
 
 	if !modelValid {
-		return &chat.ProviderError{
+		return &llm.ProviderError{
 			Provider:  ap.GetName(),
-			ErrorType: chat.ErrorTypeValidation,
+			ErrorType: llm.ErrorTypeValidation,
 			Message:   fmt.Sprintf("unsupported model: %s", config.DefaultModel),
 			Retryable: false,
 		}
@@ -325,35 +474,5 @@ func (ap *AnthropicProvider) ValidateConfig(config *ProviderConfig) error {
 
 // GetHealthStatus checks the health of the Anthropic API
 func (ap *AnthropicProvider) GetHealthStatus(ctx context.Context) (*HealthStatus, error) {
-	start := time.Now()
-
-	// Create a minimal test request
-	testReq := &chat.StandardRequest{
-		Model: ap.config.DefaultModel,
-		Messages: []chat.StandardMessage{
-			{Role: "user", Content: "Hello"},
-		},
-		MaxTokens: &[]int{10}[0],
-		RequestID: "health-check",
-	}
-
-	// Try to send the request
-	_, err := ap.SendRequest(ctx, testReq)
-
-	responseTime := time.Since(start)
-
-	if err != nil {
-		return &HealthStatus{
-			Healthy:      false,
-			ResponseTime: responseTime,
-			LastCheck:    time.Now(),
-			ErrorMessage: err.Error(),
-		}, nil
-	}
-
-	return &HealthStatus{
-		Healthy:      true,
-		ResponseTime: responseTime,
-		LastCheck:    time.Now(),
-	}, nil
+	return checkHealth(ctx, ap, ap.config.DefaultModel)
 }
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+func TestAnthropicConvertRequestImages(t *testing.T) {
+	provider := NewAnthropicProvider(&ProviderConfig{Name: "anthropic", APIKey: "key"})
+	screenshot := []byte("\x89PNG\r\n\x1a\n")
+
+	converted, err := provider.convertRequest(&llm.Request{
+		Model: "claude-3-haiku-20240307",
+		Messages: []llm.Message{
+			{Role: "system", Content: "Sent as the system prompt"},
+			{Role: "user", Content: "The window is blank"},
+			{Role: "assistant", Content: "Send a screenshot"},
+			{Role: "user", Content: "Here it is", Parts: []llm.ContentPart{
+				{Type: llm.ContentImage, MediaType: "image/png", Data: screenshot},
+				{Type: llm.ContentText, Text: "Here it is"},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, converted.Messages, 3, "system messages are left out")
+	assert.Equal(t, "The window is blank", converted.Messages[0].Content, "messages without parts stay text")
+	assert.Equal(t, []AnthropicContentBlock{
+		{Type: "image", Source: &AnthropicImageSource{Type: "base64", MediaType: "image/png", Data: base64.StdEncoding.EncodeToString(screenshot)}},
+		{Type: "text", Text: "Here it is"},
+	}, converted.Messages[2].Content)
+	assert.Equal(t, anthropicMaxTokens, converted.MaxTokens)
+}
+
+func TestAnthropicTool(t *testing.T) {
+	provider := NewAnthropicProvider(&ProviderConfig{Name: "anthropic", APIKey: "key"})
+	req := &llm.Request{
+		Messages: []llm.Message{{Role: "user", Content: "why did it crash?"}},
+		Tool:     &llm.Tool{Name: "respond", Description: "Reply", Schema: json.RawMessage(`{"type":"object"}`)},
+	}
+
+	converted, err := provider.convertRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, []AnthropicTool{{Name: "respond", Description: "Reply", InputSchema: req.Tool.Schema}}, converted.Tools)
+	assert.Equal(t, &AnthropicToolChoice{Type: "tool", Name: "respond"}, converted.ToolChoice)
+
+	var resp AnthropicResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"content": [{"type": "text", "text": "Let me look"}, {"type": "tool_use", "name": "respond", "input": {"message": "a null pointer"}}],
+		"usage": {"input_tokens": 100, "output_tokens": 20}
+	}`), &resp))
+	response, err := provider.convertResponse(&resp, req, 0, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message": "a null pointer"}`, response.Content, "the tool's input is the reply")
+	assert.Equal(t, 100, response.Metadata.PromptTokens)
+	assert.Equal(t, 20, response.Metadata.ResponseTokens)
+}
+
+func TestHTTPError(t *testing.T) {
+	overloaded := httpError("anthropic", 529, []byte("overloaded")).(*llm.ProviderError)
+	assert.True(t, overloaded.Retryable)
+	assert.Equal(t, "anthropic API error (status 529): overloaded", overloaded.Error())
+
+	rejected := httpError("openai", 401, []byte("bad key")).(*llm.ProviderError)
+	assert.Equal(t, llm.ErrorTypeAuth, rejected.ErrorType)
+	assert.False(t, rejected.Retryable)
+
+	assert.Equal(t, llm.ErrorTypeQuota, httpError("openrouter", 402, nil).(*llm.ProviderError).ErrorType)
+}
@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// defaultEmbeddingModel is the embedding model used when none is configured
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+// maxEmbeddingBatch caps the texts sent in one embeddings request
+const maxEmbeddingBatch = 96
+
+// OpenAIEmbedder creates embeddings with OpenAI's embeddings API, or with a compatible
+// server's, e.g. Ollama or llama.cpp running locally
+type OpenAIEmbedder struct {
+	client  *http.Client
+	baseURL string
+	model   string
+}
+
+// OpenAIEmbeddingRequest is a request to the embeddings API
+type OpenAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OpenAIEmbeddingResponse is the embeddings API's response, an embedding per input
+type OpenAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// NewOpenAIEmbedder creates an embedder for config's BaseURL, OpenAI's when empty, and
+// DefaultModel
+func NewOpenAIEmbedder(config *ProviderConfig) *OpenAIEmbedder {
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com"
+	}
+	model := config.DefaultModel
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{client: &http.Client{Timeout: timeout}, baseURL: baseURL, model: model}
+}
+
+// Model returns the embedding model, which embeddings must share to be compared
+func (e *OpenAIEmbedder) Model() string {
+	return "openai/" + e.model
+}
+
+// Embed returns an embedding for each text. Servers without authentication, like most
+// local ones, need no API key.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, apiKey string, texts []string) ([][]float32, error) {
+	headers := map[string]string{}
+	if apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		batch := texts[start:min(start+maxEmbeddingBatch, len(texts))]
+		resp, err := postJSON(ctx, e.client, "openai", e.baseURL+"/v1/embeddings", headers, OpenAIEmbeddingRequest{Model: e.model, Input: batch})
+		if err != nil {
+			return nil, err
+		}
+		var decoded OpenAIEmbeddingResponse
+		err = json.NewDecoder(resp.Body).Decode(&decoded)
+		resp.Body.Close()
+		if err != nil || len(decoded.Data) != len(batch) {
+			return nil, &llm.ProviderError{
+				Provider:  "openai",
+				ErrorType: llm.ErrorTypeInvalidJSON,
+				Message:   fmt.Sprintf("invalid embeddings response: %d embeddings for %d texts (%v)", len(decoded.Data), len(batch), err),
+			}
+		}
+		ordered := make([][]float32, len(batch))
+		for _, item := range decoded.Data {
+			if item.Index >= 0 && item.Index < len(ordered) {
+				ordered[item.Index] = item.Embedding
+			}
+		}
+		embeddings = append(embeddings, ordered...)
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,439 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// continueInstruction asks the model to continue a prefilled reply, which OpenAI's API
+// cannot prefill itself
+const continueInstruction = "Your reply above was cut off. Continue it exactly where it stopped, without repeating any of it or adding anything before it."
+
+// Base URLs of the APIs speaking OpenAI's chat completions protocol
+const (
+	openAIBaseURL     = "https://api.openai.com"
+	openRouterBaseURL = "https://openrouter.ai/api"
+)
+
+// OpenAIProvider implements the Provider interface for OpenAI and for the services
+// offering its chat completions API, like OpenRouter
+type OpenAIProvider struct {
+	*BaseProvider
+	client  *http.Client
+	baseURL string            // Used when the configuration sets none
+	headers map[string]string // Sent with every request besides the API key
+}
+
+// OpenAIRequest represents a request to the OpenAI API
+type OpenAIRequest struct {
+	Model               string                `json:"model"`
+	Messages            []OpenAIMessage       `json:"messages"`
+	ResponseFormat      *OpenAIResponseFormat `json:"response_format,omitempty"`
+	Temperature         *float64              `json:"temperature,omitempty"`
+	MaxCompletionTokens int                   `json:"max_completion_tokens,omitempty"`
+	Tools               []OpenAITool          `json:"tools,omitempty"`
+	ToolChoice          *OpenAIToolChoice     `json:"tool_choice,omitempty"`
+	Stream              bool                  `json:"stream,omitempty"`
+	StreamOptions       *OpenAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+// OpenAIStreamOptions asks for the usage in the last chunk of a streamed response
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIMessage represents a message for OpenAI API
+type OpenAIMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // A string, or []OpenAIContentPart for messages with parts
+}
+
+// OpenAIContentPart is a part of a message with several, e.g. text and images
+type OpenAIContentPart struct {
+	Type     string          `json:"type"` // "text" or "image_url"
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+}
+
+// OpenAIImageURL is the content of an image part, here always a data URL
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// OpenAIResponseFormat specifies the format of the response, e.g. "json_object"
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"` // Of a "json_schema" response
+}
+
+// OpenAIJSONSchema is the schema structured outputs hold a response to
+type OpenAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// OpenAITool describes a function the model may call
+type OpenAITool struct {
+	Type     string         `json:"type"` // Always "function"
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a function's name and parameters
+type OpenAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// OpenAIToolChoice forces the model to call a specific function
+type OpenAIToolChoice struct {
+	Type     string         `json:"type"` // Always "function"
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIResponse represents a response from the OpenAI API
+type OpenAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string `json:"content"`
+			Refusal   string `json:"refusal,omitempty"` // Set instead of content when the model refuses in JSON mode
+			ToolCalls []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"` // JSON-encoded arguments
+				} `json:"function"`
+			} `json:"tool_calls,omitempty"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason,omitempty"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+	Model string `json:"model,omitempty"`
+}
+
+// NewOpenAIProvider creates a new OpenAI provider
+func NewOpenAIProvider(config *ProviderConfig) *OpenAIProvider {
+	return newOpenAICompatibleProvider("openai", openAIBaseURL, nil, config)
+}
+
+// NewOpenRouterProvider creates a provider for OpenRouter, which serves the models of
+// many vendors through OpenAI's API
+func NewOpenRouterProvider(config *ProviderConfig) *OpenAIProvider {
+	headers := map[string]string{"HTTP-Referer": "https://github.com/yourusername/gogdbllm"}
+	return newOpenAICompatibleProvider("openrouter", openRouterBaseURL, headers, config)
+}
+
+// newOpenAICompatibleProvider creates a provider of a service offering OpenAI's API
+func newOpenAICompatibleProvider(name, baseURL string, headers map[string]string, config *ProviderConfig) *OpenAIProvider {
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &OpenAIProvider{
+		BaseProvider: NewBaseProvider(name, config),
+		client:       &http.Client{Timeout: timeout},
+		baseURL:      baseURL,
+		headers:      headers,
+	}
+}
+
+// SendRequest sends a request to the chat completions API
+func (op *OpenAIProvider) SendRequest(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	start := time.Now()
+
+	resp, err := postJSON(ctx, op.client, op.GetName(), op.url(), op.requestHeaders(), op.convertRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  op.GetName(),
+			ErrorType: llm.ErrorTypeNetwork,
+			Message:   fmt.Sprintf("failed to read response: %v", err),
+			Retryable: true,
+		}
+	}
+
+	var openAIResp OpenAIResponse
+	if err := json.Unmarshal(respBody, &openAIResp); err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  op.GetName(),
+			ErrorType: llm.ErrorTypeInternal,
+			Message:   fmt.Sprintf("failed to parse response: %v", err),
+			Retryable: false,
+		}
+	}
+
+	return op.convertResponse(&openAIResp, req, time.Since(start), string(respBody))
+}
+
+// openAIStreamChunk is a chunk of a streamed OpenAI response
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			Refusal   string `json:"refusal"`
+			ToolCalls []struct {
+				Function struct {
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"` // OpenRouter's report of a failure mid-stream
+}
+
+// StreamRequest streams a response from the chat completions API. With a tool, the
+// function's arguments are streamed rather than text.
+func (op *OpenAIProvider) StreamRequest(ctx context.Context, req *llm.Request, onDelta func(delta string)) (*llm.Response, error) {
+	start := time.Now()
+
+	openAIReq := op.convertRequest(req)
+	openAIReq.Stream = true
+	openAIReq.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
+
+	resp, err := postJSON(ctx, op.client, op.GetName(), op.url(), op.requestHeaders(), openAIReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	metadata := &llm.ProviderMetadata{}
+	response := &llm.Response{Provider: op.GetName(), RequestID: req.RequestID, Metadata: metadata}
+	var content strings.Builder
+	complete := false
+
+	err = readEvents(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			complete = true
+			return nil
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("unreadable chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return &llm.ProviderError{
+				Provider:  op.GetName(),
+				ErrorType: llm.ErrorTypeNetwork,
+				Message:   fmt.Sprintf("%s API error: %s", op.GetName(), chunk.Error.Message),
+				Retryable: true,
+			}
+		}
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			metadata.PromptTokens = chunk.Usage.PromptTokens
+			metadata.ResponseTokens = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			text := choice.Delta.Content + choice.Delta.Refusal
+			if req.Tool != nil {
+				text = ""
+				for _, call := range choice.Delta.ToolCalls {
+					text += call.Function.Arguments
+				}
+			}
+			if text != "" {
+				content.WriteString(text)
+				onDelta(text)
+			}
+			if choice.FinishReason != "" {
+				metadata.FinishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	var providerErr *llm.ProviderError
+	if errors.As(err, &providerErr) {
+		return nil, err
+	}
+	if err != nil || !complete {
+		return nil, streamError(op.GetName(), err)
+	}
+
+	response.Content = content.String()
+	response.TokensUsed = metadata.PromptTokens + metadata.ResponseTokens
+	metadata.ResponseTime = time.Since(start)
+	return response, nil
+}
+
+// url returns the chat completions endpoint
+func (op *OpenAIProvider) url() string {
+	baseURL := op.baseURL
+	if op.config.BaseURL != "" {
+		baseURL = op.config.BaseURL
+	}
+	return baseURL + "/v1/chat/completions"
+}
+
+// requestHeaders returns the headers sent with every request: the API key and the
+// service's own
+func (op *OpenAIProvider) requestHeaders() map[string]string {
+	headers := map[string]string{"Authorization": "Bearer " + op.config.APIKey}
+	for name, value := range op.headers {
+		headers[name] = value
+	}
+	return headers
+}
+
+// convertRequest converts a standard request to OpenAI format. The system prompt is the
+// first message.
+func (op *OpenAIProvider) convertRequest(req *llm.Request) *OpenAIRequest {
+	messages := make([]OpenAIMessage, 0, len(req.Messages)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, OpenAIMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	for _, msg := range req.Messages {
+		messages = append(messages, OpenAIMessage{Role: msg.Role, Content: openAIContent(msg)})
+	}
+	if req.Prefill != "" {
+		messages = append(messages,
+			OpenAIMessage{Role: "assistant", Content: req.Prefill},
+			OpenAIMessage{Role: "user", Content: continueInstruction})
+	}
+
+	openAIReq := &OpenAIRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+	}
+	if req.MaxTokens != nil && *req.MaxTokens > 0 {
+		openAIReq.MaxCompletionTokens = *req.MaxTokens
+	}
+	switch {
+	case req.Tool != nil:
+		function := OpenAIFunction{Name: req.Tool.Name, Description: req.Tool.Description, Parameters: req.Tool.Schema}
+		openAIReq.Tools = []OpenAITool{{Type: "function", Function: function}}
+		openAIReq.ToolChoice = &OpenAIToolChoice{Type: "function", Function: OpenAIFunction{Name: req.Tool.Name}}
+	case req.ResponseFormat != nil && req.Prefill == "":
+		// A continuation is not a JSON object by itself
+		openAIReq.ResponseFormat = &OpenAIResponseFormat{Type: req.ResponseFormat.Type}
+		if req.ResponseFormat.Type == llm.FormatJSONSchema {
+			openAIReq.ResponseFormat.JSONSchema = &OpenAIJSONSchema{Name: req.ResponseFormat.Name, Schema: req.ResponseFormat.Schema}
+		}
+	}
+	return openAIReq
+}
+
+// openAIContent converts a message's content: its text, or its parts, with images as
+// data URLs
+func openAIContent(msg llm.Message) interface{} {
+	if len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	parts := make([]OpenAIContentPart, len(msg.Parts))
+	for i, part := range msg.Parts {
+		switch part.Type {
+		case llm.ContentImage:
+			url := "data:" + part.MediaType + ";base64," + base64.StdEncoding.EncodeToString(part.Data)
+			parts[i] = OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: url}}
+		default:
+			parts[i] = OpenAIContentPart{Type: "text", Text: part.Text}
+		}
+	}
+	return parts
+}
+
+// convertResponse converts an OpenAI response to standard format: the forced tool's
+// arguments, the message or, when the model refused without one, the refusal
+func (op *OpenAIProvider) convertResponse(resp *OpenAIResponse, req *llm.Request, responseTime time.Duration, rawResp string) (*llm.Response, error) {
+	if len(resp.Choices) == 0 {
+		return nil, &llm.ProviderError{
+			Provider:  op.GetName(),
+			ErrorType: llm.ErrorTypeInternal,
+			Message:   "no content in response",
+			Retryable: false,
+		}
+	}
+
+	choice := resp.Choices[0]
+	content := choice.Message.Content
+	if content == "" && choice.Message.Refusal != "" {
+		content = choice.Message.Refusal
+	}
+	if req.Tool != nil {
+		for _, call := range choice.Message.ToolCalls {
+			if call.Function.Name == req.Tool.Name {
+				content = call.Function.Arguments
+				break
+			}
+		}
+	}
+
+	metadata := &llm.ProviderMetadata{
+		RawResponse:  rawResp,
+		FinishReason: choice.FinishReason,
+		ResponseTime: responseTime,
+	}
+	tokensUsed := 0
+	if resp.Usage != nil {
+		metadata.PromptTokens = resp.Usage.PromptTokens
+		metadata.ResponseTokens = resp.Usage.CompletionTokens
+		tokensUsed = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	}
+
+	return &llm.Response{
+		Content:    content,
+		TokensUsed: tokensUsed,
+		Model:      resp.Model,
+		Provider:   op.GetName(),
+		RequestID:  req.RequestID,
+		Metadata:   metadata,
+	}, nil
+}
+
+// GetSupportedModels returns the models offered through OpenAI's API. OpenRouter serves
+// too many to list; the model catalog asks it for them.
+func (op *OpenAIProvider) GetSupportedModels() []ModelInfo {
+	if op.GetName() != "openai" {
+		return nil
+	}
+	return []ModelInfo{
+		{
+			ID:           "gpt-4o",
+			Name:         "GPT-4o",
+			Description:  "Fast, intelligent model that accepts images",
+			MaxTokens:    128000,
+			Capabilities: []string{"text", "analysis", "coding", "vision"},
+			CostTier:     "premium",
+		},
+		{
+			ID:           "gpt-4o-mini",
+			Name:         "GPT-4o mini",
+			Description:  "Affordable model for simple tasks",
+			MaxTokens:    128000,
+			Capabilities: []string{"text", "simple-analysis", "vision"},
+			CostTier:     "economy",
+		},
+	}
+}
+
+// GetHealthStatus checks the health of the API
+func (op *OpenAIProvider) GetHealthStatus(ctx context.Context) (*HealthStatus, error) {
+	return checkHealth(ctx, op, op.config.DefaultModel)
+}
@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+func TestOpenAIConvertRequest(t *testing.T) {
+	provider := NewOpenAIProvider(&ProviderConfig{Name: "openai", APIKey: "key"})
+	maxTokens := 300
+	req := &llm.Request{
+		Model:        "gpt-4o",
+		SystemPrompt: "You debug programs",
+		MaxTokens:    &maxTokens,
+		Messages: []llm.Message{{Role: "user", Content: "Here it is", Parts: []llm.ContentPart{
+			{Type: llm.ContentImage, MediaType: "image/png", Data: []byte("png")},
+			{Type: llm.ContentText, Text: "Here it is"},
+		}}},
+		ResponseFormat: &llm.ResponseFormat{Type: llm.FormatJSONObject},
+	}
+
+	converted := provider.convertRequest(req)
+	require.Len(t, converted.Messages, 2)
+	assert.Equal(t, OpenAIMessage{Role: "system", Content: "You debug programs"}, converted.Messages[0])
+	assert.Equal(t, []OpenAIContentPart{
+		{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64,cG5n"}},
+		{Type: "text", Text: "Here it is"},
+	}, converted.Messages[1].Content)
+	assert.Equal(t, 300, converted.MaxCompletionTokens)
+	assert.Equal(t, &OpenAIResponseFormat{Type: "json_object"}, converted.ResponseFormat)
+
+	req.ResponseFormat = &llm.ResponseFormat{Type: llm.FormatJSONSchema, Name: "respond", Schema: json.RawMessage(`{"type":"object"}`)}
+	converted = provider.convertRequest(req)
+	assert.Equal(t, &OpenAIJSONSchema{Name: "respond", Schema: json.RawMessage(`{"type":"object"}`)}, converted.ResponseFormat.JSONSchema)
+
+	req.Tool = &llm.Tool{Name: "respond", Schema: json.RawMessage(`{"type":"object"}`)}
+	converted = provider.convertRequest(req)
+	assert.Nil(t, converted.ResponseFormat, "the tool replaces the JSON mode")
+	assert.Equal(t, "respond", converted.ToolChoice.Function.Name)
+}
+
+func TestOpenRouterSendRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-or-key", r.Header.Get("Authorization"))
+		assert.NotEmpty(t, r.Header.Get("HTTP-Referer"))
+		w.Write([]byte(`{
+			"model": "meta-llama/llama-3-70b",
+			"choices": [{"message": {"content": "", "refusal": "I cannot help with that"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 50, "completion_tokens": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	provider, err := New("openrouter", &ProviderConfig{Name: "openrouter", APIKey: "sk-or-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	resp, err := provider.SendRequest(context.Background(), &llm.Request{Model: "meta-llama/llama-3-70b", Messages: []llm.Message{{Role: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "I cannot help with that", resp.Content, "a refusal replaces missing content")
+	assert.Equal(t, "openrouter", resp.Provider)
+	assert.Equal(t, 57, resp.TokensUsed)
+
+	_, err = New("ollama", &ProviderConfig{})
+	assert.Error(t, err)
+}
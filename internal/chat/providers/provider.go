@@ -1,16 +1,21 @@
 package providers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 	"time"
 
-	"github.com/yourusername/gogdbllm/internal/chat"
+	"github.com/yourusername/gogdbllm/internal/llm"
 )
 
 // Provider defines the interface that all LLM providers must implement
 type Provider interface {
 	// SendRequest sends a standardized request to the provider
-	SendRequest(ctx context.Context, req *chat.StandardRequest) (*chat.StandardResponse, error)
+	SendRequest(ctx context.Context, req *llm.Request) (*llm.Response, error)
 
 	// ValidateConfig validates the provider configuration
 	ValidateConfig(config *ProviderConfig) error
@@ -22,7 +27,7 @@ type Provider interface {
 	GetName() string
 
 	// EstimateCost estimates the cost for a request (optional)
-	EstimateCost(req *chat.StandardRequest) float64
+	EstimateCost(req *llm.Request) float64
 
 	// GetHealthStatus checks if the provider is healthy
 	GetHealthStatus(ctx context.Context) (*HealthStatus, error)
@@ -47,10 +52,12 @@ type ProviderConfig struct {
 	CostPerToken *CostConfig `yaml:"cost_per_token,omitempty"`
 }
 
-// RateLimitConfig holds rate limiting configuration
+// RateLimitConfig holds rate limiting configuration. Requests that would exceed the
+// limits wait up to MaxWait for capacity and are rejected after that.
 type RateLimitConfig struct {
-	RequestsPerMinute int `yaml:"requests_per_minute"`
-	TokensPerMinute   int `yaml:"tokens_per_minute"`
+	RequestsPerMinute int           `yaml:"requests_per_minute"`
+	TokensPerMinute   int           `yaml:"tokens_per_minute"`
+	MaxWait           time.Duration `yaml:"max_wait,omitempty"`
 }
 
 // CostConfig holds cost calculation configuration
@@ -77,6 +84,25 @@ type HealthStatus struct {
 	ErrorMessage string        `json:"error_message,omitempty"`
 }
 
+// New creates the provider of a name: "anthropic", "openai" or "openrouter"
+func New(name string, config *ProviderConfig) (Provider, error) {
+	switch name {
+	case "anthropic":
+		return NewAnthropicProvider(config), nil
+	case "openai":
+		return NewOpenAIProvider(config), nil
+	case "openrouter":
+		return NewOpenRouterProvider(config), nil
+	default:
+		return nil, &llm.ProviderError{
+			Provider:  name,
+			ErrorType: llm.ErrorTypeValidation,
+			Message:   fmt.Sprintf("unsupported provider: %s", name),
+			Retryable: false,
+		}
+	}
+}
+
 // Registry manages all available providers
 type Registry struct {
 	providers map[string]Provider
@@ -91,13 +117,14 @@ func NewRegistry() *Registry {
 	}
 }
 
-// Register registers a provider with the registry
+// Register registers a provider with the registry. A provider with a RateLimitConfig is
+// rate limited accordingly.
 func (r *Registry) Register(name string, provider Provider, config *ProviderConfig) error {
 	if err := provider.ValidateConfig(config); err != nil {
 		return err
 	}
 
-	r.providers[name] = provider
+	r.providers[name] = withRateLimit(provider, config)
 	r.configs[name] = config
 	return nil
 }
@@ -134,6 +161,17 @@ func (r *Registry) GetProviderNames() []string {
 	return names
 }
 
+// RateLimitStats returns the throttle counts of each rate limited provider
+func (r *Registry) RateLimitStats() map[string]RateLimitStats {
+	stats := make(map[string]RateLimitStats)
+	for name, provider := range r.providers {
+		if limited, ok := provider.(*rateLimitedProvider); ok {
+			stats[name] = limited.Stats()
+		}
+	}
+	return stats
+}
+
 // GetProviderConfig returns the configuration for a provider
 func (r *Registry) GetProviderConfig(name string) (*ProviderConfig, bool) {
 	config, exists := r.configs[name]
@@ -144,22 +182,35 @@ func (r *Registry) GetProviderConfig(name string) (*ProviderConfig, bool) {
 func (r *Registry) UpdateProviderConfig(name string, config *ProviderConfig) error {
 	provider, exists := r.providers[name]
 	if !exists {
-		return &chat.ProviderError{
+		return &llm.ProviderError{
 			Provider:  name,
-			ErrorType: chat.ErrorTypeValidation,
+			ErrorType: llm.ErrorTypeValidation,
 			Message:   "provider not found",
 			Retryable: false,
 		}
 	}
 
+	if limited, ok := provider.(*rateLimitedProvider); ok {
+		provider = limited.Provider
+	}
 	if err := provider.ValidateConfig(config); err != nil {
 		return err
 	}
 
+	// The limits start afresh with the new configuration
+	r.providers[name] = withRateLimit(provider, config)
 	r.configs[name] = config
 	return nil
 }
 
+// withRateLimit wraps a provider with the rate limits of its configuration, if it has any
+func withRateLimit(provider Provider, config *ProviderConfig) Provider {
+	if config.RateLimit == nil || (config.RateLimit.RequestsPerMinute <= 0 && config.RateLimit.TokensPerMinute <= 0) {
+		return provider
+	}
+	return newRateLimitedProvider(provider, config.RateLimit)
+}
+
 // BaseProvider provides common functionality for all providers
 type BaseProvider struct {
 	name   string
@@ -182,18 +233,18 @@ func (bp *BaseProvider) GetName() string {
 // ValidateConfig provides basic validation for provider config
 func (bp *BaseProvider) ValidateConfig(config *ProviderConfig) error {
 	if config.Name == "" {
-		return &chat.ProviderError{
+		return &llm.ProviderError{
 			Provider:  bp.name,
-			ErrorType: chat.ErrorTypeValidation,
+			ErrorType: llm.ErrorTypeValidation,
 			Message:   "provider name is required",
 			Retryable: false,
 		}
 	}
 
 	if config.APIKey == "" {
-		return &chat.ProviderError{
+		return &llm.ProviderError{
 			Provider:  bp.name,
-			ErrorType: chat.ErrorTypeAuth,
+			ErrorType: llm.ErrorTypeAuth,
 			Message:   "API key is required",
 			Retryable: false,
 		}
@@ -203,7 +254,7 @@ func (bp *BaseProvider) ValidateConfig(config *ProviderConfig) error {
 }
 
 // EstimateCost provides basic cost estimation
-func (bp *BaseProvider) EstimateCost(req *chat.StandardRequest) float64 {
+func (bp *BaseProvider) EstimateCost(req *llm.Request) float64 {
 	if bp.config.CostPerToken == nil {
 		return 0.0
 	}
@@ -224,7 +275,7 @@ func (bp *BaseProvider) EstimateCost(req *chat.StandardRequest) float64 {
 }
 
 // estimateInputTokens estimates the number of input tokens
-func (bp *BaseProvider) estimateInputTokens(req *chat.StandardRequest) int {
+func (bp *BaseProvider) estimateInputTokens(req *llm.Request) int {
 	tokens := 0
 
 	// System prompt
@@ -234,7 +285,7 @@ func (bp *BaseProvider) estimateInputTokens(req *chat.StandardRequest) int {
 
 	// Messages
 	for _, msg := range req.Messages {
-		tokens += len(msg.Content) / 4 // Rough approximation
+		tokens += msg.EstimatedChars() / 4 // Rough approximation
 	}
 
 	return tokens
@@ -253,3 +304,114 @@ func (bp *BaseProvider) GetHealthStatus(ctx context.Context) (*HealthStatus, err
 		LastCheck:    time.Now(),
 	}, nil
 }
+
+// checkHealth sends a provider a minimal request for a model, reporting it healthy when
+// it answers
+func checkHealth(ctx context.Context, provider Provider, model string) (*HealthStatus, error) {
+	start := time.Now()
+
+	// Create a minimal test request
+	testReq := &llm.Request{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: "user", Content: "Hello"},
+		},
+		MaxTokens: &[]int{10}[0],
+		RequestID: "health-check",
+	}
+
+	// Try to send the request
+	_, err := provider.SendRequest(ctx, testReq)
+
+	responseTime := time.Since(start)
+
+	if err != nil {
+		return &HealthStatus{
+			Healthy:      false,
+			ResponseTime: responseTime,
+			LastCheck:    time.Now(),
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &HealthStatus{
+		Healthy:      true,
+		ResponseTime: responseTime,
+		LastCheck:    time.Now(),
+	}, nil
+}
+
+// postJSON sends body as JSON to a provider's API, returning the response when it
+// succeeded; the caller closes its body
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body interface{}) (*http.Response, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  provider,
+			ErrorType: llm.ErrorTypeInternal,
+			Message:   fmt.Sprintf("failed to marshal request: %v", err),
+			Retryable: false,
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  provider,
+			ErrorType: llm.ErrorTypeInternal,
+			Message:   fmt.Sprintf("failed to create HTTP request: %v", err),
+			Retryable: false,
+		}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, &llm.ProviderError{
+			Provider:  provider,
+			ErrorType: llm.ErrorTypeNetwork,
+			Message:   fmt.Sprintf("failed to send request: %v", err),
+			Retryable: true,
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, httpError(provider, resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+// httpError converts an error response from a provider's API to a provider error. Rate
+// limits and server errors, including Anthropic's 529 when overloaded, are retryable;
+// 402, OpenRouter's for exhausted credits, is a quota error.
+func httpError(provider string, statusCode int, body []byte) error {
+	var errorType string
+	var retryable bool
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		errorType = llm.ErrorTypeAuth
+	case statusCode == http.StatusPaymentRequired:
+		errorType = llm.ErrorTypeQuota
+	case statusCode == http.StatusTooManyRequests:
+		errorType = llm.ErrorTypeRateLimit
+		retryable = true
+	case statusCode >= http.StatusInternalServerError:
+		errorType = llm.ErrorTypeNetwork
+		retryable = true
+	default:
+		errorType = llm.ErrorTypeInternal
+	}
+
+	return &llm.ProviderError{
+		Provider:  provider,
+		ErrorType: errorType,
+		Message:   fmt.Sprintf("%s API error (status %d): %s", provider, statusCode, body),
+		Code:      statusCode,
+		Retryable: retryable,
+	}
+}
@@ -0,0 +1,183 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// defaultRateLimitWait is how long a request may be delayed when RateLimitConfig.MaxWait
+// is not set
+const defaultRateLimitWait = 10 * time.Second
+
+// defaultResponseTokens is the response size assumed when a request sets no MaxTokens
+const defaultResponseTokens = 500
+
+// RateLimitStats counts the requests a provider's rate limiter held back
+type RateLimitStats struct {
+	Delayed    uint64        `json:"delayed"`
+	Rejected   uint64        `json:"rejected"`
+	TotalDelay time.Duration `json:"totalDelay"`
+}
+
+// rateBucket is a token bucket that may go into debt: a reservation larger than the
+// tokens available is granted, and the caller waits until the debt is repaid
+type rateBucket struct {
+	rate   float64 // Tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateBucket creates a full bucket holding a minute's worth of perMinute
+func newRateBucket(perMinute int, now time.Time) *rateBucket {
+	return &rateBucket{rate: float64(perMinute) / 60, burst: float64(perMinute), tokens: float64(perMinute), last: now}
+}
+
+// take removes n tokens and returns how long until the bucket is out of debt
+func (b *rateBucket) take(now time.Time, n float64) time.Duration {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// give returns n tokens, e.g. when a reservation is abandoned or overestimated
+func (b *rateBucket) give(n float64) {
+	b.tokens = math.Min(b.burst, b.tokens+n)
+}
+
+// rateLimitedProvider enforces a provider's RateLimitConfig before requests reach the
+// vendor. Requests that would exceed the limits wait for capacity, up to MaxWait, and
+// are otherwise rejected with a rate limit error, as the vendor's 429 would be.
+type rateLimitedProvider struct {
+	Provider
+	maxWait  time.Duration
+	requests *rateBucket // nil when requests are not limited
+	tokens   *rateBucket // nil when tokens are not limited
+	stats    RateLimitStats
+	mutex    sync.Mutex
+}
+
+// newRateLimitedProvider wraps a provider with its configured rate limits
+func newRateLimitedProvider(provider Provider, cfg *RateLimitConfig) *rateLimitedProvider {
+	now := time.Now()
+	p := &rateLimitedProvider{Provider: provider, maxWait: cfg.MaxWait}
+	if p.maxWait <= 0 {
+		p.maxWait = defaultRateLimitWait
+	}
+	if cfg.RequestsPerMinute > 0 {
+		p.requests = newRateBucket(cfg.RequestsPerMinute, now)
+	}
+	if cfg.TokensPerMinute > 0 {
+		p.tokens = newRateBucket(cfg.TokensPerMinute, now)
+	}
+	return p
+}
+
+// SendRequest waits for capacity and sends the request, then corrects the token bucket
+// by the tokens the provider reports having used
+func (p *rateLimitedProvider) SendRequest(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	estimate := float64(estimateRequestTokens(req))
+	wait, err := p.reserve(time.Now(), estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			p.release(estimate)
+			return nil, ctx.Err()
+		}
+	}
+
+	resp, err := p.Provider.SendRequest(ctx, req)
+	if err == nil && resp.TokensUsed > 0 && p.tokens != nil {
+		p.mutex.Lock()
+		p.tokens.give(estimate - float64(resp.TokensUsed))
+		p.mutex.Unlock()
+	}
+	return resp, err
+}
+
+// reserve takes capacity for a request and returns how long to wait before sending it,
+// or a rate limit error if that would take longer than maxWait
+func (p *rateLimitedProvider) reserve(now time.Time, tokens float64) (time.Duration, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var wait time.Duration
+	if p.requests != nil {
+		wait = p.requests.take(now, 1)
+	}
+	if p.tokens != nil {
+		wait = max(wait, p.tokens.take(now, tokens))
+	}
+
+	if wait > p.maxWait {
+		p.releaseLocked(tokens)
+		p.stats.Rejected++
+		return 0, &llm.ProviderError{
+			Provider:  p.GetName(),
+			ErrorType: llm.ErrorTypeRateLimit,
+			Message:   fmt.Sprintf("%s rate limit reached; capacity is available again in %s", p.GetName(), wait.Round(time.Second)),
+			Code:      http.StatusTooManyRequests,
+			Retryable: true,
+		}
+	}
+	if wait > 0 {
+		p.stats.Delayed++
+		p.stats.TotalDelay += wait
+	}
+	return wait, nil
+}
+
+// release returns the capacity of a request that was not sent
+func (p *rateLimitedProvider) release(tokens float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.releaseLocked(tokens)
+}
+
+// releaseLocked returns the capacity of a request that was not sent. The caller holds the mutex.
+func (p *rateLimitedProvider) releaseLocked(tokens float64) {
+	if p.requests != nil {
+		p.requests.give(1)
+	}
+	if p.tokens != nil {
+		p.tokens.give(tokens)
+	}
+}
+
+// Stats returns the limiter's throttle counts
+func (p *rateLimitedProvider) Stats() RateLimitStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats
+}
+
+// estimateRequestTokens estimates the tokens a request will use: about four characters
+// per input token plus the response's MaxTokens, or a typical response size
+func estimateRequestTokens(req *llm.Request) int {
+	chars := len(req.SystemPrompt)
+	for _, msg := range req.Messages {
+		chars += msg.EstimatedChars()
+	}
+	output := defaultResponseTokens
+	if req.MaxTokens != nil && *req.MaxTokens > 0 {
+		output = *req.MaxTokens
+	}
+	return chars/4 + output
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+func TestRateLimitedProviderReserve(t *testing.T) {
+	fake, _ := newFakeProvider("limited", nil)
+	limited := newRateLimitedProvider(fake, &RateLimitConfig{RequestsPerMinute: 60, TokensPerMinute: 6000, MaxWait: 2 * time.Second})
+	now := time.Now()
+
+	// A full bucket lets a minute's worth of requests through at once
+	for i := 0; i < 60; i++ {
+		wait, err := limited.reserve(now, 10)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+
+	// Then requests are spaced out at the configured rate
+	wait, err := limited.reserve(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	// A request that would wait longer than MaxWait is rejected like a vendor 429
+	_, err = limited.reserve(now, 10)
+	require.NoError(t, err)
+	_, err = limited.reserve(now, 10)
+	var providerErr *llm.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, llm.ErrorTypeRateLimit, providerErr.ErrorType)
+	assert.True(t, ShouldFallback(err))
+
+	stats := limited.Stats()
+	assert.Equal(t, uint64(2), stats.Delayed)
+	assert.Equal(t, uint64(1), stats.Rejected)
+}
+
+func TestRegistryRateLimitsProviders(t *testing.T) {
+	registry := NewRegistry()
+	fake, cfg := newFakeProvider("limited", nil)
+	cfg.RateLimit = &RateLimitConfig{RequestsPerMinute: 1, MaxWait: time.Millisecond}
+	require.NoError(t, registry.Register("limited", fake, cfg))
+
+	provider, _, _ := registry.GetProvider("limited")
+	_, err := provider.SendRequest(context.Background(), &llm.Request{})
+	require.NoError(t, err)
+	_, err = provider.SendRequest(context.Background(), &llm.Request{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, fake.calls, "the throttled request never reaches the vendor")
+	assert.Equal(t, uint64(1), registry.RateLimitStats()["limited"].Rejected)
+}
@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/resilience"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// Router sends requests along an ordered list of provider routes, falling back to the
+// next route when a provider is rate limited or unavailable. Each provider has a circuit
+// breaker, and providers whose breaker is open are skipped without being called.
+type Router struct {
+	registry *Registry
+	routes   []config.RouteConfig
+	breaker  config.CircuitBreakerConfig
+	breakers map[string]*resilience.CircuitBreaker
+	mutex    sync.Mutex
+}
+
+// NewRouter creates a router over the registry's providers. Without routes it sends
+// requests to each enabled provider in name order.
+func NewRouter(registry *Registry, routing config.RoutingConfig, breaker config.CircuitBreakerConfig) *Router {
+	routes := routing.Routes
+	if len(routes) == 0 {
+		for _, name := range registry.GetProviderNames() {
+			if cfg, ok := registry.GetProviderConfig(name); ok && cfg.Enabled {
+				routes = append(routes, config.RouteConfig{Provider: name})
+			}
+		}
+		sort.Slice(routes, func(i, j int) bool { return routes[i].Provider < routes[j].Provider })
+	}
+	if breaker.FailureThreshold <= 0 {
+		breaker.FailureThreshold = 5
+	}
+	if breaker.RecoveryTimeout <= 0 {
+		breaker.RecoveryTimeout = 30 * time.Second
+	}
+	return &Router{
+		registry: registry,
+		routes:   routes,
+		breaker:  breaker,
+		breakers: make(map[string]*resilience.CircuitBreaker),
+	}
+}
+
+// Send sends a request along the routes and returns the first response. The request's
+// model is replaced by each route's model, or the provider's default model. The
+// response's Route records every route tried; when all fail the last error is returned.
+func (r *Router) Send(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	if len(r.routes) == 0 {
+		return nil, &llm.ProviderError{
+			Provider:  "router",
+			ErrorType: llm.ErrorTypeValidation,
+			Message:   "no providers are configured",
+		}
+	}
+
+	var attempts []llm.RouteAttempt
+	var lastErr error
+	for _, route := range r.routes {
+		provider, providerCfg, ok := r.registry.GetProvider(route.Provider)
+		if !ok || !providerCfg.Enabled {
+			continue
+		}
+		model := route.Model
+		if model == "" {
+			model = providerCfg.DefaultModel
+		}
+		attempt := llm.RouteAttempt{Provider: route.Provider, Model: model}
+
+		breaker := r.circuitBreaker(route.Provider)
+		if !breaker.Allow() {
+			attempt.Skipped = true
+			attempts = append(attempts, attempt)
+			lastErr = &resilience.CircuitBreakerError{State: resilience.StateOpen, Message: fmt.Sprintf("circuit breaker of %s is open", route.Provider)}
+			continue
+		}
+
+		routed := *req
+		routed.Model = model
+		resp, err := provider.SendRequest(ctx, &routed)
+		if err == nil {
+			breaker.Record(nil)
+			attempts = append(attempts, attempt)
+			resp.Route = attempts
+			return resp, nil
+		}
+
+		attempt.Error = err.Error()
+		attempts = append(attempts, attempt)
+		lastErr = err
+		if ctx.Err() != nil || !ShouldFallback(err) {
+			// Other errors, such as a rejected key or request, are not a reason to switch providers
+			return nil, err
+		}
+		breaker.Record(err)
+	}
+
+	if lastErr == nil {
+		lastErr = &llm.ProviderError{
+			Provider:  "router",
+			ErrorType: llm.ErrorTypeValidation,
+			Message:   "none of the routed providers is registered and enabled",
+		}
+	}
+	return nil, fmt.Errorf("all providers failed (%s): %w", describeAttempts(attempts), lastErr)
+}
+
+// ShouldFallback reports whether an error means the provider is rate limited, over quota
+// or unavailable, so a request may be sent to another provider
+func ShouldFallback(err error) bool {
+	if resilience.IsCircuitBreakerError(err) {
+		return true
+	}
+	var providerErr *llm.ProviderError
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+	switch providerErr.ErrorType {
+	case llm.ErrorTypeRateLimit, llm.ErrorTypeQuota, llm.ErrorTypeNetwork, llm.ErrorTypeTimeout:
+		return true
+	}
+	return providerErr.Code == http.StatusTooManyRequests || providerErr.Code >= http.StatusInternalServerError
+}
+
+// BreakerStates returns the circuit breaker state of each routed provider
+func (r *Router) BreakerStates() map[string]resilience.CircuitBreakerState {
+	states := make(map[string]resilience.CircuitBreakerState, len(r.routes))
+	for _, route := range r.routes {
+		states[route.Provider] = r.circuitBreaker(route.Provider).GetState()
+	}
+	return states
+}
+
+// circuitBreaker returns a provider's circuit breaker, creating it on first use
+func (r *Router) circuitBreaker(provider string) *resilience.CircuitBreaker {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	breaker, ok := r.breakers[provider]
+	if !ok {
+		breaker = resilience.NewCircuitBreaker(r.breaker.FailureThreshold, r.breaker.RecoveryTimeout)
+		r.breakers[provider] = breaker
+	}
+	return breaker
+}
+
+// describeAttempts summarizes the routes tried for an error message
+func describeAttempts(attempts []llm.RouteAttempt) string {
+	parts := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		if attempt.Skipped {
+			parts[i] = attempt.Provider + ": circuit open"
+		} else {
+			parts[i] = attempt.Provider + ": " + attempt.Error
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/chat/resilience"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// fakeProvider answers with its name, or fails with err
+type fakeProvider struct {
+	*BaseProvider
+	err   error
+	calls int
+}
+
+func newFakeProvider(name string, err error) (*fakeProvider, *ProviderConfig) {
+	cfg := &ProviderConfig{Name: name, Enabled: true, APIKey: "key", DefaultModel: name + "-default"}
+	return &fakeProvider{BaseProvider: NewBaseProvider(name, cfg), err: err}, cfg
+}
+
+func (p *fakeProvider) SendRequest(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &llm.Response{Content: "from " + p.GetName(), Provider: p.GetName(), Model: req.Model}, nil
+}
+
+func (p *fakeProvider) GetSupportedModels() []ModelInfo { return nil }
+
+func TestRouterFallsBack(t *testing.T) {
+	registry := NewRegistry()
+	primary, primaryCfg := newFakeProvider("primary", &llm.ProviderError{Provider: "primary", ErrorType: llm.ErrorTypeRateLimit, Code: 429, Message: "slow down"})
+	secondary, secondaryCfg := newFakeProvider("secondary", nil)
+	require.NoError(t, registry.Register("primary", primary, primaryCfg))
+	require.NoError(t, registry.Register("secondary", secondary, secondaryCfg))
+
+	router := NewRouter(registry, config.RoutingConfig{Routes: []config.RouteConfig{
+		{Provider: "primary", Model: "big"},
+		{Provider: "secondary"},
+	}}, config.CircuitBreakerConfig{FailureThreshold: 2, RecoveryTimeout: time.Minute})
+
+	resp, err := router.Send(context.Background(), &llm.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "from secondary", resp.Content)
+	assert.Equal(t, "secondary-default", resp.Model)
+	assert.Equal(t, "primary", resp.FallbackFrom())
+	require.Len(t, resp.Route, 2)
+	assert.Equal(t, "big", resp.Route[0].Model)
+	assert.Equal(t, "slow down", resp.Route[0].Error)
+
+	// Once the primary's circuit opens it is skipped without being called
+	_, err = router.Send(context.Background(), &llm.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, resilience.StateOpen, router.BreakerStates()["primary"])
+	resp, err = router.Send(context.Background(), &llm.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.calls)
+	assert.True(t, resp.Route[0].Skipped)
+}
+
+func TestRouterKeepsRequestErrors(t *testing.T) {
+	registry := NewRegistry()
+	primary, primaryCfg := newFakeProvider("primary", &llm.ProviderError{Provider: "primary", ErrorType: llm.ErrorTypeAuth, Code: 401, Message: "bad key"})
+	secondary, secondaryCfg := newFakeProvider("secondary", nil)
+	require.NoError(t, registry.Register("primary", primary, primaryCfg))
+	require.NoError(t, registry.Register("secondary", secondary, secondaryCfg))
+
+	// Without routes the enabled providers are tried in name order
+	router := NewRouter(registry, config.RoutingConfig{}, config.CircuitBreakerConfig{})
+	_, err := router.Send(context.Background(), &llm.Request{})
+	assert.EqualError(t, err, "bad key")
+	assert.Equal(t, 0, secondary.calls, "errors other than rate limits and outages are not retried elsewhere")
+}
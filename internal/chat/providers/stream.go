@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// maxEventSize is the largest server-sent event line read from a streamed response
+const maxEventSize = 4 * 1024 * 1024
+
+// StreamingProvider is a provider that can stream its responses
+type StreamingProvider interface {
+	Provider
+
+	// StreamRequest sends a request, passing each piece of the response's content to
+	// onDelta as it arrives, and returns the whole response. A stream that breaks off
+	// fails with a retryable network error after the pieces that arrived.
+	StreamRequest(ctx context.Context, req *llm.Request, onDelta func(delta string)) (*llm.Response, error)
+}
+
+// Stream receives a streamed response and passes it on as it arrives. It keeps what has
+// arrived, so the request sent again after the stream broke off, e.g. by a retry,
+// resumes rather than starting over: the provider is asked to continue the text received,
+// and only the continuation is passed on. Requests that cannot be continued, like those
+// forcing a tool, are answered again from the start, and only the part beyond what was
+// passed on already is.
+type Stream struct {
+	onDelta   func(delta string)
+	received  strings.Builder // The response so far
+	delivered int             // Bytes of the response passed to onDelta
+	resumes   int
+}
+
+// NewStream creates a stream passing the response to onDelta as it arrives
+func NewStream(onDelta func(delta string)) *Stream {
+	return &Stream{onDelta: onDelta}
+}
+
+// Resumes returns how many times a broken off response was resumed or started again
+func (s *Stream) Resumes() int {
+	return s.resumes
+}
+
+// Send streams a provider's response to a request, continuing the response received by
+// an earlier Send if that broke off. The returned response holds the whole text.
+func (s *Stream) Send(ctx context.Context, provider StreamingProvider, req *llm.Request) (*llm.Response, error) {
+	received := s.received.String()
+	s.received.Reset()
+	if received != "" {
+		s.resumes++
+		if req.Tool == nil {
+			resumed := *req
+			resumed.Prefill = received
+			req = &resumed
+			s.received.WriteString(received)
+		}
+	}
+
+	resp, err := provider.StreamRequest(ctx, req, s.receive)
+	if err != nil {
+		return nil, err
+	}
+	resp.Content = s.received.String()
+	return resp, nil
+}
+
+// receive adds a piece of the response, passing on what was not passed on before
+func (s *Stream) receive(delta string) {
+	s.received.WriteString(delta)
+	if s.received.Len() <= s.delivered {
+		return
+	}
+	fresh := s.received.String()[s.delivered:]
+	s.delivered = s.received.Len()
+	if s.onDelta != nil {
+		s.onDelta(fresh)
+	}
+}
+
+// readEvents reads the server-sent events of a streamed response, calling fn with each
+// event's name, if it has one, and data. Comments and events without data are skipped.
+func readEvents(body io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		defer func() { event, data = "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		return fn(event, strings.Join(data, "\n"))
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+// streamError is the error of a streamed response that could not be read to its end,
+// which a retry may complete
+func streamError(provider string, err error) error {
+	message := "stream ended before the response was complete"
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	return &llm.ProviderError{
+		Provider:  provider,
+		ErrorType: llm.ErrorTypeNetwork,
+		Message:   message,
+		Retryable: true,
+	}
+}
@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/llm"
+)
+
+// anthropicEvents renders a streamed Anthropic response of text deltas, ending it with
+// message_stop unless it breaks off
+func anthropicEvents(complete bool, deltas ...string) string {
+	var sb strings.Builder
+	sb.WriteString("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3-haiku\",\"usage\":{\"input_tokens\":10}}}\n\n")
+	for _, delta := range deltas {
+		text, _ := json.Marshal(delta)
+		fmt.Fprintf(&sb, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":%s}}\n\n", text)
+	}
+	if complete {
+		sb.WriteString("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":5}}\n\n")
+		sb.WriteString("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}
+	return sb.String()
+}
+
+func TestStreamResume(t *testing.T) {
+	var prefills []string
+	responses := []string{
+		anthropicEvents(false, "The crash is ", "in parse(). "),
+		anthropicEvents(true, " Check the pointer."),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AnthropicRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == "assistant" {
+			prefills = append(prefills, last.Content.(string))
+		}
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&ProviderConfig{Name: "anthropic", APIKey: "key", BaseURL: server.URL})
+	var deltas []string
+	stream := NewStream(func(delta string) { deltas = append(deltas, delta) })
+	req := &llm.Request{Model: "claude-3-haiku", Messages: []llm.Message{{Role: "user", Content: "why did it crash?"}}}
+
+	_, err := stream.Send(context.Background(), provider, req)
+	var providerErr *llm.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	assert.True(t, providerErr.Retryable, "a broken stream may be resumed")
+
+	resp, err := stream.Send(context.Background(), provider, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The crash is in parse()."}, prefills, "the resumed request continues the text received, without trailing spaces")
+	assert.Equal(t, "The crash is in parse(). Check the pointer.", resp.Content)
+	assert.Equal(t, []string{"The crash is ", "in parse(). ", "Check the pointer."}, deltas, "nothing is passed on twice")
+	assert.Equal(t, 1, stream.Resumes())
+	assert.Empty(t, req.Prefill, "the caller's request is left alone")
+}
+
+// scriptedStreamer streams its scripts' deltas in turn, failing a script with an error
+type scriptedStreamer struct {
+	Provider
+	scripts  [][]string
+	errs     []error
+	requests []*llm.Request
+}
+
+func (p *scriptedStreamer) StreamRequest(ctx context.Context, req *llm.Request, onDelta func(delta string)) (*llm.Response, error) {
+	p.requests = append(p.requests, req)
+	script, err := p.scripts[0], p.errs[0]
+	p.scripts, p.errs = p.scripts[1:], p.errs[1:]
+	for _, delta := range script {
+		onDelta(delta)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &llm.Response{Content: strings.Join(script, "")}, nil
+}
+
+func TestStreamRestart(t *testing.T) {
+	provider := &scriptedStreamer{
+		scripts: [][]string{{`{"text": "a null`}, {`{"text": `, `"a null pointer"}`}},
+		errs:    []error{streamError("openai", nil), nil},
+	}
+	var deltas []string
+	stream := NewStream(func(delta string) { deltas = append(deltas, delta) })
+	req := &llm.Request{Tool: &llm.Tool{Name: "respond"}}
+
+	_, err := stream.Send(context.Background(), provider, req)
+	require.Error(t, err)
+	resp, err := stream.Send(context.Background(), provider, req)
+	require.NoError(t, err)
+
+	assert.Empty(t, provider.requests[1].Prefill, "requests forcing a tool are answered again from the start")
+	assert.Equal(t, []string{`{"text": "a null`, ` pointer"}`}, deltas, "only the text beyond what was passed on is")
+	assert.Equal(t, `{"text": "a null pointer"}`, resp.Content)
+}
+
+func TestReadEvents(t *testing.T) {
+	body := ": OPENROUTER PROCESSING\n\ndata: {\"a\":1}\n\nevent: ping\ndata: one\ndata: two\n\ndata: [DONE]"
+	var events []string
+	require.NoError(t, readEvents(strings.NewReader(body), func(event, data string) error {
+		events = append(events, event+"|"+data)
+		return nil
+	}))
+	assert.Equal(t, []string{`|{"a":1}`, "ping|one\ntwo", "|[DONE]"}, events)
+}
+
+func TestOpenAIStreamTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.StreamOptions.IncludeUsage)
+		w.Write([]byte(`data: {"model":"gpt-4o","choices":[{"delta":{"tool_calls":[{"function":{"name":"respond","arguments":"{\"text\":"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"\"hi\"}"}}]},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":30,"completion_tokens":4}}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&ProviderConfig{Name: "openai", APIKey: "key", BaseURL: server.URL})
+	var streamed strings.Builder
+	resp, err := provider.StreamRequest(context.Background(), &llm.Request{
+		Model:    "gpt-4o",
+		Messages: []llm.Message{{Role: "user", Content: "hi"}},
+		Tool:     &llm.Tool{Name: "respond", Schema: json.RawMessage(`{"type":"object"}`)},
+	}, func(delta string) { streamed.WriteString(delta) })
+	require.NoError(t, err)
+	assert.Equal(t, `{"text":"hi"}`, resp.Content)
+	assert.Equal(t, resp.Content, streamed.String())
+	assert.Equal(t, 34, resp.TokensUsed)
+	assert.Equal(t, "stop", resp.Metadata.FinishReason)
+}
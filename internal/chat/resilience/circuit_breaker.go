@@ -66,6 +66,31 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 	return nil
 }
 
+// Allow reports whether a call may be made now, moving an open circuit whose timeout has
+// passed to half-open. Unlike Call it does not hold the breaker while the call runs; the
+// caller reports the outcome with Record.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.lastFailureTime) > cb.timeout {
+		cb.state = StateHalfOpen
+	}
+	return cb.state != StateOpen
+}
+
+// Record reports the outcome of a call admitted by Allow
+func (cb *CircuitBreaker) Record(err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if err != nil {
+		cb.recordFailure()
+		return
+	}
+	cb.recordSuccess()
+}
+
 // recordFailure records a failure and potentially opens the circuit
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failureCount++
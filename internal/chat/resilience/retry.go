@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/yourusername/gogdbllm/internal/chat"
+	"github.com/yourusername/gogdbllm/internal/llm"
 )
 
 // RetryConfig holds configuration for retry behavior
@@ -111,7 +111,7 @@ func (rm *RetryManager) shouldRetry(err error, attempt int) bool {
 	}
 
 	// Check if it's a provider error with retry information
-	if providerErr, ok := err.(*chat.ProviderError); ok {
+	if providerErr, ok := err.(*llm.ProviderError); ok {
 		return providerErr.Retryable
 	}
 
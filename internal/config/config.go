@@ -2,43 +2,365 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
-// Config holds all configuration for the application
+// Config holds all configuration for the application. Fields holding secrets are tagged
+// redact:"true", which Redact and Diff hide.
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	LLM     LLMConfig     `mapstructure:"llm"`
-	GDB     GDBConfig     `mapstructure:"gdb"`
-	Logs    LogConfig     `mapstructure:"logs"`
-	Uploads UploadsConfig `mapstructure:"uploads"`
-	Chat    ChatConfig    `mapstructure:"chat"`
+	Server     ServerConfig     `mapstructure:"server"`
+	LLM        LLMConfig        `mapstructure:"llm"`
+	GDB        GDBConfig        `mapstructure:"gdb"`
+	Logs       LogConfig        `mapstructure:"logs"`
+	Uploads    UploadsConfig    `mapstructure:"uploads"`
+	Chat       ChatConfig       `mapstructure:"chat"`
+	Features   FeaturesConfig   `mapstructure:"features"`
+	Compiler   CompilerConfig   `mapstructure:"compiler"`
+	Decompiler DecompilerConfig `mapstructure:"decompiler"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	WebSocket  WebSocketConfig  `mapstructure:"websocket"`
+	Secrets    SecretsConfig    `mapstructure:"secrets"`
+	Labs       LabsConfig       `mapstructure:"labs"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Prompts    PromptsConfig    `mapstructure:"prompts"`
+	Sessions   SessionsConfig   `mapstructure:"sessions"`
+	GRPC       GRPCConfig       `mapstructure:"grpc"`
+	DAP        DAPConfig        `mapstructure:"dap"`
+	MCP        MCPConfig        `mapstructure:"mcp"`
+	Triage     TriageConfig     `mapstructure:"triage"`
+	Sources    SourcesConfig    `mapstructure:"sources"`
+	Health     HealthConfig     `mapstructure:"health"`
+	Audit      AuditConfig      `mapstructure:"audit"`
+	Events     EventsConfig     `mapstructure:"events"`
+
+	// Overrides are set from command-line flags rather than loaded from the file
+	Overrides Overrides `mapstructure:"-"`
+
+	// File is the configuration file read, or "" if there was none
+	File string `mapstructure:"-"`
+}
+
+// Overrides holds values given on the command line, which take precedence over all other sources
+type Overrides struct {
+	Provider string
+	Model    string
+}
+
+// EnvPrefix is the prefix of environment variables that override configuration keys
+const EnvPrefix = "GOGDBLLM"
+
+// EnvVar returns the environment variable that overrides a configuration key, e.g.
+// llm.api_key -> GOGDBLLM_LLM_API_KEY
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// PromptsConfig holds prompt template configuration
+type PromptsConfig struct {
+	// Directory holds <name>.tmpl files overriding the built-in prompt templates
+	Directory      string                `mapstructure:"directory"`
+	DefaultProfile string                `mapstructure:"default_profile"` // Profile of users who have not chosen one
+	Profiles       []PromptProfileConfig `mapstructure:"profiles"`
+}
+
+// PromptProfileConfig defines a prompt profile, or changes the built-in profile of the
+// same name. Commands are matched word by word from the start, so "info" covers every
+// info command; a denied command wins over an allowed one.
+type PromptProfileConfig struct {
+	Name            string   `mapstructure:"name"`
+	Description     string   `mapstructure:"description"`
+	Instructions    string   `mapstructure:"instructions"`     // Template appended to the system prompt; defaults to the profile_<name> template
+	AllowedCommands []string `mapstructure:"allowed_commands"` // GDB commands the model may run; empty allows all
+	DeniedCommands  []string `mapstructure:"denied_commands"`
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
 	Port         int           `mapstructure:"port"`
+	Address      string        `mapstructure:"address"` // host:port to listen on; empty listens on every interface at Port
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// With both set the server speaks HTTPS. The files are checked every
+	// CertReloadInterval, so certificates renewed by an ACME client such as certbot are
+	// picked up without a restart; 0 loads them once.
+	CertFile           string        `mapstructure:"cert_file"`
+	KeyFile            string        `mapstructure:"key_file"`
+	CertReloadInterval time.Duration `mapstructure:"cert_reload_interval"`
+
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers are believed
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// BasePath is the path the server is reached under behind a proxy, e.g. "/gdb";
+	// requests under it are served with it stripped and the UI links into it
+	BasePath string `mapstructure:"base_path"`
+
+	// AllowedOrigins are the origins, e.g. "https://gdb.example.com", browsers may open
+	// websockets and send state-changing requests from; empty allows only the server's own
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+
+	// CSRF requires browsers' state-changing requests to carry the token the UI gets in a
+	// cookie, which pages on other sites cannot read
+	CSRF bool `mapstructure:"csrf"`
+
+	// ShutdownTimeout is how long a shutting-down server waits for in-flight chat
+	// requests to get their LLM response before cancelling them
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	// ReloadInterval is how often the config file is checked for changes, which are
+	// applied without a restart where possible, as on SIGHUP; 0 reloads only on SIGHUP
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// ListenAddress returns the address the server listens on
+func (c ServerConfig) ListenAddress() string {
+	if c.Address != "" {
+		return c.Address
+	}
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// TLS reports whether the server speaks HTTPS
+func (c ServerConfig) TLS() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// Validate checks the server's certificate, proxy and base path settings
+func (c ServerConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("server.cert_file and server.key_file must be set together")
+	}
+	if _, err := ParseCIDRs(c.TrustedProxies); err != nil {
+		return fmt.Errorf("server.trusted_proxies: %w", err)
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || strings.HasSuffix(c.BasePath, "/")) {
+		return fmt.Errorf("server.base_path must start and not end with /, e.g. /gdb")
+	}
+	return nil
+}
+
+// ParseCIDRs parses addresses and CIDR ranges; an address is a range of one
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", value)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
 }
 
 // LLMConfig holds configuration for LLM providers
 type LLMConfig struct {
 	DefaultProvider string `mapstructure:"default_provider"`
 	DefaultModel    string `mapstructure:"default_model"`
-	APIKey          string `mapstructure:"api_key"`
+	APIKey          string `mapstructure:"api_key" redact:"true"`
+
+	// ModelsCacheTTL is how long model lists fetched from the providers are reused
+	ModelsCacheTTL time.Duration `mapstructure:"models_cache_ttl"`
 }
 
 // GDBConfig holds GDB-related configuration
 type GDBConfig struct {
-	Path         string `mapstructure:"path"`
-	Timeout      int    `mapstructure:"timeout"`
-	MaxProcesses int    `mapstructure:"max_processes"`
+	Debugger       string               `mapstructure:"debugger"` // DebuggerGDB or DebuggerCDB
+	Backend        string               `mapstructure:"backend"`  // BackendLocal or BackendDocker
+	Docker         DockerConfig         `mapstructure:"docker"`
+	Kubernetes     KubernetesConfig     `mapstructure:"kubernetes"`
+	Path           string               `mapstructure:"path"`
+	Timeout        int                  `mapstructure:"timeout"`
+	MaxProcesses   int                  `mapstructure:"max_processes"`
+	PTY            bool                 `mapstructure:"pty"`                 // Run the program on a pseudo-terminal so it can read input
+	OutputLines    int                  `mapstructure:"output_buffer_lines"` // Recent terminal output lines kept for chat requests and the output API
+	Observe        ObserveConfig        `mapstructure:"observe"`
+	Restart        RestartConfig        `mapstructure:"restart"`
+	Debuginfod     DebuginfodConfig     `mapstructure:"debuginfod"`
+	RunUntil       RunUntilConfig       `mapstructure:"run_until"`
+	Emulation      EmulationConfig      `mapstructure:"emulation"`
+	Hooks          HooksConfig          `mapstructure:"hooks"`
+	Scripts        ScriptsConfig        `mapstructure:"scripts"`
+	PrettyPrinters PrettyPrintersConfig `mapstructure:"pretty_printers"`
+	Memory         MemoryConfig         `mapstructure:"memory"`
+}
+
+// EmulationConfig runs ELF executables built for another architecture than the server's,
+// e.g. ARM or RISC-V on x86-64, under qemu-user, whose GDB stub a multi-architecture GDB
+// connects to
+type EmulationConfig struct {
+	Enabled        bool                      `mapstructure:"enabled"`
+	GDBPath        string                    `mapstructure:"gdb_path"`        // A GDB that debugs every architecture, e.g. gdb-multiarch
+	ConnectTimeout time.Duration             `mapstructure:"connect_timeout"` // How long GDB waits for qemu's stub
+	Architectures  map[string]EmulatorConfig `mapstructure:"architectures"`   // By architecture, as binary metadata names it, e.g. "aarch64"
+}
+
+// EmulatorConfig is how executables of one architecture are emulated
+type EmulatorConfig struct {
+	QEMU    string   `mapstructure:"qemu"`    // The qemu-user binary, e.g. qemu-aarch64
+	Sysroot string   `mapstructure:"sysroot"` // Where the architecture's libraries are, for qemu -L and GDB's sysroot
+	Args    []string `mapstructure:"args"`    // More qemu options, e.g. ["-cpu", "max"]
+}
+
+// RunUntilConfig bounds how long the program may run when the assistant or the API
+// continues it, before it is interrupted
+type RunUntilConfig struct {
+	Timeout    time.Duration `mapstructure:"timeout"`     // When the request names none
+	MaxTimeout time.Duration `mapstructure:"max_timeout"` // Longer requests are cut to this
+}
+
+// HooksConfig limits the hooks users set on their sessions: GDB commands run before each
+// command the assistant runs, after each stop of the program or when it exits
+type HooksConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	MaxHooks    int  `mapstructure:"max_hooks"`    // Per session
+	MaxCommands int  `mapstructure:"max_commands"` // Per hook
+	KeepRuns    int  `mapstructure:"keep_runs"`    // Recent runs kept per session with their output
+}
+
+// ScriptsConfig limits the GDB Python scripts users upload to their sessions and run
+// there, besides the library shipped with the server
+type ScriptsConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	MaxSize     int  `mapstructure:"max_size"`     // Of a script, in bytes
+	MaxScripts  int  `mapstructure:"max_scripts"`  // Uploaded per session
+	KeepResults int  `mapstructure:"keep_results"` // Recent runs kept per session with their output
+	Timeout     int  `mapstructure:"timeout"`      // Seconds a run's output is collected; gdb.timeout if 0
+}
+
+// PrettyPrintersConfig lets GDB load the pretty-printers that come with a program's
+// language, so containers print as their elements rather than their internals.
+// libstdc++'s load from GDB's default auto-load path; Go's runtime-gdb.py and Rust's
+// printers load from the directories of their installation, which GDB must trust.
+type PrettyPrintersConfig struct {
+	Directories []string `mapstructure:"directories"` // Trusted to auto-load from and searched for the scripts programs name, as GDB sees them
+}
+
+// MemoryConfig limits who may change the debugged program's memory through the memory
+// API. It is a role the operator assigns rather than the user's prompt profile, which
+// users choose themselves.
+type MemoryConfig struct {
+	WriteRole string `mapstructure:"write_role"` // viewer, debugger or admin; admin if empty
+}
+
+// DebuginfodConfig lets GDB download the separate debug information and sources of the
+// libraries a program uses, e.g. libc, from debuginfod servers, so backtraces through
+// them show functions, arguments and lines
+type DebuginfodConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	URLs      []string      `mapstructure:"urls"`       // Servers to ask; the server's DEBUGINFOD_URLS if empty
+	CachePath string        `mapstructure:"cache_path"` // Where downloads are kept, as GDB sees it; the client's default if empty
+	Timeout   time.Duration `mapstructure:"timeout"`    // Per download, before giving up on a server
+}
+
+// Debuggers the server can drive
+const (
+	DebuggerGDB = "gdb" // GDB, including MinGW's GDB on Windows
+	DebuggerCDB = "cdb" // CDB from the Debugging Tools for Windows
+)
+
+// Where the debugger runs
+const (
+	BackendLocal      = "local"      // On the server
+	BackendDocker     = "docker"     // In a container per session
+	BackendKubernetes = "kubernetes" // In a pod per session
+)
+
+// DockerConfig configures the containers of the docker backend. The image must have the
+// debugger at gdb.path and the libraries the uploaded programs need.
+type DockerConfig struct {
+	Binary       string        `mapstructure:"binary"` // The docker CLI, or a compatible one like podman
+	Image        string        `mapstructure:"image"`
+	CPUs         string        `mapstructure:"cpus"`   // e.g. "1.5"
+	Memory       string        `mapstructure:"memory"` // e.g. "512m"
+	PidsLimit    int           `mapstructure:"pids_limit"`
+	Network      string        `mapstructure:"network"` // "none" keeps programs off the network
+	StartTimeout time.Duration `mapstructure:"start_timeout"`
+}
+
+// KubernetesConfig configures the pods of the kubernetes backend, created with kubectl.
+// The image must have the debugger at gdb.path and tar, which unpacks the session's
+// files in the pod.
+type KubernetesConfig struct {
+	Binary       string            `mapstructure:"binary"`    // kubectl
+	Context      string            `mapstructure:"context"`   // kubeconfig context; the current one if empty
+	Namespace    string            `mapstructure:"namespace"` // The context's namespace if empty
+	Image        string            `mapstructure:"image"`
+	CPU          string            `mapstructure:"cpu"`    // Request and limit, e.g. "500m"
+	Memory       string            `mapstructure:"memory"` // Request and limit, e.g. "512Mi"
+	NodeSelector map[string]string `mapstructure:"node_selector"`
+	StartTimeout time.Duration     `mapstructure:"start_timeout"`
+	CopyTimeout  time.Duration     `mapstructure:"copy_timeout"` // Copying the session's files into the pod
+}
+
+// Validate rejects unknown debuggers and backends
+func (c GDBConfig) Validate() error {
+	switch c.Debugger {
+	case "", DebuggerGDB, DebuggerCDB:
+	default:
+		return fmt.Errorf("unknown gdb.debugger %q (expected gdb or cdb)", c.Debugger)
+	}
+	switch c.Backend {
+	case "", BackendLocal:
+	case BackendDocker:
+		if c.Docker.Image == "" {
+			return fmt.Errorf("gdb.docker.image is required with gdb.backend docker")
+		}
+	case BackendKubernetes:
+		if c.Kubernetes.Image == "" {
+			return fmt.Errorf("gdb.kubernetes.image is required with gdb.backend kubernetes")
+		}
+	default:
+		return fmt.Errorf("unknown gdb.backend %q (expected local, docker or kubernetes)", c.Backend)
+	}
+	switch c.Memory.WriteRole {
+	case "", "viewer", "debugger", "admin":
+	default:
+		return fmt.Errorf("unknown gdb.memory.write_role %q (expected viewer, debugger or admin)", c.Memory.WriteRole)
+	}
+	if c.Emulation.Enabled && (c.Backend != "" && c.Backend != BackendLocal || c.Debugger == DebuggerCDB) {
+		return fmt.Errorf("gdb.emulation needs gdb.backend local and gdb.debugger gdb")
+	}
+	if c.Debuginfod.Enabled && c.Backend == BackendDocker && (c.Docker.Network == "" || c.Docker.Network == "none") {
+		return fmt.Errorf("gdb.debuginfod needs network access: set gdb.docker.network to a network that reaches the servers")
+	}
+	return nil
+}
+
+// RestartConfig controls the automatic restart of GDB when it exits on its own. A
+// restarted GDB loads the same executable and sets the breakpoints it had again.
+type RestartConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MaxRestarts int           `mapstructure:"max_restarts"` // Restarts allowed within Window before giving up
+	Window      time.Duration `mapstructure:"window"`
+}
+
+// ObserveConfig controls observe mode, which samples the backtraces of a running process.
+// Attaching to a process exposes its memory, so it is off by default.
+type ObserveConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	MaxDuration        time.Duration `mapstructure:"max_duration"`
+	MinInterval        time.Duration `mapstructure:"min_interval"`
+	AllowedExecutables []string      `mapstructure:"allowed_executables"` // Executable names that may be observed; empty allows any
 }
 
 // LogConfig holds logging configuration
@@ -48,26 +370,566 @@ type LogConfig struct {
 	JSONFormat bool   `mapstructure:"json_format"`
 }
 
+// AuditConfig controls the audit trail of the GDB commands run and the settings changed,
+// kept apart from the session logs for deployments with compliance requirements
+type AuditConfig struct {
+	Enabled   bool     `mapstructure:"enabled"`
+	Directory string   `mapstructure:"directory"` // Holds audit.jsonl
+	Admins    []string `mapstructure:"admins"`    // Users who may read every entry, where others read their own; with authentication disabled, anyone may if this is empty
+}
+
+// HealthConfig controls the checks behind /health: how long each may take, when a slow
+// one counts as degraded and how long results are reused
+type HealthConfig struct {
+	Timeout         time.Duration `mapstructure:"timeout"`          // Per check; a check that takes longer fails
+	DegradedLatency time.Duration `mapstructure:"degraded_latency"` // Checks slower than this are degraded
+	CacheTTL        time.Duration `mapstructure:"cache_ttl"`        // How long the results of local checks are reused
+	ProviderTTL     time.Duration `mapstructure:"provider_ttl"`     // How long the results of LLM provider checks are reused
+}
+
 // UploadsConfig holds file upload configuration
 type UploadsConfig struct {
-	Directory   string `mapstructure:"directory"`
-	MaxFileSize int64  `mapstructure:"max_file_size"` // in bytes
+	Directory      string `mapstructure:"directory"`
+	MaxFileSize    int64  `mapstructure:"max_file_size"`    // in bytes
+	MaxSourceSize  int64  `mapstructure:"max_source_size"`  // total extracted size of a source archive, in bytes
+	MaxSourceFiles int    `mapstructure:"max_source_files"` // number of files in a source archive
+
+	// Chunked uploads, for files over MaxFileSize such as binaries with debug information
+	MaxChunkedSize int64         `mapstructure:"max_chunked_size"` // in bytes
+	ChunkSize      int64         `mapstructure:"chunk_size"`       // most bytes sent per request
+	ChunkedTTL     time.Duration `mapstructure:"chunked_ttl"`      // Unfinished uploads idle this long are removed
+
+	// Uploads are stored once per content; stored binaries unused this long are removed
+	BinaryTTL time.Duration `mapstructure:"binary_ttl"`
+}
+
+// CompilerConfig holds configuration for compiling pasted source on the server
+type CompilerConfig struct {
+	CCPath        string        `mapstructure:"cc_path"`
+	CXXPath       string        `mapstructure:"cxx_path"`
+	DefaultFlags  []string      `mapstructure:"default_flags"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	MaxSourceSize int64         `mapstructure:"max_source_size"` // in bytes
+}
+
+// Decompilers that show the code of a function of an executable without debug information
+const (
+	DecompilerObjdump = "objdump" // Its disassembly, from objdump -d
+	DecompilerRetDec  = "retdec"  // C, from RetDec's retdec-decompiler
+	DecompilerGhidra  = "ghidra"  // C, from Ghidra's headless analyzer
+)
+
+// DecompilerConfig lets the assistant see the code of a function the user asks about when
+// the executable has no debug information, and so no source, by decompiling it
+type DecompilerConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Backend     string        `mapstructure:"backend"` // objdump, retdec or ghidra
+	ObjdumpPath string        `mapstructure:"objdump_path"`
+	RetDecPath  string        `mapstructure:"retdec_path"`  // retdec-decompiler
+	GhidraPath  string        `mapstructure:"ghidra_path"`  // analyzeHeadless, in Ghidra's support directory
+	ProjectsDir string        `mapstructure:"projects_dir"` // Where Ghidra keeps the executables it analyzed, to analyze each once
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxLines    int           `mapstructure:"max_lines"` // Longer code is cut
+}
+
+// Validate checks that an enabled decompiler is one the server knows
+func (c DecompilerConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Backend {
+	case DecompilerObjdump, DecompilerRetDec:
+	case DecompilerGhidra:
+		if c.GhidraPath == "" {
+			return fmt.Errorf("decompiler.ghidra_path is required with decompiler.backend ghidra")
+		}
+	default:
+		return fmt.Errorf("unknown decompiler.backend %q (expected objdump, retdec or ghidra)", c.Backend)
+	}
+	return nil
+}
+
+// AuthConfig holds authentication configuration
+type AuthConfig struct {
+	Mode         string            `mapstructure:"mode"`                // "none", "token" or "password"
+	Token        string            `mapstructure:"token" redact:"true"` // Shared secret for token mode
+	Users        map[string]string `mapstructure:"users" redact:"true"` // Username to password hash (see the hash-password command) for password mode
+	Roles        map[string]string `mapstructure:"roles"`               // Username to role: viewer, debugger or admin ("token" in token mode)
+	DefaultRole  string            `mapstructure:"default_role"`        // Role of users not in Roles
+	SessionTTL   time.Duration     `mapstructure:"session_ttl"`
+	CookieSecure bool              `mapstructure:"cookie_secure"` // Only send the session cookie over HTTPS; always set for HTTPS requests
+}
+
+// GRPCConfig configures the gRPC API. gRPC needs HTTP/2, which the server offers over
+// TLS with a certificate and key, or, with Plaintext, unencrypted (h2c) for a TLS
+// terminating proxy or sidecar in front of it.
+type GRPCConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Port      int    `mapstructure:"port"`
+	Plaintext bool   `mapstructure:"plaintext"`
+	CertFile  string `mapstructure:"cert_file"`
+	KeyFile   string `mapstructure:"key_file"`
+}
+
+// Validate checks that an enabled gRPC API has a certificate and key, unless it is
+// served in plaintext
+func (c GRPCConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Plaintext {
+		if c.CertFile != "" || c.KeyFile != "" {
+			return fmt.Errorf("grpc.cert_file and grpc.key_file cannot be set with grpc.plaintext")
+		}
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("grpc.cert_file and grpc.key_file are required when the gRPC API is enabled, unless grpc.plaintext is set")
+	}
+	return nil
+}
+
+// DAPConfig configures the Debug Adapter Protocol server. DAP clients connect over plain
+// TCP and send their credentials in the launch request, so the default address only
+// accepts local connections.
+type DAPConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// MCPConfig configures the Model Context Protocol endpoint, which lets external agents
+// drive the debugger through tools. The commands the tools run are limited by a prompt
+// profile's allowed and denied commands, as the assistant's are.
+type MCPConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Profile string `mapstructure:"profile"` // Defaults to prompts.default_profile
+}
+
+// TriageConfig configures the endpoint CI systems post crashes to. Each integration
+// authenticates with its own token and has the triage report posted back to it.
+type TriageConfig struct {
+	Enabled       bool                `mapstructure:"enabled"`
+	Directory     string              `mapstructure:"directory"`       // Keeps each job's artifacts and report
+	MaxUploadSize int64               `mapstructure:"max_upload_size"` // Bytes of binary, core dump and stderr together
+	MaxQueued     int                 `mapstructure:"max_queued"`      // Jobs waiting to run; more are refused
+	MaxTurns      int                 `mapstructure:"max_turns"`
+	Timeout       time.Duration       `mapstructure:"timeout"` // Per job
+	Profile       string              `mapstructure:"profile"` // Defaults to triage for core dumps, else prompts.default_profile
+	Integrations  []TriageIntegration `mapstructure:"integrations"`
+}
+
+// Kinds of triage integrations, which decide where the report is posted
+const (
+	TriageGitHub  = "github"  // A comment on the pull request named in the request
+	TriageSlack   = "slack"   // A message to a Slack incoming webhook
+	TriageWebhook = "webhook" // The JSON report POSTed to a URL
+)
+
+// TriageIntegration is a CI system allowed to post crashes
+type TriageIntegration struct {
+	Name        string `mapstructure:"name"`
+	Token       string `mapstructure:"token" redact:"true"` // Bearer token the CI system sends
+	Kind        string `mapstructure:"kind"`
+	URL         string `mapstructure:"url" redact:"true"`          // Webhook URL, a credential in itself, or the GitHub API URL (default https://api.github.com)
+	Repository  string `mapstructure:"repository"`                 // GitHub owner/name
+	GitHubToken string `mapstructure:"github_token" redact:"true"` // Token the comments are posted with
+}
+
+// Validate checks that an enabled endpoint's integrations can authenticate and post
+func (c TriageConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for i, integration := range c.Integrations {
+		if integration.Name == "" {
+			return fmt.Errorf("triage.integrations[%d]: name is required", i)
+		}
+		if names[integration.Name] {
+			return fmt.Errorf("triage.integrations: %s is defined twice", integration.Name)
+		}
+		names[integration.Name] = true
+		if integration.Token == "" || tokens[integration.Token] {
+			return fmt.Errorf("triage.integrations: %s needs a token of its own", integration.Name)
+		}
+		tokens[integration.Token] = true
+		switch integration.Kind {
+		case TriageGitHub:
+			if integration.Repository == "" || integration.GitHubToken == "" {
+				return fmt.Errorf("triage.integrations: %s needs repository and github_token", integration.Name)
+			}
+		case TriageSlack, TriageWebhook:
+			if integration.URL == "" {
+				return fmt.Errorf("triage.integrations: %s needs a url", integration.Name)
+			}
+		default:
+			return fmt.Errorf("triage.integrations: %s has unknown kind %q (want %s, %s or %s)",
+				integration.Name, integration.Kind, TriageGitHub, TriageSlack, TriageWebhook)
+		}
+	}
+	return nil
+}
+
+// EventsConfig configures where the events of debugging sessions, like a crash or a
+// spent budget, are sent. Each target receives the types of events it names.
+type EventsConfig struct {
+	Timeout     time.Duration `mapstructure:"timeout"`      // Per delivery attempt
+	MaxAttempts int           `mapstructure:"max_attempts"` // Tries of each delivery, including the first
+	Targets     []EventTarget `mapstructure:"targets"`
+}
+
+// Kinds of event targets, which decide how events are sent
+const (
+	EventWebhook = "webhook" // The event POSTed as JSON to a URL
+	EventSlack   = "slack"   // A message to a Slack incoming webhook
+	EventEmail   = "email"   // A mail sent through an SMTP server
+)
+
+// EventTarget is a destination of events
+type EventTarget struct {
+	Name     string   `mapstructure:"name"`
+	Kind     string   `mapstructure:"kind"`
+	Events   []string `mapstructure:"events"`               // Types of events sent; empty for every type
+	URL      string   `mapstructure:"url" redact:"true"`    // Of the webhook or Slack incoming webhook, a credential in itself
+	Secret   string   `mapstructure:"secret" redact:"true"` // Signs a webhook's bodies with HMAC-SHA256 when set
+	SMTP     string   `mapstructure:"smtp"`                 // host:port of the mail server
+	Username string   `mapstructure:"username"`             // Signs in to the mail server when set
+	Password string   `mapstructure:"password" redact:"true"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// SourcesConfig configures where a session's sources come from when none were uploaded
+type SourcesConfig struct {
+	GitHub GitHubSourcesConfig `mapstructure:"github"`
+}
+
+// GitHubSourcesConfig fetches the sources a binary's debug information refers to from the
+// GitHub repository and commit named with its upload
+type GitHubSourcesConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Token        string        `mapstructure:"token" redact:"true"` // For private repositories and a higher rate limit
+	APIURL       string        `mapstructure:"api_url"`             // For GitHub Enterprise; defaults to https://api.github.com
+	Repositories []string      `mapstructure:"repositories"`        // owner/name or owner/* that may be fetched; empty allows all
+	MaxFiles     int           `mapstructure:"max_files"`
+	MaxFileSize  int64         `mapstructure:"max_file_size"` // Larger files are skipped
+	Timeout      time.Duration `mapstructure:"timeout"`       // For fetching all of an upload's sources
+}
+
+// SessionsConfig controls how long an unused debugging session is kept. An idle session's
+// GDB is stopped, its log closed and its uploaded files deleted.
+type SessionsConfig struct {
+	IdleTTL      time.Duration `mapstructure:"idle_ttl"`      // 0 keeps idle sessions
+	ReapInterval time.Duration `mapstructure:"reap_interval"` // How often sessions are checked
+	ShareTTL     time.Duration `mapstructure:"share_ttl"`     // How long read-only share links last unless asked otherwise
+	ShareMaxTTL  time.Duration `mapstructure:"share_max_ttl"` // The longest a share link may last
+}
+
+// LabsConfig holds the catalog of lab targets students can start sessions on
+type LabsConfig struct {
+	Directory string   `mapstructure:"directory"` // Holds labs.json and the targets' executables
+	Admins    []string `mapstructure:"admins"`    // Users who may manage targets; with authentication disabled, anyone may if this is empty
+}
+
+// TracingConfig configures OpenTelemetry tracing of requests. Spans are sent to an OTLP
+// collector over HTTP in the JSON encoding.
+type TracingConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	Endpoint      string            `mapstructure:"endpoint"`              // OTLP/HTTP traces URL
+	Headers       map[string]string `mapstructure:"headers" redact:"true"` // Sent with every export, e.g. for authentication
+	ServiceName   string            `mapstructure:"service_name"`
+	SampleRatio   float64           `mapstructure:"sample_ratio"` // Fraction of new traces recorded
+	BatchSize     int               `mapstructure:"batch_size"`
+	FlushInterval time.Duration     `mapstructure:"flush_interval"`
+}
+
+// WebSocketConfig holds limits applied to messages from WebSocket clients
+type WebSocketConfig struct {
+	MaxMessageSize    int     `mapstructure:"max_message_size"`    // Bytes; larger messages are rejected
+	MaxCommandLength  int     `mapstructure:"max_command_length"`  // Characters in a single GDB command
+	CommandsPerSecond float64 `mapstructure:"commands_per_second"` // Sustained command rate per client
+	CommandBurst      int     `mapstructure:"command_burst"`       // Commands a client may send at once
+	MaxViolations     int     `mapstructure:"max_violations"`      // Consecutive rejected messages before disconnecting
+
+	// A client whose send queue stays full this long is disconnected rather than missing
+	// output indefinitely
+	SlowClientTimeout time.Duration `mapstructure:"slow_client_timeout"`
+}
+
+// SecretsConfig holds where API keys may be read from besides the settings file.
+// Per-provider environment variables (GOGDBLLM_<PROVIDER>_KEY) are always honoured.
+type SecretsConfig struct {
+	Keychain        bool   `mapstructure:"keychain"`         // Read keys from the OS keychain
+	KeychainService string `mapstructure:"keychain_service"` // Keychain service name; the provider is the account
 }
 
 // ChatConfig holds chat service configuration
 type ChatConfig struct {
-	Cache          CacheConfig          `mapstructure:"cache"`
-	Context        ContextConfig        `mapstructure:"context"`
-	Retry          RetryConfig          `mapstructure:"retry"`
-	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	Cache          CacheConfig           `mapstructure:"cache"`
+	Context        ContextConfig         `mapstructure:"context"`
+	Retry          RetryConfig           `mapstructure:"retry"`
+	CircuitBreaker CircuitBreakerConfig  `mapstructure:"circuit_breaker"`
+	Envelope       EnvelopeConfig        `mapstructure:"envelope"`
+	Output         OutputConfig          `mapstructure:"output"`
+	Attachments    AttachmentsConfig     `mapstructure:"attachments"`
+	Queue          QueueConfig           `mapstructure:"queue"`
+	Routing        RoutingConfig         `mapstructure:"routing"`
+	Cost           CostConfig            `mapstructure:"cost"`
+	Metrics        ChatMetricsConfig     `mapstructure:"metrics"`
+	PostProcessors []PostProcessorConfig `mapstructure:"post_processors"`
+	Retrieval      RetrievalConfig       `mapstructure:"retrieval"`
+}
+
+// Embedders create the embeddings retrieval compares chunks with
+const (
+	EmbedderOpenAI = "openai" // OpenAI's embeddings API, or a compatible server's at base_url
+	EmbedderHash   = "hash"   // Hashed words, computed in-process without a model or network
+)
+
+// RetrievalConfig turns on retrieval: the uploaded sources and session log of a debugging
+// session are indexed with embeddings, and the chunks most relevant to each chat message
+// are attached to it as context
+type RetrievalConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Embedder     string        `mapstructure:"embedder"`
+	Model        string        `mapstructure:"model"`    // Embedding model; the embedder's default when empty
+	BaseURL      string        `mapstructure:"base_url"` // OpenAI-compatible server, e.g. a local one; OpenAI's when empty
+	Timeout      time.Duration `mapstructure:"timeout"`  // Per embeddings request
+	TopK         int           `mapstructure:"top_k"`    // Chunks attached per message
+	MinScore     float64       `mapstructure:"min_score"`
+	ChunkLines   int           `mapstructure:"chunk_lines"`
+	ChunkOverlap int           `mapstructure:"chunk_overlap"` // Lines shared by consecutive chunks of a file
+	MaxFiles     int           `mapstructure:"max_files"`     // Source files indexed per session
+	MaxFileSize  int64         `mapstructure:"max_file_size"` // Bytes; larger files are skipped
+}
+
+// Validate rejects unknown embedders and chunk sizes that would not advance
+func (c RetrievalConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Embedder {
+	case EmbedderOpenAI, EmbedderHash:
+	default:
+		return fmt.Errorf("unknown chat.retrieval.embedder %q (expected openai or hash)", c.Embedder)
+	}
+	if c.TopK <= 0 || c.ChunkLines <= 0 {
+		return fmt.Errorf("chat.retrieval.top_k and chunk_lines must be positive")
+	}
+	if c.ChunkOverlap < 0 || c.ChunkOverlap >= c.ChunkLines {
+		return fmt.Errorf("chat.retrieval.chunk_overlap must be at least 0 and less than chunk_lines")
+	}
+	return nil
+}
+
+// PostProcessorConfig is one step of the chain rewriting LLM responses, in order, before
+// they are returned or their commands run. Which fields apply depends on the step.
+type PostProcessorConfig struct {
+	Name     string   `mapstructure:"name"`     // e.g. command_sanitizer, pii_filter, profanity_filter, markdown or source_links
+	URL      string   `mapstructure:"url"`      // source_links: link target, with {file} and {line}
+	Words    []string `mapstructure:"words"`    // profanity_filter: the words to mask
+	Patterns []string `mapstructure:"patterns"` // pii_filter: regular expressions to redact besides emails and API keys
+}
+
+// ChatMetricsConfig turns the per-provider request, error, retry and cache counts of
+// /api/chat/metrics on or off
+type ChatMetricsConfig struct {
+	Enabled bool                 `mapstructure:"enabled"`
+	History MetricsHistoryConfig `mapstructure:"history"`
+}
+
+// MetricsHistoryConfig keeps the chat metrics across restarts: what each provider did is
+// written to directory every interval, for /api/chat/metrics/history and the dashboard
+type MetricsHistoryConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Directory string        `mapstructure:"directory"`
+	Interval  time.Duration `mapstructure:"interval"`
+	Retention time.Duration `mapstructure:"retention"` // How long samples are kept; 0 keeps them forever
+}
+
+// CostConfig prices the tokens used by chat requests and caps what a debugging session
+// may spend
+type CostConfig struct {
+	SessionBudget float64       `mapstructure:"session_budget"` // US dollars; 0 for no limit
+	Prices        []PriceConfig `mapstructure:"prices"`
+}
+
+// PriceConfig is the price of a model in US dollars per million tokens. A model ending
+// in * matches every model with that prefix.
+type PriceConfig struct {
+	Model  string  `mapstructure:"model"`
+	Input  float64 `mapstructure:"input_per_mtok"`
+	Output float64 `mapstructure:"output_per_mtok"`
+}
+
+// RoutingConfig lists the providers and models a chat request is sent to, in order. A
+// request falls back to the next route when a provider is rate limited or unavailable.
+type RoutingConfig struct {
+	Routes []RouteConfig `mapstructure:"routes"`
+}
+
+// RouteConfig is one provider and model to route chat requests to. An empty model uses
+// the provider's default model.
+type RouteConfig struct {
+	Provider string `mapstructure:"provider"`
+	Model    string `mapstructure:"model"`
+}
+
+// QueueConfig limits the chat requests processed at once for a debugging session, so the
+// GDB commands of one request and their output are not interleaved with another's.
+// Requests beyond MaxConcurrent wait in order; once MaxQueued wait, further requests are
+// rejected.
+type QueueConfig struct {
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+	MaxQueued     int           `mapstructure:"max_queued"`
+	MaxWait       time.Duration `mapstructure:"max_wait"` // How long a request may wait before it is rejected
+}
+
+// OutputConfig limits the size of chat responses. Longer responses are stored as artifacts
+// and returned a page at a time.
+type OutputConfig struct {
+	MaxResponseSize int           `mapstructure:"max_response_size"` // Bytes per page
+	ArtifactDir     string        `mapstructure:"artifact_dir"`
+	ArtifactTTL     time.Duration `mapstructure:"artifact_ttl"`
+}
+
+// AttachmentsConfig limits the files uploaded for chat requests to refer to by ID: text,
+// e.g. logs, headers or linker maps, and images for models that accept them
+type AttachmentsConfig struct {
+	Directory     string        `mapstructure:"directory"`
+	MaxSize       int64         `mapstructure:"max_size"`        // Bytes per text file
+	MaxImageSize  int64         `mapstructure:"max_image_size"`  // Bytes per image
+	MaxPerSession int           `mapstructure:"max_per_session"` // Files kept per debugging session
+	TTL           time.Duration `mapstructure:"ttl"`             // Files older than this are removed
+}
+
+// Envelope modes control how a model is asked to structure its replies
+const (
+	EnvelopeJSON   = "json"   // Reply with a JSON object; commands run automatically
+	EnvelopeSchema = "schema" // Reply with a JSON object the provider holds to the response schema, where it can
+	EnvelopeTools  = "tools"  // Reply through the provider's tool calling; commands run automatically
+	EnvelopePlain  = "plain"  // Reply in free text; commands are only suggested to the user
+)
+
+// EnvelopeConfig selects the envelope mode per model, so models without reliable JSON
+// output can still be used
+type EnvelopeConfig struct {
+	Default string          `mapstructure:"default"` // Mode for models not listed in Models
+	Models  []ModelEnvelope `mapstructure:"models"`
+	// ReformatAttempts is how many times a reply that does not match the response schema
+	// is sent back to the model, with the schema's complaint, to be reformatted
+	ReformatAttempts int `mapstructure:"reformat_attempts"`
+}
+
+// ModelEnvelope sets the envelope mode of one model. It is a list entry rather than a map
+// key because viper would split model names containing dots (e.g. gpt-4.1) into nested keys.
+type ModelEnvelope struct {
+	Model string `mapstructure:"model"`
+	Mode  string `mapstructure:"mode"`
+}
+
+// Validate rejects unknown envelope modes and negative reformat attempts
+func (c EnvelopeConfig) Validate() error {
+	if c.ReformatAttempts < 0 {
+		return fmt.Errorf("chat.envelope.reformat_attempts must not be negative")
+	}
+	modes := []string{c.Default}
+	for _, entry := range c.Models {
+		modes = append(modes, entry.Mode)
+	}
+	for _, mode := range modes {
+		switch mode {
+		case "", EnvelopeJSON, EnvelopeSchema, EnvelopeTools, EnvelopePlain:
+		default:
+			return fmt.Errorf("unknown chat.envelope mode %q (expected json, schema, tools or plain)", mode)
+		}
+	}
+	return nil
+}
+
+// ModeFor returns the envelope mode for a model
+func (c EnvelopeConfig) ModeFor(model string) string {
+	for _, entry := range c.Models {
+		if strings.EqualFold(entry.Model, model) {
+			return entry.Mode
+		}
+	}
+	if c.Default == "" {
+		return EnvelopeJSON
+	}
+	return c.Default
 }
 
 // CacheConfig holds caching configuration
 type CacheConfig struct {
-	Enabled     bool          `mapstructure:"enabled"`
-	TTL         time.Duration `mapstructure:"ttl"`
-	MaxSize     int           `mapstructure:"max_size"`
-	Compression bool          `mapstructure:"compression"`
+	Enabled     bool                `mapstructure:"enabled"`
+	TTL         time.Duration       `mapstructure:"ttl"`
+	MaxSize     int                 `mapstructure:"max_size"`
+	Compression bool                `mapstructure:"compression"`
+	Backend     string              `mapstructure:"backend"`   // "memory", "disk" or "redis"
+	Directory   string              `mapstructure:"directory"` // Where the disk backend keeps entries
+	Redis       RedisConfig         `mapstructure:"redis"`
+	Admins      []string            `mapstructure:"admins"` // Users who may manage the cache; with authentication disabled, anyone may if this is empty
+	Semantic    SemanticCacheConfig `mapstructure:"semantic"`
+}
+
+// SemanticCacheConfig lets the cache answer a request from the response to a reworded
+// one: same provider, model, history and context, and a message whose embedding is at
+// least threshold similar
+type SemanticCacheConfig struct {
+	Enabled    bool               `mapstructure:"enabled"`
+	Threshold  float64            `mapstructure:"threshold"`  // Cosine similarity, up to 1
+	Providers  []string           `mapstructure:"providers"`  // Providers whose requests are matched semantically; all when empty
+	Thresholds map[string]float64 `mapstructure:"thresholds"` // Per-provider thresholds replacing threshold
+	Embedder   string             `mapstructure:"embedder"`   // EmbedderOpenAI or EmbedderHash
+	Model      string             `mapstructure:"model"`
+	BaseURL    string             `mapstructure:"base_url"`
+	Timeout    time.Duration      `mapstructure:"timeout"`
+}
+
+// Validate rejects unknown embedders and thresholds that are not similarities
+func (c SemanticCacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Embedder {
+	case EmbedderOpenAI, EmbedderHash:
+	default:
+		return fmt.Errorf("unknown chat.cache.semantic.embedder %q (expected openai or hash)", c.Embedder)
+	}
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		return fmt.Errorf("chat.cache.semantic.threshold must be more than 0 and at most 1")
+	}
+	for provider, threshold := range c.Thresholds {
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("chat.cache.semantic.thresholds.%s must be more than 0 and at most 1", provider)
+		}
+	}
+	return nil
+}
+
+// ThresholdFor returns the similarity a provider's requests must reach to match, or 0
+// when they are only matched exactly
+func (c SemanticCacheConfig) ThresholdFor(provider string) float64 {
+	if !c.Enabled || (len(c.Providers) > 0 && !slices.Contains(c.Providers, provider)) {
+		return 0
+	}
+	if threshold, ok := c.Thresholds[provider]; ok {
+		return threshold
+	}
+	return c.Threshold
+}
+
+// RedisConfig holds the connection to the Redis server of the redis cache backend
+type RedisConfig struct {
+	Addr     string        `mapstructure:"addr"` // host:port
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password" redact:"true"`
+	DB       int           `mapstructure:"db"`
+	Prefix   string        `mapstructure:"prefix"` // Prepended to every key, so servers can share a database
+	Timeout  time.Duration `mapstructure:"timeout"`
+	PoolSize int           `mapstructure:"pool_size"` // Idle connections kept open for concurrent requests
 }
 
 // ContextConfig holds context management configuration
@@ -79,8 +941,11 @@ type ContextConfig struct {
 	PreserveSystemContext  bool `mapstructure:"preserve_system_context"`
 }
 
-// RetryConfig holds retry logic configuration
+// RetryConfig holds retry logic configuration. Enabled retries the chat pipeline's LLM
+// calls that fail for a reason that may pass: a rate limit, a server error or a network
+// failure.
 type RetryConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
 	MaxAttempts       int           `mapstructure:"max_attempts"`
 	BaseDelay         time.Duration `mapstructure:"base_delay"`
 	MaxDelay          time.Duration `mapstructure:"max_delay"`
@@ -88,12 +953,29 @@ type RetryConfig struct {
 	BackoffMultiplier float64       `mapstructure:"backoff_multiplier"`
 }
 
-// CircuitBreakerConfig holds circuit breaker configuration
+// CircuitBreakerConfig holds circuit breaker configuration. Enabled fails the chat
+// pipeline's LLM calls to a provider at once after FailureThreshold failures in a row,
+// until RecoveryTimeout has passed; the provider router always has breakers.
 type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
 	FailureThreshold int           `mapstructure:"failure_threshold"`
 	RecoveryTimeout  time.Duration `mapstructure:"timeout"`
 }
 
+// FeaturesConfig holds feature flag configuration
+type FeaturesConfig struct {
+	RemoteURL       string                `mapstructure:"remote_url"`
+	RefreshInterval time.Duration         `mapstructure:"refresh_interval"`
+	Flags           map[string]FlagConfig `mapstructure:"flags"`
+}
+
+// FlagConfig holds the configuration of a single feature flag
+type FlagConfig struct {
+	Enabled  bool           `mapstructure:"enabled" json:"enabled"`
+	Rollout  int            `mapstructure:"rollout" json:"rollout"`   // Percentage of sessions (1-100) receiving the flag; 0 means all
+	Variants map[string]int `mapstructure:"variants" json:"variants"` // Optional weighted experiment variants
+}
+
 // LoadConfig loads configuration from files and environment variables
 func LoadConfig(configPath string) (*Config, error) {
 	v := viper.New()
@@ -117,7 +999,7 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	// Read environment variables
-	v.SetEnvPrefix("GOGDBLLM")
+	v.SetEnvPrefix(EnvPrefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
@@ -134,6 +1016,7 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	config.File = v.ConfigFileUsed()
 
 	return &config, nil
 }
@@ -144,15 +1027,64 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.read_timeout", 30*time.Second)
 	v.SetDefault("server.write_timeout", 30*time.Second)
+	v.SetDefault("server.shutdown_timeout", 30*time.Second)
+	v.SetDefault("server.reload_interval", 10*time.Second)
+	v.SetDefault("server.cert_reload_interval", time.Hour)
+	v.SetDefault("server.csrf", true)
 
 	// LLM defaults
 	v.SetDefault("llm.default_provider", "anthropic")
 	v.SetDefault("llm.default_model", "claude-3-sonnet-20240229")
+	v.SetDefault("llm.models_cache_ttl", time.Hour)
 
 	// GDB defaults
+	v.SetDefault("gdb.debugger", DebuggerGDB)
 	v.SetDefault("gdb.path", "gdb")
+	v.SetDefault("gdb.backend", BackendLocal)
+	v.SetDefault("gdb.docker.binary", "docker")
+	v.SetDefault("gdb.docker.cpus", "1")
+	v.SetDefault("gdb.docker.memory", "512m")
+	v.SetDefault("gdb.docker.pids_limit", 256)
+	v.SetDefault("gdb.docker.network", "none")
+	v.SetDefault("gdb.docker.start_timeout", 30*time.Second)
+	v.SetDefault("gdb.kubernetes.binary", "kubectl")
+	v.SetDefault("gdb.kubernetes.cpu", "1")
+	v.SetDefault("gdb.kubernetes.memory", "512Mi")
+	v.SetDefault("gdb.kubernetes.start_timeout", 2*time.Minute)
+	v.SetDefault("gdb.kubernetes.copy_timeout", 5*time.Minute)
 	v.SetDefault("gdb.timeout", 2)
 	v.SetDefault("gdb.max_processes", 5)
+	v.SetDefault("gdb.pty", true)
+	v.SetDefault("gdb.output_buffer_lines", 2000)
+	v.SetDefault("gdb.restart.enabled", true)
+	v.SetDefault("gdb.restart.max_restarts", 3)
+	v.SetDefault("gdb.restart.window", 5*time.Minute)
+	v.SetDefault("gdb.observe.enabled", false)
+	v.SetDefault("gdb.observe.max_duration", 20*time.Second)
+	v.SetDefault("gdb.observe.min_interval", 100*time.Millisecond)
+	v.SetDefault("gdb.debuginfod.enabled", false)
+	v.SetDefault("gdb.debuginfod.urls", []string{})
+	v.SetDefault("gdb.debuginfod.timeout", 30*time.Second)
+	v.SetDefault("gdb.run_until.timeout", 10*time.Second)
+	v.SetDefault("gdb.run_until.max_timeout", 25*time.Second)
+	v.SetDefault("gdb.hooks.enabled", true)
+	v.SetDefault("gdb.hooks.max_hooks", 10)
+	v.SetDefault("gdb.hooks.max_commands", 20)
+	v.SetDefault("gdb.hooks.keep_runs", 50)
+	v.SetDefault("gdb.memory.write_role", "admin")
+	v.SetDefault("gdb.scripts.enabled", true)
+	v.SetDefault("gdb.scripts.max_size", 65536)
+	v.SetDefault("gdb.scripts.max_scripts", 20)
+	v.SetDefault("gdb.scripts.keep_results", 20)
+	v.SetDefault("gdb.scripts.timeout", 3)
+	v.SetDefault("gdb.emulation.enabled", false)
+	v.SetDefault("gdb.emulation.gdb_path", "gdb-multiarch")
+	v.SetDefault("gdb.emulation.connect_timeout", 15*time.Second)
+	v.SetDefault("gdb.emulation.architectures", map[string]interface{}{
+		"aarch64": map[string]interface{}{"qemu": "qemu-aarch64", "sysroot": "/usr/aarch64-linux-gnu"},
+		"arm":     map[string]interface{}{"qemu": "qemu-arm", "sysroot": "/usr/arm-linux-gnueabihf"},
+		"riscv":   map[string]interface{}{"qemu": "qemu-riscv64", "sysroot": "/usr/riscv64-linux-gnu"},
+	})
 
 	// Logs defaults
 	v.SetDefault("logs.level", "info")
@@ -161,7 +1093,146 @@ func setDefaults(v *viper.Viper) {
 
 	// Uploads defaults
 	v.SetDefault("uploads.directory", "./uploads")
-	v.SetDefault("uploads.max_file_size", 10*1024*1024) // 10MB
+	v.SetDefault("labs.directory", "./labs")
+	v.SetDefault("sessions.idle_ttl", 2*time.Hour)
+	v.SetDefault("grpc.enabled", false)
+	v.SetDefault("grpc.port", 9090)
+	v.SetDefault("grpc.plaintext", false)
+	v.SetDefault("dap.enabled", false)
+	v.SetDefault("dap.address", "127.0.0.1:4711")
+	v.SetDefault("mcp.enabled", false)
+	v.SetDefault("triage.enabled", false)
+	v.SetDefault("triage.directory", "./triage")
+	v.SetDefault("triage.max_upload_size", 512*1024*1024) // 512MB
+	v.SetDefault("triage.max_queued", 16)
+	v.SetDefault("triage.max_turns", 5)
+	v.SetDefault("triage.timeout", 10*time.Minute)
+	v.SetDefault("sources.github.enabled", false)
+	v.SetDefault("sources.github.api_url", "https://api.github.com")
+	v.SetDefault("sources.github.max_files", 500)
+	v.SetDefault("sources.github.max_file_size", 1024*1024) // 1MB
+	v.SetDefault("sources.github.timeout", 30*time.Second)
+	v.SetDefault("sessions.reap_interval", time.Minute)
+	v.SetDefault("sessions.share_ttl", time.Hour)
+	v.SetDefault("sessions.share_max_ttl", 24*time.Hour)
+	v.SetDefault("health.timeout", 5*time.Second)
+	v.SetDefault("health.degraded_latency", 2*time.Second)
+	v.SetDefault("health.cache_ttl", 15*time.Second)
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.directory", "./logs/audit")
+	v.SetDefault("events.timeout", 10*time.Second)
+	v.SetDefault("events.max_attempts", 3)
+	v.SetDefault("health.provider_ttl", 5*time.Minute)
+	v.SetDefault("uploads.max_file_size", 10*1024*1024)    // 10MB
+	v.SetDefault("uploads.max_source_size", 100*1024*1024) // 100MB
+	v.SetDefault("uploads.max_source_files", 10000)
+	v.SetDefault("uploads.max_chunked_size", 2*1024*1024*1024) // 2GB
+	v.SetDefault("uploads.chunk_size", 8*1024*1024)            // 8MB
+	v.SetDefault("uploads.chunked_ttl", 24*time.Hour)
+	v.SetDefault("uploads.binary_ttl", 30*24*time.Hour)
+
+	// Compiler defaults
+	v.SetDefault("compiler.cc_path", "gcc")
+	v.SetDefault("compiler.cxx_path", "g++")
+	v.SetDefault("compiler.default_flags", []string{"-g", "-O0"})
+	v.SetDefault("compiler.timeout", 30*time.Second)
+	v.SetDefault("compiler.max_source_size", 1024*1024) // 1MB
+
+	// Decompiler defaults
+	v.SetDefault("decompiler.enabled", false)
+	v.SetDefault("decompiler.backend", DecompilerObjdump)
+	v.SetDefault("decompiler.objdump_path", "objdump")
+	v.SetDefault("decompiler.retdec_path", "retdec-decompiler")
+	v.SetDefault("decompiler.projects_dir", "./ghidra")
+	v.SetDefault("decompiler.timeout", 2*time.Minute)
+	v.SetDefault("decompiler.max_lines", 300)
+
+	// Auth defaults
+	v.SetDefault("auth.mode", "none")
+	v.SetDefault("auth.session_ttl", 24*time.Hour)
+	v.SetDefault("auth.default_role", "admin")
+	v.SetDefault("auth.cookie_secure", false)
+
+	// WebSocket defaults
+	v.SetDefault("websocket.max_message_size", 4096)
+	v.SetDefault("websocket.max_command_length", 1024)
+	v.SetDefault("websocket.commands_per_second", 5.0)
+	v.SetDefault("websocket.command_burst", 10)
+	v.SetDefault("websocket.max_violations", 20)
+	v.SetDefault("websocket.slow_client_timeout", 10*time.Second)
+
+	// Chat defaults
+	v.SetDefault("chat.envelope.default", EnvelopeJSON)
+	v.SetDefault("chat.envelope.reformat_attempts", 1)
+	v.SetDefault("chat.retrieval.enabled", false)
+	v.SetDefault("chat.retrieval.embedder", EmbedderOpenAI)
+	v.SetDefault("chat.retrieval.timeout", 30*time.Second)
+	v.SetDefault("chat.retrieval.top_k", 5)
+	v.SetDefault("chat.retrieval.min_score", 0.3)
+	v.SetDefault("chat.retrieval.chunk_lines", 40)
+	v.SetDefault("chat.retrieval.chunk_overlap", 10)
+	v.SetDefault("chat.retrieval.max_files", 2000)
+	v.SetDefault("chat.retrieval.max_file_size", 1024*1024) // 1MB
+	v.SetDefault("chat.cache.enabled", false)
+	v.SetDefault("chat.cache.ttl", time.Hour)
+	v.SetDefault("chat.cache.max_size", 1000)
+	v.SetDefault("chat.cache.backend", "memory")
+	v.SetDefault("chat.cache.directory", "./logs/cache")
+	v.SetDefault("chat.cache.redis.addr", "localhost:6379")
+	v.SetDefault("chat.cache.redis.prefix", "gogdbllm:cache:")
+	v.SetDefault("chat.cache.redis.timeout", 2*time.Second)
+	v.SetDefault("chat.cache.redis.pool_size", 8)
+	v.SetDefault("chat.cache.semantic.enabled", false)
+	v.SetDefault("chat.cache.semantic.threshold", 0.92)
+	v.SetDefault("chat.cache.semantic.embedder", EmbedderOpenAI)
+	v.SetDefault("chat.cache.semantic.timeout", 30*time.Second)
+	v.SetDefault("chat.output.max_response_size", 32*1024)
+	v.SetDefault("chat.output.artifact_dir", "./logs/artifacts")
+	v.SetDefault("chat.output.artifact_ttl", 24*time.Hour)
+	v.SetDefault("chat.attachments.directory", "./logs/attachments")
+	v.SetDefault("chat.attachments.max_size", 1024*1024)         // 1MB
+	v.SetDefault("chat.attachments.max_image_size", 5*1024*1024) // 5MB, Anthropic's limit
+	v.SetDefault("chat.attachments.max_per_session", 20)
+	v.SetDefault("chat.attachments.ttl", 24*time.Hour)
+	v.SetDefault("chat.retry.enabled", true)
+	v.SetDefault("chat.retry.max_attempts", 3)
+	v.SetDefault("chat.retry.base_delay", time.Second)
+	v.SetDefault("chat.retry.max_delay", 30*time.Second)
+	v.SetDefault("chat.retry.jitter", true)
+	v.SetDefault("chat.retry.backoff_multiplier", 2.0)
+	v.SetDefault("chat.circuit_breaker.enabled", true)
+	v.SetDefault("chat.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("chat.circuit_breaker.timeout", 30*time.Second)
+	v.SetDefault("chat.metrics.enabled", true)
+	v.SetDefault("chat.metrics.history.enabled", false)
+	v.SetDefault("chat.metrics.history.directory", "./logs/metrics")
+	v.SetDefault("chat.metrics.history.interval", time.Minute)
+	v.SetDefault("chat.metrics.history.retention", 30*24*time.Hour)
+	v.SetDefault("chat.queue.max_concurrent", 1)
+	v.SetDefault("chat.queue.max_queued", 4)
+	v.SetDefault("chat.queue.max_wait", 60*time.Second)
+
+	// Prompts defaults
+	v.SetDefault("prompts.directory", "./config/prompts")
+
+	// Secrets defaults
+	v.SetDefault("secrets.keychain", false)
+	v.SetDefault("secrets.keychain_service", "gogdbllm")
+
+	// Tracing defaults
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "http://localhost:4318/v1/traces")
+	v.SetDefault("tracing.service_name", "gogdbllm")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("tracing.batch_size", 256)
+	v.SetDefault("tracing.flush_interval", 5*time.Second)
+
+	// Feature flag defaults
+	v.SetDefault("features.refresh_interval", 5*time.Minute)
+	v.SetDefault("features.flags", map[string]interface{}{
+		"streaming":  map[string]interface{}{"enabled": false},
+		"agent_loop": map[string]interface{}{"enabled": true, "variants": map[string]int{"v1": 100}},
+	})
 }
 
 // WriteDefaultConfig writes a default configuration file
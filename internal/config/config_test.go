@@ -17,6 +17,9 @@ func TestLoadConfig(t *testing.T) {
 		assert.NotNil(t, cfg)
 		assert.Equal(t, 8080, cfg.Server.Port)
 		assert.Equal(t, "anthropic", cfg.LLM.DefaultProvider)
+		assert.True(t, cfg.Features.Flags["agent_loop"].Enabled)
+		assert.Equal(t, 100, cfg.Features.Flags["agent_loop"].Variants["v1"])
+		assert.Equal(t, []string{"-g", "-O0"}, cfg.Compiler.DefaultFlags)
 	})
 
 	// Test with file configuration
@@ -94,3 +97,55 @@ func TestWriteDefaultConfig(t *testing.T) {
 	assert.NotNil(t, cfg)
 	assert.Equal(t, 8080, cfg.Server.Port)
 }
+
+func TestDiff(t *testing.T) {
+	old := &Config{}
+	old.Chat.Cache.TTL = time.Hour
+	old.LLM.APIKey = "sk-ant-old"
+	new := *old
+	new.Chat.Cache.TTL = 2 * time.Hour
+	new.LLM.APIKey = "sk-ant-new"
+	new.Labs.Admins = []string{"alice"}
+	new.File = "config.yaml"
+
+	assert.Empty(t, Diff(old, old))
+	assert.Equal(t, []Change{
+		{Key: "chat.cache.ttl", Old: "1h0m0s", New: "2h0m0s"},
+		{Key: "labs.admins", Old: "[]", New: "[alice]"},
+		{Key: "llm.api_key", Old: "********", New: "********"},
+	}, Diff(old, &new), "sorted, with secrets redacted and the file name left out")
+}
+
+func TestGRPCConfigValidate(t *testing.T) {
+	assert.NoError(t, GRPCConfig{}.Validate())
+	assert.NoError(t, GRPCConfig{Enabled: true, CertFile: "tls.crt", KeyFile: "tls.key"}.Validate())
+	assert.NoError(t, GRPCConfig{Enabled: true, Plaintext: true}.Validate())
+
+	for _, invalid := range []GRPCConfig{
+		{Enabled: true},
+		{Enabled: true, CertFile: "tls.crt"},
+		{Enabled: true, Plaintext: true, CertFile: "tls.crt", KeyFile: "tls.key"},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	assert.NoError(t, ServerConfig{}.Validate())
+	assert.Equal(t, ":8080", ServerConfig{Port: 8080}.ListenAddress())
+	assert.Equal(t, "127.0.0.1:9000", ServerConfig{Port: 8080, Address: "127.0.0.1:9000"}.ListenAddress())
+
+	valid := ServerConfig{CertFile: "tls.crt", KeyFile: "tls.key", TrustedProxies: []string{"10.0.0.0/8", "::1"}, BasePath: "/gdb"}
+	assert.NoError(t, valid.Validate())
+	assert.True(t, valid.TLS())
+
+	for _, invalid := range []ServerConfig{
+		{CertFile: "tls.crt"},
+		{TrustedProxies: []string{"proxy.example.com"}},
+		{TrustedProxies: []string{"10.0.0.0/33"}},
+		{BasePath: "gdb"},
+		{BasePath: "/gdb/"},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is a setting whose value differs between two configurations
+type Change struct {
+	Key string `json:"key"` // As in the file, e.g. "chat.cache.ttl"
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Diff lists the settings whose values differ from old to new, sorted by key, with
+// secrets redacted. Values given on the command line are not compared.
+func Diff(old, new *Config) []Change {
+	oldValues, newValues, secrets := make(map[string]string), make(map[string]string), make(map[string]bool)
+	flatten("", reflect.ValueOf(*old), oldValues, secrets)
+	flatten("", reflect.ValueOf(*new), newValues, secrets)
+
+	var changes []Change
+	for key, value := range newValues {
+		if oldValues[key] == value {
+			continue
+		}
+		change := Change{Key: key, Old: oldValues[key], New: value}
+		if secrets[key] {
+			change.Old, change.New = Redacted, Redacted
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten records the settings of a configuration struct by their keys, and which of
+// them are secrets or, like the triage integrations, hold some
+func flatten(prefix string, v reflect.Value, values map[string]string, secrets map[string]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if value := v.Field(i); value.Kind() == reflect.Struct {
+			flatten(key, value, values, secrets)
+		} else {
+			values[key] = fmt.Sprintf("%v", value.Interface())
+			if secret(field) || holdsSecrets(field.Type) {
+				secrets[key] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Redacted replaces secret values when a configuration is shown
+const Redacted = "********"
+
+// Redact returns a copy of cfg with the fields tagged redact:"true" masked: strings that
+// are set, and every value of maps and slices. Slices, maps and pointers are copied on
+// the way down, so cfg itself is unchanged.
+func Redact(cfg Config) Config {
+	return redactValue(reflect.ValueOf(cfg)).Interface().(Config)
+}
+
+// redactValue returns a copy of v with its tagged fields masked
+func redactValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if secret(field) {
+				out.Field(i).Set(mask(v.Field(i)))
+			} else if holdsSecrets(field.Type) {
+				out.Field(i).Set(redactValue(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(iter.Key(), redactValue(iter.Value()))
+		}
+		return out
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(redactValue(v.Elem()))
+		return out
+	}
+	return v
+}
+
+// mask hides a secret: a string that is set, or each value of a map or slice. Secrets of
+// other types are left out altogether.
+func mask(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return v
+		}
+		return reflect.ValueOf(Redacted).Convert(v.Type())
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(iter.Key(), mask(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(mask(v.Index(i)))
+		}
+		return out
+	}
+	return reflect.Zero(v.Type())
+}
+
+// secret reports whether a field is tagged as holding a secret
+func secret(field reflect.StructField) bool {
+	return field.Tag.Get("redact") == "true"
+}
+
+// holdsSecrets reports whether values of t have tagged fields anywhere inside them
+func holdsSecrets(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.IsExported() && (secret(field) || holdsSecrets(field.Type)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+		return holdsSecrets(t.Elem())
+	}
+	return false
+}
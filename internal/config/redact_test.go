@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	cfg := Config{}
+	cfg.Chat.Cache.Redis.Password = "redis-pass"
+	cfg.Tracing.Headers = map[string]string{"Authorization": "Bearer otlp"}
+	cfg.Triage.Integrations = []TriageIntegration{
+		{Name: "gh", Token: "t0ken", Kind: "github", Repository: "acme/app", GitHubToken: "ghp_x"},
+		{Name: "chat", Token: "t0ken", Kind: "slack", URL: "https://hooks.slack.com/services/T/B/y"},
+	}
+	cfg.Events.Targets = []EventTarget{
+		{Name: "slack", Kind: EventSlack, URL: "https://hooks.slack.com/services/T/B/x"},
+		{Name: "ci", Kind: EventWebhook, URL: "https://example.com/hook", Secret: "s3cret"},
+		{Name: "oncall", Kind: EventEmail, SMTP: "smtp.example.com:587", Username: "gdb", Password: "hunter2"},
+	}
+
+	redacted := Redact(cfg)
+	assert.Equal(t, Redacted, redacted.Chat.Cache.Redis.Password)
+	assert.Equal(t, map[string]string{"Authorization": Redacted}, redacted.Tracing.Headers)
+	assert.Equal(t, Redacted, redacted.Triage.Integrations[0].Token)
+	assert.Equal(t, Redacted, redacted.Triage.Integrations[0].GitHubToken)
+	assert.Equal(t, "acme/app", redacted.Triage.Integrations[0].Repository)
+	assert.Equal(t, Redacted, redacted.Triage.Integrations[1].URL, "a Slack URL is a credential in itself")
+	assert.Equal(t, Redacted, redacted.Events.Targets[0].URL)
+	assert.Empty(t, redacted.Events.Targets[0].Secret, "unset secrets stay empty")
+	assert.Equal(t, Redacted, redacted.Events.Targets[1].Secret)
+	assert.Equal(t, Redacted, redacted.Events.Targets[2].Password)
+	assert.Equal(t, "smtp.example.com:587", redacted.Events.Targets[2].SMTP)
+
+	// The configuration itself is unchanged
+	assert.Equal(t, "Bearer otlp", cfg.Tracing.Headers["Authorization"])
+	assert.Equal(t, "https://hooks.slack.com/services/T/B/y", cfg.Triage.Integrations[1].URL)
+	assert.Equal(t, "https://hooks.slack.com/services/T/B/x", cfg.Events.Targets[0].URL)
+}
+
+// fillSecrets sets every tagged field reachable from v, adding an element to slices and
+// maps on the way, to a value naming its path
+func fillSecrets(t *testing.T, path string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := path + "." + field.Name
+			if !secret(field) {
+				fillSecrets(t, fieldPath, v.Field(i))
+				continue
+			}
+			leak := reflect.ValueOf("leaked" + fieldPath)
+			switch field.Type.Kind() {
+			case reflect.String:
+				v.Field(i).Set(leak.Convert(field.Type))
+			case reflect.Map:
+				v.Field(i).Set(reflect.MakeMap(field.Type))
+				v.Field(i).SetMapIndex(reflect.ValueOf("key").Convert(field.Type.Key()), leak.Convert(field.Type.Elem()))
+			case reflect.Slice:
+				v.Field(i).Set(reflect.Append(reflect.MakeSlice(field.Type, 0, 1), leak.Convert(field.Type.Elem())))
+			default:
+				t.Fatalf("%s: redact:\"true\" on a %s", fieldPath, field.Type)
+			}
+		}
+	case reflect.Slice:
+		if holdsSecrets(v.Type()) {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+			fillSecrets(t, path+"[0]", v.Index(0))
+		}
+	case reflect.Map:
+		if holdsSecrets(v.Type()) {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			fillSecrets(t, path+"[key]", elem)
+			v.Set(reflect.MakeMap(v.Type()))
+			v.SetMapIndex(reflect.ValueOf("key").Convert(v.Type().Key()), elem)
+		}
+	case reflect.Ptr:
+		if holdsSecrets(v.Type()) {
+			v.Set(reflect.New(v.Type().Elem()))
+			fillSecrets(t, path, v.Elem())
+		}
+	}
+}
+
+// TestRedactHidesEveryTaggedField fails when any field tagged redact:"true" reaches the
+// redacted configuration or a diff
+func TestRedactHidesEveryTaggedField(t *testing.T) {
+	var cfg Config
+	fillSecrets(t, "Config", reflect.ValueOf(&cfg).Elem())
+	before, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	require.Greater(t, strings.Count(string(before), "leaked"), 10, "every tagged field is set")
+
+	redacted, err := json.Marshal(Redact(cfg))
+	require.NoError(t, err)
+	assert.NotContains(t, string(redacted), "leaked")
+	after, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after), "the configuration itself is unchanged")
+
+	for _, change := range Diff(&Config{}, &cfg) {
+		assert.NotContains(t, fmt.Sprint(change), "leaked", change.Key)
+	}
+}
@@ -0,0 +1,106 @@
+package dap
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patterns of GDB's output the adapter turns into DAP responses and events
+var (
+	// "Breakpoint 2 at 0x1149: file crash.c, line 5."
+	breakpointSet = regexp.MustCompile(`Breakpoint (\d+) at 0x[0-9a-fA-F]+: file (.+), line (\d+)\.`)
+	// "Breakpoint 2, main () at crash.c:5"
+	breakpointHit = regexp.MustCompile(`^(?:Temporary breakpoint|Breakpoint) (\d+), `)
+	// "Program received signal SIGSEGV, Segmentation fault."
+	signalReceived = regexp.MustCompile(`^Program received signal (\w+), (.+?)\.?$`)
+	// "[Inferior 1 (process 4242) exited normally]" or "... exited with code 01]"
+	inferiorExited = regexp.MustCompile(`^\[Inferior \d+ \(process \d+\) exited (?:normally|with code (\d+))\]`)
+	// "#1  0x0000555555555171 in crash (p=0x0) at crash.c:5"
+	backtraceFrame = regexp.MustCompile(`^#(\d+)\s+(?:0x[0-9a-fA-F]+ in )?(\S+) \(.*?\)(?: at (\S+):(\d+))?`)
+	// "$1 = 42"
+	printValue = regexp.MustCompile(`(?s)^\$\d+ = (.*)$`)
+	// "x = 42" in info locals and info args
+	variableLine = regexp.MustCompile(`^([A-Za-z_][\w.]*) = (.*)$`)
+)
+
+// breakpointResult reads GDB's confirmation of a breakpoint
+func breakpointResult(output string, line int) Breakpoint {
+	if m := breakpointSet.FindStringSubmatch(output); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		line, _ = strconv.Atoi(m[3])
+		return Breakpoint{ID: id, Verified: true, Line: line}
+	}
+	return Breakpoint{Line: line, Message: firstLine(output)}
+}
+
+// parseBacktrace reads the frames of GDB's backtrace output
+func parseBacktrace(output string) []StackFrame {
+	var frames []StackFrame
+	for _, line := range strings.Split(output, "\n") {
+		m := backtraceFrame.FindStringSubmatch(trimPrompt(line))
+		if m == nil {
+			continue
+		}
+		level, _ := strconv.Atoi(m[1])
+		frame := StackFrame{ID: level + 1, Name: m[2]}
+		if m[3] != "" {
+			frame.Line, _ = strconv.Atoi(m[4])
+			frame.Column = 1
+			frame.Source = &Source{Name: filepath.Base(m[3]), Path: m[3]}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// parseVariables reads the variables GDB's info locals or info args lists. A value GDB
+// prints over several lines, like a struct, continues with indented lines and a closing
+// brace.
+func parseVariables(output string) []Variable {
+	var variables []Variable
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		for strings.HasPrefix(line, "(gdb) ") {
+			line = strings.TrimPrefix(line, "(gdb) ")
+		}
+		if m := variableLine.FindStringSubmatch(line); m != nil {
+			variables = append(variables, Variable{Name: m[1], Value: m[2]})
+		} else if len(variables) > 0 && strings.TrimSpace(line) != "" {
+			last := &variables[len(variables)-1]
+			last.Value += " " + strings.TrimSpace(line)
+		}
+	}
+	return variables
+}
+
+// parseValue returns the value GDB printed, or its output if it printed no value, e.g.
+// an error
+func parseValue(output string) string {
+	output = trimPrompt(strings.TrimSuffix(strings.TrimSpace(output), "(gdb)"))
+	if m := printValue.FindStringSubmatch(output); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return output
+}
+
+// trimPrompt removes the prompts GDB printed before the output on a line, since the
+// commands it reads are not echoed, and surrounding space
+func trimPrompt(line string) string {
+	line = strings.TrimSpace(line)
+	for strings.HasPrefix(line, "(gdb)") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "(gdb)"))
+	}
+	return line
+}
+
+// firstLine returns the first non-empty line of output
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = trimPrompt(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package dap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakpointResult(t *testing.T) {
+	assert.Equal(t, Breakpoint{ID: 2, Verified: true, Line: 6},
+		breakpointResult("(gdb) Breakpoint 2 at 0x1149: file crash.c, line 6.\n", 5))
+	assert.Equal(t, Breakpoint{Line: 40, Message: `No source file named nope.c.`},
+		breakpointResult("(gdb) No source file named nope.c.\nMake breakpoint pending on future shared library load? (y or [n]) [answered N; input not from terminal]\n", 40))
+}
+
+func TestParseBacktrace(t *testing.T) {
+	frames := parseBacktrace(`(gdb) #0  0x00007ffff7e3f9fc in __pthread_kill_implementation () from /lib/libc.so.6
+#1  0x0000555555555171 in crash (p=0x0, n=3) at src/crash.c:5
+#2  main () at src/crash.c:12
+`)
+	assert.Equal(t, []StackFrame{
+		{ID: 1, Name: "__pthread_kill_implementation"},
+		{ID: 2, Name: "crash", Source: &Source{Name: "crash.c", Path: "src/crash.c"}, Line: 5, Column: 1},
+		{ID: 3, Name: "main", Source: &Source{Name: "crash.c", Path: "src/crash.c"}, Line: 12, Column: 1},
+	}, frames)
+}
+
+func TestParseVariables(t *testing.T) {
+	variables := parseVariables(`(gdb) total = 42
+point = {
+  x = 1,
+  y = 2
+}
+name = 0x555555556004 "crash"
+`)
+	assert.Equal(t, []Variable{
+		{Name: "total", Value: "42"},
+		{Name: "point", Value: "{ x = 1, y = 2 }"},
+		{Name: "name", Value: `0x555555556004 "crash"`},
+	}, variables)
+	assert.Nil(t, parseVariables("(gdb) No locals.\n"))
+}
+
+func TestParseValue(t *testing.T) {
+	assert.Equal(t, "42", parseValue("(gdb) $3 = 42\n"))
+	assert.Equal(t, `No symbol "nope" in current context.`, parseValue("(gdb) No symbol \"nope\" in current context.\n"))
+}
@@ -0,0 +1,136 @@
+// Package dap serves the Debug Adapter Protocol, so editors like VS Code can debug an
+// uploaded executable through the server's GDB session. Requests are translated to GDB
+// commands and GDB's output to DAP events; custom requests add the assistant.
+package dap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// Sessions is the part of the GDB handler the adapter drives debugging sessions through
+type Sessions interface {
+	// StartSession starts GDB on one of user's uploaded executables
+	StartSession(user, filename string) error
+
+	// StopSession stops the current session's GDB for user
+	StopSession(user string) error
+
+	// SubscribeSession returns the ID of the session whose token user presents
+	SubscribeSession(user, token string) (string, error)
+
+	// HandleUserCommand sends a command to GDB for user without waiting for its output
+	HandleUserCommand(user, cmd string) error
+
+	// RunUserCommand runs a GDB command for user and returns its output
+	RunUserCommand(user, cmd string) (string, error)
+
+	// HandleProgramInput sends input to the program's terminal for user
+	HandleProgramInput(user, input string) error
+}
+
+// Authenticator checks the token a client sends with its launch or attach request, and
+// that its user may debug
+type Authenticator interface {
+	AuthenticateToken(token string) (string, bool)
+	RequireRole(user, required string) error
+}
+
+// Server accepts DAP clients
+type Server struct {
+	sessions Sessions
+	hub      *websocket.Hub
+	auth     Authenticator
+	router   http.Handler // Serves the chat requests askAssistant passes on
+
+	listener net.Listener
+	conns    map[net.Conn]bool
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// NewServer creates a DAP server
+func NewServer(sessions Sessions, hub *websocket.Hub, auth Authenticator, router http.Handler) *Server {
+	return &Server{
+		sessions: sessions,
+		hub:      hub,
+		auth:     auth,
+		router:   router,
+		conns:    make(map[net.Conn]bool),
+	}
+}
+
+// Serve accepts clients on listener until Close is called, which returns nil
+func (s *Server) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = true
+		s.mutex.Unlock()
+		go func() {
+			s.serveConn(conn)
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+		}()
+	}
+}
+
+// Close stops accepting clients and disconnects the connected ones
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// serveConn handles a client's requests in order until it disconnects
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	session := newSession(s, conn)
+	defer session.close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		req, err := readMessage(reader)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("DAP client %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if req.Type != "request" {
+			continue
+		}
+		if !session.handle(req) {
+			return
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// fakeSessions plays GDB: commands that run the program print where it stopped to the
+// session's subscribers, and queries answer with canned output
+type fakeSessions struct {
+	hub      *websocket.Hub
+	mutex    sync.Mutex
+	started  string
+	stopped  bool
+	commands []string
+}
+
+func (f *fakeSessions) StartSession(user, filename string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.started = filename
+	return nil
+}
+
+func (f *fakeSessions) StopSession(user string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.stopped = true
+	return nil
+}
+
+func (f *fakeSessions) SubscribeSession(user, token string) (string, error) {
+	if token != "session-token" {
+		return "", fmt.Errorf("%w: invalid or expired session token", appErrors.ErrForbidden)
+	}
+	return "session-1", nil
+}
+
+func (f *fakeSessions) HandleUserCommand(user, cmd string) error {
+	f.mutex.Lock()
+	f.commands = append(f.commands, cmd)
+	f.mutex.Unlock()
+	switch cmd {
+	case "run":
+		go f.hub.BroadcastToSession("session-1", "Starting program: /uploads/crash\r\n\x1b[1mBreakpoint 1, \x1b[mmain () at crash.c:5\r\n5\t  crash(0);\r\n")
+	case "next":
+		go f.hub.BroadcastToSession("session-1", "6\t  return 0;\r\n")
+	case "continue":
+		go f.hub.BroadcastToSession("session-1", "Continuing.\r\n[Inferior 1 (process 42) exited with code 03]\r\n")
+	}
+	return nil
+}
+
+func (f *fakeSessions) RunUserCommand(user, cmd string) (string, error) {
+	f.mutex.Lock()
+	f.commands = append(f.commands, cmd)
+	f.mutex.Unlock()
+	switch cmd {
+	case "break crash.c:5":
+		return "(gdb) Breakpoint 1 at 0x1149: file crash.c, line 5.\n", nil
+	case "backtrace":
+		return "(gdb) #0  main () at crash.c:5\n", nil
+	case "frame apply level 0 -q info locals":
+		return "(gdb) total = 42\nv = std::vector of length 2, capacity 2 = {1, 2}\n", nil
+	case "frame apply level 0 -q print total * 2":
+		return "(gdb) $1 = 84\n", nil
+	}
+	return "", nil
+}
+
+func (f *fakeSessions) HandleProgramInput(user, input string) error { return nil }
+
+// fakeAuth accepts one token
+type fakeAuth struct{}
+
+func (fakeAuth) AuthenticateToken(token string) (string, bool) {
+	return "alice", token == "secret"
+}
+
+func (fakeAuth) RequireRole(user, required string) error { return nil }
+
+// client is a DAP client for the tests
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+func (c *client) request(command string, arguments interface{}) {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// next returns the next message
+func (c *client) next() map[string]interface{} {
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	require.NoError(c.t, err)
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader, body)
+	require.NoError(c.t, err)
+	var message map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(body, &message))
+	return message
+}
+
+// expect returns the next response or event named name, skipping console output
+func (c *client) expect(name string) map[string]interface{} {
+	for {
+		message := c.next()
+		if message["event"] == "output" {
+			continue
+		}
+		if message["type"] == "response" {
+			require.Equal(c.t, name, message["command"], "response %v", message)
+		} else {
+			require.Equal(c.t, name, message["event"], "event %v", message)
+		}
+		return message
+	}
+}
+
+func newTestClient(t *testing.T) (*client, *fakeSessions) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	sessions := &fakeSessions{hub: hub}
+	router := http.NewServeMux()
+	router.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response":          "It dereferences NULL: " + req["message"].(string),
+			"suggestedCommands": []string{"print p"},
+		})
+	})
+	server := NewServer(sessions, hub, fakeAuth{}, router)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, reader: bufio.NewReader(conn)}, sessions
+}
+
+func TestDebugSession(t *testing.T) {
+	c, sessions := newTestClient(t)
+
+	c.request("initialize", map[string]interface{}{"adapterID": "gogdbllm"})
+	resp := c.expect("initialize")
+	assert.Equal(t, true, resp["body"].(map[string]interface{})["supportsConditionalBreakpoints"])
+
+	c.request("launch", map[string]interface{}{"program": "crash", "sessionToken": "session-token", "token": "secret"})
+	assert.Equal(t, true, c.expect("launch")["success"])
+	c.expect("initialized")
+	assert.Equal(t, "crash", sessions.started)
+
+	c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": "/home/alice/project/crash.c"},
+		"breakpoints": []map[string]interface{}{{"line": 5}},
+	})
+	breakpoints := c.expect("setBreakpoints")["body"].(map[string]interface{})["breakpoints"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "verified": true, "line": float64(5)}, breakpoints[0])
+
+	c.request("configurationDone", nil)
+	c.expect("configurationDone")
+	stopped := c.expect("stopped")["body"].(map[string]interface{})
+	assert.Equal(t, "breakpoint", stopped["reason"])
+	assert.Equal(t, []interface{}{float64(1)}, stopped["hitBreakpointIds"])
+
+	c.request("stackTrace", map[string]interface{}{"threadId": 1})
+	frames := c.expect("stackTrace")["body"].(map[string]interface{})["stackFrames"].([]interface{})
+	require.Len(t, frames, 1)
+	assert.Equal(t, "main", frames[0].(map[string]interface{})["name"])
+
+	c.request("scopes", map[string]interface{}{"frameId": 1})
+	scopes := c.expect("scopes")["body"].(map[string]interface{})["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})["variablesReference"]
+	c.request("variables", map[string]interface{}{"variablesReference": locals})
+	variables := c.expect("variables")["body"].(map[string]interface{})["variables"].([]interface{})
+	assert.Equal(t, "42", variables[0].(map[string]interface{})["value"])
+	assert.Equal(t, float64(0), variables[0].(map[string]interface{})["variablesReference"])
+	vector := variables[1].(map[string]interface{})
+	assert.Equal(t, "std::vector of length 2, capacity 2", vector["value"])
+	c.request("variables", map[string]interface{}{"variablesReference": vector["variablesReference"]})
+	elements := c.expect("variables")["body"].(map[string]interface{})["variables"].([]interface{})
+	require.Len(t, elements, 2)
+	assert.Equal(t, map[string]interface{}{"name": "[1]", "value": "2", "variablesReference": float64(0)}, elements[1])
+
+	c.request("evaluate", map[string]interface{}{"expression": "total * 2", "frameId": 1, "context": "hover"})
+	assert.Equal(t, "84", c.expect("evaluate")["body"].(map[string]interface{})["result"])
+
+	c.request("next", map[string]interface{}{"threadId": 1})
+	c.expect("next")
+	assert.Equal(t, "step", c.expect("stopped")["body"].(map[string]interface{})["reason"])
+
+	c.request("askAssistant", map[string]interface{}{"question": "why did it crash?"})
+	answer := c.expect("askAssistant")["body"].(map[string]interface{})
+	assert.Equal(t, "It dereferences NULL: why did it crash?", answer["response"])
+	assert.Equal(t, []interface{}{"print p"}, answer["suggestedCommands"])
+
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	c.expect("continue")
+	assert.Equal(t, float64(3), c.expect("exited")["body"].(map[string]interface{})["exitCode"])
+	c.expect("terminated")
+
+	c.request("disconnect", nil)
+	c.expect("disconnect")
+	assert.True(t, sessions.stopped)
+	assert.Equal(t, []string{"break crash.c:5", "run", "backtrace", "frame apply level 0 -q info locals",
+		"frame apply level 0 -q print total * 2", "next", "continue"}, sessions.commands)
+}
+
+func TestLaunchRequiresCredentials(t *testing.T) {
+	c, sessions := newTestClient(t)
+
+	c.request("threads", nil)
+	assert.Equal(t, "launch or attach first", c.expect("threads")["message"])
+
+	c.request("launch", map[string]interface{}{"program": "crash", "sessionToken": "session-token", "token": "wrong"})
+	assert.Equal(t, false, c.expect("launch")["success"])
+
+	c.request("attach", map[string]interface{}{"sessionToken": "stolen", "token": "secret"})
+	assert.Contains(t, c.expect("attach")["message"], "invalid or expired session token")
+	assert.Empty(t, sessions.started)
+}
@@ -0,0 +1,652 @@
+package dap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/utils"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// threadID is the one thread the adapter reports: GDB's current thread
+const threadID = 1
+
+// childReferences is the first variables reference of an aggregate's children. The
+// references below it are the scopes of frames.
+const childReferences = 1 << 20
+
+// stepStop matches the line GDB prints where a step ends: a source line ("6\t  foo();")
+// or, on entering or leaving a function, its location
+var stepStop = regexp.MustCompile(`^(?:\d+\t|(?:0x[0-9a-fA-F]+ in )?\S+ \(.*\) at \S+:\d+$)`)
+
+var (
+	errNotLaunched = errors.New("launch or attach first")
+	errNoProgram   = errors.New("program is required: the name of an uploaded executable")
+)
+
+// session is a client's connection. Requests are handled one at a time; the session's
+// output reaches it from the hub on another goroutine.
+type session struct {
+	server *Server
+	writer io.Writer
+
+	writeMutex sync.Mutex
+	seq        int
+
+	// Set by launch or attach
+	user     string
+	token    string // Credentials passed on to the chat endpoint
+	launched bool   // GDB was started by launch, so disconnecting stops it
+	entry    bool   // Stop at main when the program starts
+	client   *websocket.Client
+	relayed  chan struct{} // Closed once the hub's messages stop
+
+	// Execution state, read from GDB's output
+	mutex    sync.Mutex
+	running  bool
+	reason   string        // Reason of the next stop if no breakpoint or signal explains it
+	partial  string        // Output after the last newline
+	querying int           // Commands whose output is for the adapter rather than the client
+	children [][]gdb.Value // Children of the aggregates shown since the program last resumed, by reference - childReferences
+
+	sourceBreakpoints   map[string][]int // GDB's breakpoint numbers per source file
+	functionBreakpoints []int
+}
+
+func newSession(server *Server, writer io.Writer) *session {
+	return &session{
+		server:            server,
+		writer:            writer,
+		sourceBreakpoints: make(map[string][]int),
+	}
+}
+
+// send writes a response or event
+func (s *session) send(message interface{}) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	s.seq++
+	switch m := message.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	writeMessage(s.writer, message)
+}
+
+// sendEvent sends an event
+func (s *session) sendEvent(name string, body interface{}) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// handle answers a request. It returns false when the client disconnected.
+func (s *session) handle(req *request) bool {
+	handlers := map[string]func(json.RawMessage) (interface{}, error){
+		"initialize":             s.initialize,
+		"launch":                 s.launch,
+		"attach":                 s.attach,
+		"configurationDone":      s.configurationDone,
+		"setBreakpoints":         s.setBreakpoints,
+		"setFunctionBreakpoints": s.setFunctionBreakpoints,
+		"setExceptionBreakpoints": func(json.RawMessage) (interface{}, error) {
+			return nil, nil
+		},
+		"threads":      s.threads,
+		"stackTrace":   s.stackTrace,
+		"scopes":       s.scopes,
+		"variables":    s.variables,
+		"evaluate":     s.evaluate,
+		"continue":     s.execute("continue", ""),
+		"next":         s.execute("next", "step"),
+		"stepIn":       s.execute("step", "step"),
+		"stepOut":      s.execute("finish", "step"),
+		"pause":        s.pause,
+		"terminate":    s.terminate,
+		"disconnect":   s.disconnect,
+		"askAssistant": s.askAssistant,
+	}
+
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: true}
+	handler, ok := handlers[req.Command]
+	switch {
+	case !ok:
+		resp.Success, resp.Message = false, fmt.Sprintf("unsupported request %q", req.Command)
+	case s.client == nil && req.Command != "initialize" && req.Command != "launch" &&
+		req.Command != "attach" && req.Command != "disconnect":
+		resp.Success, resp.Message = false, errNotLaunched.Error()
+	default:
+		body, err := handler(req.Arguments)
+		if err != nil {
+			resp.Success, resp.Message = false, err.Error()
+		} else {
+			resp.Body = body
+		}
+	}
+	s.send(resp)
+
+	if resp.Success {
+		switch req.Command {
+		case "launch", "attach":
+			// The client sends its breakpoints and configurationDone after this
+			s.sendEvent("initialized", nil)
+		case "terminate":
+			s.sendEvent("terminated", nil)
+		case "disconnect":
+			return false
+		}
+	}
+	return true
+}
+
+// close ends the session's subscription to the hub
+func (s *session) close() {
+	if s.client != nil {
+		s.server.hub.Unsubscribe(s.client)
+		<-s.relayed
+	}
+}
+
+func (s *session) initialize(json.RawMessage) (interface{}, error) {
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsFunctionBreakpoints:      true,
+		SupportsConditionalBreakpoints:   true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+// launch starts GDB on an uploaded executable. The program runs once the client is done
+// setting breakpoints.
+func (s *session) launch(raw json.RawMessage) (interface{}, error) {
+	args, err := s.subscribe(raw)
+	if err != nil {
+		return nil, err
+	}
+	if args.Program == "" {
+		err = errNoProgram
+	} else {
+		err = s.server.sessions.StartSession(s.user, args.Program)
+	}
+	if err != nil {
+		s.close()
+		s.client = nil
+		return nil, err
+	}
+	s.launched = true
+	s.entry = args.StopOnEntry
+	return nil, nil
+}
+
+// attach joins the session's running GDB, e.g. one started from the web UI
+func (s *session) attach(raw json.RawMessage) (interface{}, error) {
+	_, err := s.subscribe(raw)
+	return nil, err
+}
+
+// subscribe authenticates the client and subscribes it to the session of its token
+func (s *session) subscribe(raw json.RawMessage) (*LaunchArguments, error) {
+	if s.client != nil {
+		return nil, errors.New("already launched")
+	}
+	var args LaunchArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	user, ok := s.server.auth.AuthenticateToken(args.Token)
+	if !ok {
+		return nil, errors.New("authentication required: set token to the server's token or a login session")
+	}
+	if err := s.server.auth.RequireRole(user, auth.RoleDebugger); err != nil {
+		return nil, err
+	}
+	sessionID, err := s.server.sessions.SubscribeSession(user, args.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.user, s.token = user, args.Token
+	s.client = s.server.hub.Subscribe(user, sessionID)
+	s.relayed = make(chan struct{})
+	go s.relay(s.client)
+	return &args, nil
+}
+
+// configurationDone runs a launched program
+func (s *session) configurationDone(json.RawMessage) (interface{}, error) {
+	if !s.launched {
+		return nil, nil
+	}
+	reason := ""
+	if s.entry {
+		if err := s.server.sessions.HandleUserCommand(s.user, "tbreak main"); err != nil {
+			return nil, err
+		}
+		reason = "entry"
+	}
+	return nil, s.resume("run", reason)
+}
+
+// execute returns the handler of a request that resumes the program with a GDB command
+func (s *session) execute(command, reason string) func(json.RawMessage) (interface{}, error) {
+	return func(json.RawMessage) (interface{}, error) {
+		if err := s.resume(command, reason); err != nil {
+			return nil, err
+		}
+		if command == "continue" {
+			return map[string]interface{}{"allThreadsContinued": true}, nil
+		}
+		return nil, nil
+	}
+}
+
+// resume sends a command that runs the program until its next stop, which GDB's output
+// reports
+func (s *session) resume(command, reason string) error {
+	s.mutex.Lock()
+	s.running, s.reason = true, reason
+	s.children = nil
+	s.mutex.Unlock()
+	if err := s.server.sessions.HandleUserCommand(s.user, command); err != nil {
+		s.mutex.Lock()
+		s.running = false
+		s.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// pause interrupts the program as Ctrl-C in its terminal does
+func (s *session) pause(json.RawMessage) (interface{}, error) {
+	s.mutex.Lock()
+	s.reason = "pause"
+	s.mutex.Unlock()
+	return nil, s.server.sessions.HandleProgramInput(s.user, "\x03")
+}
+
+func (s *session) terminate(json.RawMessage) (interface{}, error) {
+	return nil, s.server.sessions.StopSession(s.user)
+}
+
+// disconnect ends the session, stopping GDB if launch started it and the client does not
+// ask to keep it
+func (s *session) disconnect(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		TerminateDebuggee *bool `json:"terminateDebuggee"`
+	}
+	json.Unmarshal(raw, &args)
+	terminate := s.launched
+	if args.TerminateDebuggee != nil {
+		terminate = *args.TerminateDebuggee
+	}
+	if terminate && s.client != nil {
+		return nil, s.server.sessions.StopSession(s.user)
+	}
+	return nil, nil
+}
+
+// query runs a GDB command whose output answers a request, keeping it out of the client's
+// console
+func (s *session) query(command string) (string, error) {
+	s.mutex.Lock()
+	s.querying++
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.querying--
+		s.mutex.Unlock()
+	}()
+	return s.server.sessions.RunUserCommand(s.user, command)
+}
+
+// setBreakpoints replaces the breakpoints of a source file. GDB looks the file up by its
+// name, since the client's paths are not the server's.
+func (s *session) setBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Source      Source             `json:"source"`
+		Breakpoints []SourceBreakpoint `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	file := filepath.Base(args.Source.Path)
+	if args.Source.Path == "" {
+		file = args.Source.Name
+	}
+
+	if err := s.deleteBreakpoints(s.sourceBreakpoints[file]); err != nil {
+		return nil, err
+	}
+	breakpoints := make([]Breakpoint, 0, len(args.Breakpoints))
+	var numbers []int
+	for _, bp := range args.Breakpoints {
+		output, err := s.query(breakCommand(fmt.Sprintf("%s:%d", file, bp.Line), bp.Condition))
+		if err != nil {
+			return nil, err
+		}
+		result := breakpointResult(output, bp.Line)
+		if result.Verified {
+			numbers = append(numbers, result.ID)
+		}
+		breakpoints = append(breakpoints, result)
+	}
+	s.sourceBreakpoints[file] = numbers
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// setFunctionBreakpoints replaces the breakpoints on functions
+func (s *session) setFunctionBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Breakpoints []FunctionBreakpoint `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if err := s.deleteBreakpoints(s.functionBreakpoints); err != nil {
+		return nil, err
+	}
+	breakpoints := make([]Breakpoint, 0, len(args.Breakpoints))
+	s.functionBreakpoints = nil
+	for _, bp := range args.Breakpoints {
+		output, err := s.query(breakCommand(bp.Name, bp.Condition))
+		if err != nil {
+			return nil, err
+		}
+		result := breakpointResult(output, 0)
+		if result.Verified {
+			s.functionBreakpoints = append(s.functionBreakpoints, result.ID)
+		}
+		breakpoints = append(breakpoints, result)
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// deleteBreakpoints deletes breakpoints by GDB's numbers
+func (s *session) deleteBreakpoints(numbers []int) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+	list := make([]string, len(numbers))
+	for i, n := range numbers {
+		list[i] = strconv.Itoa(n)
+	}
+	_, err := s.query("delete " + strings.Join(list, " "))
+	return err
+}
+
+// breakCommand returns the GDB command for a breakpoint at location
+func breakCommand(location, condition string) string {
+	if condition = strings.TrimSpace(condition); condition != "" {
+		return fmt.Sprintf("break %s if %s", location, condition)
+	}
+	return "break " + location
+}
+
+func (s *session) threads(json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *session) stackTrace(raw json.RawMessage) (interface{}, error) {
+	output, err := s.query("backtrace")
+	if err != nil {
+		return nil, err
+	}
+	frames := parseBacktrace(output)
+	if frames == nil {
+		frames = []StackFrame{}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+// scopes returns a frame's locals and arguments. Their variables references encode the
+// frame: 2*ID-1 for the locals and 2*ID for the arguments.
+func (s *session) scopes(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil || args.FrameID < 1 {
+		return nil, errors.New("invalid frameId")
+	}
+	return map[string]interface{}{"scopes": []Scope{
+		{Name: "Locals", VariablesReference: 2*args.FrameID - 1},
+		{Name: "Arguments", VariablesReference: 2 * args.FrameID},
+	}}, nil
+}
+
+// variables lists the variables of a frame's scope, or the children of an aggregate
+// shown before: the members of a structure or the elements a pretty-printer shows for a
+// container such as a std::vector or a Go slice
+func (s *session) variables(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil || args.VariablesReference < 1 {
+		return nil, errors.New("invalid variablesReference")
+	}
+	if args.VariablesReference >= childReferences {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		index := args.VariablesReference - childReferences
+		if index >= len(s.children) {
+			return nil, errors.New("the variable is gone: the program ran since it was shown")
+		}
+		variables := make([]Variable, len(s.children[index]))
+		for i, child := range s.children[index] {
+			variables[i] = s.variableLocked(child)
+		}
+		return map[string]interface{}{"variables": variables}, nil
+	}
+
+	frameID := (args.VariablesReference + 1) / 2
+	command := "info locals"
+	if args.VariablesReference%2 == 0 {
+		command = "info args"
+	}
+	output, err := s.query(fmt.Sprintf("frame apply level %d -q %s", frameID-1, command))
+	if err != nil {
+		return nil, err
+	}
+	variables := []Variable{}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, v := range parseVariables(output) {
+		variables = append(variables, s.variableLocked(gdb.ParseValue(v.Name, v.Value)))
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+// variableLocked returns the variable of a value, whose children, if it has any, get a
+// reference the client expands it with. The caller holds mutex.
+func (s *session) variableLocked(value gdb.Value) Variable {
+	variable := Variable{Name: value.Name, Value: value.Value}
+	if len(value.Children) > 0 {
+		s.children = append(s.children, value.Children)
+		variable.VariablesReference = childReferences + len(s.children) - 1
+	}
+	return variable
+}
+
+// evaluate prints an expression in a frame. Expressions typed in the debug console are
+// run as GDB commands.
+func (s *session) evaluate(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+		Context    string `json:"context"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Context == "repl" {
+		output, err := s.query(args.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"result": strings.TrimSpace(output), "variablesReference": 0}, nil
+	}
+
+	command := "print " + args.Expression
+	if args.FrameID > 0 {
+		command = fmt.Sprintf("frame apply level %d -q %s", args.FrameID-1, command)
+	}
+	output, err := s.query(command)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := s.variableLocked(gdb.ParseValue("", parseValue(output)))
+	return map[string]interface{}{"result": result.Value, "variablesReference": result.VariablesReference}, nil
+}
+
+// askAssistant passes a question to the chat endpoint with the client's credentials, so
+// the assistant sees the session's state as it does for the web UI
+func (s *session) askAssistant(raw json.RawMessage) (interface{}, error) {
+	var args AssistantArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Question) == "" {
+		return nil, errors.New("question is required")
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"message":       args.Question,
+		"terminalLines": args.TerminalLines,
+		"model":         args.Model,
+	})
+	req, err := http.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		// The chat endpoint accepts either form of the token
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: s.token})
+	}
+
+	rec := &recorder{header: make(http.Header), status: http.StatusOK}
+	s.server.router.ServeHTTP(rec, req)
+	if rec.status != http.StatusOK {
+		return nil, fmt.Errorf("assistant: %s", strings.TrimSpace(rec.body.String()))
+	}
+	var answer AssistantResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &answer); err != nil {
+		return nil, fmt.Errorf("decoding the assistant's answer: %w", err)
+	}
+	return answer, nil
+}
+
+// relay turns the session's messages from the hub into events until the client is
+// unsubscribed
+func (s *session) relay(client *websocket.Client) {
+	defer close(s.relayed)
+	for message := range client.Send {
+		switch payload := message.Payload.(type) {
+		case websocket.OutputPayload:
+			s.output(payload.Text)
+		case websocket.StatusPayload:
+			switch payload.GDB {
+			case "exited":
+				s.sendEvent("terminated", nil)
+			case "restarted":
+				// Custom event: GDB was restarted with the breakpoints it had, and the
+				// program must be run again
+				s.sendEvent("gdbRestarted", map[string]interface{}{
+					"reason":      payload.Reason,
+					"breakpoints": payload.Breakpoints,
+				})
+			}
+		}
+	}
+}
+
+// output sends GDB's output to the client's console and reports the program's stops
+func (s *session) output(raw string) {
+	text := strings.ReplaceAll(utils.StripAnsiAndControlChars(raw), "\r\n", "\n")
+
+	s.mutex.Lock()
+	if s.querying > 0 {
+		s.mutex.Unlock()
+		return
+	}
+	lines := strings.Split(s.partial+text, "\n")
+	s.partial = lines[len(lines)-1]
+	var events []*event
+	for _, line := range lines[:len(lines)-1] {
+		events = append(events, s.stopEvents(trimPrompt(line))...)
+	}
+	s.mutex.Unlock()
+
+	s.sendEvent("output", map[string]interface{}{"category": "console", "output": text})
+	for _, e := range events {
+		s.sendEvent(e.Event, e.Body)
+	}
+}
+
+// stopEvents returns the events a line of output means while the program runs; the
+// caller holds the mutex
+func (s *session) stopEvents(line string) []*event {
+	if !s.running {
+		return nil
+	}
+	stopped := func(reason string, body map[string]interface{}) []*event {
+		s.running = false
+		body["reason"] = reason
+		body["threadId"] = threadID
+		body["allThreadsStopped"] = true
+		return []*event{{Event: "stopped", Body: body}}
+	}
+
+	if m := inferiorExited.FindStringSubmatch(line); m != nil {
+		s.running = false
+		code, _ := strconv.Atoi(m[1])
+		return []*event{
+			{Event: "exited", Body: map[string]interface{}{"exitCode": code}},
+			{Event: "terminated"},
+		}
+	}
+	if m := breakpointHit.FindStringSubmatch(line); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		if s.reason == "entry" {
+			return stopped("entry", map[string]interface{}{})
+		}
+		return stopped("breakpoint", map[string]interface{}{"hitBreakpointIds": []int{id}})
+	}
+	if m := signalReceived.FindStringSubmatch(line); m != nil {
+		if m[1] == "SIGINT" && s.reason == "pause" {
+			return stopped("pause", map[string]interface{}{})
+		}
+		return stopped("exception", map[string]interface{}{"description": m[1] + ", " + m[2], "text": m[1]})
+	}
+	if s.reason == "step" && stepStop.MatchString(line) {
+		return stopped("step", map[string]interface{}{})
+	}
+	return nil
+}
+
+// recorder collects the response of a request passed to the router
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
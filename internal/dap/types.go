@@ -0,0 +1,95 @@
+package dap
+
+// The parts of the DAP specification's types the adapter uses
+
+// Capabilities are the features the adapter supports, sent in the initialize response
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsFunctionBreakpoints      bool `json:"supportsFunctionBreakpoints"`
+	SupportsConditionalBreakpoints   bool `json:"supportsConditionalBreakpoints"`
+	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+// LaunchArguments are the arguments of launch and attach requests. Program is the name of
+// an uploaded executable and SessionToken the token its upload returned; Token
+// authenticates the client when the server requires it.
+type LaunchArguments struct {
+	Program      string `json:"program"`
+	SessionToken string `json:"sessionToken"`
+	Token        string `json:"token"`
+	StopOnEntry  bool   `json:"stopOnEntry"`
+}
+
+// Source is a source file
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// SourceBreakpoint is a breakpoint a client asks for in a source file
+type SourceBreakpoint struct {
+	Line      int    `json:"line"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// FunctionBreakpoint is a breakpoint a client asks for on a function
+type FunctionBreakpoint struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// Breakpoint is a breakpoint as GDB set it. ID is GDB's breakpoint number.
+type Breakpoint struct {
+	ID       int    `json:"id,omitempty"`
+	Verified bool   `json:"verified"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Thread is a thread of the program
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// StackFrame is a frame of the stack. ID is GDB's frame level plus one, as DAP clients
+// treat 0 as no frame.
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+// Scope is a group of variables of a frame
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+// Variable is a variable and its value as GDB printed it, or for an aggregate its summary
+// and the reference of its children
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// AssistantArguments are the arguments of the custom askAssistant request
+type AssistantArguments struct {
+	Question      string `json:"question"`
+	TerminalLines int    `json:"terminalLines,omitempty"`
+	Model         string `json:"model,omitempty"`
+}
+
+// AssistantResponse is the body of the askAssistant response: the assistant's answer as
+// POST /api/chat returns it
+type AssistantResponse struct {
+	Response          string   `json:"response"`
+	Model             string   `json:"model,omitempty"`
+	SuggestedCommands []string `json:"suggestedCommands,omitempty"`
+	Refused           bool     `json:"refused,omitempty"`
+}
@@ -0,0 +1,140 @@
+package decompile
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// ghidraScript is the script Ghidra's headless analyzer runs to decompile a function
+//
+//go:embed ghidra/GoGDBLLMDecompile.java
+var ghidraScript embed.FS
+
+// ghidraMutex serializes Ghidra's runs, as it locks a project while it works on it
+var ghidraMutex sync.Mutex
+
+// ghidraError starts what the script writes when it cannot decompile a function
+const ghidraError = "ERROR: "
+
+// objdump disassembles a function
+func (d *Decompiler) objdump(ctx context.Context, path string, fn *Function) (string, error) {
+	output, err := run(ctx, d.cfg.ObjdumpPath, "-d", "-C", "--no-show-raw-insn",
+		fmt.Sprintf("--start-address=0x%x", fn.Address),
+		fmt.Sprintf("--stop-address=0x%x", fn.Address+fn.Size),
+		path)
+	if err != nil {
+		return "", err
+	}
+	// Leave out the file's header: the disassembly follows "Disassembly of section .text:"
+	if _, code, ok := strings.Cut(output, "Disassembly of section"); ok {
+		if _, code, ok = strings.Cut(code, "\n"); ok {
+			output = code
+		}
+	}
+	return output, nil
+}
+
+// retdec decompiles a function with RetDec, decoding no more of the executable than it
+func (d *Decompiler) retdec(ctx context.Context, path string, fn *Function) (string, error) {
+	dir, err := os.MkdirTemp("", "gogdbllm-retdec-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "function.c")
+	args := []string{"-o", output, "--cleanup", "--select-decode-only"}
+	if fn.Name != "" {
+		args = append(args, "--select-functions", fn.Name)
+	} else {
+		args = append(args, "--select-ranges", fmt.Sprintf("0x%x-0x%x", fn.Address, fn.Address+fn.Size-1))
+	}
+	if _, err := run(ctx, d.cfg.RetDecPath, append(args, path)...); err != nil {
+		return "", err
+	}
+	code, err := os.ReadFile(output)
+	if err != nil {
+		return "", fmt.Errorf("retdec wrote no code: %w", err)
+	}
+	return string(code), nil
+}
+
+// ghidra decompiles a function with Ghidra's headless analyzer. The first function of an
+// executable has Ghidra import and analyze it into a project of its own in ProjectsDir,
+// which later ones reuse. One function is decompiled at a time.
+func (d *Decompiler) ghidra(ctx context.Context, path string, fn *Function) (string, error) {
+	ghidraMutex.Lock()
+	defer ghidraMutex.Unlock()
+
+	projectsDir, err := filepath.Abs(d.cfg.ProjectsDir)
+	if err != nil {
+		return "", err
+	}
+	scriptsDir := filepath.Join(projectsDir, "scripts")
+	if err := os.MkdirAll(scriptsDir, 0755); err != nil {
+		return "", err
+	}
+	script, _ := ghidraScript.ReadFile("ghidra/GoGDBLLMDecompile.java")
+	if err := os.WriteFile(filepath.Join(scriptsDir, "GoGDBLLMDecompile.java"), script, 0644); err != nil {
+		return "", err
+	}
+	project, err := projectName(path)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := os.CreateTemp("", "gogdbllm-ghidra-*.c")
+	if err != nil {
+		return "", err
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	args := []string{projectsDir, project}
+	if _, err := os.Stat(filepath.Join(projectsDir, project+".gpr")); err == nil {
+		args = append(args, "-process", filepath.Base(path), "-noanalysis", "-readOnly")
+	} else {
+		args = append(args, "-import", path)
+	}
+	args = append(args, "-scriptPath", scriptsDir,
+		"-postScript", "GoGDBLLMDecompile.java", fmt.Sprintf("0x%x", fn.Address), output.Name())
+	if _, err := run(ctx, d.cfg.GhidraPath, args...); err != nil {
+		return "", err
+	}
+
+	code, err := os.ReadFile(output.Name())
+	if err != nil {
+		return "", err
+	}
+	if message, failed := strings.CutPrefix(string(code), ghidraError); failed {
+		return "", fmt.Errorf("%w: ghidra: %s", appErrors.ErrNotFound, strings.TrimSpace(message))
+	}
+	if len(strings.TrimSpace(string(code))) == 0 {
+		return "", fmt.Errorf("ghidra decompiled nothing at 0x%x", fn.Address)
+	}
+	return string(code), nil
+}
+
+// projectName names the Ghidra project of an executable after its contents, so an
+// executable uploaded again under the same name is analyzed again
+func projectName(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return "gogdbllm-" + hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
@@ -0,0 +1,251 @@
+// Package decompile shows the assistant the code of a function of an executable without
+// debug information, where GDB has no source to list: objdump's disassembly of it, or C
+// decompiled by RetDec or Ghidra's headless analyzer.
+package decompile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// unsizedSpan is how much of an executable is taken as a function when no symbol says
+// where it ends
+const unsizedSpan = 0x400
+
+var (
+	// generatedName matches the names decompilers give functions without symbols, e.g.
+	// Ghidra's "FUN_00401136" or IDA's "sub_401136"
+	generatedName = regexp.MustCompile(`^(?:FUN_|sub_)([0-9A-Fa-f]+)$`)
+
+	// mentions match how a message asks about a function: "function parse_header",
+	// "parse_header()", "FUN_00401136" or "function at 0x401136"
+	mentions = []*regexp.Regexp{
+		regexp.MustCompile(`\b((?:FUN_|sub_)[0-9A-Fa-f]+)\b`),
+		regexp.MustCompile(`(?i)\bfunc(?:tion)?\s+(?:at\s+)?(0x[0-9A-Fa-f]+|[A-Za-z_][\w.]*)`),
+		regexp.MustCompile(`\b([A-Za-z_]\w*)\(\)`),
+	}
+)
+
+// Function is a function of an executable
+type Function struct {
+	Name    string `json:"name,omitempty"` // "" if no symbol names it
+	Address uint64 `json:"address"`
+	Size    uint64 `json:"size"` // As its symbol says, or a guess
+}
+
+// Result is the code of a function
+type Result struct {
+	Function  Function `json:"function"`
+	Backend   string   `json:"backend"`  // The decompiler
+	Language  string   `json:"language"` // "asm" or "c"
+	Code      string   `json:"code"`
+	Truncated bool     `json:"truncated,omitempty"` // Cut to the configured max_lines
+}
+
+// String formats the result as context for the assistant
+func (r *Result) String() string {
+	name := r.Function.Name
+	if name == "" {
+		name = "function"
+	}
+	kind := "Decompiled by " + r.Backend
+	if r.Language == "asm" {
+		kind = "Disassembled by " + r.Backend
+	}
+	text := fmt.Sprintf("%s at 0x%x (%s):\n%s", name, r.Function.Address, kind, r.Code)
+	if r.Truncated {
+		text += "\n... (truncated)"
+	}
+	return text
+}
+
+// Decompiler runs the configured decompiler
+type Decompiler struct {
+	cfg config.DecompilerConfig
+}
+
+// New creates a decompiler, or returns nil if none is enabled
+func New(cfg config.DecompilerConfig) *Decompiler {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Decompiler{cfg: cfg}
+}
+
+// Decompile returns the code of a function of an executable, named by its symbol, its
+// address ("0x401136") or a name decompilers generate ("FUN_00401136")
+func (d *Decompiler) Decompile(ctx context.Context, path, function string) (*Result, error) {
+	if d == nil {
+		return nil, fmt.Errorf("%w: no decompiler is enabled", appErrors.ErrUnsupported)
+	}
+	f, err := binfile.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := Resolve(f, function)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := d.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &Result{Function: *fn, Backend: d.cfg.Backend, Language: "c"}
+	switch d.cfg.Backend {
+	case config.DecompilerObjdump:
+		result.Language = "asm"
+		result.Code, err = d.objdump(ctx, path, fn)
+	case config.DecompilerRetDec:
+		result.Code, err = d.retdec(ctx, path, fn)
+	case config.DecompilerGhidra:
+		result.Code, err = d.ghidra(ctx, path, fn)
+	default:
+		return nil, fmt.Errorf("%w: unknown decompiler %q", appErrors.ErrInvalidConfiguration, d.cfg.Backend)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%w: %s gave up on %s after %s", appErrors.ErrTimeout, d.cfg.Backend, function, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Code, result.Truncated = truncateLines(strings.TrimSpace(result.Code), d.cfg.MaxLines)
+	return result, nil
+}
+
+// Resolve finds a function of an executable by its symbol, its address or a generated
+// name. An address inside a function with a symbol resolves to that function.
+func Resolve(f *binfile.File, function string) (*Function, error) {
+	function = strings.TrimSpace(function)
+	if function == "" {
+		return nil, fmt.Errorf("%w: no function given", appErrors.ErrBadRequest)
+	}
+	var functions []binfile.Symbol
+	for _, symbol := range f.Symbols() {
+		if symbol.Kind == "function" && symbol.Defined && symbol.Address != 0 {
+			functions = append(functions, symbol)
+		}
+	}
+
+	address, ok := parseAddress(function)
+	if !ok {
+		for _, symbol := range functions {
+			if symbol.Name == function {
+				return sized(symbol.Name, symbol.Address, symbol.Size, functions), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no function %s in the symbols; name it by its address instead", appErrors.ErrNotFound, function)
+	}
+
+	if !inCode(f, address) {
+		return nil, fmt.Errorf("%w: 0x%x is not in the executable's code", appErrors.ErrNotFound, address)
+	}
+	for _, symbol := range functions {
+		if address >= symbol.Address && address < symbol.Address+symbol.Size {
+			return sized(symbol.Name, symbol.Address, symbol.Size, functions), nil
+		}
+	}
+	return sized("", address, 0, functions), nil
+}
+
+// Mentioned returns the functions a message asks about, in the order it mentions them
+func Mentioned(message string) []string {
+	var functions []string
+	seen := make(map[string]bool)
+	for _, pattern := range mentions {
+		for _, match := range pattern.FindAllStringSubmatch(message, -1) {
+			if name := match[1]; !seen[name] {
+				seen[name] = true
+				functions = append(functions, name)
+			}
+		}
+	}
+	return functions
+}
+
+// sized returns a function with a size, guessing one from the next function when its
+// symbol has none
+func sized(name string, address, size uint64, functions []binfile.Symbol) *Function {
+	if size == 0 {
+		size = unsizedSpan
+		for _, symbol := range functions {
+			if symbol.Address > address && symbol.Address-address < size {
+				size = symbol.Address - address
+			}
+		}
+	}
+	return &Function{Name: name, Address: address, Size: size}
+}
+
+// parseAddress reads an address, in hexadecimal with "0x" or in a generated name
+func parseAddress(function string) (uint64, bool) {
+	digits := ""
+	if match := generatedName.FindStringSubmatch(function); match != nil {
+		digits = match[1]
+	} else if strings.HasPrefix(strings.ToLower(function), "0x") {
+		digits = function[2:]
+	} else {
+		return 0, false
+	}
+	address, err := strconv.ParseUint(digits, 16, 64)
+	return address, err == nil
+}
+
+// inCode reports whether an address is in an executable section
+func inCode(f *binfile.File, address uint64) bool {
+	for _, section := range f.Sections() {
+		if strings.HasSuffix(section.Permissions, "x") && address >= section.Address && address < section.Address+section.Size {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs a decompiler and returns its standard output, or an error with the end of
+// what it reported
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s failed: %s", name, lastLines(stderr.String()+stdout.String(), 5))
+		}
+		return "", fmt.Errorf("%w: running %s: %v", appErrors.ErrUnsupported, name, err)
+	}
+	return stdout.String(), nil
+}
+
+// truncateLines cuts text to max lines, if max is positive
+func truncateLines(text string, max int) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if max <= 0 || len(lines) <= max {
+		return text, false
+	}
+	return strings.Join(lines[:max], "\n"), true
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,130 @@
+package decompile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+const program = `int checksum(const char *s) {
+	int sum = 0;
+	while (*s) sum += *s++;
+	return sum;
+}
+
+int main(int argc, char **argv) {
+	return argc > 1 ? checksum(argv[1]) : 0;
+}
+`
+
+// compile builds the test program, stripped if strip is set
+func compile(t *testing.T, strip bool) string {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "checksum.c")
+	require.NoError(t, os.WriteFile(source, []byte(program), 0644))
+	executable := filepath.Join(dir, "checksum")
+	args := []string{"-O0", "-o", executable, source}
+	if strip {
+		args = append(args, "-s")
+	}
+	output, err := exec.Command("gcc", args...).CombinedOutput()
+	require.NoError(t, err, string(output))
+	return executable
+}
+
+func TestResolve(t *testing.T) {
+	f, err := binfile.Open(compile(t, false))
+	require.NoError(t, err)
+	defer f.Close()
+
+	checksum, err := Resolve(f, "checksum")
+	require.NoError(t, err)
+	assert.Equal(t, "checksum", checksum.Name)
+	assert.NotZero(t, checksum.Size)
+
+	// An address inside a function, however it is written, resolves to the function
+	for _, ref := range []string{
+		fmt.Sprintf("0x%x", checksum.Address+4),
+		fmt.Sprintf("FUN_%08x", checksum.Address),
+		fmt.Sprintf("sub_%x", checksum.Address),
+	} {
+		fn, err := Resolve(f, ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, *checksum, *fn, ref)
+	}
+
+	_, err = Resolve(f, "no_such_function")
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	_, err = Resolve(f, "0x1")
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	_, err = Resolve(f, " ")
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+}
+
+func TestResolveStripped(t *testing.T) {
+	path := compile(t, false)
+	f, err := binfile.Open(path)
+	require.NoError(t, err)
+	checksum, err := Resolve(f, "checksum")
+	f.Close()
+	require.NoError(t, err)
+
+	stripped, err := exec.Command("strip", "-o", path+".stripped", path).CombinedOutput()
+	if err != nil {
+		t.Skip("strip not available: " + string(stripped))
+	}
+	f, err = binfile.Open(path + ".stripped")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = Resolve(f, "checksum")
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	fn, err := Resolve(f, fmt.Sprintf("0x%x", checksum.Address))
+	require.NoError(t, err)
+	assert.Empty(t, fn.Name)
+	assert.Equal(t, checksum.Address, fn.Address)
+	assert.NotZero(t, fn.Size)
+	assert.LessOrEqual(t, fn.Size, uint64(unsizedSpan))
+}
+
+func TestMentioned(t *testing.T) {
+	assert.Equal(t, []string{"FUN_00401136", "parse_header", "check"},
+		Mentioned("Why does FUN_00401136 call the function parse_header before check()?"))
+	assert.Equal(t, []string{"0x401136"}, Mentioned("What does the function at 0x401136 do?"))
+	assert.Empty(t, Mentioned("Why did it crash at 0x401136?"))
+}
+
+func TestObjdump(t *testing.T) {
+	if _, err := exec.LookPath("objdump"); err != nil {
+		t.Skip("objdump not available")
+	}
+	path := compile(t, false)
+	d := New(config.DecompilerConfig{Enabled: true, Backend: config.DecompilerObjdump, ObjdumpPath: "objdump", MaxLines: 3})
+
+	result, err := d.Decompile(context.Background(), path, "checksum")
+	require.NoError(t, err)
+	assert.Equal(t, "asm", result.Language)
+	assert.Contains(t, result.Code, "<checksum>:")
+	assert.NotContains(t, result.Code, "file format")
+	assert.NotContains(t, result.Code, "<main>:")
+	assert.True(t, result.Truncated)
+	assert.Contains(t, result.String(), "checksum at 0x")
+}
+
+func TestDisabled(t *testing.T) {
+	d := New(config.DecompilerConfig{Backend: config.DecompilerObjdump})
+	_, err := d.Decompile(context.Background(), "checksum", "main")
+	assert.ErrorIs(t, err, appErrors.ErrUnsupported)
+}
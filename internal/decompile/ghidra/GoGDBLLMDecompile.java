@@ -0,0 +1,44 @@
+// Decompiles the function at an address and writes its C to a file, for gogdbllm.
+// Arguments: the address in hexadecimal, as in the executable, and the output file.
+// @category gogdbllm
+import ghidra.app.decompiler.DecompInterface;
+import ghidra.app.decompiler.DecompileResults;
+import ghidra.app.script.GhidraScript;
+import ghidra.program.model.address.Address;
+import ghidra.program.model.listing.Function;
+
+import java.io.FileWriter;
+import java.io.PrintWriter;
+
+public class GoGDBLLMDecompile extends GhidraScript {
+	@Override
+	public void run() throws Exception {
+		String[] args = getScriptArgs();
+		long offset = Long.parseUnsignedLong(args[0].replaceFirst("^0x", ""), 16);
+		long imageBase = currentProgram.getImageBase().getOffset();
+		// Ghidra loads position-independent executables above 0 while their symbols start at 0
+		if (Long.compareUnsigned(offset, imageBase) < 0) {
+			offset += imageBase;
+		}
+		Address address = toAddr(offset);
+
+		try (PrintWriter out = new PrintWriter(new FileWriter(args[1]))) {
+			Function function = getFunctionContaining(address);
+			if (function == null) {
+				function = createFunction(address, null);
+			}
+			if (function == null) {
+				out.println("ERROR: no function at " + address);
+				return;
+			}
+			DecompInterface decompiler = new DecompInterface();
+			decompiler.openProgram(currentProgram);
+			DecompileResults results = decompiler.decompileFunction(function, 60, monitor);
+			if (!results.decompileCompleted()) {
+				out.println("ERROR: " + results.getErrorMessage());
+				return;
+			}
+			out.print(results.getDecompiledFunction().getC());
+		}
+	}
+}
@@ -4,12 +4,22 @@ import (
 	"fmt"
 
 	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
 	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/features"
 	"github.com/yourusername/gogdbllm/internal/gdb"
 	"github.com/yourusername/gogdbllm/internal/handlers"
+	"github.com/yourusername/gogdbllm/internal/labs"
 	"github.com/yourusername/gogdbllm/internal/logger"
 	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/mcp"
+	"github.com/yourusername/gogdbllm/internal/prompts"
 	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/tracing"
+	"github.com/yourusername/gogdbllm/internal/transcript"
+	"github.com/yourusername/gogdbllm/internal/triage"
 	"github.com/yourusername/gogdbllm/internal/websocket"
 	"go.uber.org/dig"
 )
@@ -26,13 +36,15 @@ func NewContainer() *Container {
 	}
 }
 
-// Configure sets up the dependency injection container
-func (c *Container) Configure(configPath string) error {
+// Configure sets up the dependency injection container. Overrides from command-line
+// flags take precedence over every other configuration source.
+func (c *Container) Configure(configPath string, overrides config.Overrides) error {
 	// Initialize logger - call directly instead of providing a function
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	cfg.Overrides = overrides
 
 	// Initialize logger directly
 	if err := logger.Init(cfg); err != nil {
@@ -53,47 +65,224 @@ func (c *Container) Configure(configPath string) error {
 		return fmt.Errorf("failed to provide logger holder: %w", err)
 	}
 
+	// Provide the tracer
+	if err := c.container.Provide(tracing.NewTracer); err != nil {
+		return fmt.Errorf("failed to provide tracer: %w", err)
+	}
+
+	// Provide authenticator
+	if err := c.container.Provide(auth.NewAuthenticator); err != nil {
+		return fmt.Errorf("failed to provide authenticator: %w", err)
+	}
+
+	// Provide feature flag manager, which forgets a session's assignments when it ends
+	if err := c.container.Provide(func(cfg *config.Config, holder handlers.LoggerHolder) *features.Manager {
+		manager := features.NewManager(cfg)
+		holder.OnSessionEnd(manager.ReleaseSession)
+		return manager
+	}); err != nil {
+		return fmt.Errorf("failed to provide feature manager: %w", err)
+	}
+
 	// Provide WebSocket hub
 	if err := c.container.Provide(websocket.NewHub); err != nil {
 		return fmt.Errorf("failed to provide WebSocket hub: %w", err)
 	}
 
+	// Provide the audit trail (nil while auditing is disabled)
+	if err := c.container.Provide(func(cfg *config.Config) *audit.Trail {
+		if !cfg.Audit.Enabled {
+			return nil
+		}
+		return audit.New(cfg.Audit.Directory)
+	}); err != nil {
+		return fmt.Errorf("failed to provide audit trail: %w", err)
+	}
+
+	// Provide the bus of session events, and the notifier sending them to events.targets
+	if err := c.container.Provide(events.NewBus); err != nil {
+		return fmt.Errorf("failed to provide event bus: %w", err)
+	}
+
+	if err := c.container.Provide(func(cfg *config.Config, bus *events.Bus) (*events.Notifier, error) {
+		return events.NewNotifier(bus, cfg.Events)
+	}); err != nil {
+		return fmt.Errorf("failed to provide event notifier: %w", err)
+	}
+
 	// Provide handlers
 	if err := c.container.Provide(handlers.NewFileHandler); err != nil {
 		return fmt.Errorf("failed to provide file handler: %w", err)
 	}
 
-	if err := c.container.Provide(handlers.NewGDBHandler); err != nil {
+	if err := c.container.Provide(func(hub *websocket.Hub, loggerHolder handlers.LoggerHolder, cfg *config.Config, trail *audit.Trail, bus *events.Bus) (*handlers.GDBHandler, error) {
+		if err := cfg.GDB.Validate(); err != nil {
+			return nil, err
+		}
+		if err := cfg.Decompiler.Validate(); err != nil {
+			return nil, err
+		}
+		handler := handlers.NewGDBHandler(hub, loggerHolder, cfg)
+		handler.SetAuditTrail(trail)
+		handler.SetEventBus(bus)
+		return handler, nil
+	}); err != nil {
 		return fmt.Errorf("failed to provide GDB handler: %w", err)
 	}
 
+	if err := c.container.Provide(api.NewModelCatalog); err != nil {
+		return fmt.Errorf("failed to provide model catalog: %w", err)
+	}
+
 	if err := c.container.Provide(handlers.NewSettingsHandler); err != nil {
 		return fmt.Errorf("failed to provide settings handler: %w", err)
 	}
 
+	if err := c.container.Provide(handlers.NewMemoryHandler); err != nil {
+		return fmt.Errorf("failed to provide memory handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewBreakpointHandler); err != nil {
+		return fmt.Errorf("failed to provide breakpoint handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewCheckpointHandler); err != nil {
+		return fmt.Errorf("failed to provide checkpoint handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewScriptHandler); err != nil {
+		return fmt.Errorf("failed to provide script handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewRunHandler); err != nil {
+		return fmt.Errorf("failed to provide run handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewBinaryHandler); err != nil {
+		return fmt.Errorf("failed to provide binary handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewCapabilitiesHandler); err != nil {
+		return fmt.Errorf("failed to provide capabilities handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewCompileHandler); err != nil {
+		return fmt.Errorf("failed to provide compile handler: %w", err)
+	}
+
+	// Provide session exporters
+	if err := c.container.Provide(transcript.NewRegistry); err != nil {
+		return fmt.Errorf("failed to provide exporter registry: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewExportHandler); err != nil {
+		return fmt.Errorf("failed to provide export handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewLogHandler); err != nil {
+		return fmt.Errorf("failed to provide log handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewAdminHandler); err != nil {
+		return fmt.Errorf("failed to provide admin handler: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewAuditHandler); err != nil {
+		return fmt.Errorf("failed to provide audit handler: %w", err)
+	}
+
+	// Provide the lab catalog
+	if err := c.container.Provide(labs.NewCatalog); err != nil {
+		return fmt.Errorf("failed to provide lab catalog: %w", err)
+	}
+
+	if err := c.container.Provide(handlers.NewLabHandler); err != nil {
+		return fmt.Errorf("failed to provide lab handler: %w", err)
+	}
+
+	// Provide the LLM response cache
+	if err := c.container.Provide(func(cfg *config.Config) (*api.ResponseCache, error) {
+		return api.NewResponseCacheFromConfig(cfg.Chat.Cache)
+	}); err != nil {
+		return fmt.Errorf("failed to provide response cache: %w", err)
+	}
+
+	// Provide the prompt template engine
+	if err := c.container.Provide(prompts.NewEngine); err != nil {
+		return fmt.Errorf("failed to provide prompt engine: %w", err)
+	}
+
 	// Provide simple chat handler (clean architecture)
 	if err := c.container.Provide(func(
+		cfg *config.Config,
 		settingsManager *settings.Manager,
 		loggerHolder api.LoggerHolder,
 		gdbHandler api.GDBCommandHandler,
-	) *api.SimpleChatHandler {
-		return api.NewSimpleChatHandler(settingsManager, loggerHolder, gdbHandler)
+		featureManager *features.Manager,
+		responseCache *api.ResponseCache,
+		promptEngine *prompts.Engine,
+		bus *events.Bus,
+	) (*api.SimpleChatHandler, error) {
+		if err := cfg.Chat.Envelope.Validate(); err != nil {
+			return nil, err
+		}
+		if err := api.ValidatePostProcessors(cfg.Chat.PostProcessors); err != nil {
+			return nil, err
+		}
+		if err := cfg.Chat.Retrieval.Validate(); err != nil {
+			return nil, err
+		}
+		handler := api.NewSimpleChatHandler(settingsManager, loggerHolder, gdbHandler, featureManager, cfg.Chat, responseCache, promptEngine)
+		handler.SetEventBus(bus)
+		return handler, nil
 	}); err != nil {
 		return fmt.Errorf("failed to provide simple chat handler: %w", err)
 	}
 
+	// Provide the MCP tools endpoint
+	if err := c.container.Provide(func(cfg *config.Config, gdbHandler *handlers.GDBHandler, promptEngine *prompts.Engine) (*mcp.Handler, error) {
+		return mcp.NewHandler(cfg, gdbHandler, promptEngine)
+	}); err != nil {
+		return fmt.Errorf("failed to provide MCP handler: %w", err)
+	}
+
+	// Provide the CI crash triage endpoint
+	if err := c.container.Provide(func(
+		cfg *config.Config,
+		hub *websocket.Hub,
+		settingsManager *settings.Manager,
+		featureManager *features.Manager,
+		responseCache *api.ResponseCache,
+		promptEngine *prompts.Engine,
+	) (*triage.Handler, error) {
+		analyzer := triage.NewAnalyzer(cfg, hub, settingsManager, featureManager, responseCache, promptEngine)
+		return triage.NewHandler(cfg.Triage, analyzer)
+	}); err != nil {
+		return fmt.Errorf("failed to provide triage handler: %w", err)
+	}
+
 	// Provide GDB service
 	if err := c.container.Provide(gdb.NewGDBService); err != nil {
 		return fmt.Errorf("failed to provide GDB service: %w", err)
 	}
 
 	// Provide settings manager
-	if err := c.container.Provide(func() (*settings.Manager, error) {
-		return settings.NewManager("")
+	if err := c.container.Provide(func(cfg *config.Config) (*settings.Manager, error) {
+		return settings.NewManagerFromConfig("", cfg)
 	}); err != nil {
 		return fmt.Errorf("failed to provide settings manager: %w", err)
 	}
 
+	// Provide the checks behind /health
+	if err := c.container.Provide(newHealthChecker); err != nil {
+		return fmt.Errorf("failed to provide health checker: %w", err)
+	}
+
+	// Provide the watcher applying changes to the config file
+	if err := c.container.Provide(newReloadWatcher); err != nil {
+		return fmt.Errorf("failed to provide reload watcher: %w", err)
+	}
+
 	// Provide LoggerHolder for API package
 	if err := c.container.Provide(func(holder handlers.LoggerHolder) api.LoggerHolder {
 		return holder // Use the same LoggerHolder instance
@@ -0,0 +1,52 @@
+package di
+
+import (
+	"context"
+
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/health"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// newHealthChecker creates the checker behind /health: the debugger, or the CLI of the
+// backend running it, the directories the server writes to and the providers the server
+// has an API key for
+func newHealthChecker(cfg *config.Config, settingsManager *settings.Manager, catalog *api.ModelCatalog) *health.Checker {
+	checker := health.New(cfg.Health)
+
+	gdbCfg := cfg.GDB
+	switch gdbCfg.Backend {
+	case config.BackendDocker:
+		// Fails when the daemon is unreachable, not only when the CLI is missing
+		checker.Add("gdb", true, health.Command(gdbCfg.Docker.Binary, "version", "--format", "{{.Server.Version}}"))
+	case config.BackendKubernetes:
+		args := []string{"version"}
+		if gdbCfg.Kubernetes.Context != "" {
+			args = append(args, "--context", gdbCfg.Kubernetes.Context)
+		}
+		checker.Add("gdb", true, health.Command(gdbCfg.Kubernetes.Binary, args...))
+	default:
+		if gdbCfg.Debugger == config.DebuggerCDB {
+			checker.Add("gdb", true, health.Command(gdbCfg.Path, "-version"))
+		} else {
+			checker.Add("gdb", true, health.Command(gdbCfg.Path, "--version"))
+		}
+		if gdbCfg.Emulation.Enabled {
+			checker.Add("emulation", false, health.Command(gdbCfg.Emulation.GDBPath, "--version"))
+		}
+	}
+
+	checker.Add("uploads", true, health.Writable(cfg.Uploads.Directory))
+	checker.Add("logs", true, health.Writable(cfg.Logs.Directory))
+
+	for _, provider := range settings.Providers {
+		provider := provider
+		checker.AddProvider(provider,
+			func() bool { return settingsManager.APIKeyFor("", provider) != "" },
+			func(ctx context.Context) (map[string]interface{}, error) {
+				return nil, catalog.Ping(ctx, provider, settingsManager.APIKeyFor("", provider))
+			})
+	}
+	return checker
+}
@@ -0,0 +1,54 @@
+package di
+
+import (
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/handlers"
+	"github.com/yourusername/gogdbllm/internal/health"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/reload"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// newReloadWatcher creates the watcher of the config file, with the components that
+// apply its settings without a restart
+func newReloadWatcher(
+	cfg *config.Config,
+	settingsManager *settings.Manager,
+	promptEngine *prompts.Engine,
+	chatHandler *api.SimpleChatHandler,
+	healthChecker *health.Checker,
+	labHandler *handlers.LabHandler,
+	auditHandler *handlers.AuditHandler,
+	notifier *events.Notifier,
+) *reload.Watcher {
+	watcher := reload.New(cfg)
+	watcher.Register(func(cfg *config.Config) error {
+		return promptEngine.Reload(cfg.Prompts)
+	}, "prompts")
+	watcher.Register(func(cfg *config.Config) error {
+		settingsManager.Reload(cfg)
+		return nil
+	}, "llm.default_provider", "llm.default_model", "llm.api_key", "prompts.default_profile")
+	watcher.Register(func(cfg *config.Config) error {
+		return chatHandler.Reload(cfg.Chat)
+	}, "chat.envelope", "chat.context", "chat.cost", "chat.queue", "chat.cache.enabled", "chat.cache.ttl", "chat.cache.admins",
+		"chat.retry", "chat.circuit_breaker", "chat.metrics", "chat.post_processors")
+	watcher.Register(func(cfg *config.Config) error {
+		healthChecker.Reload(cfg.Health)
+		return nil
+	}, "health")
+	watcher.Register(func(cfg *config.Config) error {
+		labHandler.Reload(cfg.Labs)
+		return nil
+	}, "labs.admins")
+	watcher.Register(func(cfg *config.Config) error {
+		auditHandler.Reload(cfg.Audit)
+		return nil
+	}, "audit.admins")
+	watcher.Register(func(cfg *config.Config) error {
+		return notifier.Reload(cfg.Events)
+	}, "events")
+	return watcher
+}
@@ -15,6 +15,8 @@ var (
 	ErrTimeout              = errors.New("operation timed out")
 	ErrInvalidConfiguration = errors.New("invalid configuration")
 	ErrUnsupported          = errors.New("operation not supported")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrUnavailable          = errors.New("service unavailable")
 )
 
 // Domain-specific errors
@@ -50,18 +52,47 @@ func Unwrap(err error) error {
 	return errors.Unwrap(err)
 }
 
+// StatusCode returns the HTTP status for an error wrapping one of the sentinel errors,
+// or 500 for any other error
+func StatusCode(err error) int {
+	var appErr *AppError
+	switch {
+	case errors.As(err, &appErr):
+		return int(appErr.Code)
+	case errors.Is(err, ErrBadRequest):
+		return int(CodeBadRequest)
+	case errors.Is(err, ErrUnauthorized):
+		return int(CodeUnauthorized)
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrUnsupported):
+		return int(CodeForbidden)
+	case errors.Is(err, ErrNotFound):
+		return int(CodeNotFound)
+	case errors.Is(err, ErrTimeout):
+		return int(CodeTimeout)
+	case errors.Is(err, ErrTooManyRequests):
+		return int(CodeTooManyRequests)
+	case errors.Is(err, ErrUnavailable):
+		return int(CodeUnavailable)
+	default:
+		return int(CodeInternal)
+	}
+}
+
 // ErrorCode represents an HTTP status code for an error
 type ErrorCode int
 
 // Error codes
 const (
-	CodeBadRequest     ErrorCode = 400
-	CodeUnauthorized   ErrorCode = 401
-	CodeForbidden      ErrorCode = 403
-	CodeNotFound       ErrorCode = 404
-	CodeTimeout        ErrorCode = 408
-	CodeInternal       ErrorCode = 500
-	CodeNotImplemented ErrorCode = 501
+	CodeBadRequest      ErrorCode = 400
+	CodeUnauthorized    ErrorCode = 401
+	CodePaymentRequired ErrorCode = 402
+	CodeForbidden       ErrorCode = 403
+	CodeNotFound        ErrorCode = 404
+	CodeTimeout         ErrorCode = 408
+	CodeTooManyRequests ErrorCode = 429
+	CodeInternal        ErrorCode = 500
+	CodeNotImplemented  ErrorCode = 501
+	CodeUnavailable     ErrorCode = 503
 )
 
 // AppError represents an application error with a status code and user-friendly message
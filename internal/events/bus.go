@@ -0,0 +1,135 @@
+// Package events publishes what happens in debugging sessions: a session started, the
+// program hit a breakpoint or crashed, the assistant answered, a session spent its LLM
+// budget. Subscribers within the server receive them from a Bus; the notifiers of
+// events.targets post them to webhooks, Slack channels and mailboxes.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Types of events
+const (
+	SessionStarted = "session.started" // GDB started on a session's executable
+	BreakpointHit  = "breakpoint.hit"  // The program stopped at a breakpoint or watchpoint
+	CrashDetected  = "crash.detected"  // The program received a fatal signal
+	ChatCompleted  = "chat.completed"  // The assistant answered a chat request
+	BudgetExceeded = "budget.exceeded" // A session spent its LLM budget
+)
+
+// Types lists every type of event
+var Types = []string{SessionStarted, BreakpointHit, CrashDetected, ChatCompleted, BudgetExceeded}
+
+// subscriberBuffer is how many events a subscriber may fall behind by; further events
+// are dropped for it rather than holding up the publisher
+const subscriberBuffer = 256
+
+// Event is something that happened in a debugging session
+type Event struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	Time    time.Time              `json:"time"`
+	User    string                 `json:"user,omitempty"` // "" when authentication is disabled
+	Session string                 `json:"session,omitempty"`
+	Message string                 `json:"message"`        // Says what happened, for people
+	Data    map[string]interface{} `json:"data,omitempty"` // Details by type, e.g. the signal of a crash
+}
+
+// Bus delivers published events to its subscribers. A nil Bus publishes nothing, so
+// callers need not check whether anyone listens.
+type Bus struct {
+	mutex       sync.RWMutex
+	subscribers map[*subscription]struct{}
+}
+
+// subscription is a subscriber's queue of events
+type subscription struct {
+	types  map[string]bool // nil for every type
+	events chan Event
+	done   chan struct{}
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscription]struct{})}
+}
+
+// Subscribe calls handler with each event of the given types, or of every type when none
+// is given. Events reach handler in the order they were published, on a goroutine of its
+// own, so a slow handler holds up no one but itself. It returns a function ending the
+// subscription.
+func (b *Bus) Subscribe(handler func(Event), types ...string) (unsubscribe func()) {
+	if b == nil {
+		return func() {}
+	}
+	s := &subscription{events: make(chan Event, subscriberBuffer), done: make(chan struct{})}
+	if len(types) > 0 {
+		s.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	b.mutex.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mutex.Unlock()
+
+	go func() {
+		for {
+			select {
+			case event := <-s.events:
+				handler(event)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subscribers, s)
+			b.mutex.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// Publish sends an event to the subscribers of its type, filling in its ID and time
+// when they are unset. It never blocks.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for s := range b.subscribers {
+		if s.types != nil && !s.types[event.Type] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			log.Printf("events: dropped %s event %s for a subscriber that fell behind", event.Type, event.ID)
+		}
+	}
+}
+
+// newEventID returns a random event ID
+func newEventID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
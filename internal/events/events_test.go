@@ -0,0 +1,168 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// receive returns the next event of ch, failing after a second
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	all := make(chan Event, 10)
+	crashes := make(chan Event, 10)
+	bus.Subscribe(func(e Event) { all <- e })
+	unsubscribe := bus.Subscribe(func(e Event) { crashes <- e }, CrashDetected)
+
+	bus.Publish(Event{Type: SessionStarted, User: "alice"})
+	bus.Publish(Event{Type: CrashDetected, User: "alice"})
+
+	started := receive(t, all)
+	assert.Equal(t, SessionStarted, started.Type)
+	assert.NotEmpty(t, started.ID)
+	assert.False(t, started.Time.IsZero())
+	assert.Equal(t, CrashDetected, receive(t, all).Type, "events arrive in order")
+	assert.Equal(t, CrashDetected, receive(t, crashes).Type, "subscribers only receive the types they asked for")
+
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(Event{Type: CrashDetected})
+	receive(t, all)
+	select {
+	case <-crashes:
+		t.Fatal("an ended subscription received an event")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var nilBus *Bus
+	nilBus.Publish(Event{Type: SessionStarted})
+	nilBus.Subscribe(func(Event) {})()
+}
+
+func TestValidate(t *testing.T) {
+	webhook := config.EventTarget{Name: "ci", Kind: config.EventWebhook, URL: "https://example.com/hook"}
+	mail := config.EventTarget{Name: "oncall", Kind: config.EventEmail, SMTP: "smtp.example.com:587", From: "gdb@example.com", To: []string{"oncall@example.com"}}
+	assert.NoError(t, Validate(config.EventsConfig{Targets: []config.EventTarget{webhook, mail}}))
+
+	for name, target := range map[string]config.EventTarget{
+		"no name":       {Kind: config.EventSlack, URL: "https://hooks.slack.com/x"},
+		"no url":        {Name: "slack", Kind: config.EventSlack},
+		"unknown kind":  {Name: "pager", Kind: "pager", URL: "https://example.com"},
+		"no smtp port":  {Name: "mail", Kind: config.EventEmail, SMTP: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}},
+		"no recipients": {Name: "mail", Kind: config.EventEmail, SMTP: "smtp.example.com:25", From: "a@example.com"},
+		"unknown event": {Name: "ci", Kind: config.EventWebhook, URL: "https://example.com", Events: []string{"session.ended"}},
+	} {
+		assert.Error(t, Validate(config.EventsConfig{Targets: []config.EventTarget{target}}), name)
+	}
+	assert.Error(t, Validate(config.EventsConfig{Targets: []config.EventTarget{webhook, webhook}}), "names are unique")
+}
+
+func TestNotifierPostsWebhooks(t *testing.T) {
+	retryBackoff = time.Millisecond
+	var failures atomic.Int32
+	failures.Store(1)
+	bodies := make(chan *http.Request, 10)
+	payloads := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r
+		payloads <- body
+	}))
+	defer server.Close()
+
+	bus := NewBus()
+	notifier, err := NewNotifier(bus, config.EventsConfig{
+		MaxAttempts: 3,
+		Targets: []config.EventTarget{
+			{Name: "ci", Kind: config.EventWebhook, URL: server.URL + "/hook", Secret: "s3cret", Events: []string{CrashDetected}},
+			{Name: "slack", Kind: config.EventSlack, URL: server.URL + "/slack", Events: []string{BudgetExceeded}},
+		},
+	})
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	bus.Publish(Event{Type: SessionStarted, Message: "not sent anywhere"})
+	bus.Publish(Event{Type: CrashDetected, User: "alice", Message: "The program crashed with SIGSEGV"})
+
+	req := <-bodies
+	body := <-payloads
+	assert.Equal(t, "/hook", req.URL.Path, "the failed delivery is retried")
+	assert.Equal(t, CrashDetected, req.Header.Get(HeaderEvent))
+	assert.Equal(t, Sign("s3cret", body), req.Header.Get(HeaderSignature))
+	var event Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "alice", event.User)
+	assert.Equal(t, event.ID, req.Header.Get(HeaderDelivery))
+
+	bus.Publish(Event{Type: BudgetExceeded, User: "bob", Message: "The session spent its budget"})
+	req = <-bodies
+	body = <-payloads
+	assert.Equal(t, "/slack", req.URL.Path)
+	assert.JSONEq(t, `{"text": "*budget.exceeded* The session spent its budget (bob)"}`, string(body))
+
+	// Reloading replaces the targets
+	require.NoError(t, notifier.Reload(config.EventsConfig{MaxAttempts: 1}))
+	bus.Publish(Event{Type: CrashDetected})
+	select {
+	case <-bodies:
+		t.Fatal("a removed target received an event")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierSendsMail(t *testing.T) {
+	type mail struct {
+		addr string
+		to   []string
+		msg  string
+	}
+	sent := make(chan mail, 1)
+	sendMail = func(addr string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+		sent <- mail{addr, to, string(msg)}
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	bus := NewBus()
+	notifier, err := NewNotifier(bus, config.EventsConfig{MaxAttempts: 1, Targets: []config.EventTarget{{
+		Name: "oncall", Kind: config.EventEmail, SMTP: "smtp.example.com:25", From: "gdb@example.com", To: []string{"oncall@example.com"},
+	}}})
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	bus.Publish(Event{Type: CrashDetected, Session: "abc", Message: "The program crashed\r\nBcc: someone", Data: map[string]interface{}{"signal": "SIGSEGV"}})
+	select {
+	case m := <-sent:
+		assert.Equal(t, "smtp.example.com:25", m.addr)
+		assert.Equal(t, []string{"oncall@example.com"}, m.to)
+		assert.Contains(t, m.msg, "Subject: [gogdbllm] The program crashed  Bcc: someone\r\n", "messages cannot add headers")
+		assert.Contains(t, m.msg, "Session: abc\r\n")
+		assert.Contains(t, m.msg, "signal: SIGSEGV\r\n")
+	case <-time.After(time.Second):
+		t.Fatal("no mail sent")
+	}
+}
@@ -0,0 +1,263 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Headers of the requests posted to webhooks
+const (
+	HeaderEvent     = "X-GoGDBLLM-Event"     // The event's type
+	HeaderDelivery  = "X-GoGDBLLM-Delivery"  // The event's ID, the same for each attempt
+	HeaderSignature = "X-GoGDBLLM-Signature" // sha256=<hex HMAC-SHA256 of the body with the target's secret>
+)
+
+// retryBackoff is the wait before retrying a failed delivery, doubled for each further one
+var retryBackoff = time.Second
+
+// sendMail sends a mail; replaced in tests
+var sendMail = smtp.SendMail
+
+// Validate checks that each target can be sent to and names known types of events
+func Validate(cfg config.EventsConfig) error {
+	names := make(map[string]bool)
+	for i, target := range cfg.Targets {
+		if target.Name == "" {
+			return fmt.Errorf("events.targets[%d]: name is required", i)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("events.targets: %s is defined twice", target.Name)
+		}
+		names[target.Name] = true
+		switch target.Kind {
+		case config.EventWebhook, config.EventSlack:
+			if target.URL == "" {
+				return fmt.Errorf("events.targets: %s needs a url", target.Name)
+			}
+		case config.EventEmail:
+			if _, _, err := net.SplitHostPort(target.SMTP); err != nil {
+				return fmt.Errorf("events.targets: %s needs smtp as host:port", target.Name)
+			}
+			if target.From == "" || len(target.To) == 0 {
+				return fmt.Errorf("events.targets: %s needs from and to", target.Name)
+			}
+		default:
+			return fmt.Errorf("events.targets: %s has unknown kind %q (want %s, %s or %s)",
+				target.Name, target.Kind, config.EventWebhook, config.EventSlack, config.EventEmail)
+		}
+		for _, t := range target.Events {
+			if !slices.Contains(Types, t) {
+				return fmt.Errorf("events.targets: %s names unknown event %q (want one of %s)", target.Name, t, strings.Join(Types, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Notifier sends the events published on a bus to the configured targets. Each target
+// has a subscription of its own, so a target that is down delays no other.
+type Notifier struct {
+	bus    *Bus
+	client *http.Client
+	mutex  sync.Mutex
+	stop   []func() // Ends the subscriptions of the current targets
+}
+
+// NewNotifier subscribes the targets of cfg to bus
+func NewNotifier(bus *Bus, cfg config.EventsConfig) (*Notifier, error) {
+	n := &Notifier{bus: bus, client: &http.Client{}}
+	if err := n.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Reload replaces the targets with those of cfg. Events already queued for a removed
+// target are dropped.
+func (n *Notifier) Reload(cfg config.EventsConfig) error {
+	if err := Validate(cfg); err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, stop := range n.stop {
+		stop()
+	}
+	n.stop = nil
+	for _, target := range cfg.Targets {
+		n.stop = append(n.stop, n.bus.Subscribe(func(event Event) {
+			n.deliver(cfg, target, event)
+		}, target.Events...))
+	}
+	return nil
+}
+
+// Close ends the subscriptions of every target
+func (n *Notifier) Close() {
+	n.Reload(config.EventsConfig{})
+}
+
+// deliver sends an event to a target, retrying failures that may pass up to
+// cfg.MaxAttempts times, and logs it when that fails
+func (n *Notifier) deliver(cfg config.EventsConfig, target config.EventTarget, event Event) {
+	wait := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := n.send(cfg.Timeout, target, event)
+		if err == nil {
+			return
+		}
+		var permanent *permanentError
+		if attempt >= cfg.MaxAttempts || errors.As(err, &permanent) {
+			log.Printf("events: sending %s event %s to %s failed after %d attempts: %v", event.Type, event.ID, target.Name, attempt, err)
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// permanentError is a failure that retrying will not fix, like a webhook refusing the
+// request as malformed
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// send sends an event to a target once
+func (n *Notifier) send(timeout time.Duration, target config.EventTarget, event Event) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	switch target.Kind {
+	case config.EventSlack:
+		body, err := json.Marshal(map[string]string{"text": slackText(event)})
+		if err != nil {
+			return err
+		}
+		return n.post(ctx, target.URL, nil, body)
+	case config.EventEmail:
+		return sendEmail(target, event)
+	default:
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		header := http.Header{}
+		header.Set(HeaderEvent, event.Type)
+		header.Set(HeaderDelivery, event.ID)
+		if target.Secret != "" {
+			header.Set(HeaderSignature, Sign(target.Secret, body))
+		}
+		return n.post(ctx, target.URL, header, body)
+	}
+}
+
+// Sign returns the signature of a webhook's body with its secret, as sent in
+// HeaderSignature, for receivers to check the event came from the server
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post POSTs a JSON body and fails unless the answer is a success. Client errors other
+// than 408 and 429 are permanent.
+func (n *Notifier) post(ctx context.Context, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	if resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// sendEmail mails an event to a target's recipients
+func sendEmail(target config.EventTarget, event Event) error {
+	var auth smtp.Auth
+	if target.Username != "" {
+		host, _, _ := net.SplitHostPort(target.SMTP)
+		auth = smtp.PlainAuth("", target.Username, target.Password, host)
+	}
+	return sendMail(target.SMTP, auth, target.From, target.To, mailMessage(target, event))
+}
+
+// mailMessage is an event as a plain text mail
+func mailMessage(target config.EventTarget, event Event) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", target.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", strings.Join(target.To, ", "))
+	fmt.Fprintf(&sb, "Subject: [gogdbllm] %s\r\n", headerSafe(event.Message))
+	fmt.Fprintf(&sb, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	sb.WriteString(event.Message + "\r\n\r\n")
+	fmt.Fprintf(&sb, "Event: %s (%s)\r\n", event.Type, event.ID)
+	if event.User != "" {
+		fmt.Fprintf(&sb, "User: %s\r\n", event.User)
+	}
+	if event.Session != "" {
+		fmt.Fprintf(&sb, "Session: %s\r\n", event.Session)
+	}
+	for _, key := range sortedKeys(event.Data) {
+		fmt.Fprintf(&sb, "%s: %v\r\n", key, event.Data[key])
+	}
+	return []byte(sb.String())
+}
+
+// headerSafe keeps text from ending a mail header
+func headerSafe(text string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+}
+
+// slackText is an event as a Slack message
+func slackText(event Event) string {
+	text := fmt.Sprintf("*%s* %s", event.Type, event.Message)
+	if event.User != "" {
+		text += " (" + event.User + ")"
+	}
+	return text
+}
+
+// sortedKeys returns the keys of an event's data in order
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
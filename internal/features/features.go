@@ -0,0 +1,252 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Known feature flags
+const (
+	FlagStreaming = "streaming"  // Stream chat responses to the user's clients
+	FlagAgentLoop = "agent_loop" // How the assistant follows up on the output of its commands
+)
+
+// Versions of the agent loop, the variants of FlagAgentLoop. With the flag off, the
+// assistant's commands run but their output is not sent back to it.
+const (
+	AgentLoopV1 = "v1" // One follow-up with the output of the response's commands
+	AgentLoopV2 = "v2" // Follow-ups run the commands they ask for too, for a few rounds
+)
+
+// Flag values for flags without experiment variants
+const (
+	ValueOn  = "on"
+	ValueOff = "off"
+)
+
+// Assignments maps flag names to the value assigned to a session
+type Assignments map[string]string
+
+// Enabled reports whether a flag is switched on (any value other than off)
+func (a Assignments) Enabled(flag string) bool {
+	value, ok := a[flag]
+	return ok && value != ValueOff
+}
+
+// Variant returns the value assigned to a flag, or off if it is not assigned
+func (a Assignments) Variant(flag string) string {
+	if value, ok := a[flag]; ok {
+		return value
+	}
+	return ValueOff
+}
+
+// remoteDocument is the format of the optional remote flag document
+type remoteDocument struct {
+	Flags map[string]config.FlagConfig `json:"flags"`
+}
+
+// Manager evaluates feature flags and keeps each session's assignments stable
+type Manager struct {
+	localFlags  map[string]config.FlagConfig
+	remoteFlags map[string]config.FlagConfig
+	sessions    map[string]Assignments
+	remoteURL   string
+	interval    time.Duration
+	httpClient  *http.Client
+	mutex       sync.RWMutex
+}
+
+// NewManager creates a new feature flag manager from configuration
+func NewManager(cfg *config.Config) *Manager {
+	flags := make(map[string]config.FlagConfig, len(cfg.Features.Flags))
+	for name, flag := range cfg.Features.Flags {
+		flags[name] = flag
+	}
+
+	return &Manager{
+		localFlags:  flags,
+		remoteFlags: make(map[string]config.FlagConfig),
+		sessions:    make(map[string]Assignments),
+		remoteURL:   cfg.Features.RemoteURL,
+		interval:    cfg.Features.RefreshInterval,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ForSession returns the flag assignments for a session, evaluating them on first use.
+// Assignments are cached so a session keeps its behaviour even if the flags change.
+func (m *Manager) ForSession(sessionID string) Assignments {
+	m.mutex.RLock()
+	assignments, ok := m.sessions[sessionID]
+	m.mutex.RUnlock()
+	if ok {
+		return assignments
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if assignments, ok := m.sessions[sessionID]; ok {
+		return assignments
+	}
+
+	assignments = m.evaluate(sessionID)
+	m.sessions[sessionID] = assignments
+	return assignments
+}
+
+// ReleaseSession forgets the cached assignments for a session
+func (m *Manager) ReleaseSession(sessionID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.sessions, sessionID)
+}
+
+// evaluate computes assignments for a session from the merged flag set; callers hold the lock
+func (m *Manager) evaluate(sessionID string) Assignments {
+	assignments := make(Assignments)
+	for name, flag := range m.mergedFlags() {
+		assignments[name] = evaluateFlag(sessionID, name, flag)
+	}
+	return assignments
+}
+
+// mergedFlags returns local flags overridden by any remote flags
+func (m *Manager) mergedFlags() map[string]config.FlagConfig {
+	merged := make(map[string]config.FlagConfig, len(m.localFlags)+len(m.remoteFlags))
+	for name, flag := range m.localFlags {
+		merged[name] = flag
+	}
+	for name, flag := range m.remoteFlags {
+		merged[name] = flag
+	}
+	return merged
+}
+
+// evaluateFlag deterministically assigns a flag value to a session
+func evaluateFlag(sessionID, name string, flag config.FlagConfig) string {
+	if !flag.Enabled {
+		return ValueOff
+	}
+
+	if flag.Rollout > 0 && flag.Rollout < 100 && bucket(sessionID, name, "rollout") >= flag.Rollout {
+		return ValueOff
+	}
+
+	if len(flag.Variants) == 0 {
+		return ValueOn
+	}
+
+	// Walk the variants in a stable order so the same bucket always maps to the same variant
+	names := make([]string, 0, len(flag.Variants))
+	total := 0
+	for variant, weight := range flag.Variants {
+		if weight > 0 {
+			names = append(names, variant)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return ValueOn
+	}
+	sort.Strings(names)
+
+	point := bucket(sessionID, name, "variant") * total / 100
+	for _, variant := range names {
+		point -= flag.Variants[variant]
+		if point < 0 {
+			return variant
+		}
+	}
+	return names[len(names)-1]
+}
+
+// bucket hashes a session and flag into the range [0, 100)
+func bucket(sessionID, flag, salt string) int {
+	h := fnv.New32a()
+	h.Write([]byte(sessionID + ":" + flag + ":" + salt))
+	return int(h.Sum32() % 100)
+}
+
+// RefreshRemote fetches the remote flag document, if one is configured
+func (m *Manager) RefreshRemote(ctx context.Context) error {
+	if m.remoteURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.remoteURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create feature flag request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote feature flags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote feature flags returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read remote feature flags: %w", err)
+	}
+
+	var doc remoteDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("failed to parse remote feature flags: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.remoteFlags = doc.Flags
+	if m.remoteFlags == nil {
+		m.remoteFlags = make(map[string]config.FlagConfig)
+	}
+	return nil
+}
+
+// StartRemoteRefresh periodically refreshes the remote flags until ctx is cancelled
+func (m *Manager) StartRemoteRefresh(ctx context.Context) {
+	if m.remoteURL == "" {
+		return
+	}
+
+	interval := m.interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		if err := m.RefreshRemote(ctx); err != nil {
+			log.Printf("Feature flags: %v", err)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.RefreshRemote(ctx); err != nil {
+					log.Printf("Feature flags: %v", err)
+				}
+			}
+		}
+	}()
+}
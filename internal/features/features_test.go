@@ -0,0 +1,49 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestEvaluateFlag(t *testing.T) {
+	assert.Equal(t, ValueOff, evaluateFlag("s1", "streaming", config.FlagConfig{Enabled: false}))
+	assert.Equal(t, ValueOn, evaluateFlag("s1", "streaming", config.FlagConfig{Enabled: true}))
+	assert.Equal(t, "v2", evaluateFlag("s1", "agent_loop", config.FlagConfig{
+		Enabled:  true,
+		Variants: map[string]int{"v1": 0, "v2": 100},
+	}))
+}
+
+func TestRolloutDistribution(t *testing.T) {
+	flag := config.FlagConfig{Enabled: true, Rollout: 30}
+	on := 0
+	for i := 0; i < 1000; i++ {
+		if evaluateFlag(string(rune('a'+i%26))+string(rune(i)), "new_parser", flag) == ValueOn {
+			on++
+		}
+	}
+	assert.InDelta(t, 300, on, 80)
+}
+
+func TestForSessionIsStable(t *testing.T) {
+	cfg := &config.Config{Features: config.FeaturesConfig{Flags: map[string]config.FlagConfig{
+		FlagStreaming: {Enabled: true},
+	}}}
+	manager := NewManager(cfg)
+
+	first := manager.ForSession("session-1")
+	assert.True(t, first.Enabled(FlagStreaming))
+
+	// Changing flags must not affect a session that already has assignments
+	manager.localFlags[FlagStreaming] = config.FlagConfig{Enabled: false}
+	assert.True(t, manager.ForSession("session-1").Enabled(FlagStreaming))
+	assert.False(t, manager.ForSession("session-2").Enabled(FlagStreaming))
+	assert.Equal(t, ValueOff, manager.ForSession("session-2").Variant(FlagAgentLoop))
+
+	// A released session is evaluated afresh
+	manager.ReleaseSession("session-1")
+	assert.Len(t, manager.sessions, 1)
+	assert.False(t, manager.ForSession("session-1").Enabled(FlagStreaming))
+}
@@ -0,0 +1,210 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// AddressAnnotation describes where an address lives in the inferior
+type AddressAnnotation struct {
+	Address uint64 `json:"address"`
+	Module  string `json:"module,omitempty"`
+	// Offset is the address's offset in Module's file, known only when a mapping matched
+	Offset      *uint64 `json:"offset,omitempty"`
+	Symbol      string  `json:"symbol,omitempty"`
+	Section     string  `json:"section,omitempty"`
+	Permissions string  `json:"permissions,omitempty"`
+}
+
+// MemoryMapping represents a single line of `info proc mappings`
+type MemoryMapping struct {
+	Start       uint64
+	End         uint64
+	FileOffset  uint64
+	Permissions string
+	ObjFile     string
+}
+
+var (
+	// infoSymbolRegex matches `info symbol` output such as "main + 4 in section .text of /lib/libc.so.6"
+	infoSymbolRegex = regexp.MustCompile(`^(.+?) in section (\S+)(?: of (.+))?$`)
+
+	// permsRegex matches the Perms column of `info proc mappings` on newer GDB versions
+	permsRegex = regexp.MustCompile(`^[r-][w-][x-][psr-]$`)
+
+	// crashSignalRegex matches the line GDB prints when the inferior receives a fatal signal
+	crashSignalRegex = regexp.MustCompile(`Program (?:received|terminated with) signal (SIG[A-Z]+)`)
+
+	// frameAddressRegex matches addresses at the start of a stop location or backtrace frame
+	frameAddressRegex = regexp.MustCompile(`(?m)^(?:#\d+\s+)?(0x[0-9a-fA-F]+) in `)
+)
+
+// maxCrashAddresses limits how many addresses are annotated for a single crash
+const maxCrashAddresses = 3
+
+// AnnotateAddress resolves an address to module+offset, nearest symbol, section and mapping permissions
+func (g *GDBService) AnnotateAddress(addr uint64) (*AddressAnnotation, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("address annotation"); err != nil {
+		return nil, err
+	}
+
+	annotation := &AddressAnnotation{Address: addr}
+
+	symbolOutput, err := g.ExecuteCommandWithOutput(fmt.Sprintf("info symbol 0x%x", addr), g.commandTimeout())
+	if err != nil {
+		return nil, appErrors.Wrap(err, "failed to resolve symbol")
+	}
+	annotation.Symbol, annotation.Section, annotation.Module = parseInfoSymbol(symbolOutput)
+
+	// Mappings are only available while the inferior is alive, so a failure here is not fatal
+	mappingsOutput, err := g.ExecuteCommandWithOutput("info proc mappings", g.commandTimeout())
+	if err == nil {
+		if mapping := findMapping(parseMappings(mappingsOutput), addr); mapping != nil {
+			annotation.Permissions = mapping.Permissions
+			offset := addr - mapping.Start + mapping.FileOffset
+			annotation.Offset = &offset
+			if mapping.ObjFile != "" {
+				annotation.Module = mapping.ObjFile
+			}
+		}
+	}
+
+	return annotation, nil
+}
+
+// commandTimeout returns the configured per-command timeout in seconds
+func (g *GDBService) commandTimeout() int {
+	if g.config == nil || g.config.Timeout <= 0 {
+		return 2
+	}
+	return g.config.Timeout
+}
+
+// String renders the annotation as a single line suitable for LLM context
+func (a *AddressAnnotation) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("0x%x:", a.Address))
+
+	if a.Module != "" {
+		sb.WriteString(" " + a.Module)
+		if a.Offset != nil {
+			sb.WriteString(fmt.Sprintf("+0x%x", *a.Offset))
+		}
+	}
+	if a.Symbol != "" {
+		sb.WriteString(fmt.Sprintf(" <%s>", a.Symbol))
+	} else {
+		sb.WriteString(" <no symbol>")
+	}
+	if a.Section != "" {
+		sb.WriteString(" section " + a.Section)
+	}
+	if a.Permissions != "" {
+		sb.WriteString(" [" + a.Permissions + "]")
+	}
+
+	return sb.String()
+}
+
+// ParseAddress parses a hexadecimal (0x-prefixed) or decimal address
+func ParseAddress(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// ReportsCrash reports whether GDB output reports a fatal signal
+func ReportsCrash(output string) bool {
+	return crashSignalRegex.MatchString(output)
+}
+
+// FindCrashAddresses extracts the faulting addresses from GDB output that reports a fatal signal
+func FindCrashAddresses(output string) []uint64 {
+	loc := crashSignalRegex.FindStringIndex(output)
+	if loc == nil {
+		return nil
+	}
+
+	var addresses []uint64
+	seen := make(map[uint64]bool)
+	for _, match := range frameAddressRegex.FindAllStringSubmatch(output[loc[0]:], -1) {
+		addr, err := ParseAddress(match[1])
+		if err != nil || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		addresses = append(addresses, addr)
+		if len(addresses) >= maxCrashAddresses {
+			break
+		}
+	}
+
+	return addresses
+}
+
+// parseInfoSymbol extracts the symbol, section and module from `info symbol` output
+func parseInfoSymbol(output string) (symbol, section, module string) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "(gdb)"))
+		if match := infoSymbolRegex.FindStringSubmatch(line); match != nil {
+			return match[1], match[2], match[3]
+		}
+	}
+	return "", "", ""
+}
+
+// parseMappings parses the output of `info proc mappings`
+func parseMappings(output string) []MemoryMapping {
+	var mappings []MemoryMapping
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "0x") {
+			continue
+		}
+
+		start, err := ParseAddress(fields[0])
+		if err != nil {
+			continue
+		}
+		end, err := ParseAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		fileOffset, err := ParseAddress(fields[3])
+		if err != nil {
+			continue
+		}
+
+		mapping := MemoryMapping{Start: start, End: end, FileOffset: fileOffset}
+		rest := fields[4:]
+		if len(rest) > 0 && permsRegex.MatchString(rest[0]) {
+			mapping.Permissions = rest[0]
+			rest = rest[1:]
+		}
+		mapping.ObjFile = strings.Join(rest, " ")
+
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings
+}
+
+// findMapping returns the mapping that contains addr, if any
+func findMapping(mappings []MemoryMapping, addr uint64) *MemoryMapping {
+	for i := range mappings {
+		if addr >= mappings[i].Start && addr < mappings[i].End {
+			return &mappings[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseInfoSymbol tests parsing of `info symbol` output
+func TestParseInfoSymbol(t *testing.T) {
+	symbol, section, module := parseInfoSymbol("main + 4 in section .text\n")
+	assert.Equal(t, "main + 4", symbol)
+	assert.Equal(t, ".text", section)
+	assert.Equal(t, "", module)
+
+	symbol, section, module = parseInfoSymbol("__GI_raise + 203 in section .text of /lib/x86_64-linux-gnu/libc.so.6")
+	assert.Equal(t, "__GI_raise + 203", symbol)
+	assert.Equal(t, ".text", section)
+	assert.Equal(t, "/lib/x86_64-linux-gnu/libc.so.6", module)
+
+	symbol, _, _ = parseInfoSymbol("No symbol matches 0x1234.")
+	assert.Equal(t, "", symbol)
+}
+
+// TestParseMappings tests parsing of `info proc mappings` output with and without the Perms column
+func TestParseMappings(t *testing.T) {
+	output := `process 1234
+Mapped address spaces:
+
+          Start Addr           End Addr       Size     Offset  Perms  objfile
+      0x555555554000     0x555555555000     0x1000        0x0  r--p   /tmp/crash
+      0x555555555000     0x555555556000     0x1000     0x1000  r-xp   /tmp/crash
+      0x7ffffffde000     0x7ffffffff000    0x21000        0x0  rw-p   [stack]
+`
+	mappings := parseMappings(output)
+	assert.Len(t, mappings, 3)
+	assert.Equal(t, "r-xp", mappings[1].Permissions)
+	assert.Equal(t, "/tmp/crash", mappings[1].ObjFile)
+
+	mapping := findMapping(mappings, 0x555555555139)
+	assert.NotNil(t, mapping)
+	assert.Equal(t, uint64(0x1000), mapping.FileOffset)
+
+	legacy := parseMappings("            0x400000           0x401000     0x1000        0x0 /tmp/crash")
+	assert.Len(t, legacy, 1)
+	assert.Equal(t, "", legacy[0].Permissions)
+	assert.Equal(t, "/tmp/crash", legacy[0].ObjFile)
+
+	assert.Nil(t, findMapping(mappings, 0x10))
+}
+
+// TestFindCrashAddresses tests extraction of faulting addresses from crash output
+func TestFindCrashAddresses(t *testing.T) {
+	output := `Starting program: /tmp/crash
+
+Program received signal SIGSEGV, Segmentation fault.
+0x0000555555555139 in main () at crash.c:5
+5	    *p = 1;`
+	assert.Equal(t, []uint64{0x555555555139}, FindCrashAddresses(output))
+	assert.True(t, ReportsCrash(output))
+
+	assert.Nil(t, FindCrashAddresses("0x0000555555555139 in main () at crash.c:5"))
+	assert.False(t, ReportsCrash("0x0000555555555139 in main () at crash.c:5"))
+}
+
+// TestAddressAnnotationString tests the single-line rendering of an annotation
+func TestAddressAnnotationString(t *testing.T) {
+	offset := uint64(0x1139)
+	annotation := &AddressAnnotation{
+		Address:     0x555555555139,
+		Module:      "/tmp/crash",
+		Offset:      &offset,
+		Symbol:      "main + 16",
+		Section:     ".text",
+		Permissions: "r-xp",
+	}
+	assert.Equal(t, "0x555555555139: /tmp/crash+0x1139 <main + 16> section .text [r-xp]", annotation.String())
+
+	// Without mappings only `info symbol` names the module, and the offset is unknown
+	annotation = &AddressAnnotation{Address: 0x7ffff7e5a000, Module: "/lib/libc.so.6", Symbol: "abort + 0", Section: ".text"}
+	assert.Equal(t, "0x7ffff7e5a000: /lib/libc.so.6 <abort + 0> section .text", annotation.String())
+}
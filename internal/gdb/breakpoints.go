@@ -0,0 +1,262 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// breakpointCommands are the commands, with their abbreviations, that create a
+// breakpoint, watchpoint or catchpoint
+var breakpointCommands = map[string]bool{
+	"break": true, "b": true, "br": true, "bre": true, "brea": true,
+	"tbreak": true, "tb": true, "hbreak": true, "thbreak": true,
+	"watch": true, "wa": true, "rwatch": true, "awatch": true,
+	"catch": true, "tcatch": true,
+}
+
+// breakpointCreated matches GDB's confirmation of a new breakpoint, e.g. "Breakpoint 2 at
+// 0x1149: file crash.c, line 5." or "Hardware watchpoint 3: total"
+var breakpointCreated = regexp.MustCompile(`^(?:\(gdb\) )*(?:Temporary breakpoint|Breakpoint|Hardware breakpoint|(?:Hardware |Software )?(?:read |access \(read/write\) )?[Ww]atchpoint|Catchpoint) (\d+)(?: at |: | \()`)
+
+// temporaryHit matches GDB's report that a temporary breakpoint was hit, which deletes it
+var temporaryHit = regexp.MustCompile(`^(?:\(gdb\) )*Temporary breakpoint (\d+), `)
+
+// breakpointsDeleted matches GDB's report of the breakpoints a clear command deleted
+var breakpointsDeleted = regexp.MustCompile(`^(?:\(gdb\) )*Deleted breakpoints? ((?:\d+ ?)+)`)
+
+// Breakpoint is a breakpoint, watchpoint or catchpoint GDB confirmed
+type Breakpoint struct {
+	Number    int    `json:"number"`
+	Command   string `json:"command"` // The command that created it, e.g. "break crash.c:12"
+	Condition string `json:"condition,omitempty"`
+	Disabled  bool   `json:"disabled,omitempty"`
+}
+
+// BreakpointSpec describes a breakpoint to set: where, when it stops, and the commands
+// GDB runs when it does, e.g. {"print x", "continue"} to log x without stopping
+type BreakpointSpec struct {
+	Location    string   `json:"location"` // Function, file:line or *address
+	Condition   string   `json:"condition,omitempty"`
+	IgnoreCount int      `json:"ignoreCount,omitempty"` // Hits to pass before stopping
+	Commands    []string `json:"commands,omitempty"`
+	Temporary   bool     `json:"temporary,omitempty"`
+}
+
+// Command returns the GDB command setting the breakpoint. With an ignore count or
+// commands it spans several lines, which GDB applies to the breakpoint just set; sent as
+// one command, it is recorded and restored as one.
+func (s BreakpointSpec) Command() (string, error) {
+	location, condition := strings.TrimSpace(s.Location), strings.TrimSpace(s.Condition)
+	if location == "" {
+		return "", fmt.Errorf("%w: location is required", appErrors.ErrBadRequest)
+	}
+	if s.IgnoreCount < 0 {
+		return "", fmt.Errorf("%w: ignoreCount must not be negative", appErrors.ErrBadRequest)
+	}
+	fields := append([]string{location, condition}, s.Commands...)
+	for _, field := range fields {
+		if strings.ContainsAny(field, "\r\n") {
+			return "", fmt.Errorf("%w: breakpoint fields must not contain line breaks", appErrors.ErrBadRequest)
+		}
+	}
+
+	command := "break " + location
+	if s.Temporary {
+		command = "tbreak " + location
+	}
+	if condition != "" {
+		command += " if " + condition
+	}
+	lines := []string{command}
+	if s.IgnoreCount > 0 {
+		lines = append(lines, fmt.Sprintf("ignore $bpnum %d", s.IgnoreCount))
+	}
+	if len(s.Commands) > 0 {
+		lines = append(lines, "commands")
+		for _, line := range s.Commands {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			// An end would close the list early and run the rest as ordinary commands
+			if line == "end" {
+				return "", fmt.Errorf("%w: commands must not contain end", appErrors.ErrBadRequest)
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, "end")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// BreakpointStore follows the breakpoints of a GDB process from the commands sent to it
+// and its output, so they can be set again when GDB has to be restarted. A creation
+// command is only recorded once GDB confirms it, before the next command is sent, so
+// failed ones are not repeated.
+type BreakpointStore struct {
+	pending     string // Creation command GDB has not confirmed yet
+	breakpoints map[int]*Breakpoint
+	mutex       sync.Mutex
+}
+
+// NewBreakpointStore creates an empty breakpoint store
+func NewBreakpointStore() *BreakpointStore {
+	return &BreakpointStore{breakpoints: make(map[int]*Breakpoint)}
+}
+
+// Command records a command sent to GDB
+func (s *BreakpointStore) Command(command string) {
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name, args := words[0], words[1:]
+	s.pending = ""
+	switch {
+	case breakpointCommands[name]:
+		s.pending = strings.TrimSpace(command)
+	case name == "delete" || name == "d":
+		if len(args) > 0 && args[0] == "breakpoints" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			s.breakpoints = make(map[int]*Breakpoint)
+		}
+		for _, n := range breakpointNumbers(args) {
+			delete(s.breakpoints, n)
+		}
+	case name == "disable" || name == "disa" || name == "enable" || name == "en":
+		if len(args) > 0 && args[0] == "breakpoints" {
+			args = args[1:]
+		}
+		disabled := strings.HasPrefix(name, "disa")
+		if len(args) == 0 {
+			for _, bp := range s.breakpoints {
+				bp.Disabled = disabled
+			}
+		}
+		for _, n := range breakpointNumbers(args) {
+			if bp, ok := s.breakpoints[n]; ok {
+				bp.Disabled = disabled
+			}
+		}
+	case name == "condition" && len(args) > 0:
+		n, err := strconv.Atoi(args[0])
+		if bp, ok := s.breakpoints[n]; err == nil && ok {
+			bp.Condition = strings.Join(args[1:], " ")
+		}
+	}
+}
+
+// Output records a line of GDB's clean output
+func (s *BreakpointStore) Output(line string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if m := temporaryHit.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		delete(s.breakpoints, n)
+		return
+	}
+	if m := breakpointsDeleted.FindStringSubmatch(line); m != nil {
+		for _, n := range breakpointNumbers(strings.Fields(m[1])) {
+			delete(s.breakpoints, n)
+		}
+		return
+	}
+	if m := breakpointCreated.FindStringSubmatch(line); m != nil && s.pending != "" {
+		n, _ := strconv.Atoi(m[1])
+		if _, known := s.breakpoints[n]; !known {
+			s.breakpoints[n] = &Breakpoint{Number: n, Command: s.pending}
+			s.pending = ""
+		}
+	}
+}
+
+// List returns the breakpoints by number
+func (s *BreakpointStore) List() []Breakpoint {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list := make([]Breakpoint, 0, len(s.breakpoints))
+	for _, bp := range s.breakpoints {
+		list = append(list, *bp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+	return list
+}
+
+// RestoreCommands returns the commands that set the breakpoints again in a new GDB
+// process, where they get new numbers
+func (s *BreakpointStore) RestoreCommands() []string {
+	var commands []string
+	for _, bp := range s.List() {
+		commands = append(commands, bp.Command)
+		if bp.Condition != "" {
+			commands = append(commands, "condition $bpnum "+bp.Condition)
+		}
+		if bp.Disabled {
+			commands = append(commands, "disable $bpnum")
+		}
+	}
+	return commands
+}
+
+// Renumber numbers the breakpoints from 1 in their order, as a new GDB process numbers
+// them when RestoreCommands are sent to it
+func (s *BreakpointStore) Renumber() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	numbers := make([]int, 0, len(s.breakpoints))
+	for n := range s.breakpoints {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	renumbered := make(map[int]*Breakpoint, len(numbers))
+	for i, n := range numbers {
+		bp := s.breakpoints[n]
+		bp.Number = i + 1
+		renumbered[bp.Number] = bp
+	}
+	s.breakpoints = renumbered
+	s.pending = ""
+}
+
+// Reset forgets every breakpoint, for a new GDB process
+func (s *BreakpointStore) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = ""
+	s.breakpoints = make(map[int]*Breakpoint)
+}
+
+// breakpointNumbers parses breakpoint numbers and ranges like "2-4"; other arguments are
+// skipped
+func breakpointNumbers(args []string) []int {
+	var numbers []int
+	for _, arg := range args {
+		from, to, isRange := strings.Cut(arg, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				continue
+			}
+		}
+		for n := first; n <= last && n-first < 1000; n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,151 @@
+package gdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestBreakpointStore(t *testing.T) {
+	store := NewBreakpointStore()
+
+	store.Command("break crash.c:12")
+	store.Output("Breakpoint 1 at 0x1149: file crash.c, line 12.")
+	store.Command("tbreak main")
+	store.Output("(gdb) Temporary breakpoint 2 at 0x1151: file crash.c, line 3.")
+	store.Command("watch total")
+	store.Output("Hardware watchpoint 3: total")
+
+	// Unconfirmed and unrelated commands are not recorded
+	store.Command("break nosuchfunction")
+	store.Output(`Function "nosuchfunction" not defined.`)
+	store.Command("print total")
+	store.Output("Breakpoint 9 at 0x1: file other.c, line 1.")
+
+	store.Command("condition 1 i > 3")
+	store.Command("disable 3")
+	assert.Equal(t, []Breakpoint{
+		{Number: 1, Command: "break crash.c:12", Condition: "i > 3"},
+		{Number: 2, Command: "tbreak main"},
+		{Number: 3, Command: "watch total", Disabled: true},
+	}, store.List())
+
+	// Hitting a temporary breakpoint deletes it
+	store.Output("Temporary breakpoint 2, main () at crash.c:3")
+	assert.Len(t, store.List(), 2)
+
+	assert.Equal(t, []string{
+		"break crash.c:12",
+		"condition $bpnum i > 3",
+		"watch total",
+		"disable $bpnum",
+	}, store.RestoreCommands())
+
+	store.Renumber()
+	numbers := []int{}
+	for _, bp := range store.List() {
+		numbers = append(numbers, bp.Number)
+	}
+	assert.Equal(t, []int{1, 2}, numbers)
+
+	store.Command("delete 2")
+	assert.Len(t, store.List(), 1)
+	store.Output("Deleted breakpoint 1 ")
+	assert.Empty(t, store.List())
+}
+
+func TestBreakpointSpecCommand(t *testing.T) {
+	command, err := BreakpointSpec{Location: "parse.c:42", Condition: "len > 64", Temporary: true}.Command()
+	require.NoError(t, err)
+	assert.Equal(t, "tbreak parse.c:42 if len > 64", command)
+
+	spec := BreakpointSpec{Location: "parse.c:42", IgnoreCount: 3, Commands: []string{"silent", " print len ", "", "continue"}}
+	command, err = spec.Command()
+	require.NoError(t, err)
+	assert.Equal(t, "break parse.c:42\nignore $bpnum 3\ncommands\nsilent\nprint len\ncontinue\nend", command)
+
+	// The block is recorded and restored as the one command that created the breakpoint
+	store := NewBreakpointStore()
+	store.Command(command)
+	store.Output("Breakpoint 1 at 0x1149: file parse.c, line 42.")
+	assert.Equal(t, []string{command}, store.RestoreCommands())
+
+	for _, invalid := range []BreakpointSpec{
+		{},
+		{Location: "main", IgnoreCount: -1},
+		{Location: "main\nshell id"},
+		{Location: "main", Condition: "x\rshell id"},
+		{Location: "main", Commands: []string{"print x", "end", "shell id"}},
+	} {
+		_, err := invalid.Command()
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
+
+func TestBreakpointNumbers(t *testing.T) {
+	assert.Equal(t, []int{1, 3, 4, 5}, breakpointNumbers([]string{"1", "3-5", "x"}))
+	assert.Empty(t, breakpointNumbers([]string{"$bpnum"}))
+}
+
+// fakeGDB is a shell script standing in for GDB: it confirms breakpoints, dies when sent
+// "crash" and echoes everything else
+const fakeGDB = `#!/bin/sh
+n=0
+while read line; do
+	case "$line" in
+	break*) n=$((n+1)); echo "Breakpoint $n at 0x1149: file crash.c, line 5." ;;
+	crash) kill -9 $$ ;;
+	*) echo "$line" ;;
+	esac
+done
+`
+
+func TestGDBServiceRestart(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "gdb")
+	require.NoError(t, os.WriteFile(script, []byte(fakeGDB), 0755))
+
+	cfg := &config.Config{GDB: config.GDBConfig{
+		Path:    script,
+		Restart: config.RestartConfig{Enabled: true, MaxRestarts: 1, Window: time.Minute},
+	}}
+	service := NewGDBService(cfg)
+	statuses := make(chan Status, 2)
+	service.SetStatusHandler(func(s Status) { statuses <- s })
+	go func() {
+		for range service.GetOutputChannel() {
+		}
+	}()
+
+	require.NoError(t, service.StartGDB("crash"))
+	defer service.StopGDB()
+	require.NoError(t, service.SendCommand("break crash.c:5"))
+	require.Eventually(t, func() bool { return len(service.Breakpoints()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, service.SendCommand("crash"))
+	select {
+	case status := <-statuses:
+		assert.Equal(t, StatusRestarted, status.State)
+		assert.Equal(t, "signal: killed", status.Reason)
+		assert.Equal(t, 1, status.Breakpoints)
+	case <-time.After(5 * time.Second):
+		t.Fatal("GDB was not restarted")
+	}
+	assert.True(t, service.IsRunning())
+	require.NotNil(t, service.LastRestart())
+	assert.Equal(t, 1, service.LastRestart().Breakpoints)
+
+	// A second crash within the window exceeds MaxRestarts
+	require.NoError(t, service.SendCommand("crash"))
+	select {
+	case status := <-statuses:
+		assert.Equal(t, StatusExited, status.State)
+	case <-time.After(5 * time.Second):
+		t.Fatal("GDB's exit was not reported")
+	}
+	assert.False(t, service.IsRunning())
+}
@@ -0,0 +1,300 @@
+package gdb
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// checkpointKillWait is how long stopping GDB waits for it to kill the checkpoints
+const checkpointKillWait = time.Second
+
+var (
+	// checkpointCreated matches GDB's report of a new checkpoint, e.g.
+	// "checkpoint 1: fork returned pid 12346."
+	checkpointCreated = regexp.MustCompile(`^(?:\(gdb\) )*checkpoint (\d+): fork returned pid (\d+)\.`)
+
+	// checkpointLine matches a line of `info checkpoints` output, e.g.
+	// "* 0 process 12345 (main process) at 0x555555555139, file crash.c, line 5"
+	checkpointLine = regexp.MustCompile(`^(\*)?\s*(\d+)\s+(?:process (\d+)|Thread 0x[0-9a-f]+ \(LWP (\d+)\))( \(main process\))?(?:,? at (.*))?$`)
+
+	// checkpointsKilled matches GDB's report that it killed the program and with it the
+	// checkpoints, e.g. "[Inferior 1 (process 12345) killed]"
+	checkpointsKilled = regexp.MustCompile(`^(?:\(gdb\) )*\[Inferior \d+ \(process \d+\) killed\]`)
+
+	// checkpointNotFound and checkpointRefused match GDB's refusals of checkpoint commands
+	checkpointNotFound = regexp.MustCompile(`(?i)(?:Not found|No such checkpoint|checkpoint \d+ not found)[^\n]*`)
+	checkpointRefused  = regexp.MustCompile(`(?:The program is not being run|Please switch to another checkpoint|checkpoint: can't find fork function|Cannot delete|not supported|Undefined command)[^\n]*`)
+)
+
+// Checkpoint is a snapshot of the program, a forked copy of its process that GDB can
+// switch back to
+type Checkpoint struct {
+	ID       int        `json:"id"` // 0 is the program itself
+	PID      int        `json:"pid"`
+	Main     bool       `json:"main"` // The program's original process
+	Current  bool       `json:"current"`
+	Location string     `json:"location,omitempty"` // e.g. "0x555555555139, file crash.c, line 5"
+	Note     string     `json:"note,omitempty"`
+	Created  *time.Time `json:"created,omitempty"`
+}
+
+// checkpointRecord is what the store knows of a checkpoint besides GDB's listing
+type checkpointRecord struct {
+	pid     int
+	note    string
+	created time.Time
+}
+
+// CheckpointStore follows the checkpoints GDB makes, however they are made, so they can
+// be listed with their notes and killed when the session ends
+type CheckpointStore struct {
+	mutex   sync.Mutex
+	records map[int]checkpointRecord
+	killed  chan struct{} // Closed when GDB reports it killed the program
+}
+
+// NewCheckpointStore creates an empty checkpoint store
+func NewCheckpointStore() *CheckpointStore {
+	return &CheckpointStore{records: make(map[int]checkpointRecord)}
+}
+
+// Output records the checkpoints created, and their end when the program is killed,
+// from a line of GDB's output
+func (s *CheckpointStore) Output(line string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if match := checkpointCreated.FindStringSubmatch(line); match != nil {
+		id, _ := strconv.Atoi(match[1])
+		pid, _ := strconv.Atoi(match[2])
+		s.records[id] = checkpointRecord{pid: pid, created: time.Now()}
+		return
+	}
+	if checkpointsKilled.MatchString(line) {
+		s.records = make(map[int]checkpointRecord)
+		if s.killed != nil {
+			close(s.killed)
+			s.killed = nil
+		}
+	}
+}
+
+// SetNote attaches a note to a checkpoint
+func (s *CheckpointStore) SetNote(id int, note string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if record, ok := s.records[id]; ok {
+		record.note = note
+		s.records[id] = record
+	}
+}
+
+// Delete forgets a deleted checkpoint
+func (s *CheckpointStore) Delete(id int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.records, id)
+}
+
+// awaitKill returns a channel closed when GDB reports it killed the program, or nil if
+// there are no checkpoints to kill
+func (s *CheckpointStore) awaitKill() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.records) == 0 {
+		return nil
+	}
+	if s.killed == nil {
+		s.killed = make(chan struct{})
+	}
+	return s.killed
+}
+
+// Reset forgets every checkpoint and returns the process IDs they had
+func (s *CheckpointStore) Reset() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pids := make([]int, 0, len(s.records))
+	for _, record := range s.records {
+		pids = append(pids, record.pid)
+	}
+	s.records = make(map[int]checkpointRecord)
+	return pids
+}
+
+// annotate adds the store's notes and creation times to checkpoints GDB listed
+func (s *CheckpointStore) annotate(checkpoints []Checkpoint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range checkpoints {
+		if record, ok := s.records[checkpoints[i].ID]; ok {
+			created := record.created
+			checkpoints[i].Note, checkpoints[i].Created = record.note, &created
+		}
+	}
+}
+
+// Checkpoint snapshots the program, which must be running and stopped, with a note
+// saying why, and returns the checkpoint. GDB makes checkpoints on Linux only.
+func (g *GDBService) Checkpoint(note string) (*Checkpoint, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("checkpoints"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("checkpoint", g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	var match []string
+	for _, line := range strings.Split(output, "\n") {
+		if match = checkpointCreated.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			break
+		}
+	}
+	if match == nil {
+		return nil, checkpointError(output)
+	}
+	id, _ := strconv.Atoi(match[1])
+	pid, _ := strconv.Atoi(match[2])
+	g.checkpoints.SetNote(id, note)
+	created := time.Now()
+	return &Checkpoint{ID: id, PID: pid, Note: note, Created: &created}, nil
+}
+
+// Checkpoints lists the program's checkpoints, with the program itself as checkpoint 0,
+// or none if no checkpoint was made
+func (g *GDBService) Checkpoints() ([]Checkpoint, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("checkpoints"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("info checkpoints", g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := ParseCheckpoints(output)
+	g.checkpoints.annotate(checkpoints)
+	return checkpoints, nil
+}
+
+// RestoreCheckpoint switches the program back to a checkpoint and returns GDB's report of
+// where it stopped. The checkpoint is kept, so it can be restored again.
+func (g *GDBService) RestoreCheckpoint(id int) (string, error) {
+	if !g.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("checkpoints"); err != nil {
+		return "", err
+	}
+	output, err := g.ExecuteCommandWithOutput(RestoreCheckpointCommand(id), g.commandTimeout())
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(output, "Switching to") {
+		return "", checkpointError(output)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// DeleteCheckpoint deletes a checkpoint, killing its process. The current one cannot be
+// deleted.
+func (g *GDBService) DeleteCheckpoint(id int) error {
+	if !g.IsRunning() {
+		return appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("checkpoints"); err != nil {
+		return err
+	}
+	output, err := g.ExecuteCommandWithOutput(DeleteCheckpointCommand(id), g.commandTimeout())
+	if err != nil {
+		return err
+	}
+	if checkpointNotFound.MatchString(output) || checkpointRefused.MatchString(output) {
+		return checkpointError(output)
+	}
+	g.checkpoints.Delete(id)
+	return nil
+}
+
+// RestoreCheckpointCommand returns the GDB command switching to a checkpoint
+func RestoreCheckpointCommand(id int) string {
+	return "restart " + strconv.Itoa(id)
+}
+
+// DeleteCheckpointCommand returns the GDB command deleting a checkpoint
+func DeleteCheckpointCommand(id int) string {
+	return "delete checkpoint " + strconv.Itoa(id)
+}
+
+// checkpointError turns GDB's refusal of a checkpoint command into an error
+func checkpointError(output string) error {
+	if match := checkpointNotFound.FindString(output); match != "" {
+		return fmt.Errorf("%w: %s", appErrors.ErrNotFound, strings.TrimSpace(match))
+	}
+	if match := checkpointRefused.FindString(output); match != "" {
+		return fmt.Errorf("%w: %s", appErrors.ErrBadRequest, strings.TrimSpace(match))
+	}
+	return fmt.Errorf("%w: %s", appErrors.ErrGDBCommandFailed, lastLines(strings.TrimSpace(output), 3))
+}
+
+// killCheckpoints has GDB kill the program and its checkpoints before it is stopped, as
+// they are processes of their own that would outlive it. The caller holds processLock.
+func (g *GDBService) killCheckpoints() {
+	killed := g.checkpoints.awaitKill()
+	if killed == nil {
+		return
+	}
+	// GDB answers its own confirmation when its input is not a terminal
+	g.writeCommand("kill")
+	select {
+	case <-killed:
+	case <-time.After(checkpointKillWait):
+	}
+}
+
+// killOrphanedCheckpoints kills the checkpoints of a GDB that exited on its own. Only
+// the local backend's are processes of the server; a container's go with it.
+func (g *GDBService) killOrphanedCheckpoints() {
+	pids := g.checkpoints.Reset()
+	if g.config.Backend != "" && g.config.Backend != config.BackendLocal {
+		return
+	}
+	for _, pid := range pids {
+		if process, err := os.FindProcess(pid); err == nil {
+			process.Kill()
+		}
+	}
+}
+
+// ParseCheckpoints reads the checkpoints in `info checkpoints` output
+func ParseCheckpoints(output string) []Checkpoint {
+	var checkpoints []Checkpoint
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "(gdb)"))
+		match := checkpointLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(match[2])
+		pid, _ := strconv.Atoi(match[3] + match[4])
+		checkpoints = append(checkpoints, Checkpoint{
+			ID:       id,
+			PID:      pid,
+			Main:     match[5] != "",
+			Current:  match[1] == "*",
+			Location: strings.TrimSpace(match[6]),
+		})
+	}
+	return checkpoints
+}
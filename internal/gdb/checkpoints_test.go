@@ -0,0 +1,62 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// TestParseCheckpoints tests parsing of `info checkpoints` output
+func TestParseCheckpoints(t *testing.T) {
+	output := `(gdb)   0 process 12345 (main process) at 0x555555555139, file crash.c, line 5
+* 1 process 12346 at 0x555555555141, file crash.c, line 6
+  2 Thread 0x7ffff7d89740 (LWP 12347) at 0x555555555150, file crash.c, line 8
+`
+	checkpoints := ParseCheckpoints(output)
+	require.Len(t, checkpoints, 3)
+	assert.Equal(t, Checkpoint{ID: 0, PID: 12345, Main: true, Location: "0x555555555139, file crash.c, line 5"}, checkpoints[0])
+	assert.Equal(t, Checkpoint{ID: 1, PID: 12346, Current: true, Location: "0x555555555141, file crash.c, line 6"}, checkpoints[1])
+	assert.Equal(t, 12347, checkpoints[2].PID)
+
+	assert.Empty(t, ParseCheckpoints("No checkpoints.\n"))
+}
+
+// TestCheckpointStore tests following checkpoints through GDB's output
+func TestCheckpointStore(t *testing.T) {
+	store := NewCheckpointStore()
+	assert.Nil(t, store.awaitKill(), "nothing to kill without checkpoints")
+
+	store.Output("(gdb) checkpoint 1: fork returned pid 12346.")
+	store.Output("checkpoint 2: fork returned pid 12347.")
+	store.SetNote(1, "before parse")
+
+	checkpoints := []Checkpoint{{ID: 0}, {ID: 1}, {ID: 2}}
+	store.annotate(checkpoints)
+	assert.Nil(t, checkpoints[0].Created)
+	assert.Equal(t, "before parse", checkpoints[1].Note)
+	require.NotNil(t, checkpoints[2].Created)
+
+	store.Delete(2)
+	killed := store.awaitKill()
+	require.NotNil(t, killed)
+	store.Output("[Inferior 1 (process 12345) killed]")
+	select {
+	case <-killed:
+	default:
+		t.Fatal("the kill was not reported")
+	}
+	assert.Empty(t, store.Reset())
+
+	store.Output("checkpoint 3: fork returned pid 12350.")
+	assert.Equal(t, []int{12350}, store.Reset())
+}
+
+// TestCheckpointError tests turning GDB's refusals of checkpoint commands into errors
+func TestCheckpointError(t *testing.T) {
+	assert.ErrorIs(t, checkpointError("Not found: checkpoint id 7\n"), appErrors.ErrNotFound)
+	assert.ErrorIs(t, checkpointError("Please switch to another checkpoint before deleting the current one\n"), appErrors.ErrBadRequest)
+	assert.ErrorIs(t, checkpointError("The program is not being run.\n"), appErrors.ErrBadRequest)
+	assert.ErrorIs(t, checkpointError("something else\n"), appErrors.ErrGDBCommandFailed)
+}
@@ -0,0 +1,47 @@
+package gdb
+
+import (
+	"strings"
+)
+
+// completeTimeoutSeconds bounds how long CompleteCommand waits for GDB's completions
+const completeTimeoutSeconds = 2
+
+// maxCompletions bounds the completions returned for one request
+const maxCompletions = 200
+
+// CompleteCommand returns GDB's completions of a partial command line, using GDB's
+// "complete" command since GDB reads commands from a pipe rather than through readline
+func (g *GDBService) CompleteCommand(text string) ([]string, error) {
+	if err := g.requireGDB("command completion"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("complete "+text, completeTimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return parseCompletions(text, output), nil
+}
+
+// parseCompletions picks the completions of text out of "complete" output, which lists
+// one whole command line per line, possibly after a "(gdb) " prompt left over from the
+// previous command
+func parseCompletions(text, output string) []string {
+	completions := []string{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		for strings.HasPrefix(line, "(gdb) ") {
+			line = strings.TrimPrefix(line, "(gdb) ")
+		}
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "*** ") || !strings.HasPrefix(line, text) || seen[line] {
+			continue
+		}
+		seen[line] = true
+		completions = append(completions, line)
+		if len(completions) == maxCompletions {
+			break
+		}
+	}
+	return completions
+}
@@ -0,0 +1,122 @@
+package gdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// driver starts one kind of debugger. The service talks to every debugger through its
+// standard input and output; the driver knows its command line and which of GDB's
+// commands the service may rely on.
+type driver interface {
+	// Name is the debugger's display name, e.g. in the assistant's prompt
+	Name() string
+	// Args returns the debugger's arguments to load filePath with sources searched for
+	// in sourceDirs, running the program on the terminal tty if it is not empty
+	Args(filePath string, sourceDirs []string, tty string) []string
+	// Env returns the environment variables the debugger is started with, besides the
+	// execution's own
+	Env() []string
+	// SupportsTerminal reports whether the program can run on a terminal of its own
+	SupportsTerminal() bool
+	// SpeaksGDB reports whether the debugger understands GDB's commands, which command
+	// completion, address annotation and breakpoint restoring send
+	SpeaksGDB() bool
+}
+
+// newDriver returns the driver for gdb.debugger, which config.GDBConfig.Validate checks
+func newDriver(cfg *config.GDBConfig) driver {
+	if cfg.Debugger == config.DebuggerCDB {
+		return cdbDriver{}
+	}
+	return gdbDriver{debuginfod: cfg.Debuginfod, prettyPrinters: cfg.PrettyPrinters}
+}
+
+// gdbDriver starts GDB, including MinGW's and Cygwin's GDB on Windows
+type gdbDriver struct {
+	debuginfod     config.DebuginfodConfig
+	prettyPrinters config.PrettyPrintersConfig
+}
+
+func (gdbDriver) Name() string { return "GDB" }
+
+func (d gdbDriver) Args(filePath string, sourceDirs []string, tty string) []string {
+	args := make([]string, 0, 2*len(sourceDirs)+4*len(d.prettyPrinters.Directories)+4)
+	if d.debuginfod.Enabled {
+		// Before the program is loaded, or GDB asks whether to use debuginfod
+		args = append(args, "-iex", "set debuginfod enabled on")
+	}
+	for _, dir := range d.prettyPrinters.Directories {
+		// Before the program is loaded, so the scripts it names, e.g. in its
+		// .debug_gdb_scripts section, are found and allowed to run
+		args = append(args, "-iex", "add-auto-load-safe-path "+dir, "-iex", "directory "+dir)
+	}
+	if tty != "" {
+		args = append(args, "--tty="+tty)
+	}
+	for _, dir := range sourceDirs {
+		args = append(args, "-d", dir)
+	}
+	return append(args, filePath)
+}
+
+// Env points GDB's debuginfod client at the configured servers
+func (d gdbDriver) Env() []string {
+	if !d.debuginfod.Enabled {
+		return nil
+	}
+	var env []string
+	if len(d.debuginfod.URLs) > 0 {
+		env = append(env, "DEBUGINFOD_URLS="+strings.Join(d.debuginfod.URLs, " "))
+	}
+	if d.debuginfod.CachePath != "" {
+		env = append(env, "DEBUGINFOD_CACHE_PATH="+d.debuginfod.CachePath)
+	}
+	if d.debuginfod.Timeout > 0 {
+		env = append(env, fmt.Sprintf("DEBUGINFOD_TIMEOUT=%d", int(d.debuginfod.Timeout.Seconds())))
+	}
+	return env
+}
+
+func (gdbDriver) SupportsTerminal() bool { return ptySupported }
+
+func (gdbDriver) SpeaksGDB() bool { return true }
+
+// cdbDriver starts CDB, the console debugger of the Debugging Tools for Windows, which
+// shares its command language with WinDbg. CDB starts the program right away and stops
+// at its initial breakpoint, where "g" continues it.
+type cdbDriver struct{}
+
+func (cdbDriver) Name() string { return "CDB" }
+
+func (cdbDriver) Args(filePath string, sourceDirs []string, tty string) []string {
+	// Show source lines in stack traces and when stepping
+	args := []string{"-lines"}
+	if len(sourceDirs) > 0 {
+		args = append(args, "-srcpath", strings.Join(sourceDirs, ";"))
+	}
+	return append(args, filePath)
+}
+
+func (cdbDriver) Env() []string { return nil }
+
+func (cdbDriver) SupportsTerminal() bool { return false }
+
+func (cdbDriver) SpeaksGDB() bool { return false }
+
+// Debugger returns the name of the debugger the service drives, e.g. "GDB" or "CDB"
+func (g *GDBService) Debugger() string {
+	return g.driver.Name()
+}
+
+// requireGDB fails with errors.ErrUnsupported when the debugger does not understand
+// GDB's commands, naming what needed them
+func (g *GDBService) requireGDB(feature string) error {
+	if g.driver.SpeaksGDB() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s needs GDB, but the server drives %s", appErrors.ErrUnsupported, feature, g.driver.Name())
+}
@@ -0,0 +1,49 @@
+package gdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestDriverArgs(t *testing.T) {
+	gdb := newDriver(&config.GDBConfig{Debugger: config.DebuggerGDB})
+	assert.Equal(t, "GDB", gdb.Name())
+	assert.Equal(t, []string{"--tty=/dev/pts/3", "-d", "src", "-d", "lib", "crash"},
+		gdb.Args("crash", []string{"src", "lib"}, "/dev/pts/3"))
+	assert.Equal(t, []string{"crash"}, gdb.Args("crash", nil, ""))
+	assert.Empty(t, gdb.Env())
+
+	gdb = newDriver(&config.GDBConfig{Debuginfod: config.DebuginfodConfig{
+		Enabled: true,
+		URLs:    []string{"https://debuginfod.elfutils.org/", "https://debuginfod.ubuntu.com/"},
+		Timeout: 15 * time.Second,
+	}})
+	assert.Equal(t, []string{"-iex", "set debuginfod enabled on", "crash"}, gdb.Args("crash", nil, ""))
+	assert.Equal(t, []string{"DEBUGINFOD_URLS=https://debuginfod.elfutils.org/ https://debuginfod.ubuntu.com/", "DEBUGINFOD_TIMEOUT=15"}, gdb.Env())
+
+	gdb = newDriver(&config.GDBConfig{PrettyPrinters: config.PrettyPrintersConfig{Directories: []string{"/usr/local/go/src/runtime"}}})
+	assert.Equal(t, []string{"-iex", "add-auto-load-safe-path /usr/local/go/src/runtime", "-iex", "directory /usr/local/go/src/runtime", "crash"},
+		gdb.Args("crash", nil, ""))
+
+	cdb := newDriver(&config.GDBConfig{Debugger: config.DebuggerCDB})
+	assert.Equal(t, "CDB", cdb.Name())
+	assert.Equal(t, []string{"-lines", "-srcpath", `C:\src;C:\lib`, "crash.exe"},
+		cdb.Args("crash.exe", []string{`C:\src`, `C:\lib`}, ""))
+	assert.False(t, cdb.SupportsTerminal())
+}
+
+func TestCDBRejectsGDBCommands(t *testing.T) {
+	service := NewGDBService(&config.Config{GDB: config.GDBConfig{Debugger: config.DebuggerCDB}})
+	assert.Equal(t, "CDB", service.Debugger())
+	_, err := service.CompleteCommand("bre")
+	assert.ErrorIs(t, err, appErrors.ErrUnsupported)
+
+	assert.Error(t, config.GDBConfig{Debugger: "lldb"}.Validate())
+	assert.NoError(t, config.GDBConfig{}.Validate())
+	assert.Error(t, config.GDBConfig{Backend: config.BackendDocker, Docker: config.DockerConfig{Image: "gdb", Network: "none"},
+		Debuginfod: config.DebuginfodConfig{Enabled: true}}.Validate(), "debuginfod needs the network")
+}
@@ -0,0 +1,201 @@
+package gdb
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// hostArchitectures names the server's architectures as binary metadata does
+var hostArchitectures = map[string]string{
+	"amd64":   "x86-64",
+	"386":     "x86",
+	"arm64":   "aarch64",
+	"arm":     "arm",
+	"riscv64": "riscv",
+	"ppc64":   "powerpc64",
+	"ppc64le": "powerpc64",
+	"s390x":   "s390",
+}
+
+// native reports whether executables of an architecture run on the server as they are
+func native(arch string) bool {
+	host := hostArchitectures[runtime.GOARCH]
+	return arch == host || host == "x86-64" && arch == "x86"
+}
+
+// emulator runs a program built for another architecture than the server's under
+// qemu-user, which starts it stopped at its entry point and lets GDB debug it through its
+// GDB stub on a local port
+type emulator struct {
+	arch      string
+	cfg       config.EmulatorConfig
+	port      int
+	cmd       *exec.Cmd
+	closeOnce sync.Once
+}
+
+// emulatorFor returns the emulator for the ELF executable at filePath, or nil if it runs
+// on the server as it is or gdb.emulation is disabled
+func (g *GDBService) emulatorFor(filePath string) (*emulator, error) {
+	emulation := g.config.Emulation
+	if !emulation.Enabled {
+		return nil, nil
+	}
+	f, err := binfile.Open(filePath)
+	if err != nil {
+		// GDB reports what it cannot load
+		return nil, nil
+	}
+	defer f.Close()
+	metadata := f.Metadata()
+	if f.Format != binfile.FormatELF || native(metadata.Arch) {
+		return nil, nil
+	}
+	cfg, ok := emulation.Architectures[metadata.Arch]
+	if !ok || cfg.QEMU == "" {
+		return nil, fmt.Errorf("%w: %s executables do not run on this %s server, and gdb.emulation.architectures has no emulator for them",
+			appErrors.ErrUnsupported, metadata.Arch, hostArchitectures[runtime.GOARCH])
+	}
+	return &emulator{arch: metadata.Arch, cfg: cfg}, nil
+}
+
+// qemuArgs returns qemu's arguments running the executable at filePath with the program's
+// options, its stub listening on port
+func (e *emulator) qemuArgs(filePath string, program ProgramOptions, port int) []string {
+	args := []string{"-g", strconv.Itoa(port)}
+	if e.cfg.Sysroot != "" {
+		args = append(args, "-L", e.cfg.Sysroot)
+	}
+	names := make([]string, 0, len(program.Env))
+	for name := range program.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-E", name+"="+program.Env[name])
+	}
+	args = append(append(args, e.cfg.Args...), filePath)
+	return append(args, program.Args...)
+}
+
+// gdbArgs returns the arguments connecting GDB to the stub, waiting up to timeout for it
+func (e *emulator) gdbArgs(timeout int) []string {
+	var args []string
+	if e.cfg.Sysroot != "" {
+		// So GDB reads the symbols of the libraries qemu loads from the sysroot
+		args = append(args, "-ex", "set sysroot "+e.cfg.Sysroot)
+	}
+	if timeout > 0 {
+		args = append(args, "-ex", fmt.Sprintf("set tcp connect-timeout %d", timeout))
+	}
+	return append(args, "-ex", fmt.Sprintf("target remote localhost:%d", e.port))
+}
+
+// start starts the program under qemu. Its standard streams are the terminal, if there is
+// one; otherwise its output is sent to emit. An input file replaces the terminal's input.
+func (e *emulator) start(filePath string, program ProgramOptions, terminal *inferiorTerminal, emit func(string)) error {
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	cmd := exec.Command(e.cfg.QEMU, e.qemuArgs(filePath, program, port)...)
+	cmd.Dir = program.WorkingDir
+	startProcessGroup(cmd)
+
+	var output *os.File
+	if terminal != nil {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = terminal.slave, terminal.slave, terminal.slave
+	} else {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to create the program's output pipe: %w", err)
+		}
+		defer writer.Close()
+		cmd.Stdout, cmd.Stderr = writer, writer
+		output = reader
+	}
+	if program.StdinFile != "" {
+		input, err := os.Open(program.StdinFile)
+		if err != nil {
+			if output != nil {
+				output.Close()
+			}
+			return fmt.Errorf("failed to open the program's input: %w", err)
+		}
+		defer input.Close()
+		cmd.Stdin = input
+	}
+
+	if err := cmd.Start(); err != nil {
+		if output != nil {
+			output.Close()
+		}
+		return fmt.Errorf("failed to start %s: %w", e.cfg.QEMU, err)
+	}
+	e.port, e.cmd = port, cmd
+	go cmd.Wait()
+	if output != nil {
+		go pumpOutput(output, emit)
+	}
+	return nil
+}
+
+// Close stops qemu and the program; it may be called more than once
+func (e *emulator) Close() {
+	if e.cmd == nil || e.cmd.Process == nil {
+		return
+	}
+	e.closeOnce.Do(func() { killProcessGroup(e.cmd.Process) })
+}
+
+// emulatedExecution is an execution whose program runs under an emulator, which is
+// stopped with the debugger
+type emulatedExecution struct {
+	execution
+	emulator *emulator
+}
+
+func (e emulatedExecution) Close() {
+	e.emulator.Close()
+	e.execution.Close()
+}
+
+// freePort returns a local TCP port nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a port for the emulator: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// pumpOutput sends what is read from r to emit in chunks as it arrives, until r ends
+func pumpOutput(r io.ReadCloser, emit func(string)) {
+	defer r.Close()
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			emit(string(buf[:n]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
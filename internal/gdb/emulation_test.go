@@ -0,0 +1,152 @@
+package gdb
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// foreignELF writes the header of a 64-bit ELF executable for an architecture the server
+// is not, and returns its path and the architecture
+func foreignELF(t *testing.T) (string, string) {
+	machine, arch := elf.EM_AARCH64, "aarch64"
+	if native(arch) {
+		machine, arch = elf.EM_RISCV, "riscv"
+	}
+	header := make([]byte, 64)
+	copy(header, elf.ELFMAG)
+	header[elf.EI_CLASS], header[elf.EI_DATA], header[elf.EI_VERSION] = byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)
+	binary.LittleEndian.PutUint16(header[16:], uint16(elf.ET_EXEC))
+	binary.LittleEndian.PutUint16(header[18:], uint16(machine))
+	binary.LittleEndian.PutUint32(header[20:], uint32(elf.EV_CURRENT))
+	binary.LittleEndian.PutUint16(header[52:], 64) // Header size
+	path := filepath.Join(t.TempDir(), "firmware")
+	require.NoError(t, os.WriteFile(path, header, 0755))
+	return path, arch
+}
+
+func TestNative(t *testing.T) {
+	host, ok := hostArchitectures[runtime.GOARCH]
+	if !ok {
+		t.Skip("no name for " + runtime.GOARCH)
+	}
+	assert.True(t, native(host))
+	assert.False(t, native("mips"))
+	if host == "x86-64" {
+		assert.True(t, native("x86"), "32-bit x86 programs run on x86-64")
+	}
+}
+
+func TestEmulatorFor(t *testing.T) {
+	path, arch := foreignELF(t)
+	service := NewGDBService(&config.Config{})
+	emulator, err := service.emulatorFor(path)
+	require.NoError(t, err)
+	assert.Nil(t, emulator, "emulation is disabled")
+
+	service.config.Emulation.Enabled = true
+	_, err = service.emulatorFor(path)
+	assert.ErrorIs(t, err, appErrors.ErrUnsupported)
+
+	service.config.Emulation.Architectures = map[string]config.EmulatorConfig{arch: {QEMU: "qemu-" + arch}}
+	emulator, err = service.emulatorFor(path)
+	require.NoError(t, err)
+	require.NotNil(t, emulator)
+	assert.Equal(t, arch, emulator.arch)
+
+	// Files GDB loads natively, or not at all, are left to GDB
+	notes := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("not an executable"), 0644))
+	emulator, err = service.emulatorFor(notes)
+	require.NoError(t, err)
+	assert.Nil(t, emulator)
+}
+
+func TestEmulatorArgs(t *testing.T) {
+	e := &emulator{cfg: config.EmulatorConfig{QEMU: "qemu-aarch64", Sysroot: "/usr/aarch64-linux-gnu", Args: []string{"-cpu", "max"}}, port: 1234}
+	program := ProgramOptions{Args: []string{"--verbose", "input file"}, Env: map[string]string{"B": "2", "A": "1"}}
+	assert.Equal(t, []string{
+		"-g", "1234", "-L", "/usr/aarch64-linux-gnu", "-E", "A=1", "-E", "B=2", "-cpu", "max",
+		"/uploads/firmware", "--verbose", "input file",
+	}, e.qemuArgs("/uploads/firmware", program, 1234))
+	assert.Equal(t, []string{
+		"-ex", "set sysroot /usr/aarch64-linux-gnu",
+		"-ex", "set tcp connect-timeout 15",
+		"-ex", "target remote localhost:1234",
+	}, e.gdbArgs(15))
+}
+
+// fakeQEMU and fakeMultiarchGDB stand in for qemu-user and gdb-multiarch: they log their
+// arguments, then qemu waits to be stopped and GDB echoes its commands
+const (
+	fakeQEMU = `#!/bin/sh
+printf '%s\n' "$*" > "$(dirname "$0")/qemu.log"
+exec sleep 60
+`
+	fakeMultiarchGDB = `#!/bin/sh
+printf '%s\n' "$*" > "$(dirname "$0")/gdb.log"
+while read line; do echo "$line"; done
+`
+)
+
+func TestEmulatedExecution(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fakes are shell scripts")
+	}
+	path, arch := foreignELF(t)
+	dir := t.TempDir()
+	qemu, gdb := filepath.Join(dir, "qemu"), filepath.Join(dir, "gdb-multiarch")
+	require.NoError(t, os.WriteFile(qemu, []byte(fakeQEMU), 0755))
+	require.NoError(t, os.WriteFile(gdb, []byte(fakeMultiarchGDB), 0755))
+
+	cfg := &config.Config{GDB: config.GDBConfig{
+		Path: "gdb",
+		Emulation: config.EmulationConfig{
+			Enabled:        true,
+			GDBPath:        gdb,
+			ConnectTimeout: 5 * time.Second,
+			Architectures:  map[string]config.EmulatorConfig{arch: {QEMU: qemu}},
+		},
+	}}
+	service := NewGDBService(cfg)
+	output := make(chan string, 100)
+	go func() {
+		for o := range service.GetOutputChannel() {
+			output <- o.Clean
+		}
+	}()
+
+	require.NoError(t, service.StartProgram(path, ProgramOptions{Args: []string{"fast"}}))
+	defer service.StopGDB()
+	read := func(name string) string {
+		var data []byte
+		require.Eventually(t, func() bool {
+			data, _ = os.ReadFile(filepath.Join(dir, name))
+			return len(data) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		return strings.TrimSpace(string(data))
+	}
+
+	qemuArgs := strings.Fields(read("qemu.log"))
+	require.Len(t, qemuArgs, 4)
+	assert.Equal(t, []string{"-g", path, "fast"}, []string{qemuArgs[0], qemuArgs[2], qemuArgs[3]})
+	assert.Equal(t, "-ex set tcp connect-timeout 5 -ex target remote localhost:"+qemuArgs[1]+" "+path, read("gdb.log"))
+
+	// The program's options went to qemu rather than GDB, which is told how to run it
+	select {
+	case line := <-output:
+		assert.Contains(t, line, "echo [The "+arch+" program runs under")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no output from GDB")
+	}
+}
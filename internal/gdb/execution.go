@@ -0,0 +1,190 @@
+package gdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// execution is where one debugger process runs: on the server itself, or in a container
+// of its own for isolation from the server and other sessions
+type execution interface {
+	// Files returns the paths the debugger sees filePath and sourceDirs, paths on the
+	// server, at
+	Files(filePath string, sourceDirs []string) (string, []string)
+	// Command returns the command running the debugger at path with args and the
+	// environment variables env. Its standard input and output are the debugger's.
+	Command(path string, args, env []string) (*exec.Cmd, error)
+	// SupportsTerminal reports whether the program can be given a terminal on the server
+	SupportsTerminal() bool
+	// Close releases what the execution holds once its debugger has exited or been
+	// stopped; it may be called more than once
+	Close()
+}
+
+// newExecution returns an execution for one debugger process on gdb.backend
+func newExecution(cfg *config.GDBConfig) (execution, error) {
+	if cfg.Backend != config.BackendDocker && cfg.Backend != config.BackendKubernetes {
+		return localExecution{}, nil
+	}
+	name, err := containerName()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Backend == config.BackendKubernetes {
+		return &kubernetesExecution{cfg: cfg.Kubernetes, pod: name}, nil
+	}
+	return &dockerExecution{cfg: cfg.Docker, name: name}, nil
+}
+
+// localExecution runs the debugger on the server
+type localExecution struct{}
+
+func (localExecution) Files(filePath string, sourceDirs []string) (string, []string) {
+	return filePath, sourceDirs
+}
+
+func (localExecution) Command(path string, args, env []string) (*exec.Cmd, error) {
+	cmd := exec.Command(path, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	startProcessGroup(cmd)
+	return cmd, nil
+}
+
+func (localExecution) SupportsTerminal() bool { return true }
+
+func (localExecution) Close() {}
+
+// Container paths the session's files are mounted at, read-only
+const (
+	containerWorkDir    = "/work"
+	containerSourcesDir = "/src"
+)
+
+// dockerExecution runs the debugger and the program in a container of their own, started
+// from gdb.docker.image with its CPU, memory and process limits and no network. Only the
+// session's executable and sources are mounted, read-only. The debugger is started in it
+// with docker exec and talked to over the exec's streams; the container is removed when
+// the debugger exits or is stopped.
+type dockerExecution struct {
+	cfg       config.DockerConfig
+	name      string
+	mounts    []string // Volume options for docker run
+	started   bool
+	closeOnce sync.Once
+}
+
+// containerName returns a unique name for a session's container or pod
+func containerName() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate container name: %w", err)
+	}
+	return "gogdbllm-" + hex.EncodeToString(b), nil
+}
+
+// containerFiles returns the paths the executable and source directories of a session
+// have in a container: /work/<executable> and /src/0, /src/1...
+func containerFiles(filePath string, sourceDirs []string) (string, []string) {
+	dirs := make([]string, len(sourceDirs))
+	for i := range sourceDirs {
+		dirs[i] = path.Join(containerSourcesDir, strconv.Itoa(i))
+	}
+	return path.Join(containerWorkDir, filepath.Base(filePath)), dirs
+}
+
+func (d *dockerExecution) Files(filePath string, sourceDirs []string) (string, []string) {
+	file, dirs := containerFiles(filePath, sourceDirs)
+	d.mounts = append(d.mounts, mountOption(filePath, file))
+	for i, dir := range sourceDirs {
+		d.mounts = append(d.mounts, mountOption(dir, dirs[i]))
+	}
+	return file, dirs
+}
+
+// mountOption returns the docker run option mounting hostPath at containerPath, read-only
+func mountOption(hostPath, containerPath string) string {
+	if abs, err := filepath.Abs(hostPath); err == nil {
+		hostPath = abs
+	}
+	return fmt.Sprintf("--volume=%s:%s:ro", hostPath, containerPath)
+}
+
+func (d *dockerExecution) Command(debuggerPath string, args, env []string) (*exec.Cmd, error) {
+	if err := d.startContainer(); err != nil {
+		return nil, err
+	}
+	execArgs := []string{"exec", "--interactive", "--env", "HOME=/tmp"}
+	for _, variable := range env {
+		execArgs = append(execArgs, "--env", variable)
+	}
+	execArgs = append(append(execArgs, d.name, debuggerPath), args...)
+	cmd := exec.Command(d.cfg.Binary, execArgs...)
+	startProcessGroup(cmd)
+	return cmd, nil
+}
+
+// runArgs returns the docker run arguments starting the container. It idles until the
+// debugger is started in it, and is removed once stopped.
+func (d *dockerExecution) runArgs() []string {
+	args := []string{"run", "--detach", "--rm", "--init",
+		"--name", d.name,
+		"--label", "gogdbllm=session",
+		"--network", d.cfg.Network,
+		"--read-only", "--tmpfs", "/tmp:exec",
+		"--cap-drop", "ALL", "--cap-add", "SYS_PTRACE",
+		"--security-opt", "no-new-privileges",
+	}
+	if d.cfg.CPUs != "" {
+		args = append(args, "--cpus", d.cfg.CPUs)
+	}
+	if d.cfg.Memory != "" {
+		args = append(args, "--memory", d.cfg.Memory)
+	}
+	if d.cfg.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(d.cfg.PidsLimit))
+	}
+	args = append(args, d.mounts...)
+	return append(args, d.cfg.Image, "sleep", "infinity")
+}
+
+// startContainer starts the container, waiting up to gdb.docker.start_timeout
+func (d *dockerExecution) startContainer() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.StartTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, d.cfg.Binary, d.runArgs()...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to start container from %s: %w: %s", d.cfg.Image, err, strings.TrimSpace(string(out)))
+	}
+	d.started = true
+	return nil
+}
+
+func (d *dockerExecution) SupportsTerminal() bool { return false }
+
+func (d *dockerExecution) Close() {
+	if !d.started {
+		return
+	}
+	d.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, d.cfg.Binary, "rm", "--force", d.name).CombinedOutput(); err != nil {
+			log.Printf("Error removing container %s: %v: %s", d.name, err, strings.TrimSpace(string(out)))
+		}
+	})
+}
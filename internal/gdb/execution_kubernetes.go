@@ -0,0 +1,236 @@
+package gdb
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// kubernetesExecution runs the debugger and the program in a pod of their own, created
+// from gdb.kubernetes.image with its resource limits. The session's executable and
+// sources are copied into the pod, since it may run on any node, and the debugger is
+// started with kubectl exec, whose streams the API server relays to the pod. The pod
+// lives as long as the debugger: it is deleted when the debugger exits or is stopped,
+// and a pod that goes away ends the debugger.
+type kubernetesExecution struct {
+	cfg        config.KubernetesConfig
+	pod        string
+	filePath   string // On the server, copied to the pod
+	sourceDirs []string
+	created    bool
+	closeOnce  sync.Once
+}
+
+func (k *kubernetesExecution) Files(filePath string, sourceDirs []string) (string, []string) {
+	k.filePath, k.sourceDirs = filePath, sourceDirs
+	return containerFiles(filePath, sourceDirs)
+}
+
+func (k *kubernetesExecution) Command(debuggerPath string, args, env []string) (*exec.Cmd, error) {
+	if err := k.createPod(); err != nil {
+		return nil, err
+	}
+	if err := k.copyFiles(); err != nil {
+		return nil, err
+	}
+	execArgs := k.kubectlArgs("exec", "--stdin", k.pod, "--")
+	if len(env) > 0 {
+		// kubectl exec cannot set variables, so env sets them in the pod
+		execArgs = append(append(execArgs, "env"), env...)
+	}
+	execArgs = append(append(execArgs, debuggerPath), args...)
+	cmd := exec.Command(k.cfg.Binary, execArgs...)
+	startProcessGroup(cmd)
+	return cmd, nil
+}
+
+func (k *kubernetesExecution) SupportsTerminal() bool { return false }
+
+func (k *kubernetesExecution) Close() {
+	if !k.created {
+		return
+	}
+	k.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		args := k.kubectlArgs("delete", "pod", k.pod, "--ignore-not-found", "--wait=false")
+		if out, err := exec.CommandContext(ctx, k.cfg.Binary, args...).CombinedOutput(); err != nil {
+			log.Printf("Error deleting pod %s: %v: %s", k.pod, err, strings.TrimSpace(string(out)))
+		}
+	})
+}
+
+// kubectlArgs returns kubectl's arguments for a command in the configured context and
+// namespace
+func (k *kubernetesExecution) kubectlArgs(args ...string) []string {
+	var global []string
+	if k.cfg.Context != "" {
+		global = append(global, "--context", k.cfg.Context)
+	}
+	if k.cfg.Namespace != "" {
+		global = append(global, "--namespace", k.cfg.Namespace)
+	}
+	return append(global, args...)
+}
+
+// kubectl runs a kubectl command with input on its standard input, failing after timeout
+func (k *kubernetesExecution) kubectl(timeout time.Duration, input io.Reader, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, k.cfg.Binary, k.kubectlArgs(args...)...)
+	cmd.Stdin = input
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("kubectl %s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// createPod creates the pod and waits until it is running
+func (k *kubernetesExecution) createPod() error {
+	manifest, err := json.Marshal(k.manifest())
+	if err != nil {
+		return fmt.Errorf("failed to build pod manifest: %w", err)
+	}
+	if err := k.kubectl(k.cfg.StartTimeout, bytes.NewReader(manifest), "create", "--filename", "-"); err != nil {
+		return err
+	}
+	k.created = true
+	return k.kubectl(k.cfg.StartTimeout, nil, "wait", "--for=condition=Ready", "pod/"+k.pod, "--timeout="+k.cfg.StartTimeout.String())
+}
+
+// manifest returns the pod's manifest. The container idles until the debugger is
+// started in it; /work, /src and /tmp are its only writable directories.
+func (k *kubernetesExecution) manifest() map[string]interface{} {
+	resources := map[string]string{}
+	if k.cfg.CPU != "" {
+		resources["cpu"] = k.cfg.CPU
+	}
+	if k.cfg.Memory != "" {
+		resources["memory"] = k.cfg.Memory
+	}
+	volumes := []map[string]interface{}{}
+	mounts := []map[string]interface{}{}
+	for _, volume := range []struct{ name, dir string }{{"work", containerWorkDir}, {"src", containerSourcesDir}, {"tmp", "/tmp"}} {
+		name, dir := volume.name, volume.dir
+		volumes = append(volumes, map[string]interface{}{"name": name, "emptyDir": map[string]interface{}{}})
+		mounts = append(mounts, map[string]interface{}{"name": name, "mountPath": dir})
+	}
+	spec := map[string]interface{}{
+		"restartPolicy":                "Never",
+		"automountServiceAccountToken": false,
+		"enableServiceLinks":           false,
+		"containers": []map[string]interface{}{{
+			"name":         "debug",
+			"image":        k.cfg.Image,
+			"command":      []string{"sleep", "infinity"},
+			"env":          []map[string]string{{"name": "HOME", "value": "/tmp"}},
+			"resources":    map[string]interface{}{"requests": resources, "limits": resources},
+			"volumeMounts": mounts,
+			"securityContext": map[string]interface{}{
+				"allowPrivilegeEscalation": false,
+				"readOnlyRootFilesystem":   true,
+				"capabilities":             map[string]interface{}{"drop": []string{"ALL"}, "add": []string{"SYS_PTRACE"}},
+			},
+		}},
+		"volumes": volumes,
+	}
+	if len(k.cfg.NodeSelector) > 0 {
+		spec["nodeSelector"] = k.cfg.NodeSelector
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":   k.pod,
+			"labels": map[string]string{"app.kubernetes.io/name": "gogdbllm-session"},
+		},
+		"spec": spec,
+	}
+}
+
+// copyFiles copies the executable and sources into the pod as a tar stream, which the
+// pod's tar unpacks. The archive is packed while kubectl sends it, so the files are never
+// held in memory, and the copy has its own timeout, as large files take a while.
+func (k *kubernetesExecution) copyFiles() error {
+	archive, w := io.Pipe()
+	packed := make(chan error, 1)
+	go func() {
+		err := writeSessionArchive(w, k.filePath, k.sourceDirs)
+		w.CloseWithError(err)
+		packed <- err
+	}()
+	err := k.kubectl(k.cfg.CopyTimeout, archive, "exec", "--stdin", k.pod, "--", "tar", "-x", "-f", "-", "-C", "/")
+	// Stops the packing if kubectl exited before reading the whole archive
+	archive.Close()
+	if packErr := <-packed; packErr != nil && !errors.Is(packErr, io.ErrClosedPipe) {
+		return fmt.Errorf("failed to pack the session's files: %w", packErr)
+	}
+	return err
+}
+
+// writeSessionArchive writes a tar archive placing the executable and source directories
+// at their container paths
+func writeSessionArchive(w io.Writer, filePath string, sourceDirs []string) error {
+	file, dirs := containerFiles(filePath, sourceDirs)
+	tw := tar.NewWriter(w)
+	if err := addArchiveFile(tw, filePath, strings.TrimPrefix(file, "/"), 0755); err != nil {
+		return err
+	}
+	for i, dir := range sourceDirs {
+		root := strings.TrimPrefix(dirs[i], "/")
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			name := path.Join(root, filepath.ToSlash(rel))
+			switch {
+			case d.IsDir():
+				return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755})
+			case d.Type().IsRegular():
+				return addArchiveFile(tw, p, name, 0644)
+			}
+			return nil // Links and special files are left out
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// addArchiveFile adds the file at p to the archive as name
+func addArchiveFile(tw *tar.Writer, p, name string, mode int64) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: mode, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
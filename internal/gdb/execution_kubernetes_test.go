@@ -0,0 +1,136 @@
+package gdb
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// fakeKubectl is a shell script standing in for kubectl: it logs its arguments, keeps
+// the manifest and the archive copied into the pod, and runs exec'd debuggers on the host
+const fakeKubectl = `#!/bin/sh
+dir="$(dirname "$0")"
+echo "$@" >> "$dir/kubectl.log"
+shift 2 # --namespace debug
+case "$1" in
+create) cat > "$dir/pod.json" ;;
+exec)
+	shift 4 # exec --stdin <pod> --
+	if [ "$1" = tar ]; then cat > "$dir/files.tar"; else exec "$@"; fi ;;
+esac
+`
+
+func TestKubernetesExecution(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := filepath.Join(dir, "kubectl")
+	gdb := filepath.Join(dir, "gdb")
+	require.NoError(t, os.WriteFile(kubectl, []byte(fakeKubectl), 0755))
+	require.NoError(t, os.WriteFile(gdb, []byte(fakeGDB), 0755))
+	executable := filepath.Join(dir, "crash")
+	sources := filepath.Join(dir, "sources")
+	require.NoError(t, os.WriteFile(executable, []byte("ELF"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sources, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sources, "lib", "crash.c"), []byte("int main;"), 0644))
+
+	cfg := &config.Config{GDB: config.GDBConfig{
+		Path:    gdb,
+		Backend: config.BackendKubernetes,
+		Kubernetes: config.KubernetesConfig{
+			Binary:       kubectl,
+			Namespace:    "debug",
+			Image:        "gogdbllm-debug",
+			CPU:          "500m",
+			Memory:       "256Mi",
+			StartTimeout: 5 * time.Second,
+			CopyTimeout:  5 * time.Second,
+		},
+	}}
+	service := NewGDBService(cfg)
+	go func() {
+		for range service.GetOutputChannel() {
+		}
+	}()
+
+	require.NoError(t, service.StartGDB(executable, sources))
+	require.NoError(t, service.SendCommand("break crash.c:5"))
+	require.Eventually(t, func() bool { return len(service.Breakpoints()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, service.StopGDB())
+
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filepath.Join(dir, "kubectl.log"))
+		return strings.Contains(string(data), "delete pod")
+	}, 5*time.Second, 10*time.Millisecond)
+	data, err := os.ReadFile(filepath.Join(dir, "kubectl.log"))
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, calls, 5)
+	pod := strings.Fields(calls[4])[4]
+	assert.Equal(t, "--namespace debug create --filename -", calls[0])
+	assert.Equal(t, "--namespace debug wait --for=condition=Ready pod/"+pod+" --timeout=5s", calls[1])
+	assert.Equal(t, "--namespace debug exec --stdin "+pod+" -- "+gdb+" -d /src/0 /work/crash", calls[3])
+
+	var manifest struct {
+		Spec struct {
+			Containers []struct {
+				Image     string
+				Resources struct{ Limits map[string]string }
+			}
+		}
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "pod.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, "gogdbllm-debug", manifest.Spec.Containers[0].Image)
+	assert.Equal(t, map[string]string{"cpu": "500m", "memory": "256Mi"}, manifest.Spec.Containers[0].Resources.Limits)
+
+	f, err := os.Open(filepath.Join(dir, "files.tar"))
+	require.NoError(t, err)
+	defer f.Close()
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"work/crash", "src/0/", "src/0/lib/", "src/0/lib/crash.c"}, names)
+}
+
+func TestKubernetesCopyFiles(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "crash")
+	require.NoError(t, os.WriteFile(executable, []byte("ELF"), 0755))
+	kubectl := func(script string) string {
+		path := filepath.Join(dir, "kubectl")
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+		return path
+	}
+
+	// The copy has its own timeout, not the pod's start timeout
+	k := &kubernetesExecution{
+		cfg:      config.KubernetesConfig{Binary: kubectl("exec sleep 10"), StartTimeout: time.Hour, CopyTimeout: 100 * time.Millisecond},
+		pod:      "gogdbllm-test",
+		filePath: executable,
+	}
+	start := time.Now()
+	err := k.copyFiles()
+	assert.ErrorContains(t, err, "kubectl exec failed")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// Files that cannot be read fail the copy even if kubectl does not notice
+	k.cfg = config.KubernetesConfig{Binary: kubectl("cat > /dev/null"), CopyTimeout: 5 * time.Second}
+	k.filePath = filepath.Join(dir, "missing")
+	assert.ErrorContains(t, k.copyFiles(), "failed to pack the session's files")
+}
@@ -0,0 +1,85 @@
+package gdb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// fakeDocker is a shell script standing in for the docker CLI: it logs its arguments and
+// runs exec'd commands on the host
+const fakeDocker = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/docker.log"
+case "$1" in
+run) echo 0123456789ab ;;
+exec) shift 5; exec "$@" ;;
+esac
+`
+
+func TestDockerExecution(t *testing.T) {
+	dir := t.TempDir()
+	docker := filepath.Join(dir, "docker")
+	gdb := filepath.Join(dir, "gdb")
+	require.NoError(t, os.WriteFile(docker, []byte(fakeDocker), 0755))
+	require.NoError(t, os.WriteFile(gdb, []byte(fakeGDB), 0755))
+
+	cfg := &config.Config{GDB: config.GDBConfig{
+		Path:    gdb,
+		PTY:     true,
+		Backend: config.BackendDocker,
+		Docker: config.DockerConfig{
+			Binary:       docker,
+			Image:        "gogdbllm-debug",
+			Memory:       "256m",
+			Network:      "none",
+			StartTimeout: 5 * time.Second,
+		},
+	}}
+	service := NewGDBService(cfg)
+	go func() {
+		for range service.GetOutputChannel() {
+		}
+	}()
+
+	require.NoError(t, service.StartGDB("/uploads/crash", "/uploads/sources/s1"))
+	require.NoError(t, service.SendCommand("break crash.c:5"))
+	require.Eventually(t, func() bool { return len(service.Breakpoints()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, service.StopGDB())
+
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filepath.Join(dir, "docker.log"))
+		return strings.Contains(string(data), "rm --force")
+	}, 5*time.Second, 10*time.Millisecond)
+	data, err := os.ReadFile(filepath.Join(dir, "docker.log"))
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, calls, 3)
+
+	run := calls[0]
+	assert.Contains(t, run, "--network none")
+	assert.Contains(t, run, "--memory 256m")
+	assert.Contains(t, run, "--volume=/uploads/crash:/work/crash:ro")
+	assert.Contains(t, run, "--volume=/uploads/sources/s1:/src/0:ro")
+	assert.True(t, strings.HasSuffix(run, "gogdbllm-debug sleep infinity"))
+
+	// The program has no terminal on the server, and GDB sees the container's paths
+	name := strings.Fields(calls[2])[2]
+	assert.Equal(t, "exec --interactive --env HOME=/tmp "+name+" "+gdb+" -d /src/0 /work/crash", calls[1])
+}
+
+func TestExecutionEnvironment(t *testing.T) {
+	cmd, err := localExecution{}.Command("gdb", []string{"crash"}, []string{"DEBUGINFOD_URLS=https://debuginfod.example"})
+	require.NoError(t, err)
+	assert.Contains(t, cmd.Env, "DEBUGINFOD_URLS=https://debuginfod.example")
+	assert.Contains(t, cmd.Env, "PATH="+os.Getenv("PATH"), "the server's environment is kept")
+
+	cmd, err = localExecution{}.Command("gdb", []string{"crash"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, cmd.Env, "GDB inherits the environment unchanged")
+}
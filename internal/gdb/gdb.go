@@ -6,76 +6,187 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
+	"github.com/creack/pty"
 	"github.com/yourusername/gogdbllm/internal/config"
 	appErrors "github.com/yourusername/gogdbllm/internal/errors"
 )
 
+// GDB states reported to the status handler
+const (
+	StatusExited    = "exited"    // GDB exited and was not restarted
+	StatusRestarted = "restarted" // GDB exited on its own and was restarted
+)
+
+// Status reports a change in the GDB process's state
+type Status struct {
+	State       string
+	Reason      string // Why GDB exited, e.g. "signal: killed"
+	Breakpoints int    // Breakpoints set again after a restart
+}
+
+// Restart describes an automatic restart of GDB after it exited on its own
+type Restart struct {
+	Time        time.Time `json:"time"`
+	Reason      string    `json:"reason"`
+	Breakpoints int       `json:"breakpoints"`
+}
+
 // GDBService manages the interaction with the GDB process
 type GDBService struct {
 	cmd         *exec.Cmd
 	stdin       io.WriteCloser
 	stdout      io.ReadCloser
-	outputChan  chan string
+	outputChan  chan Output
 	mutex       sync.Mutex
 	processLock sync.Mutex
 	isRunning   bool
-	// Add new fields for capturing command output
+	// Clean output captured for ExecuteCommandWithOutput
 	lastOutput     []string
 	outputLock     sync.Mutex
 	captureEnabled bool
 	config         *config.GDBConfig
+	driver         driver
+	execution      execution         // Where the current GDB process runs
+	terminal       *inferiorTerminal // The program's terminal; nil when gdb.pty is disabled
+	terminalSize   pty.Winsize       // Size last reported by a client
+
+	// What GDB was started with, to restart it after a crash
+	run         int // Counts the processes started and stopped, so a process's reader knows whether it was replaced
+	filePath    string
+	sourceDirs  []string
+	program     []string       // Commands applying the program's options
+	options     ProgramOptions // The options, for an emulator, which takes them on its command line
+	breakpoints *BreakpointStore
+	registers   *RegisterTracker
+	checkpoints *CheckpointStore
+	stops       *StopTracker
+	restarts    []time.Time // Recent automatic restarts
+	lastRestart *Restart
+	onStatus    func(Status)
+	quit        bool // quit was sent to the current process, so its exit is not a crash
 }
 
 // NewGDBService creates a new GDB service
 func NewGDBService(cfg *config.Config) *GDBService {
 	return &GDBService{
-		outputChan:     make(chan string, 100),
+		outputChan:     make(chan Output, 100),
 		isRunning:      false,
 		lastOutput:     make([]string, 0),
 		captureEnabled: false,
 		config:         &cfg.GDB,
+		driver:         newDriver(&cfg.GDB),
+		breakpoints:    NewBreakpointStore(),
+		registers:      NewRegisterTracker(),
+		checkpoints:    NewCheckpointStore(),
+		stops:          NewStopTracker(),
 	}
 }
 
-// StartGDB starts a new GDB process for the specified file
-func (g *GDBService) StartGDB(filePath string) error {
+// SetStatusHandler sets the function told when GDB exits or is restarted
+func (g *GDBService) SetStatusHandler(handler func(Status)) {
 	g.processLock.Lock()
 	defer g.processLock.Unlock()
+	g.onStatus = handler
+}
+
+// StartGDB starts a new GDB process for the specified file. Any sourceDirs are added
+// to GDB's source search path.
+func (g *GDBService) StartGDB(filePath string, sourceDirs ...string) error {
+	return g.StartProgram(filePath, ProgramOptions{}, sourceDirs...)
+}
 
-	// Stop any existing GDB process
-	if g.isRunning {
-		g.StopGDB()
+// start starts GDB. The caller holds processLock.
+func (g *GDBService) start(filePath string, sourceDirs []string) error {
+	g.registers.Reset()
+	// The sources of stops are looked for where GDB looks, and next to the executable
+	g.stops.Reset(append(append([]string{}, sourceDirs...), filepath.Dir(filePath)))
+	execution, err := newExecution(g.config)
+	if err != nil {
+		return err
+	}
+	emulator, err := g.emulatorFor(filePath)
+	if err != nil {
+		execution.Close()
+		return err
+	}
+
+	// Run the program on its own terminal so it can be driven interactively
+	tty := ""
+	if g.config.PTY && g.driver.SupportsTerminal() && execution.SupportsTerminal() {
+		terminal, err := openInferiorTerminal(g.terminalSizeOrDefault())
+		if err != nil {
+			return err
+		}
+		g.terminal = terminal
+		tty = terminal.Name()
+	}
+	failed := func(err error, message string) error {
+		g.closeTerminal()
+		execution.Close()
+		return appErrors.Wrap(err, message)
 	}
 
-	// Create a new GDB command
-	g.cmd = exec.Command(g.config.Path, filePath)
+	// Create a new debugger command
+	file, dirs := execution.Files(filePath, sourceDirs)
+	path, args := g.config.Path, g.driver.Args(file, dirs, tty)
+	if emulator != nil {
+		// The program runs under qemu on the terminal, and GDB connects to qemu's stub
+		programOutput := newOutputPipeline()
+		if err := emulator.start(filePath, g.options, g.terminal, func(chunk string) { g.emit(programOutput.process(chunk)) }); err != nil {
+			return failed(err, "failed to start the emulator")
+		}
+		execution = emulatedExecution{execution: execution, emulator: emulator}
+		path = g.config.Emulation.GDBPath
+		args = append(emulator.gdbArgs(int(g.config.Emulation.ConnectTimeout.Seconds())), g.driver.Args(file, dirs, "")...)
+	}
+	cmd, err := execution.Command(path, args, g.driver.Env())
+	if err != nil {
+		return failed(err, "failed to prepare GDB")
+	}
 
 	// Set up stdin and stdout
-	var err error
-	g.stdin, err = g.cmd.StdinPipe()
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return appErrors.Wrap(err, "failed to create stdin pipe")
+		return failed(err, "failed to create stdin pipe")
 	}
 
-	g.stdout, err = g.cmd.StdoutPipe()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return appErrors.Wrap(err, "failed to create stdout pipe")
+		return failed(err, "failed to create stdout pipe")
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return failed(err, "failed to start GDB")
 	}
 
+	g.mutex.Lock()
+	g.cmd, g.stdin, g.stdout = cmd, stdin, stdout
+	g.quit = false
+	g.mutex.Unlock()
+	g.run++
+	g.filePath, g.sourceDirs = filePath, sourceDirs
+	g.execution = execution
+
 	// Start reading from stdout
-	go g.readOutput()
+	go g.readOutput(g.run, cmd, stdin, stdout, g.terminal, execution)
 
-	// Start the command
-	if err := g.cmd.Start(); err != nil {
-		return appErrors.Wrap(err, "failed to start GDB")
+	if g.terminal != nil {
+		terminalOutput := newOutputPipeline()
+		go g.terminal.pump(func(chunk string) { g.emit(terminalOutput.process(chunk)) })
 	}
 
 	g.isRunning = true
+	if emulator != nil {
+		g.writeCommand(fmt.Sprintf("echo [The %s program runs under %s, stopped at its entry point: continue it rather than run it]\\n", emulator.arch, emulator.cfg.QEMU))
+		return nil
+	}
+	g.applyProgram()
 	return nil
 }
 
@@ -92,11 +203,22 @@ func (g *GDBService) StopOutputCapture() string {
 	g.outputLock.Lock()
 	defer g.outputLock.Unlock()
 	g.captureEnabled = false
-	output := strings.Join(g.lastOutput, "\n")
+	output := strings.TrimSuffix(strings.Join(g.lastOutput, ""), "\n")
 	g.lastOutput = make([]string, 0)
 	return output
 }
 
+// capturedSince returns the output captured after the first from chunks, and the number
+// of chunks captured, to read a capture in progress
+func (g *GDBService) capturedSince(from int) (string, int) {
+	g.outputLock.Lock()
+	defer g.outputLock.Unlock()
+	if from > len(g.lastOutput) {
+		from = len(g.lastOutput)
+	}
+	return strings.Join(g.lastOutput[from:], ""), len(g.lastOutput)
+}
+
 // ExecuteCommandWithOutput executes a GDB command and captures its output
 func (g *GDBService) ExecuteCommandWithOutput(command string, timeoutSeconds int) (string, error) {
 	if !g.isRunning {
@@ -147,20 +269,25 @@ func (g *GDBService) StopGDB() error {
 	if !g.isRunning {
 		return nil
 	}
+	g.stop()
+	return nil
+}
 
-	// Send SIGTERM to process group
-	if g.cmd.Process != nil {
-		pgid, err := syscall.Getpgid(g.cmd.Process.Pid)
-		if err == nil {
-			syscall.Kill(-pgid, syscall.SIGTERM)
-		}
+// stop kills GDB. Its reader sees that it was stopped and does not restart it. The caller
+// holds processLock.
+func (g *GDBService) stop() {
+	g.run++
 
-		// Try to kill the process directly if still running
-		g.cmd.Process.Kill()
+	// Stop the debugger and the program it runs, with its checkpoints
+	g.killCheckpoints()
+	g.checkpoints.Reset()
+	if g.cmd.Process != nil {
+		killProcessGroup(g.cmd.Process)
 	}
+	g.execution.Close()
+	g.closeTerminal()
 
 	g.isRunning = false
-	return nil
 }
 
 // SendCommand sends a command to GDB
@@ -176,11 +303,31 @@ func (g *GDBService) SendCommand(command string) error {
 	if err != nil {
 		return appErrors.Wrap(err, "failed to send command to GDB")
 	}
+	g.breakpoints.Command(command)
+	g.registers.Command(command)
+	g.stops.Command(command)
+	if name := strings.Fields(command); len(name) > 0 && (name[0] == "quit" || name[0] == "q") {
+		g.quit = true
+	}
 	return nil
 }
 
-// GetOutputChannel returns the channel for GDB output
-func (g *GDBService) GetOutputChannel() <-chan string {
+// Breakpoints returns the breakpoints GDB confirmed, which are set again when it is
+// restarted
+func (g *GDBService) Breakpoints() []Breakpoint {
+	return g.breakpoints.List()
+}
+
+// LastRestart returns the last automatic restart of GDB, or nil if there was none since
+// it was started
+func (g *GDBService) LastRestart() *Restart {
+	g.processLock.Lock()
+	defer g.processLock.Unlock()
+	return g.lastRestart
+}
+
+// GetOutputChannel returns the channel for GDB's and the program's output
+func (g *GDBService) GetOutputChannel() <-chan Output {
 	return g.outputChan
 }
 
@@ -191,37 +338,142 @@ func (g *GDBService) IsRunning() bool {
 	return g.isRunning
 }
 
-// readOutput reads the output from GDB and sends it to the output channel
-func (g *GDBService) readOutput() {
-	scanner := bufio.NewScanner(g.stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
+// Executable returns the path of the executable GDB was last started with, or "" if it
+// never was
+func (g *GDBService) Executable() string {
+	g.processLock.Lock()
+	defer g.processLock.Unlock()
+	return g.filePath
+}
 
-		// Check if output capture is enabled
-		g.outputLock.Lock()
-		if g.captureEnabled {
-			g.lastOutput = append(g.lastOutput, line)
-		}
-		g.outputLock.Unlock()
+// LastStop returns where the program last stopped, with the source around it, or nil
+// before it first stopped
+func (g *GDBService) LastStop() *StopLocation {
+	return g.stops.Last()
+}
+
+// emit records clean output for any capture in progress and sends output to the output
+// channel
+func (g *GDBService) emit(output Output) {
+	g.outputLock.Lock()
+	if g.captureEnabled {
+		g.lastOutput = append(g.lastOutput, output.Clean)
+	}
+	g.outputLock.Unlock()
+
+	g.outputChan <- output
+}
+
+// closeTerminal releases the program's terminal, if any. The caller holds processLock.
+func (g *GDBService) closeTerminal() {
+	if g.terminal != nil {
+		g.terminal.Close()
+		g.terminal = nil
+	}
+}
+
+// readOutput reads the output from a GDB process and sends it to the output channel.
+// When GDB exits it closes terminal and execution, the program terminal and execution of
+// the same run, and restarts GDB if it exited on its own.
+func (g *GDBService) readOutput(run int, cmd *exec.Cmd, stdin io.WriteCloser, stdout io.Reader, terminal *inferiorTerminal, execution execution) {
+	pipeline := newOutputPipeline()
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		output := pipeline.processLine(scanner.Text())
+		g.breakpoints.Output(strings.TrimSuffix(output.Clean, "\n"))
+		g.checkpoints.Output(strings.TrimSuffix(output.Clean, "\n"))
+		output.Stop = g.stops.Output(strings.TrimSuffix(output.Clean, "\n"))
+		g.emit(output)
+	}
 
-		g.outputChan <- line
+	// Try to send an EOF signal to any waiting goroutines, and wait for the process to
+	// clean up
+	stdin.Close()
+	reason := "exited"
+	if err := cmd.Wait(); err != nil {
+		reason = err.Error()
 	}
+	execution.Close()
 
 	// Process has exited
 	g.processLock.Lock()
-	g.isRunning = false
+	current := run == g.run
+	if current {
+		g.isRunning = false
+	}
+	if g.terminal == terminal {
+		g.closeTerminal()
+	}
 	g.processLock.Unlock()
 
 	// Output a message that GDB has exited
-	g.outputChan <- "\n[GDB has exited]"
+	g.outputChan <- Output{Raw: "\n[GDB has exited]", Clean: "[GDB has exited]\n"}
 
-	// Try to send an EOF signal to any waiting goroutines
-	if g.stdin != nil {
-		g.stdin.Close()
+	if current {
+		g.killOrphanedCheckpoints()
+		g.recover(run, reason)
 	}
+}
 
-	// Wait for the process to clean up
-	if g.cmd.Process != nil {
-		g.cmd.Wait()
+// recover restarts GDB after it exited on its own, e.g. killed by the program or for lack
+// of memory, on the same executable and with the breakpoints it had. Restarts stop when
+// they are disabled or GDB exits too often.
+func (g *GDBService) recover(run int, reason string) {
+	g.mutex.Lock()
+	quit := g.quit
+	g.mutex.Unlock()
+
+	g.processLock.Lock()
+	restartCfg := g.config.Restart
+	now := time.Now()
+	recent := g.restarts[:0]
+	for _, t := range g.restarts {
+		if now.Sub(t) < restartCfg.Window {
+			recent = append(recent, t)
+		}
+	}
+	g.restarts = recent
+
+	status := Status{State: StatusExited, Reason: reason}
+	var notice string
+	switch {
+	case run != g.run || g.isRunning || quit:
+		// Started again meanwhile, or quit on request
+		g.processLock.Unlock()
+		return
+	case !restartCfg.Enabled:
+	case len(recent) >= restartCfg.MaxRestarts:
+		notice = fmt.Sprintf("[GDB exited (%s) and was not restarted: it was restarted %d times in %s]", reason, len(recent), restartCfg.Window)
+	default:
+		commands := g.breakpoints.RestoreCommands()
+		if err := g.start(g.filePath, g.sourceDirs); err != nil {
+			notice = fmt.Sprintf("[GDB exited (%s) and could not be restarted: %v]", reason, err)
+			break
+		}
+		g.restarts = append(g.restarts, now)
+		for _, command := range commands {
+			g.writeCommand(command)
+		}
+		g.breakpoints.Renumber()
+		status = Status{State: StatusRestarted, Reason: reason, Breakpoints: len(g.breakpoints.List())}
+		g.lastRestart = &Restart{Time: now, Reason: reason, Breakpoints: status.Breakpoints}
+		notice = fmt.Sprintf("[GDB exited (%s) and was restarted on %s with %d breakpoints; the program must be run again]",
+			reason, filepath.Base(g.filePath), status.Breakpoints)
+	}
+	onStatus := g.onStatus
+	g.processLock.Unlock()
+
+	if notice != "" {
+		g.emit(Output{Raw: "\n" + notice, Clean: notice + "\n"})
 	}
+	if onStatus != nil {
+		onStatus(status)
+	}
+}
+
+// writeCommand sends a command to GDB without recording it in the breakpoint store
+func (g *GDBService) writeCommand(command string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	fmt.Fprintln(g.stdin, command)
 }
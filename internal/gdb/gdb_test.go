@@ -106,7 +106,7 @@ func TestGDBOutputCapture(t *testing.T) {
 	// Create a sample output manually
 	gdbService.outputLock.Lock()
 	gdbService.captureEnabled = true
-	gdbService.lastOutput = []string{"Line 1", "Line 2", "Line 3"}
+	gdbService.lastOutput = []string{"Line 1\n", "Line 2\n", "Line 3\n"}
 	gdbService.outputLock.Unlock()
 
 	// Test capturing output
@@ -118,3 +118,9 @@ func TestGDBOutputCapture(t *testing.T) {
 
 // Test mocking would be implemented here in a real-world scenario
 // For this example, we'll use skippable integration tests
+
+func TestParseCompletions(t *testing.T) {
+	output := "(gdb) info registers\ninfo registers rip\ninfo registers rip\n*** List may be truncated, max-completions reached. ***\nunrelated"
+	assert.Equal(t, []string{"info registers", "info registers rip"}, parseCompletions("info reg", output))
+	assert.Equal(t, []string{}, parseCompletions("zzz", output))
+}
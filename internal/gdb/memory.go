@@ -0,0 +1,158 @@
+package gdb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// MaxMemoryRead limits the bytes ReadMemory reads at once
+const MaxMemoryRead = 4096
+
+// MaxMemoryWrite limits the bytes WriteMemory writes at once
+const MaxMemoryWrite = 256
+
+var (
+	// memoryLineRegex matches a line of `x/Nxb` output such as
+	// "0x555555558010 <buf>:\t0x48\t0x65\t0x6c"
+	memoryLineRegex = regexp.MustCompile(`^(0x[0-9a-fA-F]+)(?:\s*<[^>]*>)?:\s*(.*)$`)
+
+	// memoryErrorRegex matches GDB's report of an unreadable address
+	memoryErrorRegex = regexp.MustCompile(`Cannot access memory at address (0x[0-9a-fA-F]+)`)
+)
+
+// Memory is a block of the program's memory
+type Memory struct {
+	Address uint64
+	Bytes   []byte
+}
+
+// ReadMemory reads length bytes at addr with GDB's x command. When only the start of the
+// block is readable, the bytes before the first unreadable address are returned.
+func (g *GDBService) ReadMemory(addr uint64, length int) (*Memory, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("memory inspection"); err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > MaxMemoryRead {
+		return nil, fmt.Errorf("%w: length must be between 1 and %d", appErrors.ErrBadRequest, MaxMemoryRead)
+	}
+
+	output, err := g.ExecuteCommandWithOutput(fmt.Sprintf("x/%dxb 0x%x", length, addr), g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return parseMemory(output, addr)
+}
+
+// WriteMemory writes data at addr
+func (g *GDBService) WriteMemory(addr uint64, data []byte) error {
+	if !g.IsRunning() {
+		return appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("memory writes"); err != nil {
+		return err
+	}
+	output, err := g.ExecuteCommandWithOutput(WriteMemoryCommand(addr, data), g.commandTimeout())
+	if err != nil {
+		return err
+	}
+	if match := memoryErrorRegex.FindString(output); match != "" {
+		return fmt.Errorf("%w: %s", appErrors.ErrBadRequest, match)
+	}
+	return nil
+}
+
+// WriteMemoryCommand returns the GDB command writing data at addr, e.g.
+// "set {unsigned char[2]}0x1000 = {0xde, 0xad}"
+func WriteMemoryCommand(addr uint64, data []byte) string {
+	values := make([]string, len(data))
+	for i, b := range data {
+		values[i] = fmt.Sprintf("0x%02x", b)
+	}
+	return fmt.Sprintf("set {unsigned char[%d]}0x%x = {%s}", len(data), addr, strings.Join(values, ", "))
+}
+
+// parseMemory reads the bytes of `x/Nxb` output, which must start at addr
+func parseMemory(output string, addr uint64) (*Memory, error) {
+	memory := &Memory{Address: addr}
+	next := addr
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if match := memoryErrorRegex.FindStringSubmatch(line); match != nil {
+			if len(memory.Bytes) == 0 {
+				return nil, fmt.Errorf("%w: cannot access memory at %s", appErrors.ErrBadRequest, match[1])
+			}
+			break
+		}
+		match := memoryLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineAddr, err := ParseAddress(match[1])
+		if err != nil || lineAddr != next {
+			return nil, fmt.Errorf("unexpected memory output: %s", line)
+		}
+		for _, field := range strings.Fields(match[2]) {
+			value, err := strconv.ParseUint(strings.TrimPrefix(field, "0x"), 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected memory output: %s", line)
+			}
+			memory.Bytes = append(memory.Bytes, byte(value))
+			next++
+		}
+	}
+	if len(memory.Bytes) == 0 {
+		return nil, fmt.Errorf("no memory read at 0x%x: %s", addr, strings.TrimSpace(output))
+	}
+	return memory, nil
+}
+
+// Hex returns the bytes as a hex string
+func (m *Memory) Hex() string {
+	return hex.EncodeToString(m.Bytes)
+}
+
+// ASCII returns the bytes with unprintable ones shown as dots
+func (m *Memory) ASCII() string {
+	text := make([]byte, len(m.Bytes))
+	for i, b := range m.Bytes {
+		if b >= 0x20 && b < 0x7f {
+			text[i] = b
+		} else {
+			text[i] = '.'
+		}
+	}
+	return string(text)
+}
+
+// Hexdump returns the bytes as lines of `hexdump -C`: the address, 16 bytes in hex and
+// the same bytes as ASCII
+func (m *Memory) Hexdump() []string {
+	ascii := m.ASCII()
+	var lines []string
+	for offset := 0; offset < len(m.Bytes); offset += 16 {
+		end := min(offset+16, len(m.Bytes))
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%016x  ", m.Address+uint64(offset))
+		for i := offset; i < offset+16; i++ {
+			if i < end {
+				fmt.Fprintf(&sb, "%02x ", m.Bytes[i])
+			} else {
+				sb.WriteString("   ")
+			}
+			if i == offset+7 {
+				sb.WriteString(" ")
+			}
+		}
+		fmt.Fprintf(&sb, " |%s|", ascii[offset:end])
+		lines = append(lines, sb.String())
+	}
+	return lines
+}
@@ -0,0 +1,58 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// TestParseMemory tests parsing of `x/Nxb` output, with and without symbol labels
+func TestParseMemory(t *testing.T) {
+	output := "0x555555558010 <buf>:\t0x48\t0x65\t0x6c\t0x6c\t0x6f\t0x00\t0x01\t0x02\n" +
+		"0x555555558018 <buf+8>:\t0x7f\t0xff\n"
+	memory, err := parseMemory(output, 0x555555558010)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x555555558010), memory.Address)
+	assert.Equal(t, []byte{'H', 'e', 'l', 'l', 'o', 0, 1, 2, 0x7f, 0xff}, memory.Bytes)
+	assert.Equal(t, "48656c6c6f0001027fff", memory.Hex())
+	assert.Equal(t, "Hello.....", memory.ASCII())
+
+	memory, err = parseMemory("0x1000:\t0x90\t0xc3\n", 0x1000)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x90, 0xc3}, memory.Bytes)
+}
+
+// TestParseMemoryUnreadable tests reads that stop at, or start at, an unreadable address
+func TestParseMemoryUnreadable(t *testing.T) {
+	output := "0x7ffff7ffeff8:\t0x01\t0x02\t0x03\t0x04\t0x05\t0x06\t0x07\t0x08\n" +
+		"Cannot access memory at address 0x7ffff7fff000\n"
+	memory, err := parseMemory(output, 0x7ffff7ffeff8)
+	require.NoError(t, err)
+	assert.Len(t, memory.Bytes, 8)
+
+	_, err = parseMemory("Cannot access memory at address 0x0\n", 0)
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+
+	_, err = parseMemory("0x2000:\t0x01\n", 0x1000)
+	assert.Error(t, err)
+
+	_, err = parseMemory("No symbol table is loaded.\n", 0x1000)
+	assert.Error(t, err)
+}
+
+// TestWriteMemoryCommand tests the command that writes bytes
+func TestWriteMemoryCommand(t *testing.T) {
+	assert.Equal(t, "set {unsigned char[2]}0x1000 = {0xde, 0xad}", WriteMemoryCommand(0x1000, []byte{0xde, 0xad}))
+}
+
+// TestHexdump tests the hexdump -C style lines, including a short last line
+func TestHexdump(t *testing.T) {
+	memory := &Memory{Address: 0x1000, Bytes: []byte("0123456789abcdefXY")}
+	lines := memory.Hexdump()
+	require.Len(t, lines, 2)
+	assert.Equal(t, "0000000000001000  30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  |0123456789abcdef|", lines[0])
+	assert.Equal(t, "0000000000001010  58 59                                             |XY|", lines[1])
+}
@@ -0,0 +1,293 @@
+package gdb
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObserveOptions describes an observe run: sample the backtraces of every thread of a
+// running process every Interval for Duration
+type ObserveOptions struct {
+	PID      int
+	Duration time.Duration
+	Interval time.Duration
+}
+
+// Observe defaults, used when options leave them unset
+const (
+	defaultObserveDuration = 10 * time.Second
+	defaultObserveInterval = 500 * time.Millisecond
+)
+
+// NewObserveOptions builds options from a duration and interval in seconds, using the
+// defaults for values that are not positive
+func NewObserveOptions(pid int, durationSeconds, intervalSeconds float64) ObserveOptions {
+	opts := ObserveOptions{
+		PID:      pid,
+		Duration: time.Duration(durationSeconds * float64(time.Second)),
+		Interval: time.Duration(intervalSeconds * float64(time.Second)),
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = defaultObserveDuration
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultObserveInterval
+	}
+	return opts
+}
+
+// Sampler captures one "thread apply all bt" sample of a process
+type Sampler func(ctx context.Context, pid int) (string, error)
+
+// BatchSampler returns a Sampler that attaches a fresh GDB in batch mode for each sample
+// and detaches when it exits, so the process only stops while its stacks are read
+func BatchSampler(gdbPath string) Sampler {
+	return func(ctx context.Context, pid int) (string, error) {
+		cmd := exec.CommandContext(ctx, gdbPath, "-batch", "-nx",
+			"-ex", "set pagination off",
+			"-ex", "thread apply all bt",
+			"-p", strconv.Itoa(pid))
+		out, err := cmd.CombinedOutput()
+		if err != nil && !strings.Contains(string(out), "#0") {
+			return "", fmt.Errorf("gdb failed to sample process %d: %w: %s", pid, err, strings.TrimSpace(lastLines(string(out), 3)))
+		}
+		return string(out), nil
+	}
+}
+
+// StackCount is a distinct call stack and how often it was seen
+type StackCount struct {
+	Frames  []string `json:"frames"` // Innermost frame first
+	Count   int      `json:"count"`
+	Percent float64  `json:"percent"`
+}
+
+// FunctionCount is how often a function was seen at the top of a stack (self) and
+// anywhere in a stack (total)
+type FunctionCount struct {
+	Function     string  `json:"function"`
+	Self         int     `json:"self"`
+	Total        int     `json:"total"`
+	SelfPercent  float64 `json:"selfPercent"`
+	TotalPercent float64 `json:"totalPercent"`
+}
+
+// ObserveReport summarises where a process spent its time while it was observed
+type ObserveReport struct {
+	PID          int             `json:"pid"`
+	Executable   string          `json:"executable,omitempty"`
+	Duration     time.Duration   `json:"duration"`
+	Interval     time.Duration   `json:"interval"`
+	Samples      int             `json:"samples"`
+	ThreadStacks int             `json:"threadStacks"` // Stacks collected across all threads and samples
+	MaxThreads   int             `json:"maxThreads"`
+	Stacks       []StackCount    `json:"stacks"`
+	Functions    []FunctionCount `json:"functions"`
+	Errors       []string        `json:"errors,omitempty"`
+}
+
+// maxReportStacks and maxReportFunctions bound the size of a report
+const (
+	maxReportStacks    = 20
+	maxReportFunctions = 25
+)
+
+// Observe samples the process's stacks until opts.Duration has passed or ctx is done, then
+// aggregates the samples into a report. A failed sample is recorded in the report's errors;
+// Observe fails only if no sample succeeds.
+func Observe(ctx context.Context, sample Sampler, opts ObserveOptions) (*ObserveReport, error) {
+	report := &ObserveReport{PID: opts.PID, Duration: opts.Duration, Interval: opts.Interval}
+	stacks := make(map[string]*StackCount)
+	functions := make(map[string]*FunctionCount)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		output, err := sample(ctx, opts.PID)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// The sample was cut short by the end of the run
+		case err != nil:
+			report.Errors = append(report.Errors, err.Error())
+		default:
+			report.Samples++
+			threads := ParseBacktraces(output)
+			if len(threads) > report.MaxThreads {
+				report.MaxThreads = len(threads)
+			}
+			for _, frames := range threads {
+				report.ThreadStacks++
+				countStack(stacks, functions, frames)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if report.Samples == 0 {
+				if len(report.Errors) > 0 {
+					return nil, fmt.Errorf("no samples collected: %s", report.Errors[0])
+				}
+				return nil, fmt.Errorf("no samples collected in %s", opts.Duration)
+			}
+			report.finish(stacks, functions)
+			return report, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// countStack adds one thread's stack to the running totals
+func countStack(stacks map[string]*StackCount, functions map[string]*FunctionCount, frames []string) {
+	if len(frames) == 0 {
+		return
+	}
+	key := strings.Join(frames, "\x00")
+	if stacks[key] == nil {
+		stacks[key] = &StackCount{Frames: frames}
+	}
+	stacks[key].Count++
+
+	// Count each function once per stack, so recursion does not inflate its total
+	seen := make(map[string]bool)
+	for i, function := range frames {
+		if functions[function] == nil {
+			functions[function] = &FunctionCount{Function: function}
+		}
+		if i == 0 {
+			functions[function].Self++
+		}
+		if !seen[function] {
+			seen[function] = true
+			functions[function].Total++
+		}
+	}
+}
+
+// finish sorts the totals, keeps the most frequent, and computes percentages of all
+// thread stacks
+func (r *ObserveReport) finish(stacks map[string]*StackCount, functions map[string]*FunctionCount) {
+	percent := func(n int) float64 {
+		if r.ThreadStacks == 0 {
+			return 0
+		}
+		return float64(n) * 100 / float64(r.ThreadStacks)
+	}
+
+	for _, stack := range stacks {
+		stack.Percent = percent(stack.Count)
+		r.Stacks = append(r.Stacks, *stack)
+	}
+	sort.Slice(r.Stacks, func(i, j int) bool {
+		if r.Stacks[i].Count != r.Stacks[j].Count {
+			return r.Stacks[i].Count > r.Stacks[j].Count
+		}
+		return strings.Join(r.Stacks[i].Frames, ";") < strings.Join(r.Stacks[j].Frames, ";")
+	})
+	if len(r.Stacks) > maxReportStacks {
+		r.Stacks = r.Stacks[:maxReportStacks]
+	}
+
+	for _, function := range functions {
+		function.SelfPercent = percent(function.Self)
+		function.TotalPercent = percent(function.Total)
+		r.Functions = append(r.Functions, *function)
+	}
+	sort.Slice(r.Functions, func(i, j int) bool {
+		a, b := r.Functions[i], r.Functions[j]
+		if a.Self != b.Self {
+			return a.Self > b.Self
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Function < b.Function
+	})
+	if len(r.Functions) > maxReportFunctions {
+		r.Functions = r.Functions[:maxReportFunctions]
+	}
+}
+
+// String formats the report as plain text for the LLM
+func (r *ObserveReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Observed process %d", r.PID)
+	if r.Executable != "" {
+		fmt.Fprintf(&sb, " (%s)", r.Executable)
+	}
+	fmt.Fprintf(&sb, " for %s: %d samples every %s, up to %d threads, %d thread stacks\n",
+		r.Duration, r.Samples, r.Interval, r.MaxThreads, r.ThreadStacks)
+
+	sb.WriteString("\nFunctions by samples on top of the stack (self) and anywhere in it (total):\n")
+	for _, f := range r.Functions {
+		fmt.Fprintf(&sb, "  %5.1f%% self  %5.1f%% total  %s\n", f.SelfPercent, f.TotalPercent, f.Function)
+	}
+
+	sb.WriteString("\nMost frequent stacks (innermost frame first):\n")
+	for _, stack := range r.Stacks {
+		fmt.Fprintf(&sb, "  %5.1f%% (%d)  %s\n", stack.Percent, stack.Count, strings.Join(stack.Frames, " <- "))
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(&sb, "\n%d samples failed, e.g.: %s\n", len(r.Errors), r.Errors[0])
+	}
+	return sb.String()
+}
+
+var (
+	// threadHeaderPattern matches "Thread 2 (Thread 0x7f... (LWP 123)):" lines
+	threadHeaderPattern = regexp.MustCompile(`^Thread \d+ `)
+	// framePattern matches "#0  0x00007f... in poll (...) from /lib/libc.so.6" and
+	// "#1  main (argc=1, argv=...) at main.c:12"
+	framePattern = regexp.MustCompile(`^#\d+\s+(?:0x[0-9a-fA-F]+\s+in\s+)?([^\s(]+)`)
+)
+
+// ParseBacktraces splits "thread apply all bt" output into one list of function names per
+// thread, innermost frame first. Output of a single-threaded "bt" is one thread.
+func ParseBacktraces(output string) [][]string {
+	var threads [][]string
+	var current []string
+
+	flush := func() {
+		if len(current) > 0 {
+			threads = append(threads, current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if threadHeaderPattern.MatchString(line) {
+			flush()
+			continue
+		}
+		match := framePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		// Frame #0 always starts a new stack, even without a thread header
+		if strings.HasPrefix(line, "#0 ") {
+			flush()
+		}
+		current = append(current, match[1])
+	}
+	flush()
+	return threads
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
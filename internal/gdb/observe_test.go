@@ -0,0 +1,65 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleBacktraces = `[Thread debugging using libthread_db enabled]
+0x00007f3a1c2e8d7f in __GI___poll (fds=0x55d0, nfds=1, timeout=-1) at ../sysdeps/unix/sysv/linux/poll.c:29
+
+Thread 2 (Thread 0x7f3a1b5ff640 (LWP 4242) "worker"):
+#0  0x00007f3a1c26a117 in __futex_abstimed_wait_common () from /lib/x86_64-linux-gnu/libc.so.6
+#1  0x00007f3a1c26ca41 in pthread_mutex_lock () from /lib/x86_64-linux-gnu/libc.so.6
+#2  0x000055d0a1b2c1d9 in take_lock (m=0x55d0a1b30040) at worker.c:18
+#3  worker (arg=0x0) at worker.c:31
+
+Thread 1 (Thread 0x7f3a1c1e7740 (LWP 4241) "server"):
+#0  0x00007f3a1c2e8d7f in __GI___poll (fds=0x55d0, nfds=1, timeout=-1) at ../sysdeps/unix/sysv/linux/poll.c:29
+#1  0x000055d0a1b2c2aa in main () at server.c:52
+[Inferior 1 (process 4241) detached]
+`
+
+func TestParseBacktraces(t *testing.T) {
+	threads := ParseBacktraces(sampleBacktraces)
+	assert.Equal(t, [][]string{
+		{"__futex_abstimed_wait_common", "pthread_mutex_lock", "take_lock", "worker"},
+		{"__GI___poll", "main"},
+	}, threads)
+
+	// A plain "bt" without thread headers is a single stack
+	assert.Equal(t, [][]string{{"crash", "main"}},
+		ParseBacktraces("#0  crash () at a.c:3\n#1  0x0000000000401136 in main () at a.c:8\n"))
+}
+
+func TestObserve(t *testing.T) {
+	calls := 0
+	sampler := func(ctx context.Context, pid int) (string, error) {
+		calls++
+		if calls == 2 {
+			return "", errors.New("ptrace: Operation not permitted")
+		}
+		return sampleBacktraces, nil
+	}
+
+	report, err := Observe(context.Background(), sampler, ObserveOptions{PID: 4241, Duration: 60 * time.Millisecond, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, calls-1, report.Samples)
+	assert.Len(t, report.Errors, 1)
+	assert.Equal(t, 2, report.MaxThreads)
+	assert.Equal(t, 2*report.Samples, report.ThreadStacks)
+
+	// Both stacks appear in every sample, so each holds half of the thread stacks
+	require.Len(t, report.Stacks, 2)
+	assert.InDelta(t, 50, report.Stacks[0].Percent, 0.01)
+	assert.Contains(t, report.String(), "take_lock")
+
+	failing := func(ctx context.Context, pid int) (string, error) { return "", errors.New("no such process") }
+	_, err = Observe(context.Background(), failing, ObserveOptions{PID: 1, Duration: 20 * time.Millisecond, Interval: 10 * time.Millisecond})
+	assert.ErrorContains(t, err, "no such process")
+}
@@ -0,0 +1,51 @@
+package gdb
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/yourusername/gogdbllm/internal/utils"
+)
+
+// Output is a chunk of GDB's or the program's output in two forms: raw for the
+// terminal, which renders its colours, and clean for everything that reads it as text,
+// like the session log, the LLM context and command capture
+type Output struct {
+	Raw   string        // As written, with ANSI escape codes
+	Clean string        // Without escape codes and GDB's control characters; lines end in a newline
+	Stop  *StopLocation // Set on the line that completes GDB's report of a stop
+}
+
+// outputPipeline turns one output stream into Output chunks. The raw stream is copied
+// as is, and through a CleanWriter to the clean one. Each stream needs its own pipeline,
+// since an escape sequence may be split between its chunks.
+type outputPipeline struct {
+	raw    bytes.Buffer
+	clean  bytes.Buffer
+	writer io.Writer
+}
+
+// newOutputPipeline creates a pipeline for an output stream
+func newOutputPipeline() *outputPipeline {
+	p := &outputPipeline{}
+	p.writer = io.MultiWriter(&p.raw, utils.NewCleanWriter(&p.clean))
+	return p
+}
+
+// process runs a chunk of the stream through the pipeline
+func (p *outputPipeline) process(chunk string) Output {
+	io.WriteString(p.writer, chunk)
+	output := Output{Raw: p.raw.String(), Clean: p.clean.String()}
+	p.raw.Reset()
+	p.clean.Reset()
+	return output
+}
+
+// processLine runs a line the scanner has taken the newline off through the pipeline.
+// The terminal shows each chunk on its own line, so only the clean form gets the
+// newline back.
+func (p *outputPipeline) processLine(line string) Output {
+	output := p.process(line)
+	output.Clean += "\n"
+	return output
+}
@@ -0,0 +1,110 @@
+package gdb
+
+import (
+	"strings"
+	"sync"
+)
+
+// OutputLine is a line of terminal output. Seq numbers the session's lines from 1, so
+// clients can ask for the lines after the last one they saw.
+type OutputLine struct {
+	Seq  uint64 `json:"seq"`
+	Text string `json:"text"`
+}
+
+// OutputRing keeps the most recent lines of a session's terminal output. Output that
+// does not end in a newline, like GDB's prompt, is kept as a partial last line until
+// the rest of it arrives.
+type OutputRing struct {
+	lines   []OutputLine
+	start   int    // Index of the oldest line in lines once the ring is full
+	next    uint64 // Seq of the next complete line
+	partial string
+	mutex   sync.Mutex
+}
+
+// NewOutputRing creates a ring holding up to size lines
+func NewOutputRing(size int) *OutputRing {
+	if size < 1 {
+		size = 1
+	}
+	return &OutputRing{lines: make([]OutputLine, 0, size), next: 1}
+}
+
+// Write adds output to the ring
+func (r *OutputRing) Write(output string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	text := r.partial + strings.ReplaceAll(output, "\r\n", "\n")
+	parts := strings.Split(text, "\n")
+	r.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		r.add(line)
+	}
+}
+
+// add appends a complete line, dropping the oldest one when the ring is full; the caller
+// holds the lock
+func (r *OutputRing) add(text string) {
+	line := OutputLine{Seq: r.next, Text: text}
+	r.next++
+	if len(r.lines) < cap(r.lines) {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % len(r.lines)
+}
+
+// Tail returns the last n lines, including a partial last line
+func (r *OutputRing) Tail(n int) []OutputLine {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	lines := r.ordered()
+	if n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Since returns up to limit lines from seq on, including a partial last line. Lines that
+// have left the ring are skipped; a limit of 0 returns every line held.
+func (r *OutputRing) Since(seq uint64, limit int) []OutputLine {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	lines := r.ordered()
+	first := 0
+	for first < len(lines) && lines[first].Seq < seq {
+		first++
+	}
+	lines = lines[first:]
+	if limit > 0 && limit < len(lines) {
+		lines = lines[:limit]
+	}
+	return lines
+}
+
+// ordered returns the lines oldest first, followed by the partial line if there is one;
+// the caller holds the lock
+func (r *OutputRing) ordered() []OutputLine {
+	lines := make([]OutputLine, 0, len(r.lines)+1)
+	lines = append(lines, r.lines[r.start:]...)
+	lines = append(lines, r.lines[:r.start]...)
+	if r.partial != "" {
+		lines = append(lines, OutputLine{Seq: r.next, Text: r.partial})
+	}
+	return lines
+}
+
+// JoinLines returns the text of lines, one per line
+func JoinLines(lines []OutputLine) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(line.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,29 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputRing(t *testing.T) {
+	ring := NewOutputRing(3)
+	ring.Write("Breakpoint 1, main () at crash.c:5\r\n5\t  int *p = 0;\n(gdb) ")
+	assert.Equal(t, []OutputLine{
+		{Seq: 1, Text: "Breakpoint 1, main () at crash.c:5"},
+		{Seq: 2, Text: "5\t  int *p = 0;"},
+		{Seq: 3, Text: "(gdb) "},
+	}, ring.Tail(10), "the prompt is a partial last line")
+
+	// The rest of a partial line completes it; old lines leave the ring
+	ring.Write("next\n6\t  return *p;\n(gdb) ")
+	assert.Equal(t, "(gdb) next\n6\t  return *p;\n(gdb) ", JoinLines(ring.Tail(3)))
+	lines := ring.Tail(10)
+	assert.Equal(t, uint64(2), lines[0].Seq)
+	assert.Len(t, lines, 4, "three complete lines and the partial one")
+
+	assert.Equal(t, []OutputLine{{Seq: 3, Text: "(gdb) next"}}, ring.Since(3, 1))
+	assert.Equal(t, uint64(2), ring.Since(1, 0)[0].Seq, "lines that left the ring are skipped")
+	assert.Empty(t, ring.Since(6, 0))
+	assert.Empty(t, ring.Tail(0))
+}
@@ -0,0 +1,22 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputPipeline(t *testing.T) {
+	pipeline := newOutputPipeline()
+
+	// An escape sequence split between chunks is removed from the clean stream only
+	output := pipeline.process("\x1b[01;31mSegmentation fault\x1b[")
+	assert.Equal(t, "\x1b[01;31mSegmentation fault\x1b[", output.Raw)
+	assert.Equal(t, "Segmentation fault", output.Clean)
+	output = pipeline.process("0m at \x1b]8;;file:///src/crash.c\x07crash.c\x1b]8;;\x1b\\:5\n")
+	assert.Equal(t, " at crash.c:5\n", output.Clean)
+
+	output = pipeline.processLine("\x01\x1b[0m\x02(gdb) \x1b(Bbt")
+	assert.Equal(t, "\x01\x1b[0m\x02(gdb) \x1b(Bbt", output.Raw, "the terminal gets every byte")
+	assert.Equal(t, "(gdb) bt\n", output.Clean)
+}
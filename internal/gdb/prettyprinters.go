@@ -0,0 +1,129 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+var (
+	// prettyPrinterLocus matches the heading of the printers of a locus in `info
+	// pretty-printer` output, e.g. "objfile /usr/lib/libstdc++.so.6 pretty-printers:"
+	prettyPrinterLocus = regexp.MustCompile(`^(global|progspace .*|objfile .*) pretty-printers:$`)
+
+	// prettyPrinterLine matches a printer, indented by two spaces, or a subprinter, by four,
+	// e.g. "    std::vector [disabled]"
+	prettyPrinterLine = regexp.MustCompile(`^(  |    )(\S.*?)( \[disabled\])?$`)
+
+	// prettyPrintersCounted matches GDB's count after enabling or disabling printers, e.g.
+	// "163 of 165 printers enabled"
+	prettyPrintersCounted = regexp.MustCompile(`\d+ of \d+ printers enabled`)
+)
+
+// PrettyPrinter is a pretty-printer GDB loaded, e.g. libstdc++'s, Go's runtime-gdb.py or
+// Rust's, with the types it prints
+type PrettyPrinter struct {
+	Locus       string       `json:"locus"` // "global", or the objfile or progspace it came with, e.g. "objfile /usr/lib/libstdc++.so.6"
+	Name        string       `json:"name"`
+	Enabled     bool         `json:"enabled"`
+	Subprinters []Subprinter `json:"subprinters,omitempty"`
+}
+
+// Subprinter is what a pretty-printer prints one type with, e.g. libstdc++'s std::vector
+type Subprinter struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// PrettyPrinters lists the pretty-printers GDB loaded
+func (g *GDBService) PrettyPrinters() ([]PrettyPrinter, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("pretty-printers"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("info pretty-printer", g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	if err := pythonError(output); err != nil {
+		return nil, err
+	}
+	return ParsePrettyPrinters(output), nil
+}
+
+// EnablePrettyPrinters enables or disables the pretty-printers whose locus matches the
+// regular expression object and whose name matches name, "printer" or
+// "printer;subprinter". Empty expressions match every locus and printer. It returns GDB's
+// count of enabled printers.
+func (g *GDBService) EnablePrettyPrinters(enable bool, object, name string) (string, error) {
+	if !g.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("pretty-printers"); err != nil {
+		return "", err
+	}
+	command, err := EnablePrettyPrintersCommand(enable, object, name)
+	if err != nil {
+		return "", err
+	}
+	output, err := g.ExecuteCommandWithOutput(command, g.commandTimeout())
+	if err != nil {
+		return "", err
+	}
+	if err := pythonError(output); err != nil {
+		return "", err
+	}
+	count := prettyPrintersCounted.FindString(output)
+	if count == "" {
+		return "", fmt.Errorf("%w: %s", appErrors.ErrBadRequest, lastLines(strings.TrimSpace(output), 3))
+	}
+	return count, nil
+}
+
+// EnablePrettyPrintersCommand returns the GDB command enabling or disabling pretty-printers,
+// e.g. "disable pretty-printer global builtin;mpx_bound128"
+func EnablePrettyPrintersCommand(enable bool, object, name string) (string, error) {
+	if strings.ContainsAny(object+name, " \t\r\n") {
+		return "", fmt.Errorf("%w: the locus and name of pretty-printers are regular expressions without spaces", appErrors.ErrBadRequest)
+	}
+	command := "disable pretty-printer"
+	if enable {
+		command = "enable pretty-printer"
+	}
+	if object == "" && name != "" {
+		object = ".*"
+	}
+	return strings.TrimSpace(strings.Join([]string{command, object, name}, " ")), nil
+}
+
+// ParsePrettyPrinters reads `info pretty-printer` output
+func ParsePrettyPrinters(output string) []PrettyPrinter {
+	var printers []PrettyPrinter
+	locus := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		for strings.HasPrefix(line, "(gdb) ") {
+			line = strings.TrimPrefix(line, "(gdb) ")
+		}
+		if m := prettyPrinterLocus.FindStringSubmatch(line); m != nil {
+			locus = m[1]
+			continue
+		}
+		m := prettyPrinterLine.FindStringSubmatch(line)
+		if m == nil || locus == "" {
+			continue
+		}
+		enabled := m[3] == ""
+		if m[1] == "  " {
+			printers = append(printers, PrettyPrinter{Locus: locus, Name: m[2], Enabled: enabled})
+		} else if len(printers) > 0 {
+			last := &printers[len(printers)-1]
+			last.Subprinters = append(last.Subprinters, Subprinter{Name: m[2], Enabled: enabled})
+		}
+	}
+	return printers
+}
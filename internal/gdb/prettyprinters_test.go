@@ -0,0 +1,49 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// TestParsePrettyPrinters tests parsing of `info pretty-printer` output
+func TestParsePrettyPrinters(t *testing.T) {
+	printers := ParsePrettyPrinters(`(gdb) global pretty-printers:
+  builtin
+    mpx_bound128
+objfile /usr/lib/x86_64-linux-gnu/libstdc++.so.6 pretty-printers:
+  libstdc++-v6
+    std::map
+    std::vector [disabled]
+objfile /uploads/alice/server pretty-printers:
+  go-runtime [disabled]
+`)
+	require.Len(t, printers, 3)
+	assert.Equal(t, PrettyPrinter{Locus: "global", Name: "builtin", Enabled: true, Subprinters: []Subprinter{{Name: "mpx_bound128", Enabled: true}}}, printers[0])
+	assert.Equal(t, "objfile /usr/lib/x86_64-linux-gnu/libstdc++.so.6", printers[1].Locus)
+	assert.Equal(t, []Subprinter{{Name: "std::map", Enabled: true}, {Name: "std::vector"}}, printers[1].Subprinters)
+	assert.Equal(t, PrettyPrinter{Locus: "objfile /uploads/alice/server", Name: "go-runtime"}, printers[2])
+
+	assert.Empty(t, ParsePrettyPrinters("(gdb) \n"))
+}
+
+func TestEnablePrettyPrintersCommand(t *testing.T) {
+	for _, tc := range []struct {
+		enable       bool
+		object, name string
+		want         string
+	}{
+		{true, "", "", "enable pretty-printer"},
+		{false, "global", "builtin;mpx_bound128", "disable pretty-printer global builtin;mpx_bound128"},
+		{true, "", "libstdc\\+\\+-v6", "enable pretty-printer .* libstdc\\+\\+-v6"},
+		{false, "libstdc", "", "disable pretty-printer libstdc"},
+	} {
+		command, err := EnablePrettyPrintersCommand(tc.enable, tc.object, tc.name)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, command)
+	}
+	_, err := EnablePrettyPrintersCommand(true, "global\nshell id", "")
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+}
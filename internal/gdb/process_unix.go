@@ -0,0 +1,33 @@
+//go:build !windows
+
+package gdb
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ptySupported reports whether the program can be given a pseudo-terminal
+const ptySupported = true
+
+// startProcessGroup puts the debugger in a process group of its own, so stopping it stops
+// the programs it started and signals meant for the server do not reach it
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup terminates the debugger's process group, then the debugger itself if
+// it is still running
+func killProcessGroup(process *os.Process) {
+	if pgid, err := syscall.Getpgid(process.Pid); err == nil && pgid == process.Pid {
+		syscall.Kill(-pgid, syscall.SIGTERM)
+	}
+	process.Kill()
+}
+
+// interruptProcess sends the debugger SIGINT, which it passes on to the running program as
+// Ctrl-C in its terminal would
+func interruptProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGINT)
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package gdb
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// ptySupported reports whether the program can be given a pseudo-terminal. Windows has
+// no terminal devices for the debugger to open, so the program shares its console.
+const ptySupported = false
+
+// startProcessGroup puts the debugger in a process group of its own, so console control
+// events meant for the server do not reach it
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessGroup terminates the debugger and the programs it started. Windows has no
+// process group signals, so the process tree is ended with taskkill.
+func killProcessGroup(process *os.Process) {
+	exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid)).Run()
+	process.Kill()
+}
+
+// interruptProcess would interrupt the running program, but Windows cannot send a console
+// control event to a single process group from outside its console
+func interruptProcess(process *os.Process) error {
+	return errors.New("interrupting the program is not supported on Windows")
+}
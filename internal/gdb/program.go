@@ -0,0 +1,113 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// envName matches the name of an environment variable
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ProgramOptions describe how GDB runs the program: its arguments, environment, standard
+// input and working directory. They last until GDB is started on another program, across
+// automatic restarts.
+type ProgramOptions struct {
+	Args       []string          `json:"args,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	StdinFile  string            `json:"stdinFile,omitempty"`  // Path on the server of a file read as the program's input
+	WorkingDir string            `json:"workingDir,omitempty"` // As the program sees it
+}
+
+// IsZero reports whether the options leave the program as GDB runs it by default
+func (p ProgramOptions) IsZero() bool {
+	return len(p.Args) == 0 && len(p.Env) == 0 && p.StdinFile == "" && p.WorkingDir == ""
+}
+
+// Commands returns the GDB commands applying the options. The arguments and the input
+// redirection are quoted for the shell GDB starts the program with.
+func (p ProgramOptions) Commands() ([]string, error) {
+	values := append([]string{p.StdinFile, p.WorkingDir}, p.Args...)
+	for name, value := range p.Env {
+		if !envName.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid environment variable name %q", appErrors.ErrBadRequest, name)
+		}
+		values = append(values, value)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return nil, fmt.Errorf("%w: program arguments, environment and paths must not contain line breaks", appErrors.ErrBadRequest)
+		}
+	}
+
+	var commands []string
+	if len(p.Args) > 0 || p.StdinFile != "" {
+		words := make([]string, 0, len(p.Args)+2)
+		for _, arg := range p.Args {
+			words = append(words, shellQuote(arg))
+		}
+		if p.StdinFile != "" {
+			words = append(words, "<", shellQuote(p.StdinFile))
+		}
+		commands = append(commands, "set args "+strings.Join(words, " "))
+	}
+	names := make([]string, 0, len(p.Env))
+	for name := range p.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		commands = append(commands, fmt.Sprintf("set environment %s=%s", name, p.Env[name]))
+	}
+	if p.WorkingDir != "" {
+		commands = append(commands, "set cwd "+p.WorkingDir)
+	}
+	return commands, nil
+}
+
+// shellQuote quotes s as one word for the shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// StartProgram starts a new GDB process for the specified file, running the program with
+// the options. Any sourceDirs are added to GDB's source search path.
+func (g *GDBService) StartProgram(filePath string, program ProgramOptions, sourceDirs ...string) error {
+	if !program.IsZero() {
+		if err := g.requireGDB("program arguments, environment and input"); err != nil {
+			return err
+		}
+		if program.StdinFile != "" && g.config.Backend != "" && g.config.Backend != config.BackendLocal {
+			return fmt.Errorf("%w: an input file needs gdb.backend local, not %s", appErrors.ErrUnsupported, g.config.Backend)
+		}
+	}
+	commands, err := program.Commands()
+	if err != nil {
+		return err
+	}
+
+	g.processLock.Lock()
+	defer g.processLock.Unlock()
+
+	// Stop any existing GDB process
+	if g.isRunning {
+		g.stop()
+	}
+	g.breakpoints.Reset()
+	g.restarts = nil
+	g.program = commands
+	g.options = program
+	return g.start(filePath, sourceDirs)
+}
+
+// applyProgram sends GDB the commands applying the program's options. The caller holds
+// processLock.
+func (g *GDBService) applyProgram() {
+	for _, command := range g.program {
+		g.writeCommand(command)
+	}
+}
@@ -0,0 +1,43 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// TestProgramOptionsCommands tests the GDB commands applying a program's options, with
+// arguments and input quoted for the shell
+func TestProgramOptionsCommands(t *testing.T) {
+	commands, err := ProgramOptions{
+		Args:       []string{"-v", "it's", "$HOME", ""},
+		Env:        map[string]string{"MALLOC_CHECK_": "3", "LANG": "C.UTF-8"},
+		StdinFile:  "/uploads/sources/s1/inputs/crash 1.txt",
+		WorkingDir: "/tmp/run",
+	}.Commands()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`set args '-v' 'it'\''s' '$HOME' '' < '/uploads/sources/s1/inputs/crash 1.txt'`,
+		"set environment LANG=C.UTF-8",
+		"set environment MALLOC_CHECK_=3",
+		"set cwd /tmp/run",
+	}, commands)
+
+	commands, err = ProgramOptions{StdinFile: "in.txt"}.Commands()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"set args < 'in.txt'"}, commands)
+
+	commands, err = ProgramOptions{}.Commands()
+	require.NoError(t, err)
+	assert.Empty(t, commands)
+	assert.True(t, ProgramOptions{}.IsZero())
+
+	_, err = ProgramOptions{Args: []string{"a\nshell id"}}.Commands()
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+	_, err = ProgramOptions{Env: map[string]string{"A=B": "c"}}.Commands()
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+	_, err = ProgramOptions{WorkingDir: "/tmp\nshell id"}.Commands()
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+}
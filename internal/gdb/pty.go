@@ -0,0 +1,117 @@
+package gdb
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/creack/pty"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Initial size of the inferior's terminal
+const (
+	defaultTerminalRows = 24
+	defaultTerminalCols = 80
+)
+
+// inferiorTerminal is the pseudo-terminal the debugged program runs on, so programs that
+// read stdin or draw with curses behave as they would in a real terminal. GDB opens the
+// terminal by name for each run; the server keeps the terminal side open between runs so
+// reads from the controlling side do not fail while no program is running.
+type inferiorTerminal struct {
+	master *os.File
+	slave  *os.File
+}
+
+// openInferiorTerminal allocates a pseudo-terminal of the given size for the inferior
+func openInferiorTerminal(size pty.Winsize) (*inferiorTerminal, error) {
+	master, slave, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pseudo-terminal: %w", err)
+	}
+	if err := pty.Setsize(master, &size); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, fmt.Errorf("failed to size pseudo-terminal: %w", err)
+	}
+	return &inferiorTerminal{master: master, slave: slave}, nil
+}
+
+// Name returns the device path passed to GDB's -tty option
+func (t *inferiorTerminal) Name() string {
+	return t.slave.Name()
+}
+
+// Resize changes the terminal's size. The kernel signals SIGWINCH to the program, so
+// full-screen programs redraw.
+func (t *inferiorTerminal) Resize(rows, cols int) error {
+	return pty.Setsize(t.master, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+}
+
+// Write sends input to the program as if typed at its terminal
+func (t *inferiorTerminal) Write(data []byte) (int, error) {
+	return t.master.Write(data)
+}
+
+// pump sends the program's output to emit in chunks as it arrives, without waiting for
+// whole lines, until the terminal is closed
+func (t *inferiorTerminal) pump(emit func(string)) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := t.master.Read(buf)
+		if n > 0 {
+			emit(string(buf[:n]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Close releases both sides of the terminal
+func (t *inferiorTerminal) Close() error {
+	t.slave.Close()
+	return t.master.Close()
+}
+
+// WriteProgramInput sends input to the debugged program's terminal
+func (g *GDBService) WriteProgramInput(input string) error {
+	g.processLock.Lock()
+	terminal, running := g.terminal, g.isRunning
+	g.processLock.Unlock()
+
+	if !running {
+		return appErrors.ErrGDBNotRunning
+	}
+	if terminal == nil {
+		return fmt.Errorf("%w: the program has no terminal (gdb.pty is disabled)", appErrors.ErrUnsupported)
+	}
+	if _, err := terminal.Write([]byte(input)); err != nil {
+		return appErrors.Wrap(err, "failed to send input to the program")
+	}
+	return nil
+}
+
+// ResizeTerminal sets the size of the debugged program's terminal. The size is kept for
+// the terminals of later sessions, so a client may report its size before GDB starts.
+func (g *GDBService) ResizeTerminal(rows, cols int) error {
+	g.processLock.Lock()
+	defer g.processLock.Unlock()
+
+	g.terminalSize = pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)}
+	if g.terminal == nil {
+		return nil
+	}
+	if err := g.terminal.Resize(rows, cols); err != nil {
+		return appErrors.Wrap(err, "failed to resize the program's terminal")
+	}
+	return nil
+}
+
+// terminalSizeOrDefault returns the last size reported by a client, or 80x24
+func (g *GDBService) terminalSizeOrDefault() pty.Winsize {
+	if g.terminalSize.Rows == 0 || g.terminalSize.Cols == 0 {
+		return pty.Winsize{Rows: defaultTerminalRows, Cols: defaultTerminalCols}
+	}
+	return g.terminalSize
+}
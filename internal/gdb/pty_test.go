@@ -0,0 +1,60 @@
+package gdb
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestInferiorTerminal(t *testing.T) {
+	terminal, err := openInferiorTerminal(pty.Winsize{Rows: 24, Cols: 80})
+	if err != nil {
+		t.Skipf("pseudo-terminals unavailable: %v", err)
+	}
+	defer terminal.Close()
+
+	var mutex sync.Mutex
+	var output strings.Builder
+	go terminal.pump(func(chunk string) {
+		mutex.Lock()
+		output.WriteString(chunk)
+		mutex.Unlock()
+	})
+
+	// What the program writes to its terminal reaches the pump
+	_, err = terminal.slave.WriteString("Enter a number: ")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return strings.Contains(output.String(), "Enter a number: ")
+	}, time.Second, 10*time.Millisecond)
+
+	// Resizing is visible to the program
+	require.NoError(t, terminal.Resize(40, 132))
+	rows, cols, err := pty.Getsize(terminal.slave)
+	require.NoError(t, err)
+	assert.Equal(t, []int{40, 132}, []int{rows, cols})
+
+	// Input is readable by the program as a line
+	_, err = terminal.Write([]byte("42\n"))
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := terminal.slave.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "42\n", string(buf[:n]))
+}
+
+func TestWriteProgramInputNotRunning(t *testing.T) {
+	g := &GDBService{}
+	assert.ErrorIs(t, g.WriteProgramInput("x"), appErrors.ErrGDBNotRunning)
+
+	g.isRunning = true
+	assert.ErrorIs(t, g.WriteProgramInput("x"), appErrors.ErrUnsupported)
+}
@@ -0,0 +1,74 @@
+package gdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+var (
+	// pythonFailed matches GDB's report of a Python script that raised, e.g.
+	// "Python Exception <class 'gdb.error'>: No symbol "buf" in current context."
+	pythonFailed = regexp.MustCompile(`(?m)^(?:\(gdb\) )*(Python Exception .*|Error while executing Python code\.)$`)
+
+	// pythonMissing matches the refusal of a GDB built without Python
+	pythonMissing = regexp.MustCompile(`Python scripting is not supported in this copy of GDB\.`)
+)
+
+// PythonCommand returns the one-line GDB command running a Python script with args, which
+// the script reads as the list gogdbllm_args. The script travels base64-encoded in the
+// command itself, so it runs wherever GDB does, e.g. in a session's container, and
+// tracebacks name it.
+func PythonCommand(name, source string, args []string) string {
+	if args == nil {
+		args = []string{}
+	}
+	encodedArgs, _ := json.Marshal(args)
+	return fmt.Sprintf(`python import base64, json; exec(compile(base64.b64decode("%s").decode(), "%s", "exec"), {"__name__": "__main__", "gogdbllm_args": json.loads(base64.b64decode("%s").decode())})`,
+		base64.StdEncoding.EncodeToString([]byte(source)), pythonString(name),
+		base64.StdEncoding.EncodeToString(encodedArgs))
+}
+
+// pythonString escapes s for a double-quoted Python string on one line
+func pythonString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// RunPython runs a Python script in GDB with args and returns what it printed. A script
+// that raised returns its output with an error naming the exception.
+func (g *GDBService) RunPython(name, source string, args []string) (string, error) {
+	if !g.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("Python scripts"); err != nil {
+		return "", err
+	}
+	output, err := g.ExecuteCommandWithOutput(PythonCommand(name, source, args), g.scriptTimeout())
+	if err != nil {
+		return "", err
+	}
+	return output, pythonError(output)
+}
+
+// scriptTimeout is how long RunPython collects a script's output, in seconds
+func (g *GDBService) scriptTimeout() int {
+	if g.config == nil || g.config.Scripts.Timeout <= 0 {
+		return g.commandTimeout()
+	}
+	return g.config.Scripts.Timeout
+}
+
+// pythonError returns why a script failed from its output, or nil if it did not
+func pythonError(output string) error {
+	if pythonMissing.MatchString(output) {
+		return fmt.Errorf("%w: GDB was built without Python", appErrors.ErrUnsupported)
+	}
+	if match := pythonFailed.FindStringSubmatch(output); match != nil {
+		return fmt.Errorf("%w: %s", appErrors.ErrGDBCommandFailed, match[1])
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package gdb
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// TestPythonCommand tests that scripts and their arguments travel intact on one line
+func TestPythonCommand(t *testing.T) {
+	source := "import gdb\nprint(\"args:\", gogdbllm_args)\n"
+	command := PythonCommand(`my"script`, source, []string{"buf", "$rsp + 8"})
+	assert.NotContains(t, command, "\n")
+	assert.True(t, strings.HasPrefix(command, "python "))
+	assert.Contains(t, command, `"my\"script"`)
+
+	encoded := regexp.MustCompile(`b64decode\("([^"]*)"\)`).FindAllStringSubmatch(command, -1)
+	require.Len(t, encoded, 2)
+	decoded, err := base64.StdEncoding.DecodeString(encoded[0][1])
+	require.NoError(t, err)
+	assert.Equal(t, source, string(decoded))
+	decoded, err = base64.StdEncoding.DecodeString(encoded[1][1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `["buf", "$rsp + 8"]`, string(decoded))
+
+	assert.Contains(t, PythonCommand("s", "pass", nil), base64.StdEncoding.EncodeToString([]byte("[]")), "no arguments is an empty list")
+}
+
+// TestPythonError tests recognizing failed scripts in GDB's output
+func TestPythonError(t *testing.T) {
+	assert.NoError(t, pythonError("0x00007fffffffe000  48 65 6c 6c 6f  Hello\n"))
+
+	err := pythonError("Traceback (most recent call last):\n  File \"hexdump\", line 9, in <module>\n(gdb) Python Exception <class 'gdb.error'>: No symbol \"buf\" in current context.\nError while executing Python code.\n")
+	assert.ErrorIs(t, err, appErrors.ErrGDBCommandFailed)
+	assert.Contains(t, err.Error(), `No symbol "buf"`)
+
+	assert.ErrorIs(t, pythonError("Python scripting is not supported in this copy of GDB.\n"), appErrors.ErrUnsupported)
+}
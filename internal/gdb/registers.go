@@ -0,0 +1,173 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// resumeCommands are the commands, with their abbreviations, that let the program run,
+// so its registers may differ when it stops again
+var resumeCommands = map[string]bool{
+	"run": true, "r": true, "start": true, "starti": true,
+	"continue": true, "c": true, "cont": true, "fg": true,
+	"next": true, "n": true, "step": true, "s": true,
+	"nexti": true, "ni": true, "stepi": true, "si": true,
+	"finish": true, "fin": true, "until": true, "u": true, "advance": true,
+	"jump": true, "j": true, "signal": true, "return": true,
+	"reverse-continue": true, "rc": true, "reverse-next": true, "rn": true,
+	"reverse-step": true, "rs": true, "reverse-nexti": true, "rni": true,
+	"reverse-stepi": true, "rsi": true, "reverse-finish": true,
+}
+
+// registerLine matches a line of `info registers` output such as
+// "rax            0x1c                28", "eflags         0x246               [ IF ZF PF ]"
+// or a vector register's "xmm0           {v4_float = {0x0, 0x0, 0x0, 0x0}, ...}"
+var registerLine = regexp.MustCompile(`^(?:\(gdb\) )*([a-z][a-z0-9_]*)\s+(\{.*\}|0x[0-9a-fA-F]+|-?[0-9][0-9a-fA-F.e+-]*|<[a-z ]+>)\s*(.*)$`)
+
+// Register is a register's value at a stop of the program
+type Register struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`             // As GDB shows it in hex, e.g. "0x1c"
+	Natural  string `json:"natural,omitempty"` // In its natural format, e.g. "28" or "[ IF ZF PF ]"
+	Changed  bool   `json:"changed"`
+	Previous string `json:"previous,omitempty"` // The value at the previous stop, when it changed
+}
+
+// Registers are the registers read at a stop
+type Registers struct {
+	Stop      int        `json:"stop"` // Counts the times the program was resumed
+	Registers []Register `json:"registers"`
+}
+
+// Changed returns the registers that changed since the previous stop
+func (r *Registers) Changed() []Register {
+	var changed []Register
+	for _, register := range r.Registers {
+		if register.Changed {
+			changed = append(changed, register)
+		}
+	}
+	return changed
+}
+
+// RegisterTracker follows register values across the program's stops, from the commands
+// sent to GDB and the registers read, so a read can mark the registers that changed since
+// the last stop they were read at
+type RegisterTracker struct {
+	stops    int               // Resume commands sent
+	stop     int               // The stop current was read at
+	current  map[string]string // Values read at stop
+	previous map[string]string // Values read at the stop before
+	mutex    sync.Mutex
+}
+
+// NewRegisterTracker creates a tracker that has seen no registers
+func NewRegisterTracker() *RegisterTracker {
+	return &RegisterTracker{}
+}
+
+// Command records a command sent to GDB
+func (t *RegisterTracker) Command(command string) {
+	words := strings.Fields(command)
+	if len(words) == 0 || !resumeCommands[words[0]] {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stops++
+}
+
+// Record records registers read at the current stop and marks the ones whose values
+// differ from the previous stop's
+func (t *RegisterTracker) Record(registers []Register) *Registers {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.current == nil || t.stop != t.stops {
+		if t.current != nil {
+			t.previous = t.current
+		}
+		t.current = make(map[string]string, len(registers))
+		t.stop = t.stops
+	}
+	for i := range registers {
+		register := &registers[i]
+		t.current[register.Name] = register.Value
+		if previous, ok := t.previous[register.Name]; ok && previous != register.Value {
+			register.Changed = true
+			register.Previous = previous
+		}
+	}
+	return &Registers{Stop: t.stops, Registers: registers}
+}
+
+// Reset forgets all stops, for a new program
+func (t *RegisterTracker) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stops, t.stop = 0, 0
+	t.current, t.previous = nil, nil
+}
+
+// IsRegisterCommand reports whether command lists registers, e.g. "info registers rip"
+// or "i r"
+func IsRegisterCommand(command string) bool {
+	words := strings.Fields(command)
+	if len(words) < 2 || (words[0] != "info" && words[0] != "i") {
+		return false
+	}
+	return strings.HasPrefix("registers", words[1]) || (len(words[1]) > 1 && strings.HasPrefix("all-registers", words[1]))
+}
+
+// ParseRegisters reads the registers in `info registers` output
+func ParseRegisters(output string) []Register {
+	var registers []Register
+	for _, line := range strings.Split(output, "\n") {
+		match := registerLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		registers = append(registers, Register{Name: match[1], Value: match[2], Natural: strings.TrimSpace(match[3])})
+	}
+	return registers
+}
+
+// Registers reads the registers of the selected frame, the general ones or, with all,
+// the floating point and vector ones too, and marks those that changed since the last stop
+func (g *GDBService) Registers(all bool) (*Registers, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("the register view"); err != nil {
+		return nil, err
+	}
+
+	command := "info registers"
+	if all {
+		command = "info all-registers"
+	}
+	output, err := g.ExecuteCommandWithOutput(command, g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	registers := ParseRegisters(output)
+	if len(registers) == 0 {
+		return nil, fmt.Errorf("%w: no registers: %s", appErrors.ErrBadRequest, strings.TrimSpace(output))
+	}
+	return g.registers.Record(registers), nil
+}
+
+// RecordRegisters records the registers in the output of a register command run some
+// other way, e.g. by the assistant, and returns them marked as Registers does, or nil if
+// the output has none
+func (g *GDBService) RecordRegisters(output string) *Registers {
+	registers := ParseRegisters(output)
+	if len(registers) == 0 {
+		return nil
+	}
+	return g.registers.Record(registers)
+}
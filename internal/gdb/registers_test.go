@@ -0,0 +1,79 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRegisters tests parsing of `info registers` and `info all-registers` output
+func TestParseRegisters(t *testing.T) {
+	output := `(gdb) rax            0x1c                28
+rip            0x555555555131      0x555555555131 <main+8>
+eflags         0x246               [ IF ZF PF ]
+st0            0                   (raw 0x00000000000000000000)
+xmm0           {v4_float = {0x0, 0x0, 0x0, 0x0}, v2_int64 = {0x0, 0x0}}
+k0             <unavailable>
+The program being debugged has been started already.
+`
+	registers := ParseRegisters(output)
+	require.Len(t, registers, 6)
+	assert.Equal(t, Register{Name: "rax", Value: "0x1c", Natural: "28"}, registers[0])
+	assert.Equal(t, "0x555555555131 <main+8>", registers[1].Natural)
+	assert.Equal(t, "[ IF ZF PF ]", registers[2].Natural)
+	assert.Equal(t, "0", registers[3].Value)
+	assert.Equal(t, "{v4_float = {0x0, 0x0, 0x0, 0x0}, v2_int64 = {0x0, 0x0}}", registers[4].Value)
+	assert.Equal(t, "<unavailable>", registers[5].Value)
+
+	assert.Empty(t, ParseRegisters("The program has no registers now.\n"))
+	assert.Empty(t, ParseRegisters("#0  main () at crash.c:5\n5\t  int x = 0;\nx = 5\n"))
+}
+
+// TestRegisterTracker tests that registers are marked changed against the previous stop,
+// not against earlier reads at the same stop
+func TestRegisterTracker(t *testing.T) {
+	tracker := NewRegisterTracker()
+	read := func(rax, rip string) *Registers {
+		return tracker.Record([]Register{{Name: "rax", Value: rax}, {Name: "rip", Value: rip}})
+	}
+
+	tracker.Command("run")
+	registers := read("0x1", "0x1000")
+	assert.Equal(t, 1, registers.Stop)
+	assert.Empty(t, registers.Changed())
+
+	// Read again at the same stop: nothing to compare with yet
+	assert.Empty(t, read("0x1", "0x1000").Changed())
+
+	tracker.Command("x/4xb $sp")
+	tracker.Command("next")
+	registers = read("0x1", "0x1004")
+	assert.Equal(t, 2, registers.Stop)
+	require.Len(t, registers.Changed(), 1)
+	assert.Equal(t, Register{Name: "rip", Value: "0x1004", Changed: true, Previous: "0x1000"}, registers.Changed()[0])
+
+	// A register set at the stop is compared with the previous stop too
+	registers = read("0x2", "0x1004")
+	assert.Len(t, registers.Changed(), 2)
+
+	// Stops where registers were not read are skipped
+	tracker.Command("continue")
+	tracker.Command("c")
+	registers = read("0x2", "0x1010")
+	assert.Equal(t, 4, registers.Stop)
+	assert.Equal(t, []Register{{Name: "rip", Value: "0x1010", Changed: true, Previous: "0x1004"}}, registers.Changed())
+
+	tracker.Reset()
+	assert.Empty(t, read("0x3", "0x2000").Changed())
+}
+
+// TestIsRegisterCommand tests recognising register commands by their abbreviations
+func TestIsRegisterCommand(t *testing.T) {
+	for _, command := range []string{"info registers", "i r", "info reg rip", "info all-registers", "i all"} {
+		assert.True(t, IsRegisterCommand(command), command)
+	}
+	for _, command := range []string{"info", "info locals", "info frame", "print $rip", "i a"} {
+		assert.False(t, IsRegisterCommand(command), command)
+	}
+}
@@ -0,0 +1,333 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Events RunUntil waits for
+const (
+	EventStop       = "stop"       // Any stop
+	EventBreakpoint = "breakpoint" // A breakpoint, watchpoint or catchpoint
+	EventSignal     = "signal"     // A signal, e.g. a crash
+	EventExit       = "exit"       // The end of the program
+)
+
+// Reasons the program stopped
+const (
+	StopBreakpoint = "breakpoint"
+	StopWatchpoint = "watchpoint"
+	StopSignal     = "signal"
+	StopExited     = "exited"
+	StopStopped    = "stopped" // At its first instruction, after starti
+	StopTimeout    = "timeout"
+)
+
+const (
+	// runUntilPoll is how often RunUntil reads the output
+	runUntilPoll = 50 * time.Millisecond
+	// runUntilSettle is how long output must be quiet after a stop before the stop's
+	// report, e.g. its location, is taken as complete
+	runUntilSettle = 200 * time.Millisecond
+	// interruptWait is how long RunUntil waits for the program to stop once interrupted
+	interruptWait = 3 * time.Second
+)
+
+// continueCommands are the commands, with their abbreviations, that let the program run
+// until something stops it, always reporting why
+var continueCommands = map[string]bool{
+	"continue": true, "c": true, "cont": true, "fg": true,
+	"run": true, "r": true, "start": true, "starti": true,
+}
+
+var (
+	// "Breakpoint 2, main () at crash.c:5", or in a multithreaded program
+	// `Thread 3 "worker" hit Breakpoint 2, worker (arg=0x0) at crash.c:9`, or for a
+	// catchpoint "Catchpoint 1 (exception thrown), 0x00007ffff7e4a672 in __cxa_throw ()"
+	breakpointStop = regexp.MustCompile(`^(?:Thread \d+ (?:"[^"]*" )?hit )?(?:Temporary breakpoint|Breakpoint|Catchpoint) (\d+)(?: \([^)]*\))?, (.*)$`)
+	// "Hardware watchpoint 3: total", followed by its old and new values
+	watchpointStop = regexp.MustCompile(`^(?:Thread \d+ (?:"[^"]*" )?hit )?(?:Hardware |Software )?(?:read |access \(read/write\) )?[Ww]atchpoint (\d+): (.*)$`)
+	// "Program received signal SIGSEGV, Segmentation fault." or
+	// `Thread 2 "worker" received signal SIGSEGV, Segmentation fault.`
+	signalStop = regexp.MustCompile(`^(?:Program|Thread \d+(?: "[^"]*")?) received signal (\w+), (.+?)\.?$`)
+	// "Program terminated with signal SIGSEGV, Segmentation fault."
+	signalExit = regexp.MustCompile(`^Program terminated with signal (\w+), (.+?)\.?$`)
+	// "[Inferior 1 (process 4242) exited normally]" or "... exited with code 01]"
+	programExit = regexp.MustCompile(`^\[Inferior \d+ \(process \d+\) exited (?:normally|with code ([0-7]+))\]`)
+	// "Program stopped." after starti
+	programStopped = regexp.MustCompile(`^Program stopped\.`)
+)
+
+// RunUntilOptions describe a RunUntil call
+type RunUntilOptions struct {
+	Event   string        // What to wait for; EventStop if empty
+	Timeout time.Duration // gdb.run_until.timeout if not positive, at most gdb.run_until.max_timeout
+	Command string        // The command letting the program run; "continue", or "run" if it has not started
+}
+
+// StopReason tells why the program stopped
+type StopReason struct {
+	Command     string        `json:"command"` // The command that let the program run
+	Reason      string        `json:"reason"`
+	Breakpoint  int           `json:"breakpoint,omitempty"` // Number of the breakpoint or watchpoint hit
+	Signal      string        `json:"signal,omitempty"`
+	Description string        `json:"description,omitempty"` // e.g. "Segmentation fault"
+	ExitCode    *int          `json:"exitCode,omitempty"`
+	Location    string        `json:"location,omitempty"`    // e.g. "main () at crash.c:5"
+	Interrupted bool          `json:"interrupted,omitempty"` // Stopped with SIGINT at the timeout
+	Skipped     int           `json:"skipped,omitempty"`     // Stops that were not the event and were continued from
+	Elapsed     time.Duration `json:"elapsed"`
+	Output      string        `json:"output"`
+}
+
+// String describes the stop in a line for the assistant
+func (s *StopReason) String() string {
+	var sb strings.Builder
+	switch s.Reason {
+	case StopBreakpoint, StopWatchpoint:
+		fmt.Fprintf(&sb, "Stopped at %s %d", s.Reason, s.Breakpoint)
+	case StopSignal:
+		fmt.Fprintf(&sb, "Stopped by signal %s (%s)", s.Signal, s.Description)
+	case StopExited:
+		switch {
+		case s.Signal != "":
+			fmt.Fprintf(&sb, "The program was killed by signal %s (%s)", s.Signal, s.Description)
+		case s.ExitCode != nil:
+			fmt.Fprintf(&sb, "The program exited with code %d", *s.ExitCode)
+		default:
+			sb.WriteString("The program exited")
+		}
+	case StopTimeout:
+		if s.Interrupted {
+			fmt.Fprintf(&sb, "Still running after %s, interrupted", s.Elapsed.Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(&sb, "Still running after %s and could not be interrupted", s.Elapsed.Round(time.Millisecond))
+		}
+	default:
+		sb.WriteString("Stopped")
+	}
+	if s.Location != "" {
+		fmt.Fprintf(&sb, " in %s", s.Location)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(&sb, ", after continuing from %d other stops", s.Skipped)
+	}
+	return sb.String()
+}
+
+// matches reports whether the stop is the event waited for
+func (s *StopReason) matches(event string) bool {
+	switch event {
+	case EventBreakpoint:
+		return s.Reason == StopBreakpoint || s.Reason == StopWatchpoint
+	case EventSignal:
+		return s.Reason == StopSignal || (s.Reason == StopExited && s.Signal != "")
+	case EventExit:
+		return s.Reason == StopExited
+	}
+	return true
+}
+
+// IsContinueCommand reports whether command lets the program run until something stops
+// it, so RunUntil can run it
+func IsContinueCommand(command string) bool {
+	words := strings.Fields(command)
+	return len(words) > 0 && continueCommands[words[0]]
+}
+
+// RunUntil lets the program run until the event, continuing from other stops, or until
+// the timeout, when it interrupts the program so GDB takes commands again. It never
+// leaves the program running: the assistant can continue without risking a hang.
+func (g *GDBService) RunUntil(opts RunUntilOptions) (*StopReason, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("run until"); err != nil {
+		return nil, err
+	}
+	switch opts.Event {
+	case "":
+		opts.Event = EventStop
+	case EventStop, EventBreakpoint, EventSignal, EventExit:
+	default:
+		return nil, fmt.Errorf("%w: unknown event %q; use stop, breakpoint, signal or exit", appErrors.ErrBadRequest, opts.Event)
+	}
+	command := strings.TrimSpace(opts.Command)
+	if command == "" {
+		command = "continue"
+	}
+	if !IsContinueCommand(command) || strings.ContainsAny(command, "\r\n") {
+		return nil, fmt.Errorf("%w: %q does not continue the program", appErrors.ErrBadRequest, command)
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = g.config.RunUntil.Timeout
+	}
+	if max := g.config.RunUntil.MaxTimeout; max > 0 && timeout > max {
+		timeout = max
+	}
+
+	started := time.Now()
+	deadline := started.Add(timeout)
+	g.StartOutputCapture()
+	defer g.StopOutputCapture()
+	if err := g.SendCommand(command); err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+	read, skipped := 0, 0
+	for {
+		stop, next, notRunning := g.awaitStop(read, deadline, &output)
+		read = next
+		if notRunning && !strings.HasPrefix(command, "r") {
+			// Nothing to continue: start the program instead
+			command = "run"
+			if err := g.SendCommand(command); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if notRunning {
+			return nil, fmt.Errorf("%w: the program could not be run: %s", appErrors.ErrBadRequest, lastLines(output.String(), 3))
+		}
+		if stop == nil {
+			stop = g.interruptForTimeout(read, &output)
+		}
+		if stop.Reason == StopTimeout || stop.Reason == StopExited || stop.matches(opts.Event) {
+			stop.Command = command
+			stop.Skipped = skipped
+			stop.Elapsed = time.Since(started)
+			stop.Output = strings.TrimSpace(output.String())
+			return stop, nil
+		}
+		skipped++
+		if err := g.SendCommand("continue"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// awaitStop reads the capture from chunk read on into output until the program stops, GDB
+// reports it is not running, or the deadline passes (nil stop). It returns the chunks read.
+func (g *GDBService) awaitStop(read int, deadline time.Time, output *strings.Builder) (*StopReason, int, bool) {
+	var stop *StopReason
+	var quietSince time.Time
+	start := output.Len() // Earlier stops are in the output before start
+	for {
+		chunk, next := g.capturedSince(read)
+		read = next
+		if chunk != "" {
+			output.WriteString(chunk)
+			quietSince = time.Now()
+			if strings.Contains(chunk, "The program is not being run.") {
+				return nil, read, true
+			}
+		}
+		// Parse again after each chunk to take the location GDB prints after the stop line
+		stop = parseStop(output.String()[start:])
+		now := time.Now()
+		if stop != nil && now.Sub(quietSince) >= runUntilSettle {
+			return stop, read, false
+		}
+		if stop == nil && now.After(deadline) {
+			return nil, read, false
+		}
+		time.Sleep(runUntilPoll)
+	}
+}
+
+// interruptForTimeout interrupts the program after the deadline and waits for it to stop
+func (g *GDBService) interruptForTimeout(read int, output *strings.Builder) *StopReason {
+	timedOut := &StopReason{Reason: StopTimeout}
+	if err := g.interrupt(); err != nil {
+		timedOut.Description = err.Error()
+		return timedOut
+	}
+	if stop, _, _ := g.awaitStop(read, time.Now().Add(interruptWait), output); stop != nil {
+		timedOut.Interrupted = true
+		timedOut.Location = stop.Location
+	}
+	return timedOut
+}
+
+// interrupt stops the running program as Ctrl-C does: through its terminal if it has
+// one, otherwise through GDB
+func (g *GDBService) interrupt() error {
+	g.processLock.Lock()
+	terminal, cmd := g.terminal, g.cmd
+	g.processLock.Unlock()
+	if terminal != nil {
+		_, err := terminal.Write([]byte("\x03"))
+		return err
+	}
+	if cmd == nil || cmd.Process == nil {
+		return appErrors.ErrGDBNotRunning
+	}
+	return interruptProcess(cmd.Process)
+}
+
+// parseStop reads the last stop GDB reports in output, with the location it prints after
+// it, or returns nil if the program has not stopped
+func parseStop(output string) *StopReason {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := trimPrompts(lines[i])
+		var stop *StopReason
+		if m := breakpointStop.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			stop = &StopReason{Reason: StopBreakpoint, Breakpoint: n, Location: m[2]}
+		} else if m := watchpointStop.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			stop = &StopReason{Reason: StopWatchpoint, Breakpoint: n, Description: m[2]}
+			stop.Location = frameAfter(lines[i+1:])
+		} else if m := signalStop.FindStringSubmatch(line); m != nil {
+			stop = &StopReason{Reason: StopSignal, Signal: m[1], Description: m[2], Location: frameAfter(lines[i+1:])}
+		} else if m := signalExit.FindStringSubmatch(line); m != nil {
+			stop = &StopReason{Reason: StopExited, Signal: m[1], Description: m[2]}
+		} else if m := programExit.FindStringSubmatch(line); m != nil {
+			code := 0
+			if m[1] != "" {
+				parsed, _ := strconv.ParseInt(m[1], 8, 32)
+				code = int(parsed)
+			}
+			stop = &StopReason{Reason: StopExited, ExitCode: &code}
+		} else if programStopped.MatchString(line) {
+			stop = &StopReason{Reason: StopStopped, Location: frameAfter(lines[i+1:])}
+		}
+		if stop != nil {
+			return stop
+		}
+	}
+	return nil
+}
+
+// frameAfter returns the frame GDB prints after a stop line, e.g.
+// "0x0000555555555139 in main () at crash.c:5" or "main () at crash.c:5"
+func frameAfter(lines []string) string {
+	for _, line := range lines {
+		line = trimPrompts(line)
+		if line == "" || strings.HasPrefix(line, "Old value") || strings.HasPrefix(line, "New value") || strings.HasPrefix(line, "Value = ") {
+			continue
+		}
+		if strings.Contains(line, " in ") || strings.Contains(line, " at ") || strings.Contains(line, " () ") {
+			return line
+		}
+		return ""
+	}
+	return ""
+}
+
+// trimPrompts removes GDB prompts and surrounding space from a line of output
+func trimPrompts(line string) string {
+	line = strings.TrimSpace(line)
+	for strings.HasPrefix(line, "(gdb)") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "(gdb)"))
+	}
+	return line
+}
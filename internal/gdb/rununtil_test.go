@@ -0,0 +1,88 @@
+package gdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseStop tests reading why the program stopped from GDB's reports
+func TestParseStop(t *testing.T) {
+	stop := parseStop("(gdb) Continuing.\n\nBreakpoint 2, main () at crash.c:5\n5\t  int x = 0;\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, StopReason{Reason: StopBreakpoint, Breakpoint: 2, Location: "main () at crash.c:5"}, *stop)
+
+	stop = parseStop("[Switching to Thread 0x7ffff7587640 (LWP 12347)]\n\nThread 3 \"worker\" hit Breakpoint 1, worker (arg=0x0) at crash.c:9\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, 1, stop.Breakpoint)
+	assert.Equal(t, "worker (arg=0x0) at crash.c:9", stop.Location)
+
+	stop = parseStop("Continuing.\n\nHardware watchpoint 3: total\n\nOld value = 1\nNew value = 2\nsum (n=4) at sum.c:7\n7\t  }\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, StopReason{Reason: StopWatchpoint, Breakpoint: 3, Description: "total", Location: "sum (n=4) at sum.c:7"}, *stop)
+
+	stop = parseStop("Continuing.\n\nProgram received signal SIGSEGV, Segmentation fault.\n0x000055555555513d in main () at crash.c:5\n5\t  *p = 1;\n(gdb) ")
+	require.NotNil(t, stop)
+	assert.Equal(t, StopReason{Reason: StopSignal, Signal: "SIGSEGV", Description: "Segmentation fault", Location: "0x000055555555513d in main () at crash.c:5"}, *stop)
+	assert.True(t, stop.matches(EventSignal))
+	assert.False(t, stop.matches(EventBreakpoint))
+
+	stop = parseStop("Thread 2 \"worker\" received signal SIGABRT, Aborted.\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, "SIGABRT", stop.Signal)
+
+	stop = parseStop("Continuing.\n[Inferior 1 (process 4242) exited with code 012]\n")
+	require.NotNil(t, stop)
+	require.NotNil(t, stop.ExitCode)
+	assert.Equal(t, 10, *stop.ExitCode, "GDB prints exit codes in octal")
+	assert.True(t, stop.matches(EventExit))
+	assert.False(t, stop.matches(EventSignal))
+
+	stop = parseStop("\nProgram terminated with signal SIGKILL, Killed.\nThe program no longer exists.\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, StopExited, stop.Reason)
+	assert.True(t, stop.matches(EventSignal))
+
+	// The last stop is the one reported
+	stop = parseStop("Breakpoint 1, main () at crash.c:3\n(gdb) Continuing.\n[Inferior 1 (process 4242) exited normally]\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, 0, *stop.ExitCode)
+
+	stop = parseStop("Catchpoint 1 (exception thrown), 0x00007ffff7e4a672 in __cxa_throw () from /lib/libstdc++.so.6\n")
+	require.NotNil(t, stop)
+	assert.Equal(t, StopBreakpoint, stop.Reason)
+	assert.Equal(t, "0x00007ffff7e4a672 in __cxa_throw () from /lib/libstdc++.so.6", stop.Location)
+
+	assert.Nil(t, parseStop("Continuing.\n"))
+	assert.Nil(t, parseStop("Breakpoint 1 at 0x1139: file crash.c, line 5.\n"))
+}
+
+// TestStopReasonString tests the stop summaries given to the assistant
+func TestStopReasonString(t *testing.T) {
+	code := 1
+	for _, tc := range []struct {
+		stop StopReason
+		want string
+	}{
+		{StopReason{Reason: StopBreakpoint, Breakpoint: 2, Location: "main () at crash.c:5", Skipped: 3}, "Stopped at breakpoint 2 in main () at crash.c:5, after continuing from 3 other stops"},
+		{StopReason{Reason: StopSignal, Signal: "SIGSEGV", Description: "Segmentation fault"}, "Stopped by signal SIGSEGV (Segmentation fault)"},
+		{StopReason{Reason: StopExited, ExitCode: &code}, "The program exited with code 1"},
+		{StopReason{Reason: StopExited, Signal: "SIGKILL", Description: "Killed"}, "The program was killed by signal SIGKILL (Killed)"},
+		{StopReason{Reason: StopTimeout, Interrupted: true, Elapsed: 10 * time.Second, Location: "spin () at loop.c:4"}, "Still running after 10s, interrupted in spin () at loop.c:4"},
+		{StopReason{Reason: StopTimeout, Elapsed: 2500 * time.Millisecond}, "Still running after 2.5s and could not be interrupted"},
+	} {
+		assert.Equal(t, tc.want, tc.stop.String())
+	}
+}
+
+// TestIsContinueCommand tests recognising the commands that let the program run
+func TestIsContinueCommand(t *testing.T) {
+	for _, command := range []string{"continue", "c", "cont 3", "run", "r < input.txt", "start", "starti"} {
+		assert.True(t, IsContinueCommand(command), command)
+	}
+	for _, command := range []string{"", "next", "step", "finish", "call run()", "print c"} {
+		assert.False(t, IsContinueCommand(command), command)
+	}
+}
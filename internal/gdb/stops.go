@@ -0,0 +1,278 @@
+package gdb
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StopStepped is the reason of a stop after a step, next, finish or the like, which GDB
+// reports with the new location only
+const StopStepped = "stepped"
+
+// sourceContext is how many lines of source are read around the line the program stopped at
+const sourceContext = 3
+
+var (
+	// stopFrame matches the frame GDB prints where the program stopped, e.g.
+	// "main () at crash.c:5", "0x0000555555555139 in main () at crash.c:5" or
+	// "0x00007ffff7e4a672 in __cxa_throw () from /lib/x86_64-linux-gnu/libstdc++.so.6"
+	stopFrame = regexp.MustCompile(`^(?:(0x[0-9a-fA-F]+) in )?(.+?) \(.*\)(?: at (\S+):(\d+)|( from )\S+)?$`)
+
+	// stopSourceLine matches the source line GDB prints at a stop, e.g. "5\t  *p = 1;", or
+	// after stepi inside a line "0x0000555555555141\t5\t  *p = 1;"
+	stopSourceLine = regexp.MustCompile(`^(?:0x[0-9a-fA-F]+\t)?(\d+)\t`)
+)
+
+// SourceLine is a line of a source file
+type SourceLine struct {
+	Number  int    `json:"number"`
+	Text    string `json:"text"`
+	Current bool   `json:"current,omitempty"` // The line the program stopped at
+}
+
+// StopLocation is where the program stopped, read from GDB's report of the stop, with the
+// source around it when the server has the file
+type StopLocation struct {
+	Reason      string       `json:"reason"`               // StopBreakpoint, StopSignal, StopExited, StopStepped, ...
+	Breakpoint  int          `json:"breakpoint,omitempty"` // Number of the breakpoint or watchpoint hit
+	Signal      string       `json:"signal,omitempty"`
+	Description string       `json:"description,omitempty"` // e.g. "Segmentation fault"
+	Function    string       `json:"function,omitempty"`
+	Address     string       `json:"address,omitempty"` // When GDB printed it, i.e. not at the start of a line
+	File        string       `json:"file,omitempty"`    // As GDB names it
+	Line        int          `json:"line,omitempty"`
+	Source      []SourceLine `json:"source,omitempty"`
+}
+
+// String describes the location for the assistant, with its source
+func (l *StopLocation) String() string {
+	var sb strings.Builder
+	switch {
+	case l.Reason == StopExited:
+		sb.WriteString("The program exited")
+	case l.File != "":
+		sb.WriteString("Stopped in " + l.Function + " at " + l.File + ":" + strconv.Itoa(l.Line))
+	default:
+		sb.WriteString("Stopped in " + l.Function)
+	}
+	if l.Signal != "" {
+		sb.WriteString(" by signal " + l.Signal + " (" + l.Description + ")")
+	} else if l.Breakpoint > 0 {
+		sb.WriteString(" at " + l.Reason + " " + strconv.Itoa(l.Breakpoint))
+	}
+	for _, line := range l.Source {
+		marker := "   "
+		if line.Current {
+			marker = "=> "
+		}
+		sb.WriteString("\n" + marker + strconv.Itoa(line.Number) + "\t" + line.Text)
+	}
+	return sb.String()
+}
+
+// StopTracker follows the program's stops in GDB's output, however the commands that let
+// it run were sent, so each stop's location can be reported without asking GDB for it
+type StopTracker struct {
+	mutex   sync.Mutex
+	resumed bool          // A command letting the program run was sent and it has not stopped yet
+	pending *StopLocation // A stop whose location GDB is still printing
+	last    *StopLocation
+	sources []string // Where the sources of stops are read from
+}
+
+// NewStopTracker creates a tracker that has seen no stops
+func NewStopTracker() *StopTracker {
+	return &StopTracker{}
+}
+
+// Command records a command sent to GDB
+func (t *StopTracker) Command(command string) {
+	words := strings.Fields(command)
+	if len(words) == 0 || !resumeCommands[words[0]] {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.resumed = true
+}
+
+// Output reads a line of GDB's output and returns the stop it completes, if any. A stop
+// is complete with its source line, with the first line after its frame, or when GDB
+// prompts for the next command.
+func (t *StopTracker) Output(line string) *StopLocation {
+	prompt := strings.HasPrefix(strings.TrimSpace(line), "(gdb)")
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var completed *StopLocation
+	if prompt {
+		completed = t.complete()
+	}
+	if stop := t.read(trimPrompts(line)); stop != nil {
+		completed = stop
+	}
+	return completed
+}
+
+// read reads a line of output without its prompt. The caller holds the mutex.
+func (t *StopTracker) read(line string) *StopLocation {
+	// Watchpoints are reported as they are set too
+	if stop := parseStop(line); stop != nil && (stop.Reason != StopWatchpoint || t.resumed) {
+		completed := t.complete()
+		t.pending = &StopLocation{Reason: stop.Reason, Breakpoint: stop.Breakpoint, Signal: stop.Signal}
+		if stop.Reason == StopSignal || stop.Reason == StopExited {
+			t.pending.Description = stop.Description
+		}
+		t.setFrame(stop.Location)
+		if stop.Reason == StopExited {
+			return t.complete()
+		}
+		return completed
+	}
+	if t.pending == nil && !t.resumed {
+		return nil
+	}
+
+	if isFrame(line) && (t.pending == nil || t.pending.Function == "") {
+		if t.pending == nil {
+			t.pending = &StopLocation{Reason: StopStepped}
+		}
+		t.setFrame(line)
+		return nil
+	}
+	if match := stopSourceLine.FindStringSubmatch(line); match != nil {
+		if t.pending == nil {
+			// A step within a function prints the new line only
+			t.pending = &StopLocation{Reason: StopStepped}
+			if t.last != nil && t.last.Reason != StopExited {
+				t.pending.Function, t.pending.File = t.last.Function, t.last.File
+			}
+		}
+		t.pending.Line, _ = strconv.Atoi(match[1])
+		return t.complete()
+	}
+	if t.pending != nil && t.pending.Function != "" {
+		return t.complete()
+	}
+	return nil
+}
+
+// Last returns the last stop, or nil before the program first stopped
+func (t *StopTracker) Last() *StopLocation {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.last
+}
+
+// Reset forgets all stops, for a new program whose sources are in dirs
+func (t *StopTracker) Reset(dirs []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.resumed, t.pending, t.last, t.sources = false, nil, nil, dirs
+}
+
+// setFrame sets the pending stop's location from a frame GDB printed
+func (t *StopTracker) setFrame(frame string) {
+	match := stopFrame.FindStringSubmatch(frame)
+	if match == nil {
+		return
+	}
+	t.pending.Address, t.pending.Function, t.pending.File = match[1], match[2], match[3]
+	t.pending.Line, _ = strconv.Atoi(match[4])
+}
+
+// isFrame reports whether a line is the frame of a stop rather than, say, the program's
+// output. Frames in backtraces, which start with their number, and finish's
+// "Run till exit from #0 ..." are not stops.
+func isFrame(line string) bool {
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "Run till exit") {
+		return false
+	}
+	match := stopFrame.FindStringSubmatch(line)
+	return match != nil && (match[1] != "" || match[3] != "" || match[5] != "")
+}
+
+// complete ends the pending stop, reads its source and returns it. The caller holds the
+// mutex.
+func (t *StopTracker) complete() *StopLocation {
+	stop := t.pending
+	if stop == nil {
+		return nil
+	}
+	stop.readSource(t.sources)
+	t.pending, t.last, t.resumed = nil, stop, false
+	return stop
+}
+
+// readSource adds the lines around the line the program stopped at to a stop, from its
+// file in one of dirs. Only files within dirs are read, whatever path the executable's
+// debug information gives them.
+func (l *StopLocation) readSource(dirs []string) {
+	if l.File == "" || l.Line <= 0 {
+		return
+	}
+	path := findSource(l.File, dirs)
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for number := 1; scanner.Scan() && number <= l.Line+sourceContext; number++ {
+		if number >= l.Line-sourceContext {
+			l.Source = append(l.Source, SourceLine{Number: number, Text: scanner.Text(), Current: number == l.Line})
+		}
+	}
+}
+
+// findSource returns the path of a source file GDB named, searched for in dirs by its
+// path, relative to them or not, and then by its name, or "" if it is not there
+func findSource(file string, dirs []string) string {
+	var candidates []string
+	for _, dir := range dirs {
+		if filepath.IsAbs(file) {
+			candidates = append(candidates, file)
+		} else {
+			candidates = append(candidates, filepath.Join(dir, file))
+		}
+	}
+	for _, dir := range dirs {
+		candidates = append(candidates, filepath.Join(dir, filepath.Base(file)))
+	}
+	for _, candidate := range candidates {
+		candidate = filepath.Clean(candidate)
+		if !withinAny(candidate, dirs) {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// withinAny reports whether path is in one of dirs, following symbolic links
+func withinAny(path string, dirs []string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
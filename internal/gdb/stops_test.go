@@ -0,0 +1,118 @@
+package gdb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feed sends output to a tracker line by line and returns the stops completed
+func feed(t *StopTracker, output string) []*StopLocation {
+	var stops []*StopLocation
+	for _, line := range strings.Split(output, "\n") {
+		if stop := t.Output(line); stop != nil {
+			stops = append(stops, stop)
+		}
+	}
+	return stops
+}
+
+func TestStopTracker(t *testing.T) {
+	tracker := NewStopTracker()
+
+	// Setting breakpoints and watchpoints is not a stop
+	assert.Empty(t, feed(tracker, "(gdb) Breakpoint 1 at 0x1139: file crash.c, line 5.\n(gdb) Hardware watchpoint 2: total\n"))
+
+	tracker.Command("run")
+	stops := feed(tracker, "(gdb) Starting program: /tmp/crash \n\nBreakpoint 1, main () at crash.c:5\n5\t  int *p = 0;\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopLocation{Reason: StopBreakpoint, Breakpoint: 1, Function: "main", File: "crash.c", Line: 5}, *stops[0])
+
+	// A step within the function prints the new line only
+	tracker.Command("next")
+	stops = feed(tracker, "(gdb) 6\t  *p = 1;\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopLocation{Reason: StopStepped, Function: "main", File: "crash.c", Line: 6}, *stops[0])
+
+	// Listing source is not a stop
+	tracker.Command("list")
+	assert.Empty(t, feed(tracker, "(gdb) 1\t#include <stdio.h>\n2\t\n3\tint main(void) {\n"))
+
+	tracker.Command("continue")
+	stops = feed(tracker, "(gdb) Continuing.\n\nProgram received signal SIGSEGV, Segmentation fault.\n0x0000555555555139 in main () at crash.c:6\n6\t  *p = 1;\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopLocation{Reason: StopSignal, Signal: "SIGSEGV", Description: "Segmentation fault",
+		Function: "main", Address: "0x0000555555555139", File: "crash.c", Line: 6}, *stops[0])
+	assert.Equal(t, stops[0], tracker.Last())
+
+	tracker.Command("continue")
+	stops = feed(tracker, "(gdb) Continuing.\n\nProgram terminated with signal SIGSEGV, Segmentation fault.\nThe program no longer exists.\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopExited, stops[0].Reason)
+
+	tracker.Reset(nil)
+	assert.Nil(t, tracker.Last())
+}
+
+func TestStopTrackerFrames(t *testing.T) {
+	tracker := NewStopTracker()
+
+	// finish reports the caller's frame, not the one it leaves
+	tracker.Command("finish")
+	stops := feed(tracker, "(gdb) Run till exit from #0  add (a=1, b=2) at math.c:3\n0x0000555555555160 in main () at math.c:9\n9\t  int sum = add(1, 2);\nValue returned is $1 = 3\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, "main", stops[0].Function)
+	assert.Equal(t, 9, stops[0].Line)
+
+	// Without debug information there is no source line: the next line completes the stop
+	tracker.Command("continue")
+	stops = feed(tracker, "(gdb) Continuing.\n\nBreakpoint 2, 0x0000555555555131 in helper ()\n(gdb) ")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopLocation{Reason: StopBreakpoint, Breakpoint: 2, Function: "helper", Address: "0x0000555555555131"}, *stops[0])
+
+	// A watchpoint is reported with its values before the frame
+	tracker.Command("continue")
+	stops = feed(tracker, "(gdb) Continuing.\n\nHardware watchpoint 3: total\n\nOld value = 0\nNew value = 1\nmain () at sum.c:7\n7\t  for (i = 0; i < n; i++)\n")
+	require.Len(t, stops, 1)
+	assert.Equal(t, StopLocation{Reason: StopWatchpoint, Breakpoint: 3, Function: "main", File: "sum.c", Line: 7}, *stops[0])
+
+	// Backtraces are not stops, nor is the program's output
+	tracker.Command("bt")
+	assert.Empty(t, feed(tracker, "(gdb) #0  main () at sum.c:7\n"))
+	tracker.Command("continue")
+	assert.Empty(t, feed(tracker, "(gdb) Continuing.\nResult (ok)\n"))
+}
+
+func TestStopSource(t *testing.T) {
+	dir := t.TempDir()
+	var source strings.Builder
+	for i := 1; i <= 10; i++ {
+		source.WriteString("line " + string(rune('0'+i%10)) + "\n")
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crash.c"), []byte(source.String()), 0644))
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.c"), []byte("secret\n"), 0644))
+
+	tracker := NewStopTracker()
+	tracker.Reset([]string{dir})
+	tracker.Command("run")
+	stops := feed(tracker, "Breakpoint 1, main () at /build/src/crash.c:2\n2\tline 2\n")
+	require.Len(t, stops, 1)
+	// Found by its name, with the lines around it
+	assert.Equal(t, []SourceLine{{1, "line 1", false}, {2, "line 2", true}, {3, "line 3", false}, {4, "line 4", false}, {5, "line 5", false}}, stops[0].Source)
+	assert.Contains(t, stops[0].String(), "=> 2\tline 2")
+
+	// Files outside the source directories are not read, whatever path GDB gives
+	tracker.Command("continue")
+	stops = feed(tracker, "Breakpoint 2, leak () at "+filepath.Join(outside, "secret.c")+":1\n1\tsecret\n")
+	require.Len(t, stops, 1)
+	assert.Empty(t, stops[0].Source)
+	tracker.Command("continue")
+	stops = feed(tracker, "Breakpoint 2, leak () at ../"+filepath.Base(outside)+"/secret.c:1\n1\tsecret\n")
+	require.Len(t, stops, 1)
+	assert.Empty(t, stops[0].Source)
+}
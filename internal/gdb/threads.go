@@ -0,0 +1,174 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// crashBacktraceFrames limits the frames of each thread's backtrace for a crash
+const crashBacktraceFrames = 16
+
+var (
+	// threadLine matches a line of `info threads` output such as
+	// `* 1    Thread 0x7ffff7d89740 (LWP 12345) "worker" main () at crash.c:20` or
+	// `  1    process 4321 main () at crash.c:20`
+	threadLine = regexp.MustCompile(`^(\*)?\s*(\d+)\s+((?:Thread|process|LWP) \S+(?: \(LWP \d+\))?)(?: "([^"]*)")?\s+(.*)$`)
+
+	// goroutineLine matches a line of `info goroutines` output, from the Go runtime's GDB
+	// extension, such as "* 1 running  runtime.gosched" or "  17 waiting  runtime.gopark"
+	goroutineLine = regexp.MustCompile(`^(\*)?\s*(\d+)\s+([a-z]+)\s+(.*)$`)
+
+	// unknownThread matches GDB's refusal to switch to a thread, e.g. "Invalid thread ID: 7"
+	// or "Unknown thread 7."
+	unknownThread = regexp.MustCompile(`(?:Invalid thread ID|Unknown thread)[^\n]*`)
+)
+
+// Thread is a thread of the program
+type Thread struct {
+	ID       int    `json:"id"` // GDB's thread number, used to switch to it
+	TargetID string `json:"targetId"`
+	Name     string `json:"name,omitempty"`
+	Frame    string `json:"frame"` // The thread's innermost frame
+	Current  bool   `json:"current"`
+}
+
+// Goroutine is a goroutine of a Go program
+type Goroutine struct {
+	ID       int    `json:"id"`
+	Status   string `json:"status"` // e.g. "running" or "waiting"
+	Function string `json:"function"`
+	Running  bool   `json:"running"` // Running on a thread
+}
+
+// Threads lists the program's threads
+func (g *GDBService) Threads() ([]Thread, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("the thread list"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("info threads", g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return ParseThreads(output), nil
+}
+
+// SelectThread makes a thread the current one and returns GDB's report of its frame
+func (g *GDBService) SelectThread(id int) (string, error) {
+	if !g.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("thread switching"); err != nil {
+		return "", err
+	}
+	output, err := g.ExecuteCommandWithOutput(SelectThreadCommand(id), g.commandTimeout())
+	if err != nil {
+		return "", err
+	}
+	if match := unknownThread.FindString(output); match != "" {
+		return "", fmt.Errorf("%w: %s", appErrors.ErrBadRequest, strings.TrimSpace(match))
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// SelectThreadCommand returns the GDB command switching to a thread
+func SelectThreadCommand(id int) string {
+	return "thread " + strconv.Itoa(id)
+}
+
+// Goroutines lists a Go program's goroutines. It needs the Go runtime's GDB extension,
+// which GDB loads from the Go installation the program was built with when it is allowed
+// to load it (add-auto-load-safe-path).
+func (g *GDBService) Goroutines() ([]Goroutine, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("the goroutine list"); err != nil {
+		return nil, err
+	}
+	output, err := g.ExecuteCommandWithOutput("info goroutines", g.commandTimeout())
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(output, "Undefined info command") {
+		return nil, fmt.Errorf("%w: GDB has not loaded the Go runtime's extension (runtime-gdb.py) for this program", appErrors.ErrUnsupported)
+	}
+	return ParseGoroutines(output), nil
+}
+
+// ThreadBacktraces returns the backtraces of all threads, up to crashBacktraceFrames
+// frames each, or "" if the program has fewer than two threads
+func (g *GDBService) ThreadBacktraces() (string, error) {
+	if !g.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("thread backtraces"); err != nil {
+		return "", err
+	}
+	output, err := g.ExecuteCommandWithOutput(fmt.Sprintf("thread apply all bt %d", crashBacktraceFrames), g.commandTimeout())
+	if err != nil {
+		return "", err
+	}
+	if countThreadHeaders(output) < 2 {
+		return "", nil
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ParseThreads reads the threads in `info threads` output
+func ParseThreads(output string) []Thread {
+	var threads []Thread
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "(gdb)"))
+		match := threadLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(match[2])
+		threads = append(threads, Thread{
+			ID:       id,
+			TargetID: match[3],
+			Name:     match[4],
+			Frame:    strings.TrimSpace(match[5]),
+			Current:  match[1] == "*",
+		})
+	}
+	return threads
+}
+
+// ParseGoroutines reads the goroutines in `info goroutines` output
+func ParseGoroutines(output string) []Goroutine {
+	var goroutines []Goroutine
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "(gdb)"))
+		match := goroutineLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(match[2])
+		goroutines = append(goroutines, Goroutine{
+			ID:       id,
+			Status:   match[3],
+			Function: strings.TrimSpace(match[4]),
+			Running:  match[1] == "*",
+		})
+	}
+	return goroutines
+}
+
+// countThreadHeaders counts the threads in "thread apply all bt" output
+func countThreadHeaders(output string) int {
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if threadHeaderPattern.MatchString(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "(gdb)"))) {
+			count++
+		}
+	}
+	return count
+}
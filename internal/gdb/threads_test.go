@@ -0,0 +1,53 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseThreads tests parsing of `info threads` output with and without thread names
+func TestParseThreads(t *testing.T) {
+	output := `(gdb)   Id   Target Id                                   Frame
+* 1    Thread 0x7ffff7d89740 (LWP 12345) "crash" main () at crash.c:20
+  2    Thread 0x7ffff7d88640 (LWP 12346) "worker" 0x00007ffff7e91a3d in __GI___clock_nanosleep (clock_id=0) at nanosleep.c:48
+  3    Thread 0x7ffff7587640 (LWP 12347) worker (arg=0x0) at crash.c:9
+`
+	threads := ParseThreads(output)
+	require.Len(t, threads, 3)
+	assert.Equal(t, Thread{ID: 1, TargetID: "Thread 0x7ffff7d89740 (LWP 12345)", Name: "crash", Frame: "main () at crash.c:20", Current: true}, threads[0])
+	assert.Equal(t, "worker", threads[1].Name)
+	assert.Equal(t, "0x00007ffff7e91a3d in __GI___clock_nanosleep (clock_id=0) at nanosleep.c:48", threads[1].Frame)
+	assert.False(t, threads[1].Current)
+	assert.Equal(t, "", threads[2].Name)
+	assert.Equal(t, "worker (arg=0x0) at crash.c:9", threads[2].Frame)
+
+	threads = ParseThreads("* 1    process 4321 main () at crash.c:20\n")
+	require.Len(t, threads, 1)
+	assert.Equal(t, "process 4321", threads[0].TargetID)
+
+	assert.Empty(t, ParseThreads("No threads.\n"))
+}
+
+// TestParseGoroutines tests parsing of the Go runtime extension's `info goroutines` output
+func TestParseGoroutines(t *testing.T) {
+	output := "* 1 running  runtime.gosched\n  17 waiting  runtime.gopark\n"
+	assert.Equal(t, []Goroutine{
+		{ID: 1, Status: "running", Function: "runtime.gosched", Running: true},
+		{ID: 17, Status: "waiting", Function: "runtime.gopark"},
+	}, ParseGoroutines(output))
+}
+
+// TestCountThreadHeaders tests counting the threads of "thread apply all bt" output
+func TestCountThreadHeaders(t *testing.T) {
+	output := `(gdb)
+Thread 2 (Thread 0x7ffff7d88640 (LWP 12346) "worker"):
+#0  0x00007ffff7e91a3d in __GI___clock_nanosleep () from /lib/libc.so.6
+
+Thread 1 (Thread 0x7ffff7d89740 (LWP 12345) "crash"):
+#0  main () at crash.c:20
+`
+	assert.Equal(t, 2, countThreadHeaders(output))
+	assert.Equal(t, 1, countThreadHeaders("Thread 1 (process 4321):\n#0  main () at crash.c:20\n"))
+}
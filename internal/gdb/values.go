@@ -0,0 +1,342 @@
+package gdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+var (
+	// variableLine matches the first line of a variable in `info locals` or `info args`
+	// output, e.g. "v = std::vector of length 2, capacity 2 = {1, 2}"
+	variableLine = regexp.MustCompile(`^([A-Za-z_$][\w$.]*) = (.*)$`)
+
+	// elementName matches the name of an element of an aggregate: a member, a base class
+	// ("<Base>"), a map key or array index ("[1]") or a pretty-printer's child name, e.g.
+	// "get()" of a std::unique_ptr
+	elementName = regexp.MustCompile(`^(?:\[.*\]|<.*>|[A-Za-z_$][\w$:.]*(?:\(\))?)$`)
+
+	// rustField matches a field of a Rust struct, which GDB prints as "x: 1"
+	rustField = regexp.MustCompile(`^([A-Za-z_]\w*): (.*)$`)
+
+	// rustVariant matches the path of a Rust enum's variant, e.g.
+	// "core::option::Option<i32>::Some", whose name starts with a capital
+	rustVariant = regexp.MustCompile(`^(?:[A-Za-z_]\w*(?:<.*>)?::)*[A-Z]\w*$`)
+
+	// summaryTail matches the rest of a container's summary after its comma, e.g.
+	// "capacity 0" of "std::vector of length 0, capacity 0" or "cap 2" of a Go slice's
+	summaryTail = regexp.MustCompile(`^(?:capacity|cap) \d+(?: = |$)`)
+
+	// repeats matches an element GDB folded with its repeats, e.g. "0 <repeats 16 times>"
+	repeats = regexp.MustCompile(`<repeats (\d+) times>$`)
+
+	// noFrame matches GDB's refusal to select a frame, e.g. "No frame at level 7."
+	noFrame = regexp.MustCompile(`No (?:frame at level \d+|stack)\.?`)
+)
+
+// Value is a value as GDB prints it, split into children when it is an aggregate: the
+// members of a structure, the elements of an array, or the children a pretty-printer
+// shows for a container, e.g. the elements of a std::vector or a Go slice, the entries
+// of a std::map, or the fields of a Rust enum's variant
+type Value struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"` // A scalar as printed, or an aggregate's summary, e.g. "std::vector of length 2, capacity 2"
+	Children []Value `json:"children,omitempty"`
+}
+
+// Variables are the variables of a frame
+type Variables struct {
+	Frame  int     `json:"frame"` // GDB's frame level, 0 for the innermost
+	Args   []Value `json:"args"`
+	Locals []Value `json:"locals"`
+}
+
+// FrameVariables returns the arguments and locals of the frame at level, with aggregates
+// split into children as the loaded pretty-printers show them
+func (g *GDBService) FrameVariables(level int) (*Variables, error) {
+	if !g.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	if err := g.requireGDB("the variables"); err != nil {
+		return nil, err
+	}
+	if level < 0 {
+		return nil, fmt.Errorf("%w: invalid frame level %d", appErrors.ErrBadRequest, level)
+	}
+	variables := &Variables{Frame: level}
+	for _, scope := range []struct {
+		command string
+		values  *[]Value
+	}{{"info args", &variables.Args}, {"info locals", &variables.Locals}} {
+		output, err := g.ExecuteCommandWithOutput(fmt.Sprintf("frame apply level %d -q %s", level, scope.command), g.commandTimeout())
+		if err != nil {
+			return nil, err
+		}
+		if match := noFrame.FindString(output); match != "" {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrBadRequest, match)
+		}
+		*scope.values = ParseVariables(output)
+	}
+	return variables, nil
+}
+
+// String renders the variables as an indented tree suitable for LLM context, each child
+// under its aggregate
+func (v *Variables) String() string {
+	var sb strings.Builder
+	for _, scope := range []struct {
+		title  string
+		values []Value
+	}{{"Arguments", v.Args}, {"Locals", v.Locals}} {
+		if len(scope.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s of frame %d:\n", scope.title, v.Frame)
+		writeValues(&sb, scope.values, 1)
+	}
+	return sb.String()
+}
+
+// writeValues writes values as lines of "name = value" indented by depth
+func writeValues(sb *strings.Builder, values []Value, depth int) {
+	for _, value := range values {
+		fmt.Fprintf(sb, "%s%s = %s\n", strings.Repeat("  ", depth), value.Name, value.Value)
+		writeValues(sb, value.Children, depth+1)
+	}
+}
+
+// ParseVariables reads the variables of `info locals` or `info args` output. A value GDB
+// prints over several lines, as with set print pretty on, continues with indented lines.
+func ParseVariables(output string) []Value {
+	type variable struct{ name, text string }
+	var variables []variable
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		for strings.HasPrefix(line, "(gdb) ") {
+			line = strings.TrimPrefix(line, "(gdb) ")
+		}
+		if m := variableLine.FindStringSubmatch(line); m != nil {
+			variables = append(variables, variable{m[1], m[2]})
+		} else if len(variables) > 0 && strings.TrimSpace(line) != "" {
+			variables[len(variables)-1].text += " " + strings.TrimSpace(line)
+		}
+	}
+	values := make([]Value, len(variables))
+	for i, v := range variables {
+		values[i] = ParseValue(v.name, v.text)
+	}
+	return values
+}
+
+// ParseValue splits a value GDB printed into its children, if it is an aggregate:
+//
+//	{a = 1, b = {c = 2}}                              a structure
+//	{1, 2, 0 <repeats 14 times>}                      an array
+//	std::vector of length 2, capacity 2 = {1, 2}      a libstdc++ container
+//	std::map with 1 element = {[1] = "one"}           a map, whose keys name its entries
+//	[]int len 2, cap 2 = {1, 2}                       a Go slice
+//	core::option::Option<i32>::Some(5)                a Rust enum's tuple variant
+//	Point {x: 1, y: 2}                                a Rust struct
+func ParseValue(name, text string) Value {
+	text = strings.TrimSpace(text)
+	value := Value{Name: name, Value: text}
+	if open := topLevelIndex(text, '{'); open >= 0 && closing(text, open) == len(text)-1 {
+		value.Value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text[:open]), " ="))
+		if value.Value == "" {
+			value.Value = "{...}"
+		}
+		value.Children = parseElements(text[open+1 : len(text)-1])
+		if value.Children == nil {
+			value.Value = text
+		}
+		return value
+	}
+	if strings.HasSuffix(text, ")") {
+		open := strings.LastIndex(text, "(")
+		for open >= 0 && closing(text, open) != len(text)-1 {
+			open = strings.LastIndex(text[:open], "(")
+		}
+		if open > 0 && rustVariant.MatchString(text[:open]) {
+			// A Rust tuple variant, e.g. Some(5)
+			value.Value = text[:open]
+			value.Children = numberElements(splitTopLevel(text[open+1 : len(text)-1]))
+		} else if open == 0 && len(splitTopLevel(text[1:len(text)-1])) > 1 {
+			// A Rust tuple, e.g. (1, "one")
+			value.Value = "(...)"
+			value.Children = numberElements(splitTopLevel(text[1 : len(text)-1]))
+		}
+	}
+	return value
+}
+
+// parseElements parses the elements between an aggregate's braces: named members and
+// entries, or the unnamed elements of an array, which are named by their index
+func parseElements(text string) []Value {
+	parts := mergeParts(splitTopLevel(text))
+	if len(parts) == 0 {
+		return nil
+	}
+	if _, _, named := splitElement(parts[0]); !named {
+		var elements []Value
+		index := 0
+		for _, part := range parts {
+			elements = append(elements, ParseValue(fmt.Sprintf("[%d]", index), part))
+			index++
+			if m := repeats.FindStringSubmatch(part); m != nil {
+				count, _ := strconv.Atoi(m[1])
+				index += count - 1
+			}
+		}
+		return elements
+	}
+
+	var elements []Value
+	for _, part := range parts {
+		name, text, named := splitElement(part)
+		if !named && len(elements) > 0 {
+			// The rest of a character array printed in pieces, e.g. "AB", 'C' <repeats 9 times>
+			last := &elements[len(elements)-1]
+			*last = ParseValue(last.Name, last.Value+", "+part)
+			continue
+		}
+		elements = append(elements, ParseValue(name, text))
+	}
+	return elements
+}
+
+// numberElements names the fields of a Rust tuple by their position
+func numberElements(parts []string) []Value {
+	elements := make([]Value, len(parts))
+	for i, part := range parts {
+		elements[i] = ParseValue(strconv.Itoa(i), part)
+	}
+	return elements
+}
+
+// mergeParts joins the parts split at commas that belong together: a pretty-printer's
+// summary holding a comma, e.g. "std::vector of length 2, capacity 2 = {1, 2}", was split
+// before its " = " or its end
+func mergeParts(parts []string) []string {
+	var merged []string
+	for _, part := range parts {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			_, lastValue, _ := splitElement(last)
+			eq := topLevelEquals(part)
+			if summaryTail.MatchString(part) || eq >= 0 && !elementName.MatchString(part[:eq]) && topLevelEquals(lastValue) < 0 {
+				merged[len(merged)-1] = last + ", " + part
+				continue
+			}
+		}
+		merged = append(merged, part)
+	}
+	return merged
+}
+
+// splitElement splits an element into its name and value, reporting whether it has a name
+func splitElement(part string) (string, string, bool) {
+	if eq := topLevelEquals(part); eq >= 0 && elementName.MatchString(part[:eq]) {
+		return part[:eq], strings.TrimSpace(part[eq+3:]), true
+	}
+	if m := rustField.FindStringSubmatch(part); m != nil {
+		return m[1], m[2], true
+	}
+	return "", part, false
+}
+
+// topLevelEquals returns the index of the first " = " of text outside quotes and
+// brackets, or -1
+func topLevelEquals(text string) int {
+	index := -1
+	scan(text, func(i, depth int) bool {
+		if depth == 0 && strings.HasPrefix(text[i:], " = ") {
+			index = i
+			return false
+		}
+		return true
+	})
+	return index
+}
+
+// topLevelIndex returns the index of the first c of text outside quotes and brackets,
+// or -1
+func topLevelIndex(text string, c byte) int {
+	index := -1
+	scan(text, func(i, depth int) bool {
+		if depth == 0 && text[i] == c {
+			index = i
+			return false
+		}
+		return true
+	})
+	return index
+}
+
+// splitTopLevel splits text at the commas outside quotes and brackets, trimming the parts
+func splitTopLevel(text string) []string {
+	var parts []string
+	start := 0
+	scan(text, func(i, depth int) bool {
+		if depth == 0 && text[i] == ',' {
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+		return true
+	})
+	if last := strings.TrimSpace(text[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// closing returns the index of the bracket closing the one at open, or -1
+func closing(text string, open int) int {
+	index := -1
+	scan(text[open:], func(i, depth int) bool {
+		if i > 0 && depth == 0 {
+			index = open + i
+			return false
+		}
+		return true
+	})
+	return index
+}
+
+// scan calls visit with each index of text outside quotes, and the depth of brackets it is
+// in, until visit returns false. The depth counts a closing bracket as outside it.
+func scan(text string, visit func(i, depth int) bool) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+			continue
+		case '{', '(', '[', '<':
+			if !visit(i, depth) {
+				return
+			}
+			depth++
+			continue
+		case '}', ')', ']', '>':
+			if depth > 0 {
+				depth--
+			}
+		}
+		if !visit(i, depth) {
+			return
+		}
+	}
+}
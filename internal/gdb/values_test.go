@@ -0,0 +1,108 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseValue tests splitting values into children as pretty-printers print them
+func TestParseValue(t *testing.T) {
+	for _, tc := range []struct {
+		name, text string
+		want       Value
+	}{
+		{"scalar", `0x555555556004 "crash"`, Value{Value: `0x555555556004 "crash"`}},
+		{"cast", `(int *) 0x0`, Value{Value: `(int *) 0x0`}},
+		{"function pointer", `{int (int)} 0x401126 <square(int)>`, Value{Value: `{int (int)} 0x401126 <square(int)>`}},
+		{"nan", `nan(0x8000000000000)`, Value{Value: `nan(0x8000000000000)`}},
+		{"empty", `{}`, Value{Value: `{}`}},
+		{"struct", `{x = 1, name = "a, b = c", inner = {y = 2}}`, Value{Value: "{...}", Children: []Value{
+			{Name: "x", Value: "1"},
+			{Name: "name", Value: `"a, b = c"`},
+			{Name: "inner", Value: "{...}", Children: []Value{{Name: "y", Value: "2"}}},
+		}}},
+		{"array", `{7, 0 <repeats 14 times>, 9}`, Value{Value: "{...}", Children: []Value{
+			{Name: "[0]", Value: "7"},
+			{Name: "[1]", Value: "0 <repeats 14 times>"},
+			{Name: "[15]", Value: "9"},
+		}}},
+		{"character array", `{buf = "AB", 'C' <repeats 9 times>, len = 11}`, Value{Value: "{...}", Children: []Value{
+			{Name: "buf", Value: `"AB", 'C' <repeats 9 times>`},
+			{Name: "len", Value: "11"},
+		}}},
+		{"std::vector", `std::vector of length 2, capacity 4 = {1, 2}`, Value{Value: "std::vector of length 2, capacity 4", Children: []Value{
+			{Name: "[0]", Value: "1"},
+			{Name: "[1]", Value: "2"},
+		}}},
+		{"vector of vectors", `std::vector of length 2, capacity 2 = {std::vector of length 1, capacity 1 = {1}, std::vector of length 0, capacity 0}`,
+			Value{Value: "std::vector of length 2, capacity 2", Children: []Value{
+				{Name: "[0]", Value: "std::vector of length 1, capacity 1", Children: []Value{{Name: "[0]", Value: "1"}}},
+				{Name: "[1]", Value: "std::vector of length 0, capacity 0"},
+			}}},
+		{"std::map", `std::map with 2 elements = {[1] = "one", ["two, 2"] = std::vector of length 1, capacity 1 = {2}}`,
+			Value{Value: "std::map with 2 elements", Children: []Value{
+				{Name: "[1]", Value: `"one"`},
+				{Name: `["two, 2"]`, Value: "std::vector of length 1, capacity 1", Children: []Value{{Name: "[0]", Value: "2"}}},
+			}}},
+		{"std::unique_ptr", `std::unique_ptr<std::pair<int, int>> = {get() = 0x4172b0}`, Value{Value: "std::unique_ptr<std::pair<int, int>>", Children: []Value{
+			{Name: "get()", Value: "0x4172b0"},
+		}}},
+		{"base class", `{<Base> = {id = 1}, _vptr.Derived = 0x403d48 <vtable for Derived+16>}`, Value{Value: "{...}", Children: []Value{
+			{Name: "<Base>", Value: "{...}", Children: []Value{{Name: "id", Value: "1"}}},
+			{Name: "_vptr.Derived", Value: "0x403d48 <vtable for Derived+16>"},
+		}}},
+		{"Go slice", `[]int len 3, cap 3 = {1, 2, 3}`, Value{Value: "[]int len 3, cap 3", Children: []Value{
+			{Name: "[0]", Value: "1"}, {Name: "[1]", Value: "2"}, {Name: "[2]", Value: "3"},
+		}}},
+		{"Go map", `map[string]int = {["a"] = 1}`, Value{Value: "map[string]int", Children: []Value{{Name: `["a"]`, Value: "1"}}}},
+		{"Rust enum", `core::option::Option<rust_app::Point>::Some(rust_app::Point {x: 1, y: 2})`, Value{Value: "core::option::Option<rust_app::Point>::Some", Children: []Value{
+			{Name: "0", Value: "rust_app::Point", Children: []Value{{Name: "x", Value: "1"}, {Name: "y", Value: "2"}}},
+		}}},
+		{"Rust struct variant", `rust_app::Shape::Rect{w: 2, h: 3}`, Value{Value: "rust_app::Shape::Rect", Children: []Value{
+			{Name: "w", Value: "2"}, {Name: "h", Value: "3"},
+		}}},
+		{"Rust tuple", `(1, "one")`, Value{Value: "(...)", Children: []Value{{Name: "0", Value: "1"}, {Name: "1", Value: `"one"`}}}},
+		{"Rust Vec", `Vec(size=2) = {5, 6}`, Value{Value: "Vec(size=2)", Children: []Value{{Name: "[0]", Value: "5"}, {Name: "[1]", Value: "6"}}}},
+	} {
+		assert.Equal(t, tc.want, ParseValue("", tc.text), tc.name)
+	}
+}
+
+// TestParseVariables tests reading `info locals` output, also as set print pretty on
+// prints it
+func TestParseVariables(t *testing.T) {
+	variables := ParseVariables(`(gdb) total = 42
+point = {
+  x = 1,
+  y = 2
+}
+v = std::vector of length 1, capacity 1 = {3}
+`)
+	require.Len(t, variables, 3)
+	assert.Equal(t, Value{Name: "total", Value: "42"}, variables[0])
+	assert.Equal(t, []Value{{Name: "x", Value: "1"}, {Name: "y", Value: "2"}}, variables[1].Children)
+	assert.Equal(t, "std::vector of length 1, capacity 1", variables[2].Value)
+	assert.Empty(t, ParseVariables("(gdb) No locals.\n"))
+}
+
+// TestVariablesString tests rendering variables as a tree
+func TestVariablesString(t *testing.T) {
+	variables := &Variables{
+		Frame: 1,
+		Args:  []Value{ParseValue("argc", "1")},
+		Locals: []Value{
+			ParseValue("v", "std::vector of length 2, capacity 2 = {1, {x = 2}}"),
+		},
+	}
+	assert.Equal(t, `Arguments of frame 1:
+  argc = 1
+Locals of frame 1:
+  v = std::vector of length 2, capacity 2
+    [0] = 1
+    [1] = {...}
+      x = 2
+`, variables.String())
+	assert.Empty(t, (&Variables{}).String())
+}
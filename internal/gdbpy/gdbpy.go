@@ -0,0 +1,65 @@
+// Package gdbpy is the library of GDB Python scripts shipped with the server. Each script
+// adds a GDB command, e.g. hexdump or logbreak, and runs it when it is run with arguments;
+// the first line of its docstring describes it.
+package gdbpy
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed library/*.py
+var library embed.FS
+
+// Script is a GDB Python script
+type Script struct {
+	Name        string `json:"name"` // Without .py
+	Description string `json:"description,omitempty"`
+	Source      string `json:"-"`
+	Library     bool   `json:"library"` // Shipped with the server rather than uploaded
+}
+
+// Library returns the scripts shipped with the server, by name
+func Library() []Script {
+	entries, _ := library.ReadDir("library")
+	scripts := make([]Script, 0, len(entries))
+	for _, entry := range entries {
+		source, err := library.ReadFile(path.Join("library", entry.Name()))
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".py")
+		scripts = append(scripts, Script{Name: name, Description: Describe(string(source)), Source: string(source), Library: true})
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts
+}
+
+// Lookup returns the library script named name, with or without .py
+func Lookup(name string) (Script, bool) {
+	name = strings.TrimSuffix(name, ".py")
+	for _, script := range Library() {
+		if script.Name == name {
+			return script, true
+		}
+	}
+	return Script{}, false
+}
+
+// Describe returns the first line of a script's docstring, or "" if it starts with none
+func Describe(source string) string {
+	source = strings.TrimSpace(source)
+	for _, quote := range []string{`"""`, `'''`} {
+		if rest, ok := strings.CutPrefix(source, quote); ok {
+			end := strings.Index(rest, quote)
+			if end < 0 {
+				return ""
+			}
+			line, _, _ := strings.Cut(strings.TrimSpace(rest[:end]), "\n")
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
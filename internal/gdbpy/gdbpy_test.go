@@ -0,0 +1,32 @@
+package gdbpy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibrary(t *testing.T) {
+	scripts := Library()
+	require.NotEmpty(t, scripts)
+	for _, script := range scripts {
+		assert.NotEmpty(t, script.Description, script.Name)
+		assert.Contains(t, script.Source, "gogdbllm_args", "%s runs its command with the arguments it is run with", script.Name)
+		assert.True(t, script.Library)
+	}
+
+	hexdump, ok := Lookup("hexdump.py")
+	require.True(t, ok)
+	assert.Equal(t, "hexdump", hexdump.Name)
+	assert.Contains(t, hexdump.Description, "hexdump ADDRESS [LENGTH]")
+	_, ok = Lookup("missing")
+	assert.False(t, ok)
+}
+
+func TestDescribe(t *testing.T) {
+	assert.Equal(t, "Print the heap.", Describe("\n\"\"\"Print the heap.\n\nMore details.\n\"\"\"\nimport gdb\n"))
+	assert.Equal(t, "Single quotes", Describe("'''Single quotes'''"))
+	assert.Empty(t, Describe("import gdb\n\"\"\"Not a docstring\"\"\""))
+	assert.Empty(t, Describe(`"""Unterminated`))
+}
@@ -0,0 +1,50 @@
+"""frame-locals [DEPTH]: the arguments and local variables of each frame of the backtrace (10 frames by default)"""
+import gdb
+
+
+def frame_locals(args):
+    depth = int(gdb.parse_and_eval(args[0])) if args else 10
+    frame = gdb.newest_frame()
+    level = 0
+    while frame is not None and level < depth:
+        sal = frame.find_sal()
+        where = ""
+        if sal.symtab is not None:
+            where = " at %s:%d" % (sal.symtab.filename, sal.line)
+        gdb.write("#%d %s%s\n" % (level, frame.name() or "??", where))
+        try:
+            block = frame.block()
+        except RuntimeError:
+            block = None  # No debug information for the frame
+        seen = set()
+        while block is not None:
+            for symbol in block:
+                if not (symbol.is_argument or symbol.is_variable) or symbol.name in seen:
+                    continue
+                seen.add(symbol.name)
+                try:
+                    value = str(symbol.value(frame))
+                except Exception as error:  # e.g. optimized out
+                    value = "<%s>" % error
+                gdb.write("    %s = %s\n" % (symbol.name, value))
+            if block.function is not None:
+                break
+            block = block.superblock
+        frame = frame.older()
+        level += 1
+
+
+class FrameLocals(gdb.Command):
+    """Print the arguments and locals of each frame: frame-locals [DEPTH]"""
+
+    def __init__(self):
+        super().__init__("frame-locals", gdb.COMMAND_STACK)
+
+    def invoke(self, argument, from_tty):
+        frame_locals(gdb.string_to_argv(argument))
+
+
+FrameLocals()
+
+# Running the script also prints the frames
+frame_locals(gogdbllm_args)
@@ -0,0 +1,32 @@
+"""hexdump ADDRESS [LENGTH]: dump memory as hex and ASCII, 16 bytes a line (64 bytes by default)"""
+import gdb
+
+
+def hexdump(args):
+    if not args:
+        raise gdb.GdbError("usage: hexdump ADDRESS [LENGTH]")
+    address = int(gdb.parse_and_eval("(unsigned long)(%s)" % args[0]))
+    length = int(gdb.parse_and_eval(args[1])) if len(args) > 1 else 64
+    data = gdb.selected_inferior().read_memory(address, length).tobytes()
+    for offset in range(0, len(data), 16):
+        chunk = data[offset:offset + 16]
+        hex_part = " ".join("%02x" % byte for byte in chunk)
+        text = "".join(chr(byte) if 32 <= byte < 127 else "." for byte in chunk)
+        gdb.write("0x%016x  %-47s  %s\n" % (address + offset, hex_part, text))
+
+
+class Hexdump(gdb.Command):
+    """Dump memory as hex and ASCII: hexdump ADDRESS [LENGTH]"""
+
+    def __init__(self):
+        super().__init__("hexdump", gdb.COMMAND_DATA)
+
+    def invoke(self, argument, from_tty):
+        hexdump(gdb.string_to_argv(argument))
+
+
+Hexdump()
+
+# Run with arguments, the script also dumps them
+if gogdbllm_args:
+    hexdump(gogdbllm_args)
@@ -0,0 +1,44 @@
+"""logbreak LOCATION EXPRESSION...: a breakpoint that prints expressions each time it is hit and lets the program go on"""
+import gdb
+
+
+class LogBreakpoint(gdb.Breakpoint):
+    """A breakpoint printing expressions instead of stopping"""
+
+    def __init__(self, location, expressions):
+        super().__init__(location)
+        self.expressions = expressions
+
+    def stop(self):
+        values = []
+        for expression in self.expressions:
+            try:
+                values.append("%s = %s" % (expression, gdb.parse_and_eval(expression)))
+            except gdb.error as error:
+                values.append("%s: %s" % (expression, error))
+        gdb.write("[logbreak %d] %s\n" % (self.number, ", ".join(values)))
+        return False
+
+
+def logbreak(args):
+    if len(args) < 2:
+        raise gdb.GdbError("usage: logbreak LOCATION EXPRESSION...")
+    breakpoint = LogBreakpoint(args[0], args[1:])
+    gdb.write("Logging breakpoint %d at %s\n" % (breakpoint.number, args[0]))
+
+
+class LogBreak(gdb.Command):
+    """Log expressions each time LOCATION is reached: logbreak LOCATION EXPRESSION..."""
+
+    def __init__(self):
+        super().__init__("logbreak", gdb.COMMAND_BREAKPOINTS)
+
+    def invoke(self, argument, from_tty):
+        logbreak(gdb.string_to_argv(argument))
+
+
+LogBreak()
+
+# Run with arguments, the script also sets the breakpoint
+if gogdbllm_args:
+    logbreak(gogdbllm_args)
@@ -0,0 +1,33 @@
+"""vmmap [FILTER]: the memory map of the program from /proc/PID/maps, optionally only the mappings whose path contains FILTER (Linux)"""
+import gdb
+
+
+def vmmap(args):
+    pid = gdb.selected_inferior().pid
+    if pid == 0:
+        raise gdb.GdbError("the program is not running")
+    wanted = " ".join(args)
+    with open("/proc/%d/maps" % pid) as maps:
+        for line in maps:
+            fields = line.split(None, 5)
+            path = fields[5].strip() if len(fields) > 5 else ""
+            if wanted and wanted not in path:
+                continue
+            start, end = (int(bound, 16) for bound in fields[0].split("-"))
+            gdb.write("0x%012x-0x%012x %s %8dK %s\n" % (start, end, fields[1], (end - start) // 1024, path))
+
+
+class Vmmap(gdb.Command):
+    """Print the memory map of the program: vmmap [FILTER]"""
+
+    def __init__(self):
+        super().__init__("vmmap", gdb.COMMAND_STATUS)
+
+    def invoke(self, argument, from_tty):
+        vmmap(gdb.string_to_argv(argument))
+
+
+Vmmap()
+
+# Running the script also prints the map
+vmmap(gogdbllm_args)
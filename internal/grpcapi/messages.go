@@ -0,0 +1,402 @@
+package grpcapi
+
+import (
+	"math"
+	"sort"
+)
+
+// The messages of debugger.proto, encoded and decoded by hand. Code generated by
+// protoc-gen-go and protoc-gen-go-grpc would bring in google.golang.org/protobuf and
+// google.golang.org/grpc and a protoc step to the build, for a dozen flat messages; the
+// server keeps to the standard library instead, as its Redis and disk caches do. Field
+// numbers must match debugger.proto, which TestMessagesMatchProto checks field by field.
+
+// UploadRequest uploads an executable with an optional source archive
+type UploadRequest struct {
+	Filename       string
+	Executable     []byte
+	SourceArchive  []byte
+	SourceFilename string
+}
+
+func (m *UploadRequest) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Filename = f.string()
+		case 2:
+			m.Executable = f.Bytes
+		case 3:
+			m.SourceArchive = f.Bytes
+		case 4:
+			m.SourceFilename = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *UploadRequest) marshal() []byte {
+	var e encoder
+	e.string(1, m.Filename)
+	e.bytes(2, m.Executable)
+	e.bytes(3, m.SourceArchive)
+	e.string(4, m.SourceFilename)
+	return e.buf
+}
+
+// UploadResponse describes an uploaded executable and the session started for it
+type UploadResponse struct {
+	Filename     string
+	Format       string
+	SessionToken string // Subscribes a Terminal stream to the session
+	SourceFiles  int
+}
+
+func (m *UploadResponse) marshal() []byte {
+	var e encoder
+	e.string(1, m.Filename)
+	e.string(2, m.Format)
+	e.string(3, m.SessionToken)
+	e.int(4, m.SourceFiles)
+	return e.buf
+}
+
+func (m *UploadResponse) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Filename = f.string()
+		case 2:
+			m.Format = f.string()
+		case 3:
+			m.SessionToken = f.string()
+		case 4:
+			m.SourceFiles = f.int()
+		}
+		return nil
+	})
+}
+
+// StartDebuggerRequest starts GDB on an uploaded executable, with how the program is run
+type StartDebuggerRequest struct {
+	Filename   string
+	Args       []string
+	Env        map[string]string
+	StdinFile  string // A file of the source archive uploaded with the executable
+	WorkingDir string
+}
+
+func (m *StartDebuggerRequest) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Filename = f.string()
+		case 2:
+			m.Args = append(m.Args, f.string())
+		case 3:
+			// A map entry: key 1, value 2
+			var name, value string
+			if err := decode(f.Bytes, func(f field) error {
+				switch f.Number {
+				case 1:
+					name = f.string()
+				case 2:
+					value = f.string()
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if m.Env == nil {
+				m.Env = make(map[string]string)
+			}
+			m.Env[name] = value
+		case 4:
+			m.StdinFile = f.string()
+		case 5:
+			m.WorkingDir = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *StartDebuggerRequest) marshal() []byte {
+	var e encoder
+	e.string(1, m.Filename)
+	for _, arg := range m.Args {
+		e.message(2, []byte(arg))
+	}
+	names := make([]string, 0, len(m.Env))
+	for name := range m.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var entry encoder
+		entry.string(1, name)
+		entry.string(2, m.Env[name])
+		e.message(3, entry.buf)
+	}
+	e.string(4, m.StdinFile)
+	e.string(5, m.WorkingDir)
+	return e.buf
+}
+
+// MessageResponse is the response of StartDebugger and StopDebugger
+type MessageResponse struct {
+	Message string
+}
+
+func (m *MessageResponse) marshal() []byte {
+	var e encoder
+	e.string(1, m.Message)
+	return e.buf
+}
+
+func (m *MessageResponse) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		if f.Number == 1 {
+			m.Message = f.string()
+		}
+		return nil
+	})
+}
+
+// SendCommandRequest runs a GDB command
+type SendCommandRequest struct {
+	Command string
+}
+
+func (m *SendCommandRequest) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		if f.Number == 1 {
+			m.Command = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *SendCommandRequest) marshal() []byte {
+	var e encoder
+	e.string(1, m.Command)
+	return e.buf
+}
+
+// SendCommandResponse holds a GDB command's output
+type SendCommandResponse struct {
+	Output string
+}
+
+func (m *SendCommandResponse) marshal() []byte {
+	var e encoder
+	e.string(1, m.Output)
+	return e.buf
+}
+
+func (m *SendCommandResponse) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		if f.Number == 1 {
+			m.Output = f.string()
+		}
+		return nil
+	})
+}
+
+// ChatRequest asks the assistant a question, like a POST /api/chat request
+type ChatRequest struct {
+	Message       string `json:"message"`
+	RequestID     string `json:"requestId,omitempty"`
+	Model         string `json:"model,omitempty"`
+	MaxTokens     int    `json:"maxTokens,omitempty"`
+	TerminalLines int    `json:"terminalLines,omitempty"`
+	Profile       string `json:"profile,omitempty"`
+}
+
+func (m *ChatRequest) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Message = f.string()
+		case 2:
+			m.RequestID = f.string()
+		case 3:
+			m.Model = f.string()
+		case 4:
+			m.MaxTokens = f.int()
+		case 5:
+			m.TerminalLines = f.int()
+		case 6:
+			m.Profile = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *ChatRequest) marshal() []byte {
+	var e encoder
+	e.string(1, m.Message)
+	e.string(2, m.RequestID)
+	e.string(3, m.Model)
+	e.int(4, m.MaxTokens)
+	e.int(5, m.TerminalLines)
+	e.string(6, m.Profile)
+	return e.buf
+}
+
+// ChatResponse is the assistant's answer, decoded from the JSON of POST /api/chat
+type ChatResponse struct {
+	Response          string   `json:"response"`
+	Model             string   `json:"model"`
+	SuggestedCommands []string `json:"suggestedCommands"`
+	Refused           bool     `json:"refused"`
+	Cancelled         bool     `json:"cancelled"`
+	Cost              float64  `json:"cost"`
+}
+
+func (m *ChatResponse) marshal() []byte {
+	var e encoder
+	e.string(1, m.Response)
+	e.string(2, m.Model)
+	for _, command := range m.SuggestedCommands {
+		e.message(3, []byte(command))
+	}
+	e.bool(4, m.Refused)
+	e.bool(5, m.Cancelled)
+	e.double(6, m.Cost)
+	return e.buf
+}
+
+func (m *ChatResponse) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Response = f.string()
+		case 2:
+			m.Model = f.string()
+		case 3:
+			m.SuggestedCommands = append(m.SuggestedCommands, f.string())
+		case 4:
+			m.Refused = f.bool()
+		case 5:
+			m.Cancelled = f.bool()
+		case 6:
+			m.Cost = math.Float64frombits(f.Varint)
+		}
+		return nil
+	})
+}
+
+// TerminalRequest is a message of a Terminal stream. The first one sets SessionToken;
+// each later one sets one of Command, Input or the terminal's size.
+type TerminalRequest struct {
+	SessionToken string
+	Command      string
+	Input        string
+	Resize       *Resize
+}
+
+// Resize is the size of a client's terminal
+type Resize struct {
+	Rows int
+	Cols int
+}
+
+func (m *TerminalRequest) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.SessionToken = f.string()
+		case 2:
+			m.Command = f.string()
+		case 3:
+			m.Input = f.string()
+		case 4:
+			m.Resize = &Resize{}
+			return decode(f.Bytes, func(f field) error {
+				switch f.Number {
+				case 1:
+					m.Resize.Rows = f.int()
+				case 2:
+					m.Resize.Cols = f.int()
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func (m *TerminalRequest) marshal() []byte {
+	var e encoder
+	switch {
+	case m.SessionToken != "":
+		e.message(1, []byte(m.SessionToken))
+	case m.Command != "":
+		e.message(2, []byte(m.Command))
+	case m.Input != "":
+		e.message(3, []byte(m.Input))
+	case m.Resize != nil:
+		var resize encoder
+		resize.int(1, m.Resize.Rows)
+		resize.int(2, m.Resize.Cols)
+		e.message(4, resize.buf)
+	}
+	return e.buf
+}
+
+// TerminalEvent is a message of a Terminal stream from the server: output of GDB or the
+// program, a change of the session's status, or why a request was rejected
+type TerminalEvent struct {
+	Output string
+	Status *Status
+	Error  string
+}
+
+// Status is the state of the session's GDB process
+type Status struct {
+	GDB    string
+	File   string
+	Reason string
+}
+
+func (m *TerminalEvent) marshal() []byte {
+	var e encoder
+	switch {
+	case m.Status != nil:
+		var status encoder
+		status.string(1, m.Status.GDB)
+		status.string(2, m.Status.File)
+		status.string(3, m.Status.Reason)
+		e.message(2, status.buf)
+	case m.Error != "":
+		e.message(3, []byte(m.Error))
+	default:
+		e.message(1, []byte(m.Output))
+	}
+	return e.buf
+}
+
+func (m *TerminalEvent) unmarshal(b []byte) error {
+	return decode(b, func(f field) error {
+		switch f.Number {
+		case 1:
+			m.Output = f.string()
+		case 2:
+			m.Status = &Status{}
+			return decode(f.Bytes, func(f field) error {
+				switch f.Number {
+				case 1:
+					m.Status.GDB = f.string()
+				case 2:
+					m.Status.File = f.string()
+				case 3:
+					m.Status.Reason = f.string()
+				}
+				return nil
+			})
+		case 3:
+			m.Error = f.string()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+package grpcapi
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoMessage is a message encoded and decoded by hand
+type protoMessage interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+var (
+	protoMessageLine = regexp.MustCompile(`^message (\w+) \{(\})?$`)
+	protoFieldLine   = regexp.MustCompile(`^\s+(?:repeated\s+)?(?:map<[^>]+>|\w+)\s+(\w+)\s*=\s*(\d+);`)
+)
+
+// readProto returns the field numbers of each message in debugger.proto, by field name
+// without underscores
+func readProto(t *testing.T) map[string]map[string]int {
+	file, err := os.Open("debugger.proto")
+	require.NoError(t, err)
+	defer file.Close()
+
+	messages := map[string]map[string]int{}
+	var current map[string]int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := protoMessageLine.FindStringSubmatch(line); match != nil {
+			current = map[string]int{}
+			messages[match[1]] = current
+			continue
+		}
+		if line == "}" {
+			current = nil
+		}
+		if match := protoFieldLine.FindStringSubmatch(line); match != nil && current != nil {
+			number, _ := strconv.Atoi(match[2])
+			current[strings.ReplaceAll(match[1], "_", "")] = number
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return messages
+}
+
+// sample returns a value of a field's type that is not its zero value
+func sample(t *testing.T, typ reflect.Type) reflect.Value {
+	switch typ.Kind() {
+	case reflect.String:
+		return reflect.ValueOf("x").Convert(typ)
+	case reflect.Int:
+		return reflect.ValueOf(7).Convert(typ)
+	case reflect.Bool:
+		return reflect.ValueOf(true)
+	case reflect.Float64:
+		return reflect.ValueOf(1.5)
+	case reflect.Uint8:
+		return reflect.ValueOf(byte(1))
+	case reflect.Slice:
+		return reflect.Append(reflect.MakeSlice(typ, 0, 1), sample(t, typ.Elem()))
+	case reflect.Map:
+		m := reflect.MakeMap(typ)
+		m.SetMapIndex(sample(t, typ.Key()), sample(t, typ.Elem()))
+		return m
+	case reflect.Ptr:
+		return reflect.New(typ.Elem())
+	}
+	t.Fatalf("no sample for %s", typ)
+	return reflect.Value{}
+}
+
+// fieldNumbers returns the numbers of the fields of an encoded message
+func fieldNumbers(t *testing.T, b []byte) map[int]bool {
+	numbers := map[int]bool{}
+	require.NoError(t, decode(b, func(f field) error {
+		numbers[f.Number] = true
+		return nil
+	}))
+	return numbers
+}
+
+// TestMessagesMatchProto checks every field of the hand-written messages against
+// debugger.proto: set on its own, it is encoded with the number the .proto gives it and
+// decoded back.
+func TestMessagesMatchProto(t *testing.T) {
+	proto := readProto(t)
+	messages := map[string]protoMessage{
+		"UploadRequest":         &UploadRequest{},
+		"UploadResponse":        &UploadResponse{},
+		"StartDebuggerRequest":  &StartDebuggerRequest{},
+		"StartDebuggerResponse": &MessageResponse{},
+		"StopDebuggerResponse":  &MessageResponse{},
+		"SendCommandRequest":    &SendCommandRequest{},
+		"SendCommandResponse":   &SendCommandResponse{},
+		"ChatRequest":           &ChatRequest{},
+		"ChatResponse":          &ChatResponse{},
+		"TerminalRequest":       &TerminalRequest{},
+		"TerminalEvent":         &TerminalEvent{},
+	}
+	for name, fields := range proto {
+		if _, ok := messages[name]; !ok && len(fields) > 0 && name != "Resize" && name != "Status" {
+			t.Errorf("message %s of debugger.proto has no Go type", name)
+		}
+	}
+
+	for name, message := range messages {
+		fields, ok := proto[name]
+		require.True(t, ok, "message %s is not in debugger.proto", name)
+		typ := reflect.TypeOf(message).Elem()
+		assert.Equal(t, len(fields), typ.NumField(), "fields of %s", name)
+
+		for i := 0; i < typ.NumField(); i++ {
+			goField := typ.Field(i)
+			number, ok := fields[strings.ToLower(goField.Name)]
+			if !assert.True(t, ok, "%s.%s is not in debugger.proto", name, goField.Name) {
+				continue
+			}
+			value := reflect.New(typ)
+			value.Elem().Field(i).Set(sample(t, goField.Type))
+			encoded := value.Interface().(protoMessage).marshal()
+			assert.Equal(t, map[int]bool{number: true}, fieldNumbers(t, encoded), "%s.%s", name, goField.Name)
+
+			decoded := reflect.New(typ).Interface().(protoMessage)
+			require.NoError(t, decoded.unmarshal(encoded))
+			assert.Equal(t, value.Interface(), decoded, "%s.%s", name, goField.Name)
+		}
+	}
+}
+
+// TestEmbeddedMessagesMatchProto checks the fields of the messages embedded in the
+// Terminal stream's messages the same way
+func TestEmbeddedMessagesMatchProto(t *testing.T) {
+	proto := readProto(t)
+	embedded := map[string]func(v reflect.Value) protoMessage{
+		"Resize": func(v reflect.Value) protoMessage { return &TerminalRequest{Resize: v.Interface().(*Resize)} },
+		"Status": func(v reflect.Value) protoMessage { return &TerminalEvent{Status: v.Interface().(*Status)} },
+	}
+	types := map[string]reflect.Type{"Resize": reflect.TypeOf(Resize{}), "Status": reflect.TypeOf(Status{})}
+
+	for name, wrap := range embedded {
+		typ := types[name]
+		assert.Equal(t, len(proto[name]), typ.NumField(), "fields of %s", name)
+		for i := 0; i < typ.NumField(); i++ {
+			goField := typ.Field(i)
+			number, ok := proto[name][strings.ToLower(goField.Name)]
+			if !assert.True(t, ok, "%s.%s is not in debugger.proto", name, goField.Name) {
+				continue
+			}
+			value := reflect.New(typ)
+			value.Elem().Field(i).Set(sample(t, goField.Type))
+			message := wrap(value)
+			var inner []byte
+			require.NoError(t, decode(message.marshal(), func(f field) error {
+				inner = f.Bytes
+				return nil
+			}))
+			assert.Equal(t, map[int]bool{number: true}, fieldNumbers(t, inner), "%s.%s", name, goField.Name)
+
+			decoded := reflect.New(reflect.TypeOf(message).Elem()).Interface().(protoMessage)
+			require.NoError(t, decoded.unmarshal(message.marshal()))
+			assert.Equal(t, message, decoded, "%s.%s", name, goField.Name)
+		}
+	}
+}
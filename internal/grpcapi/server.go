@@ -0,0 +1,363 @@
+// Package grpcapi serves the gRPC API described in debugger.proto. It speaks the gRPC
+// protocol over the standard library's HTTP/2 server, and mirrors the HTTP endpoints:
+// uploads and chat requests are passed to the router as HTTP requests, so they are
+// validated and limited the same way.
+//
+// The messages are encoded by hand (messages.go, wire.go) rather than generated with
+// protoc-gen-go and served with google.golang.org/grpc. That is deliberate: the server
+// builds from the standard library and its existing modules alone, without protoc in
+// the build or the grpc module's dependency tree in the binary, and the API is small
+// and changes rarely. debugger.proto remains the contract; messages_test.go checks every
+// hand-written field against it, so a field added to the .proto without a Go
+// counterpart, or given a different number, fails the tests. Clients generate their
+// stubs from debugger.proto as usual.
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// servicePath is the path prefix of the Debugger service's methods
+const servicePath = "/gogdbllm.v1.Debugger/"
+
+// gRPC status codes
+const (
+	codeOK                 = 0
+	codeCanceled           = 1
+	codeUnknown            = 2
+	codeInvalidArgument    = 3
+	codeDeadlineExceeded   = 4
+	codeNotFound           = 5
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeUnauthenticated    = 16
+)
+
+var (
+	errUnimplemented     = errors.New("unimplemented")
+	errResourceExhausted = errors.New("resource exhausted")
+)
+
+// Sessions is the part of the GDB handler the API drives debugging sessions through
+type Sessions interface {
+	websocket.GDBHandler
+
+	// StartSessionWithProgram starts GDB on one of user's uploaded executables, running
+	// the program with the options
+	StartSessionWithProgram(user, filename string, program gdb.ProgramOptions) error
+
+	// StopSession stops the current session's GDB for user
+	StopSession(user string) error
+
+	// RunUserCommand runs a GDB command for user and returns its output
+	RunUserCommand(user, cmd string) (string, error)
+}
+
+// Server is the gRPC API's HTTP handler
+type Server struct {
+	sessions Sessions
+	hub      *websocket.Hub
+	router   http.Handler // Serves the HTTP endpoints uploads and chat requests are passed to
+}
+
+// NewServer creates the gRPC API's handler. It expects requests to carry the user set
+// by the authenticator's middleware.
+func NewServer(sessions Sessions, hub *websocket.Hub, router http.Handler) *Server {
+	return &Server{sessions: sessions, hub: hub, router: router}
+}
+
+// httpError is a failed response from the router
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+// ServeHTTP serves a gRPC call
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || r.Method != http.MethodPost ||
+		!strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests must be POSTs over HTTP/2 with content type application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	method := strings.TrimPrefix(r.URL.Path, servicePath)
+	if method == "Terminal" {
+		finish(w, s.terminal(w, r))
+		return
+	}
+
+	unary := map[string]func(*http.Request, []byte) ([]byte, error){
+		"Upload":        s.upload,
+		"StartDebugger": s.startDebugger,
+		"StopDebugger":  s.stopDebugger,
+		"SendCommand":   s.sendCommand,
+		"Chat":          s.chat,
+	}[method]
+	if unary == nil || !strings.HasPrefix(r.URL.Path, servicePath) {
+		finish(w, fmt.Errorf("%w: unknown method %s", errUnimplemented, r.URL.Path))
+		return
+	}
+
+	request, err := readFrame(r.Body)
+	if err == io.EOF {
+		err = errMalformed
+	}
+	if err != nil {
+		finish(w, err)
+		return
+	}
+	response, err := unary(r, request)
+	if err == nil {
+		err = writeFrame(w, response)
+	}
+	finish(w, err)
+}
+
+// finish ends a call with the status of err in the trailers
+func finish(w http.ResponseWriter, err error) {
+	code, message := codeOK, ""
+	if err != nil {
+		code, message = codeOf(err), err.Error()
+	}
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	w.Header().Set("Grpc-Message", encodeMessage(message))
+}
+
+// codeOf returns the gRPC status code for an error
+func codeOf(err error) int {
+	var httpErr *httpError
+	switch {
+	case errors.Is(err, errMalformed):
+		return codeInvalidArgument
+	case errors.Is(err, errUnimplemented):
+		return codeUnimplemented
+	case errors.Is(err, errResourceExhausted):
+		return codeResourceExhausted
+	case errors.Is(err, context.Canceled):
+		return codeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codeDeadlineExceeded
+	case errors.Is(err, appErrors.ErrGDBNotRunning):
+		return codeFailedPrecondition
+	case errors.As(err, &httpErr):
+		return codeForStatus(httpErr.status)
+	default:
+		return codeForStatus(appErrors.StatusCode(err))
+	}
+}
+
+// codeForStatus returns the gRPC status code for an HTTP status
+func codeForStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codeInvalidArgument
+	case http.StatusUnauthorized:
+		return codeUnauthenticated
+	case http.StatusForbidden:
+		return codePermissionDenied
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codeDeadlineExceeded
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codeResourceExhausted
+	case http.StatusNotImplemented:
+		return codeUnimplemented
+	case http.StatusServiceUnavailable:
+		return codeUnavailable
+	case http.StatusInternalServerError:
+		return codeInternal
+	default:
+		return codeUnknown
+	}
+}
+
+// encodeMessage percent-encodes a status message for the Grpc-Message trailer
+func encodeMessage(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c >= 0x20 && c <= 0x7e && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// user returns the user the authenticator's middleware set on a request
+func user(r *http.Request) string {
+	user, _ := auth.UserFromContext(r.Context())
+	return user
+}
+
+// upload passes an upload to POST /upload as a multipart form
+func (s *Server) upload(r *http.Request, b []byte) ([]byte, error) {
+	var req UploadRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, err
+	}
+	if req.Filename == "" || len(req.Executable) == 0 {
+		return nil, fmt.Errorf("%w: filename and executable are required", appErrors.ErrBadRequest)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("executable", req.Filename)
+	part.Write(req.Executable)
+	if len(req.SourceArchive) > 0 {
+		name := req.SourceFilename
+		if name == "" {
+			name = "sources.tar.gz"
+		}
+		part, _ = form.CreateFormFile("source", name)
+		part.Write(req.SourceArchive)
+	}
+	form.Close()
+
+	var resp struct {
+		Data struct {
+			Filename     string `json:"filename"`
+			Format       string `json:"format"`
+			SessionToken string `json:"sessionToken"`
+			SourceFiles  int    `json:"sourceFiles"`
+		} `json:"data"`
+	}
+	if err := s.forward(r, "/upload", form.FormDataContentType(), &body, &resp); err != nil {
+		return nil, err
+	}
+	return (&UploadResponse{
+		Filename:     resp.Data.Filename,
+		Format:       resp.Data.Format,
+		SessionToken: resp.Data.SessionToken,
+		SourceFiles:  resp.Data.SourceFiles,
+	}).marshal(), nil
+}
+
+// startDebugger starts GDB on an uploaded executable
+func (s *Server) startDebugger(r *http.Request, b []byte) ([]byte, error) {
+	var req StartDebuggerRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, err
+	}
+	if req.Filename == "" {
+		return nil, fmt.Errorf("%w: filename is required", appErrors.ErrBadRequest)
+	}
+	program := gdb.ProgramOptions{Args: req.Args, Env: req.Env, StdinFile: req.StdinFile, WorkingDir: req.WorkingDir}
+	if err := s.sessions.StartSessionWithProgram(user(r), req.Filename, program); err != nil {
+		return nil, err
+	}
+	return (&MessageResponse{Message: "GDB started successfully"}).marshal(), nil
+}
+
+// stopDebugger stops the session's GDB
+func (s *Server) stopDebugger(r *http.Request, b []byte) ([]byte, error) {
+	if err := s.sessions.StopSession(user(r)); err != nil {
+		return nil, err
+	}
+	return (&MessageResponse{Message: "GDB stopped"}).marshal(), nil
+}
+
+// sendCommand runs a GDB command and returns its output
+func (s *Server) sendCommand(r *http.Request, b []byte) ([]byte, error) {
+	var req SendCommandRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		return nil, fmt.Errorf("%w: command is required", appErrors.ErrBadRequest)
+	}
+	output, err := s.sessions.RunUserCommand(user(r), req.Command)
+	if err != nil {
+		return nil, err
+	}
+	return (&SendCommandResponse{Output: output}).marshal(), nil
+}
+
+// chat passes a chat request to POST /api/chat
+func (s *Server) chat(r *http.Request, b []byte) ([]byte, error) {
+	var req ChatRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, err
+	}
+	body, _ := json.Marshal(req)
+
+	var resp ChatResponse
+	if err := s.forward(r, "/api/chat", "application/json", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+	return resp.marshal(), nil
+}
+
+// forward serves a POST to path with the router, with the credentials of the gRPC call,
+// and decodes the JSON response into v
+func (s *Server) forward(r *http.Request, path, contentType string, body io.Reader, v interface{}) error {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	req.RemoteAddr = r.RemoteAddr
+	req.Header.Set("Content-Type", contentType)
+	for _, name := range []string{"Authorization", "Cookie", "Traceparent"} {
+		for _, value := range r.Header.Values(name) {
+			req.Header.Add(name, value)
+		}
+	}
+
+	rec := &recorder{header: make(http.Header), status: http.StatusOK}
+	s.router.ServeHTTP(rec, req)
+	if rec.status != http.StatusOK {
+		return &httpError{status: rec.status, message: errorMessage(rec.body.Bytes())}
+	}
+	if err := json.Unmarshal(rec.body.Bytes(), v); err != nil {
+		return fmt.Errorf("decoding the response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// errorMessage returns the error of a failed response: the error field of a JSON body,
+// or a plain text body
+func errorMessage(body []byte) string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error != "" {
+		return resp.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// recorder collects the response of a forwarded request
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
@@ -0,0 +1,316 @@
+package grpcapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// fakeSessions records what the API asks of the GDB handler
+type fakeSessions struct {
+	mutex    sync.Mutex
+	commands []string
+	started  string
+	program  gdb.ProgramOptions
+}
+
+func (f *fakeSessions) HandleUserCommand(user, cmd string) error {
+	if cmd == "bad" {
+		return fmt.Errorf("%w: rejected", appErrors.ErrForbidden)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.commands = append(f.commands, cmd)
+	return nil
+}
+
+func (f *fakeSessions) SubscribeSession(user, token string) (string, error) {
+	if token != "token" {
+		return "", fmt.Errorf("%w: invalid or expired session token", appErrors.ErrForbidden)
+	}
+	return "session-1", nil
+}
+
+func (f *fakeSessions) WatchSession(token string) (string, time.Time, error) {
+	return "", time.Time{}, fmt.Errorf("%w: invalid or expired share link", appErrors.ErrForbidden)
+}
+
+func (f *fakeSessions) HandleProgramInput(user, input string) error      { return nil }
+func (f *fakeSessions) ResizeTerminal(user string, rows, cols int) error { return nil }
+func (f *fakeSessions) CompleteCommand(user, text string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeSessions) StartSessionWithProgram(user, filename string, program gdb.ProgramOptions) error {
+	f.started, f.program = filename, program
+	return nil
+}
+
+func (f *fakeSessions) StopSession(user string) error {
+	return fmt.Errorf("%w: the debugging session belongs to another user", appErrors.ErrForbidden)
+}
+
+func (f *fakeSessions) RunUserCommand(user, cmd string) (string, error) {
+	return "$1 = 42\n", nil
+}
+
+func (f *fakeSessions) received() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+// newTestServer serves the API over HTTP/2 with TLS, with a router standing in for the
+// HTTP endpoints
+func newTestServer(t *testing.T, sessions Sessions, hub *websocket.Hub) *httptest.Server {
+	return serveTLS(t, NewServer(sessions, hub, testRouter(t)))
+}
+
+// serveTLS serves handler over HTTP/2 with TLS
+func serveTLS(t *testing.T, handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newPlaintextTestServer serves the API over unencrypted HTTP/2, as with grpc.plaintext
+func newPlaintextTestServer(t *testing.T, sessions Sessions, hub *websocket.Hub) *httptest.Server {
+	server := httptest.NewUnstartedServer(NewServer(sessions, hub, testRouter(t)))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+	server.Client().Transport.(*http.Transport).Protocols = server.Config.Protocols
+	return server
+}
+
+// testRouter stands in for the HTTP endpoints the API forwards requests to
+func testRouter(t *testing.T) http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("executable")
+		if !assert.NoError(t, err) {
+			return
+		}
+		content, _ := io.ReadAll(file)
+		assert.Equal(t, "\x7fELF", string(content))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"filename": header.Filename, "format": "ELF", "sessionToken": "token"},
+		})
+	})
+	router.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	})
+	return router
+}
+
+// unary makes a unary call and returns the response message and status
+func unary(t *testing.T, server *httptest.Server, method string, request []byte) ([]byte, string, string) {
+	var body bytes.Buffer
+	require.NoError(t, writeFrame(&body, request))
+	req, err := http.NewRequest(http.MethodPost, server.URL+servicePath+method, &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc")
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	message, err := readFrame(resp.Body)
+	if err == io.EOF {
+		message = nil
+	} else {
+		require.NoError(t, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return message, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+func TestUnaryCalls(t *testing.T) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	sessions := &fakeSessions{}
+	server := newTestServer(t, sessions, hub)
+
+	message, status, _ := unary(t, server, "Upload", (&UploadRequest{Filename: "crash", Executable: []byte("\x7fELF")}).marshal())
+	assert.Equal(t, "0", status)
+	var upload UploadResponse
+	require.NoError(t, upload.unmarshal(message))
+	assert.Equal(t, UploadResponse{Filename: "crash", Format: "ELF", SessionToken: "token"}, upload)
+
+	_, status, _ = unary(t, server, "StartDebugger", (&StartDebuggerRequest{
+		Filename:  "crash",
+		Args:      []string{"-v", ""},
+		Env:       map[string]string{"MALLOC_CHECK_": "3", "LANG": "C"},
+		StdinFile: "inputs/crash.txt",
+	}).marshal())
+	assert.Equal(t, "0", status)
+	assert.Equal(t, "crash", sessions.started)
+	assert.Equal(t, gdb.ProgramOptions{
+		Args:      []string{"-v", ""},
+		Env:       map[string]string{"MALLOC_CHECK_": "3", "LANG": "C"},
+		StdinFile: "inputs/crash.txt",
+	}, sessions.program)
+
+	message, status, _ = unary(t, server, "SendCommand", (&SendCommandRequest{Command: "print x"}).marshal())
+	assert.Equal(t, "0", status)
+	var output SendCommandResponse
+	require.NoError(t, output.unmarshal(message))
+	assert.Equal(t, "$1 = 42\n", output.Output)
+
+	// Errors of the handler and of forwarded requests map to gRPC status codes
+	_, status, errMessage := unary(t, server, "StopDebugger", nil)
+	assert.Equal(t, "7", status)
+	assert.Contains(t, errMessage, "belongs to another user")
+
+	_, status, errMessage = unary(t, server, "Chat", (&ChatRequest{Message: "why?"}).marshal())
+	assert.Equal(t, "3", status)
+	assert.Equal(t, "Invalid request body", errMessage)
+
+	_, status, _ = unary(t, server, "Missing", nil)
+	assert.Equal(t, "12", status)
+}
+
+func TestUnaryCallOverPlaintext(t *testing.T) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	server := newPlaintextTestServer(t, &fakeSessions{}, hub)
+
+	message, status, _ := unary(t, server, "SendCommand", (&SendCommandRequest{Command: "print x"}).marshal())
+	assert.Equal(t, "0", status)
+	var output SendCommandResponse
+	require.NoError(t, output.unmarshal(message))
+	assert.Equal(t, "$1 = 42\n", output.Output)
+}
+
+func TestTerminalStream(t *testing.T) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	sessions := &fakeSessions{}
+	server := newTestServer(t, sessions, hub)
+
+	requests, requestWriter := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, server.URL+servicePath+"Terminal", requests)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc")
+	go writeFrame(requestWriter, (&TerminalRequest{SessionToken: "token"}).marshal())
+
+	// The response starts once the stream is subscribed to the session
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	next := func() TerminalEvent {
+		message, err := readFrame(resp.Body)
+		require.NoError(t, err)
+		var event TerminalEvent
+		require.NoError(t, event.unmarshal(message))
+		return event
+	}
+
+	hub.BroadcastToSession("session-1", "Breakpoint 1, main ()\r\n")
+	hub.BroadcastToSession("other-session", "not for this stream")
+	hub.BroadcastSessionStatus("session-1", websocket.StatusPayload{GDB: "exited", Reason: "crashed"})
+	assert.Equal(t, TerminalEvent{Output: "Breakpoint 1, main ()\r\n"}, next())
+	assert.Equal(t, TerminalEvent{Status: &Status{GDB: "exited", Reason: "crashed"}}, next())
+
+	require.NoError(t, writeFrame(requestWriter, (&TerminalRequest{Command: "bad"}).marshal()))
+	assert.Contains(t, next().Error, "rejected")
+	require.NoError(t, writeFrame(requestWriter, (&TerminalRequest{Command: "next"}).marshal()))
+
+	// Closing the client's side ends the stream
+	requestWriter.Close()
+	io.Copy(io.Discard, resp.Body)
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, []string{"next"}, sessions.received())
+}
+
+func TestViewerWatchesTerminal(t *testing.T) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	sessions := &fakeSessions{}
+	authenticator, err := auth.NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "token", Token: "s3cret", DefaultRole: auth.RoleViewer}})
+	require.NoError(t, err)
+	server := serveTLS(t, authenticator.Middleware(NewServer(sessions, hub, testRouter(t))))
+	call := func(method string, body io.Reader) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+servicePath+method, body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Authorization", "Bearer s3cret")
+		return server.Client().Do(req)
+	}
+
+	// Viewers cannot call the methods that change the session
+	resp, err := call("SendCommand", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	requests, requestWriter := io.Pipe()
+	go writeFrame(requestWriter, (&TerminalRequest{SessionToken: "token"}).marshal())
+	resp, err = call("Terminal", requests)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	next := func() TerminalEvent {
+		message, err := readFrame(resp.Body)
+		require.NoError(t, err)
+		var event TerminalEvent
+		require.NoError(t, event.unmarshal(message))
+		return event
+	}
+
+	// They watch the session, but what they send is refused
+	hub.BroadcastToSession("session-1", "Breakpoint 1, main ()\r\n")
+	assert.Equal(t, TerminalEvent{Output: "Breakpoint 1, main ()\r\n"}, next())
+	require.NoError(t, writeFrame(requestWriter, (&TerminalRequest{Command: "next"}).marshal()))
+	assert.Contains(t, next().Error, "the viewer role may only watch")
+	require.NoError(t, writeFrame(requestWriter, (&TerminalRequest{Input: "y\n"}).marshal()))
+	assert.Contains(t, next().Error, "the viewer role may only watch")
+
+	requestWriter.Close()
+	io.Copy(io.Discard, resp.Body)
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	assert.Empty(t, sessions.received())
+}
+
+func TestTerminalRejectsInvalidToken(t *testing.T) {
+	hub := websocket.NewHub(&config.Config{})
+	go hub.Run()
+	server := newTestServer(t, &fakeSessions{}, hub)
+
+	_, status, message := unary(t, server, "Terminal", (&TerminalRequest{SessionToken: "stolen"}).marshal())
+	assert.Equal(t, "7", status)
+	assert.Contains(t, message, "invalid or expired session token")
+}
+
+func TestRequiresGRPCOverHTTP2(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, servicePath+"SendCommand", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+	NewServer(&fakeSessions{}, nil, nil).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestEncodeMessage(t *testing.T) {
+	assert.Equal(t, "GDB is not running: 100%25 sure", encodeMessage("GDB is not running: 100% sure"))
+	assert.Equal(t, "line%0Anext %C3%A9", encodeMessage("line\nnext é"))
+}
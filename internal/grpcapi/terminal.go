@@ -0,0 +1,152 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// terminal serves a Terminal stream, which does what the WebSocket does for the UI: it
+// subscribes to a session with its token, then relays the session's output and status
+// to the client and the client's commands, input and resizes to the session
+func (s *Server) terminal(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("%w: streaming is not supported", errUnimplemented)
+	}
+	user := user(r)
+
+	first, err := readFrame(r.Body)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var subscribe TerminalRequest
+	if err := subscribe.unmarshal(first); err != nil {
+		return err
+	}
+	if subscribe.SessionToken == "" {
+		return fmt.Errorf("%w: the first request must carry the session token", appErrors.ErrBadRequest)
+	}
+	sessionID, err := s.sessions.SubscribeSession(user, subscribe.SessionToken)
+	if err != nil {
+		return err
+	}
+
+	client := s.hub.Subscribe(user, sessionID)
+	defer s.hub.Unsubscribe(client)
+
+	// Events come from the hub and from rejected requests. The goroutine reading requests
+	// may still be running when the stream ends, and must not write after that.
+	var writeMutex sync.Mutex
+	ended := false
+	defer func() {
+		writeMutex.Lock()
+		ended = true
+		writeMutex.Unlock()
+	}()
+	send := func(event *TerminalEvent) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		if ended {
+			return io.ErrClosedPipe
+		}
+		if err := writeFrame(w, event.marshal()); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	requests := make(chan error, 1)
+	go func() {
+		requests <- s.relayRequests(r, user, send)
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			if !ok {
+				return errors.New("disconnected: the client did not keep up with the session's output")
+			}
+			if event := eventFor(message); event != nil {
+				if err := send(event); err != nil {
+					return err
+				}
+			}
+		case err := <-requests:
+			return err
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+	}
+}
+
+// relayRequests passes the requests of a Terminal stream to the session until the client
+// closes its side of the stream. A rejected request, or any request of a viewer, is
+// reported with an error event.
+func (s *Server) relayRequests(r *http.Request, user string, send func(*TerminalEvent) error) error {
+	for {
+		b, err := readFrame(r.Body)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req TerminalRequest
+		if err := req.unmarshal(b); err != nil {
+			return err
+		}
+		if role := auth.RoleFromContext(r.Context()); !auth.Allows(role, auth.RoleDebugger) {
+			err := fmt.Errorf("%w: the %s role may only watch; running commands and typing input require the debugger role", appErrors.ErrForbidden, role)
+			if err := send(&TerminalEvent{Error: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case req.Command != "":
+			err = s.sessions.HandleUserCommand(user, req.Command)
+		case req.Input != "":
+			err = s.sessions.HandleProgramInput(user, req.Input)
+		case req.Resize != nil:
+			err = s.sessions.ResizeTerminal(user, req.Resize.Rows, req.Resize.Cols)
+		default:
+			err = fmt.Errorf("%w: a request must set a command, input or resize", appErrors.ErrBadRequest)
+		}
+		if err != nil {
+			if err := send(&TerminalEvent{Error: err.Error()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventFor returns the Terminal event for a hub message, or nil for messages the stream
+// does not carry, like streamed chat responses
+func eventFor(message websocket.Message) *TerminalEvent {
+	switch payload := message.Payload.(type) {
+	case websocket.OutputPayload:
+		return &TerminalEvent{Output: payload.Text}
+	case websocket.StatusPayload:
+		if payload.GDB == "" {
+			return nil
+		}
+		return &TerminalEvent{Status: &Status{GDB: payload.GDB, File: payload.File, Reason: payload.Reason}}
+	case websocket.ErrorPayload:
+		return &TerminalEvent{Error: payload.Error}
+	}
+	return nil
+}
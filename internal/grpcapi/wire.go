@@ -0,0 +1,168 @@
+package grpcapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Protocol buffer wire types used by the messages in debugger.proto
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// maxMessageSize limits the size of a request message, which holds an upload's whole
+// executable and source archive
+const maxMessageSize = 256 << 20
+
+// errMalformed is returned for a message that is not valid protocol buffer encoding
+var errMalformed = errors.New("malformed protocol buffer message")
+
+// encoder appends the fields of a protocol buffer message. Fields with their zero value
+// are left out, as proto3 does.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(field int, s string) {
+	e.bytes(field, []byte(s))
+}
+
+// message encodes an embedded message, which is written even when empty so a oneof
+// field is set
+func (e *encoder) message(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) int(field int, v int) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, uint64(int64(v)))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.tag(field, wireVarint)
+		e.buf = append(e.buf, 1)
+	}
+}
+
+func (e *encoder) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireI64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// field is a decoded field of a message. Varint holds the value of varint, fixed64 and
+// fixed32 fields; Bytes that of length-delimited ones.
+type field struct {
+	Number int
+	Type   int
+	Varint uint64
+	Bytes  []byte
+}
+
+func (f field) string() string { return string(f.Bytes) }
+func (f field) int() int       { return int(int32(f.Varint)) }
+func (f field) bool() bool     { return f.Varint != 0 }
+
+// decode calls fn with each field of a message in order. Fields fn does not know are
+// skipped, so clients built from a newer debugger.proto keep working.
+func decode(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformed
+		}
+		b = b[n:]
+		f := field{Number: int(key >> 3), Type: int(key & 7)}
+		if f.Number == 0 {
+			return errMalformed
+		}
+		switch f.Type {
+		case wireVarint:
+			if f.Varint, n = binary.Uvarint(b); n <= 0 {
+				return errMalformed
+			}
+			b = b[n:]
+		case wireI64:
+			if len(b) < 8 {
+				return errMalformed
+			}
+			f.Varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireI32:
+			if len(b) < 4 {
+				return errMalformed
+			}
+			f.Varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errMalformed
+			}
+			f.Bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return errMalformed
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFrame reads a length-prefixed gRPC message. io.EOF is returned when the stream
+// ends between messages.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errMalformed
+		}
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, fmt.Errorf("%w: compressed messages are not supported", errUnimplemented)
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("%w: message of %d bytes exceeds the limit of %d", errResourceExhausted, size, maxMessageSize)
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, errMalformed
+	}
+	return message, nil
+}
+
+// writeFrame writes a length-prefixed gRPC message
+func writeFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	_, err := w.Write(append(frame, message...))
+	return err
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// AdminHandler exposes server configuration for administrators
+type AdminHandler struct {
+	cfg             *config.Config
+	settingsManager *settings.Manager
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(cfg *config.Config, settingsManager *settings.Manager) *AdminHandler {
+	return &AdminHandler{
+		cfg:             cfg,
+		settingsManager: settingsManager,
+	}
+}
+
+// HandleEffectiveConfig returns the effective configuration with secrets redacted. The LLM
+// settings are resolved for the requesting user and report which layer supplied each value.
+func (h *AdminHandler) HandleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"llm": h.settingsManager.Effective(user).Redacted(),
+			"precedence": []settings.Source{
+				settings.SourceFlag,
+				settings.SourceEnv,
+				settings.SourceKeychain,
+				settings.SourceSettings,
+				settings.SourceFile,
+				settings.SourceDefault,
+			},
+			"config": config.Redact(*h.cfg),
+		},
+	})
+}
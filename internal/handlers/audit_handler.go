@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Audit error codes returned in Response.Code
+const (
+	AuditErrDisabled       = "audit_disabled"
+	AuditErrInvalidRequest = "invalid_request"
+	AuditErrForbidden      = "forbidden"
+)
+
+// SetAuditTrail makes the handler record the commands users and the LLM run in the trail
+func (h *GDBHandler) SetAuditTrail(trail *audit.Trail) {
+	h.audit = trail
+}
+
+// AuditCommand records that actor ran a command in the current session for user, while
+// serving the HTTP request requestID when there was one, and why it failed
+func (h *GDBHandler) AuditCommand(actor, user, requestID, command string, err error) {
+	if h.audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Action:    audit.ActionCommand,
+		Actor:     actor,
+		User:      user,
+		RequestID: requestID,
+		Command:   command,
+	}
+	if logger := h.loggerHolder.Get(); logger != nil {
+		entry.Session = logger.SessionID()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := h.audit.Record(entry); err != nil {
+		log.Printf("Auditing command %q failed: %v", command, err)
+	}
+}
+
+// AuditHandler serves the audit trail. Audit admins read every entry; other users read
+// the entries of their own actions.
+type AuditHandler struct {
+	trail       *audit.Trail // nil unless auditing is enabled
+	admins      map[string]bool
+	adminsMutex sync.RWMutex
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(cfg *config.Config, trail *audit.Trail) *AuditHandler {
+	return &AuditHandler{trail: trail, admins: adminSet(cfg.Audit.Admins)}
+}
+
+// Reload replaces the audit admins when audit.admins changes
+func (h *AuditHandler) Reload(cfg config.AuditConfig) {
+	h.adminsMutex.Lock()
+	defer h.adminsMutex.Unlock()
+	h.admins = adminSet(cfg.Admins)
+}
+
+// isAdmin reports whether a user may read every entry
+func (h *AuditHandler) isAdmin(user string) bool {
+	h.adminsMutex.RLock()
+	defer h.adminsMutex.RUnlock()
+	return h.admins[user] || (user == "" && len(h.admins) == 0)
+}
+
+// HandleQuery returns the audit entries matching the query, oldest first, e.g.
+// GET /api/v1/audit?session=<id>&actor=llm&limit=100. session, user, actor ("user" or
+// "llm") and action ("gdb.command" or "settings.change") select entries, from and to
+// (RFC 3339) a time range, and limit keeps the last n.
+func (h *AuditHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.trail == nil {
+		writeError(w, http.StatusNotFound, AuditErrDisabled, "Auditing is disabled (audit.enabled)")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Session: query.Get("session"),
+		User:    query.Get("user"),
+		Actor:   query.Get("actor"),
+		Action:  query.Get("action"),
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	if !h.isAdmin(user) {
+		// Without authentication there is no one whose own entries could be told apart
+		if user == "" || (filter.User != "" && filter.User != user) {
+			writeError(w, http.StatusForbidden, AuditErrForbidden, "Only audit admins may read the entries of other users")
+			return
+		}
+		filter.User = user
+	}
+	for name, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, AuditErrInvalidRequest, name+" must be an RFC 3339 time")
+				return
+			}
+			*t = parsed
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, AuditErrInvalidRequest, "limit must be a non-negative number")
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := h.trail.Query(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"entries": entries,
+	}})
+}
+
+// HandleVerify checks that no entry of the trail was changed, removed or inserted, e.g.
+// GET /api/v1/audit/verify; only audit admins may
+func (h *AuditHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.trail == nil {
+		writeError(w, http.StatusNotFound, AuditErrDisabled, "Auditing is disabled (audit.enabled)")
+		return
+	}
+	if user, _ := auth.UserFromContext(r.Context()); !h.isAdmin(user) {
+		writeError(w, http.StatusForbidden, AuditErrForbidden, "Only audit admins may verify the audit trail")
+		return
+	}
+
+	count, err := h.trail.Verify()
+	data := map[string]interface{}{"entries": count, "valid": err == nil}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: data})
+}
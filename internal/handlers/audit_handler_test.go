@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+func TestAuditCommand(t *testing.T) {
+	trail := audit.New(t.TempDir())
+	holder := logsession.NewLoggerHolder()
+	h := NewGDBHandler(websocket.NewHub(&config.Config{}), holder, &config.Config{Uploads: config.UploadsConfig{Directory: t.TempDir()}})
+	h.AuditCommand(audit.ActorUser, "alice", "", "run", nil)
+
+	h.SetAuditTrail(trail)
+	h.AuditCommand(audit.ActorLLM, "alice", "r1", "bt", errors.New("GDB is not running"))
+
+	entries, err := trail.Query(audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "nothing is audited without a trail")
+	assert.Equal(t, audit.ActorLLM, entries[0].Actor)
+	assert.Equal(t, "r1", entries[0].RequestID)
+	assert.Equal(t, "GDB is not running", entries[0].Error)
+
+	// Only audit admins may read the trail without authentication, which has no users
+	handler := NewAuditHandler(&config.Config{}, trail)
+	w := httptest.NewRecorder()
+	handler.HandleQuery(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit?actor=llm&limit=10", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data struct {
+			Entries []audit.Entry `json:"entries"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Len(t, response.Data.Entries, 1)
+	assert.Equal(t, "bt", response.Data.Entries[0].Command)
+
+	w = httptest.NewRecorder()
+	handler.HandleQuery(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit?limit=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	handler.Reload(config.AuditConfig{Admins: []string{"carol"}})
+	w = httptest.NewRecorder()
+	handler.HandleQuery(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = httptest.NewRecorder()
+	handler.HandleVerify(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit/verify", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	NewAuditHandler(&config.Config{}, nil).HandleQuery(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSettingsChanges(t *testing.T) {
+	old := settings.Settings{Provider: "openai", Model: "gpt-4o", APIKey: "sk-old"}
+	changes := settingsChanges(old, settings.Settings{Provider: "openai", Model: "gpt-4o-mini", APIKey: "sk-new", Profile: "exploit"})
+	assert.Equal(t, map[string]audit.Change{
+		"model":   {From: "gpt-4o", To: "gpt-4o-mini"},
+		"profile": {From: "", To: "exploit"},
+		"apiKey":  {From: "(set)", To: "(set)"},
+	}, changes, "API keys are never recorded")
+	assert.Empty(t, settingsChanges(old, old))
+}
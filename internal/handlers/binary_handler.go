@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/decompile"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// BinaryHandler describes a user's uploaded executables statically, without running them:
+// their sections, symbols, imports and strings
+type BinaryHandler struct {
+	uploadsDir string
+	decompiler *decompile.Decompiler // nil unless one is enabled
+}
+
+// NewBinaryHandler creates a new binary handler
+func NewBinaryHandler(cfg *config.Config) *BinaryHandler {
+	return &BinaryHandler{uploadsDir: cfg.Uploads.Directory, decompiler: decompile.New(cfg.Decompiler)}
+}
+
+// path returns the path of the executable a request names, one of the user's uploads
+func (h *BinaryHandler) path(r *http.Request) (string, error) {
+	user, _ := auth.UserFromContext(r.Context())
+	name := sanitizeFilename(mux.Vars(r)["filename"])
+	if name == "" {
+		return "", fmt.Errorf("%w: invalid filename", appErrors.ErrBadRequest)
+	}
+	path := filepath.Join(userUploadsDir(h.uploadsDir, user), name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: no uploaded file %s", appErrors.ErrNotFound, name)
+	}
+	return path, nil
+}
+
+// open opens the executable a request names
+func (h *BinaryHandler) open(r *http.Request) (*binfile.File, error) {
+	path, err := h.path(r)
+	if err != nil {
+		return nil, err
+	}
+	return binfile.Open(path)
+}
+
+// serve opens the executable a request names and writes what describe returns
+func (h *BinaryHandler) serve(w http.ResponseWriter, r *http.Request, describe func(*binfile.File) (interface{}, error)) {
+	f, err := h.open(r)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	defer f.Close()
+	data, err := describe(f)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: data})
+}
+
+// HandleSections lists an executable's sections, e.g.
+// GET /api/v1/binaries/crash/sections
+func (h *BinaryHandler) HandleSections(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, func(f *binfile.File) (interface{}, error) {
+		return f.Sections(), nil
+	})
+}
+
+// HandleSymbols lists an executable's symbols, e.g.
+// GET /api/v1/binaries/crash/symbols?filter=parse&kind=function&defined=true
+func (h *BinaryHandler) HandleSymbols(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, kind := strings.ToLower(query.Get("filter")), query.Get("kind")
+	limit, err := limitParam(query.Get("limit"))
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	h.serve(w, r, func(f *binfile.File) (interface{}, error) {
+		symbols := []binfile.Symbol{}
+		total := 0
+		for _, symbol := range f.Symbols() {
+			if filter != "" && !strings.Contains(strings.ToLower(symbol.Name), filter) ||
+				kind != "" && symbol.Kind != kind ||
+				query.Get("defined") != "" && strconv.FormatBool(symbol.Defined) != query.Get("defined") {
+				continue
+			}
+			total++
+			if len(symbols) < limit {
+				symbols = append(symbols, symbol)
+			}
+		}
+		return map[string]interface{}{
+			"stripped": f.Stripped(),
+			"total":    total,
+			"symbols":  symbols,
+		}, nil
+	})
+}
+
+// HandleImports lists the libraries an executable links and the symbols it imports, e.g.
+// GET /api/v1/binaries/crash/imports
+func (h *BinaryHandler) HandleImports(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, func(f *binfile.File) (interface{}, error) {
+		return f.Imports()
+	})
+}
+
+// HandleStrings lists the strings in an executable's data, e.g.
+// GET /api/v1/binaries/crash/strings?min=6&interesting=true&filter=http
+func (h *BinaryHandler) HandleStrings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := limitParam(query.Get("limit"))
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	opts := binfile.StringOptions{Limit: limit, Filter: query.Get("filter"), Interesting: query.Get("interesting") == "true"}
+	if value := query.Get("min"); value != "" {
+		if opts.Min, err = strconv.Atoi(value); err != nil || opts.Min < 1 {
+			writeDebuggerError(w, fmt.Errorf("%w: min must be a positive number", appErrors.ErrBadRequest))
+			return
+		}
+	}
+	h.serve(w, r, func(f *binfile.File) (interface{}, error) {
+		strs, total, err := f.Strings(opts)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"total": total, "strings": strs}, nil
+	})
+}
+
+// HandleSummary summarizes an executable as the assistant sees it, e.g.
+// GET /api/v1/binaries/crash/summary
+func (h *BinaryHandler) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, func(f *binfile.File) (interface{}, error) {
+		summary, err := f.Summary()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"summary": summary, "text": summary.String()}, nil
+	})
+}
+
+// HandleDecompile returns the code of a function, named by its symbol or address, from
+// the configured decompiler, e.g.
+// GET /api/v1/binaries/crash/decompile?function=FUN_00401136
+func (h *BinaryHandler) HandleDecompile(w http.ResponseWriter, r *http.Request) {
+	function := r.URL.Query().Get("function")
+	if function == "" {
+		writeDebuggerError(w, fmt.Errorf("%w: function is required", appErrors.ErrBadRequest))
+		return
+	}
+	path, err := h.path(r)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	result, err := h.decompiler.Decompile(r.Context(), path, function)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: result})
+}
+
+// limitParam reads a limit query parameter, binfile.DefaultStringLimit if it is empty
+func limitParam(value string) (int, error) {
+	if value == "" {
+		return binfile.DefaultStringLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > binfile.MaxStringLimit {
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", appErrors.ErrBadRequest, binfile.MaxStringLimit)
+	}
+	return limit, nil
+}
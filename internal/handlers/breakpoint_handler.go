@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// BreakpointHandler lists and sets breakpoints. A breakpoint's commands run in the program
+// at each hit, so the user's prompt profile must allow each of them, as it must for the
+// assistant.
+type BreakpointHandler struct {
+	gdbHandler *GDBHandler
+	settings   *settings.Manager
+	prompts    *prompts.Engine
+}
+
+// NewBreakpointHandler creates a new breakpoint handler
+func NewBreakpointHandler(gdbHandler *GDBHandler, settingsManager *settings.Manager, promptEngine *prompts.Engine) *BreakpointHandler {
+	return &BreakpointHandler{gdbHandler: gdbHandler, settings: settingsManager, prompts: promptEngine}
+}
+
+// HandleList returns the breakpoints GDB confirmed, e.g. GET /api/v1/debugger/breakpoints
+func (h *BreakpointHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	breakpoints, err := h.gdbHandler.Breakpoints(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: breakpoints})
+}
+
+// HandleSet sets a breakpoint, e.g. POST /api/v1/debugger/breakpoints with
+// {"location": "parse.c:42", "condition": "len > 64", "commands": ["silent", "print len", "continue"]}
+func (h *BreakpointHandler) HandleSet(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var spec gdb.BreakpointSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+		return
+	}
+	command, err := spec.Command()
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	if err := checkProfile(h.settings, h.prompts, user, command, "this breakpoint"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	output, err := h.gdbHandler.RunUserCommand(user, command)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"command": command,
+		"output":  output,
+	}})
+}
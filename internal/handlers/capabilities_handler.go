@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yourusername/gogdbllm/internal/features"
+)
+
+// CapabilitiesHandler reports the capabilities and feature flags of the current session
+type CapabilitiesHandler struct {
+	features     *features.Manager
+	loggerHolder LoggerHolder
+}
+
+// NewCapabilitiesHandler creates a new capabilities handler
+func NewCapabilitiesHandler(featureManager *features.Manager, loggerHolder LoggerHolder) *CapabilitiesHandler {
+	return &CapabilitiesHandler{
+		features:     featureManager,
+		loggerHolder: loggerHolder,
+	}
+}
+
+// HandleCapabilities returns the feature flags assigned to the active session
+func (h *CapabilitiesHandler) HandleCapabilities(w http.ResponseWriter, r *http.Request) {
+	sessionID := ""
+	if logger := h.loggerHolder.Get(); logger != nil {
+		sessionID = logger.SessionID()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"features":  h.features.ForSession(sessionID),
+		},
+	})
+}
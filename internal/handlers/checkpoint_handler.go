@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// CheckpointHandler makes, lists, restores and deletes checkpoints of the program.
+// Restoring one changes the program's state, so the user's prompt profile must allow
+// the commands, as it must for the assistant.
+type CheckpointHandler struct {
+	gdbHandler *GDBHandler
+	settings   *settings.Manager
+	prompts    *prompts.Engine
+}
+
+// NewCheckpointHandler creates a new checkpoint handler
+func NewCheckpointHandler(gdbHandler *GDBHandler, settingsManager *settings.Manager, promptEngine *prompts.Engine) *CheckpointHandler {
+	return &CheckpointHandler{gdbHandler: gdbHandler, settings: settingsManager, prompts: promptEngine}
+}
+
+// HandleList lists the checkpoints, e.g. GET /api/v1/debugger/checkpoints
+func (h *CheckpointHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	checkpoints, err := h.gdbHandler.Checkpoints(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: checkpoints})
+}
+
+// CheckpointRequest is the body of a request creating a checkpoint
+type CheckpointRequest struct {
+	Note string `json:"note"`
+}
+
+// HandleCreate snapshots the program, e.g. POST /api/v1/debugger/checkpoints with
+// {"note": "before the parser runs"}
+func (h *CheckpointHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var req CheckpointRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+			return
+		}
+	}
+	if err := checkProfile(h.settings, h.prompts, user, "checkpoint", "checkpoints"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	checkpoint, err := h.gdbHandler.Checkpoint(user, req.Note)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: checkpoint})
+}
+
+// HandleRestore switches the program back to a checkpoint, e.g.
+// POST /api/v1/debugger/checkpoints/1/restore
+func (h *CheckpointHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	id, ok := checkpointID(w, r)
+	if !ok {
+		return
+	}
+	if err := checkProfile(h.settings, h.prompts, user, gdb.RestoreCheckpointCommand(id), "restoring checkpoints"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	output, err := h.gdbHandler.RestoreCheckpoint(user, id)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"id":     id,
+		"output": output,
+	}})
+}
+
+// HandleDelete deletes a checkpoint, e.g. DELETE /api/v1/debugger/checkpoints/1
+func (h *CheckpointHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	id, ok := checkpointID(w, r)
+	if !ok {
+		return
+	}
+	if err := checkProfile(h.settings, h.prompts, user, gdb.DeleteCheckpointCommand(id), "deleting checkpoints"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	if err := h.gdbHandler.DeleteCheckpoint(user, id); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{"id": id}})
+}
+
+// checkpointID reads the checkpoint ID of a request's path, writing the error if it is
+// invalid
+func checkpointID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id < 0 {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid checkpoint ID", appErrors.ErrBadRequest))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+const (
+	// chunkedDir holds a user's unfinished chunked uploads in their uploads directory
+	chunkedDir = ".chunked"
+	// maxChunkedUploads is how many unfinished chunked uploads a user may have
+	maxChunkedUploads = 4
+	// uploadOffsetHeader carries the offset a chunk starts at, as in the tus protocol
+	uploadOffsetHeader = "Upload-Offset"
+
+	defaultChunkSize  = 8 << 20 // 8 MB
+	defaultChunkedTTL = 24 * time.Hour
+)
+
+var (
+	// chunkedIDPattern matches the IDs of chunked uploads
+	chunkedIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// sha256Pattern matches a hex SHA-256 digest
+	sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+// ChunkedUpload is a file sent in chunks of at most ChunkSize bytes, for files too large
+// for one request such as binaries with debug information. A failed chunk is resent from
+// Offset, the bytes received so far.
+type ChunkedUpload struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	SHA256    string    `json:"sha256,omitempty"` // Checked when the upload is completed
+	ChunkSize int64     `json:"chunkSize"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"` // Removed if no chunk arrives before then
+}
+
+// Complete reports whether every byte of the upload was received
+func (u *ChunkedUpload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// chunkedUploads keeps unfinished chunked uploads in the users' uploads directories, each
+// as the bytes received and a JSON description
+type chunkedUploads struct {
+	maxSize   int64
+	chunkSize int64
+	ttl       time.Duration
+	mutex     sync.Mutex
+	locks     map[string]*sync.Mutex // Serialize the chunks of each upload
+}
+
+// newChunkedUploads creates the chunked upload store from the uploads configuration
+func newChunkedUploads(cfg config.UploadsConfig, maxFileSize int64) *chunkedUploads {
+	c := &chunkedUploads{maxSize: cfg.MaxChunkedSize, chunkSize: cfg.ChunkSize, ttl: cfg.ChunkedTTL, locks: make(map[string]*sync.Mutex)}
+	if c.maxSize <= 0 {
+		c.maxSize = maxFileSize
+	}
+	if c.chunkSize <= 0 {
+		c.chunkSize = defaultChunkSize
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultChunkedTTL
+	}
+	return c
+}
+
+// create starts an upload of size bytes into dir
+func (c *chunkedUploads) create(dir, filename string, size int64, checksum string) (*ChunkedUpload, error) {
+	if size <= 0 {
+		return nil, &UploadValidationError{Code: UploadErrInvalidRequest, Message: "size must be the file's size in bytes"}
+	}
+	if size > c.maxSize {
+		return nil, &UploadValidationError{Code: UploadErrTooLarge,
+			Message: fmt.Sprintf("The file is %d bytes, over the maximum chunked upload size of %d bytes (uploads.max_chunked_size)", size, c.maxSize)}
+	}
+	if checksum != "" && !sha256Pattern.MatchString(checksum) {
+		return nil, &UploadValidationError{Code: UploadErrInvalidRequest, Message: "sha256 must be a hex SHA-256 digest"}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create chunked upload directory: %w", err)
+	}
+	c.reap(dir)
+	if paths, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(paths) >= maxChunkedUploads {
+		return nil, &UploadValidationError{Code: UploadErrInvalidRequest,
+			Message: fmt.Sprintf("You have %d unfinished uploads; complete or cancel one first", len(paths))}
+	}
+
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	now := time.Now()
+	upload := &ChunkedUpload{
+		ID:        hex.EncodeToString(raw[:]),
+		Filename:  filename,
+		Size:      size,
+		SHA256:    strings.ToLower(checksum),
+		ChunkSize: c.chunkSize,
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.ttl),
+	}
+	if err := os.WriteFile(filepath.Join(dir, upload.ID+".part"), nil, 0600); err != nil {
+		return nil, fmt.Errorf("failed to create chunked upload: %w", err)
+	}
+	description, _ := json.Marshal(upload)
+	if err := os.WriteFile(filepath.Join(dir, upload.ID+".json"), description, 0600); err != nil {
+		os.Remove(filepath.Join(dir, upload.ID+".part"))
+		return nil, fmt.Errorf("failed to create chunked upload: %w", err)
+	}
+	return upload, nil
+}
+
+// get returns an upload with the bytes received so far
+func (c *chunkedUploads) get(dir, id string) (*ChunkedUpload, error) {
+	notFound := &UploadValidationError{Code: UploadErrNotFound, Message: "No such upload; it may have expired"}
+	if !chunkedIDPattern.MatchString(id) {
+		return nil, notFound
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, notFound
+	}
+	var upload ChunkedUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, notFound
+	}
+	info, err := os.Stat(filepath.Join(dir, id+".part"))
+	if err != nil {
+		return nil, notFound
+	}
+	upload.Offset = info.Size()
+	upload.ExpiresAt = info.ModTime().Add(c.ttl)
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, notFound
+	}
+	return &upload, nil
+}
+
+// write appends a chunk starting at offset to an upload. Chunks must arrive in order; the
+// bytes of a chunk cut short are kept, so the next one starts after them.
+func (c *chunkedUploads) write(dir, id string, offset int64, chunk io.Reader, length int64) (*ChunkedUpload, error) {
+	lock := c.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, err := c.get(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return upload, &UploadValidationError{Code: UploadErrOffsetMismatch,
+			Message: fmt.Sprintf("The upload has %d bytes; send the chunk starting there", upload.Offset)}
+	}
+	limit := min(c.chunkSize, upload.Size-upload.Offset)
+	if length > limit {
+		return upload, &UploadValidationError{Code: UploadErrTooLarge,
+			Message: fmt.Sprintf("Chunks are limited to %d bytes and the upload has %d bytes left", c.chunkSize, upload.Size-upload.Offset)}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, id+".part"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunked upload: %w", err)
+	}
+	written, copyErr := io.Copy(f, io.LimitReader(chunk, limit))
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	upload.Offset += written
+	upload.ExpiresAt = time.Now().Add(c.ttl)
+	if copyErr != nil {
+		return upload, fmt.Errorf("failed to write chunk: %w", copyErr)
+	}
+	return upload, nil
+}
+
+// finished returns an upload that has every byte, after checking them against its
+// SHA-256 digest if it has one, and the path of its file
+func (c *chunkedUploads) finished(dir, id string) (*ChunkedUpload, string, error) {
+	upload, err := c.get(dir, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !upload.Complete() {
+		return upload, "", &UploadValidationError{Code: UploadErrIncomplete,
+			Message: fmt.Sprintf("The upload has %d of %d bytes", upload.Offset, upload.Size)}
+	}
+	path := filepath.Join(dir, id+".part")
+	if upload.SHA256 != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open chunked upload: %w", err)
+		}
+		defer f.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, f); err != nil {
+			return nil, "", fmt.Errorf("failed to read chunked upload: %w", err)
+		}
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != upload.SHA256 {
+			return upload, "", &UploadValidationError{Code: UploadErrChecksum,
+				Message: fmt.Sprintf("The file's SHA-256 is %s, not %s; cancel the upload and send it again", sum, upload.SHA256)}
+		}
+	}
+	return upload, path, nil
+}
+
+// remove deletes an upload's description and bytes
+func (c *chunkedUploads) remove(dir, id string) {
+	os.Remove(filepath.Join(dir, id+".json"))
+	os.Remove(filepath.Join(dir, id+".part"))
+	c.mutex.Lock()
+	delete(c.locks, id)
+	c.mutex.Unlock()
+}
+
+// reap removes the uploads in dir that received no chunk for the TTL; the caller holds
+// the mutex
+func (c *chunkedUploads) reap(dir string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	cutoff := time.Now().Add(-c.ttl)
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		if info, err := os.Stat(filepath.Join(dir, id+".part")); err == nil && info.ModTime().After(cutoff) {
+			continue
+		}
+		os.Remove(path)
+		os.Remove(filepath.Join(dir, id+".part"))
+		delete(c.locks, id)
+	}
+}
+
+// lock returns the mutex serializing an upload's chunks
+func (c *chunkedUploads) lock(id string) *sync.Mutex {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lock, ok := c.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.locks[id] = lock
+	}
+	return lock
+}
+
+// chunkedDirFor returns the directory of a user's unfinished chunked uploads
+func (h *FileHandler) chunkedDirFor(user string) string {
+	return filepath.Join(userUploadsDir(h.uploadsDir, user), chunkedDir)
+}
+
+// writeUploadError writes an upload error with the status its code calls for, and the
+// upload it concerns, if any, so clients learn where to resume
+func writeUploadError(w http.ResponseWriter, err error, upload *ChunkedUpload) {
+	var validationErr *UploadValidationError
+	if !errors.As(err, &validationErr) {
+		log.Printf("Chunked upload failed: %v", err)
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to store the upload")
+		return
+	}
+	status := http.StatusBadRequest
+	switch validationErr.Code {
+	case UploadErrTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case UploadErrNotFound:
+		status = http.StatusNotFound
+	case UploadErrOffsetMismatch, UploadErrIncomplete:
+		status = http.StatusConflict
+	case UploadErrChecksum:
+		status = http.StatusUnprocessableEntity
+	case UploadErrUnsupportedType, UploadErrScript, UploadErrEmpty:
+		status = http.StatusUnsupportedMediaType
+	}
+	w.WriteHeader(status)
+	response := Response{Success: false, Error: validationErr.Message, Code: validationErr.Code}
+	if upload != nil {
+		response.Data = upload
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ChunkedCreateRequest is the body of a request starting a chunked upload
+type ChunkedCreateRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// HandleChunkedCreate starts a chunked upload, e.g. POST /api/v1/uploads with
+// {"filename": "server", "size": 734003200, "sha256": "..."}. It answers 201 with the
+// upload, whose ID the chunks are sent to.
+func (h *FileHandler) HandleChunkedCreate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req ChunkedCreateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "Invalid request body")
+		return
+	}
+	filename := sanitizeFilename(req.Filename)
+	if filename == "" || strings.HasPrefix(filename, ".") {
+		writeError(w, http.StatusBadRequest, UploadErrInvalidFilename, "Invalid filename")
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	upload, err := h.chunked.create(h.chunkedDirFor(user), filename, req.Size, req.SHA256)
+	if err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	log.Printf("Started chunked upload %s of %s (%d bytes)", upload.ID, upload.Filename, upload.Size)
+	w.Header().Set("Location", "/api/v1/uploads/"+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Success: true, Data: upload})
+}
+
+// HandleChunkedStatus returns a chunked upload with the bytes received so far, e.g. to
+// resume it after a failure
+func (h *FileHandler) HandleChunkedStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user, _ := auth.UserFromContext(r.Context())
+	upload, err := h.chunked.get(h.chunkedDirFor(user), mux.Vars(r)["id"])
+	if err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	json.NewEncoder(w).Encode(Response{Success: true, Data: upload})
+}
+
+// HandleChunkedWrite appends a chunk to an upload, e.g. PATCH /api/v1/uploads/{id} with
+// the bytes as the body and the offset they start at in the Upload-Offset header. A chunk
+// for the wrong offset is refused with 409 and the upload, whose offset is the right one.
+func (h *FileHandler) HandleChunkedWrite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "The Upload-Offset header must give the offset the chunk starts at")
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	upload, err := h.chunked.write(h.chunkedDirFor(user), mux.Vars(r)["id"], offset, r.Body, r.ContentLength)
+	if err != nil {
+		writeUploadError(w, err, upload)
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	json.NewEncoder(w).Encode(Response{Success: true, Data: upload})
+}
+
+// HandleChunkedCancel deletes an unfinished upload
+func (h *FileHandler) HandleChunkedCancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user, _ := auth.UserFromContext(r.Context())
+	dir, id := h.chunkedDirFor(user), mux.Vars(r)["id"]
+	if _, err := h.chunked.get(dir, id); err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	h.chunked.remove(dir, id)
+	json.NewEncoder(w).Encode(Response{Success: true})
+}
+
+// ChunkedCompleteRequest is the body of a request finishing a chunked upload of an
+// executable, naming where its sources come from, if anywhere
+type ChunkedCompleteRequest struct {
+	Source     string `json:"source"`
+	Repository string `json:"repository"`
+	Commit     string `json:"commit"`
+}
+
+// HandleChunkedComplete finishes a chunked upload of an executable and starts its
+// debugging session like HandleUpload, e.g. POST /api/v1/uploads/{id}/complete with
+// {"source": "<upload id>"} naming a completed chunked upload of its source archive, or
+// {"repository": "owner/name", "commit": "<sha>"} to fetch its sources from GitHub
+func (h *FileHandler) HandleChunkedComplete(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req ChunkedCompleteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "Invalid request body")
+			return
+		}
+	}
+	if !validSourceRepository(req.Repository, req.Commit) {
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "repository must be owner/name and commit a commit SHA")
+		return
+	}
+
+	// An upload starts a new session, which must not take over another user's running one
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.gdbHandler.ClaimSession(user); err != nil {
+		writeClaimError(w, err, UploadErrSessionInUse)
+		return
+	}
+
+	dir, id := h.chunkedDirFor(user), mux.Vars(r)["id"]
+	lock := h.chunked.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	upload, path, err := h.chunked.finished(dir, id)
+	if err != nil {
+		writeUploadError(w, err, upload)
+		return
+	}
+
+	var source *os.File
+	var sourceSize int64
+	if req.Source != "" {
+		if req.Source == id {
+			writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "The source archive must be another upload")
+			return
+		}
+		sourceUpload, sourcePath, err := h.chunked.finished(dir, req.Source)
+		if err != nil {
+			writeUploadError(w, err, sourceUpload)
+			return
+		}
+		if source, err = os.Open(sourcePath); err != nil {
+			writeUploadError(w, err, nil)
+			return
+		}
+		defer source.Close()
+		defer h.chunked.remove(dir, req.Source)
+		sourceSize = sourceUpload.Size
+	}
+
+	// Make sure the upload is an executable GDB can load
+	f, err := os.Open(path)
+	if err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	format, _, err := sniffExecutable(f)
+	f.Close()
+	if err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+
+	// Only validated executables are marked executable
+	dstPath := filepath.Join(userUploadsDir(h.uploadsDir, user), upload.Filename)
+	if err := os.Chmod(path, 0755); err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	binary, deduplicated, err := h.binaries.Add(path, dstPath, user, upload.Filename, format)
+	if err != nil {
+		writeUploadError(w, err, nil)
+		return
+	}
+	h.chunked.remove(dir, id)
+	log.Printf("Completed chunked upload %s of %s (%d bytes)", upload.ID, upload.Filename, upload.Size)
+
+	executable := uploadedExecutable{
+		filename:     upload.Filename,
+		path:         dstPath,
+		format:       format,
+		binary:       binary,
+		deduplicated: deduplicated,
+		repository:   req.Repository,
+		commit:       req.Commit,
+	}
+	if source != nil {
+		executable.source, executable.sourceSize = source, sourceSize
+	}
+	h.startUploadSession(w, r, user, executable)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+func TestChunkedUploads(t *testing.T) {
+	dir := t.TempDir()
+	uploads := newChunkedUploads(config.UploadsConfig{MaxChunkedSize: 10, ChunkSize: 4}, 0)
+
+	_, err := uploads.create(dir, "server", 11, "")
+	assertUploadError(t, err, UploadErrTooLarge)
+	_, err = uploads.create(dir, "server", 10, "not a digest")
+	assertUploadError(t, err, UploadErrInvalidRequest)
+
+	upload, err := uploads.create(dir, "server", 10, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), upload.ChunkSize)
+
+	// Chunks must arrive in order and within the chunk size
+	upload, err = uploads.write(dir, upload.ID, 0, strings.NewReader("0123"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), upload.Offset)
+	upload, err = uploads.write(dir, upload.ID, 0, strings.NewReader("0123"), 4)
+	assertUploadError(t, err, UploadErrOffsetMismatch)
+	assert.Equal(t, int64(4), upload.Offset, "the offset to resume from is returned")
+	_, err = uploads.write(dir, upload.ID, 4, strings.NewReader("45678"), 5)
+	assertUploadError(t, err, UploadErrTooLarge)
+
+	// A chunk cut short keeps what arrived
+	upload, err = uploads.write(dir, upload.ID, 4, strings.NewReader("45"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), upload.Offset)
+	_, _, err = uploads.finished(dir, upload.ID)
+	assertUploadError(t, err, UploadErrIncomplete)
+
+	// Bytes past the declared size are never written
+	upload, err = uploads.write(dir, upload.ID, 6, strings.NewReader("6789abc"), -1)
+	require.NoError(t, err)
+	assert.True(t, upload.Complete())
+	_, path, err := uploads.finished(dir, upload.ID)
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(content))
+
+	uploads.remove(dir, upload.ID)
+	_, err = uploads.get(dir, upload.ID)
+	assertUploadError(t, err, UploadErrNotFound)
+	_, err = uploads.get(dir, "../../etc/passwd")
+	assertUploadError(t, err, UploadErrNotFound)
+}
+
+func TestChunkedUploadChecksum(t *testing.T) {
+	dir := t.TempDir()
+	uploads := newChunkedUploads(config.UploadsConfig{MaxChunkedSize: 10}, 0)
+	sum := sha256.Sum256([]byte("expected"))
+
+	upload, err := uploads.create(dir, "server", 8, strings.ToUpper(hex.EncodeToString(sum[:])))
+	require.NoError(t, err)
+	_, err = uploads.write(dir, upload.ID, 0, strings.NewReader("received"), 8)
+	require.NoError(t, err)
+	_, _, err = uploads.finished(dir, upload.ID)
+	assertUploadError(t, err, UploadErrChecksum)
+}
+
+func TestChunkedUploadsExpire(t *testing.T) {
+	dir := t.TempDir()
+	uploads := newChunkedUploads(config.UploadsConfig{MaxChunkedSize: 10, ChunkedTTL: time.Hour}, 0)
+
+	for i := 0; i < maxChunkedUploads; i++ {
+		_, err := uploads.create(dir, "server", 10, "")
+		require.NoError(t, err)
+	}
+	_, err := uploads.create(dir, "server", 10, "")
+	assertUploadError(t, err, UploadErrInvalidRequest)
+
+	// Uploads without a chunk for the TTL are removed
+	paths, _ := os.ReadDir(dir)
+	old := time.Now().Add(-2 * time.Hour)
+	for _, entry := range paths {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, entry.Name()), old, old))
+	}
+	_, err = uploads.create(dir, "server", 10, "")
+	assert.NoError(t, err)
+}
+
+func TestChunkedUploadHandlers(t *testing.T) {
+	cfg := &config.Config{Uploads: config.UploadsConfig{Directory: t.TempDir(), MaxChunkedSize: 64, ChunkSize: 8}}
+	h := &FileHandler{
+		uploadsDir: cfg.Uploads.Directory,
+		chunked:    newChunkedUploads(cfg.Uploads, 0),
+		gdbHandler: NewGDBHandler(websocket.NewHub(cfg), logsession.NewLoggerHolder(), cfg),
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/uploads", h.HandleChunkedCreate).Methods("POST")
+	router.HandleFunc("/api/v1/uploads/{id}", h.HandleChunkedStatus).Methods("GET")
+	router.HandleFunc("/api/v1/uploads/{id}", h.HandleChunkedWrite).Methods("PATCH")
+	router.HandleFunc("/api/v1/uploads/{id}", h.HandleChunkedCancel).Methods("DELETE")
+	router.HandleFunc("/api/v1/uploads/{id}/complete", h.HandleChunkedComplete).Methods("POST")
+
+	serve := func(method, path string, body []byte, offset int64) (*httptest.ResponseRecorder, ChunkedUpload) {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if offset >= 0 {
+			req.Header.Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var response struct {
+			Data ChunkedUpload `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response.Data
+	}
+
+	rec, upload := serve("POST", "/api/v1/uploads", []byte(`{"filename": "notes.txt", "size": 12}`), -1)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/v1/uploads/"+upload.ID, rec.Header().Get("Location"))
+
+	rec, _ = serve("PATCH", "/api/v1/uploads/"+upload.ID, []byte("hello, w"), -1)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "chunks need their offset")
+	rec, upload = serve("PATCH", "/api/v1/uploads/"+upload.ID, []byte("hello, w"), 0)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "8", rec.Header().Get(uploadOffsetHeader))
+
+	rec, _ = serve("POST", "/api/v1/uploads/"+upload.ID+"/complete", nil, -1)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// A resend of the same chunk learns where to resume
+	rec, upload = serve("PATCH", "/api/v1/uploads/"+upload.ID, []byte("hello, w"), 0)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, int64(8), upload.Offset)
+	rec, _ = serve("PATCH", "/api/v1/uploads/"+upload.ID, []byte("orld"), 8)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec, upload = serve("GET", "/api/v1/uploads/"+upload.ID, nil, -1)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, upload.Complete())
+
+	// Only executables start a session
+	rec, _ = serve("POST", "/api/v1/uploads/"+upload.ID+"/complete", nil, -1)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	rec, _ = serve("DELETE", "/api/v1/uploads/"+upload.ID, nil, -1)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, _ = serve("GET", "/api/v1/uploads/"+upload.ID, nil, -1)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
+)
+
+// Compile error codes returned in Response.Code
+const (
+	CompileErrInvalidRequest = "invalid_request"
+	CompileErrSourceTooLarge = "source_too_large"
+	CompileErrInvalidFlag    = "invalid_flag"
+	CompileErrFailed         = "compile_failed"
+	CompileErrTimeout        = "compile_timeout"
+	CompileErrStorage        = "storage_error"
+	CompileErrGDB            = "gdb_start_failed"
+	CompileErrSessionInUse   = "session_in_use"
+)
+
+// maxCompilerOutput caps the compiler diagnostics returned to the client
+const maxCompilerOutput = 64 << 10 // 64 KB
+
+// allowedFlagRegex lists the compiler flags users may add. Anything that can read or write
+// arbitrary files (-o, -I, -include, @file, -wrapper, ...) is rejected.
+var allowedFlagRegex = regexp.MustCompile(`^(-O[0-3sgz]?|-g[0-3]?|-ggdb[0-3]?|-std=[a-z0-9+]+|-W[a-z0-9-]*(=[a-z0-9]+)?|-D[A-Za-z_][A-Za-z0-9_]*(=[A-Za-z0-9_.-]*)?|-U[A-Za-z_][A-Za-z0-9_]*|-fno-[a-z0-9-]+|-fsanitize=[a-z,-]+|-fstack-protector(-all|-strong)?|-m32|-m64|-pthread|-static|-no-pie|-l[a-z0-9_+-]+)$`)
+
+// CompileRequest is the body of a compile request
+type CompileRequest struct {
+	Source   string   `json:"source"`
+	Language string   `json:"language"`           // "c" (default) or "cpp"
+	Filename string   `json:"filename,omitempty"` // Optional name for the program
+	Flags    []string `json:"flags,omitempty"`    // Optional flags, replacing the configured defaults
+}
+
+// CompileHandler compiles pasted source on the server and starts a debug session on the result
+type CompileHandler struct {
+	uploadsDir   string
+	cfg          config.CompilerConfig
+	gdbHandler   *GDBHandler
+	loggerHolder LoggerHolder
+	features     *features.Manager
+}
+
+// NewCompileHandler creates a new compile handler
+func NewCompileHandler(cfg *config.Config, gdbHandler *GDBHandler, loggerHolder LoggerHolder, featureManager *features.Manager) *CompileHandler {
+	return &CompileHandler{
+		uploadsDir:   cfg.Uploads.Directory,
+		cfg:          cfg.Compiler,
+		gdbHandler:   gdbHandler,
+		loggerHolder: loggerHolder,
+		features:     featureManager,
+	}
+}
+
+// HandleCompile compiles the submitted source and launches GDB on the resulting executable
+func (h *CompileHandler) HandleCompile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.gdbHandler.ClaimSession(user); err != nil {
+		writeClaimError(w, err, CompileErrSessionInUse)
+		return
+	}
+
+	maxSourceSize := h.cfg.MaxSourceSize
+	if maxSourceSize <= 0 {
+		maxSourceSize = 1 << 20
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxSourceSize)
+
+	var req CompileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, CompileErrSourceTooLarge,
+				fmt.Sprintf("Source exceeds the maximum size of %d bytes", maxSourceSize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, CompileErrInvalidRequest, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.Source) == "" {
+		writeError(w, http.StatusBadRequest, CompileErrInvalidRequest, "Source is empty")
+		return
+	}
+	if int64(len(req.Source)) > maxSourceSize {
+		writeError(w, http.StatusRequestEntityTooLarge, CompileErrSourceTooLarge,
+			fmt.Sprintf("Source exceeds the maximum size of %d bytes", maxSourceSize))
+		return
+	}
+
+	compiler, ext, err := h.compilerFor(req.Language)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CompileErrInvalidRequest, err.Error())
+		return
+	}
+
+	flags := h.cfg.DefaultFlags
+	if len(req.Flags) > 0 {
+		if err := validateCompilerFlags(req.Flags); err != nil {
+			writeError(w, http.StatusBadRequest, CompileErrInvalidFlag, err.Error())
+			return
+		}
+		flags = req.Flags
+	}
+
+	name := strings.TrimSuffix(sanitizeFilename(req.Filename), filepath.Ext(req.Filename))
+	if name == "" || name == "." {
+		name = "program"
+	}
+	sessionID := newSessionID(time.Now(), name)
+
+	// Each session compiles in its own directory, which doubles as GDB's source path
+	workDir := sourcesDirFor(h.uploadsDir, sessionID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		log.Printf("Error creating compile directory: %v", err)
+		writeError(w, http.StatusInternalServerError, CompileErrStorage, "Unable to prepare compile directory")
+		return
+	}
+	sourcePath := filepath.Join(workDir, name+ext)
+	if err := os.WriteFile(sourcePath, []byte(req.Source), 0644); err != nil {
+		log.Printf("Error writing source file: %v", err)
+		writeError(w, http.StatusInternalServerError, CompileErrStorage, "Unable to save source")
+		return
+	}
+
+	uploadsDir := userUploadsDir(h.uploadsDir, user)
+	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+		log.Printf("Error creating uploads directory: %v", err)
+		writeError(w, http.StatusInternalServerError, CompileErrStorage, "Unable to create uploads directory")
+		return
+	}
+
+	executable := sessionID
+	output, err := h.compile(r.Context(), compiler, flags, sourcePath, filepath.Join(uploadsDir, executable))
+	if err != nil {
+		os.RemoveAll(workDir)
+		status, code := http.StatusUnprocessableEntity, CompileErrFailed
+		if errors.Is(err, context.DeadlineExceeded) {
+			status, code = http.StatusGatewayTimeout, CompileErrTimeout
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Response{
+			Success: false,
+			Error:   "Compilation failed: " + err.Error(),
+			Code:    code,
+			Data:    map[string]interface{}{"compilerOutput": output},
+		})
+		return
+	}
+
+	sessionToken, err := startLogSession(h.loggerHolder, h.features, sessionID, user, map[string]interface{}{
+		"session.filename": executable,
+		"session.format":   FormatELF,
+		"session.sources":  true,
+		"session.compiled": map[string]interface{}{"language": req.Language, "flags": flags},
+	})
+	if err != nil {
+		log.Printf("CRITICAL: %v", err)
+		writeError(w, http.StatusInternalServerError, CompileErrStorage, "Compiled but failed to start logging session")
+		return
+	}
+
+	if err := h.gdbHandler.StartSession(user, executable); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{
+			Success: false,
+			Error:   "Compiled but failed to start GDB: " + err.Error(),
+			Code:    CompileErrGDB,
+			Data:    map[string]interface{}{"compilerOutput": output, "filename": executable},
+		})
+		return
+	}
+
+	log.Printf("Compiled %s with %s %v and started GDB", sourcePath, compiler, flags)
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"message":        "Compiled successfully and started GDB",
+			"filename":       executable,
+			"flags":          flags,
+			"compilerOutput": output,
+			"sessionToken":   sessionToken,
+		},
+	})
+}
+
+// compilerFor returns the compiler and source extension for a language
+func (h *CompileHandler) compilerFor(language string) (string, string, error) {
+	switch strings.ToLower(language) {
+	case "", "c":
+		return h.cfg.CCPath, ".c", nil
+	case "cpp", "c++", "cxx":
+		return h.cfg.CXXPath, ".cpp", nil
+	default:
+		return "", "", fmt.Errorf("unsupported language %q (expected \"c\" or \"cpp\")", language)
+	}
+}
+
+// compile runs the compiler and returns its combined output, truncated to maxCompilerOutput
+func (h *CompileHandler) compile(ctx context.Context, compiler string, flags []string, sourcePath, outputPath string) (string, error) {
+	timeout := h.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Libraries must follow the source file for the linker to resolve them
+	var args, libs []string
+	for _, flag := range flags {
+		if strings.HasPrefix(flag, "-l") {
+			libs = append(libs, flag)
+		} else {
+			args = append(args, flag)
+		}
+	}
+	args = append(args, "-o", outputPath, sourcePath)
+	args = append(args, libs...)
+	cmd := exec.CommandContext(ctx, compiler, args...)
+	cmd.Dir = filepath.Dir(sourcePath)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	output := out.String()
+	if len(output) > maxCompilerOutput {
+		output = output[:maxCompilerOutput] + "\n... (output truncated)"
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("compiler timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+// validateCompilerFlags rejects flags outside the allowed set
+func validateCompilerFlags(flags []string) error {
+	for _, flag := range flags {
+		if !allowedFlagRegex.MatchString(flag) {
+			return fmt.Errorf("compiler flag %q is not allowed", flag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestValidateCompilerFlags(t *testing.T) {
+	assert.NoError(t, validateCompilerFlags([]string{"-g3", "-O2", "-std=c11", "-Wall", "-DDEBUG=1", "-fsanitize=address", "-lm"}))
+
+	for _, flag := range []string{"-o/etc/passwd", "-I/root", "-include/etc/shadow", "@args.txt", "-wrapper", "-B/tmp", "-fplugin=evil.so"} {
+		assert.Error(t, validateCompilerFlags([]string{flag}), flag)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+
+	dir := t.TempDir()
+	h := &CompileHandler{cfg: config.CompilerConfig{CCPath: "gcc"}}
+
+	source := filepath.Join(dir, "ok.c")
+	require.NoError(t, os.WriteFile(source, []byte("int main(void) { return 0; }\n"), 0644))
+	_, err := h.compile(context.Background(), "gcc", []string{"-g", "-O0"}, source, filepath.Join(dir, "ok"))
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "ok"))
+
+	broken := filepath.Join(dir, "broken.c")
+	require.NoError(t, os.WriteFile(broken, []byte("int main(void) { return undefined_var; }\n"), 0644))
+	output, err := h.compile(context.Background(), "gcc", nil, broken, filepath.Join(dir, "broken"))
+	assert.Error(t, err)
+	assert.Contains(t, output, "undefined_var")
+}
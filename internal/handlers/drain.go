@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// SessionErrDraining is the Response.Code of requests refused a new session because the
+// server is shutting down
+const SessionErrDraining = "server_draining"
+
+// errDraining is returned when a session is started while the server shuts down
+var errDraining = fmt.Errorf("%w: the server is shutting down and accepts no new debugging sessions", appErrors.ErrUnavailable)
+
+// Drain stops the handler starting debugging sessions, for a server shutting down. The
+// current session keeps running until Shutdown.
+func (h *GDBHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// writeClaimError answers a request refused a new session by ClaimSession: 503 while the
+// server drains, otherwise 409 with code, as another user's session is running
+func writeClaimError(w http.ResponseWriter, err error, code string) {
+	if errors.Is(err, appErrors.ErrUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, SessionErrDraining, "The server is shutting down")
+		return
+	}
+	writeError(w, http.StatusConflict, code, "Another user's debugging session is running")
+}
+
+// Shutdown ends the current session as the server exits. Its state — whether GDB was
+// running, the breakpoints and where the program last stopped — is recorded in its log
+// before GDB is stopped and the log closed, so the session can be reviewed and exported
+// after a restart.
+func (h *GDBHandler) Shutdown() {
+	logger := h.loggerHolder.Get()
+	if logger != nil {
+		details := map[string]interface{}{
+			"gdb.running":     h.IsRunning(),
+			"gdb.breakpoints": h.gdbService.Breakpoints(),
+		}
+		if stop := h.gdbService.LastStop(); stop != nil {
+			details["gdb.last_stop"] = stop
+		}
+		logger.LogEvent("INFO", "session.shutdown", "Server shut down during the session", details)
+	}
+
+	if h.IsRunning() {
+		if err := h.gdbService.StopGDB(); err != nil {
+			log.Printf("Stopping GDB on shutdown failed: %v", err)
+		}
+	}
+	if logger != nil {
+		// Closes the session's log
+		h.loggerHolder.Set(nil)
+		log.Printf("Saved the state of session %s", logger.SessionID())
+	}
+}
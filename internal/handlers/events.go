@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// crashSignals are the signals a program receives when it crashes, as opposed to those of
+// the user interrupting it or of timers and children
+var crashSignals = map[string]bool{
+	"SIGSEGV": true,
+	"SIGBUS":  true,
+	"SIGABRT": true,
+	"SIGFPE":  true,
+	"SIGILL":  true,
+	"SIGSYS":  true,
+}
+
+// SetEventBus makes the handler publish when sessions start and their programs hit
+// breakpoints or crash
+func (h *GDBHandler) SetEventBus(bus *events.Bus) {
+	h.events = bus
+}
+
+// publishStop publishes a stop of the program in a session of user, debugging
+// executable, when it was at a breakpoint or a crash
+func (h *GDBHandler) publishStop(user, session, executable string, stop *gdb.StopLocation) {
+	event, ok := stopEvent(stop)
+	if !ok {
+		return
+	}
+	event.User, event.Session = user, session
+	event.Data["executable"] = executable
+	h.events.Publish(event)
+}
+
+// stopEvent returns the event of a stop of the program, and false for stops that are
+// none, like steps
+func stopEvent(stop *gdb.StopLocation) (events.Event, bool) {
+	data := map[string]interface{}{"reason": stop.Reason}
+	where := ""
+	if stop.Function != "" {
+		data["function"] = stop.Function
+		where = " in " + stop.Function
+	}
+	if stop.File != "" {
+		data["file"], data["line"] = stop.File, stop.Line
+		where += fmt.Sprintf(" at %s:%d", stop.File, stop.Line)
+	}
+
+	switch {
+	case stop.Reason == gdb.StopBreakpoint || stop.Reason == gdb.StopWatchpoint:
+		data["breakpoint"] = stop.Breakpoint
+		return events.Event{
+			Type:    events.BreakpointHit,
+			Message: fmt.Sprintf("The program stopped at %s %d%s", stop.Reason, stop.Breakpoint, where),
+			Data:    data,
+		}, true
+	case stop.Reason == gdb.StopSignal && crashSignals[stop.Signal]:
+		data["signal"], data["description"] = stop.Signal, stop.Description
+		signal := stop.Signal
+		if stop.Description != "" {
+			signal += " (" + stop.Description + ")"
+		}
+		return events.Event{
+			Type:    events.CrashDetected,
+			Message: fmt.Sprintf("The program crashed with %s%s", signal, where),
+			Data:    data,
+		}, true
+	}
+	return events.Event{}, false
+}
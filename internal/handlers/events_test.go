@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/events"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+func TestStopEvent(t *testing.T) {
+	event, ok := stopEvent(&gdb.StopLocation{Reason: gdb.StopBreakpoint, Breakpoint: 2, Function: "main", File: "crash.c", Line: 5})
+	assert.True(t, ok)
+	assert.Equal(t, events.BreakpointHit, event.Type)
+	assert.Equal(t, "The program stopped at breakpoint 2 in main at crash.c:5", event.Message)
+	assert.Equal(t, 5, event.Data["line"])
+
+	event, ok = stopEvent(&gdb.StopLocation{Reason: gdb.StopSignal, Signal: "SIGSEGV", Description: "Segmentation fault", Function: "main"})
+	assert.True(t, ok)
+	assert.Equal(t, events.CrashDetected, event.Type)
+	assert.Equal(t, "The program crashed with SIGSEGV (Segmentation fault) in main", event.Message)
+
+	_, ok = stopEvent(&gdb.StopLocation{Reason: gdb.StopSignal, Signal: "SIGINT"})
+	assert.False(t, ok, "interrupting the program is no crash")
+	_, ok = stopEvent(&gdb.StopLocation{Reason: gdb.StopStepped, Function: "main"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/transcript"
+)
+
+// currentSessionAlias selects the session currently being logged
+const currentSessionAlias = "current"
+
+// ExportHandler serves session transcripts in the formats provided by the exporter registry
+type ExportHandler struct {
+	registry     *transcript.Registry
+	loggerHolder LoggerHolder
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(registry *transcript.Registry, loggerHolder LoggerHolder) *ExportHandler {
+	return &ExportHandler{
+		registry:     registry,
+		loggerHolder: loggerHolder,
+	}
+}
+
+// HandleExport exports a session as a download, e.g. GET /api/sessions/current/export?format=gdb.
+// Users may only export sessions they own.
+func (h *ExportHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	sessionID := mux.Vars(r)["id"]
+	if sessionID == currentSessionAlias {
+		logger := h.loggerHolder.Get()
+		if logger == nil {
+			writeJSONResponseError(w, http.StatusNotFound, "No active session")
+			return
+		}
+		sessionID = logger.SessionID()
+	}
+	if !validSessionID(sessionID) {
+		writeJSONResponseError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "gdb"
+	}
+	exporter, ok := h.registry.Get(format)
+	if !ok {
+		writeJSONResponseError(w, http.StatusBadRequest,
+			fmt.Sprintf("Unknown export format %q (available: %s)", format, strings.Join(h.registry.Names(), ", ")))
+		return
+	}
+
+	session, err := transcript.LoadSession(sessionID, logsession.LogFilePath(sessionID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSONResponseError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		log.Printf("Error loading session %s for export: %v", sessionID, err)
+		writeJSONResponseError(w, http.StatusInternalServerError, "Unable to read session log")
+		return
+	}
+	if session.Metadata("session.owner") != user {
+		writeJSONResponseError(w, http.StatusForbidden, "Session belongs to another user")
+		return
+	}
+
+	// Render before writing headers so a failed export still returns a JSON error
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, session); err != nil {
+		log.Printf("Error exporting session %s as %s: %v", sessionID, format, err)
+		writeJSONResponseError(w, http.StatusInternalServerError, "Unable to export session")
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sessionID+exporter.FileExtension()))
+	w.Write(buf.Bytes())
+}
+
+// validSessionID rejects IDs that could escape the log directory
+func validSessionID(sessionID string) bool {
+	return sessionID != "" && sessionID == filepath.Base(sessionID) && !strings.Contains(sessionID, "..")
+}
+
+// writeJSONResponseError writes a JSON error response with the given status
+func writeJSONResponseError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	writeError(w, status, "", message)
+}
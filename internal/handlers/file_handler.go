@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,27 +14,73 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/binstore"
 	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
 	"github.com/yourusername/gogdbllm/internal/logsession" // Import logsession
+	"github.com/yourusername/gogdbllm/internal/sources"
 )
 
 // Define SharedLogger interface locally for dependency inversion (optional but good practice)
 type LoggerHolder interface {
 	Set(newLogger *logsession.SessionLogger)
 	Get() *logsession.SessionLogger
+	// CompareAndClear clears the logger only if it is still logger, reporting whether it did
+	CompareAndClear(logger *logsession.SessionLogger) bool
+	// OnSessionEnd calls fn with the ID of each session that ends
+	OnSessionEnd(fn func(sessionID string))
 }
 
 // FileHandler handles file uploads
 type FileHandler struct {
 	uploadsDir   string
-	loggerHolder LoggerHolder // Use the interface type
+	maxFileSize  int64
+	sourceLimits sourceLimits
+	chunked      *chunkedUploads // Uploads of files over maxFileSize, sent in chunks
+	binaries     *binstore.Store // One copy of each uploaded executable, by content
+	loggerHolder LoggerHolder    // Use the interface type
+	features     *features.Manager
+	gdbHandler   *GDBHandler
+	github       *sources.GitHub // Fetches sources by repository and commit; nil when disabled
 }
 
+// defaultMaxFileSize is used when the configuration does not set uploads.max_file_size
+const defaultMaxFileSize = 10 << 20 // 10 MB
+
+// uploadFormMemory is how much of a multipart upload is held in memory; the rest of the
+// files are spooled to temporary files, so large limits do not cost memory
+const uploadFormMemory = 32 << 20 // 32 MB
+
 // NewFileHandler creates a new file handler
-func NewFileHandler(cfg *config.Config, loggerHolder LoggerHolder) *FileHandler { // Use config
+func NewFileHandler(cfg *config.Config, loggerHolder LoggerHolder, featureManager *features.Manager, gdbHandler *GDBHandler) *FileHandler { // Use config
+	maxFileSize := cfg.Uploads.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+	maxSourceSize := cfg.Uploads.MaxSourceSize
+	if maxSourceSize <= 0 {
+		maxSourceSize = 10 * maxFileSize
+	}
+
+	var github *sources.GitHub
+	if cfg.Sources.GitHub.Enabled {
+		github = sources.NewGitHub(cfg.Sources.GitHub)
+	}
+
 	return &FileHandler{
-		uploadsDir:   cfg.Uploads.Directory,
+		uploadsDir:  cfg.Uploads.Directory,
+		maxFileSize: maxFileSize,
+		sourceLimits: sourceLimits{
+			maxTotalSize: maxSourceSize,
+			maxFiles:     cfg.Uploads.MaxSourceFiles,
+		},
+		chunked:      newChunkedUploads(cfg.Uploads, maxFileSize),
+		binaries:     binstore.New(cfg.Uploads),
 		loggerHolder: loggerHolder,
+		features:     featureManager,
+		gdbHandler:   gdbHandler,
+		github:       github,
 	}
 }
 
@@ -39,9 +88,16 @@ func NewFileHandler(cfg *config.Config, loggerHolder LoggerHolder) *FileHandler
 type Response struct {
 	Success bool        `json:"success"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // Machine-readable error code
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// writeError writes a structured JSON error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Success: false, Error: message, Code: code})
+}
+
 // HandleUpload handles file upload requests
 func (h *FileHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	// Always set JSON content type first
@@ -53,91 +109,289 @@ func (h *FileHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An upload starts a new session, which must not take over another user's running one
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.gdbHandler.ClaimSession(user); err != nil {
+		writeClaimError(w, err, UploadErrSessionInUse)
+		return
+	}
+
+	// Reject oversized uploads before reading the body. The form may carry the executable
+	// and a source archive, each up to the configured file size, plus multipart overhead.
+	r.Body = http.MaxBytesReader(w, r.Body, 2*h.maxFileSize+(1<<20))
+
 	// Parse the multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max file size
+	err := r.ParseMultipartForm(uploadFormMemory)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Unable to parse form: " + err.Error()})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, UploadErrTooLarge, h.tooLargeMessage("The upload", -1))
+			return
+		}
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "Unable to parse form: "+err.Error())
+		return
+	}
+
+	// The repository and commit the binary was built from, to fetch its sources when none
+	// are uploaded
+	repository, commit := r.FormValue("repository"), r.FormValue("commit")
+	if !validSourceRepository(repository, commit) {
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "repository must be owner/name and commit a commit SHA")
 		return
 	}
 
 	// Get the file from the form data
 	file, handler, err := r.FormFile("executable")
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Unable to get file from form: " + err.Error()})
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "Unable to get file from form: "+err.Error())
 		return
 	}
 	defer file.Close()
 
+	if handler.Size > h.maxFileSize {
+		writeError(w, http.StatusRequestEntityTooLarge, UploadErrTooLarge, h.tooLargeMessage("The executable", handler.Size))
+		return
+	}
+
 	// Sanitize filename
 	sanitizedFilename := sanitizeFilename(handler.Filename)
 	if sanitizedFilename == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Invalid filename"})
+		writeError(w, http.StatusBadRequest, UploadErrInvalidFilename, "Invalid filename")
 		return
 	}
 
-	// Create the uploads directory if it doesn't exist
-	if err := os.MkdirAll(h.uploadsDir, 0755); err != nil {
+	// Make sure the upload is an executable GDB can load
+	format, header, err := sniffExecutable(file)
+	if err != nil {
+		var validationErr *UploadValidationError
+		if errors.As(err, &validationErr) {
+			writeError(w, http.StatusUnsupportedMediaType, validationErr.Code, validationErr.Message)
+			return
+		}
+		log.Printf("Error reading uploaded file: %v", err)
+		writeError(w, http.StatusBadRequest, UploadErrInvalidRequest, "Unable to read uploaded file")
+		return
+	}
+
+	// Create the user's uploads directory if it doesn't exist
+	uploadsDir := userUploadsDir(h.uploadsDir, user)
+	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
 		log.Printf("Error creating uploads directory: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Unable to create uploads directory"})
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to create uploads directory")
 		return
 	}
 
 	// Create the destination file path
-	dstPath := filepath.Join(h.uploadsDir, sanitizedFilename)
+	dstPath := filepath.Join(uploadsDir, sanitizedFilename)
 
-	// Create the destination file
-	dst, err := os.Create(dstPath)
+	// Write to a temporary file first so a failed upload never replaces an existing binary
+	dst, err := os.CreateTemp(uploadsDir, ".upload-*")
 	if err != nil {
 		log.Printf("Error creating destination file: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Unable to create the file for writing"})
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to create the file for writing")
 		return
 	}
-	defer dst.Close()
+	tmpPath := dst.Name()
+	defer os.Remove(tmpPath) // No-op once the file has been renamed
 
-	// Copy the uploaded file data to the destination file
-	if _, err := io.Copy(dst, file); err != nil {
+	// Copy the sniffed header followed by the rest of the uploaded file data
+	if _, err := io.Copy(dst, io.MultiReader(bytes.NewReader(header), file)); err != nil {
+		dst.Close()
 		log.Printf("Error copying uploaded file: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Error: "Unable to save file"})
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to save file")
+		return
+	}
+	if err := dst.Close(); err != nil {
+		log.Printf("Error closing uploaded file: %v", err)
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to save file")
 		return
 	}
 
+	// Only validated executables are marked executable
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		log.Printf("Error setting permissions on uploaded file: %v", err)
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to save file")
+		return
+	}
+	// The same executable uploaded before is reused rather than stored again
+	binary, deduplicated, err := h.binaries.Add(tmpPath, dstPath, user, sanitizedFilename, format)
+	if err != nil {
+		log.Printf("Error storing uploaded file: %v", err)
+		writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to save file")
+		return
+	}
+
+	// An optional source archive lets GDB find the program's sources
+	var source io.ReaderAt
+	var sourceSize int64
+	if sourceFile, sourceHeader, err := r.FormFile("source"); err == nil {
+		defer sourceFile.Close()
+
+		if sourceHeader.Size > h.maxFileSize {
+			writeError(w, http.StatusRequestEntityTooLarge, UploadErrTooLarge, h.tooLargeMessage("The source archive", sourceHeader.Size))
+			return
+		}
+		source, sourceSize = sourceFile, sourceHeader.Size
+	}
+
+	h.startUploadSession(w, r, user, uploadedExecutable{
+		filename:     sanitizedFilename,
+		path:         dstPath,
+		format:       format,
+		binary:       binary,
+		deduplicated: deduplicated,
+		source:       source,
+		sourceSize:   sourceSize,
+		repository:   repository,
+		commit:       commit,
+	})
+}
+
+// uploadedExecutable is an executable stored in the user's uploads directory, with what
+// came with it to find its sources
+type uploadedExecutable struct {
+	filename     string
+	path         string
+	format       string
+	binary       *binstore.Binary
+	deduplicated bool        // The binary was stored before
+	source       io.ReaderAt // A source archive; nil if none was uploaded
+	sourceSize   int64
+	repository   string // Where to fetch the sources from when no archive was uploaded
+	commit       string
+}
+
+// startUploadSession starts the debugging session of an uploaded executable: its sources
+// are extracted or fetched, its log session is started and the upload's result is written
+func (h *FileHandler) startUploadSession(w http.ResponseWriter, r *http.Request, user string, upload uploadedExecutable) {
+	sanitizedFilename, dstPath := upload.filename, upload.path
+	format, repository, commit := upload.format, upload.repository, upload.commit
+	sessionID := newSessionID(time.Now(), sanitizedFilename)
+
+	// Extract the source archive so GDB can find the program's sources
+	var archive *sourceArchiveResult
+	if upload.source != nil {
+		var err error
+		archive, err = extractSourceArchive(upload.source, upload.sourceSize, sourcesDirFor(h.uploadsDir, sessionID), h.sourceLimits)
+		if err != nil {
+			var validationErr *UploadValidationError
+			if errors.As(err, &validationErr) {
+				writeError(w, http.StatusUnprocessableEntity, validationErr.Code, validationErr.Message)
+				return
+			}
+			log.Printf("Error extracting source archive: %v", err)
+			writeError(w, http.StatusInternalServerError, UploadErrStorage, "Unable to extract source archive")
+			return
+		}
+		log.Printf("Extracted %d source files (%d bytes) for %s", archive.Files, archive.Size, sanitizedFilename)
+	}
+
+	// Otherwise fetch the sources the binary refers to from GitHub. The upload succeeds
+	// without them; GDB only lacks source listings.
+	var fetched *sources.Result
+	var fetchErr error
+	if archive == nil && repository != "" && h.github != nil {
+		fetched, fetchErr = h.fetchSources(r.Context(), dstPath, sessionID, repository, commit)
+		if fetchErr != nil {
+			log.Printf("Error fetching sources of %s from %s at %s: %v", sanitizedFilename, repository, commit, fetchErr)
+		} else {
+			log.Printf("Fetched %d source files (%d bytes) of %s from %s at %s", fetched.Files, fetched.Size, sanitizedFilename, repository, commit)
+		}
+	}
+
 	// --- Start New Log Session ---
-	uploadTime := time.Now().Format("20060102_150405")
-	sessionID := fmt.Sprintf("%s_%s", uploadTime, sanitizedFilename)
 
-	newLogger, err := logsession.NewSessionLogger(sessionID)
+	metadata := map[string]interface{}{
+		"session.filename": sanitizedFilename,
+		"session.format":   format,
+		"session.sources":  archive != nil || (fetched != nil && fetched.Files > 0),
+	}
+	if upload.binary != nil && upload.binary.Metadata != nil {
+		metadata["session.arch"] = upload.binary.Metadata.Arch
+		metadata["session.debug_info"] = upload.binary.Metadata.DebugInfo
+	}
+	if repository != "" {
+		metadata["session.repository"] = repository
+		metadata["session.commit"] = commit
+	}
+	sessionToken, err := startLogSession(h.loggerHolder, h.features, sessionID, user, metadata)
 	if err != nil {
 		// Log to console, but don't fail the upload entirely
-		log.Printf("CRITICAL: Failed to create new session logger for %s: %v", sessionID, err)
+		log.Printf("CRITICAL: %v", err)
 		// Respond with success=false but indicate the underlying issue
 		w.WriteHeader(http.StatusInternalServerError) // Use 500, as logging is critical
 		json.NewEncoder(w).Encode(Response{Success: false, Error: "File uploaded but failed to start logging session"})
 		return
-	} else {
-		h.loggerHolder.Set(newLogger) // Set the new logger, implicitly closes the old one
-		log.Printf("Started new log session: %s", sessionID)
 	}
 	// --- End New Log Session ---
 
+	data := map[string]interface{}{
+		"message":      "File uploaded successfully",
+		"filename":     sanitizedFilename,
+		"format":       format,
+		"sessionToken": sessionToken, // Pass as /ws?session=<token> to receive the session's output
+	}
+	if upload.binary != nil {
+		data["binaryId"] = upload.binary.ID
+		data["deduplicated"] = upload.deduplicated
+		// What the binary runs on and whether it can be debugged at the source level
+		if upload.binary.Metadata != nil {
+			data["metadata"] = upload.binary.Metadata
+			if warning := upload.binary.Metadata.Warning(); warning != "" {
+				data["warning"] = warning
+			}
+		}
+	}
+	if archive != nil {
+		data["sourceFiles"] = archive.Files
+	}
+	if fetched != nil {
+		data["sourceFiles"] = fetched.Files
+	}
+	if fetchErr != nil {
+		data["sourceError"] = "Unable to fetch sources from GitHub: " + fetchErr.Error()
+	}
+
 	// Send success response (use Response struct for consistency)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(Response{
 		Success: true,
-		Data: map[string]string{
-			"message":  "File uploaded successfully",
-			"filename": sanitizedFilename,
-		},
+		Data:    data,
 	})
 
 	log.Printf("File uploaded successfully: %s", sanitizedFilename)
 }
 
+// HandleListBinaries lists the executables the user uploaded, each stored once by its
+// SHA-256, with its size and when it was last uploaded or debugged
+func (h *FileHandler) HandleListBinaries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user, _ := auth.UserFromContext(r.Context())
+	binaries := h.binaries.List(user)
+	for i := range binaries {
+		// Other users who uploaded the same file are not told apart
+		binaries[i].Owners = nil
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: binaries})
+}
+
+// tooLargeMessage explains that a file of size bytes, or of unknown size if negative, is
+// over uploads.max_file_size, and how larger files are uploaded
+func (h *FileHandler) tooLargeMessage(what string, size int64) string {
+	if size < 0 {
+		return fmt.Sprintf("%s exceeds the maximum upload size of %d bytes (uploads.max_file_size); upload larger files in chunks with /api/v1/uploads",
+			what, h.maxFileSize)
+	}
+	return fmt.Sprintf("%s is %d bytes, over the maximum upload size of %d bytes (uploads.max_file_size); upload larger files in chunks with /api/v1/uploads",
+		what, size, h.maxFileSize)
+}
+
+// validSourceRepository reports whether an upload's repository and commit are both
+// unset or are a GitHub owner/name and a commit SHA
+func validSourceRepository(repository, commit string) bool {
+	return repository == "" && commit == "" || sources.ValidRepository(repository) && sources.ValidCommit(commit)
+}
+
 // sanitizeFilename removes potentially unsafe characters from a filename.
 func sanitizeFilename(filename string) string {
 	// Basic sanitization: replace slashes and dots (except the last one for extension)
@@ -150,3 +404,19 @@ func sanitizeFilename(filename string) string {
 	// A better approach might be a whitelist of allowed characters.
 	return name
 }
+
+// fetchSources fetches the sources an uploaded binary refers to from GitHub into the
+// session's sources directory, where GDB finds uploaded sources
+func (h *FileHandler) fetchSources(ctx context.Context, executable, sessionID, repository, commit string) (*sources.Result, error) {
+	referenced, err := sources.Referenced(executable)
+	if err != nil {
+		return nil, err
+	}
+	dest := sourcesDirFor(h.uploadsDir, sessionID)
+	result, err := h.github.Fetch(ctx, repository, commit, referenced, dest)
+	if err != nil {
+		os.RemoveAll(dest)
+		return nil, err
+	}
+	return result, nil
+}
@@ -1,35 +1,101 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/binfile"
+	"github.com/yourusername/gogdbllm/internal/binstore"
 	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/decompile"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/events"
 	"github.com/yourusername/gogdbllm/internal/gdb"
-	"github.com/yourusername/gogdbllm/internal/utils"
+	"github.com/yourusername/gogdbllm/internal/logsession"
 	"github.com/yourusername/gogdbllm/internal/websocket"
 )
 
-// GDBRequest represents the expected JSON payload for starting GDB
+// GDBRequest represents the expected JSON payload for starting GDB, with how the program
+// is run. Its stdinFile names a file of the source archive uploaded with the executable.
 type GDBRequest struct {
 	Filename string `json:"filename"`
+	gdb.ProgramOptions
 }
 
+// errSessionNotOwned is returned when a user acts on another user's debugging session
+var errSessionNotOwned = fmt.Errorf("%w: the debugging session belongs to another user", appErrors.ErrForbidden)
+
+// errInvalidSessionToken is returned when a WebSocket client presents a token that does not
+// belong to the current debugging session
+var errInvalidSessionToken = fmt.Errorf("%w: invalid or expired session token", appErrors.ErrForbidden)
+
 // GDBHandler handles GDB-related operations
 type GDBHandler struct {
 	gdbService   *gdb.GDBService
+	uploadsDir   string
+	binaries     *binstore.Store
 	hub          *websocket.Hub
 	loggerHolder LoggerHolder // Use the interface type defined in file_handler (or move interface)
+	observeCfg   config.ObserveConfig
+	sampler      gdb.Sampler
+
+	outputLines int
+	outputs     map[string]*gdb.OutputRing // Recent terminal output by session ID
+	outputMutex sync.Mutex
+
+	sessionsCfg config.SessionsConfig
+	activity    sessionActivity
+	draining    atomic.Bool // No new sessions are started once set
+
+	decompiler *decompile.Decompiler // nil unless one is enabled
+
+	binaryDigest binaryDigest // Of the executable, for StateFingerprint
+
+	audit  *audit.Trail // nil unless auditing is enabled
+	events *events.Bus  // nil publishes nothing
+
+	hooksCfg    config.HooksConfig
+	hooks       *sessionHooks // Of the current session; nil until its owner sets some
+	hooksMutex  sync.Mutex    // Guards hooks
+	hookRun     sync.Mutex    // Runs one hook at a time
+	hookRunning atomic.Bool   // Set while a hook runs, whose stops run no hooks
+
+	scriptsCfg   config.ScriptsConfig
+	scripts      *sessionScripts // Of the current session; nil until a script is uploaded or run
+	scriptsMutex sync.Mutex      // Guards scripts
 }
 
 // NewGDBHandler creates a new GDB handler
 func NewGDBHandler(hub *websocket.Hub, loggerHolder LoggerHolder, cfg *config.Config) *GDBHandler { // Accept config
 	return &GDBHandler{
 		gdbService:   gdb.NewGDBService(cfg),
+		uploadsDir:   cfg.Uploads.Directory,
+		binaries:     binstore.New(cfg.Uploads),
 		hub:          hub,
 		loggerHolder: loggerHolder,
+		observeCfg:   cfg.GDB.Observe,
+		sampler:      gdb.BatchSampler(cfg.GDB.Path),
+		outputLines:  cfg.GDB.OutputLines,
+		outputs:      make(map[string]*gdb.OutputRing),
+		sessionsCfg:  cfg.Sessions,
+		decompiler:   decompile.New(cfg.Decompiler),
+		hooksCfg:     cfg.GDB.Hooks,
+		scriptsCfg:   cfg.GDB.Scripts,
 	}
 }
 
@@ -41,58 +107,197 @@ func (h *GDBHandler) HandleStartGDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.StartSessionWithProgram(user, req.Filename, req.ProgramOptions); err != nil {
+		if errors.Is(err, appErrors.ErrForbidden) || errors.Is(err, appErrors.ErrBadRequest) || errors.Is(err, appErrors.ErrUnsupported) ||
+			errors.Is(err, appErrors.ErrUnavailable) {
+			http.Error(w, err.Error(), appErrors.StatusCode(err))
+			return
+		}
+		http.Error(w, "Failed to start GDB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Send success response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "GDB started successfully",
+	})
+}
+
+// HandleStopGDB handles requests to stop the current session's GDB
+func (h *GDBHandler) HandleStopGDB(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.StopSession(user); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "GDB stopped",
+	})
+}
+
+// StopSession stops the current session's GDB, provided user owns the session. The
+// session stays current, so GDB can be started again in it.
+func (h *GDBHandler) StopSession(user string) error {
+	if err := h.authorizeOwner(user); err != nil {
+		return err
+	}
+	return h.gdbService.StopGDB()
+}
+
+// StartSession starts GDB on one of user's uploaded executables and streams its output to
+// the user's WebSocket clients. Sources uploaded for the current session are added to GDB's
+// source path. The current session must belong to user.
+func (h *GDBHandler) StartSession(user, filename string) error {
+	return h.StartSessionWithProgram(user, filename, gdb.ProgramOptions{})
+}
+
+// StartSessionWithProgram starts a session as StartSession does, running the program
+// with the arguments, environment, input and working directory of program. Its StdinFile
+// is a path within the source archive uploaded with the session's executable.
+func (h *GDBHandler) StartSessionWithProgram(user, filename string, program gdb.ProgramOptions) error {
+	if h.draining.Load() {
+		return errDraining
+	}
+	if err := h.authorizeOwner(user); err != nil {
+		return err
+	}
+
 	// Construct the full path to the executable
-	// Assuming uploadsDir is accessible or configured elsewhere. For simplicity, using relative path.
-	uploadsDir := "uploads" // Define or get from config
-	filePath := filepath.Join(uploadsDir, req.Filename)
+	filePath := filepath.Join(userUploadsDir(h.uploadsDir, user), sanitizeFilename(filename))
+	// Debugging a stored binary keeps it from expiring
+	h.binaries.Touch(filePath)
 
 	// Get current logger
 	logger := h.loggerHolder.Get()
 
+	if program.StdinFile != "" {
+		sessionID := ""
+		if logger != nil {
+			sessionID = logger.SessionID()
+		}
+		stdinFile, err := h.sessionInputFile(sessionID, program.StdinFile)
+		if err != nil {
+			return err
+		}
+		program.StdinFile = stdinFile
+	}
+
+	// Output is delivered to the clients subscribed to the session, or without a session
+	// (GDB started without an upload) to all of the user's clients
+	broadcast := func(content string) { h.hub.BroadcastToUser(user, content) }
+	status := func(s websocket.StatusPayload) { h.hub.BroadcastStatus(user, s) }
+	stop := func(s websocket.StopPayload) { h.hub.BroadcastStop(user, s) }
+	sessionID := ""
+	if logger != nil {
+		sessionID = logger.SessionID()
+		broadcast = func(content string) { h.hub.BroadcastToSession(sessionID, content) }
+		status = func(s websocket.StatusPayload) { h.hub.BroadcastSessionStatus(sessionID, s) }
+		stop = func(s websocket.StopPayload) { h.hub.BroadcastSessionStop(sessionID, s) }
+	}
+	output := h.outputRing(sessionID)
+
+	// Point GDB at any sources uploaded with this session's binary
+	var sourceDirs []string
+	if logger != nil {
+		sourcesDir := sourcesDirFor(h.uploadsDir, logger.SessionID())
+		if info, err := os.Stat(sourcesDir); err == nil && info.IsDir() {
+			sourceDirs = collectSourceDirs(sourcesDir)
+		}
+	}
+
+	// Tell the clients when GDB exits on its own and whether it was restarted
+	h.gdbService.SetStatusHandler(func(s gdb.Status) {
+		if currentLogger := h.loggerHolder.Get(); currentLogger != nil {
+			currentLogger.LogEvent("WARN", "gdb."+s.State, "GDB exited on its own", map[string]interface{}{
+				"reason":      s.Reason,
+				"breakpoints": s.Breakpoints,
+			})
+		}
+		status(websocket.StatusPayload{GDB: s.State, File: filepath.Base(filePath), Reason: s.Reason, Breakpoints: s.Breakpoints})
+	})
+
 	// Start GDB
-	if err := h.gdbService.StartGDB(filePath); err != nil {
-		http.Error(w, "Failed to start GDB: "+err.Error(), http.StatusInternalServerError)
+	if err := h.gdbService.StartProgram(filePath, program, sourceDirs...); err != nil {
 		if logger != nil {
 			logger.LogError(err, "Starting GDB session for "+filePath)
 		}
-		return
+		return err
 	}
 
 	log.Println("GDB session started for:", filePath)
+	if len(sourceDirs) > 0 {
+		log.Printf("GDB source path includes %d directories", len(sourceDirs))
+	}
+
+	status(websocket.StatusPayload{GDB: "running", File: filepath.Base(filePath)})
+	h.touchSession()
+	h.events.Publish(events.Event{
+		Type:    events.SessionStarted,
+		User:    user,
+		Session: sessionID,
+		Message: "Debugging session started on " + filepath.Base(filePath),
+		Data:    map[string]interface{}{"executable": filepath.Base(filePath)},
+	})
 
 	// Start a goroutine to receive messages from GDB and broadcast them
 	go func() {
 		outputChan := h.gdbService.GetOutputChannel()
-		for outputBytes := range outputChan {
-			rawOutputString := string(outputBytes)
-			// Sanitize the string for logging
-			sanitizedOutputString := utils.StripAnsiAndControlChars(rawOutputString)
+		for chunk := range outputChan {
+			// The clean form is kept for chat requests and logged
+			output.Write(chunk.Clean)
 
 			// Get current logger inside goroutine (it might change)
 			currentLogger := h.loggerHolder.Get()
 			if currentLogger != nil {
-				// Log the sanitized string
-				currentLogger.LogTerminalOutput(sanitizedOutputString)
+				currentLogger.LogTerminalOutput(strings.TrimSuffix(chunk.Clean, "\n"))
+			}
+			// Send the raw form, with its ANSI codes, to the session's subscribers
+			broadcast(chunk.Raw)
+			if chunk.Stop != nil {
+				stop(stopPayload(chunk.Stop))
+				h.publishStop(user, sessionID, filepath.Base(filePath), chunk.Stop)
+				h.afterStop(chunk.Stop)
 			}
-			// Broadcast the original bytes (which might contain ANSI codes for frontend)
-			h.hub.Broadcast(outputBytes)
 		}
 		log.Println("GDB output channel closed for:", filePath)
+		status(websocket.StatusPayload{GDB: "exited", File: filepath.Base(filePath)})
 	}()
 
-	// Send success response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "GDB started successfully",
-	})
+	return nil
+}
+
+// stopPayload converts a stop of the program for the WebSocket
+func stopPayload(stop *gdb.StopLocation) websocket.StopPayload {
+	payload := websocket.StopPayload{
+		Reason:      stop.Reason,
+		Breakpoint:  stop.Breakpoint,
+		Signal:      stop.Signal,
+		Description: stop.Description,
+		Function:    stop.Function,
+		Address:     stop.Address,
+		File:        stop.File,
+		Line:        stop.Line,
+	}
+	for _, line := range stop.Source {
+		payload.Source = append(payload.Source, websocket.SourceLine{Number: line.Number, Text: line.Text, Current: line.Current})
+	}
+	return payload
 }
 
 // HandleCommand handles incoming GDB commands from WebSocket clients (received as string)
 // Signature changed to satisfy the websocket.GDBHandler interface
 func (h *GDBHandler) HandleCommand(cmd string) error { // Changed parameter to string, added error return
-	// Get current logger
-	logger := h.loggerHolder.Get()
+	return h.sendCommand(h.loggerHolder.Get(), cmd)
+}
+
+// sendCommand sends a command typed in the terminal into GDB, logging it with logger
+func (h *GDBHandler) sendCommand(logger *logsession.SessionLogger, cmd string) error {
 	if err := h.gdbService.SendCommand(cmd); err != nil {
 		log.Printf("Error sending command to GDB: %v", err)
 		if logger != nil {
@@ -100,14 +305,138 @@ func (h *GDBHandler) HandleCommand(cmd string) error { // Changed parameter to s
 		}
 		return err // Return the error
 	}
+	if logger != nil {
+		logger.LogGDBCommand(cmd, "user")
+	}
 	return nil // Return nil on success
 }
 
+// HandleUserCommand sends a command typed by user into GDB, provided user owns or joined
+// the session
+func (h *GDBHandler) HandleUserCommand(user, cmd string) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	err := h.sendCommand(h.loggerHolder.Get().ForUser(user), cmd)
+	h.AuditCommand(audit.ActorUser, user, "", cmd, err)
+	return err
+}
+
+// HandleProgramInput sends input typed by user to the debugged program's terminal, provided
+// user owns the session
+func (h *GDBHandler) HandleProgramInput(user, input string) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	if err := h.gdbService.WriteProgramInput(input); err != nil {
+		if logger != nil {
+			logger.LogError(err, "Sending input to the program")
+		}
+		return err
+	}
+	if logger != nil {
+		logger.LogEvent("INFO", "program.input", "Sent input to the program", map[string]interface{}{
+			"program.input": input,
+		})
+	}
+	return nil
+}
+
+// ResizeTerminal sets the size of the debugged program's terminal to that of user's
+// terminal. Resizes from users who do not own the session are ignored, since every
+// client reports its size when it connects.
+func (h *GDBHandler) ResizeTerminal(user string, rows, cols int) error {
+	if h.AuthorizeSession(user) != nil {
+		return nil
+	}
+	return h.gdbService.ResizeTerminal(rows, cols)
+}
+
+// CompleteCommand returns GDB's completions of a partial command typed by user, provided
+// user owns the session
+func (h *GDBHandler) CompleteCommand(user, text string) ([]string, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.CompleteCommand(text)
+}
+
+// AuthorizeSession checks that user owns the current debugging session or joined it.
+// Without authentication every request has the empty user and owns every session. Every
+// use of the session by its members is authorized here, so it also keeps the session from
+// being reaped as idle.
+func (h *GDBHandler) AuthorizeSession(user string) error {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return nil
+	}
+	if !logger.IsMember(user) {
+		return errSessionNotOwned
+	}
+	h.activity.touch(logger.SessionID(), time.Now())
+	return nil
+}
+
+// authorizeOwner checks that user owns the current debugging session, for what members
+// who joined it may not do: stopping it or replacing it with another
+func (h *GDBHandler) authorizeOwner(user string) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	if logger := h.loggerHolder.Get(); logger != nil && logger.Owner() != user {
+		return errSessionNotOwned
+	}
+	return nil
+}
+
+// SubscribeSession returns the ID of the current session if token is its subscription token
+// and user owns or joined it, so the user's WebSocket client may receive its output
+func (h *GDBHandler) SubscribeSession(user, token string) (string, error) {
+	logger := h.loggerHolder.Get()
+	if !validSessionToken(logger, token) {
+		return "", errInvalidSessionToken
+	}
+	if !logger.IsMember(user) {
+		return "", errSessionNotOwned
+	}
+	return logger.SessionID(), nil
+}
+
+// validSessionToken reports whether token is the subscription token of logger's session
+func validSessionToken(logger *logsession.SessionLogger, token string) bool {
+	return logger != nil && logger.Token() != "" &&
+		subtle.ConstantTimeCompare([]byte(logger.Token()), []byte(token)) == 1
+}
+
+// ClaimSession checks that user may replace the current session with a new one: they must
+// own it, or its GDB process must have exited. No session may be started while the server
+// drains.
+func (h *GDBHandler) ClaimSession(user string) error {
+	if h.draining.Load() {
+		return errDraining
+	}
+	if err := h.authorizeOwner(user); err != nil && h.IsRunning() {
+		return err
+	}
+	return nil
+}
+
 // IsRunning returns whether GDB is currently running
 func (h *GDBHandler) IsRunning() bool {
 	return h.gdbService.IsRunning()
 }
 
+// Debugger returns the name of the debugger sessions run, e.g. "GDB" or "CDB"
+func (h *GDBHandler) Debugger() string {
+	return h.gdbService.Debugger()
+}
+
+// LastRestart returns the last automatic restart of GDB, or nil if there was none
+func (h *GDBHandler) LastRestart() *gdb.Restart {
+	return h.gdbService.LastRestart()
+}
+
 // ExecuteCommandWithOutput runs a GDB command and returns its output
 func (h *GDBHandler) ExecuteCommandWithOutput(cmd string) (string, error) {
 	// Get current logger
@@ -125,7 +454,446 @@ func (h *GDBHandler) ExecuteCommandWithOutput(cmd string) (string, error) {
 	// Log that we executed the command
 	if logger != nil {
 		logger.LogTerminalOutput("(LLM-Capture) " + cmd)
+		logger.LogGDBCommand(cmd, "llm")
+	}
+
+	return output, nil
+}
+
+// RunUserCommand runs a command typed by user, provided user owns the session, and returns
+// its output
+func (h *GDBHandler) RunUserCommand(user, cmd string) (string, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return "", err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	output, err := h.gdbService.ExecuteCommandWithOutput(cmd, 2)
+	h.AuditCommand(audit.ActorUser, user, "", cmd, err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, "Running command for "+user+": "+cmd)
+		}
+		return "", err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(cmd, "user")
+	}
+	return output, nil
+}
+
+// ReadMemory reads length bytes at addr for a user, who must own the session
+func (h *GDBHandler) ReadMemory(user string, addr uint64, length int) (*gdb.Memory, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.ReadMemory(addr, length)
+}
+
+// WriteMemory writes data at addr for a user, who must own the session. The write is
+// logged as the command making it, so replays show the change.
+func (h *GDBHandler) WriteMemory(user string, addr uint64, data []byte) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	err := h.gdbService.WriteMemory(addr, data)
+	h.AuditCommand(audit.ActorUser, user, "", gdb.WriteMemoryCommand(addr, data), err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, fmt.Sprintf("Writing %d bytes at 0x%x for %s", len(data), addr, user))
+		}
+		return err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(gdb.WriteMemoryCommand(addr, data), "user")
+	}
+	return nil
+}
+
+// Registers reads the registers for a user, who must own the session, marking those that
+// changed since the last stop
+func (h *GDBHandler) Registers(user string, all bool) (*gdb.Registers, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.Registers(all)
+}
+
+// RecordRegisters records the registers in the output of a register command the assistant
+// ran, so the assistant learns which changed since the last stop
+func (h *GDBHandler) RecordRegisters(output string) *gdb.Registers {
+	return h.gdbService.RecordRegisters(output)
+}
+
+// HandleRegisters returns the registers, e.g. GET /api/v1/debugger/registers?all=true
+func (h *GDBHandler) HandleRegisters(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	registers, err := h.Registers(user, all)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: registers})
+}
+
+// Breakpoints returns the breakpoints GDB confirmed for a user, who must own the session
+func (h *GDBHandler) Breakpoints(user string) ([]gdb.Breakpoint, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.Breakpoints(), nil
+}
 
+// Threads lists the program's threads for a user, who must own the session
+func (h *GDBHandler) Threads(user string) ([]gdb.Thread, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.Threads()
+}
+
+// SelectThread switches to a thread for a user, who must own the session, and returns
+// GDB's report of the thread's frame. The switch is logged as the command making it.
+func (h *GDBHandler) SelectThread(user string, id int) (string, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return "", err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	output, err := h.gdbService.SelectThread(id)
+	h.AuditCommand(audit.ActorUser, user, "", gdb.SelectThreadCommand(id), err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, fmt.Sprintf("Switching to thread %d for %s", id, user))
+		}
+		return "", err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(gdb.SelectThreadCommand(id), "user")
+	}
 	return output, nil
 }
+
+// Goroutines lists a Go program's goroutines for a user, who must own the session
+func (h *GDBHandler) Goroutines(user string) ([]gdb.Goroutine, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.Goroutines()
+}
+
+// ThreadBacktraces returns the backtraces of all threads of a multithreaded program, or ""
+func (h *GDBHandler) ThreadBacktraces() (string, error) {
+	return h.gdbService.ThreadBacktraces()
+}
+
+// HandleThreads lists the threads, e.g. GET /api/v1/debugger/threads
+func (h *GDBHandler) HandleThreads(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	threads, err := h.Threads(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: threads})
+}
+
+// HandleSelectThread switches to a thread, e.g. POST /api/v1/debugger/threads/2/select
+func (h *GDBHandler) HandleSelectThread(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid thread ID", appErrors.ErrBadRequest))
+		return
+	}
+	output, err := h.SelectThread(user, id)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"id":     id,
+		"output": output,
+	}})
+}
+
+// HandleGoroutines lists a Go program's goroutines, e.g. GET /api/v1/debugger/goroutines
+func (h *GDBHandler) HandleGoroutines(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	goroutines, err := h.Goroutines(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: goroutines})
+}
+
+// Checkpoints lists the program's checkpoints for a user, who must own the session
+func (h *GDBHandler) Checkpoints(user string) ([]gdb.Checkpoint, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.Checkpoints()
+}
+
+// Checkpoint snapshots the program for a user, who must own the session
+func (h *GDBHandler) Checkpoint(user, note string) (*gdb.Checkpoint, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	checkpoint, err := h.gdbService.Checkpoint(note)
+	h.AuditCommand(audit.ActorUser, user, "", "checkpoint", err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, "Making a checkpoint for "+user)
+		}
+		return nil, err
+	}
+	if logger != nil {
+		logger.LogGDBCommand("checkpoint", "user")
+	}
+	return checkpoint, nil
+}
+
+// RestoreCheckpoint switches the program back to a checkpoint for a user, who must own the
+// session
+func (h *GDBHandler) RestoreCheckpoint(user string, id int) (string, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return "", err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	output, err := h.gdbService.RestoreCheckpoint(id)
+	h.AuditCommand(audit.ActorUser, user, "", gdb.RestoreCheckpointCommand(id), err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, fmt.Sprintf("Restoring checkpoint %d for %s", id, user))
+		}
+		return "", err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(gdb.RestoreCheckpointCommand(id), "user")
+	}
+	return output, nil
+}
+
+// DeleteCheckpoint deletes a checkpoint for a user, who must own the session
+func (h *GDBHandler) DeleteCheckpoint(user string, id int) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	err := h.gdbService.DeleteCheckpoint(id)
+	h.AuditCommand(audit.ActorUser, user, "", gdb.DeleteCheckpointCommand(id), err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, fmt.Sprintf("Deleting checkpoint %d for %s", id, user))
+		}
+		return err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(gdb.DeleteCheckpointCommand(id), "user")
+	}
+	return nil
+}
+
+// StreamChat sends part of a streamed chat response to the clients of one user
+func (h *GDBHandler) StreamChat(user, requestID, delta string, done bool) {
+	h.hub.SendChatStream(user, websocket.ChatStreamPayload{RequestID: requestID, Delta: delta, Done: done})
+}
+
+// SourcesDir returns where the sources uploaded with the current session's executable
+// were extracted, or "" when none were
+func (h *GDBHandler) SourcesDir() string {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return ""
+	}
+	dir := sourcesDirFor(h.uploadsDir, logger.SessionID())
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// LastStop returns where the program last stopped, with the source around it, or nil
+// before it first stopped
+func (h *GDBHandler) LastStop() *gdb.StopLocation {
+	return h.gdbService.LastStop()
+}
+
+// BinarySummary summarizes the executable being debugged for the assistant: its
+// sections, libraries, imports and interesting strings
+func (h *GDBHandler) BinarySummary() (string, error) {
+	if !h.gdbService.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	f, err := binfile.Open(h.gdbService.Executable())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	summary, err := f.Summary()
+	if err != nil {
+		return "", err
+	}
+	return summary.String(), nil
+}
+
+// BinaryMetadata describes the executable being debugged in a few lines for the
+// assistant: its architecture, libraries, build ID and whether it has debug information
+func (h *GDBHandler) BinaryMetadata() (string, error) {
+	if !h.gdbService.IsRunning() {
+		return "", appErrors.ErrGDBNotRunning
+	}
+	f, err := binfile.Open(h.gdbService.Executable())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return f.Metadata().String(), nil
+}
+
+// DecompileFunction returns the code of a function of the executable being debugged,
+// named by its symbol or address
+func (h *GDBHandler) DecompileFunction(ctx context.Context, function string) (*decompile.Result, error) {
+	if !h.gdbService.IsRunning() {
+		return nil, appErrors.ErrGDBNotRunning
+	}
+	result, err := h.decompiler.Decompile(ctx, h.gdbService.Executable(), function)
+	if err != nil {
+		if logger := h.loggerHolder.Get(); logger != nil {
+			logger.LogError(err, fmt.Sprintf("Decompiling %s", function))
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// MentionedFunction returns the first function of the executable being debugged that a
+// message asks about, or "" if it asks about none. Only executables without debug
+// information have their functions looked for: GDB lists the source of the others.
+func (h *GDBHandler) MentionedFunction(message string) string {
+	if h.decompiler == nil || !h.gdbService.IsRunning() {
+		return ""
+	}
+	mentioned := decompile.Mentioned(message)
+	if len(mentioned) == 0 {
+		return ""
+	}
+	f, err := binfile.Open(h.gdbService.Executable())
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	if f.DebugInfo() {
+		return ""
+	}
+	for _, function := range mentioned {
+		if _, err := decompile.Resolve(f, function); err == nil {
+			return function
+		}
+	}
+	return ""
+}
+
+// RunUntil lets the program run for the assistant until an event or a timeout and
+// returns why it stopped
+func (h *GDBHandler) RunUntil(opts gdb.RunUntilOptions) (*gdb.StopReason, error) {
+	return h.runUntil(opts, "assistant")
+}
+
+// RunUserUntil lets the program run for a user, who must own the session, until an event
+// or a timeout and returns why it stopped
+func (h *GDBHandler) RunUserUntil(user string, opts gdb.RunUntilOptions) (*gdb.StopReason, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	stop, err := h.runUntil(opts, "user")
+	command := opts.Command
+	if stop != nil {
+		command = stop.Command
+	}
+	h.AuditCommand(audit.ActorUser, user, "", command, err)
+	return stop, err
+}
+
+func (h *GDBHandler) runUntil(opts gdb.RunUntilOptions, source string) (*gdb.StopReason, error) {
+	logger := h.loggerHolder.Get()
+	stop, err := h.gdbService.RunUntil(opts)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, fmt.Sprintf("Running until %q for %s", opts.Event, source))
+		}
+		return nil, err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(stop.Command, source)
+		logger.LogTerminalOutput("Stop reason: " + stop.String())
+	}
+	return stop, nil
+}
+
+// AnnotateAddress resolves an address to module, symbol, section and permissions
+func (h *GDBHandler) AnnotateAddress(addr uint64) (*gdb.AddressAnnotation, error) {
+	annotation, err := h.gdbService.AnnotateAddress(addr)
+	if err != nil {
+		if logger := h.loggerHolder.Get(); logger != nil {
+			logger.LogError(err, fmt.Sprintf("Annotating address 0x%x", addr))
+		}
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// HandleAnnotateAddress handles requests to annotate an address in the running inferior
+func (h *GDBHandler) HandleAnnotateAddress(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.AuthorizeSession(user); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	addr, err := gdb.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, "Invalid address", http.StatusBadRequest)
+		return
+	}
+
+	annotation, err := h.AnnotateAddress(addr)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrGDBNotRunning) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to annotate address: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"annotation": annotation,
+			"summary":    annotation.String(),
+		},
+	})
+}
+
+// sessionInputFile returns the path on the server of name, a file of the source archive
+// uploaded with the session's executable, to be read as the program's input
+func (h *GDBHandler) sessionInputFile(sessionID, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if sessionID == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: stdinFile must name a file of the source archive uploaded with the executable", appErrors.ErrBadRequest)
+	}
+	path := filepath.Join(sourcesDirFor(h.uploadsDir, sessionID), name)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: no file %s in the uploaded source archive", appErrors.ErrBadRequest, filepath.ToSlash(name))
+	}
+	return path, nil
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// Events of a session hooks run on
+const (
+	HookBeforeCommand = "before-command" // Before each command the assistant runs
+	HookStop          = "stop"           // After each stop of the program but its exit
+	HookExit          = "exit"           // When the program exits
+)
+
+var (
+	// errHooksDisabled is returned when hooks are set while gdb.hooks.enabled is off
+	errHooksDisabled = fmt.Errorf("%w: hooks are disabled on this server", appErrors.ErrForbidden)
+
+	// errNoSession is returned when hooks are set before a session was started
+	errNoSession = fmt.Errorf("%w: there is no debugging session; upload an executable first", appErrors.ErrBadRequest)
+)
+
+// Hook is a list of GDB commands run on an event of the session. Commands run one at a
+// time as if typed in the terminal, so a hook can be a small GDB script, e.g. printing
+// registers and a backtrace after each stop.
+type Hook struct {
+	Name     string   `json:"name,omitempty"`
+	Event    string   `json:"event"` // HookBeforeCommand, HookStop or HookExit
+	Commands []string `json:"commands"`
+}
+
+// HooksRequest is the body of a request setting the session's hooks
+type HooksRequest struct {
+	Hooks []Hook `json:"hooks"` // Replace the session's hooks; empty removes them
+}
+
+// HookRun is a run of a hook, with what its commands printed
+type HookRun struct {
+	Hook    string       `json:"hook,omitempty"`
+	Event   string       `json:"event"`
+	Trigger string       `json:"trigger,omitempty"` // The assistant's command, or the reason of the stop
+	Time    time.Time    `json:"time"`
+	Results []HookResult `json:"results"`
+}
+
+// HookResult is what a command of a hook printed, or why it failed
+type HookResult struct {
+	Command string `json:"command"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// sessionHooks are the hooks of a session, who set them and their recent runs
+type sessionHooks struct {
+	session string
+	user    string
+	hooks   []Hook
+	runs    []HookRun // Oldest first
+}
+
+// SetHooks replaces the hooks of the current session, which user must own
+func (h *GDBHandler) SetHooks(user string, hooks []Hook) error {
+	if !h.hooksCfg.Enabled {
+		return errHooksDisabled
+	}
+	if err := h.authorizeOwner(user); err != nil {
+		return err
+	}
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return errNoSession
+	}
+	hooks, err := h.validateHooks(hooks)
+	if err != nil {
+		return err
+	}
+
+	h.hooksMutex.Lock()
+	defer h.hooksMutex.Unlock()
+	if h.hooks == nil || h.hooks.session != logger.SessionID() {
+		h.hooks = &sessionHooks{session: logger.SessionID()}
+	}
+	h.hooks.user, h.hooks.hooks = user, hooks
+	logger.LogEvent("INFO", "hooks.set", "Set the session's hooks", map[string]interface{}{
+		"hooks.count": len(hooks),
+	})
+	return nil
+}
+
+// Hooks returns the hooks of the current session and their recent runs, provided user owns
+// or joined it
+func (h *GDBHandler) Hooks(user string) ([]Hook, []HookRun, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, nil, err
+	}
+	h.hooksMutex.Lock()
+	defer h.hooksMutex.Unlock()
+	current := h.currentHooksLocked()
+	if current == nil {
+		return []Hook{}, []HookRun{}, nil
+	}
+	return append([]Hook{}, current.hooks...), append([]HookRun{}, current.runs...), nil
+}
+
+// validateHooks checks hooks against the limits of gdb.hooks, returning them with their
+// commands trimmed
+func (h *GDBHandler) validateHooks(hooks []Hook) ([]Hook, error) {
+	if len(hooks) > h.hooksCfg.MaxHooks {
+		return nil, fmt.Errorf("%w: at most %d hooks may be set", appErrors.ErrBadRequest, h.hooksCfg.MaxHooks)
+	}
+	valid := make([]Hook, len(hooks))
+	for i, hook := range hooks {
+		switch hook.Event {
+		case HookBeforeCommand, HookStop, HookExit:
+		default:
+			return nil, fmt.Errorf("%w: hook %d has unknown event %q (want %s, %s or %s)",
+				appErrors.ErrBadRequest, i+1, hook.Event, HookBeforeCommand, HookStop, HookExit)
+		}
+		if len(hook.Commands) == 0 || len(hook.Commands) > h.hooksCfg.MaxCommands {
+			return nil, fmt.Errorf("%w: hook %d needs 1 to %d commands", appErrors.ErrBadRequest, i+1, h.hooksCfg.MaxCommands)
+		}
+		valid[i] = Hook{Name: hook.Name, Event: hook.Event, Commands: make([]string, len(hook.Commands))}
+		for j, command := range hook.Commands {
+			command = strings.TrimSpace(command)
+			if command == "" || strings.ContainsAny(command, "\r\n") {
+				return nil, fmt.Errorf("%w: command %d of hook %d must be a single non-empty line", appErrors.ErrBadRequest, j+1, i+1)
+			}
+			valid[i].Commands[j] = command
+		}
+	}
+	return valid, nil
+}
+
+// currentHooksLocked returns the hooks of the current session, or nil when it has none.
+// The caller holds hooksMutex.
+func (h *GDBHandler) currentHooksLocked() *sessionHooks {
+	logger := h.loggerHolder.Get()
+	if logger == nil || h.hooks == nil || h.hooks.session != logger.SessionID() {
+		return nil
+	}
+	return h.hooks
+}
+
+// BeforeLLMCommand runs the session's before-command hooks ahead of a command the
+// assistant runs, after waiting for the hooks of an earlier stop to finish
+func (h *GDBHandler) BeforeLLMCommand(command string) {
+	h.runHooks(HookBeforeCommand, command)
+}
+
+// afterStop runs the session's hooks for a stop of the program in the background, so
+// the output of the hooks' own commands keeps flowing. Stops caused by a hook's commands
+// run no hooks.
+func (h *GDBHandler) afterStop(stop *gdb.StopLocation) {
+	if h.hookRunning.Load() {
+		return
+	}
+	event := HookStop
+	if stop.Reason == gdb.StopExited {
+		event = HookExit
+	}
+	go h.runHooks(event, stop.Reason)
+}
+
+// runHooks runs the current session's hooks for an event, one run at a time, recording
+// each run with its output
+func (h *GDBHandler) runHooks(event, trigger string) {
+	h.hookRun.Lock()
+	defer h.hookRun.Unlock()
+
+	h.hooksMutex.Lock()
+	current := h.currentHooksLocked()
+	var hooks []Hook
+	session, user := "", ""
+	if current != nil {
+		session, user = current.session, current.user
+		for _, hook := range current.hooks {
+			if hook.Event == event {
+				hooks = append(hooks, hook)
+			}
+		}
+	}
+	h.hooksMutex.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	h.hookRunning.Store(true)
+	defer h.hookRunning.Store(false)
+	logger := h.loggerHolder.Get()
+	for _, hook := range hooks {
+		run := HookRun{Hook: hook.Name, Event: event, Trigger: trigger, Time: time.Now().UTC()}
+		for _, command := range hook.Commands {
+			output, err := h.gdbService.ExecuteCommandWithOutput(command, 2)
+			h.AuditCommand(audit.ActorHook, user, "", command, err)
+			result := HookResult{Command: command, Output: output}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			run.Results = append(run.Results, result)
+			if logger != nil {
+				logger.LogGDBCommand(command, "hook")
+			}
+		}
+		if logger != nil {
+			logger.LogEvent("INFO", "hook.run", "Ran a hook", map[string]interface{}{
+				"hook.name":     hook.Name,
+				"hook.event":    event,
+				"hook.trigger":  trigger,
+				"hook.commands": len(hook.Commands),
+			})
+		}
+		h.recordHookRun(session, run)
+	}
+}
+
+// recordHookRun keeps a run of a session's hook, dropping the oldest beyond
+// gdb.hooks.keep_runs
+func (h *GDBHandler) recordHookRun(session string, run HookRun) {
+	h.hooksMutex.Lock()
+	defer h.hooksMutex.Unlock()
+	if h.hooks == nil || h.hooks.session != session {
+		return
+	}
+	h.hooks.runs = append(h.hooks.runs, run)
+	if excess := len(h.hooks.runs) - h.hooksCfg.KeepRuns; excess > 0 {
+		h.hooks.runs = append([]HookRun{}, h.hooks.runs[excess:]...)
+	}
+}
+
+// HandleHooks returns the current session's hooks and their recent runs, e.g.
+// GET /api/v1/debugger/hooks
+func (h *GDBHandler) HandleHooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	hooks, runs, err := h.Hooks(user)
+	if err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"hooks": hooks,
+		"runs":  runs,
+	}})
+}
+
+// HandleSetHooks replaces the current session's hooks, e.g. POST /api/v1/debugger/hooks
+// {"hooks": [{"event": "stop", "commands": ["info registers rip", "bt 3"]}]}
+func (h *GDBHandler) HandleSetHooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req HooksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.SetHooks(user, req.Hooks); err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	hooks, _, _ := h.Hooks(user)
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"hooks": hooks,
+	}})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+func TestSessionHooks(t *testing.T) {
+	cfg := &config.Config{GDB: config.GDBConfig{Hooks: config.HooksConfig{Enabled: true, MaxHooks: 2, MaxCommands: 2, KeepRuns: 2}}}
+	h, holder := newTestGDBHandler(t, cfg)
+
+	assert.ErrorIs(t, h.SetHooks("alice", []Hook{{Event: HookStop, Commands: []string{"bt"}}}), appErrors.ErrBadRequest, "there is no session yet")
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	logger.SetOwner("alice")
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	for name, hooks := range map[string][]Hook{
+		"unknown event":  {{Event: "start", Commands: []string{"bt"}}},
+		"no commands":    {{Event: HookStop}},
+		"many commands":  {{Event: HookStop, Commands: []string{"bt", "info registers", "x/4x $sp"}}},
+		"many hooks":     {{Event: HookStop, Commands: []string{"bt"}}, {Event: HookExit, Commands: []string{"bt"}}, {Event: HookStop, Commands: []string{"bt"}}},
+		"multiline":      {{Event: HookStop, Commands: []string{"bt\nkill"}}},
+		"empty commands": {{Event: HookStop, Commands: []string{"  "}}},
+	} {
+		assert.ErrorIs(t, h.SetHooks("alice", hooks), appErrors.ErrBadRequest, name)
+	}
+	assert.ErrorIs(t, h.SetHooks("bob", []Hook{{Event: HookStop, Commands: []string{"bt"}}}), appErrors.ErrForbidden, "only the owner sets hooks")
+
+	require.NoError(t, h.SetHooks("alice", []Hook{
+		{Name: "where", Event: HookStop, Commands: []string{" bt 3 ", "info registers rip"}},
+		{Event: HookBeforeCommand, Commands: []string{"info frame"}},
+	}))
+	hooks, runs, err := h.Hooks("alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bt 3", "info registers rip"}, hooks[0].Commands)
+	assert.Empty(t, runs)
+
+	// Runs are kept with what each command printed, or why it failed, up to keep_runs
+	for _, trigger := range []string{"breakpoint", "signal", "stepped"} {
+		h.runHooks(HookStop, trigger)
+	}
+	h.runHooks(HookExit, "exited")
+	_, runs, err = h.Hooks("alice")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "signal", runs[0].Trigger)
+	assert.Equal(t, "where", runs[1].Hook)
+	require.Len(t, runs[1].Results, 2)
+	assert.Equal(t, "bt 3", runs[1].Results[0].Command)
+	assert.NotEmpty(t, runs[1].Results[0].Error, "GDB is not running")
+
+	h.BeforeLLMCommand("next")
+	_, runs, _ = h.Hooks("alice")
+	assert.Equal(t, HookBeforeCommand, runs[1].Event)
+	assert.Equal(t, "next", runs[1].Trigger)
+
+	// Another session has no hooks
+	other, err := logsession.NewSessionLogger("s2")
+	require.NoError(t, err)
+	other.SetOwner("alice")
+	holder.Set(other)
+	hooks, _, err = h.Hooks("alice")
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+
+	h.hooksCfg.Enabled = false
+	assert.ErrorIs(t, h.SetHooks("alice", nil), appErrors.ErrForbidden)
+}
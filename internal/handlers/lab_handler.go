@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/labs"
+)
+
+// Lab error codes returned in Response.Code
+const (
+	LabErrInvalidRequest = "invalid_request"
+	LabErrNotFound       = "lab_not_found"
+	LabErrForbidden      = "forbidden"
+	LabErrTooLarge       = "file_too_large"
+	LabErrStorage        = "storage_error"
+	LabErrSessionInUse   = "session_in_use"
+)
+
+// LabHandler serves the lab catalog, starts sessions on lab targets and lets lab admins
+// manage the targets
+type LabHandler struct {
+	catalog      *labs.Catalog
+	admins       map[string]bool
+	adminsMutex  sync.RWMutex
+	uploadsDir   string
+	maxFileSize  int64
+	gdbHandler   *GDBHandler
+	loggerHolder LoggerHolder
+	features     *features.Manager
+}
+
+// NewLabHandler creates a new lab handler
+func NewLabHandler(cfg *config.Config, catalog *labs.Catalog, gdbHandler *GDBHandler, loggerHolder LoggerHolder, featureManager *features.Manager) *LabHandler {
+	maxFileSize := cfg.Uploads.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+	return &LabHandler{
+		catalog:      catalog,
+		admins:       adminSet(cfg.Labs.Admins),
+		uploadsDir:   cfg.Uploads.Directory,
+		maxFileSize:  maxFileSize,
+		gdbHandler:   gdbHandler,
+		loggerHolder: loggerHolder,
+		features:     featureManager,
+	}
+}
+
+// Reload replaces the lab admins when labs.admins changes
+func (h *LabHandler) Reload(cfg config.LabsConfig) {
+	h.adminsMutex.Lock()
+	defer h.adminsMutex.Unlock()
+	h.admins = adminSet(cfg.Admins)
+}
+
+// adminSet returns the set of admins in a list
+func adminSet(list []string) map[string]bool {
+	admins := make(map[string]bool, len(list))
+	for _, admin := range list {
+		admins[admin] = true
+	}
+	return admins
+}
+
+// HandleCatalog lists the lab targets, e.g. GET /api/labs
+func (h *LabHandler) HandleCatalog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: h.catalog.List()})
+}
+
+// HandleStart prepares a fresh session on a copy of a lab target's executable, e.g.
+// POST /api/labs/{id}/start. Like an upload, it replaces the user's current session, and
+// the client subscribes with the returned token and starts GDB on the returned filename.
+func (h *LabHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.gdbHandler.ClaimSession(user); err != nil {
+		writeClaimError(w, err, LabErrSessionInUse)
+		return
+	}
+
+	target, err := h.catalog.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, LabErrNotFound, err.Error())
+		return
+	}
+
+	// Each session gets its own copy, so nothing a student does to the file carries over
+	sessionID := newSessionID(time.Now(), target.ID)
+	uploadsDir := userUploadsDir(h.uploadsDir, user)
+	if err := copyExecutable(h.catalog.BinaryPath(target.ID), filepath.Join(uploadsDir, sessionID)); err != nil {
+		log.Printf("Error copying lab %s: %v", target.ID, err)
+		writeError(w, http.StatusInternalServerError, LabErrStorage, "Unable to prepare the lab's executable")
+		return
+	}
+
+	sessionToken, err := startLogSession(h.loggerHolder, h.features, sessionID, user, map[string]interface{}{
+		"session.filename": sessionID,
+		"session.lab":      target.ID,
+	})
+	if err != nil {
+		log.Printf("CRITICAL: %v", err)
+		writeError(w, http.StatusInternalServerError, LabErrStorage, "Failed to start logging session")
+		return
+	}
+
+	log.Printf("Prepared lab %s for %q", target.ID, user)
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"message":      "Lab " + target.Name + " is ready",
+			"lab":          target,
+			"filename":     sessionID,
+			"sessionToken": sessionToken,
+		},
+	})
+}
+
+// HandleAdminPut adds or replaces a lab target, e.g. POST /api/admin/labs with a
+// multipart form holding the target as JSON in the "target" field and its executable in
+// the "binary" file. The executable may be omitted when replacing a target.
+func (h *LabHandler) HandleAdminPut(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxFileSize+(1<<20))
+	if err := r.ParseMultipartForm(uploadFormMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, LabErrTooLarge,
+				fmt.Sprintf("File exceeds the maximum upload size of %d bytes", h.maxFileSize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, LabErrInvalidRequest, "Unable to parse form: "+err.Error())
+		return
+	}
+
+	var target labs.Target
+	if err := json.Unmarshal([]byte(r.FormValue("target")), &target); err != nil {
+		writeError(w, http.StatusBadRequest, LabErrInvalidRequest, "The target field must be a JSON lab target")
+		return
+	}
+
+	var binary io.Reader
+	if file, _, err := r.FormFile("binary"); err == nil {
+		defer file.Close()
+		_, header, err := sniffExecutable(file)
+		if err != nil {
+			var validationErr *UploadValidationError
+			if errors.As(err, &validationErr) {
+				writeError(w, http.StatusUnsupportedMediaType, validationErr.Code, validationErr.Message)
+				return
+			}
+			writeError(w, http.StatusBadRequest, LabErrInvalidRequest, "Unable to read uploaded file")
+			return
+		}
+		binary = io.MultiReader(bytes.NewReader(header), file)
+	}
+
+	if err := h.catalog.Put(target, binary); err != nil {
+		if errors.Is(err, appErrors.ErrBadRequest) {
+			writeError(w, http.StatusBadRequest, LabErrInvalidRequest, err.Error())
+			return
+		}
+		log.Printf("Error saving lab %s: %v", target.ID, err)
+		writeError(w, http.StatusInternalServerError, LabErrStorage, "Unable to save the lab")
+		return
+	}
+
+	json.NewEncoder(w).Encode(Response{Success: true, Data: target})
+}
+
+// HandleAdminDelete removes a lab target, e.g. DELETE /api/admin/labs/{id}
+func (h *LabHandler) HandleAdminDelete(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	if err := h.catalog.Remove(mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			writeError(w, http.StatusNotFound, LabErrNotFound, err.Error())
+			return
+		}
+		log.Printf("Error removing lab: %v", err)
+		writeError(w, http.StatusInternalServerError, LabErrStorage, "Unable to remove the lab")
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true})
+}
+
+// authorizeAdmin checks that the requesting user may manage lab targets: they must be
+// listed in labs.admins, or with authentication disabled the list must be empty
+func (h *LabHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user, _ := auth.UserFromContext(r.Context())
+	h.adminsMutex.RLock()
+	allowed := h.admins[user] || (user == "" && len(h.admins) == 0)
+	h.adminsMutex.RUnlock()
+	if allowed {
+		return true
+	}
+	writeError(w, http.StatusForbidden, LabErrForbidden, "Only lab admins may manage lab targets")
+	return false
+}
+
+// copyExecutable copies an executable to dst, creating dst's directory
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// logFollowInterval is how often a followed session log is checked for new entries
+const logFollowInterval = 500 * time.Millisecond
+
+// maxLogLine bounds a single session log entry; GDB output lines can be large
+const maxLogLine = 16 * 1024 * 1024
+
+// LogHandler lets users list, read, follow and download the logs of their debugging sessions
+type LogHandler struct {
+	loggerHolder LoggerHolder
+}
+
+// NewLogHandler creates a new session log handler
+func NewLogHandler(loggerHolder LoggerHolder) *LogHandler {
+	return &LogHandler{loggerHolder: loggerHolder}
+}
+
+// SessionLogInfo describes a session log file
+type SessionLogInfo struct {
+	ID       string    `json:"id"`
+	Owner    string    `json:"owner,omitempty"`
+	Started  time.Time `json:"started"`
+	Modified time.Time `json:"modified"`
+	Size     int64     `json:"size"`
+	Current  bool      `json:"current"` // The session is being logged
+}
+
+// logLine is the part of a session log entry the handler filters on
+type logLine struct {
+	Timestamp string `json:"timestamp"`
+	SessionID string `json:"session.id"`
+	Type      string `json:"event.type"`
+	Owner     string `json:"session.owner"`
+}
+
+// HandleList lists the user's session logs, most recently written first, e.g. GET /api/logs
+func (h *LogHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+
+	files, err := os.ReadDir(logsession.LogDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error listing session logs: %v", err)
+		writeJSONResponseError(w, http.StatusInternalServerError, "Unable to list session logs")
+		return
+	}
+
+	current := h.currentSessionID()
+	sessions := []SessionLogInfo{}
+	for _, file := range files {
+		sessionID, ok := strings.CutSuffix(file.Name(), ".log")
+		if !ok || file.IsDir() || !validSessionID(sessionID) {
+			continue
+		}
+		info, err := readSessionLogInfo(sessionID)
+		if err != nil || info.Owner != user {
+			continue
+		}
+		info.Current = sessionID == current
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Modified.After(sessions[j].Modified) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: sessions})
+}
+
+// HandleEntries returns a session's log entries, e.g. GET /api/logs/current?type=gdb.command&tail=50.
+// type takes a comma-separated list of event types, where "gdb.*" matches every type
+// starting with "gdb."; tail keeps only the last n matching entries. With follow=true the
+// matching entries are streamed as JSON Lines, followed by new entries as they are
+// written, until the client disconnects or another session starts.
+func (h *LogHandler) HandleEntries(w http.ResponseWriter, r *http.Request) {
+	sessionID, file, ok := h.openSessionLog(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	query := r.URL.Query()
+	types := parseTypeFilter(query.Get("type"))
+	tail := 0
+	if s := query.Get("tail"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeJSONResponseError(w, http.StatusBadRequest, "tail must be a non-negative number")
+			return
+		}
+		tail = n
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var entries []json.RawMessage
+	var partial []byte
+	for {
+		line, err := readLogLine(reader, &partial)
+		if line != nil && types.match(line) {
+			entries = append(entries, line)
+			if tail > 0 && len(entries) > tail {
+				entries = entries[1:]
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if query.Get("follow") != "true" {
+		if entries == nil {
+			entries = []json.RawMessage{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+			"session": sessionID,
+			"entries": entries,
+		}})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	write := func(line []byte) bool {
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return false
+		}
+		return true
+	}
+	for _, entry := range entries {
+		if !write(entry) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			line, err := readLogLine(reader, &partial)
+			if line != nil && types.match(line) && !write(line) {
+				return
+			}
+			if err != nil {
+				break
+			}
+		}
+		if h.currentSessionID() != sessionID {
+			// The session has ended, so its log will not grow any further
+			return
+		}
+	}
+}
+
+// HandleDownload downloads a session's raw JSON Lines log, e.g. GET /api/logs/{id}/download
+func (h *LogHandler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	sessionID, file, ok := h.openSessionLog(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sessionID+".jsonl"))
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Error sending session log %s: %v", sessionID, err)
+	}
+}
+
+// openSessionLog opens the log of the session named in the request, writing an error
+// response if it does not exist or belongs to another user
+func (h *LogHandler) openSessionLog(w http.ResponseWriter, r *http.Request) (string, *os.File, bool) {
+	user, _ := auth.UserFromContext(r.Context())
+	sessionID := mux.Vars(r)["id"]
+	if sessionID == currentSessionAlias {
+		sessionID = h.currentSessionID()
+		if sessionID == "" {
+			writeJSONResponseError(w, http.StatusNotFound, "No active session")
+			return "", nil, false
+		}
+	}
+	if !validSessionID(sessionID) {
+		writeJSONResponseError(w, http.StatusBadRequest, "Invalid session ID")
+		return "", nil, false
+	}
+
+	info, err := readSessionLogInfo(sessionID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSONResponseError(w, http.StatusNotFound, "Session not found")
+			return "", nil, false
+		}
+		log.Printf("Error reading session log %s: %v", sessionID, err)
+		writeJSONResponseError(w, http.StatusInternalServerError, "Unable to read session log")
+		return "", nil, false
+	}
+	if info.Owner != user {
+		writeJSONResponseError(w, http.StatusForbidden, "Session belongs to another user")
+		return "", nil, false
+	}
+
+	file, err := os.Open(logsession.LogFilePath(sessionID))
+	if err != nil {
+		log.Printf("Error opening session log %s: %v", sessionID, err)
+		writeJSONResponseError(w, http.StatusInternalServerError, "Unable to read session log")
+		return "", nil, false
+	}
+	return sessionID, file, true
+}
+
+// currentSessionID returns the ID of the session being logged, or ""
+func (h *LogHandler) currentSessionID() string {
+	if logger := h.loggerHolder.Get(); logger != nil {
+		return logger.SessionID()
+	}
+	return ""
+}
+
+// readSessionLogInfo describes a session log from its file and its first entry, the
+// session metadata. Files whose first entry is not of the session, such as the
+// application log, are reported as not existing.
+func readSessionLogInfo(sessionID string) (SessionLogInfo, error) {
+	path := logsession.LogFilePath(sessionID)
+	file, err := os.Open(path)
+	if err != nil {
+		return SessionLogInfo{}, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return SessionLogInfo{}, err
+	}
+
+	first, err := bufio.NewReaderSize(file, 64*1024).ReadSlice('\n')
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return SessionLogInfo{}, err
+	}
+	var entry logLine
+	if json.Unmarshal(first, &entry) != nil || entry.SessionID != sessionID {
+		return SessionLogInfo{}, fmt.Errorf("%s is not a session log: %w", path, os.ErrNotExist)
+	}
+
+	info := SessionLogInfo{ID: sessionID, Modified: stat.ModTime(), Size: stat.Size()}
+	info.Started, _ = time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if entry.Type == "session.metadata" {
+		info.Owner = entry.Owner
+	}
+	return info, nil
+}
+
+// readLogLine reads the next complete line of a log. A trailing line still being written
+// is kept in partial and completed by a later call. It returns nil for blank or oversized
+// lines, and io.EOF once no complete line is left.
+func readLogLine(reader *bufio.Reader, partial *[]byte) (json.RawMessage, error) {
+	chunk, err := reader.ReadBytes('\n')
+	*partial = append(*partial, chunk...)
+	if err != nil {
+		if len(*partial) > maxLogLine {
+			*partial = nil
+		}
+		return nil, err
+	}
+
+	line := bytes.TrimSpace(*partial)
+	*partial = nil
+	if len(line) == 0 || len(line) > maxLogLine || !json.Valid(line) {
+		return nil, nil
+	}
+	return json.RawMessage(line), nil
+}
+
+// typeFilter matches log entries by event type; an empty filter matches every entry
+type typeFilter []string
+
+// parseTypeFilter parses a comma-separated list of event types
+func parseTypeFilter(s string) typeFilter {
+	var filter typeFilter
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter = append(filter, t)
+		}
+	}
+	return filter
+}
+
+// match reports whether a log entry's event type is selected by the filter
+func (f typeFilter) match(line json.RawMessage) bool {
+	if len(f) == 0 {
+		return true
+	}
+	var entry logLine
+	if json.Unmarshal(line, &entry) != nil {
+		return false
+	}
+	for _, t := range f {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			if strings.HasPrefix(entry.Type, prefix) {
+				return true
+			}
+		} else if entry.Type == t {
+			return true
+		}
+	}
+	return false
+}
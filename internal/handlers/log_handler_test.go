@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+func TestLogHandler(t *testing.T) {
+	_, holder := newTestGDBHandler(t, &config.Config{})
+	other, err := logsession.NewSessionLogger("other")
+	require.NoError(t, err)
+	other.LogSessionMetadata(map[string]interface{}{"session.owner": "bob"})
+	other.Close()
+
+	current, err := logsession.NewSessionLogger("mine")
+	require.NoError(t, err)
+	current.LogSessionMetadata(map[string]interface{}{})
+	current.LogGDBCommand("break main", "user")
+	current.LogLLMResponse("Set a breakpoint")
+	current.LogGDBCommand("run", "llm")
+	holder.Set(current)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	require.NoError(t, os.WriteFile(logsession.LogFilePath("application"), []byte("not a session log\n"), 0644))
+
+	router := mux.NewRouter()
+	h := NewLogHandler(holder)
+	router.HandleFunc("/api/logs", h.HandleList)
+	router.HandleFunc("/api/logs/{id}", h.HandleEntries)
+	router.HandleFunc("/api/logs/{id}/download", h.HandleDownload)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	// Only the user's own session logs are listed
+	rec := get("/api/logs")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct{ Data []SessionLogInfo }
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "mine", list.Data[0].ID)
+	assert.True(t, list.Data[0].Current)
+
+	// Entries can be filtered by type and tailed
+	rec = get("/api/logs/current?type=gdb.*&tail=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries struct {
+		Data struct {
+			Session string
+			Entries []map[string]interface{}
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Equal(t, "mine", entries.Data.Session)
+	require.Len(t, entries.Data.Entries, 1)
+	assert.Equal(t, "run", entries.Data.Entries[0]["gdb.command"])
+
+	rec = get("/api/logs/mine/download")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "mine.jsonl")
+	assert.Contains(t, rec.Body.String(), `"llm.response.body":"Set a breakpoint"`)
+
+	assert.Equal(t, http.StatusForbidden, get("/api/logs/other").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/logs/application").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/logs/missing/download").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/logs/mine?tail=-1").Code)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// defaultMemoryRead is how many bytes are read when the request names no length
+const defaultMemoryRead = 256
+
+// MemoryView is a block of memory as the hex viewer shows it
+type MemoryView struct {
+	Address string   `json:"address"`
+	Length  int      `json:"length"`
+	Raw     []byte   `json:"raw"` // Base64 in JSON
+	Hex     string   `json:"hex"`
+	ASCII   string   `json:"ascii"`
+	Hexdump []string `json:"hexdump"`
+}
+
+// MemoryWriteRequest writes bytes given in hex at an address
+type MemoryWriteRequest struct {
+	Address string `json:"addr"`
+	Hex     string `json:"hex"`
+}
+
+// MemoryHandler reads and writes the debugged program's memory. Writes change the
+// program, so they need the role in gdb.memory.write_role, which the operator sets.
+// The user's prompt profile must also allow the set command that makes them, as it must
+// for the assistant, but users choose their own profile, so it only keeps them from
+// changing memory by mistake, e.g. under triage.
+type MemoryHandler struct {
+	gdbHandler *GDBHandler
+	settings   *settings.Manager
+	prompts    *prompts.Engine
+	writeRole  string
+}
+
+// NewMemoryHandler creates a new memory handler
+func NewMemoryHandler(gdbHandler *GDBHandler, settingsManager *settings.Manager, promptEngine *prompts.Engine, cfg *config.Config) *MemoryHandler {
+	writeRole := cfg.GDB.Memory.WriteRole
+	if writeRole == "" {
+		writeRole = auth.RoleAdmin
+	}
+	return &MemoryHandler{gdbHandler: gdbHandler, settings: settingsManager, prompts: promptEngine, writeRole: writeRole}
+}
+
+// HandleRead returns a block of memory, e.g. GET /api/v1/debugger/memory?addr=0x4010&len=64
+func (h *MemoryHandler) HandleRead(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	addr, err := gdb.ParseAddress(r.URL.Query().Get("addr"))
+	if err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: addr must be a decimal or 0x-prefixed address", appErrors.ErrBadRequest))
+		return
+	}
+	length := defaultMemoryRead
+	if value := r.URL.Query().Get("len"); value != "" {
+		if length, err = strconv.Atoi(value); err != nil {
+			writeDebuggerError(w, fmt.Errorf("%w: len must be a number", appErrors.ErrBadRequest))
+			return
+		}
+	}
+
+	memory, err := h.gdbHandler.ReadMemory(user, addr, length)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: MemoryView{
+		Address: fmt.Sprintf("0x%x", memory.Address),
+		Length:  len(memory.Bytes),
+		Raw:     memory.Bytes,
+		Hex:     memory.Hex(),
+		ASCII:   memory.ASCII(),
+		Hexdump: memory.Hexdump(),
+	}})
+}
+
+// HandleWrite writes bytes to memory, e.g. POST /api/v1/debugger/memory with
+// {"addr": "0x4010", "hex": "deadbeef"}
+func (h *MemoryHandler) HandleWrite(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	if role := auth.RoleFromContext(r.Context()); !auth.Allows(role, h.writeRole) {
+		writeDebuggerError(w, fmt.Errorf("%w: changing memory requires the %s role (%s has %s)", appErrors.ErrForbidden, h.writeRole, user, role))
+		return
+	}
+	var req MemoryWriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+		return
+	}
+	addr, err := gdb.ParseAddress(req.Address)
+	if err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: addr must be a decimal or 0x-prefixed address", appErrors.ErrBadRequest))
+		return
+	}
+	data, err := hex.DecodeString(strings.ReplaceAll(req.Hex, " ", ""))
+	if err != nil || len(data) == 0 || len(data) > gdb.MaxMemoryWrite {
+		writeDebuggerError(w, fmt.Errorf("%w: hex must hold 1 to %d bytes", appErrors.ErrBadRequest, gdb.MaxMemoryWrite))
+		return
+	}
+
+	if err := checkProfile(h.settings, h.prompts, user, gdb.WriteMemoryCommand(addr, data), "changing memory"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	if err := h.gdbHandler.WriteMemory(user, addr, data); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"address": fmt.Sprintf("0x%x", addr),
+		"length":  len(data),
+	}})
+}
+
+// writeDebuggerError answers a debugger state request with the status the error calls for
+func writeDebuggerError(w http.ResponseWriter, err error) {
+	status := appErrors.StatusCode(err)
+	if errors.Is(err, appErrors.ErrGDBNotRunning) {
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Success: false, Error: err.Error()})
+}
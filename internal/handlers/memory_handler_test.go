@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestMemoryWriteRole(t *testing.T) {
+	// The token's user is a debugger
+	authenticator, err := auth.NewAuthenticator(&config.Config{Auth: config.AuthConfig{Mode: "token", Token: "s3cret", DefaultRole: auth.RoleDebugger}})
+	require.NoError(t, err)
+	write := func(cfg *config.Config) *httptest.ResponseRecorder {
+		h := NewMemoryHandler(nil, nil, nil, cfg)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/debugger/memory", strings.NewReader("not json"))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		authenticator.Middleware(http.HandlerFunc(h.HandleWrite)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Admins only by default, whatever the user's profile
+	rec := write(&config.Config{})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "changing memory requires the admin role")
+
+	// The operator can let debuggers write; the request is then looked at
+	rec = write(&config.Config{GDB: config.GDBConfig{Memory: config.MemoryConfig{WriteRole: auth.RoleDebugger}}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
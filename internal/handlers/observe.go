@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// ObserveRequest asks to sample the backtraces of a running process
+type ObserveRequest struct {
+	PID      int     `json:"pid"`
+	Duration float64 `json:"duration"` // Seconds
+	Interval float64 `json:"interval"` // Seconds between samples
+}
+
+// Observe samples a running process, after checking that observe mode is enabled and the
+// process may be observed. Errors wrap ErrUnsupported when observe mode is
+// disabled, ErrForbidden when the process may not be observed, and ErrBadRequest for
+// invalid options.
+func (h *GDBHandler) Observe(ctx context.Context, opts gdb.ObserveOptions) (*gdb.ObserveReport, error) {
+	if !h.observeCfg.Enabled {
+		return nil, fmt.Errorf("%w: observe mode is disabled (gdb.observe.enabled)", appErrors.ErrUnsupported)
+	}
+	if opts.PID <= 0 {
+		return nil, fmt.Errorf("%w: pid must be positive", appErrors.ErrBadRequest)
+	}
+	if h.observeCfg.MaxDuration > 0 && opts.Duration > h.observeCfg.MaxDuration {
+		return nil, fmt.Errorf("%w: duration exceeds the limit of %s", appErrors.ErrBadRequest, h.observeCfg.MaxDuration)
+	}
+	if opts.Interval < h.observeCfg.MinInterval {
+		return nil, fmt.Errorf("%w: interval is below the minimum of %s", appErrors.ErrBadRequest, h.observeCfg.MinInterval)
+	}
+
+	executable, err := h.observableExecutable(opts.PID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := gdb.Observe(ctx, h.sampler, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.Executable = executable
+	return report, nil
+}
+
+// observableExecutable returns the executable name of a process that may be observed.
+// The server itself is never observable, since its memory holds API keys and sessions.
+func (h *GDBHandler) observableExecutable(pid int) (string, error) {
+	if pid == os.Getpid() {
+		return "", fmt.Errorf("%w: the server cannot observe itself", appErrors.ErrForbidden)
+	}
+
+	exe, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "exe"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: no process %d", appErrors.ErrNotFound, pid)
+		}
+		// Without /proc (e.g. macOS) only an allowlist-free configuration can observe
+		if len(h.observeCfg.AllowedExecutables) > 0 {
+			return "", fmt.Errorf("%w: cannot determine the executable of process %d", appErrors.ErrForbidden, pid)
+		}
+		return "", nil
+	}
+
+	name := filepath.Base(exe)
+	if len(h.observeCfg.AllowedExecutables) == 0 {
+		return name, nil
+	}
+	for _, allowed := range h.observeCfg.AllowedExecutables {
+		if allowed == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not in gdb.observe.allowed_executables", appErrors.ErrForbidden, name)
+}
+
+// HandleObserve samples a running process's backtraces for a few seconds and returns a
+// report of where it spent its time, e.g. POST /api/gdb/observe {"pid": 1234, "duration": 10}
+func (h *GDBHandler) HandleObserve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ObserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+
+	report, err := h.Observe(r.Context(), gdb.NewObserveOptions(req.PID, req.Duration, req.Interval))
+	if err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"report":  report,
+			"summary": report.String(),
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"bytes"
+	"html"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// rootLink matches root-relative links in a page, but not protocol-relative ones
+var rootLink = regexp.MustCompile(`\b(href|src|action)="/([^/])`)
+
+// PageHandler serves the page at path in the UI's files. Behind a proxy under a base path
+// (server.base_path) the page's links to the server are moved under it, and the base path
+// is given to the scripts in the html element's data-base-path attribute, for the requests
+// they make. The page is read on each request, so edits show when files are on disk.
+func PageHandler(files fs.FS, path, basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := fs.ReadFile(files, path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if basePath != "" {
+			page = withBasePath(page, basePath)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, path, time.Time{}, bytes.NewReader(page))
+	}
+}
+
+// withBasePath moves a page's root-relative links under basePath and records it on the
+// html element
+func withBasePath(page []byte, basePath string) []byte {
+	prefix := bytes.ReplaceAll([]byte(basePath), []byte("$"), []byte("$$"))
+	page = rootLink.ReplaceAll(page, append(append([]byte(`$1="`), prefix...), []byte(`/$2`)...))
+	return bytes.Replace(page, []byte("<html"), []byte(`<html data-base-path="`+html.EscapeString(basePath)+`"`), 1)
+}
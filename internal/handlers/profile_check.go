@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fmt"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// checkProfile fails with errors.ErrForbidden unless the user's prompt profile allows
+// command, so what the UI does on the user's behalf stays within what the assistant may
+// do for them. action names what is refused, e.g. "changing memory".
+func checkProfile(settingsManager *settings.Manager, promptEngine *prompts.Engine, user, command, action string) error {
+	profile, err := promptEngine.Profile(settingsManager.Effective(user).Profile.Value)
+	if err != nil {
+		return err
+	}
+	if !profile.Allows(command) {
+		return fmt.Errorf("%w: the %s profile does not allow %s", appErrors.ErrForbidden, profile.Name, action)
+	}
+	return nil
+}
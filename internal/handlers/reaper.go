@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/binstore"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// sessionActivity tracks when the current session was last used, so the reaper can end
+// sessions nobody uses any more
+type sessionActivity struct {
+	session string // Session the time belongs to
+	last    time.Time
+	mutex   sync.Mutex
+
+	reaped       atomic.Uint64 // Sessions ended for being idle since the server started
+	filesRemoved atomic.Uint64 // Uploads and temporary files the reaper deleted
+}
+
+// touch records that the current session, sessionID, is in use
+func (a *sessionActivity) touch(sessionID string, now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.session, a.last = sessionID, now
+}
+
+// idleSince returns when sessionID was last used. A session seen for the first time is
+// used now, so sessions started without going through the GDB handler are timed too.
+func (a *sessionActivity) idleSince(sessionID string, now time.Time) time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.session != sessionID {
+		a.session, a.last = sessionID, now
+	}
+	return a.last
+}
+
+// SessionMetrics describes the current session and the sessions the reaper ended
+type SessionMetrics struct {
+	Active       int        `json:"active"` // 1 while there is a session
+	Session      string     `json:"session,omitempty"`
+	GDBRunning   bool       `json:"gdbRunning"`
+	LastActivity *time.Time `json:"lastActivity,omitempty"`
+	IdleTTL      string     `json:"idleTTL"` // "0s" when idle sessions are kept
+	Reaped       uint64     `json:"reaped"`
+	FilesRemoved uint64     `json:"filesRemoved"`
+}
+
+// touchSession records that the current session is in use
+func (h *GDBHandler) touchSession() {
+	if logger := h.loggerHolder.Get(); logger != nil {
+		h.activity.touch(logger.SessionID(), time.Now())
+	}
+}
+
+// SessionMetrics returns the state of the current session and the reaper's counts
+func (h *GDBHandler) SessionMetrics() SessionMetrics {
+	metrics := SessionMetrics{
+		GDBRunning:   h.IsRunning(),
+		IdleTTL:      h.sessionsCfg.IdleTTL.String(),
+		Reaped:       h.activity.reaped.Load(),
+		FilesRemoved: h.activity.filesRemoved.Load(),
+	}
+	if logger := h.loggerHolder.Get(); logger != nil {
+		last := h.activity.idleSince(logger.SessionID(), time.Now())
+		metrics.Active = 1
+		metrics.Session = logger.SessionID()
+		metrics.LastActivity = &last
+	}
+	return metrics
+}
+
+// HandleSessionMetrics returns the session metrics, e.g. GET /api/sessions/metrics
+func (h *GDBHandler) HandleSessionMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: h.SessionMetrics()})
+}
+
+// StartReaper ends the current session once it has been idle for sessions.idle_ttl,
+// checking every sessions.reap_interval until ctx is done. It does nothing when idle_ttl
+// is 0.
+func (h *GDBHandler) StartReaper(ctx context.Context) {
+	if h.sessionsCfg.IdleTTL <= 0 {
+		return
+	}
+
+	interval := h.sessionsCfg.ReapInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				h.reapIdle(now)
+			}
+		}
+	}()
+}
+
+// reapIdle ends the current session if it has been idle for longer than the TTL: its log
+// is closed, GDB is stopped, the session's clients are told and its executable, sources
+// and terminal output are deleted. Closing the log ends the session for the stores
+// subscribed through OnSessionEnd, which forget its feature assignments, branches, costs
+// and attachments. The log is closed with CompareAndClear before anything else, so a
+// session started since the reaper looked is left running. Upload temporary files left
+// behind by failed uploads are deleted as well. It reports whether a session was ended.
+func (h *GDBHandler) reapIdle(now time.Time) bool {
+	ttl := h.sessionsCfg.IdleTTL
+	h.removeStaleUploads(now.Add(-ttl))
+
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return false
+	}
+	sessionID := logger.SessionID()
+	idle := now.Sub(h.activity.idleSince(sessionID, now))
+	if idle < ttl {
+		return false
+	}
+
+	logger.LogEvent("INFO", "session.reaped", "Session ended after being idle", map[string]interface{}{
+		"session.idle": idle.String(),
+	})
+	// Closes the session's log, unless another session has replaced it
+	if !h.loggerHolder.CompareAndClear(logger) {
+		return false
+	}
+	h.gdbService.StopGDB()
+	h.hub.BroadcastSessionStatus(sessionID, websocket.StatusPayload{GDB: "exited", Reason: "idle for " + idle.Round(time.Second).String()})
+
+	// The upload's executable, or a lab's copy of its target
+	if filename, ok := logger.Metadata("session.filename").(string); ok && filename != "" {
+		h.removeUpload(filepath.Join(userUploadsDir(h.uploadsDir, logger.Owner()), sanitizeFilename(filename)))
+	}
+	h.removeUpload(sourcesDirFor(h.uploadsDir, sessionID))
+
+	h.outputMutex.Lock()
+	delete(h.outputs, sessionID)
+	h.outputMutex.Unlock()
+	h.hooksMutex.Lock()
+	if h.hooks != nil && h.hooks.session == sessionID {
+		h.hooks = nil
+	}
+	h.hooksMutex.Unlock()
+	h.scriptsMutex.Lock()
+	if h.scripts != nil && h.scripts.session == sessionID {
+		h.scripts = nil
+	}
+	h.scriptsMutex.Unlock()
+
+	h.activity.reaped.Add(1)
+	log.Printf("Ended session %s after %s idle", sessionID, idle.Round(time.Second))
+	return true
+}
+
+// removeStaleUploads deletes the temporary files of uploads that were interrupted before
+// cutoff. Uploads are written to ".upload-*" files in the user's directory first.
+func (h *GDBHandler) removeStaleUploads(cutoff time.Time) {
+	filepath.WalkDir(h.uploadsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == sourcesDirFor(h.uploadsDir, "") || path == binstore.Dir(h.uploadsDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			h.removeUpload(path)
+		}
+		return nil
+	})
+}
+
+// removeUpload deletes a file or directory of the uploads directory, counting it
+func (h *GDBHandler) removeUpload(path string) {
+	if _, err := os.Lstat(path); err != nil {
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		log.Printf("Error removing %s: %v", path, err)
+		return
+	}
+	h.activity.filesRemoved.Add(1)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+func TestReapIdleSession(t *testing.T) {
+	uploadsDir := "uploads"
+	cfg := &config.Config{
+		Uploads:  config.UploadsConfig{Directory: uploadsDir},
+		Sessions: config.SessionsConfig{IdleTTL: time.Hour},
+	}
+	h, holder := newTestGDBHandler(t, cfg)
+	var ended []string
+	holder.OnSessionEnd(func(sessionID string) { ended = append(ended, sessionID) })
+
+	executable := filepath.Join(userUploadsDir(uploadsDir, "alice"), "crash")
+	sources := filepath.Join(sourcesDirFor(uploadsDir, "s1"), "crash.c")
+	interrupted := filepath.Join(userUploadsDir(uploadsDir, "bob"), ".upload-123")
+	for _, path := range []string{executable, sources, interrupted} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+	}
+	start := time.Now()
+	require.NoError(t, os.Chtimes(interrupted, start.Add(-2*time.Hour), start.Add(-2*time.Hour)))
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	logger.SetOwner("alice")
+	logger.LogSessionMetadata(map[string]interface{}{"session.filename": "crash"})
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	// The session's idle time starts when the reaper first sees it
+	assert.False(t, h.reapIdle(start))
+	assert.NoFileExists(t, interrupted)
+	assert.FileExists(t, executable)
+
+	// Using the session keeps it
+	require.NoError(t, h.AuthorizeSession("alice"))
+	assert.False(t, h.reapIdle(time.Now().Add(59*time.Minute)))
+	assert.Equal(t, 1, h.SessionMetrics().Active)
+
+	assert.True(t, h.reapIdle(time.Now().Add(61*time.Minute)))
+	assert.Nil(t, holder.Get())
+	assert.Equal(t, []string{"s1"}, ended, "the session's stores are told it ended")
+	assert.NoFileExists(t, executable)
+	assert.NoDirExists(t, filepath.Dir(sources))
+
+	metrics := h.SessionMetrics()
+	assert.Equal(t, 0, metrics.Active)
+	assert.Equal(t, uint64(1), metrics.Reaped)
+	assert.Equal(t, uint64(3), metrics.FilesRemoved)
+	assert.Equal(t, "1h0m0s", metrics.IdleTTL)
+}
+
+// replacingHolder starts next as the reaper looks at the current session, the way an
+// upload claiming the server would between the reaper's checks
+type replacingHolder struct {
+	*logsession.LoggerHolderImpl
+	next *logsession.SessionLogger
+}
+
+func (r *replacingHolder) Get() *logsession.SessionLogger {
+	current := r.LoggerHolderImpl.Get()
+	if r.next != nil {
+		r.LoggerHolderImpl.Set(r.next)
+		r.next = nil
+	}
+	return current
+}
+
+func TestReapIdleKeepsReplacingSession(t *testing.T) {
+	cfg := &config.Config{
+		Uploads:  config.UploadsConfig{Directory: "uploads"},
+		Sessions: config.SessionsConfig{IdleTTL: time.Hour},
+	}
+	h, holder := newTestGDBHandler(t, cfg)
+	idle, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	holder.Set(idle)
+	t.Cleanup(func() { holder.Set(nil) })
+	start := time.Now()
+	assert.False(t, h.reapIdle(start))
+
+	next, err := logsession.NewSessionLogger("s2")
+	require.NoError(t, err)
+	h.loggerHolder = &replacingHolder{LoggerHolderImpl: holder, next: next}
+	assert.False(t, h.reapIdle(start.Add(61*time.Minute)))
+	assert.Same(t, next, holder.Get(), "the new session is not ended with the idle one")
+	assert.Equal(t, uint64(0), h.SessionMetrics().Reaped)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// RunHandler lets the program run until an event. Running the program is denied by some
+// prompt profiles, so the user's profile must allow the command, as it must for the
+// assistant.
+type RunHandler struct {
+	gdbHandler *GDBHandler
+	settings   *settings.Manager
+	prompts    *prompts.Engine
+}
+
+// NewRunHandler creates a new run handler
+func NewRunHandler(gdbHandler *GDBHandler, settingsManager *settings.Manager, promptEngine *prompts.Engine) *RunHandler {
+	return &RunHandler{gdbHandler: gdbHandler, settings: settingsManager, prompts: promptEngine}
+}
+
+// RunUntilRequest is the body of a run-until request
+type RunUntilRequest struct {
+	Event   string  `json:"event"`
+	Timeout float64 `json:"timeout"` // Seconds
+	Command string  `json:"command"`
+}
+
+// HandleRunUntil continues the program until the event or the timeout and returns why it
+// stopped, e.g. POST /api/v1/debugger/run-until with {"event": "signal", "timeout": 5}
+func (h *RunHandler) HandleRunUntil(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var req RunUntilRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Timeout < 0 {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+		return
+	}
+	if req.Command == "" {
+		req.Command = "continue"
+	}
+	if err := checkProfile(h.settings, h.prompts, user, req.Command, "running the program"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	stop, err := h.gdbHandler.RunUserUntil(user, gdb.RunUntilOptions{
+		Event:   req.Event,
+		Timeout: time.Duration(req.Timeout * float64(time.Second)),
+		Command: req.Command,
+	})
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"stop":    stop,
+		"summary": stop.String(),
+	}})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// ScriptHandler lists, uploads, runs and deletes GDB Python scripts. A script can run any
+// GDB command, so the user's prompt profile must allow GDB's python command to run one.
+type ScriptHandler struct {
+	gdbHandler *GDBHandler
+	settings   *settings.Manager
+	prompts    *prompts.Engine
+}
+
+// NewScriptHandler creates a new script handler
+func NewScriptHandler(gdbHandler *GDBHandler, settingsManager *settings.Manager, promptEngine *prompts.Engine) *ScriptHandler {
+	return &ScriptHandler{gdbHandler: gdbHandler, settings: settingsManager, prompts: promptEngine}
+}
+
+// ScriptUploadRequest is the body of a request uploading a script
+type ScriptUploadRequest struct {
+	Name   string `json:"name"`   // Letters, digits, '-' and '_', with or without .py
+	Source string `json:"source"` // Python for GDB; the first line of its docstring describes it
+}
+
+// ScriptRunRequest is the body of a request running a script
+type ScriptRunRequest struct {
+	Args []string `json:"args,omitempty"` // The script's gogdbllm_args
+}
+
+// HandleList lists the library's scripts, those uploaded to the session and the session's
+// recent runs, e.g. GET /api/v1/debugger/scripts
+func (h *ScriptHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	scripts, runs, err := h.gdbHandler.Scripts(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"scripts": scripts,
+		"runs":    runs,
+	}})
+}
+
+// HandleUpload uploads a script to the session, e.g. POST /api/v1/debugger/scripts with
+// {"name": "heap", "source": "import gdb\n..."}
+func (h *ScriptHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var req ScriptUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+		return
+	}
+
+	script, err := h.gdbHandler.UploadScript(user, req.Name, req.Source)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: script})
+}
+
+// HandleRun runs a script in GDB, e.g. POST /api/v1/debugger/scripts/hexdump/run with
+// {"args": ["$rsp", "32"]}. A script that raised is reported in the run's error.
+func (h *ScriptHandler) HandleRun(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var req ScriptRunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+			return
+		}
+	}
+	if err := checkProfile(h.settings, h.prompts, user, "python", "Python scripts"); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+
+	run, err := h.gdbHandler.RunScript(user, mux.Vars(r)["name"], req.Args)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: run})
+}
+
+// HandleDelete removes a script uploaded to the session, e.g.
+// DELETE /api/v1/debugger/scripts/heap
+func (h *ScriptHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	name := mux.Vars(r)["name"]
+	if err := h.gdbHandler.DeleteScript(user, name); err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{"name": name}})
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yourusername/gogdbllm/internal/audit"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdbpy"
+)
+
+const (
+	// scriptContextRuns is how many recent script runs ScriptResults describes
+	scriptContextRuns = 5
+
+	// maxScriptContextOutput limits the output of each run ScriptResults describes; the
+	// end is kept
+	maxScriptContextOutput = 2000
+)
+
+var (
+	// scriptNamePattern matches the name of an uploaded script, without .py
+	scriptNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+	// errScriptsDisabled is returned when scripts are used while gdb.scripts.enabled is off
+	errScriptsDisabled = fmt.Errorf("%w: Python scripts are disabled on this server", appErrors.ErrForbidden)
+)
+
+// ScriptRun is a run of a GDB Python script, with what it printed or why it failed
+type ScriptRun struct {
+	Script string    `json:"script"`
+	Args   []string  `json:"args,omitempty"`
+	User   string    `json:"user,omitempty"`
+	Time   time.Time `json:"time"`
+	Output string    `json:"output,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// sessionScripts are the scripts uploaded to a session and its recent script runs
+type sessionScripts struct {
+	session string
+	scripts map[string]gdbpy.Script // By name
+	runs    []ScriptRun             // Oldest first
+}
+
+// ScriptCommand returns how a run of a script is recorded in the audit trail and the
+// session log, e.g. "script hexdump buf 32"
+func ScriptCommand(name string, args []string) string {
+	return strings.Join(append([]string{"script", name}, args...), " ")
+}
+
+// UploadScript adds a Python script to the current session for user, who must own or join
+// it, replacing the session's script of the same name. Library scripts cannot be replaced.
+func (h *GDBHandler) UploadScript(user, name, source string) (gdbpy.Script, error) {
+	if !h.scriptsCfg.Enabled {
+		return gdbpy.Script{}, errScriptsDisabled
+	}
+	if err := h.AuthorizeSession(user); err != nil {
+		return gdbpy.Script{}, err
+	}
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return gdbpy.Script{}, errNoSession
+	}
+	name = strings.TrimSuffix(name, ".py")
+	if !scriptNamePattern.MatchString(name) {
+		return gdbpy.Script{}, fmt.Errorf("%w: a script's name has 1 to 64 letters, digits, '-' or '_'", appErrors.ErrBadRequest)
+	}
+	if _, ok := gdbpy.Lookup(name); ok {
+		return gdbpy.Script{}, fmt.Errorf("%w: %s is a library script", appErrors.ErrBadRequest, name)
+	}
+	if strings.TrimSpace(source) == "" || len(source) > h.scriptsCfg.MaxSize || !utf8.ValidString(source) {
+		return gdbpy.Script{}, fmt.Errorf("%w: a script is UTF-8 text of 1 to %d bytes", appErrors.ErrBadRequest, h.scriptsCfg.MaxSize)
+	}
+
+	h.scriptsMutex.Lock()
+	defer h.scriptsMutex.Unlock()
+	current := h.currentScriptsLocked(true)
+	if _, ok := current.scripts[name]; !ok && len(current.scripts) >= h.scriptsCfg.MaxScripts {
+		return gdbpy.Script{}, fmt.Errorf("%w: at most %d scripts may be uploaded to a session", appErrors.ErrBadRequest, h.scriptsCfg.MaxScripts)
+	}
+	script := gdbpy.Script{Name: name, Description: gdbpy.Describe(source), Source: source}
+	current.scripts[name] = script
+	logger.ForUser(user).LogEvent("INFO", "script.upload", "Uploaded a GDB Python script", map[string]interface{}{
+		"script.name": name,
+		"script.size": len(source),
+	})
+	return script, nil
+}
+
+// DeleteScript removes a script uploaded to the current session for user, who must own or
+// join it
+func (h *GDBHandler) DeleteScript(user, name string) error {
+	if !h.scriptsCfg.Enabled {
+		return errScriptsDisabled
+	}
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	name = strings.TrimSuffix(name, ".py")
+	h.scriptsMutex.Lock()
+	defer h.scriptsMutex.Unlock()
+	current := h.currentScriptsLocked(false)
+	if current == nil {
+		return fmt.Errorf("%w: the session has no script %s", appErrors.ErrNotFound, name)
+	}
+	if _, ok := current.scripts[name]; !ok {
+		return fmt.Errorf("%w: the session has no script %s", appErrors.ErrNotFound, name)
+	}
+	delete(current.scripts, name)
+	return nil
+}
+
+// Scripts returns the library's scripts and those uploaded to the current session, by
+// name, and the session's recent script runs, provided user owns or joined it
+func (h *GDBHandler) Scripts(user string) ([]gdbpy.Script, []ScriptRun, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, nil, err
+	}
+	scripts := gdbpy.Library()
+	h.scriptsMutex.Lock()
+	defer h.scriptsMutex.Unlock()
+	current := h.currentScriptsLocked(false)
+	if current == nil {
+		return scripts, []ScriptRun{}, nil
+	}
+	for _, script := range current.scripts {
+		scripts = append(scripts, script)
+	}
+	sort.SliceStable(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts, append([]ScriptRun{}, current.runs...), nil
+}
+
+// RunScript runs a library script, or one uploaded to the current session, in GDB with
+// args for user, who must own or join the session. The run is kept for GET
+// /api/v1/debugger/scripts and chat context even when the script failed.
+func (h *GDBHandler) RunScript(user, name string, args []string) (*ScriptRun, error) {
+	if !h.scriptsCfg.Enabled {
+		return nil, errScriptsDisabled
+	}
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSuffix(name, ".py")
+	script, ok := gdbpy.Lookup(name)
+	if !ok {
+		h.scriptsMutex.Lock()
+		if current := h.currentScriptsLocked(false); current != nil {
+			script, ok = current.scripts[name]
+		}
+		h.scriptsMutex.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: there is no script %s", appErrors.ErrNotFound, name)
+	}
+
+	logger := h.loggerHolder.Get().ForUser(user)
+	command := ScriptCommand(name, args)
+	output, err := h.gdbService.RunPython(name, script.Source, args)
+	h.AuditCommand(audit.ActorUser, user, "", command, err)
+	if logger != nil {
+		logger.LogGDBCommand(command, "user")
+	}
+	run := ScriptRun{Script: name, Args: args, User: user, Time: time.Now().UTC(), Output: output}
+	if err != nil {
+		if output == "" {
+			// GDB did not run the script at all
+			return nil, err
+		}
+		run.Error = err.Error()
+	}
+	if logger != nil {
+		h.recordScriptRun(logger.SessionID(), run)
+	}
+	return &run, nil
+}
+
+// ScriptResults describes the current session's recent script runs with their output, for
+// the assistant's context
+func (h *GDBHandler) ScriptResults() (string, error) {
+	h.scriptsMutex.Lock()
+	current := h.currentScriptsLocked(false)
+	var runs []ScriptRun
+	if current != nil {
+		runs = append(runs, current.runs[max(0, len(current.runs)-scriptContextRuns):]...)
+	}
+	h.scriptsMutex.Unlock()
+	if len(runs) == 0 {
+		return "", fmt.Errorf("%w: no script ran in the session", appErrors.ErrNotFound)
+	}
+
+	var sb strings.Builder
+	for _, run := range runs {
+		fmt.Fprintf(&sb, "$ %s (%s)\n", ScriptCommand(run.Script, run.Args), run.Time.Format(time.RFC3339))
+		output := strings.TrimSpace(run.Output)
+		if len(output) > maxScriptContextOutput {
+			output = "..." + strings.ToValidUTF8(output[len(output)-maxScriptContextOutput:], "")
+		}
+		if output != "" {
+			sb.WriteString(output + "\n")
+		}
+		if run.Error != "" {
+			sb.WriteString("Failed: " + run.Error + "\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// currentScriptsLocked returns the scripts of the current session, created if create is
+// set, or nil when it has none. The caller holds scriptsMutex.
+func (h *GDBHandler) currentScriptsLocked(create bool) *sessionScripts {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return nil
+	}
+	if h.scripts == nil || h.scripts.session != logger.SessionID() {
+		if !create {
+			return nil
+		}
+		h.scripts = &sessionScripts{session: logger.SessionID(), scripts: make(map[string]gdbpy.Script)}
+	}
+	return h.scripts
+}
+
+// recordScriptRun keeps a run of a script in a session, dropping the oldest beyond
+// gdb.scripts.keep_results
+func (h *GDBHandler) recordScriptRun(session string, run ScriptRun) {
+	h.scriptsMutex.Lock()
+	defer h.scriptsMutex.Unlock()
+	if h.scripts == nil || h.scripts.session != session {
+		h.scripts = &sessionScripts{session: session, scripts: make(map[string]gdbpy.Script)}
+	}
+	h.scripts.runs = append(h.scripts.runs, run)
+	if excess := len(h.scripts.runs) - h.scriptsCfg.KeepResults; excess > 0 {
+		h.scripts.runs = append([]ScriptRun{}, h.scripts.runs[excess:]...)
+	}
+}
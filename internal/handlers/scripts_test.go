@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+func TestSessionScripts(t *testing.T) {
+	cfg := &config.Config{GDB: config.GDBConfig{Scripts: config.ScriptsConfig{Enabled: true, MaxSize: 64, MaxScripts: 1, KeepResults: 2}}}
+	h, holder := newTestGDBHandler(t, cfg)
+
+	_, err := h.UploadScript("alice", "heap", "import gdb")
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest, "there is no session yet")
+	scripts, runs, err := h.Scripts("alice")
+	require.NoError(t, err)
+	assert.NotEmpty(t, scripts, "the library is listed without a session")
+	assert.Empty(t, runs)
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	logger.SetOwner("alice")
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	for name, upload := range map[string][2]string{
+		"bad name":      {"../heap", "import gdb"},
+		"library name":  {"hexdump.py", "import gdb"},
+		"empty":         {"heap", " \n"},
+		"too large":     {"heap", strings.Repeat("#", 65)},
+		"invalid UTF-8": {"heap", "# \xff"},
+	} {
+		_, err := h.UploadScript("alice", upload[0], upload[1])
+		assert.ErrorIs(t, err, appErrors.ErrBadRequest, name)
+	}
+	_, err = h.UploadScript("bob", "heap", "import gdb")
+	assert.ErrorIs(t, err, appErrors.ErrForbidden, "only members upload scripts")
+
+	script, err := h.UploadScript("alice", "heap.py", "\"\"\"Print the heap\"\"\"\nimport gdb\n")
+	require.NoError(t, err)
+	assert.Equal(t, "heap", script.Name)
+	assert.Equal(t, "Print the heap", script.Description)
+	_, err = h.UploadScript("alice", "arenas", "import gdb")
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest, "max_scripts")
+	_, err = h.UploadScript("alice", "heap", "import gdb")
+	assert.NoError(t, err, "a script is replaced under its name")
+
+	scripts, _, err = h.Scripts("alice")
+	require.NoError(t, err)
+	var names []string
+	for _, script := range scripts {
+		names = append(names, script.Name)
+	}
+	assert.Contains(t, names, "heap")
+	assert.Contains(t, names, "hexdump")
+
+	_, err = h.RunScript("alice", "missing", nil)
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	_, err = h.RunScript("alice", "hexdump", []string{"$sp"})
+	assert.ErrorIs(t, err, appErrors.ErrGDBNotRunning)
+
+	// Runs are kept up to keep_results and attached to chat requests
+	_, err = h.ScriptResults()
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	for i, run := range []ScriptRun{
+		{Script: "vmmap"},
+		{Script: "hexdump", Args: []string{"$sp", "16"}, Output: "0x00007fffffffe000  01 02  ..\n"},
+		{Script: "heap", Output: "Traceback\n", Error: "GDB command failed: Error while executing Python code."},
+	} {
+		run.Time = time.Date(2026, 1, 1, 12, 0, i, 0, time.UTC)
+		h.recordScriptRun("s1", run)
+	}
+	_, runs, err = h.Scripts("alice")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "hexdump", runs[0].Script)
+	results, err := h.ScriptResults()
+	require.NoError(t, err)
+	assert.Contains(t, results, "$ script hexdump $sp 16 (2026-01-01T12:00:01Z)\n0x00007fffffffe000  01 02  ..\n")
+	assert.Contains(t, results, "Failed: GDB command failed")
+
+	require.NoError(t, h.DeleteScript("alice", "heap"))
+	assert.ErrorIs(t, h.DeleteScript("alice", "heap"), appErrors.ErrNotFound)
+	assert.ErrorIs(t, h.DeleteScript("alice", "hexdump"), appErrors.ErrNotFound, "library scripts stay")
+
+	// Another session has no scripts or runs
+	other, err := logsession.NewSessionLogger("s2")
+	require.NoError(t, err)
+	other.SetOwner("alice")
+	holder.Set(other)
+	_, runs, err = h.Scripts("alice")
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	h.scriptsCfg.Enabled = false
+	_, err = h.RunScript("alice", "hexdump", nil)
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+}
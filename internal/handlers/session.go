@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+)
+
+// newSessionID builds the ID of a debugging session from its start time and executable name
+func newSessionID(startTime time.Time, filename string) string {
+	return fmt.Sprintf("%s_%s", startTime.Format("20060102_150405"), filename)
+}
+
+// userUploadsDir returns the directory holding a user's uploads and compiled programs, so
+// users cannot overwrite or start each other's executables. Without authentication all
+// uploads share the uploads directory.
+func userUploadsDir(uploadsDir, user string) string {
+	if user == "" {
+		return uploadsDir
+	}
+	return filepath.Join(uploadsDir, "users", sanitizeFilename(user))
+}
+
+// newSessionToken returns a random token for subscribing to a session's output
+func newSessionToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// startLogSession creates the session logger owned by owner, records the session's metadata
+// and feature assignments, and makes it the current logger (which closes the previous one).
+// It returns the token WebSocket clients present to receive the session's output.
+func startLogSession(holder LoggerHolder, featureManager *features.Manager, sessionID, owner string, metadata map[string]interface{}) (string, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return "", err
+	}
+	newLogger, err := logsession.NewSessionLogger(sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session logger for %s: %w", sessionID, err)
+	}
+	newLogger.SetOwner(owner)
+	newLogger.SetToken(token)
+	if owner != "" {
+		metadata["session.owner"] = owner
+	}
+
+	// Record the flags this session runs with so behaviour differences are traceable
+	metadata["session.features"] = featureManager.ForSession(sessionID)
+	newLogger.LogSessionMetadata(metadata)
+
+	holder.Set(newLogger)
+	log.Printf("Started new log session: %s", sessionID)
+	return token, nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// errOwnerCannotLeave is returned when the owner of a session tries to leave it
+var errOwnerCannotLeave = fmt.Errorf("%w: the owner cannot leave the debugging session", appErrors.ErrBadRequest)
+
+// JoinSessionRequest is the body of a request to join the current session
+type JoinSessionRequest struct {
+	Token string `json:"token"` // The session's subscription token, shared by its owner
+}
+
+// JoinSession lets user join the current session if token is its subscription token.
+// Members share the session's terminal and chat with its owner: they run commands, see
+// the output and each other's chat turns, but cannot stop the session or start another
+// in its place. It returns the ID of the session joined.
+func (h *GDBHandler) JoinSession(user, token string) (string, error) {
+	logger := h.loggerHolder.Get()
+	if !validSessionToken(logger, token) {
+		return "", errInvalidSessionToken
+	}
+	logger.ForUser(user).AddMember(user)
+	h.activity.touch(logger.SessionID(), time.Now())
+	return logger.SessionID(), nil
+}
+
+// LeaveSession ends user's membership of the current session. Their clients already
+// connected keep receiving its output until they disconnect.
+func (h *GDBHandler) LeaveSession(user string) error {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return nil
+	}
+	if logger.Owner() == user {
+		return errOwnerCannotLeave
+	}
+	logger.ForUser(user).RemoveMember(user)
+	return nil
+}
+
+// ShareChat sends a chat turn of user to every client of the current session, so the
+// session's other members follow the conversation
+func (h *GDBHandler) ShareChat(user, requestID, message, response string) {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return
+	}
+	h.hub.BroadcastSessionChat(logger.SessionID(), websocket.ChatPayload{
+		RequestID: requestID,
+		User:      user,
+		Message:   message,
+		Response:  response,
+	})
+}
+
+// HandleJoinSession joins the current session with its token, e.g.
+// POST /api/sessions/join {"token": "..."}; the client then connects to /ws?session=token
+func (h *GDBHandler) HandleJoinSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req JoinSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "", "Request body must be {\"token\": \"...\"}")
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	sessionID, err := h.JoinSession(user, req.Token)
+	if err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"session": sessionID,
+	}})
+}
+
+// HandleLeaveSession ends the user's membership of the current session, e.g.
+// POST /api/sessions/leave
+func (h *GDBHandler) HandleLeaveSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.LeaveSession(user); err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true})
+}
+
+// HandleSessionMembers returns the members of the current session, its owner first, and
+// which of them have a client connected, e.g. GET /api/sessions/members
+func (h *GDBHandler) HandleSessionMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.AuthorizeSession(user); err != nil {
+		writeError(w, http.StatusForbidden, "", err.Error())
+		return
+	}
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+			"members": []string{},
+			"online":  []string{},
+		}})
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"session": logger.SessionID(),
+		"owner":   logger.Owner(),
+		"members": logger.Members(),
+		"online":  h.hub.SessionUsers(logger.SessionID()),
+	}})
+}
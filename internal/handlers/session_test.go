@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logsession"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// newTestGDBHandler creates a GDB handler for cfg with its hub running, in a temporary
+// working directory
+func newTestGDBHandler(t *testing.T, cfg *config.Config) (*GDBHandler, *logsession.LoggerHolderImpl) {
+	// Session logs are written relative to the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	holder := logsession.NewLoggerHolder()
+	hub := websocket.NewHub(cfg)
+	go hub.Run()
+	return NewGDBHandler(hub, holder, cfg), holder
+}
+
+func TestSessionOwnership(t *testing.T) {
+	holder := logsession.NewLoggerHolder()
+	h := NewGDBHandler(websocket.NewHub(&config.Config{}), holder, &config.Config{Uploads: config.UploadsConfig{Directory: t.TempDir()}})
+
+	// Anyone may act when there is no session
+	assert.NoError(t, h.AuthorizeSession("bob"))
+
+	logger := &logsession.SessionLogger{}
+	logger.SetOwner("alice")
+	holder.Set(logger)
+
+	assert.NoError(t, h.AuthorizeSession("alice"))
+	assert.ErrorIs(t, h.AuthorizeSession("bob"), appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.HandleUserCommand("bob", "info registers"), appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.StartSession("bob", "a.out"), appErrors.ErrForbidden)
+
+	// A session whose GDB has exited can be replaced by another user
+	assert.NoError(t, h.ClaimSession("bob"))
+
+	// Subscribing to the session's output needs its token and ownership
+	logger.SetToken("secret")
+	_, err := h.SubscribeSession("alice", "guess")
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+	_, err = h.SubscribeSession("bob", "secret")
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+	_, err = h.SubscribeSession("alice", "secret")
+	assert.NoError(t, err)
+}
+
+func TestSessionMembers(t *testing.T) {
+	cfg := &config.Config{Uploads: config.UploadsConfig{Directory: "uploads"}}
+	h, holder := newTestGDBHandler(t, cfg)
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	logger.SetOwner("alice")
+	logger.SetToken("secret")
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	// Joining needs the session's token
+	_, err = h.JoinSession("bob", "guess")
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.AuthorizeSession("bob"), appErrors.ErrForbidden)
+
+	sessionID, err := h.JoinSession("bob", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "s1", sessionID)
+	assert.Equal(t, []string{"alice", "bob"}, logger.Members())
+
+	// Members share the session but only its owner may stop or replace it
+	assert.NoError(t, h.AuthorizeSession("bob"))
+	_, err = h.SubscribeSession("bob", "secret")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, h.StopSession("bob"), appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.StartSession("bob", "a.out"), appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.authorizeOwner("bob"), appErrors.ErrForbidden)
+	assert.NoError(t, h.authorizeOwner("alice"))
+
+	// Without authentication the empty user is no member of alice's session
+	w := httptest.NewRecorder()
+	h.HandleSessionMembers(w, httptest.NewRequest(http.MethodGet, "/api/sessions/members", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The log tells who did what: here bob's input fails without GDB
+	assert.Error(t, h.HandleProgramInput("bob", "42\n"))
+
+	assert.ErrorIs(t, h.LeaveSession("alice"), appErrors.ErrBadRequest)
+	require.NoError(t, h.LeaveSession("bob"))
+	assert.ErrorIs(t, h.AuthorizeSession("bob"), appErrors.ErrForbidden)
+
+	data, err := os.ReadFile(logsession.LogFilePath("s1"))
+	require.NoError(t, err)
+	entries := string(data)
+	assert.Contains(t, entries, `"session.member.action":"join"`)
+	assert.Contains(t, entries, `"session.member.action":"leave"`)
+	for _, line := range strings.Split(entries, "\n") {
+		if strings.Contains(line, "Sending input to the program") {
+			assert.Contains(t, line, `"session.user":"bob"`)
+			return
+		}
+	}
+	t.Error("the program input was not logged")
+}
+
+func TestShareLinks(t *testing.T) {
+	cfg := &config.Config{
+		Uploads:  config.UploadsConfig{Directory: "uploads"},
+		Sessions: config.SessionsConfig{ShareTTL: time.Hour, ShareMaxTTL: 24 * time.Hour},
+	}
+	h, holder := newTestGDBHandler(t, cfg)
+
+	// There is nothing to share without a session
+	_, _, err := h.ShareSession("alice", 0)
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+
+	logger, err := logsession.NewSessionLogger("s1")
+	require.NoError(t, err)
+	logger.SetOwner("alice")
+	holder.Set(logger)
+	t.Cleanup(func() { holder.Set(nil) })
+
+	// Only members may share the session, for at most sessions.share_max_ttl
+	_, _, err = h.ShareSession("bob", 0)
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+	_, _, err = h.ShareSession("alice", 48*time.Hour)
+	assert.ErrorIs(t, err, appErrors.ErrBadRequest)
+
+	token, expires, err := h.ShareSession("alice", 0)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, 2*time.Second)
+
+	// Anyone with the token may watch, but it grants no membership
+	sessionID, watchExpires, err := h.WatchSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, "s1", sessionID)
+	assert.Equal(t, expires, watchExpires)
+	assert.ErrorIs(t, h.AuthorizeSession("bob"), appErrors.ErrForbidden)
+	_, _, err = h.WatchSession("guess")
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+
+	// Expired and revoked tokens no longer work
+	_, ok := logger.Share(token, expires)
+	assert.False(t, ok)
+	assert.ErrorIs(t, h.RevokeShare("bob", token), appErrors.ErrForbidden)
+	require.NoError(t, h.RevokeShare("alice", token))
+	_, _, err = h.WatchSession(token)
+	assert.ErrorIs(t, err, appErrors.ErrForbidden)
+	assert.ErrorIs(t, h.RevokeShare("alice", token), appErrors.ErrNotFound)
+
+	// The token is never written to the log
+	data, err := os.ReadFile(logsession.LogFilePath("s1"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"event.type":"session.share"`)
+	assert.NotContains(t, string(data), token)
+}
+
+func TestUserUploadsDir(t *testing.T) {
+	assert.Equal(t, "uploads", userUploadsDir("uploads", ""))
+	assert.Equal(t, filepath.Join("uploads", "users", "alice"), userUploadsDir("uploads", "alice"))
+	assert.Equal(t, filepath.Join("uploads", "users", "_etc"), userUploadsDir("uploads", "../etc"))
+}
+
+func TestDrain(t *testing.T) {
+	holder := logsession.NewLoggerHolder()
+	h := NewGDBHandler(websocket.NewHub(&config.Config{}), holder, &config.Config{Uploads: config.UploadsConfig{Directory: t.TempDir()}})
+	assert.NoError(t, h.ClaimSession("alice"))
+
+	h.Drain()
+	assert.ErrorIs(t, h.ClaimSession("alice"), appErrors.ErrUnavailable)
+	assert.ErrorIs(t, h.StartSession("alice", "a.out"), appErrors.ErrUnavailable)
+
+	w := httptest.NewRecorder()
+	writeClaimError(w, h.ClaimSession("alice"), UploadErrSessionInUse)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), SessionErrDraining)
+}
@@ -2,9 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logger"
+	"github.com/yourusername/gogdbllm/internal/prompts"
 	"github.com/yourusername/gogdbllm/internal/settings"
 )
 
@@ -15,15 +23,40 @@ type ConnectionTestRequest struct {
 	APIKey   string `json:"apiKey"`
 }
 
+// SettingsView is the settings returned to clients. API keys are never sent back; the
+// client only learns whether one is configured and where it comes from.
+type SettingsView struct {
+	Provider     string            `json:"provider"`
+	Model        string            `json:"model"`
+	HasAPIKey    bool              `json:"hasApiKey"`
+	APIKeySource settings.Source   `json:"apiKeySource,omitempty"`
+	Profile      string            `json:"profile"`
+	Profiles     []prompts.Profile `json:"profiles"` // The profiles the user may choose from
+}
+
+// SettingsErrInvalid is the Response.Code of settings that failed validation
+const SettingsErrInvalid = "invalid_settings"
+
+// SettingsErrorData is the Response.Data of settings that failed validation
+type SettingsErrorData struct {
+	Fields []settings.FieldError `json:"fields"`
+}
+
 // SettingsHandler handles settings-related operations
 type SettingsHandler struct {
 	settingsManager *settings.Manager
+	prompts         *prompts.Engine
+	models          *api.ModelCatalog
+	audit           *audit.Trail // nil unless auditing is enabled
 }
 
 // NewSettingsHandler creates a new settings handler
-func NewSettingsHandler(settingsManager *settings.Manager) *SettingsHandler {
+func NewSettingsHandler(settingsManager *settings.Manager, promptEngine *prompts.Engine, models *api.ModelCatalog, trail *audit.Trail) *SettingsHandler {
 	return &SettingsHandler{
 		settingsManager: settingsManager,
+		prompts:         promptEngine,
+		models:          models,
+		audit:           trail,
 	}
 }
 
@@ -34,13 +67,18 @@ func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	settings := h.settingsManager.GetSettings()
-
-	// Don't expose the API key
-	settings.APIKey = ""
+	user, _ := auth.UserFromContext(r.Context())
+	effective := h.settingsManager.Effective(user)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(settings)
+	json.NewEncoder(w).Encode(SettingsView{
+		Provider:     effective.Provider.Value,
+		Model:        effective.Model.Value,
+		HasAPIKey:    effective.APIKey.Value != "",
+		APIKeySource: effective.APIKey.Source,
+		Profile:      effective.Profile.Value,
+		Profiles:     h.prompts.Profiles(),
+	})
 }
 
 // SaveSettings handles requests to save settings
@@ -52,21 +90,49 @@ func (h *SettingsHandler) SaveSettings(w http.ResponseWriter, r *http.Request) {
 
 	var newSettings settings.Settings
 	if err := json.NewDecoder(r.Body).Decode(&newSettings); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeJSONResponseError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	newSettings = newSettings.Normalize()
+	verr := newSettings.Validate()
+	if _, err := h.prompts.Profile(newSettings.Profile); err != nil {
+		verr.Add("profile", "%s", err.Error())
+	}
+	if len(verr.Fields) > 0 {
+		writeSettingsError(w, verr)
+		return
+	}
+
+	// Settings are stored per authenticated user (shared when authentication is off)
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Get current settings to use the existing API key if not provided
-	currentSettings := h.settingsManager.GetSettings()
+	// Keep the user's existing API key if not provided
+	oldSettings := h.settingsManager.StoredSettings(user)
 	if newSettings.APIKey == "" {
-		newSettings.APIKey = currentSettings.APIKey
+		newSettings.APIKey = oldSettings.APIKey
 	}
 
 	// Update settings
-	h.settingsManager.UpdateSettings(newSettings)
+	h.settingsManager.UpdateUserSettings(user, newSettings)
 
 	// Save to disk
-	if err := h.settingsManager.Save(); err != nil {
+	err := h.settingsManager.Save()
+	if changes := settingsChanges(oldSettings, newSettings); len(changes) > 0 {
+		entry := audit.Entry{
+			Action:    audit.ActionSettings,
+			Actor:     audit.ActorUser,
+			User:      user,
+			RequestID: logger.RequestID(r.Context()),
+			Changes:   changes,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if err := h.audit.Record(entry); err != nil {
+			log.Printf("Auditing the settings of %q failed: %v", user, err)
+		}
+	}
+	if err != nil {
 		http.Error(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -81,6 +147,46 @@ func (h *SettingsHandler) SaveSettings(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// settingsChanges returns the settings that differ between old and new, for the audit
+// trail. API keys are recorded as set or not.
+func settingsChanges(old, new settings.Settings) map[string]audit.Change {
+	changes := make(map[string]audit.Change)
+	for name, values := range map[string][2]string{
+		"provider": {old.Provider, new.Provider},
+		"model":    {old.Model, new.Model},
+		"profile":  {old.Profile, new.Profile},
+	} {
+		if values[0] != values[1] {
+			changes[name] = audit.Change{From: values[0], To: values[1]}
+		}
+	}
+	if old.APIKey != new.APIKey {
+		changes["apiKey"] = audit.Change{From: keyState(old.APIKey), To: keyState(new.APIKey)}
+	}
+	return changes
+}
+
+// keyState describes an API key without revealing it
+func keyState(key string) string {
+	if key == "" {
+		return "(none)"
+	}
+	return "(set)"
+}
+
+// writeSettingsError answers a request with settings that failed validation, listing
+// every failed field
+func writeSettingsError(w http.ResponseWriter, verr *settings.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(Response{
+		Success: false,
+		Error:   verr.Error(),
+		Code:    SettingsErrInvalid,
+		Data:    SettingsErrorData{Fields: verr.Fields},
+	})
+}
+
 // TestConnection handles requests to test API connection
 func (h *SettingsHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -112,3 +218,23 @@ func (h *SettingsHandler) TestConnection(w http.ResponseWriter, r *http.Request)
 		},
 	})
 }
+
+// HandleProviderModels lists the models a provider currently offers, fetched from its API
+// with the requesting user's key for that provider. ?refresh=true bypasses the cache.
+func (h *SettingsHandler) HandleProviderModels(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	provider := mux.Vars(r)["name"]
+
+	list, err := h.models.Models(r.Context(), provider, h.settingsManager.APIKeyFor(user, provider), r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, appErrors.ErrNotFound) || errors.Is(err, appErrors.ErrBadRequest) {
+			status = appErrors.StatusCode(err)
+		}
+		writeJSONResponseError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
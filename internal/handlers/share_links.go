@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// errInvalidShareToken is returned for a share token that is unknown, revoked or expired
+var errInvalidShareToken = fmt.Errorf("%w: invalid or expired share link", appErrors.ErrForbidden)
+
+// ShareRequest is the body of a request for a read-only share link
+type ShareRequest struct {
+	TTL string `json:"ttl,omitempty"` // How long the link lasts, e.g. "30m"; sessions.share_ttl by default
+}
+
+// ShareSession creates a token letting anyone signed in who presents it watch the current
+// session's terminal and chat for ttl, without running commands or chatting. A ttl of 0
+// means sessions.share_ttl; it may not exceed sessions.share_max_ttl. Only the session's
+// members may share it.
+func (h *GDBHandler) ShareSession(user string, ttl time.Duration) (string, time.Time, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return "", time.Time{}, err
+	}
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return "", time.Time{}, fmt.Errorf("%w: there is no session to share", appErrors.ErrBadRequest)
+	}
+	if ttl == 0 {
+		ttl = h.sessionsCfg.ShareTTL
+	}
+	if ttl <= 0 || (h.sessionsCfg.ShareMaxTTL > 0 && ttl > h.sessionsCfg.ShareMaxTTL) {
+		return "", time.Time{}, fmt.Errorf("%w: a share link lasts between 1s and %s (sessions.share_max_ttl)", appErrors.ErrBadRequest, h.sessionsCfg.ShareMaxTTL)
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	logger.ForUser(user).AddShare(token, expires)
+	return token, expires, nil
+}
+
+// WatchSession returns the ID of the session a share token grants read-only access to,
+// and when the token expires
+func (h *GDBHandler) WatchSession(token string) (string, time.Time, error) {
+	logger := h.loggerHolder.Get()
+	if logger == nil {
+		return "", time.Time{}, errInvalidShareToken
+	}
+	expires, ok := logger.Share(token, time.Now())
+	if !ok {
+		return "", time.Time{}, errInvalidShareToken
+	}
+	return logger.SessionID(), expires, nil
+}
+
+// RevokeShare ends a share link of the current session before it expires, disconnecting
+// those watching through it. Any member of the session may revoke its links.
+func (h *GDBHandler) RevokeShare(user, token string) error {
+	if err := h.AuthorizeSession(user); err != nil {
+		return err
+	}
+	logger := h.loggerHolder.Get()
+	if logger == nil || !logger.ForUser(user).RevokeShare(token) {
+		return fmt.Errorf("%w: no such share link", appErrors.ErrNotFound)
+	}
+	h.hub.EndShare(token)
+	return nil
+}
+
+// HandleShareSession creates a read-only share link of the current session, e.g.
+// POST /api/sessions/share {"ttl": "30m"}. Watchers open the returned url, or connect to
+// /ws?share=<token>.
+func (h *GDBHandler) HandleShareSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ShareRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "", "Invalid request body")
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			writeError(w, http.StatusBadRequest, "", "ttl must be a duration, e.g. 30m")
+			return
+		}
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	token, expires, err := h.ShareSession(user, ttl)
+	if err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"token":   token,
+		"url":     "/?watch=" + token,
+		"expires": expires,
+	}})
+}
+
+// HandleRevokeShare revokes a share link, e.g. DELETE /api/sessions/share/{token}
+func (h *GDBHandler) HandleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.RevokeShare(user, mux.Vars(r)["token"]); err != nil {
+		writeError(w, appErrors.StatusCode(err), "", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(Response{Success: true})
+}
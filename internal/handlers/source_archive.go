@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSourceDirs caps how many directories are added to GDB's source path
+const maxSourceDirs = 256
+
+// sourceLimits bounds the size of an extracted source archive
+type sourceLimits struct {
+	maxTotalSize int64
+	maxFiles     int
+}
+
+// sourceArchiveResult describes an extracted source tree
+type sourceArchiveResult struct {
+	Root  string
+	Files int
+	Size  int64
+}
+
+// sourcesDirFor returns the per-session directory that holds extracted sources
+func sourcesDirFor(uploadsDir, sessionID string) string {
+	return filepath.Join(uploadsDir, "sources", sessionID)
+}
+
+// extractSourceArchive extracts a zip, tar or tar.gz archive into dest
+func extractSourceArchive(r io.ReaderAt, size int64, dest string, limits sourceLimits) (*sourceArchiveResult, error) {
+	header := make([]byte, 512)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create source directory: %w", err)
+	}
+
+	extractor := &sourceExtractor{
+		dest:   dest,
+		limits: limits,
+		result: &sourceArchiveResult{Root: dest},
+	}
+
+	var err error
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		err = extractor.extractZip(r, size)
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err == nil {
+			err = extractor.extractTar(gz)
+			gz.Close()
+		}
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		err = extractor.extractTar(io.NewSectionReader(r, 0, size))
+	default:
+		err = &UploadValidationError{Code: UploadErrUnsupportedType, Message: "Source archive must be a zip, tar or tar.gz file"}
+	}
+
+	if err != nil {
+		os.RemoveAll(dest)
+		return nil, err
+	}
+
+	return extractor.result, nil
+}
+
+// collectSourceDirs returns root and every directory below it that contains files, so GDB
+// can find sources by base name regardless of the layout they were compiled with
+func collectSourceDirs(root string) []string {
+	dirs := make(map[string]bool)
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			dirs[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+	dirs[root] = true
+
+	result := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		result = append(result, dir)
+	}
+	sort.Strings(result)
+	if len(result) > maxSourceDirs {
+		result = result[:maxSourceDirs]
+	}
+	return result
+}
+
+// sourceExtractor writes archive entries below dest while enforcing limits
+type sourceExtractor struct {
+	dest   string
+	limits sourceLimits
+	result *sourceArchiveResult
+}
+
+// extractZip extracts every regular file in a zip archive
+func (e *sourceExtractor) extractZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return &UploadValidationError{Code: UploadErrInvalidRequest, Message: "Invalid zip archive: " + err.Error()}
+	}
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue // Skip directories and symlinks
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		err = e.writeFile(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTar extracts every regular file in a tar stream
+func (e *sourceExtractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(bufio.NewReader(r))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &UploadValidationError{Code: UploadErrInvalidRequest, Message: "Invalid tar archive: " + err.Error()}
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue // Skip directories, links and devices
+		}
+		if err := e.writeFile(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFile writes a single archive entry, rejecting paths that escape dest
+func (e *sourceExtractor) writeFile(name string, r io.Reader) error {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return &UploadValidationError{Code: UploadErrInvalidRequest, Message: "Archive entry escapes the source directory: " + name}
+	}
+
+	e.result.Files++
+	if e.limits.maxFiles > 0 && e.result.Files > e.limits.maxFiles {
+		return &UploadValidationError{Code: UploadErrTooLarge, Message: fmt.Sprintf("Source archive contains more than %d files", e.limits.maxFiles)}
+	}
+
+	target := filepath.Join(e.dest, cleaned)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer out.Close()
+
+	// Read one byte past the remaining budget to detect archives that expand too far
+	remaining := e.limits.maxTotalSize - e.result.Size
+	written, err := io.Copy(out, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	e.result.Size += written
+	if e.result.Size > e.limits.maxTotalSize {
+		return &UploadValidationError{Code: UploadErrTooLarge, Message: fmt.Sprintf("Extracted sources exceed %d bytes", e.limits.maxTotalSize)}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		assert.NoError(t, err)
+		f.Write([]byte(content))
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractSourceArchive(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "sources")
+	data := buildZip(t, map[string]string{
+		"main.c":       "int main() { return 0; }",
+		"lib/helper.c": "void helper() {}",
+	})
+
+	result, err := extractSourceArchive(bytes.NewReader(data), int64(len(data)), dest, sourceLimits{maxTotalSize: 1 << 20, maxFiles: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Files)
+
+	content, err := os.ReadFile(filepath.Join(dest, "lib", "helper.c"))
+	assert.NoError(t, err)
+	assert.Equal(t, "void helper() {}", string(content))
+
+	assert.Equal(t, []string{dest, filepath.Join(dest, "lib")}, collectSourceDirs(dest))
+}
+
+func TestExtractSourceArchiveRejectsTraversal(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "sources")
+	data := buildZip(t, map[string]string{"../../evil.c": "x"})
+
+	_, err := extractSourceArchive(bytes.NewReader(data), int64(len(data)), dest, sourceLimits{maxTotalSize: 1 << 20})
+	assertUploadError(t, err, UploadErrInvalidRequest)
+
+	_, statErr := os.Stat(dest)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestExtractSourceArchiveLimits(t *testing.T) {
+	data := buildZip(t, map[string]string{"big.c": string(bytes.Repeat([]byte("a"), 2048))})
+
+	_, err := extractSourceArchive(bytes.NewReader(data), int64(len(data)), t.TempDir(), sourceLimits{maxTotalSize: 1024})
+	assertUploadError(t, err, UploadErrTooLarge)
+
+	_, err = extractSourceArchive(bytes.NewReader([]byte("not an archive")), 14, t.TempDir(), sourceLimits{maxTotalSize: 1024})
+	assertUploadError(t, err, UploadErrUnsupportedType)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// binaryDigest is the hash of an executable's content, kept while the file is unchanged
+type binaryDigest struct {
+	mutex   sync.Mutex
+	path    string
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// get returns the hex SHA-256 of the file at path, hashing it again only when it changed
+func (d *binaryDigest) get(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.path == path && d.size == info.Size() && d.modTime.Equal(info.ModTime()) {
+		return d.sum
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return ""
+	}
+	d.path, d.size, d.modTime, d.sum = path, info.Size(), info.ModTime(), hex.EncodeToString(hash.Sum(nil))
+	return d.sum
+}
+
+// programState is what the state fingerprint is hashed from
+type programState struct {
+	Binary      string           `json:"binary"`
+	Reason      string           `json:"reason,omitempty"`
+	Signal      string           `json:"signal,omitempty"`
+	Breakpoint  int              `json:"breakpoint,omitempty"`
+	Function    string           `json:"function,omitempty"`
+	Address     string           `json:"address,omitempty"`
+	File        string           `json:"file,omitempty"`
+	Line        int              `json:"line,omitempty"`
+	Breakpoints []gdb.Breakpoint `json:"breakpoints,omitempty"`
+}
+
+// StateFingerprint identifies the state of the program being debugged: the executable's
+// content, the frame it last stopped in and why, and the breakpoints set. Chat responses
+// are cached under it, so an answer about one state is not served in another. It is ""
+// when GDB is not running.
+func (h *GDBHandler) StateFingerprint() string {
+	if !h.gdbService.IsRunning() {
+		return ""
+	}
+	state := programState{
+		Binary:      h.binaryDigest.get(h.gdbService.Executable()),
+		Breakpoints: h.gdbService.Breakpoints(),
+	}
+	if stop := h.gdbService.LastStop(); stop != nil {
+		state.Reason, state.Signal, state.Breakpoint = stop.Reason, stop.Signal, stop.Breakpoint
+		state.Function, state.Address, state.File, state.Line = stop.Function, stop.Address, stop.File, stop.Line
+	}
+	data, _ := json.Marshal(state)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
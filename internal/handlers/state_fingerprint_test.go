@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crash")
+	require.NoError(t, os.WriteFile(path, []byte("\x7fELF one"), 0755))
+
+	var digest binaryDigest
+	first := digest.get(path)
+	assert.Len(t, first, 64)
+	assert.Equal(t, first, digest.get(path))
+
+	require.NoError(t, os.WriteFile(path, []byte("\x7fELF two"), 0755))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.NotEqual(t, first, digest.get(path), "a rebuilt executable is hashed again")
+
+	assert.Empty(t, digest.get(filepath.Join(t.TempDir(), "missing")))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// outputRing returns the terminal output ring of a session, creating it. Only the
+// session being started keeps its ring: the rings of earlier sessions are dropped.
+func (h *GDBHandler) outputRing(sessionID string) *gdb.OutputRing {
+	h.outputMutex.Lock()
+	defer h.outputMutex.Unlock()
+	if ring, ok := h.outputs[sessionID]; ok {
+		return ring
+	}
+	ring := gdb.NewOutputRing(h.outputLines)
+	h.outputs = map[string]*gdb.OutputRing{sessionID: ring}
+	return ring
+}
+
+// currentOutput returns the terminal output ring of the current session, or nil before
+// GDB has been started in it
+func (h *GDBHandler) currentOutput() *gdb.OutputRing {
+	sessionID := ""
+	if logger := h.loggerHolder.Get(); logger != nil {
+		sessionID = logger.SessionID()
+	}
+	h.outputMutex.Lock()
+	defer h.outputMutex.Unlock()
+	return h.outputs[sessionID]
+}
+
+// RecentOutput returns the last lines of the current session's terminal output, without
+// ANSI escape codes
+func (h *GDBHandler) RecentOutput(lines int) string {
+	ring := h.currentOutput()
+	if ring == nil || lines <= 0 {
+		return ""
+	}
+	return gdb.JoinLines(ring.Tail(lines))
+}
+
+// HandleOutput returns recent lines of the current session's terminal output: the last
+// ones with ?last=N, or those from a line number on with ?from=SEQ&limit=N, e.g. to
+// fetch what was printed since the lines a client already has
+func (h *GDBHandler) HandleOutput(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.AuthorizeSession(user); err != nil {
+		writeError(w, http.StatusForbidden, "", err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	last, limit, from := 100, 0, uint64(0)
+	var err error
+	if v := query.Get("last"); v != "" {
+		if last, err = strconv.Atoi(v); err != nil || last < 0 {
+			writeError(w, http.StatusBadRequest, "", "last must be a non-negative number")
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, "", "limit must be a non-negative number")
+			return
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if from, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "", "from must be a line number")
+			return
+		}
+	}
+
+	lines := []gdb.OutputLine{}
+	if ring := h.currentOutput(); ring != nil {
+		if query.Has("from") {
+			lines = ring.Since(from, limit)
+		} else {
+			lines = ring.Tail(last)
+		}
+	}
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Data:    map[string]interface{}{"lines": lines},
+	})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Executable formats recognised by the upload validator
+const (
+	FormatELF   = "elf"
+	FormatMachO = "mach-o"
+)
+
+// Upload error codes returned in the Response.Code field
+const (
+	UploadErrInvalidRequest  = "invalid_request"
+	UploadErrTooLarge        = "file_too_large"
+	UploadErrUnsupportedType = "unsupported_file_type"
+	UploadErrScript          = "script_rejected"
+	UploadErrEmpty           = "empty_file"
+	UploadErrInvalidFilename = "invalid_filename"
+	UploadErrStorage         = "storage_error"
+	UploadErrSessionInUse    = "session_in_use"
+	UploadErrNotFound        = "upload_not_found"
+	UploadErrOffsetMismatch  = "offset_mismatch"
+	UploadErrIncomplete      = "upload_incomplete"
+	UploadErrChecksum        = "checksum_mismatch"
+)
+
+// sniffLen is the number of leading bytes inspected to detect the file format
+const sniffLen = 16
+
+var (
+	elfMagic = []byte{0x7f, 'E', 'L', 'F'}
+
+	// Mach-O thin (32/64-bit, both endiannesses) and universal ("fat") magics
+	machOMagics = [][]byte{
+		{0xfe, 0xed, 0xfa, 0xce},
+		{0xce, 0xfa, 0xed, 0xfe},
+		{0xfe, 0xed, 0xfa, 0xcf},
+		{0xcf, 0xfa, 0xed, 0xfe},
+	}
+	machOFatMagic = []byte{0xca, 0xfe, 0xba, 0xbe}
+)
+
+// UploadValidationError describes why an upload was rejected
+type UploadValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *UploadValidationError) Error() string {
+	return e.Message
+}
+
+// Unwrap lets callers match upload validation failures against ErrFileUpload
+func (e *UploadValidationError) Unwrap() error {
+	return appErrors.ErrFileUpload
+}
+
+// detectExecutableFormat inspects the leading bytes of a file and returns its executable format
+func detectExecutableFormat(header []byte) (string, error) {
+	if len(header) == 0 {
+		return "", &UploadValidationError{Code: UploadErrEmpty, Message: "Uploaded file is empty"}
+	}
+
+	if bytes.HasPrefix(header, []byte("#!")) {
+		return "", &UploadValidationError{Code: UploadErrScript, Message: "Scripts are not accepted; upload a compiled executable"}
+	}
+
+	if bytes.HasPrefix(header, elfMagic) {
+		return FormatELF, nil
+	}
+
+	for _, magic := range machOMagics {
+		if bytes.HasPrefix(header, magic) {
+			return FormatMachO, nil
+		}
+	}
+
+	// 0xCAFEBABE is shared with Java class files; universal binaries have a small
+	// architecture count where class files store their (much larger) version number.
+	if bytes.HasPrefix(header, machOFatMagic) && len(header) >= 8 {
+		if nfatArch := binary.BigEndian.Uint32(header[4:8]); nfatArch > 0 && nfatArch < 32 {
+			return FormatMachO, nil
+		}
+	}
+
+	return "", &UploadValidationError{
+		Code:    UploadErrUnsupportedType,
+		Message: "Unsupported file type; only ELF and Mach-O executables can be debugged",
+	}
+}
+
+// sniffExecutable reads the header from r and validates it, returning the format and the bytes read
+func sniffExecutable(r io.Reader) (string, []byte, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	header = header[:n]
+
+	format, err := detectExecutableFormat(header)
+	if err != nil {
+		return "", header, err
+	}
+	return format, header, nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestDetectExecutableFormat(t *testing.T) {
+	format, err := detectExecutableFormat([]byte{0x7f, 'E', 'L', 'F', 2, 1, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, FormatELF, format)
+
+	format, err = detectExecutableFormat([]byte{0xcf, 0xfa, 0xed, 0xfe, 7, 0, 0, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, FormatMachO, format)
+
+	// Universal binary with two architectures
+	format, err = detectExecutableFormat([]byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, FormatMachO, format)
+
+	// Java class file (major version 52) shares the 0xCAFEBABE magic
+	_, err = detectExecutableFormat([]byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 52})
+	assertUploadError(t, err, UploadErrUnsupportedType)
+
+	_, err = detectExecutableFormat([]byte("#!/bin/sh\nrm -rf /"))
+	assertUploadError(t, err, UploadErrScript)
+
+	_, err = detectExecutableFormat([]byte("hello world"))
+	assertUploadError(t, err, UploadErrUnsupportedType)
+
+	_, err = detectExecutableFormat(nil)
+	assertUploadError(t, err, UploadErrEmpty)
+}
+
+func assertUploadError(t *testing.T, err error, code string) {
+	t.Helper()
+	var validationErr *UploadValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, code, validationErr.Code)
+	}
+	assert.True(t, errors.Is(err, appErrors.ErrFileUpload))
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/yourusername/gogdbllm/internal/audit"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// PrettyPrintersRequest is the body of a request enabling or disabling pretty-printers
+type PrettyPrintersRequest struct {
+	Enabled bool   `json:"enabled"`
+	Object  string `json:"object,omitempty"` // Regular expression of the locus, e.g. "global" or "libstdc"; every locus if empty
+	Name    string `json:"name,omitempty"`   // Regular expression of the printer, or "printer;subprinter"; every printer if empty
+}
+
+// Variables returns the arguments and locals of a frame for a user, who must own or join
+// the session
+func (h *GDBHandler) Variables(user string, frame int) (*gdb.Variables, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.FrameVariables(frame)
+}
+
+// FrameVariables returns the arguments and locals of a frame for the assistant's context
+func (h *GDBHandler) FrameVariables(frame int) (*gdb.Variables, error) {
+	return h.gdbService.FrameVariables(frame)
+}
+
+// PrettyPrinters lists the pretty-printers GDB loaded for a user, who must own or join the
+// session
+func (h *GDBHandler) PrettyPrinters(user string) ([]gdb.PrettyPrinter, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return nil, err
+	}
+	return h.gdbService.PrettyPrinters()
+}
+
+// EnablePrettyPrinters enables or disables pretty-printers for a user, who must own or
+// join the session, and returns GDB's count of enabled printers
+func (h *GDBHandler) EnablePrettyPrinters(user string, req PrettyPrintersRequest) (string, error) {
+	if err := h.AuthorizeSession(user); err != nil {
+		return "", err
+	}
+	command, err := gdb.EnablePrettyPrintersCommand(req.Enabled, req.Object, req.Name)
+	if err != nil {
+		return "", err
+	}
+	logger := h.loggerHolder.Get().ForUser(user)
+	count, err := h.gdbService.EnablePrettyPrinters(req.Enabled, req.Object, req.Name)
+	h.AuditCommand(audit.ActorUser, user, "", command, err)
+	if err != nil {
+		if logger != nil {
+			logger.LogError(err, "Changing pretty-printers for "+user)
+		}
+		return "", err
+	}
+	if logger != nil {
+		logger.LogGDBCommand(command, "user")
+	}
+	return count, nil
+}
+
+// HandleVariables returns the arguments and locals of a frame, innermost by default, with
+// aggregates split into children as the pretty-printers show them, e.g.
+// GET /api/v1/debugger/variables?frame=1
+func (h *GDBHandler) HandleVariables(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	frame := 0
+	if value := r.URL.Query().Get("frame"); value != "" {
+		var err error
+		if frame, err = strconv.Atoi(value); err != nil || frame < 0 {
+			writeDebuggerError(w, fmt.Errorf("%w: invalid frame level", appErrors.ErrBadRequest))
+			return
+		}
+	}
+	variables, err := h.Variables(user, frame)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: variables})
+}
+
+// HandlePrettyPrinters lists the loaded pretty-printers, e.g.
+// GET /api/v1/debugger/pretty-printers
+func (h *GDBHandler) HandlePrettyPrinters(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	printers, err := h.PrettyPrinters(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: printers})
+}
+
+// HandleEnablePrettyPrinters enables or disables pretty-printers, e.g.
+// POST /api/v1/debugger/pretty-printers {"enabled": false, "name": "libstdc\\+\\+-v6;std::vector"}
+func (h *GDBHandler) HandleEnablePrettyPrinters(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	var req PrettyPrintersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDebuggerError(w, fmt.Errorf("%w: invalid request body", appErrors.ErrBadRequest))
+		return
+	}
+	count, err := h.EnablePrettyPrinters(user, req)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	printers, err := h.PrettyPrinters(user)
+	if err != nil {
+		writeDebuggerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Success: true, Data: map[string]interface{}{
+		"enabled":  count,
+		"printers": printers,
+	}})
+}
@@ -0,0 +1,253 @@
+// Package health checks that the server can do its work: that the debugger starts, that
+// uploads and logs can be written and that the LLM providers answer. /health reports each
+// component's status, so load balancers and operators see what is broken, not just that
+// the process is up.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Component and overall statuses
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"  // Working, but slow or without a non-critical component
+	StatusUnhealthy = "unhealthy" // A critical component is failing
+	StatusDraining  = "draining"  // Shutting down; only reported by /readyz
+)
+
+// Defaults for the configuration's zero values
+const (
+	defaultTimeout         = 5 * time.Second
+	defaultDegradedLatency = 2 * time.Second
+	defaultCacheTTL        = 15 * time.Second
+	defaultProviderTTL     = 5 * time.Minute
+)
+
+// Probe checks a component, returning details to report about it, e.g. its version
+type Probe func(ctx context.Context) (map[string]interface{}, error)
+
+// Check is a component's probe and how its result counts
+type Check struct {
+	Name     string
+	Critical bool          // The server is unhealthy when it fails, not just degraded
+	TTL      time.Duration // How long a result is reused before probing again; 0 for health.cache_ttl
+	Probe    Probe
+	// Enabled reports whether the component is in use, e.g. a provider with an API key;
+	// nil if it always is
+	Enabled func() bool
+
+	provider bool // Reused for health.provider_ttl when TTL is 0
+}
+
+// Result is a component's status as last probed
+type Result struct {
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMs int64                  `json:"latencyMs"`
+	CheckedAt time.Time              `json:"checkedAt"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the server's status and its components'
+type Report struct {
+	Status     string            `json:"status"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Components map[string]Result `json:"components,omitempty"`
+}
+
+// Checker runs the registered checks, each within a timeout, and caches their results so
+// frequent probes by load balancers do not start a debugger or call a provider each time
+type Checker struct {
+	timeout         time.Duration
+	degradedLatency time.Duration
+	cacheTTL        time.Duration
+	providerTTL     time.Duration
+
+	checks   []Check
+	results  map[string]Result
+	mutex    sync.Mutex // Held while checks run, so concurrent requests share a run
+	draining atomic.Bool
+}
+
+// New creates a checker without checks
+func New(cfg config.HealthConfig) *Checker {
+	c := &Checker{results: make(map[string]Result)}
+	c.configure(cfg)
+	return c
+}
+
+// Reload applies changed health settings. Cached results expire by the new TTLs.
+func (c *Checker) Reload(cfg config.HealthConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.configure(cfg)
+}
+
+// configure sets the timeouts and TTLs, defaulting zero values
+func (c *Checker) configure(cfg config.HealthConfig) {
+	c.timeout = cfg.Timeout
+	c.degradedLatency = cfg.DegradedLatency
+	c.cacheTTL = cfg.CacheTTL
+	c.providerTTL = cfg.ProviderTTL
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.degradedLatency <= 0 {
+		c.degradedLatency = defaultDegradedLatency
+	}
+	if c.cacheTTL <= 0 {
+		c.cacheTTL = defaultCacheTTL
+	}
+	if c.providerTTL <= 0 {
+		c.providerTTL = defaultProviderTTL
+	}
+}
+
+// Add registers a check of a local component, whose results are reused for
+// health.cache_ttl
+func (c *Checker) Add(name string, critical bool, probe Probe) {
+	c.Register(Check{Name: name, Critical: critical, Probe: probe})
+}
+
+// AddProvider registers the check of an LLM provider, which is not critical, as the
+// others may still answer, and is probed every health.provider_ttl at most, since probes
+// count against the provider's rate limits
+func (c *Checker) AddProvider(name string, enabled func() bool, probe Probe) {
+	c.Register(Check{Name: "provider." + name, Probe: probe, Enabled: enabled, provider: true})
+}
+
+// Register registers a check
+func (c *Checker) Register(check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks = append(c.checks, check)
+}
+
+// Run probes the components whose results have expired, concurrently, and reports every
+// enabled component's status
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	var resultsMutex sync.Mutex
+	report := Report{Status: StatusHealthy, Timestamp: now, Components: make(map[string]Result)}
+	for _, check := range c.checks {
+		if check.Enabled != nil && !check.Enabled() {
+			delete(c.results, check.Name)
+			continue
+		}
+		if result, ok := c.results[check.Name]; ok && now.Sub(result.CheckedAt) < c.ttl(check) {
+			report.Components[check.Name] = result
+			continue
+		}
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := c.probe(ctx, check)
+			resultsMutex.Lock()
+			c.results[check.Name] = result
+			report.Components[check.Name] = result
+			resultsMutex.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Components {
+		switch {
+		case result.Status == StatusUnhealthy && result.Critical:
+			report.Status = StatusUnhealthy
+		case result.Status != StatusHealthy && report.Status == StatusHealthy:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// ttl returns how long a check's result is reused; the caller holds the mutex
+func (c *Checker) ttl(check Check) time.Duration {
+	switch {
+	case check.TTL > 0:
+		return check.TTL
+	case check.provider:
+		return c.providerTTL
+	default:
+		return c.cacheTTL
+	}
+}
+
+// probe runs a check within the timeout. A failing check is unhealthy and a slow one
+// degraded. A client hanging up does not cut the probe, whose result is cached.
+func (c *Checker) probe(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	start := time.Now()
+	details, err := check.Probe(ctx)
+	latency := time.Since(start)
+
+	result := Result{
+		Status:    StatusHealthy,
+		Critical:  check.Critical,
+		LatencyMs: latency.Milliseconds(),
+		CheckedAt: time.Now(),
+		Details:   details,
+	}
+	switch {
+	case err != nil:
+		result.Status, result.Error = StatusUnhealthy, err.Error()
+	case latency > c.degradedLatency:
+		result.Status = StatusDegraded
+	}
+	return result
+}
+
+// HandleHealth reports the server's health: 200 when it is healthy or degraded and 503
+// when it is unhealthy, with every component's status
+func (c *Checker) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusUnhealthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Drain marks the server as shutting down: /readyz fails from then on, so load balancers
+// stop sending it requests, while /healthz still succeeds so it is not killed mid-drain
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// HandleLive reports that the server is up, e.g. GET /healthz, for liveness probes. It
+// checks nothing else, so a failing debugger or provider does not get the server
+// restarted.
+func (c *Checker) HandleLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(Report{Status: StatusHealthy, Timestamp: time.Now()})
+}
+
+// HandleReady reports whether the server should be sent requests, e.g. GET /readyz, for
+// readiness probes: 503 while it shuts down, without running the checks, or when a
+// critical component is unhealthy
+func (c *Checker) HandleReady(w http.ResponseWriter, r *http.Request) {
+	if !c.draining.Load() {
+		c.HandleHealth(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(Report{Status: StatusDraining, Timestamp: time.Now()})
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func ok(ctx context.Context) (map[string]interface{}, error) { return nil, nil }
+
+func failing(ctx context.Context) (map[string]interface{}, error) {
+	return nil, errors.New("broken")
+}
+
+func TestRunStatus(t *testing.T) {
+	c := New(config.HealthConfig{})
+	c.Add("gdb", true, ok)
+	c.AddProvider("openai", nil, failing)
+	report := c.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status, "a failing provider degrades the server")
+	assert.Equal(t, StatusHealthy, report.Components["gdb"].Status)
+	assert.Equal(t, "broken", report.Components["provider.openai"].Error)
+
+	c.Add("uploads", true, failing)
+	report = c.Run(context.Background())
+	assert.Equal(t, StatusUnhealthy, report.Status, "a failing critical component makes it unhealthy")
+	assert.Equal(t, StatusUnhealthy, report.Components["uploads"].Status)
+}
+
+func TestRunSlow(t *testing.T) {
+	c := New(config.HealthConfig{DegradedLatency: time.Millisecond, Timeout: time.Second})
+	c.Add("gdb", true, func(ctx context.Context) (map[string]interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return map[string]interface{}{"version": "GNU gdb 14.2"}, nil
+	})
+	report := c.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, "GNU gdb 14.2", report.Components["gdb"].Details["version"])
+
+	c = New(config.HealthConfig{Timeout: 10 * time.Millisecond})
+	c.Add("gdb", true, func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.Equal(t, StatusUnhealthy, c.Run(context.Background()).Status, "a check timing out fails")
+}
+
+func TestRunCache(t *testing.T) {
+	var probes atomic.Int32
+	counting := func(ctx context.Context) (map[string]interface{}, error) {
+		probes.Add(1)
+		return nil, nil
+	}
+	c := New(config.HealthConfig{CacheTTL: time.Hour})
+	c.Add("logs", true, counting)
+	c.Run(context.Background())
+	c.Run(context.Background())
+	assert.Equal(t, int32(1), probes.Load(), "the result is reused within the TTL")
+
+	c = New(config.HealthConfig{CacheTTL: time.Nanosecond})
+	c.Add("logs", true, counting)
+	time.Sleep(time.Millisecond)
+	c.Run(context.Background())
+	time.Sleep(time.Millisecond)
+	c.Run(context.Background())
+	assert.Equal(t, int32(3), probes.Load())
+}
+
+func TestRunDisabled(t *testing.T) {
+	enabled := false
+	c := New(config.HealthConfig{})
+	c.AddProvider("anthropic", func() bool { return enabled }, failing)
+	report := c.Run(context.Background())
+	assert.Equal(t, StatusHealthy, report.Status)
+	assert.Empty(t, report.Components, "providers without a key are not reported")
+
+	enabled = true
+	assert.Contains(t, c.Run(context.Background()).Components, "provider.anthropic")
+}
+
+func TestHandleHealth(t *testing.T) {
+	c := New(config.HealthConfig{})
+	c.Add("gdb", true, failing)
+	w := httptest.NewRecorder()
+	c.HandleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.True(t, report.Components["gdb"].Critical)
+}
+
+func TestProbes(t *testing.T) {
+	dir := t.TempDir()
+	details, err := Writable(dir)(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, dir, details["directory"])
+	matches, _ := filepath.Glob(filepath.Join(dir, ".health-*"))
+	assert.Empty(t, matches, "the probe removes its file")
+
+	_, err = Writable(filepath.Join(dir, "missing"))(context.Background())
+	assert.Error(t, err)
+
+	_, err = Command(filepath.Join(dir, "no-such-gdb"), "--version")(context.Background())
+	assert.Error(t, err)
+}
+
+func TestReadiness(t *testing.T) {
+	c := New(config.HealthConfig{})
+	c.Add("gdb", true, ok)
+	probe := func(handler http.HandlerFunc) (int, Report) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		var report Report
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return w.Code, report
+	}
+
+	code, report := probe(c.HandleReady)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, report.Components, "gdb")
+
+	c.Drain()
+	code, report = probe(c.HandleReady)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDraining, report.Status)
+
+	code, report = probe(c.HandleLive)
+	assert.Equal(t, http.StatusOK, code, "a draining server is still alive")
+	assert.Equal(t, StatusHealthy, report.Status)
+}
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Command probes a program by running it, e.g. "gdb --version", and reports the first
+// line of its output as its version
+func Command(path string, args ...string) Probe {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return nil, fmt.Errorf("%s %s: %w", path, strings.Join(args, " "), err)
+		}
+		version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+		return map[string]interface{}{"version": strings.TrimSpace(version)}, nil
+	}
+}
+
+// Writable probes a directory by creating and removing a file in it
+func Writable(dir string) Probe {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return nil, fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		return map[string]interface{}{"directory": dir}, nil
+	}
+}
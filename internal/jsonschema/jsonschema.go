@@ -0,0 +1,263 @@
+// Package jsonschema validates JSON documents against the subset of JSON Schema the
+// assistant's response schemas use: type, properties, required, additionalProperties,
+// items, enum and the length and range limits. Other keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	Types                []string           `json:"-"` // Allowed types; empty allows any
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"-"` // nil allows any; see noAdditional
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	noAdditional bool // additionalProperties is false
+}
+
+// ValidationError is a document's first departure from a schema
+type ValidationError struct {
+	Path    string // JSON Pointer to the offending value; "" for the document itself
+	Message string
+	Syntax  bool // The document is not JSON at all
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Compile parses a schema
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// MustCompile parses a schema known to be valid, panicking if it is not
+func MustCompile(raw json.RawMessage) *Schema {
+	schema, err := Compile(raw)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// UnmarshalJSON reads a schema, including the keywords taking more than one form
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var keywords struct {
+		plain
+		Type                 json.RawMessage `json:"type"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	*s = Schema(keywords.plain)
+
+	if len(keywords.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(keywords.Type, &one); err == nil {
+			s.Types = []string{one}
+		} else if err := json.Unmarshal(keywords.Type, &s.Types); err != nil {
+			return fmt.Errorf("type must be a string or an array of strings")
+		}
+	}
+
+	switch trimmed := bytes.TrimSpace(keywords.AdditionalProperties); {
+	case len(trimmed) == 0, string(trimmed) == "true":
+	case string(trimmed) == "false":
+		s.noAdditional = true
+	default:
+		s.AdditionalProperties = &Schema{}
+		if err := json.Unmarshal(trimmed, s.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a JSON document against the schema, returning a *ValidationError
+// describing the first value that does not match it
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Message: "invalid JSON: " + err.Error(), Syntax: true}
+	}
+	if decoder.More() {
+		return &ValidationError{Message: "invalid JSON: unexpected data after the top-level value", Syntax: true}
+	}
+	return s.validate("", value)
+}
+
+// validate checks a decoded value at a path
+func (s *Schema) validate(path string, value interface{}) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	actual := typeOf(value)
+	if len(s.Types) > 0 && !s.allows(actual) {
+		return fail("expected %s, got %s", strings.Join(s.Types, " or "), actual)
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		return fail("must be one of %s", enumList(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("must be at most %d characters long", *s.MaxLength)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			return fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fail("must be at most %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(path+"/"+strconv.Itoa(i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fail("missing required property %q", name)
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			property, known := s.Properties[name]
+			switch {
+			case known:
+			case s.noAdditional:
+				return fail("unexpected property %q", name)
+			case s.AdditionalProperties != nil:
+				property = s.AdditionalProperties
+			default:
+				continue
+			}
+			if err := property.validate(path+"/"+escapePointer(name), v[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether the schema allows a type; integers are also numbers
+func (s *Schema) allows(actual string) bool {
+	for _, t := range s.Types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// inEnum reports whether a value is one of the schema's enum values
+func (s *Schema) inEnum(value interface{}) bool {
+	for _, allowed := range s.Enum {
+		if reflect.DeepEqual(normalize(allowed), normalize(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of a decoded value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := v.Float64(); err == nil && f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// normalize makes numbers decoded with and without UseNumber comparable
+func normalize(value interface{}) interface{} {
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	return value
+}
+
+// enumList formats enum values for an error message
+func enumList(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		encoded, _ := json.Marshal(value)
+		parts[i] = string(encoded)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedKeys returns an object's property names in order, so the first error is stable
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer escapes a property name for a JSON Pointer
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
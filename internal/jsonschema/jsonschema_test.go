@@ -0,0 +1,61 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"text": {"type": "string", "minLength": 1},
+			"gdbCommands": {"type": "array", "items": {"type": "string"}},
+			"waitForOutput": {"type": "boolean"},
+			"ignoreCount": {"type": "integer", "minimum": 0},
+			"mode": {"enum": ["run", "step"]},
+			"limits": {"type": "object", "additionalProperties": {"type": "number"}},
+			"strict": {"type": "object", "properties": {"a": {}}, "additionalProperties": false}
+		},
+		"required": ["text", "gdbCommands"]
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		document string
+		err      string
+	}{
+		{`{"text": "ok", "gdbCommands": ["bt"], "waitForOutput": true, "ignoreCount": 2, "mode": "run", "limits": {"x": 1.5}, "strict": {"a": null}}`, ""},
+		{`{"text": "ok", "gdbCommands": [], "extra": 1}`, ""},
+		{`{"text": "ok"}`, `missing required property "gdbCommands"`},
+		{`{"text": "", "gdbCommands": []}`, "/text: must be at least 1 characters long"},
+		{`{"text": "ok", "gdbCommands": ["bt", 3]}`, "/gdbCommands/1: expected string, got integer"},
+		{`{"text": "ok", "gdbCommands": [], "waitForOutput": "yes"}`, "/waitForOutput: expected boolean, got string"},
+		{`{"text": "ok", "gdbCommands": [], "ignoreCount": 1.5}`, "/ignoreCount: expected integer, got number"},
+		{`{"text": "ok", "gdbCommands": [], "ignoreCount": -1}`, "/ignoreCount: must be at least 0"},
+		{`{"text": "ok", "gdbCommands": [], "mode": "jump"}`, `/mode: must be one of "run", "step"`},
+		{`{"text": "ok", "gdbCommands": [], "limits": {"x": "high"}}`, "/limits/x: expected number, got string"},
+		{`{"text": "ok", "gdbCommands": [], "strict": {"b": 1}}`, `/strict: unexpected property "b"`},
+		{`["text"]`, "expected object, got array"},
+		{`{"text": "ok",`, "invalid JSON: unexpected EOF"},
+		{`{"text": "ok", "gdbCommands": []} trailing`, "invalid JSON: unexpected data after the top-level value"},
+	}
+	for _, tt := range tests {
+		err := schema.Validate([]byte(tt.document))
+		if tt.err == "" {
+			assert.NoError(t, err, tt.document)
+			continue
+		}
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr, tt.document)
+		assert.Equal(t, tt.err, err.Error(), tt.document)
+		assert.Equal(t, strings.HasPrefix(tt.err, "invalid JSON"), validationErr.Syntax, tt.document)
+	}
+
+	_, err = Compile(json.RawMessage(`{"type": 3}`))
+	assert.Error(t, err)
+}
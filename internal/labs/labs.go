@@ -0,0 +1,214 @@
+package labs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// manifestName is the file in the labs directory listing the targets
+const manifestName = "labs.json"
+
+// binariesDir is the directory in the labs directory holding the targets' executables
+const binariesDir = "binaries"
+
+// idPattern matches valid target IDs, which double as binary file names
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Target is a predefined debugging exercise: an executable students start a fresh
+// session on, with a description of the task and hints
+type Target struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Hints       []string `json:"hints,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// manifest is the on-disk form of the catalog
+type manifest struct {
+	Targets []Target `json:"targets"`
+}
+
+// Catalog holds the lab targets. Targets are kept in a manifest in the labs directory,
+// with each target's executable in its binaries directory under the target's ID.
+type Catalog struct {
+	dir     string
+	targets map[string]Target
+	mutex   sync.RWMutex
+}
+
+// NewCatalog loads the lab catalog from the configured labs directory. A missing
+// manifest is an empty catalog.
+func NewCatalog(cfg *config.Config) (*Catalog, error) {
+	return OpenCatalog(cfg.Labs.Directory)
+}
+
+// OpenCatalog loads the lab catalog kept in dir
+func OpenCatalog(dir string) (*Catalog, error) {
+	c := &Catalog{dir: dir, targets: make(map[string]Target)}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lab manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid lab manifest %s: %w", filepath.Join(dir, manifestName), err)
+	}
+	for _, target := range m.Targets {
+		if err := target.validate(); err != nil {
+			return nil, fmt.Errorf("invalid lab manifest %s: %w", filepath.Join(dir, manifestName), err)
+		}
+		c.targets[target.ID] = target
+	}
+	return c, nil
+}
+
+// validate checks a target's ID and name
+func (t Target) validate() error {
+	if !idPattern.MatchString(t.ID) {
+		return fmt.Errorf("%w: lab ID %q must be 1-64 lowercase letters, digits, '-' or '_'", appErrors.ErrBadRequest, t.ID)
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("%w: lab %s has no name", appErrors.ErrBadRequest, t.ID)
+	}
+	return nil
+}
+
+// List returns the targets sorted by ID
+func (c *Catalog) List() []Target {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	targets := make([]Target, 0, len(c.targets))
+	for _, target := range c.targets {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	return targets
+}
+
+// Get returns the target with the given ID
+func (c *Catalog) Get(id string) (Target, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	target, ok := c.targets[id]
+	if !ok {
+		return Target{}, fmt.Errorf("lab %q: %w", id, appErrors.ErrNotFound)
+	}
+	return target, nil
+}
+
+// BinaryPath returns the path of a target's executable
+func (c *Catalog) BinaryPath(id string) string {
+	return filepath.Join(c.dir, binariesDir, id)
+}
+
+// Put adds or replaces a target. binary is the target's executable; it may be nil when
+// replacing a target to keep its executable.
+func (c *Catalog) Put(target Target, binary io.Reader) error {
+	if err := target.validate(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if binary == nil {
+		if _, err := os.Stat(c.BinaryPath(target.ID)); err != nil {
+			return fmt.Errorf("%w: lab %s needs an executable", appErrors.ErrBadRequest, target.ID)
+		}
+	} else if err := c.writeBinary(target.ID, binary); err != nil {
+		return err
+	}
+
+	c.targets[target.ID] = target
+	return c.saveLocked()
+}
+
+// Remove deletes a target and its executable
+func (c *Catalog) Remove(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.targets[id]; !ok {
+		return fmt.Errorf("lab %q: %w", id, appErrors.ErrNotFound)
+	}
+	delete(c.targets, id)
+	if err := c.saveLocked(); err != nil {
+		return err
+	}
+	if err := os.Remove(c.BinaryPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove executable of lab %s: %w", id, err)
+	}
+	return nil
+}
+
+// writeBinary stores a target's executable, replacing any previous one only once the new
+// one is complete
+func (c *Catalog) writeBinary(id string, binary io.Reader) error {
+	dir := filepath.Join(c.dir, binariesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create labs directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".lab-*")
+	if err != nil {
+		return fmt.Errorf("failed to store executable of lab %s: %w", id, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := io.Copy(tmp, binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store executable of lab %s: %w", id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store executable of lab %s: %w", id, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to store executable of lab %s: %w", id, err)
+	}
+	if err := os.Rename(tmp.Name(), c.BinaryPath(id)); err != nil {
+		return fmt.Errorf("failed to store executable of lab %s: %w", id, err)
+	}
+	return nil
+}
+
+// saveLocked writes the manifest. The caller holds the write lock.
+func (c *Catalog) saveLocked() error {
+	targets := make([]Target, 0, len(c.targets))
+	for _, target := range c.targets {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+
+	data, err := json.MarshalIndent(manifest{Targets: targets}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lab manifest: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create labs directory: %w", err)
+	}
+	tmp := filepath.Join(c.dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lab manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(c.dir, manifestName)); err != nil {
+		return fmt.Errorf("failed to write lab manifest: %w", err)
+	}
+	return nil
+}
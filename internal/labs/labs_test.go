@@ -0,0 +1,40 @@
+package labs
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	catalog, err := OpenCatalog(dir)
+	require.NoError(t, err)
+	assert.Empty(t, catalog.List())
+
+	target := Target{ID: "heap-overflow", Name: "Heap overflow", Hints: []string{"Look at malloc sizes"}}
+	require.NoError(t, catalog.Put(target, strings.NewReader("\x7fELF...")))
+
+	// Targets and their executables survive a restart
+	reopened, err := OpenCatalog(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Target{target}, reopened.List())
+	data, err := os.ReadFile(reopened.BinaryPath("heap-overflow"))
+	require.NoError(t, err)
+	assert.Equal(t, "\x7fELF...", string(data))
+
+	// Replacing a target may keep its executable; a new target needs one
+	target.Description = "Find the overflow"
+	require.NoError(t, catalog.Put(target, nil))
+	assert.ErrorIs(t, catalog.Put(Target{ID: "other", Name: "Other"}, nil), appErrors.ErrBadRequest)
+	assert.ErrorIs(t, catalog.Put(Target{ID: "../etc", Name: "Escape"}, strings.NewReader("x")), appErrors.ErrBadRequest)
+
+	require.NoError(t, catalog.Remove("heap-overflow"))
+	_, err = catalog.Get("heap-overflow")
+	assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	assert.NoFileExists(t, catalog.BinaryPath("heap-overflow"))
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // Registered for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// MaxImageTokens approximates the tokens of an image the provider scales down to fit,
+	// or whose size is unknown
+	MaxImageTokens = 1600
+	// pixelsPerToken is how many pixels Anthropic counts as a token
+	pixelsPerToken = 750
+)
+
+// ImageTokens approximates the tokens an image costs: its pixels over pixelsPerToken, up
+// to the size providers scale images down to
+func ImageTokens(data []byte) int {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return MaxImageTokens
+	}
+	return min(config.Width*config.Height/pixelsPerToken+1, MaxImageTokens)
+}
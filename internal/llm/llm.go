@@ -0,0 +1,149 @@
+// Package llm defines the requests LLM providers are sent and the responses and errors
+// they return, independent of any provider's API. The providers in
+// internal/chat/providers convert them to and from their vendor's format, so the chat
+// routes, the router and the test connection all talk to every provider the same way.
+package llm
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Request represents a standardized request to any provider
+type Request struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	MaxTokens      *int            `json:"maxTokens,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	SystemPrompt   string          `json:"systemPrompt,omitempty"`
+	ResponseFormat *ResponseFormat `json:"responseFormat,omitempty"`
+	Tool           *Tool           `json:"tool,omitempty"` // A tool the model must call; the response's content is its input
+	// Prefill is the start of the reply, e.g. received before a stream broke off. The
+	// response's content continues it, without repeating it. Requests with a Tool have none.
+	Prefill   string `json:"prefill,omitempty"`
+	RequestID string `json:"requestId"`
+}
+
+// Message represents a standardized message. Messages with images have them and their
+// text as Parts, which providers send instead of Content.
+type Message struct {
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []ContentPart `json:"parts,omitempty"`
+}
+
+// EstimatedChars approximates the size of a message in characters of text, counting
+// images by the tokens they cost
+func (m Message) EstimatedChars() int {
+	if len(m.Parts) == 0 {
+		return len(m.Content)
+	}
+	chars := 0
+	for _, part := range m.Parts {
+		switch part.Type {
+		case ContentImage:
+			chars += ImageTokens(part.Data) * 4
+		default:
+			chars += len(part.Text)
+		}
+	}
+	return chars
+}
+
+// Content part types
+const (
+	ContentText  = "text"
+	ContentImage = "image"
+)
+
+// ContentPart is a part of a message with several, e.g. text and images
+type ContentPart struct {
+	Type      string `json:"type"` // ContentText or ContentImage
+	Text      string `json:"text,omitempty"`
+	MediaType string `json:"mediaType,omitempty"` // Of an image, e.g. "image/png"
+	Data      []byte `json:"data,omitempty"`      // An image's, base64 in JSON
+}
+
+// Response format types
+const (
+	FormatJSONObject = "json_object" // Any JSON object; providers without a JSON mode ignore it
+	FormatJSONSchema = "json_schema" // A JSON object matching Schema; providers without structured outputs ignore it
+)
+
+// ResponseFormat specifies the desired response format
+type ResponseFormat struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`   // Of the schema, for providers that require one
+	Schema json.RawMessage `json:"schema,omitempty"` // JSON Schema of a FormatJSONSchema response
+}
+
+// Tool describes a tool the model is made to call, replying with the tool's input
+// rather than with text
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"` // JSON Schema of the tool's input
+}
+
+// Response represents a standardized response from any provider
+type Response struct {
+	Content    string            `json:"content"`
+	TokensUsed int               `json:"tokensUsed,omitempty"`
+	Model      string            `json:"model"`
+	Provider   string            `json:"provider"`
+	RequestID  string            `json:"requestId"`
+	Metadata   *ProviderMetadata `json:"metadata,omitempty"`
+	Route      []RouteAttempt    `json:"route,omitempty"` // Set by a router: the providers tried, ending with the one that served
+}
+
+// FallbackFrom returns the first provider a router tried when another provider served the
+// response, or "" when the first one did
+func (r *Response) FallbackFrom() string {
+	if len(r.Route) > 1 && r.Route[0].Provider != r.Provider {
+		return r.Route[0].Provider
+	}
+	return ""
+}
+
+// RouteAttempt records one provider and model a router tried for a request
+type RouteAttempt struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Skipped  bool   `json:"skipped,omitempty"` // Not tried because its circuit breaker was open
+	Error    string `json:"error,omitempty"`
+}
+
+// ProviderMetadata contains provider-specific metadata
+type ProviderMetadata struct {
+	RawResponse    string        `json:"rawResponse,omitempty"`
+	FinishReason   string        `json:"finishReason,omitempty"`
+	PromptTokens   int           `json:"promptTokens,omitempty"`
+	ResponseTokens int           `json:"responseTokens,omitempty"`
+	ResponseTime   time.Duration `json:"responseTime"`
+}
+
+// ProviderError represents an error from a provider
+type ProviderError struct {
+	Provider  string `json:"provider"`
+	ErrorType string `json:"errorType"`
+	Message   string `json:"message"`
+	Code      int    `json:"code,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+func (e *ProviderError) Error() string {
+	return e.Message
+}
+
+// ErrorType constants
+const (
+	ErrorTypeRateLimit   = "rate_limit"
+	ErrorTypeInvalidJSON = "invalid_json"
+	ErrorTypeNetwork     = "network"
+	ErrorTypeAuth        = "authentication"
+	ErrorTypeQuota       = "quota_exceeded"
+	ErrorTypeModel       = "model_error"
+	ErrorTypeValidation  = "validation"
+	ErrorTypeTimeout     = "timeout"
+	ErrorTypeInternal    = "internal"
+)
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// requestIDKey is the context key of the HTTP request ID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the ID of the HTTP request being served
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the HTTP request being served, or "" outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx returns the global logger with the context's request ID attached
+func Ctx(ctx context.Context) zerolog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return Log.With().Str("request_id", id).Logger()
+	}
+	return Log
+}
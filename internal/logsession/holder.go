@@ -7,6 +7,7 @@ import (
 // LoggerHolderImpl provides thread-safe access to a shared SessionLogger instance
 type LoggerHolderImpl struct {
 	logger *SessionLogger
+	ended  []func(sessionID string) // Called when a session ends
 	mutex  sync.RWMutex
 }
 
@@ -15,17 +16,45 @@ func NewLoggerHolder() *LoggerHolderImpl {
 	return &LoggerHolderImpl{}
 }
 
-// Set sets a new logger, replacing any existing one
+// Set sets a new logger, replacing any existing one. Replacing or clearing the logger of
+// a session ends it.
 func (h *LoggerHolderImpl) Set(newLogger *SessionLogger) {
+	h.swap(newLogger, func(*SessionLogger) bool { return true })
+}
+
+// CompareAndClear clears the logger, ending its session, only if it is still logger. It
+// reports whether it did, so a caller that looked at the session earlier cannot end a
+// session started since.
+func (h *LoggerHolderImpl) CompareAndClear(logger *SessionLogger) bool {
+	return logger != nil && h.swap(nil, func(current *SessionLogger) bool { return current == logger })
+}
+
+// swap replaces the logger with newLogger if replace accepts the current one, closing it
+// and calling the end functions if its session ended
+func (h *LoggerHolderImpl) swap(newLogger *SessionLogger, replace func(current *SessionLogger) bool) bool {
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
+	old := h.logger
+	if !replace(old) {
+		h.mutex.Unlock()
+		return false
+	}
 
 	// Close the old logger if it exists
-	if h.logger != nil {
-		h.logger.Close()
+	if old != nil {
+		old.Close()
 	}
 
 	h.logger = newLogger
+	ended := h.ended
+	h.mutex.Unlock()
+
+	// Outside the lock, so the functions can look at the new logger
+	if old != nil && (newLogger == nil || newLogger.SessionID() != old.SessionID()) {
+		for _, fn := range ended {
+			fn(old.SessionID())
+		}
+	}
+	return true
 }
 
 // Get retrieves the current logger (may be nil if not set)
@@ -34,3 +63,12 @@ func (h *LoggerHolderImpl) Get() *SessionLogger {
 	defer h.mutex.RUnlock()
 	return h.logger
 }
+
+// OnSessionEnd registers fn to be called with the ID of each session that ends, when the
+// next session replaces it or its logger is cleared, e.g. by the idle reaper, so the
+// state kept for it elsewhere can be dropped
+func (h *LoggerHolderImpl) OnSessionEnd(fn func(sessionID string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.ended = append(h.ended, fn)
+}
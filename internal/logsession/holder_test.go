@@ -0,0 +1,32 @@
+package logsession
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAndClear(t *testing.T) {
+	t.Chdir(t.TempDir())
+	holder := NewLoggerHolder()
+	var ended []string
+	holder.OnSessionEnd(func(sessionID string) { ended = append(ended, sessionID) })
+
+	first, err := NewSessionLogger("s1")
+	require.NoError(t, err)
+	second, err := NewSessionLogger("s2")
+	require.NoError(t, err)
+	holder.Set(first)
+	holder.Set(second)
+	assert.Equal(t, []string{"s1"}, ended)
+
+	// The first session has been replaced, so clearing it leaves the second running
+	assert.False(t, holder.CompareAndClear(first))
+	assert.Same(t, second, holder.Get())
+	assert.False(t, holder.CompareAndClear(nil))
+
+	assert.True(t, holder.CompareAndClear(second))
+	assert.Nil(t, holder.Get())
+	assert.Equal(t, []string{"s1", "s2"}, ended)
+}
@@ -1,13 +1,17 @@
 package logsession
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/yourusername/gogdbllm/internal/logger"
 )
 
 // ContextItem represents a piece of context sent to the LLM (defined locally)
@@ -25,6 +29,16 @@ type SessionLogger struct {
 	encoder   *json.Encoder
 	mutex     sync.Mutex
 	sessionID string
+	owner     string
+	token     string
+	metadata  map[string]interface{}
+	members   map[string]bool      // Users who joined the session besides its owner
+	shares    map[string]time.Time // Expiry of each read-only share token
+
+	// Set on loggers returned by ForRequest and ForUser, which write through their parent
+	parent    *SessionLogger
+	requestID string
+	user      string
 }
 
 // NewSessionLogger creates a new logger for a session.
@@ -33,7 +47,7 @@ func NewSessionLogger(sessionID string) (*SessionLogger, error) {
 		return nil, fmt.Errorf("failed to create log directory '%s': %w", logDir, err)
 	}
 
-	logFileName := filepath.Join(logDir, fmt.Sprintf("%s.log", sessionID))
+	logFileName := LogFilePath(sessionID)
 	file, err := os.OpenFile(logFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file '%s': %w", logFileName, err)
@@ -51,11 +65,52 @@ func NewSessionLogger(sessionID string) (*SessionLogger, error) {
 	return logger, nil
 }
 
+// ForRequest returns a logger for the session that adds the ID of the HTTP request in
+// ctx to each entry as "http.request_id", so entries can be matched with the request
+// log. It returns l itself outside a request, and nil for a nil l. The returned logger
+// writes to l's file and must not be closed.
+func (l *SessionLogger) ForRequest(ctx context.Context) *SessionLogger {
+	id := logger.RequestID(ctx)
+	if l == nil || id == "" {
+		return l
+	}
+	child := l.child()
+	child.requestID = id
+	return child
+}
+
+// ForUser returns a logger for the session that adds the user acting in it to each entry
+// as "session.user", telling apart the members of a shared session. It returns l itself
+// for "" (authentication disabled), and nil for a nil l. The returned logger writes to
+// l's file and must not be closed.
+func (l *SessionLogger) ForUser(user string) *SessionLogger {
+	if l == nil || user == "" {
+		return l
+	}
+	child := l.child()
+	child.user = user
+	return child
+}
+
+// child returns a logger writing through l's root that keeps l's request and user
+func (l *SessionLogger) child() *SessionLogger {
+	return &SessionLogger{
+		sessionID: l.sessionID,
+		owner:     l.owner,
+		token:     l.token,
+		parent:    l.root(),
+		requestID: l.requestID,
+		user:      l.user,
+	}
+}
+
+// RequestID returns the ID of the HTTP request the logger was created for by ForRequest
+func (l *SessionLogger) RequestID() string {
+	return l.requestID
+}
+
 // LogEvent creates a structured log entry and writes it as a JSON line.
 func (l *SessionLogger) LogEvent(level string, eventType string, message string, details map[string]interface{}) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-
 	entry := map[string]interface{}{
 		"timestamp":  time.Now().Format(time.RFC3339Nano),
 		"level":      level,
@@ -63,16 +118,199 @@ func (l *SessionLogger) LogEvent(level string, eventType string, message string,
 		"event.type": eventType,
 		"message":    message,
 	}
+	if l.requestID != "" {
+		entry["http.request_id"] = l.requestID
+	}
+	if l.user != "" {
+		entry["session.user"] = l.user
+	}
 
 	// Merge details into the entry
 	for k, v := range details {
 		entry[k] = v
 	}
 
-	if err := l.encoder.Encode(entry); err != nil {
+	w := l.root()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.encoder.Encode(entry); err != nil {
 		// Fallback to console logging if file write fails
-		log.Printf("ERROR writing JSON log entry to %s: %v | Entry: %+v", l.file.Name(), err, entry)
+		log.Printf("ERROR writing JSON log entry to %s: %v | Entry: %+v", w.file.Name(), err, entry)
+	}
+}
+
+// LogDir returns the directory holding the session log files.
+func LogDir() string {
+	return logDir
+}
+
+// LogFilePath returns the path of the log file for a session.
+func LogFilePath(sessionID string) string {
+	return filepath.Join(logDir, fmt.Sprintf("%s.log", sessionID))
+}
+
+// SessionID returns the identifier of the session being logged.
+func (l *SessionLogger) SessionID() string {
+	return l.sessionID
+}
+
+// SetOwner binds the session to the user who created it. It must be called before the
+// logger is shared.
+func (l *SessionLogger) SetOwner(owner string) {
+	l.owner = owner
+}
+
+// Owner returns the user who created the session ("" when authentication is disabled)
+func (l *SessionLogger) Owner() string {
+	return l.owner
+}
+
+// SetToken sets the secret that lets WebSocket clients subscribe to the session's output.
+// It must be called before the logger is shared, and is never written to the log.
+func (l *SessionLogger) SetToken(token string) {
+	l.token = token
+}
+
+// Token returns the session's subscription token
+func (l *SessionLogger) Token() string {
+	return l.token
+}
+
+// AddMember lets a user other than the owner join the session, reporting whether they
+// were not a member yet
+func (l *SessionLogger) AddMember(user string) bool {
+	root := l.root()
+	root.mutex.Lock()
+	added := user != root.owner && !root.members[user]
+	if added {
+		if root.members == nil {
+			root.members = make(map[string]bool)
+		}
+		root.members[user] = true
+	}
+	root.mutex.Unlock()
+
+	if added {
+		l.LogEvent("INFO", "session.member", "User joined the session", map[string]interface{}{
+			"session.member":        user,
+			"session.member.action": "join",
+		})
+	}
+	return added
+}
+
+// RemoveMember lets a member leave the session, reporting whether they were a member.
+// The owner cannot leave.
+func (l *SessionLogger) RemoveMember(user string) bool {
+	root := l.root()
+	root.mutex.Lock()
+	removed := root.members[user]
+	delete(root.members, user)
+	root.mutex.Unlock()
+
+	if removed {
+		l.LogEvent("INFO", "session.member", "User left the session", map[string]interface{}{
+			"session.member":        user,
+			"session.member.action": "leave",
+		})
+	}
+	return removed
+}
+
+// IsMember reports whether a user is the session's owner or joined it
+func (l *SessionLogger) IsMember(user string) bool {
+	root := l.root()
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	return user == root.owner || root.members[user]
+}
+
+// Members returns the session's owner followed by the users who joined it, sorted
+func (l *SessionLogger) Members() []string {
+	root := l.root()
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	members := make([]string, 0, len(root.members))
+	for user := range root.members {
+		members = append(members, user)
+	}
+	sort.Strings(members)
+	return append([]string{root.owner}, members...)
+}
+
+// AddShare adds a token granting read-only access to the session until expires. Like the
+// subscription token it is never written to the log.
+func (l *SessionLogger) AddShare(token string, expires time.Time) {
+	root := l.root()
+	root.mutex.Lock()
+	if root.shares == nil {
+		root.shares = make(map[string]time.Time)
+	}
+	root.shares[token] = expires
+	root.mutex.Unlock()
+
+	l.LogEvent("INFO", "session.share", "Shared the session read-only", map[string]interface{}{
+		"session.share.expires": expires.Format(time.RFC3339),
+	})
+}
+
+// Share returns when a share token expires, and false if it is unknown or expired
+func (l *SessionLogger) Share(token string, now time.Time) (time.Time, bool) {
+	root := l.root()
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	expires, ok := root.shares[token]
+	if !ok || !now.Before(expires) {
+		return time.Time{}, false
 	}
+	return expires, true
+}
+
+// RevokeShare removes a share token, reporting whether it was valid
+func (l *SessionLogger) RevokeShare(token string) bool {
+	root := l.root()
+	root.mutex.Lock()
+	_, ok := root.shares[token]
+	delete(root.shares, token)
+	root.mutex.Unlock()
+
+	if ok {
+		l.LogEvent("INFO", "session.share", "Revoked a read-only share of the session", nil)
+	}
+	return ok
+}
+
+// LogSessionMetadata records metadata describing the session (e.g. feature flag assignments).
+// The values are also kept for Metadata.
+func (l *SessionLogger) LogSessionMetadata(metadata map[string]interface{}) {
+	root := l.root()
+	root.mutex.Lock()
+	if root.metadata == nil {
+		root.metadata = make(map[string]interface{}, len(metadata))
+	}
+	for key, value := range metadata {
+		root.metadata[key] = value
+	}
+	root.mutex.Unlock()
+
+	l.LogEvent("INFO", "session.metadata", "Session metadata", metadata)
+}
+
+// Metadata returns a value recorded with LogSessionMetadata, or nil
+func (l *SessionLogger) Metadata(key string) interface{} {
+	root := l.root()
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	return root.metadata[key]
+}
+
+// root returns the logger that owns the session's file and state
+func (l *SessionLogger) root() *SessionLogger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
 }
 
 // LogUserChat logs a user chat message and its context.
@@ -109,6 +347,15 @@ func (l *SessionLogger) LogTerminalOutput(output string) {
 	})
 }
 
+// LogGDBCommand logs a command sent to GDB. Source is "user" for commands typed in the
+// terminal and "llm" for commands run on behalf of the assistant.
+func (l *SessionLogger) LogGDBCommand(command, source string) {
+	l.LogEvent("INFO", "gdb.command", "Sent command to GDB", map[string]interface{}{
+		"gdb.command":        command,
+		"gdb.command.source": source,
+	})
+}
+
 // LogError logs an error that occurred.
 func (l *SessionLogger) LogError(err error, contextMsg string) {
 	if err == nil {
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Bridge connects an MCP client that launches servers as processes, speaking
+// newline-delimited JSON-RPC on their standard streams, to the server's HTTP endpoint.
+// Each line read from in is POSTed to endpoint with token as a bearer token, and each
+// response is written to out as a line. It returns when in ends.
+func Bridge(ctx context.Context, in io.Reader, out io.Writer, endpoint, token string, client *http.Client) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		resp, err := forward(ctx, line, endpoint, token, client)
+		if err != nil {
+			// Tell the client why its request failed rather than leaving it waiting
+			var msg message
+			if json.Unmarshal(line, &msg) != nil || msg.ID == nil {
+				continue
+			}
+			resp, _ = json.Marshal(reply{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: codeInvalidRequest, Message: err.Error()}})
+		}
+		if len(resp) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", bytes.TrimSpace(resp)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// forward POSTs a message to the endpoint and returns the response, which is empty for
+// a notification
+func forward(ctx context.Context, line []byte, endpoint, token string, client *http.Client) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(line))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,236 @@
+// Package mcp serves the debugger's tools over the Model Context Protocol, so external
+// agents can drive the current debugging session. It implements the protocol's
+// streamable HTTP transport without server-initiated streams: each JSON-RPC message is
+// POSTed and answered with JSON.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+// protocolVersions are the protocol versions the server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// maxMessageSize limits the size of a client message
+const maxMessageSize = 1 << 20
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Sessions is the part of the GDB handler the tools run commands through
+type Sessions interface {
+	// RunUserCommand runs a GDB command for user, provided user owns the session, and
+	// returns its output
+	RunUserCommand(user, cmd string) (string, error)
+}
+
+// Handler serves the MCP endpoint
+type Handler struct {
+	sessions Sessions
+	profile  prompts.Profile // Its allowed and denied commands limit what the tools run
+}
+
+// NewHandler creates the MCP handler. The configured profile, or else the default
+// prompt profile, must exist.
+func NewHandler(cfg *config.Config, sessions Sessions, engine *prompts.Engine) (*Handler, error) {
+	name := cfg.MCP.Profile
+	if name == "" {
+		name = cfg.Prompts.DefaultProfile
+	}
+	profile, err := engine.Profile(name)
+	if err != nil {
+		return nil, fmt.Errorf("mcp.profile: %w", err)
+	}
+	return &Handler{sessions: sessions, profile: profile}, nil
+}
+
+// message is a JSON-RPC request or notification. Notifications have no ID.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// reply is a JSON-RPC response
+type reply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// HandleMCP answers a JSON-RPC message POSTed to the endpoint. Notifications are
+// accepted without a body.
+func (h *Handler) HandleMCP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "Message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil || msg.JSONRPC != "2.0" {
+		code, text := codeInvalidRequest, "invalid JSON-RPC 2.0 message"
+		if err != nil && !strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+			code, text = codeParseError, "parse error"
+		}
+		writeReply(w, reply{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: code, Message: text}})
+		return
+	}
+	if msg.ID == nil {
+		// A notification, e.g. notifications/initialized, or a response to a request the
+		// server never sends
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	result, err := h.call(user, msg)
+	resp := reply{JSONRPC: "2.0", ID: msg.ID, Result: result}
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+	}
+	writeReply(w, resp)
+}
+
+// call runs a request's method
+func (h *Handler) call(user string, msg message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		return map[string]interface{}{
+			"protocolVersion": negotiateVersion(params.ProtocolVersion),
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": false}},
+			"serverInfo":      map[string]interface{}{"name": "gogdbllm", "version": buildVersion()},
+			"instructions": "These tools drive the GDB session the user started in GoGDBLLM: upload and start " +
+				"the program there first. Commands run in the session's GDB, whose output the user sees.",
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		list := make([]Tool, len(tools))
+		for i, t := range tools {
+			list[i] = t.Tool
+		}
+		return map[string]interface{}{"tools": list}, nil
+	case "tools/call":
+		return h.callTool(user, msg.Params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// callTool runs a tool's GDB command. Failures of the command, like a command the profile
+// does not allow or a session the user does not own, are the tool's result, so the agent
+// sees them.
+func (h *Handler) callTool(user string, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params"}
+	}
+	var found *tool
+	for i := range tools {
+		if tools[i].Name == params.Name {
+			found = &tools[i]
+		}
+	}
+	if found == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+
+	output, err := h.run(user, found, params.Arguments)
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	return toolResult(output, false), nil
+}
+
+// run builds and runs a tool's command
+func (h *Handler) run(user string, t *tool, args json.RawMessage) (string, error) {
+	command, err := t.command(args)
+	if err != nil {
+		return "", err
+	}
+	if !t.multiline && strings.ContainsAny(command, "\r\n") {
+		return "", fmt.Errorf("%w: arguments must not contain line breaks", appErrors.ErrBadRequest)
+	}
+	if !h.profile.Allows(command) {
+		return "", fmt.Errorf("%w: profile %s does not allow %q", appErrors.ErrForbidden, h.profile.Name, command)
+	}
+
+	output, err := h.sessions.RunUserCommand(user, command)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("MCP tool %s ran %q for %q", t.Name, command, user)
+	return output, nil
+}
+
+// toolResult returns a tools/call result holding text
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// negotiateVersion returns the client's protocol version if the server speaks it, and
+// otherwise the newest the server speaks
+func negotiateVersion(requested string) string {
+	for _, version := range protocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return protocolVersions[0]
+}
+
+// buildVersion returns the version of the server's module
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// writeReply writes a JSON-RPC response
+func writeReply(w http.ResponseWriter, resp reply) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
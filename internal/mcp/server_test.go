@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+)
+
+// fakeSessions records the commands it runs, in the session of the shared token's user
+type fakeSessions struct {
+	commands []string
+}
+
+func (f *fakeSessions) RunUserCommand(user, cmd string) (string, error) {
+	if user != "token" {
+		return "", fmt.Errorf("%w: the session belongs to another user", appErrors.ErrForbidden)
+	}
+	f.commands = append(f.commands, cmd)
+	return "(gdb) output of " + cmd + "\n", nil
+}
+
+// newTestHandler returns the MCP endpoint behind token authentication
+func newTestHandler(t *testing.T, profile string) (http.Handler, *fakeSessions) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{Mode: "token", Token: "secret"},
+		MCP:  config.MCPConfig{Profile: profile},
+	}
+	authenticator, err := auth.NewAuthenticator(cfg)
+	require.NoError(t, err)
+	sessions := &fakeSessions{}
+	handler, err := NewHandler(cfg, sessions, prompts.Builtin())
+	require.NoError(t, err)
+	return authenticator.Middleware(http.HandlerFunc(handler.HandleMCP)), sessions
+}
+
+// post sends a JSON-RPC message with the token and returns the response
+func post(t *testing.T, handler http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// callTool calls a tool and returns its text and whether it failed
+func callTool(t *testing.T, handler http.Handler, name string, args interface{}) (string, bool) {
+	params, _ := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+	rec := post(t, handler, fmt.Sprintf(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":%s}`, params))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Result struct {
+			Content []struct{ Text string }
+			IsError bool
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Result.Content, 1)
+	return resp.Result.Content[0].Text, resp.Result.IsError
+}
+
+func TestInitializeAndList(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+
+	rec := post(t, handler, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	result := resp["result"].(map[string]interface{})
+	assert.Equal(t, float64(1), resp["id"])
+	assert.Equal(t, "2025-03-26", result["protocolVersion"])
+	assert.Equal(t, "gogdbllm", result["serverInfo"].(map[string]interface{})["name"])
+
+	rec = post(t, handler, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = post(t, handler, `{"jsonrpc":"2.0","id":"list","method":"tools/list"}`)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	var names []string
+	for _, tool := range resp["result"].(map[string]interface{})["tools"].([]interface{}) {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{"gdb_command", "read_memory", "backtrace", "set_breakpoint", "checkpoint", "restore_checkpoint"}, names)
+
+	rec = post(t, handler, `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`)
+	assert.Contains(t, rec.Body.String(), `"code":-32601`)
+
+	rec = post(t, handler, `{"jsonrpc":"2.0","id":3`)
+	assert.Contains(t, rec.Body.String(), `"code":-32700`)
+}
+
+func TestTools(t *testing.T) {
+	handler, sessions := newTestHandler(t, "")
+
+	text, failed := callTool(t, handler, "gdb_command", map[string]interface{}{"command": "info registers rip"})
+	assert.False(t, failed)
+	assert.Equal(t, "(gdb) output of info registers rip\n", text)
+
+	_, failed = callTool(t, handler, "read_memory", map[string]interface{}{"address": "$rsp", "length": 16})
+	assert.False(t, failed)
+	callTool(t, handler, "read_memory", map[string]interface{}{"address": "&buffer"})
+	callTool(t, handler, "backtrace", map[string]interface{}{"full": true, "limit": 3})
+	callTool(t, handler, "set_breakpoint", map[string]interface{}{"location": "parse.c:42", "condition": "len > 64", "temporary": true})
+
+	assert.Equal(t, []string{"info registers rip", "x/16xb $rsp", "x/64xb &buffer", "backtrace full 3",
+		"tbreak parse.c:42 if len > 64"}, sessions.commands)
+
+	text, failed = callTool(t, handler, "read_memory", map[string]interface{}{"address": "$rsp", "length": 1 << 20})
+	assert.True(t, failed)
+	assert.Contains(t, text, "length must be between 1 and 4096")
+
+	text, failed = callTool(t, handler, "gdb_command", map[string]interface{}{"command": "print 1\nshell id"})
+	assert.True(t, failed)
+	assert.Contains(t, text, "line breaks")
+	assert.Len(t, sessions.commands, 5)
+
+	// A breakpoint's command list is built from lines checked one by one
+	_, failed = callTool(t, handler, "set_breakpoint", map[string]interface{}{
+		"location": "parse.c:42", "ignoreCount": 2, "commands": []string{"silent", "print len", "continue"}})
+	assert.False(t, failed)
+	assert.Equal(t, "break parse.c:42\nignore $bpnum 2\ncommands\nsilent\nprint len\ncontinue\nend", sessions.commands[5])
+
+	text, failed = callTool(t, handler, "set_breakpoint", map[string]interface{}{
+		"location": "parse.c:42", "commands": []string{"print len\nshell id"}})
+	assert.True(t, failed)
+	assert.Contains(t, text, "line breaks")
+	assert.Len(t, sessions.commands, 6)
+
+	callTool(t, handler, "checkpoint", nil)
+	callTool(t, handler, "restore_checkpoint", map[string]interface{}{"id": 1})
+	assert.Equal(t, []string{"checkpoint", "restart 1"}, sessions.commands[6:])
+	text, failed = callTool(t, handler, "restore_checkpoint", map[string]interface{}{})
+	assert.True(t, failed)
+	assert.Contains(t, text, "id is required")
+}
+
+func TestToolsFollowProfile(t *testing.T) {
+	handler, sessions := newTestHandler(t, "triage")
+
+	text, failed := callTool(t, handler, "gdb_command", map[string]interface{}{"command": "continue"})
+	assert.True(t, failed)
+	assert.Contains(t, text, `profile triage does not allow "continue"`)
+
+	_, failed = callTool(t, handler, "backtrace", nil)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"backtrace"}, sessions.commands)
+
+	_, err := NewHandler(&config.Config{MCP: config.MCPConfig{Profile: "chatty"}}, sessions, prompts.Builtin())
+	assert.Error(t, err)
+}
+
+func TestBridge(t *testing.T) {
+	handler, _ := newTestHandler(t, "")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"backtrace"}}`,
+	}, "\n")
+	var out bytes.Buffer
+	require.NoError(t, Bridge(context.Background(), strings.NewReader(in), &out, server.URL+"/mcp", "secret", server.Client()))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, lines[0])
+	assert.Contains(t, lines[1], "output of backtrace")
+
+	out.Reset()
+	require.NoError(t, Bridge(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"ping"}`), &out, server.URL+"/mcp", "wrong", server.Client()))
+	assert.Contains(t, out.String(), `"id":3`)
+	assert.Contains(t, out.String(), "401 Unauthorized")
+}
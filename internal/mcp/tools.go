@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+)
+
+// maxMemoryRead limits the bytes read_memory reads at once
+const maxMemoryRead = 4096
+
+// Tool is a tool as tools/list describes it
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// tool is a tool and the GDB command a call of it runs
+type tool struct {
+	Tool
+	command func(args json.RawMessage) (string, error)
+	// multiline tools build commands of several lines themselves, from arguments they
+	// checked for line breaks
+	multiline bool
+}
+
+// object returns the JSON schema of an object with properties, of which required must
+// be set
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// property returns the JSON schema of a property
+func property(kind, description string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "description": description}
+}
+
+// tools are the debugger's tools, in the order tools/list returns them
+var tools = []tool{
+	{
+		Tool: Tool{
+			Name:        "gdb_command",
+			Description: "Run a GDB command in the current debugging session and return its output",
+			InputSchema: object(map[string]interface{}{
+				"command": property("string", "The command, e.g. \"info registers rip\""),
+			}, "command"),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			var args struct {
+				Command string `json:"command"`
+			}
+			if err := decodeArgs(raw, &args); err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(args.Command) == "" {
+				return "", fmt.Errorf("%w: command is required", appErrors.ErrBadRequest)
+			}
+			return args.Command, nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "read_memory",
+			Description: "Read bytes of the program's memory as hex, with GDB's x command",
+			InputSchema: object(map[string]interface{}{
+				"address": property("string", "Address or expression, e.g. \"0x7fffffffe000\" or \"$rsp\""),
+				"length":  property("integer", fmt.Sprintf("Bytes to read, up to %d; defaults to 64", maxMemoryRead)),
+			}, "address"),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			var args struct {
+				Address string `json:"address"`
+				Length  int    `json:"length"`
+			}
+			if err := decodeArgs(raw, &args); err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(args.Address) == "" {
+				return "", fmt.Errorf("%w: address is required", appErrors.ErrBadRequest)
+			}
+			if args.Length == 0 {
+				args.Length = 64
+			}
+			if args.Length < 0 || args.Length > maxMemoryRead {
+				return "", fmt.Errorf("%w: length must be between 1 and %d", appErrors.ErrBadRequest, maxMemoryRead)
+			}
+			return fmt.Sprintf("x/%dxb %s", args.Length, args.Address), nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "backtrace",
+			Description: "Show the stack of the program's current thread",
+			InputSchema: object(map[string]interface{}{
+				"full":  property("boolean", "Include each frame's local variables"),
+				"limit": property("integer", "Only show this many innermost frames"),
+			}),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			var args struct {
+				Full  bool `json:"full"`
+				Limit int  `json:"limit"`
+			}
+			if err := decodeArgs(raw, &args); err != nil {
+				return "", err
+			}
+			command := "backtrace"
+			if args.Full {
+				command += " full"
+			}
+			if args.Limit > 0 {
+				command += fmt.Sprintf(" %d", args.Limit)
+			}
+			return command, nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "set_breakpoint",
+			Description: "Set a breakpoint and return GDB's confirmation with its number. With commands it can collect data without stopping, e.g. [\"silent\", \"print len\", \"continue\"]",
+			InputSchema: object(map[string]interface{}{
+				"location":    property("string", "Function, file:line or *address"),
+				"condition":   property("string", "Only stop when this expression is true"),
+				"ignoreCount": property("integer", "Pass the breakpoint this many times before stopping"),
+				"commands": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "GDB commands to run each time the breakpoint stops the program",
+				},
+				"temporary": property("boolean", "Delete the breakpoint when it is first hit"),
+			}, "location"),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			var spec gdb.BreakpointSpec
+			if err := decodeArgs(raw, &spec); err != nil {
+				return "", err
+			}
+			return spec.Command()
+		},
+		multiline: true,
+	},
+	{
+		Tool: Tool{
+			Name:        "checkpoint",
+			Description: "Snapshot the stopped program before a risky step or continue, so restore_checkpoint can roll back to it. Returns the checkpoint's number; \"info checkpoints\" lists them",
+			InputSchema: object(map[string]interface{}{}),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			return "checkpoint", decodeArgs(raw, &struct{}{})
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "restore_checkpoint",
+			Description: "Roll the program back to a checkpoint. The checkpoint is kept, so it can be restored again",
+			InputSchema: object(map[string]interface{}{
+				"id": property("integer", "The checkpoint's number, as checkpoint returned it"),
+			}, "id"),
+		},
+		command: func(raw json.RawMessage) (string, error) {
+			var args struct {
+				ID *int `json:"id"`
+			}
+			if err := decodeArgs(raw, &args); err != nil {
+				return "", err
+			}
+			if args.ID == nil || *args.ID < 0 {
+				return "", fmt.Errorf("%w: id is required", appErrors.ErrBadRequest)
+			}
+			return gdb.RestoreCheckpointCommand(*args.ID), nil
+		},
+	},
+}
+
+// decodeArgs decodes a tool call's arguments
+func decodeArgs(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: invalid arguments: %v", appErrors.ErrBadRequest, err)
+	}
+	return nil
+}
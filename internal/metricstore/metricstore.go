@@ -0,0 +1,252 @@
+// Package metricstore keeps the history of the chat pipeline's per-provider metrics, which
+// the collector in memory loses on restart. Samples of what each provider did in an
+// interval are appended to a JSON Lines file per day, and queries add them up into
+// buckets of a time range for trends.
+package metricstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dayLayout names the file holding a day's samples
+const dayLayout = "2006-01-02"
+
+// MaxBuckets caps the buckets a query returns
+const MaxBuckets = 1000
+
+// Sample is what a provider did in an interval ending at Time
+type Sample struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Requests     int64     `json:"requests"`
+	Errors       int64     `json:"errors"`
+	CacheHits    int64     `json:"cache_hits"`
+	CacheMisses  int64     `json:"cache_misses"`
+	Retries      int64     `json:"retries"`
+	Refusals     int64     `json:"refusals"`
+	Responses    int64     `json:"responses"`     // Successful responses, whose latency is summed
+	ResponseTime float64   `json:"response_time"` // Milliseconds, summed over the responses
+	Cost         float64   `json:"cost"`          // US dollars
+}
+
+// Empty reports whether nothing happened in the sample's interval
+func (s Sample) Empty() bool {
+	return s.Requests == 0 && s.Errors == 0 && s.CacheHits == 0 && s.CacheMisses == 0 &&
+		s.Retries == 0 && s.Refusals == 0 && s.Responses == 0 && s.Cost == 0
+}
+
+// add adds another sample's counts to s
+func (s *Sample) add(other Sample) {
+	s.Requests += other.Requests
+	s.Errors += other.Errors
+	s.CacheHits += other.CacheHits
+	s.CacheMisses += other.CacheMisses
+	s.Retries += other.Retries
+	s.Refusals += other.Refusals
+	s.Responses += other.Responses
+	s.ResponseTime += other.ResponseTime
+	s.Cost += other.Cost
+}
+
+// Bucket is the sum of a provider's samples in [Time, Time+step), with the rates and
+// averages charts plot
+type Bucket struct {
+	Sample
+	ErrorRate       float64 `json:"error_rate"`        // Errors per request
+	CacheHitRate    float64 `json:"cache_hit_rate"`    // Hits per cache lookup
+	AvgResponseTime float64 `json:"avg_response_time"` // Milliseconds
+}
+
+// Store keeps samples in a directory
+type Store struct {
+	dir       string
+	retention time.Duration
+	mutex     sync.Mutex // Serializes writes and pruning
+}
+
+// New returns a store keeping samples in dir, which is created when they are first
+// written, for retention, or forever when it is 0
+func New(dir string, retention time.Duration) *Store {
+	return &Store{dir: dir, retention: retention}
+}
+
+// Append writes samples to the files of their days
+func (s *Store) Append(samples []Sample) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory '%s': %w", s.dir, err)
+	}
+
+	byDay := make(map[string][]Sample)
+	for _, sample := range samples {
+		day := sample.Time.UTC().Format(dayLayout)
+		byDay[day] = append(byDay[day], sample)
+	}
+	for day, daySamples := range byDay {
+		file, err := os.OpenFile(filepath.Join(s.dir, day+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open metrics file: %w", err)
+		}
+		encoder := json.NewEncoder(file)
+		for _, sample := range daySamples {
+			if err := encoder.Encode(sample); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to write metrics: %w", err)
+			}
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Prune removes the files of days entirely older than the retention at now
+func (s *Store) Prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.retention).UTC()
+	for _, day := range days {
+		start, _ := time.Parse(dayLayout, day)
+		if start.AddDate(0, 0, 1).Before(cutoff) {
+			if err := os.Remove(filepath.Join(s.dir, day+".jsonl")); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Query adds up the samples in [from, to), and extra ones not yet written, into buckets
+// of step per provider, or of one provider when provider is not "". Buckets without
+// samples are left out.
+func (s *Store) Query(from, to time.Time, step time.Duration, provider string, extra []Sample) (map[string][]Bucket, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("the range must end after it starts")
+	}
+	if step <= 0 || to.Sub(from)/step > MaxBuckets {
+		return nil, fmt.Errorf("step must be positive and make at most %d buckets", MaxBuckets)
+	}
+
+	sums := make(map[string]map[int]*Sample)
+	include := func(sample Sample) {
+		if (provider != "" && sample.Provider != provider) || sample.Time.Before(from) || !sample.Time.Before(to) {
+			return
+		}
+		index := int(sample.Time.Sub(from) / step)
+		if sums[sample.Provider] == nil {
+			sums[sample.Provider] = make(map[int]*Sample)
+		}
+		sum, ok := sums[sample.Provider][index]
+		if !ok {
+			sum = &Sample{Time: from.Add(time.Duration(index) * step), Provider: sample.Provider}
+			sums[sample.Provider][index] = sum
+		}
+		sum.add(sample)
+	}
+
+	days, err := s.days()
+	if err != nil {
+		return nil, err
+	}
+	first, last := from.UTC().Format(dayLayout), to.UTC().Format(dayLayout)
+	for _, day := range days {
+		if day < first || day > last {
+			continue
+		}
+		if err := s.read(day, include); err != nil {
+			return nil, err
+		}
+	}
+	for _, sample := range extra {
+		include(sample)
+	}
+
+	series := make(map[string][]Bucket, len(sums))
+	for name, buckets := range sums {
+		indexes := make([]int, 0, len(buckets))
+		for index := range buckets {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			series[name] = append(series[name], newBucket(*buckets[index]))
+		}
+	}
+	return series, nil
+}
+
+// newBucket computes a summed sample's rates and averages
+func newBucket(sum Sample) Bucket {
+	bucket := Bucket{Sample: sum}
+	if sum.Requests > 0 {
+		bucket.ErrorRate = float64(sum.Errors) / float64(sum.Requests)
+	}
+	if lookups := sum.CacheHits + sum.CacheMisses; lookups > 0 {
+		bucket.CacheHitRate = float64(sum.CacheHits) / float64(lookups)
+	}
+	if sum.Responses > 0 {
+		bucket.AvgResponseTime = sum.ResponseTime / float64(sum.Responses)
+	}
+	return bucket
+}
+
+// days returns the days with a file, in order
+func (s *Store) days() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, entry := range entries {
+		day, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if _, err := time.Parse(dayLayout, day); ok && err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// read calls fn with each sample in a day's file, skipping lines that are not samples,
+// like one cut short by a crash
+func (s *Store) read(day string, fn func(Sample)) error {
+	file, err := os.Open(filepath.Join(s.dir, day+".jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var sample Sample
+		if json.Unmarshal(scanner.Bytes(), &sample) == nil {
+			fn(sample)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,76 @@
+package metricstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreQuery(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "metrics")
+	store := New(dir, 0)
+	from := time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)
+
+	series, err := store.Query(from, from.Add(4*time.Hour), time.Hour, "", nil)
+	require.NoError(t, err, "a store that was never written to is empty")
+	assert.Empty(t, series)
+
+	require.NoError(t, store.Append([]Sample{
+		{Time: from.Add(10 * time.Minute), Provider: "openai", Requests: 2, Errors: 1, Responses: 1, ResponseTime: 300, Cost: 0.01},
+		{Time: from.Add(40 * time.Minute), Provider: "openai", Requests: 2, CacheHits: 1, CacheMisses: 3, Responses: 2, ResponseTime: 300},
+		{Time: from.Add(2*time.Hour + 5*time.Minute), Provider: "openai", Requests: 1, Responses: 1, ResponseTime: 50},
+		{Time: from.Add(30 * time.Minute), Provider: "ollama", Requests: 1, Responses: 1, ResponseTime: 900},
+	}))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "samples are split into a file per day")
+
+	extra := []Sample{{Time: from.Add(3*time.Hour + time.Minute), Provider: "openai", Requests: 4, Errors: 4}}
+	series, err = store.Query(from, from.Add(4*time.Hour), time.Hour, "", extra)
+	require.NoError(t, err)
+	require.Len(t, series["openai"], 3, "buckets without samples are left out")
+
+	first := series["openai"][0]
+	assert.Equal(t, from, first.Time)
+	assert.EqualValues(t, 4, first.Requests)
+	assert.InDelta(t, 0.25, first.ErrorRate, 1e-9)
+	assert.InDelta(t, 0.25, first.CacheHitRate, 1e-9)
+	assert.InDelta(t, 200, first.AvgResponseTime, 1e-9)
+	assert.InDelta(t, 0.01, first.Cost, 1e-9)
+	assert.Equal(t, from.Add(2*time.Hour), series["openai"][1].Time)
+	assert.InDelta(t, 1, series["openai"][2].ErrorRate, 1e-9, "extra samples are included")
+	require.Len(t, series["ollama"], 1)
+
+	series, err = store.Query(from, from.Add(4*time.Hour), time.Hour, "ollama", nil)
+	require.NoError(t, err)
+	assert.Len(t, series, 1)
+	assert.Contains(t, series, "ollama")
+
+	_, err = store.Query(from, from, time.Hour, "", nil)
+	assert.Error(t, err)
+	_, err = store.Query(from, from.Add(24*time.Hour), time.Second, "", nil)
+	assert.Error(t, err, "too many buckets")
+}
+
+func TestStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store := New(dir, 48*time.Hour)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Append([]Sample{
+		{Time: now.AddDate(0, 0, -5), Provider: "openai", Requests: 1},
+		{Time: now.AddDate(0, 0, -2), Provider: "openai", Requests: 1},
+		{Time: now, Provider: "openai", Requests: 1},
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0644))
+	require.NoError(t, store.Prune(now))
+
+	days, err := store.days()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-05-08", "2024-05-10"}, days, "a day partly inside the retention is kept")
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+}
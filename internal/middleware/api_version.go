@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIPrefix is the prefix of the versioned API, the paths integrators should call and the
+// OpenAPI document describes
+const APIPrefix = "/api/v1"
+
+// APIVersion serves every endpoint of the API under /api/v1. A request under /api/v1 that
+// no route serves as it is goes to the endpoint's unversioned path, which the UI and
+// older clients keep using: /api/v1/chat is served as /api/chat and /api/v1/start-gdb as
+// /start-gdb. routes reports whether a route serves a request.
+func APIVersion(routes func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, APIPrefix+"/") || routes(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rest := strings.TrimPrefix(r.URL.Path, APIPrefix)
+			for _, prefix := range []string{"/api", ""} {
+				if prefix+rest == "/" {
+					continue
+				}
+				unversioned := r.Clone(r.Context())
+				unversioned.URL.Path = prefix + rest
+				if r.URL.RawPath != "" {
+					unversioned.URL.RawPath = prefix + strings.TrimPrefix(r.URL.RawPath, APIPrefix)
+				}
+				if routes(unversioned) {
+					next.ServeHTTP(w, unversioned)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// VersionedPath returns the path under /api/v1 of an endpoint registered at path, the
+// inverse of APIVersion: /api/chat is /api/v1/chat and /start-gdb /api/v1/start-gdb
+func VersionedPath(path string) string {
+	switch {
+	case path == APIPrefix || strings.HasPrefix(path, APIPrefix+"/"):
+		return path
+	case strings.HasPrefix(path, "/api/"):
+		return APIPrefix + strings.TrimPrefix(path, "/api")
+	default:
+		return APIPrefix + path
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	registered := map[string]bool{"/api/chat": true, "/start-gdb": true, "/api/v1/uploads": true, "/": true}
+	var seen string
+	handler := APIVersion(func(r *http.Request) bool {
+		return registered[r.URL.Path]
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+	}))
+
+	// Versioned paths reach the endpoints registered without the version, or under it
+	for path, want := range map[string]string{
+		"/api/v1/chat":      "/api/chat",
+		"/api/v1/start-gdb": "/start-gdb",
+		"/api/v1/uploads":   "/api/v1/uploads",
+		"/api/chat":         "/api/chat",
+		"/api/v1/missing":   "/api/v1/missing",
+		"/api/v1/":          "/api/v1/",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, want, seen, path)
+	}
+}
+
+func TestVersionedPath(t *testing.T) {
+	assert.Equal(t, "/api/v1/chat", VersionedPath("/api/chat"))
+	assert.Equal(t, "/api/v1/start-gdb", VersionedPath("/start-gdb"))
+	assert.Equal(t, "/api/v1/uploads/{id}", VersionedPath("/api/v1/uploads/{id}"))
+}
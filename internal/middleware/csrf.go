@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// The CSRF token is set in a cookie readable by the UI's scripts, which send it back in a
+// header with their state-changing requests; another site can make a browser send the
+// cookie, but cannot read it to set the header
+const (
+	CSRFCookieName = "gogdbllm_csrf"
+	CSRFHeader     = "X-CSRF-Token"
+)
+
+// csrfTokenLength is the length of CSRF tokens in hex digits
+const csrfTokenLength = 64
+
+// OriginAllowed reports whether a request's Origin header, if any, is one of allowed
+// (e.g. "https://gdb.example.com"; "*" allows every origin) or, when allowed is empty,
+// names the host the request was sent to. Requests without an Origin header come from
+// programs rather than browsers' cross-site requests and are allowed.
+func OriginAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+	}
+	origin = strings.TrimSuffix(origin, "/")
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRF protects state-changing requests (anything but GET, HEAD and OPTIONS) made by
+// browsers, since a page on another site could otherwise make a signed-in user's browser
+// run debugger commands. Such requests must come from an allowed origin (see
+// OriginAllowed) and carry the token of the CSRF cookie, which is set on the browser's
+// first safe request, in the X-CSRF-Token header. Requests with an Authorization header,
+// which browsers never add to cross-site requests, and requests without the Origin,
+// Referer and Sec-Fetch-Site headers browsers send, e.g. from scripts and the gRPC and
+// DAP servers, are not checked.
+func CSRF(allowedOrigins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(CSRFCookieName)
+			hasToken := err == nil && len(cookie.Value) == csrfTokenLength
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if !hasToken {
+					setCSRFCookie(w, r)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !fromBrowser(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !OriginAllowed(r, allowedOrigins) {
+				writeCSRFError(w, "Cross-origin request refused (server.allowed_origins)")
+				return
+			}
+			header := r.Header.Get(CSRFHeader)
+			if !hasToken || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+				writeCSRFError(w, "Missing or invalid CSRF token; reload the page")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// fromBrowser reports whether a request looks like one a browser sends on its own, with
+// whatever cookies it holds for the server
+func fromBrowser(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	return r.Header.Get("Origin") != "" || r.Header.Get("Referer") != "" || r.Header.Get("Sec-Fetch-Site") != ""
+}
+
+// setCSRFCookie gives the browser a new CSRF token
+func setCSRFCookie(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, csrfTokenLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    hex.EncodeToString(b),
+		Path:     "/",
+		Secure:   IsHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// writeCSRFError refuses a request in the shape used by the error middleware
+func writeCSRFError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(errors.ErrorResponse{Success: false, Error: message, Code: http.StatusForbidden})
+}
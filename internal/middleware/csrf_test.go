@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	request := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://gdb.example.com/ws", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return req
+	}
+
+	// Without allowed origins only the server's own is allowed; programs send none
+	assert.True(t, OriginAllowed(request(""), nil))
+	assert.True(t, OriginAllowed(request("https://gdb.example.com"), nil))
+	assert.False(t, OriginAllowed(request("https://evil.example"), nil))
+	assert.False(t, OriginAllowed(request("null"), nil))
+
+	allowed := []string{"https://ide.example.com/"}
+	assert.True(t, OriginAllowed(request("https://IDE.example.com"), allowed))
+	assert.False(t, OriginAllowed(request("https://gdb.example.com"), allowed))
+	assert.True(t, OriginAllowed(request("https://evil.example"), []string{"*"}))
+}
+
+func TestCSRF(t *testing.T) {
+	handler := CSRF(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Loading the UI gives the browser a token
+	rec := serve(httptest.NewRequest(http.MethodGet, "http://gdb.example.com/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := cookies[0]
+	assert.Equal(t, CSRFCookieName, token.Name)
+	assert.False(t, token.HttpOnly)
+
+	post := func(origin, header string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://gdb.example.com/api/gdb/command", nil)
+		req.Header.Set("Origin", origin)
+		req.AddCookie(token)
+		if header != "" {
+			req.Header.Set(CSRFHeader, header)
+		}
+		return req
+	}
+
+	// Browsers' state-changing requests need the token, from the server's own origin
+	assert.Equal(t, http.StatusOK, serve(post("http://gdb.example.com", token.Value)).Code)
+	assert.Equal(t, http.StatusForbidden, serve(post("http://gdb.example.com", "")).Code)
+	assert.Equal(t, http.StatusForbidden, serve(post("http://gdb.example.com", token.Value[1:]+"0")).Code)
+	assert.Equal(t, http.StatusForbidden, serve(post("https://evil.example", token.Value)).Code)
+
+	// Programs and API clients with a token of their own are not checked
+	assert.Equal(t, http.StatusOK, serve(httptest.NewRequest(http.MethodPost, "/api/gdb/command", nil)).Code)
+	withBearer := post("https://evil.example", "")
+	withBearer.Header.Set("Authorization", "Bearer secret")
+	assert.Equal(t, http.StatusOK, serve(withBearer).Code)
+}
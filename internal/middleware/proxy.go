@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Headers set by reverse proxies
+const (
+	ForwardedForHeader   = "X-Forwarded-For"
+	ForwardedProtoHeader = "X-Forwarded-Proto"
+	ForwardedHostHeader  = "X-Forwarded-Host"
+)
+
+// ProxyHeaders applies the X-Forwarded-* headers of requests from trusted proxies: the
+// client's address from X-Forwarded-For becomes the request's RemoteAddr, X-Forwarded-Proto
+// its URL scheme and X-Forwarded-Host its Host. The headers of other clients are removed,
+// since anyone could have set them.
+func ProxyHeaders(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !trustedAddr(trusted, hostIP(r.RemoteAddr)) {
+				r.Header.Del(ForwardedForHeader)
+				r.Header.Del(ForwardedProtoHeader)
+				r.Header.Del(ForwardedHostHeader)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.Clone(r.Context())
+			if client := forwardedClient(trusted, r.Header.Values(ForwardedForHeader)); client != nil {
+				r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+			}
+			switch proto := strings.ToLower(strings.TrimSpace(r.Header.Get(ForwardedProtoHeader))); proto {
+			case "http", "https":
+				r.URL.Scheme = proto
+			}
+			if host := strings.TrimSpace(r.Header.Get(ForwardedHostHeader)); host != "" {
+				r.Host = host
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the client's address in X-Forwarded-For: the last one not of a
+// trusted proxy, since each proxy appends the address it received the request from
+func forwardedClient(trusted []*net.IPNet, values []string) net.IP {
+	var addrs []string
+	for _, value := range values {
+		addrs = append(addrs, strings.Split(value, ",")...)
+	}
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(addrs[i]))
+		if ip == nil {
+			return nil
+		}
+		if !trustedAddr(trusted, ip) || i == 0 {
+			return ip
+		}
+	}
+	return nil
+}
+
+// trustedAddr reports whether ip is in one of the trusted ranges
+func trustedAddr(trusted []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostIP returns the IP of a host:port address, or nil
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return net.ParseIP(host)
+}
+
+// IsHTTPS reports whether the client reached the server over HTTPS, directly or through a
+// trusted proxy
+func IsHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.URL.Scheme == "https"
+}
+
+// BasePath serves requests under a base path, e.g. "/gdb", with it stripped, so the server
+// can be reached through a proxy that forwards the path unchanged; the base path itself
+// redirects to its trailing-slash form. Requests outside it, e.g. from a proxy that strips
+// the base path itself, are served as they are. An empty base path changes nothing.
+func BasePath(base string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if base == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == base:
+				target := base + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			case strings.HasPrefix(r.URL.Path, base+"/"):
+				r = r.Clone(r.Context())
+				r.URL.Path = strings.TrimPrefix(r.URL.Path, base)
+				r.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, base)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestProxyHeaders(t *testing.T) {
+	trusted, err := config.ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+	var seen *http.Request
+	handler := ProxyHeaders(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+	}))
+
+	forwarded := func(remote string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/chat/metrics", nil)
+		req.RemoteAddr = remote
+		req.Header.Set(ForwardedForHeader, "203.0.113.7, 10.1.2.3")
+		req.Header.Set(ForwardedProtoHeader, "https")
+		req.Header.Set(ForwardedHostHeader, "gdb.example.com")
+		return req
+	}
+
+	// A trusted proxy's headers name the client, the scheme and the host it was reached at;
+	// proxies in the chain are skipped
+	handler.ServeHTTP(httptest.NewRecorder(), forwarded("192.168.1.1:4321"))
+	assert.Equal(t, "203.0.113.7:0", seen.RemoteAddr)
+	assert.True(t, IsHTTPS(seen))
+	assert.Equal(t, "gdb.example.com", seen.Host)
+
+	// Anyone else's are dropped
+	handler.ServeHTTP(httptest.NewRecorder(), forwarded("198.51.100.1:4321"))
+	assert.Equal(t, "198.51.100.1:4321", seen.RemoteAddr)
+	assert.False(t, IsHTTPS(seen))
+	assert.Equal(t, "example.com", seen.Host)
+	assert.Empty(t, seen.Header.Get(ForwardedForHeader))
+}
+
+func TestBasePath(t *testing.T) {
+	var seen string
+	handler := BasePath("/gdb")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+	}))
+
+	// Paths under the base path are served with it stripped, others as they are
+	for path, want := range map[string]string{
+		"/gdb/api/chat": "/api/chat",
+		"/gdb/":         "/",
+		"/api/chat":     "/api/chat",
+		"/gdbx/static":  "/gdbx/static",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, seen, path)
+	}
+
+	// The base path itself redirects to the UI
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gdb?watch=t", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/gdb/?watch=t", rec.Header().Get("Location"))
+}
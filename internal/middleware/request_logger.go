@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourusername/gogdbllm/internal/logger"
+)
+
+// RequestIDHeader is the header carrying a request's ID, both from clients and proxies
+// that assign one and back to the client in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of request IDs accepted from clients
+const maxRequestIDLength = 64
+
+// RequestLogger assigns each HTTP request an ID and logs its method, path, status and
+// duration once it completes. The ID is taken from the X-Request-ID header when the
+// client or a proxy sent a usable one, is echoed in the response, and is stored in the
+// request context for logger.RequestID, so session log entries written while serving the
+// request can be correlated with it. Static files are logged at debug level.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		start := time.Now()
+		recorder := &requestRecorder{responseWriterWrapper: newResponseWriterWrapper(w)}
+		next.ServeHTTP(recorder, r.WithContext(logger.ContextWithRequestID(r.Context(), id)))
+		duration := time.Since(start)
+
+		var event *zerolog.Event
+		switch {
+		case recorder.statusCode >= 500:
+			event = logger.Log.Error()
+		case recorder.statusCode >= 400:
+			event = logger.Log.Warn()
+		case strings.HasPrefix(r.URL.Path, "/static/"):
+			event = logger.Log.Debug()
+		default:
+			event = logger.Log.Info()
+		}
+		event.
+			Str("request_id", id).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", recorder.statusCode).
+			Int64("bytes", recorder.bytes).
+			Dur("duration", duration).
+			Str("remote", r.RemoteAddr).
+			Msg("HTTP request")
+	})
+}
+
+// requestRecorder captures the status code and response size
+type requestRecorder struct {
+	*responseWriterWrapper
+	bytes int64
+}
+
+// Write counts the bytes written to the response
+func (r *requestRecorder) Write(b []byte) (int, error) {
+	n, err := r.responseWriterWrapper.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (r *requestRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the WebSocket upgrader take over the connection through the recorder
+func (r *requestRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	r.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches it
+func (r *requestRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
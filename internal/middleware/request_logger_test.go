@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/logger"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var seen string
+	handler := RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logger.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	// A usable client ID is kept and echoed
+	req := httptest.NewRequest(http.MethodGet, "/api/chat/metrics", nil)
+	req.Header.Set(RequestIDHeader, "client-42.a_b")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "client-42.a_b", seen)
+	assert.Equal(t, "client-42.a_b", rec.Header().Get(RequestIDHeader))
+
+	// Missing, oversized or unsafe IDs are replaced by a generated one
+	for _, id := range []string{"", strings.Repeat("a", maxRequestIDLength+1), "bad id\n"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, id)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Len(t, seen, 16)
+		assert.NotEqual(t, id, seen)
+		assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,275 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document, built from the routes
+// the server registered and the request types of their handlers (see operations), so
+// integrators can discover the API programmatically.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/middleware"
+)
+
+// Version is the OpenAPI version of the document
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a URL the API is reached at
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem holds the operations of a path by lower-case method
+type PathItem map[string]*Operation
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the body an operation takes
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response is one of an operation's responses
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds the schemas operations refer to and the ways to authenticate
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is a way to authenticate
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Route is a registered endpoint
+type Route struct {
+	Method string
+	Path   string // Path template as registered, e.g. /api/logs/{id}
+}
+
+// Routes returns the router's endpoints that have methods, which are those of the API,
+// the pages and the health checks
+func Routes(router *mux.Router) []Route {
+	var routes []Route
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, Route{Method: method, Path: path})
+		}
+		return nil
+	})
+	return routes
+}
+
+// pathParam matches the parameters of a path template, with their optional patterns
+var pathParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// Build describes routes as they are reached under /api/v1 (see middleware.APIVersion).
+// Routes under /api are described whether operations documents them or not; others, such
+// as pages and health checks, only if it does.
+func Build(info Info, basePath string, routes []Route) *Document {
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []Server{{URL: basePath + "/"}},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer"},
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: auth.SessionCookieName},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}},
+	}
+	s.components["Response"] = s.object(reflect.TypeOf(envelope{}))
+	tags := make(map[string]bool)
+
+	for _, route := range routes {
+		path := middleware.VersionedPath(pathParam.ReplaceAllString(route.Path, "{$1}"))
+		key := route.Method + " " + path
+		op, documented := operations[key]
+		if !documented && !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		if op.Summary == "" {
+			op.Summary = key
+		}
+
+		operation := &Operation{
+			OperationID: operationID(route.Method, path),
+			Summary:     op.Summary,
+			Parameters:  parameters(path, op),
+			Responses:   responses(op),
+		}
+		if op.Tag != "" {
+			operation.Tags = []string{op.Tag}
+			tags[op.Tag] = true
+		}
+		if op.Public {
+			operation.Security = []map[string][]string{{}}
+		}
+		switch {
+		case op.Body != nil:
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/json": {Schema: s.of(reflect.TypeOf(op.Body))},
+			}}
+		case len(op.Form) > 0:
+			form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			for _, field := range op.Form {
+				form.Properties[field] = &Schema{Type: "string"}
+				if op.Files[field] {
+					form.Properties[field].Format = "binary"
+				}
+			}
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"multipart/form-data": {Schema: form},
+			}}
+		case op.Consumes != "":
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				op.Consumes: {Schema: &Schema{Type: "string", Format: "binary"}},
+			}}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+// envelope is the shape of the API's JSON responses, handlers.Response
+type envelope struct {
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Code    interface{} `json:"code,omitempty"` // An HTTP status or a handler's error code
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// parameters returns an operation's path parameters, then its query and header ones
+func parameters(path string, op operation) []Parameter {
+	var params []Parameter
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range op.Query {
+		params = append(params, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range op.Headers {
+		params = append(params, Parameter{Name: name, In: "header", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return params
+}
+
+// responses returns an operation's success response and the error one
+func responses(op operation) map[string]Response {
+	envelopeRef := map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Response"}}}
+	success := Response{Description: "Success", Content: envelopeRef}
+	if op.Produces != "" {
+		success.Content = map[string]MediaType{op.Produces: {Schema: &Schema{Type: "string"}}}
+	}
+	return map[string]Response{
+		"200":     success,
+		"default": {Description: "Error", Content: envelopeRef},
+	}
+}
+
+// operationID returns a unique ID for a method of a path, e.g. getApiV1LogsId
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// Handler serves the document of router's routes as JSON. It is built on the first
+// request, once every route is registered.
+func Handler(info Info, basePath string, router *mux.Router) http.HandlerFunc {
+	var once sync.Once
+	var body []byte
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			body, _ = json.MarshalIndent(Build(info, basePath, Routes(router)), "", "  ")
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	router := mux.NewRouter()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	router.HandleFunc("/api/chat", noop).Methods("POST")
+	router.HandleFunc("/api/logs/{id}", noop).Methods("GET")
+	router.HandleFunc("/api/v1/uploads/{id:[0-9a-f]+}", noop).Methods("PATCH", "DELETE")
+	router.HandleFunc("/start-gdb", noop).Methods("POST")
+	router.HandleFunc("/api/undocumented", noop).Methods("GET")
+	router.HandleFunc("/auth/login", noop).Methods("POST")
+	router.HandleFunc("/healthz", noop).Methods("GET")
+	router.HandleFunc("/ws", noop)
+
+	doc := Build(Info{Title: "GoGDBLLM", Version: "test"}, "/gdb", Routes(router))
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, "/gdb/", doc.Servers[0].URL)
+
+	// Every endpoint is described under /api/v1; pages, health checks and the WebSocket are not
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	assert.ElementsMatch(t, []string{
+		"/api/v1/chat", "/api/v1/logs/{id}", "/api/v1/uploads/{id}", "/api/v1/start-gdb",
+		"/api/v1/undocumented", "/api/v1/auth/login",
+	}, paths)
+
+	// Bodies are described by the handlers' request types
+	chat := doc.Paths["/api/v1/chat"]["post"]
+	require.NotNil(t, chat.RequestBody)
+	assert.Equal(t, "#/components/schemas/ChatRequest", chat.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas["ChatRequest"].Properties, "message")
+	assert.Equal(t, "postApiV1Chat", chat.OperationID)
+
+	// Path, query and header parameters
+	logs := doc.Paths["/api/v1/logs/{id}"]["get"]
+	assert.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, logs.Parameters[0])
+	assert.Equal(t, "tail", logs.Parameters[2].Name)
+	patch := doc.Paths["/api/v1/uploads/{id}"]["patch"]
+	assert.Equal(t, "Upload-Offset", patch.Parameters[1].Name)
+	assert.Contains(t, patch.RequestBody.Content, "application/octet-stream")
+	assert.NotNil(t, doc.Paths["/api/v1/uploads/{id}"]["delete"])
+
+	// Undocumented endpoints are listed by their route; public ones need no credentials
+	assert.Equal(t, "GET /api/v1/undocumented", doc.Paths["/api/v1/undocumented"]["get"].Summary)
+	assert.Equal(t, []map[string][]string{{}}, doc.Paths["/api/v1/auth/login"]["post"].Security)
+	assert.Nil(t, chat.Security)
+}
+
+func TestOperations(t *testing.T) {
+	for key, op := range operations {
+		method, path, ok := strings.Cut(key, " ")
+		require.True(t, ok, key)
+		assert.Contains(t, []string{"GET", "POST", "PATCH", "DELETE"}, method, key)
+		assert.True(t, strings.HasPrefix(path, "/api/v1/"), key)
+		assert.NotEmpty(t, op.Summary, key)
+		assert.NotEmpty(t, op.Tag, key)
+		for file := range op.Files {
+			assert.Contains(t, op.Form, file, key)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/chat", func(http.ResponseWriter, *http.Request) {}).Methods("POST")
+	handler := Handler(Info{Title: "GoGDBLLM", Version: "test"}, "", router)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Contains(t, doc["paths"], "/api/v1/chat")
+}
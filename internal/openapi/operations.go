@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/gdb"
+	"github.com/yourusername/gogdbllm/internal/handlers"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// operation documents an endpoint
+type operation struct {
+	Summary  string
+	Tag      string
+	Public   bool            // Reachable without authentication
+	Query    []string        // Query parameters
+	Headers  []string        // Required request headers
+	Body     interface{}     // A value of the type of the JSON body the handler decodes
+	Form     []string        // Fields of a multipart form body
+	Files    map[string]bool // Form fields holding files
+	Consumes string          // Content type of a raw body
+	Produces string          // Content type of a successful response other than JSON
+}
+
+// operations documents the endpoints by method and path under /api/v1. A new endpoint
+// under /api is described without this, but with neither a summary nor its body.
+var operations = map[string]operation{
+	// Authentication
+	"POST /api/v1/auth/login":  {Summary: "Log in with a token or username and password, starting a cookie session", Tag: "auth", Public: true, Body: auth.LoginRequest{}},
+	"POST /api/v1/auth/logout": {Summary: "End the cookie session", Tag: "auth", Public: true},
+	"GET /api/v1/auth/status":  {Summary: "Authentication mode and whether the caller is logged in", Tag: "auth", Public: true},
+
+	// Uploads and binaries
+	"POST /api/v1/upload": {Summary: "Upload an executable, with optional sources, and start a debugging session", Tag: "uploads",
+		Form: []string{"executable", "source", "repository", "commit"}, Files: map[string]bool{"executable": true, "source": true}},
+	"POST /api/v1/uploads":                      {Summary: "Start a chunked upload", Tag: "uploads", Body: handlers.ChunkedCreateRequest{}},
+	"GET /api/v1/uploads/{id}":                  {Summary: "A chunked upload and the offset of its next chunk", Tag: "uploads"},
+	"PATCH /api/v1/uploads/{id}":                {Summary: "Append a chunk to an upload", Tag: "uploads", Headers: []string{"Upload-Offset"}, Consumes: "application/octet-stream"},
+	"DELETE /api/v1/uploads/{id}":               {Summary: "Cancel a chunked upload", Tag: "uploads"},
+	"POST /api/v1/uploads/{id}/complete":        {Summary: "Finish a chunked upload of an executable and start its debugging session", Tag: "uploads", Body: handlers.ChunkedCompleteRequest{}},
+	"GET /api/v1/binaries":                      {Summary: "List the uploaded executables", Tag: "binaries"},
+	"GET /api/v1/binaries/{filename}/sections":  {Summary: "An executable's sections", Tag: "binaries"},
+	"GET /api/v1/binaries/{filename}/symbols":   {Summary: "An executable's symbols", Tag: "binaries", Query: []string{"filter", "kind", "defined", "limit"}},
+	"GET /api/v1/binaries/{filename}/imports":   {Summary: "An executable's imported libraries and symbols", Tag: "binaries"},
+	"GET /api/v1/binaries/{filename}/strings":   {Summary: "An executable's printable strings", Tag: "binaries", Query: []string{"filter", "interesting", "min", "limit"}},
+	"GET /api/v1/binaries/{filename}/summary":   {Summary: "Summarize an executable as the assistant sees it", Tag: "binaries"},
+	"GET /api/v1/binaries/{filename}/decompile": {Summary: "Decompile a function of an executable", Tag: "binaries", Query: []string{"function"}},
+	"POST /api/v1/compile":                      {Summary: "Compile source code and start GDB on the executable", Tag: "uploads", Body: handlers.CompileRequest{}},
+
+	// GDB and the debugger
+	"POST /api/v1/start-gdb":                         {Summary: "Start GDB on an uploaded executable", Tag: "debugger", Body: handlers.GDBRequest{}},
+	"POST /api/v1/stop-gdb":                          {Summary: "Stop the current session's GDB", Tag: "debugger"},
+	"GET /api/v1/gdb/annotate":                       {Summary: "Describe an address in the running program", Tag: "debugger", Query: []string{"address"}},
+	"POST /api/v1/gdb/observe":                       {Summary: "Sample a running process's backtraces and report where it spends its time", Tag: "debugger", Body: handlers.ObserveRequest{}},
+	"GET /api/v1/gdb/output":                         {Summary: "The session's terminal output", Tag: "debugger", Query: []string{"last", "from", "limit"}},
+	"GET /api/v1/debugger/memory":                    {Summary: "Read memory", Tag: "debugger", Query: []string{"addr", "len"}},
+	"POST /api/v1/debugger/memory":                   {Summary: "Write memory", Tag: "debugger", Body: handlers.MemoryWriteRequest{}},
+	"GET /api/v1/debugger/breakpoints":               {Summary: "List the breakpoints", Tag: "debugger"},
+	"POST /api/v1/debugger/breakpoints":              {Summary: "Set a breakpoint", Tag: "debugger", Body: gdb.BreakpointSpec{}},
+	"POST /api/v1/debugger/run-until":                {Summary: "Run until an event or a timeout", Tag: "debugger", Body: handlers.RunUntilRequest{}},
+	"GET /api/v1/debugger/checkpoints":               {Summary: "List the checkpoints", Tag: "debugger"},
+	"POST /api/v1/debugger/checkpoints":              {Summary: "Snapshot the program", Tag: "debugger", Body: handlers.CheckpointRequest{}},
+	"POST /api/v1/debugger/checkpoints/{id}/restore": {Summary: "Restore a checkpoint", Tag: "debugger"},
+	"DELETE /api/v1/debugger/checkpoints/{id}":       {Summary: "Delete a checkpoint", Tag: "debugger"},
+	"GET /api/v1/debugger/registers":                 {Summary: "The registers", Tag: "debugger", Query: []string{"all"}},
+	"GET /api/v1/debugger/threads":                   {Summary: "List the threads", Tag: "debugger"},
+	"POST /api/v1/debugger/threads/{id}/select":      {Summary: "Switch to a thread", Tag: "debugger"},
+	"GET /api/v1/debugger/goroutines":                {Summary: "List a Go program's goroutines", Tag: "debugger"},
+	"GET /api/v1/debugger/variables":                 {Summary: "A frame's arguments and locals, with their children", Tag: "debugger", Query: []string{"frame"}},
+	"GET /api/v1/debugger/pretty-printers":           {Summary: "The loaded pretty-printers", Tag: "debugger"},
+	"POST /api/v1/debugger/pretty-printers":          {Summary: "Enable or disable pretty-printers", Tag: "debugger", Body: handlers.PrettyPrintersRequest{}},
+	"GET /api/v1/debugger/hooks":                     {Summary: "The session's hooks and their recent runs", Tag: "debugger"},
+	"POST /api/v1/debugger/hooks":                    {Summary: "Set the session's hooks", Tag: "debugger", Body: handlers.HooksRequest{}},
+	"GET /api/v1/debugger/scripts":                   {Summary: "The GDB Python scripts and their recent runs", Tag: "debugger"},
+	"POST /api/v1/debugger/scripts":                  {Summary: "Upload a GDB Python script", Tag: "debugger", Body: handlers.ScriptUploadRequest{}},
+	"POST /api/v1/debugger/scripts/{name}/run":       {Summary: "Run a GDB Python script", Tag: "debugger", Body: handlers.ScriptRunRequest{}},
+	"DELETE /api/v1/debugger/scripts/{name}":         {Summary: "Delete an uploaded script", Tag: "debugger"},
+
+	// Sessions
+	"GET /api/v1/sessions/metrics":          {Summary: "Debugging session metrics", Tag: "sessions"},
+	"GET /api/v1/sessions/members":          {Summary: "The members of the current session and who is connected", Tag: "sessions"},
+	"POST /api/v1/sessions/join":            {Summary: "Join the current session with its token", Tag: "sessions", Body: handlers.JoinSessionRequest{}},
+	"POST /api/v1/sessions/leave":           {Summary: "Leave the current session", Tag: "sessions"},
+	"POST /api/v1/sessions/share":           {Summary: "Create a read-only share link of the current session", Tag: "sessions", Body: handlers.ShareRequest{}},
+	"DELETE /api/v1/sessions/share/{token}": {Summary: "Revoke a share link", Tag: "sessions"},
+	"GET /api/v1/sessions/{id}/export":      {Summary: "Export a session as a download", Tag: "sessions", Query: []string{"format"}, Produces: "application/octet-stream"},
+	"GET /api/v1/ws/metrics":                {Summary: "WebSocket connection and message metrics", Tag: "sessions"},
+
+	// Chat
+	"POST /api/v1/chat":                        {Summary: "Ask the assistant", Tag: "chat", Body: api.ChatRequest{}},
+	"POST /api/v1/chat/prompt":                 {Summary: "Preview the prompt a chat request would send", Tag: "chat", Body: api.ChatRequest{}},
+	"POST /api/v1/chat/observe":                {Summary: "Sample a running process and ask the assistant to diagnose a hang or slowdown", Tag: "chat", Body: api.ObserveChatRequest{}},
+	"POST /api/v1/chat/cancel":                 {Summary: "Cancel a chat request", Tag: "chat", Body: api.CancelChatRequest{}},
+	"GET /api/v1/chat/pages/{token}":           {Summary: "A further page of a long response", Tag: "chat"},
+	"GET /api/v1/chat/metrics":                 {Summary: "Chat request metrics", Tag: "metrics"},
+	"GET /api/v1/chat/metrics/history":         {Summary: "Chat request metrics over time", Tag: "metrics", Query: []string{"from", "to", "step", "provider"}},
+	"GET /api/v1/metrics/cost":                 {Summary: "LLM token use and cost", Tag: "metrics", Query: []string{"session"}},
+	"GET /api/v1/chat/attachments":             {Summary: "List the chat attachments", Tag: "chat"},
+	"POST /api/v1/chat/attachments":            {Summary: "Upload a chat attachment", Tag: "chat", Form: []string{"file"}, Files: map[string]bool{"file": true}},
+	"GET /api/v1/chat/attachments/{id}":        {Summary: "Download a chat attachment", Tag: "chat", Produces: "application/octet-stream"},
+	"DELETE /api/v1/chat/attachments/{id}":     {Summary: "Delete a chat attachment", Tag: "chat"},
+	"GET /api/v1/chat/branches":                {Summary: "List the conversation branches", Tag: "chat"},
+	"POST /api/v1/chat/branches":               {Summary: "Fork a conversation at a turn", Tag: "chat", Body: api.BranchRequest{}},
+	"GET /api/v1/chat/branches/{id}":           {Summary: "A conversation branch", Tag: "chat"},
+	"DELETE /api/v1/chat/branches/{id}":        {Summary: "Delete a conversation branch", Tag: "chat"},
+	"POST /api/v1/chat/branches/{id}/messages": {Summary: "Continue a conversation branch", Tag: "chat", Body: api.BranchMessageRequest{}},
+	"GET /api/v1/prompts":                      {Summary: "List the prompt templates", Tag: "chat"},
+	"POST /api/v1/prompts/preview":             {Summary: "Render a prompt template", Tag: "chat", Body: api.PromptRenderRequest{}},
+	"GET /api/v1/prompts/profiles":             {Summary: "List the prompt profiles", Tag: "chat"},
+
+	// Settings
+	"GET /api/v1/settings":                {Summary: "The user's settings", Tag: "settings"},
+	"POST /api/v1/save-settings":          {Summary: "Save the user's settings", Tag: "settings", Body: settings.Settings{}},
+	"POST /api/v1/test-connection":        {Summary: "Check a provider's credentials", Tag: "settings", Body: handlers.ConnectionTestRequest{}},
+	"GET /api/v1/providers/{name}/models": {Summary: "List a provider's models", Tag: "settings", Query: []string{"refresh"}},
+	"GET /api/v1/capabilities":            {Summary: "The feature flags of the active session", Tag: "settings"},
+
+	// Logs and audit
+	"GET /api/v1/logs":               {Summary: "List the user's session logs", Tag: "logs"},
+	"GET /api/v1/logs/{id}":          {Summary: "A session's log entries", Tag: "logs", Query: []string{"type", "tail", "follow"}},
+	"GET /api/v1/logs/{id}/download": {Summary: "Download a session's JSON Lines log", Tag: "logs", Produces: "application/x-ndjson"},
+	"GET /api/v1/audit":              {Summary: "Query the audit trail", Tag: "logs", Query: []string{"session", "user", "actor", "action", "limit"}},
+	"GET /api/v1/audit/verify":       {Summary: "Verify the audit trail's hash chain", Tag: "logs"},
+
+	// Administration
+	"GET /api/v1/admin/config":      {Summary: "The effective configuration, secrets redacted", Tag: "admin"},
+	"GET /api/v1/admin/cache":       {Summary: "List the response cache", Tag: "admin", Query: []string{"provider", "model", "prefix"}},
+	"DELETE /api/v1/admin/cache":    {Summary: "Invalidate cached responses", Tag: "admin", Query: []string{"provider", "model", "prefix"}},
+	"POST /api/v1/admin/cache/warm": {Summary: "Warm the response cache with a JSON array or JSON Lines of chat requests", Tag: "admin", Body: []api.ChatRequest{}},
+	"GET /api/v1/admin/cache/warm":  {Summary: "Progress of the cache warm-up", Tag: "admin"},
+	"POST /api/v1/admin/labs": {Summary: "Add or replace a lab target", Tag: "admin",
+		Form: []string{"target", "executable"}, Files: map[string]bool{"executable": true}},
+	"DELETE /api/v1/admin/labs/{id}": {Summary: "Remove a lab target", Tag: "admin"},
+
+	// Labs
+	"GET /api/v1/labs":             {Summary: "List the lab targets", Tag: "labs"},
+	"POST /api/v1/labs/{id}/start": {Summary: "Start a fresh session on a lab target", Tag: "labs"},
+
+	// Integrations, which authenticate with their own tokens
+	"POST /api/v1/triage": {Summary: "Queue a crash for triage", Tag: "triage", Public: true,
+		Form:  []string{"executable", "core", "stderr", "args", "question", "pullRequest", "ref", "buildUrl"},
+		Files: map[string]bool{"executable": true, "core": true}},
+	"GET /api/v1/triage/{id}": {Summary: "A triage job", Tag: "triage", Public: true},
+	"POST /api/v1/mcp":        {Summary: "Model Context Protocol JSON-RPC endpoint", Tag: "integrations"},
+
+	// This document
+	"GET /api/v1/openapi.json": {Summary: "This OpenAPI document", Tag: "docs", Public: true},
+	"GET /api/v1/docs":         {Summary: "Swagger UI for this document", Tag: "docs", Public: true, Produces: "text/html"},
+}
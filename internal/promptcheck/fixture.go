@@ -0,0 +1,69 @@
+package promptcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/yourusername/gogdbllm/internal/api"
+)
+
+// Fixture is a recorded debugging exchange used to check prompt behaviour
+type Fixture struct {
+	Name      string            `json:"name"`
+	Question  string            `json:"question"`
+	GDBOutput string            `json:"gdbOutput,omitempty"`
+	History   []api.ChatMessage `json:"history,omitempty"`
+
+	// RecordedResponse is the raw LLM response captured for replay without network access
+	RecordedResponse string `json:"recordedResponse,omitempty"`
+
+	// ExpectCommands requires the response to propose at least one GDB command
+	ExpectCommands bool `json:"expectCommands,omitempty"`
+}
+
+// ChatRequest builds the chat request the fixture represents
+func (f *Fixture) ChatRequest() *api.ChatRequest {
+	req := &api.ChatRequest{
+		Message: f.Question,
+		History: f.History,
+	}
+	if f.GDBOutput != "" {
+		req.SentContext = []api.ContextItem{{
+			Type:        "command_output",
+			Description: "GDB Command Output",
+			Content:     f.GDBOutput,
+		}}
+	}
+	return req
+}
+
+// LoadFixtures loads every *.json fixture in dir, sorted by file name
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fixtures: %w", err)
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %w", path, err)
+		}
+
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+		}
+		if fixture.Name == "" {
+			fixture.Name = filepath.Base(path)
+		}
+		fixtures = append(fixtures, &fixture)
+	}
+
+	return fixtures, nil
+}
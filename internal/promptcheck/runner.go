@@ -0,0 +1,139 @@
+package promptcheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/settings"
+)
+
+// Target is something that can answer a fixture: a live provider or the recorded responses
+type Target interface {
+	Name() string
+	Respond(ctx context.Context, fixture *Fixture) (string, error)
+}
+
+// ProviderTarget sends fixtures to a live LLM provider
+type ProviderTarget struct {
+	settings settings.Settings
+	client   *api.LLMClient
+}
+
+// NewProviderTarget creates a target for the given provider settings
+func NewProviderTarget(s settings.Settings) *ProviderTarget {
+	return &ProviderTarget{
+		settings: s,
+		client:   api.NewLLMClient(nil),
+	}
+}
+
+// Name returns the provider/model pair
+func (pt *ProviderTarget) Name() string {
+	return pt.settings.Provider + "/" + pt.settings.Model
+}
+
+// Respond sends the fixture to the provider
+func (pt *ProviderTarget) Respond(ctx context.Context, fixture *Fixture) (string, error) {
+	return pt.client.SendRequest(ctx, fixture.ChatRequest(), pt.settings, nil)
+}
+
+// ReplayTarget answers fixtures with their recorded responses
+type ReplayTarget struct{}
+
+// Name returns the replay target name
+func (ReplayTarget) Name() string {
+	return "replay"
+}
+
+// Respond returns the recorded response for the fixture
+func (ReplayTarget) Respond(ctx context.Context, fixture *Fixture) (string, error) {
+	if fixture.RecordedResponse == "" {
+		return "", fmt.Errorf("fixture %s has no recorded response", fixture.Name)
+	}
+	return fixture.RecordedResponse, nil
+}
+
+// Result is the outcome of running one fixture against one target
+type Result struct {
+	Fixture  string        `json:"fixture"`
+	Target   string        `json:"target"`
+	Score    *Score        `json:"score,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Response string        `json:"response,omitempty"`
+}
+
+// Report aggregates results from a regression run
+type Report struct {
+	StartedAt time.Time `json:"startedAt"`
+	Results   []*Result `json:"results"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+}
+
+// OK reports whether every fixture passed on every target
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+// Summary renders a human-readable table of the results
+func (r *Report) Summary() string {
+	var sb strings.Builder
+	for _, res := range r.Results {
+		status := "PASS"
+		detail := ""
+		switch {
+		case res.Error != "":
+			status = "ERROR"
+			detail = res.Error
+		case !res.Score.OK():
+			status = "FAIL"
+			var failed []string
+			for name, passed := range res.Score.Checks {
+				if !passed {
+					failed = append(failed, name)
+				}
+			}
+			sort.Strings(failed)
+			detail = "failed: " + strings.Join(failed, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("%-5s %-30s %-40s %s\n", status, res.Target, res.Fixture, detail))
+	}
+	sb.WriteString(fmt.Sprintf("\n%d passed, %d failed\n", r.Passed, r.Failed))
+	return sb.String()
+}
+
+// Run feeds every fixture through every target and scores the responses
+func Run(ctx context.Context, fixtures []*Fixture, targets []Target) *Report {
+	report := &Report{StartedAt: time.Now()}
+
+	for _, target := range targets {
+		for _, fixture := range fixtures {
+			start := time.Now()
+			result := &Result{Fixture: fixture.Name, Target: target.Name()}
+
+			response, err := target.Respond(ctx, fixture)
+			result.Duration = time.Since(start)
+			if err != nil {
+				result.Error = err.Error()
+				report.Failed++
+			} else {
+				result.Response = response
+				result.Score = ScoreResponse(response, fixture)
+				if result.Score.OK() {
+					report.Passed++
+				} else {
+					report.Failed++
+				}
+			}
+
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,79 @@
+package promptcheck
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Check names reported for each scored response
+const (
+	CheckValidJSON     = "valid_json"
+	CheckText          = "text_field"
+	CheckGDBCommands   = "gdb_commands_field"
+	CheckWaitForOutput = "wait_for_output_field"
+	CheckHasCommands   = "proposes_commands"
+)
+
+// Score holds the outcome of scoring a single response
+type Score struct {
+	Checks map[string]bool `json:"checks"`
+	Passed int             `json:"passed"`
+	Total  int             `json:"total"`
+}
+
+// Ratio returns the fraction of checks that passed
+func (s *Score) Ratio() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Total)
+}
+
+// OK reports whether every check passed
+func (s *Score) OK() bool {
+	return s.Total > 0 && s.Passed == s.Total
+}
+
+// ScoreResponse checks a raw LLM response for JSON validity and the required fields.
+// Unlike the chat pipeline's parser it does not attempt any recovery: the response
+// must be a single JSON object to pass.
+func ScoreResponse(response string, fixture *Fixture) *Score {
+	score := &Score{Checks: make(map[string]bool)}
+
+	var fields map[string]json.RawMessage
+	validJSON := json.Unmarshal([]byte(strings.TrimSpace(response)), &fields) == nil
+	score.record(CheckValidJSON, validJSON)
+
+	var text string
+	score.record(CheckText, validJSON && decodeField(fields, "text", &text) && strings.TrimSpace(text) != "")
+
+	var commands []string
+	score.record(CheckGDBCommands, validJSON && decodeField(fields, "gdbCommands", &commands))
+
+	var wait bool
+	score.record(CheckWaitForOutput, validJSON && decodeField(fields, "waitForOutput", &wait))
+
+	if fixture != nil && fixture.ExpectCommands {
+		score.record(CheckHasCommands, len(commands) > 0)
+	}
+
+	return score
+}
+
+// record stores the result of a single check
+func (s *Score) record(name string, passed bool) {
+	s.Checks[name] = passed
+	s.Total++
+	if passed {
+		s.Passed++
+	}
+}
+
+// decodeField decodes a required field, reporting false if it is missing or has the wrong type
+func decodeField(fields map[string]json.RawMessage, name string, target interface{}) bool {
+	raw, ok := fields[name]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, target) == nil
+}
@@ -0,0 +1,40 @@
+package promptcheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreResponse(t *testing.T) {
+	t.Run("Valid response", func(t *testing.T) {
+		score := ScoreResponse(`{"text": "Checking", "gdbCommands": ["bt"], "waitForOutput": true}`, &Fixture{ExpectCommands: true})
+		assert.True(t, score.OK())
+		assert.Equal(t, 5, score.Total)
+	})
+
+	t.Run("Missing fields", func(t *testing.T) {
+		score := ScoreResponse(`{"text": "Checking"}`, nil)
+		assert.False(t, score.OK())
+		assert.True(t, score.Checks[CheckValidJSON])
+		assert.False(t, score.Checks[CheckGDBCommands])
+		assert.False(t, score.Checks[CheckWaitForOutput])
+	})
+
+	t.Run("Text outside JSON", func(t *testing.T) {
+		score := ScoreResponse("Sure! {\"text\": \"hi\", \"gdbCommands\": [], \"waitForOutput\": false}", nil)
+		assert.False(t, score.Checks[CheckValidJSON])
+		assert.Equal(t, 0, score.Passed)
+	})
+}
+
+func TestRunReplay(t *testing.T) {
+	fixtures, err := LoadFixtures("../../fixtures/prompts")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fixtures)
+
+	report := Run(context.Background(), fixtures, []Target{ReplayTarget{}})
+	assert.True(t, report.OK(), report.Summary())
+	assert.Equal(t, len(fixtures), report.Passed)
+}
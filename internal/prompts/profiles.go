@@ -0,0 +1,168 @@
+package prompts
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// profileTemplatePrefix starts the name of the template holding a profile's instructions
+const profileTemplatePrefix = "profile_"
+
+// profileNamePattern matches valid profile names, which are also part of template names
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Profile tunes the assistant for a kind of task: its instructions, kept in the
+// profile_<name> template, are appended to the system prompt, and it limits which GDB
+// commands the model may run
+type Profile struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	AllowedCommands []string `json:"allowedCommands,omitempty"` // Empty allows every command not denied
+	DeniedCommands  []string `json:"deniedCommands,omitempty"`
+}
+
+// builtinProfiles are the profiles available without configuration
+var builtinProfiles = []Profile{
+	{
+		Name:        "teaching",
+		Description: "Explains every command it proposes, for users learning GDB",
+	},
+	{
+		Name:        "re",
+		Description: "Reverse engineering: works from disassembly, registers and memory",
+	},
+	{
+		Name:        "triage",
+		Description: "Terse crash triage that only inspects the program's state",
+		DeniedCommands: []string{
+			"run", "r", "start", "starti", "continue", "c", "cont", "next", "n", "step", "s",
+			"stepi", "si", "nexti", "ni", "finish", "fin", "until", "u", "advance", "jump",
+			"call", "set", "signal", "return", "kill", "k", "attach", "detach", "shell", "!",
+		},
+	},
+}
+
+// ProfileTemplate returns the name of the template holding a profile's instructions
+func ProfileTemplate(profile string) string {
+	return profileTemplatePrefix + profile
+}
+
+// Allows reports whether the model may run a GDB command under the profile. A command of
+// several lines, like a breakpoint with a command list, is allowed only if every line is.
+func (p Profile) Allows(command string) bool {
+	for _, line := range strings.Split(command, "\n") {
+		if !p.allowsLine(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsLine reports whether the profile allows one line of a command. The lines that
+// only shape a command list are always allowed.
+func (p Profile) allowsLine(line string) bool {
+	words := strings.Fields(strings.ToLower(line))
+	if len(words) == 0 || (len(words) == 1 && (words[0] == "end" || words[0] == "silent")) {
+		return true
+	}
+	for _, rule := range p.DeniedCommands {
+		if commandMatches(words, rule) {
+			return false
+		}
+	}
+	if len(p.AllowedCommands) == 0 {
+		return true
+	}
+	for _, rule := range p.AllowedCommands {
+		if commandMatches(words, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandMatches reports whether a command starts with the words of rule
+func commandMatches(words []string, rule string) bool {
+	ruleWords := strings.Fields(strings.ToLower(rule))
+	if len(ruleWords) == 0 || len(ruleWords) > len(words) {
+		return false
+	}
+	for i, word := range ruleWords {
+		if words[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// setProfiles installs the built-in profiles and the configured ones. Instructions
+// given in the configuration become the profile's built-in template.
+func (e *Engine) setProfiles(configured []config.PromptProfileConfig) error {
+	e.profiles = make(map[string]Profile, len(builtinProfiles)+len(configured))
+	for _, profile := range builtinProfiles {
+		e.profiles[profile.Name] = profile
+	}
+
+	for _, cfg := range configured {
+		if !profileNamePattern.MatchString(cfg.Name) {
+			return fmt.Errorf("invalid prompt profile name %q: use up to 32 lowercase letters, digits, '-' or '_'", cfg.Name)
+		}
+
+		profile := e.profiles[cfg.Name]
+		profile.Name = cfg.Name
+		if cfg.Description != "" {
+			profile.Description = cfg.Description
+		}
+		if cfg.AllowedCommands != nil {
+			profile.AllowedCommands = cfg.AllowedCommands
+		}
+		if cfg.DeniedCommands != nil {
+			profile.DeniedCommands = cfg.DeniedCommands
+		}
+
+		name := ProfileTemplate(cfg.Name)
+		if cfg.Instructions != "" {
+			tmpl, err := parse(name, cfg.Instructions)
+			if err != nil {
+				return fmt.Errorf("invalid instructions for prompt profile %s: %w", cfg.Name, err)
+			}
+			e.builtin[name] = &promptTemplate{tmpl: tmpl, text: cfg.Instructions}
+		} else if _, err := e.lookup(name); err != nil {
+			return fmt.Errorf("prompt profile %s has no instructions: set them in the configuration or add %s%s", cfg.Name, name, templateExt)
+		}
+		e.profiles[cfg.Name] = profile
+	}
+	return nil
+}
+
+// Profile returns a profile by name. The empty name is the default behaviour, with no
+// extra instructions and every command allowed.
+func (e *Engine) Profile(name string) (Profile, error) {
+	if name == "" {
+		return Profile{}, nil
+	}
+	e.profilesMutex.RLock()
+	profile, ok := e.profiles[name]
+	e.profilesMutex.RUnlock()
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: unknown prompt profile %q", appErrors.ErrBadRequest, name)
+	}
+	return profile, nil
+}
+
+// Profiles returns the available profiles sorted by name
+func (e *Engine) Profiles() []Profile {
+	e.profilesMutex.RLock()
+	defer e.profilesMutex.RUnlock()
+	profiles := make([]Profile, 0, len(e.profiles))
+	for _, profile := range e.profiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
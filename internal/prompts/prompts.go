@@ -0,0 +1,346 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/logger"
+)
+
+// Names of the templates the chat pipeline renders
+const (
+	SystemJSON       = "system_json"
+	SystemJSONStrict = "system_json_strict" // For models prone to wrapping the JSON in prose
+	SystemTools      = "system_tools"
+	SystemPlain      = "system_plain"
+	Reformat         = "reformat"
+)
+
+// templateExt is the file extension of prompt templates
+const templateExt = ".tmpl"
+
+// reloadInterval is how often the template directory is checked for changes
+const reloadInterval = time.Second
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Vars are the values available to prompt templates
+type Vars struct {
+	DebuggerBackend string `json:"debuggerBackend"` // e.g. "GDB"
+	Language        string `json:"language"`        // Language of the program being debugged, if known
+	Executable      string `json:"executable"`      // File name of the program being debugged
+	Envelope        string `json:"envelope"`        // Envelope mode (config.EnvelopeJSON, EnvelopeSchema, EnvelopeTools or EnvelopePlain)
+	Provider        string `json:"provider"`
+	Model           string `json:"model"`
+	Profile         string `json:"profile"` // Prompt profile whose instructions follow the system prompt
+}
+
+// Info describes a template and where it was loaded from
+type Info struct {
+	Name     string    `json:"name"`
+	Source   string    `json:"source"` // "builtin" or the template file's path
+	Modified time.Time `json:"modified,omitempty"`
+	Error    string    `json:"error,omitempty"` // Why the file's latest version was not loaded
+	Text     string    `json:"text"`
+}
+
+// promptTemplate is a parsed template and the file it came from
+type promptTemplate struct {
+	tmpl    *template.Template
+	text    string
+	path    string
+	modTime time.Time
+	size    int64
+	err     error
+}
+
+// Engine renders the prompts sent to the LLM. The built-in templates can be overridden,
+// and new ones added, by <name>.tmpl files in the prompts directory. The directory is
+// rechecked at most once a second, so edits apply to the next request without a restart;
+// a file that fails to parse is reported and its last good version kept.
+type Engine struct {
+	dir       string
+	builtin   map[string]*promptTemplate
+	overrides map[string]*promptTemplate
+	profiles  map[string]Profile
+	checked   time.Time
+	mutex     sync.Mutex
+
+	profilesMutex sync.RWMutex // Guards profiles, which Reload replaces
+}
+
+var (
+	builtinOnce   sync.Once
+	builtinEngine *Engine
+)
+
+// NewEngine creates the prompt engine for the configured prompts directory and profiles
+func NewEngine(cfg *config.Config) (*Engine, error) {
+	e, err := Open(cfg.Prompts.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.setProfiles(cfg.Prompts.Profiles); err != nil {
+		return nil, err
+	}
+	if _, err := e.Profile(cfg.Prompts.DefaultProfile); err != nil {
+		return nil, fmt.Errorf("invalid prompts.default_profile: %w", err)
+	}
+	return e, nil
+}
+
+// Reload applies changed prompts settings: the templates directory, the profiles and the
+// default profile. Nothing changes if they are invalid.
+func (e *Engine) Reload(cfg config.PromptsConfig) error {
+	next, err := NewEngine(&config.Config{Prompts: cfg})
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	e.dir, e.builtin, e.overrides, e.checked = next.dir, next.builtin, next.overrides, next.checked
+	e.mutex.Unlock()
+	e.profilesMutex.Lock()
+	e.profiles = next.profiles
+	e.profilesMutex.Unlock()
+	return nil
+}
+
+// Open creates a prompt engine whose templates can be overridden by files in dir. An
+// empty dir, or one that does not exist, uses only the built-in templates. Unlike later
+// reloads, a template file that fails to parse here is an error. Only the built-in
+// profiles are available.
+func Open(dir string) (*Engine, error) {
+	e := &Engine{dir: dir, builtin: loadBuiltin(), overrides: make(map[string]*promptTemplate)}
+	e.mutex.Lock()
+	err := e.reload()
+	for _, t := range e.overrides {
+		if t.err != nil && err == nil {
+			err = t.err
+		}
+	}
+	e.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return e, e.setProfiles(nil)
+}
+
+// Builtin returns an engine with only the built-in templates
+func Builtin() *Engine {
+	builtinOnce.Do(func() {
+		builtinEngine = &Engine{builtin: loadBuiltin(), overrides: make(map[string]*promptTemplate)}
+		builtinEngine.setProfiles(nil)
+	})
+	return builtinEngine
+}
+
+// loadBuiltin parses the embedded default templates
+func loadBuiltin() map[string]*promptTemplate {
+	entries, err := defaults.ReadDir("defaults")
+	if err != nil {
+		panic(err)
+	}
+	builtin := make(map[string]*promptTemplate, len(entries))
+	for _, entry := range entries {
+		data, err := defaults.ReadFile("defaults/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(entry.Name(), templateExt)
+		builtin[name] = &promptTemplate{tmpl: template.Must(parse(name, string(data))), text: string(data)}
+	}
+	return builtin
+}
+
+// parse parses a template. Referencing an unknown variable is an error when rendering.
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Render renders a template
+func (e *Engine) Render(name string, vars Vars) (string, error) {
+	t, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// RenderText renders template text that has not been saved, e.g. to preview an edit
+func (e *Engine) RenderText(text string, vars Vars) (string, error) {
+	tmpl, err := parse("preview", text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", appErrors.ErrBadRequest, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("%w: %v", appErrors.ErrBadRequest, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// System renders the system prompt for an envelope mode, followed by the instructions of
+// the profile in vars. A custom template that fails to render is logged and the built-in
+// one used instead, so a bad edit cannot stop chat.
+func (e *Engine) System(vars Vars) string {
+	name := SystemName(vars.Envelope)
+	prompt, err := e.Render(name, vars)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("template", name).Msg("Falling back to the built-in prompt")
+		var buf bytes.Buffer
+		e.builtin[name].tmpl.Execute(&buf, vars)
+		prompt = strings.TrimSpace(buf.String())
+	}
+
+	if vars.Profile == "" {
+		return prompt
+	}
+	instructions, err := e.Render(ProfileTemplate(vars.Profile), vars)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("profile", vars.Profile).Msg("Leaving out the prompt profile's instructions")
+		return prompt
+	}
+	return prompt + "\n\n" + instructions
+}
+
+// SystemName returns the name of the system prompt template for an envelope mode
+func SystemName(envelope string) string {
+	switch envelope {
+	case config.EnvelopeTools:
+		return SystemTools
+	case config.EnvelopePlain:
+		return SystemPlain
+	default:
+		return SystemJSON
+	}
+}
+
+// Templates describes every template, sorted by name
+func (e *Engine) Templates() []Info {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.maybeReload()
+
+	names := make(map[string]bool)
+	for name := range e.builtin {
+		names[name] = true
+	}
+	for name := range e.overrides {
+		names[name] = true
+	}
+
+	infos := make([]Info, 0, len(names))
+	for name := range names {
+		info := Info{Name: name, Source: "builtin"}
+		if t, ok := e.builtin[name]; ok {
+			info.Text = t.text
+		}
+		if t, ok := e.overrides[name]; ok {
+			if t.tmpl != nil {
+				info.Source, info.Modified, info.Text = t.path, t.modTime, t.text
+			}
+			if t.err != nil {
+				info.Error = t.err.Error()
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// lookup returns the current version of a template
+func (e *Engine) lookup(name string) (*promptTemplate, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.maybeReload()
+
+	if t, ok := e.overrides[name]; ok && t.tmpl != nil {
+		return t, nil
+	}
+	if t, ok := e.builtin[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("prompt template %q: %w", name, appErrors.ErrNotFound)
+}
+
+// maybeReload reloads the directory if it has not been checked recently; the caller
+// holds the mutex
+func (e *Engine) maybeReload() {
+	if time.Since(e.checked) < reloadInterval {
+		return
+	}
+	if err := e.reload(); err != nil {
+		logger.Log.Warn().Err(err).Str("directory", e.dir).Msg("Failed to reload prompt templates")
+	}
+}
+
+// reload picks up template files that were added, changed or removed since the last
+// check; the caller holds the mutex
+func (e *Engine) reload() error {
+	e.checked = time.Now()
+	if e.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(e.dir, "*"+templateExt))
+	if err != nil {
+		return fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), templateExt)
+		seen[name] = true
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		current := e.overrides[name]
+		if current != nil && current.modTime.Equal(stat.ModTime()) && current.size == stat.Size() {
+			continue
+		}
+
+		next := &promptTemplate{path: path, modTime: stat.ModTime(), size: stat.Size()}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			next.tmpl, err = parse(name, string(data))
+			next.text = string(data)
+		}
+		if err != nil {
+			next.err = fmt.Errorf("invalid prompt template %s: %w", path, err)
+			if current != nil && current.tmpl != nil {
+				// Keep serving the last version that parsed
+				next.tmpl, next.text = current.tmpl, current.text
+			}
+			logger.Log.Warn().Err(next.err).Msg("Keeping the previous prompt template")
+		} else if current != nil {
+			logger.Log.Info().Str("template", path).Msg("Reloaded prompt template")
+		}
+		e.overrides[name] = next
+	}
+
+	for name := range e.overrides {
+		if !seen[name] {
+			delete(e.overrides, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestBuiltinSystemPrompts(t *testing.T) {
+	engine := Builtin()
+
+	prompt := engine.System(Vars{})
+	assert.Equal(t, `You are an AI assistant that helps with programming and debugging.
+
+YOU MUST RESPOND IN VALID JSON FORMAT according to this structure:
+{
+  "text": "Your explanation or message to the user",
+  "gdbCommands": ["command1", "command2", "..."],
+  "waitForOutput": true/false
+}
+
+To collect data without stopping at every hit, you may add "breakpoints": [{"location": "file.c:42", "condition": "len > 64", "ignoreCount": 0, "commands": ["silent", "print len", "continue"]}]; they are set before gdbCommands run. Before a continue that could destroy the state you are studying, run "checkpoint"; "restart N" rolls the program back to checkpoint N.
+
+Do not include any text outside the JSON structure. Your entire response must be a single JSON object.`, prompt)
+
+	prompt = engine.System(Vars{DebuggerBackend: "GDB", Language: "C++", Envelope: config.EnvelopeTools})
+	assert.Contains(t, prompt, "debugging with GDB.\nThe program being debugged is written in C++.\n\nAlways reply by calling the respond tool.")
+
+	prompt = engine.System(Vars{Envelope: config.EnvelopePlain})
+	assert.Contains(t, prompt, "```gdb\nbreak main\nrun\n```")
+	assert.NotContains(t, prompt, "extractSuggestedCommands", "template comments are not rendered")
+
+	_, err := engine.Render("missing", Vars{})
+	assert.Error(t, err)
+}
+
+func TestOverridesReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, SystemJSON+templateExt)
+	require.NoError(t, os.WriteFile(path, []byte("Debugging {{.Executable}} with {{.DebuggerBackend}}"), 0644))
+
+	engine, err := Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "Debugging crash with GDB", engine.System(Vars{DebuggerBackend: "GDB", Executable: "crash"}))
+
+	// A bad edit keeps the last good version and is reported
+	require.NoError(t, os.WriteFile(path, []byte("Debugging {{.Executable"), 0644))
+	engine.checked = time.Time{}
+	assert.Equal(t, "Debugging crash with GDB", engine.System(Vars{DebuggerBackend: "GDB", Executable: "crash"}))
+	infos := engine.Templates()
+	for _, info := range infos {
+		if info.Name == SystemJSON {
+			assert.Equal(t, path, info.Source)
+			assert.NotEmpty(t, info.Error)
+		}
+	}
+
+	// Removing the file restores the built-in template
+	require.NoError(t, os.Remove(path))
+	engine.checked = time.Time{}
+	assert.Contains(t, engine.System(Vars{}), "YOU MUST RESPOND IN VALID JSON FORMAT")
+}
+
+func TestOpenRejectsInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Reformat+templateExt), []byte("{{if}}"), 0644))
+
+	_, err := Open(dir)
+	assert.Error(t, err)
+}
+
+func TestRenderTextRejectsUnknownVariables(t *testing.T) {
+	_, err := Builtin().RenderText("{{.Binary}}", Vars{})
+	assert.Error(t, err)
+
+	prompt, err := Builtin().RenderText("{{.Language}} on {{.Provider}}", Vars{Language: "C", Provider: "ollama"})
+	require.NoError(t, err)
+	assert.Equal(t, "C on ollama", prompt)
+}
+
+func TestProfiles(t *testing.T) {
+	engine := Builtin()
+
+	prompt := engine.System(Vars{Profile: "teaching"})
+	assert.Contains(t, prompt, "single JSON object.\n\nThe user is learning to debug.")
+
+	triage, err := engine.Profile("triage")
+	require.NoError(t, err)
+	assert.True(t, triage.Allows("bt full"))
+	assert.True(t, triage.Allows("info registers"))
+	assert.False(t, triage.Allows("continue"))
+	assert.False(t, triage.Allows("set var x = 1"))
+	assert.True(t, triage.Allows("setup"), "rules match whole words")
+	assert.True(t, triage.Allows("break parse.c:42\ncommands\nsilent\nprint len\nend"))
+	assert.False(t, triage.Allows("break parse.c:42\ncommands\nprint len\ncontinue\nend"), "every line of a command list is checked")
+
+	_, err = engine.Profile("chatty")
+	assert.Error(t, err)
+}
+
+func TestConfiguredProfiles(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Prompts.DefaultProfile = "kernel"
+	cfg.Prompts.Profiles = []config.PromptProfileConfig{
+		{Name: "kernel", Instructions: "You are debugging {{.Executable}}, a kernel module.", AllowedCommands: []string{"info", "x", "bt"}},
+		{Name: "triage", DeniedCommands: []string{}},
+	}
+	engine, err := NewEngine(cfg)
+	require.NoError(t, err)
+
+	kernel, err := engine.Profile("kernel")
+	require.NoError(t, err)
+	assert.True(t, kernel.Allows("info frame"))
+	assert.False(t, kernel.Allows("print $rax"))
+	assert.Contains(t, engine.System(Vars{Profile: "kernel", Executable: "e1000.ko"}), "You are debugging e1000.ko, a kernel module.")
+
+	triage, err := engine.Profile("triage")
+	require.NoError(t, err)
+	assert.True(t, triage.Allows("continue"), "configuration replaces the built-in command rules")
+	assert.Equal(t, "Terse crash triage that only inspects the program's state", triage.Description)
+
+	cfg.Prompts.Profiles = []config.PromptProfileConfig{{Name: "fuzzing"}}
+	_, err = NewEngine(cfg)
+	assert.Error(t, err, "a new profile needs instructions")
+}
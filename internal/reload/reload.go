@@ -0,0 +1,172 @@
+// Package reload applies changes to the configuration file while the server runs. On
+// SIGHUP, or when the file's modification time changes, the file is read again and the
+// changed settings are handed to the components registered for them, e.g. the chat
+// envelope or the prompt templates. Every reload is logged with what changed, and
+// settings no component can apply live are reported as needing a restart.
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logger"
+)
+
+// Statuses of a changed setting
+const (
+	StatusApplied         = "applied"
+	StatusRestartRequired = "restart_required" // No component applies it live
+	StatusFailed          = "failed"           // Its component rejected it; the old value stays
+)
+
+// Change is a changed setting and what became of it
+type Change struct {
+	config.Change
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// target is a component applying the settings under some keys
+type target struct {
+	prefixes []string
+	apply    func(*config.Config) error
+}
+
+// matches reports whether a setting is under one of the target's keys
+func (t target) matches(key string) bool {
+	for _, prefix := range t.prefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher reloads the configuration file and applies the changes
+type Watcher struct {
+	current *config.Config
+	targets []target
+	mutex   sync.Mutex // Serializes reloads
+
+	load func(path string) (*config.Config, error)
+}
+
+// New creates a watcher of the file cfg was loaded from
+func New(cfg *config.Config) *Watcher {
+	return &Watcher{current: cfg, load: config.LoadConfig}
+}
+
+// Register has apply called with the reloaded configuration when a setting under one of
+// the keys changes, e.g. "chat.cache" for chat.cache.ttl. A component rejecting the
+// configuration returns an error and keeps its settings.
+func (w *Watcher) Register(apply func(*config.Config) error, keys ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.targets = append(w.targets, target{prefixes: keys, apply: apply})
+}
+
+// Reload reads the configuration file again and applies the changed settings, returning
+// them. Values given on the command line still take precedence over the file's.
+func (w *Watcher) Reload() ([]Change, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.current.File == "" {
+		return nil, fmt.Errorf("no configuration file to reload")
+	}
+	next, err := w.load(w.current.File)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("event", "config.reload").Str("file", w.current.File).Msg("Configuration not reloaded")
+		return nil, err
+	}
+	next.Overrides = w.current.Overrides
+
+	diff := config.Diff(w.current, next)
+	changes := make([]Change, len(diff))
+	for i, change := range diff {
+		changes[i] = Change{Change: change, Status: StatusRestartRequired}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	for _, target := range w.targets {
+		var matched []int
+		for i, change := range changes {
+			if target.matches(change.Key) {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		err := target.apply(next)
+		for _, i := range matched {
+			if err != nil {
+				changes[i].Status, changes[i].Error = StatusFailed, err.Error()
+			} else if changes[i].Status != StatusFailed {
+				changes[i].Status = StatusApplied
+			}
+		}
+	}
+	w.current = next
+
+	logger.Log.Info().
+		Str("event", "config.reload").
+		Str("file", next.File).
+		Interface("changes", changes).
+		Msgf("Configuration reloaded: %d settings changed", len(changes))
+	return changes, nil
+}
+
+// Start reloads the configuration on SIGHUP and, every interval unless it is 0, when the
+// file's modification time changes, until ctx is done
+func (w *Watcher) Start(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	modified := w.modified()
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		tick = ticker.C
+		defer ticker.Stop()
+	}
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			modified = w.modified()
+			w.Reload()
+		case <-tick:
+			if m := w.modified(); !m.Equal(modified) {
+				modified = m
+				w.Reload()
+			}
+		}
+	}
+}
+
+// modified returns the configuration file's modification time, or zero if it has none
+func (w *Watcher) modified() time.Time {
+	w.mutex.Lock()
+	file := w.current.File
+	w.mutex.Unlock()
+	if file == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,72 @@
+package reload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// writeConfig writes a config file setting the chat cache TTL, the queue limit and the
+// server's port
+func writeConfig(t *testing.T, path, ttl string, maxQueued, port int) {
+	data := fmt.Sprintf("server:\n  port: %d\nchat:\n  cache:\n    ttl: %s\n  queue:\n    max_queued: %d\n", port, ttl, maxQueued)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "1h", 10, 8080)
+	cfg, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	cfg.Overrides.Model = "from-flag"
+
+	watcher := New(cfg)
+	var cache, queue *config.Config
+	watcher.Register(func(cfg *config.Config) error {
+		cache = cfg
+		return nil
+	}, "chat.cache")
+	watcher.Register(func(cfg *config.Config) error {
+		queue = cfg
+		return errors.New("queue limits rejected")
+	}, "chat.queue")
+
+	changes, err := watcher.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Nil(t, cache, "nothing changed")
+
+	writeConfig(t, path, "2h", 20, 9090)
+	changes, err = watcher.Reload()
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, Change{Change: config.Change{Key: "chat.cache.ttl", Old: "1h0m0s", New: "2h0m0s"}, Status: StatusApplied}, changes[0])
+	assert.Equal(t, Change{Change: config.Change{Key: "chat.queue.max_queued", Old: "10", New: "20"}, Status: StatusFailed, Error: "queue limits rejected"}, changes[1])
+	assert.Equal(t, Change{Change: config.Change{Key: "server.port", Old: "8080", New: "9090"}, Status: StatusRestartRequired}, changes[2])
+	require.NotNil(t, cache)
+	assert.Equal(t, "from-flag", cache.Overrides.Model, "command-line values survive reloads")
+	assert.NotNil(t, queue)
+
+	// Later reloads compare with the last configuration read
+	cache = nil
+	changes, err = watcher.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Nil(t, cache)
+
+	// A broken file changes nothing
+	require.NoError(t, os.WriteFile(path, []byte("chat: [broken"), 0644))
+	_, err = watcher.Reload()
+	assert.Error(t, err)
+}
+
+func TestReloadWithoutFile(t *testing.T) {
+	_, err := New(&config.Config{}).Reload()
+	assert.Error(t, err)
+}
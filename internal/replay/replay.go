@@ -0,0 +1,196 @@
+// Package replay re-runs the GDB commands recorded in a session log against a fresh GDB,
+// so a debugging session can be reproduced without the LLM, e.g. to attach to a bug
+// report about the tool. Commands the assistant ran are replayed from the log rather
+// than asked for again, which keeps the replay deterministic.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/transcript"
+)
+
+// Step kinds
+const (
+	StepCommand = "command" // A GDB command
+	StepInput   = "input"   // Input typed to the debugged program
+)
+
+// Command sources, as recorded in gdb.command.source
+const (
+	SourceUser = "user"
+	SourceLLM  = "llm"
+)
+
+// Options control which recorded steps are replayed and how
+type Options struct {
+	UserOnly bool          // Skip the commands the assistant ran
+	Timeout  time.Duration // How long to collect each command's output; 2s by default
+	Limit    int           // Replay at most this many steps; 0 for all
+}
+
+// Step is a recorded action to replay
+type Step struct {
+	Kind     string    `json:"kind"`
+	Text     string    `json:"text"`             // The command, or the program input
+	Source   string    `json:"source,omitempty"` // Who ran a command: "user" or "llm"
+	Question string    `json:"question,omitempty"`
+	Recorded time.Time `json:"recorded"`
+}
+
+// Executor runs commands against GDB; *gdb.GDBService implements it
+type Executor interface {
+	ExecuteCommandWithOutput(command string, timeoutSeconds int) (string, error)
+	WriteProgramInput(input string) error
+}
+
+// StepResult is the outcome of replaying a step
+type StepResult struct {
+	Step
+	Output   string        `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Report is the outcome of replaying a session
+type Report struct {
+	Session    string       `json:"session"`
+	Executable string       `json:"executable,omitempty"`
+	Started    time.Time    `json:"started"`
+	Skipped    int          `json:"skipped"` // Recorded steps left out by the options
+	Steps      []StepResult `json:"steps"`
+	Failed     int          `json:"failed"`
+	Cancelled  bool         `json:"cancelled,omitempty"`
+}
+
+// Steps extracts the steps to replay from a session log, in the order they were recorded,
+// and counts those left out by the options. Each command carries the user's question it
+// followed, if any.
+func Steps(session *transcript.Session, opts Options) (steps []Step, skipped int) {
+	var question string
+	for _, entry := range session.Entries {
+		switch entry.Type {
+		case transcript.EventUserInput:
+			question = strings.TrimSpace(entry.String("user.message"))
+
+		case transcript.EventGDBCommand:
+			command, ok := transcript.CleanCommand(entry.String("gdb.command"))
+			if !ok {
+				continue
+			}
+			source := entry.String("gdb.command.source")
+			if opts.UserOnly && source == SourceLLM {
+				skipped++
+				continue
+			}
+			steps = append(steps, Step{Kind: StepCommand, Text: command, Source: source, Question: question, Recorded: entry.Timestamp})
+
+		case transcript.EventInput:
+			if input := entry.String("program.input"); input != "" {
+				steps = append(steps, Step{Kind: StepInput, Text: input, Question: question, Recorded: entry.Timestamp})
+			}
+		}
+	}
+
+	if opts.Limit > 0 && len(steps) > opts.Limit {
+		skipped += len(steps) - opts.Limit
+		steps = steps[:opts.Limit]
+	}
+	return steps, skipped
+}
+
+// Run replays a session's steps with the executor. It stops early, marking the report
+// cancelled, when ctx is done. Failed steps are recorded and the replay continues, as
+// the original session did.
+func Run(ctx context.Context, exec Executor, session *transcript.Session, opts Options) *Report {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	// ExecuteCommandWithOutput collects output for a whole number of seconds, at least 2
+	seconds := max(int((timeout+time.Second-1)/time.Second), 2)
+
+	steps, skipped := Steps(session, opts)
+	report := &Report{
+		Session: session.ID,
+		Started: time.Now(),
+		Skipped: skipped,
+		Steps:   make([]StepResult, 0, len(steps)),
+	}
+
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			report.Cancelled = true
+			break
+		}
+
+		result := StepResult{Step: step}
+		start := time.Now()
+		var err error
+		switch step.Kind {
+		case StepInput:
+			err = exec.WriteProgramInput(step.Text)
+		default:
+			result.Output, err = exec.ExecuteCommandWithOutput(step.Text, seconds)
+		}
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	return report
+}
+
+// WriteText writes the report as a readable transcript of the replay
+func (r *Report) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Replay of session %s", r.Session)
+	if r.Executable != "" {
+		fmt.Fprintf(&sb, " (%s)", r.Executable)
+	}
+	fmt.Fprintf(&sb, "\n%d steps replayed, %d failed, %d skipped\n", len(r.Steps), r.Failed, r.Skipped)
+
+	question := ""
+	for i, step := range r.Steps {
+		if step.Question != "" && step.Question != question {
+			question = step.Question
+			fmt.Fprintf(&sb, "\n# Question: %s\n", question)
+		}
+
+		switch {
+		case step.Kind == StepInput:
+			fmt.Fprintf(&sb, "\n[%d] program input: %q\n", i+1, step.Text)
+		case step.Source == SourceLLM:
+			fmt.Fprintf(&sb, "\n[%d] (gdb) %s    # run by the assistant\n", i+1, step.Text)
+		default:
+			fmt.Fprintf(&sb, "\n[%d] (gdb) %s\n", i+1, step.Text)
+		}
+		if step.Output != "" {
+			sb.WriteString(strings.TrimRight(step.Output, "\n"))
+			sb.WriteString("\n")
+		}
+		if step.Error != "" {
+			fmt.Fprintf(&sb, "error: %s\n", step.Error)
+		}
+	}
+
+	if r.Cancelled {
+		sb.WriteString("\nReplay cancelled before all steps ran\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,73 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/transcript"
+)
+
+const sessionLog = `{"timestamp":"2024-05-01T10:00:00Z","event.type":"session.metadata","session.filename":"crash"}
+{"timestamp":"2024-05-01T10:00:01Z","event.type":"gdb.command","gdb.command":"break main","gdb.command.source":"user"}
+{"timestamp":"2024-05-01T10:00:02Z","event.type":"program.input","program.input":"hello\n"}
+{"timestamp":"2024-05-01T10:00:03Z","event.type":"user.input","user.message":"Why does it crash?"}
+{"timestamp":"2024-05-01T10:00:04Z","event.type":"gdb.command","gdb.command":"bt","gdb.command.source":"llm"}
+{"timestamp":"2024-05-01T10:00:05Z","event.type":"gdb.command","gdb.command":"\u0003","gdb.command.source":"user"}
+{"timestamp":"2024-05-01T10:00:06Z","event.type":"gdb.command","gdb.command":"info frame","gdb.command.source":"user"}
+{"timestamp":"2024-05-01T10:00:07Z","event.type":"gdb.command","gdb.command":"quit","gdb.command.source":"user"}
+`
+
+// fakeExecutor records what it was asked to run
+type fakeExecutor struct {
+	ran    []string
+	failOn string
+}
+
+func (f *fakeExecutor) ExecuteCommandWithOutput(command string, timeoutSeconds int) (string, error) {
+	f.ran = append(f.ran, command)
+	if command == f.failOn {
+		return "", errors.New("No frame selected.")
+	}
+	return "output of " + command, nil
+}
+
+func (f *fakeExecutor) WriteProgramInput(input string) error {
+	f.ran = append(f.ran, "input:"+input)
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	session, err := transcript.ReadSession("s1", strings.NewReader(sessionLog))
+	require.NoError(t, err)
+
+	steps, skipped := Steps(session, Options{})
+	assert.Equal(t, 0, skipped)
+	require.Len(t, steps, 4)
+	assert.Equal(t, Step{Kind: StepCommand, Text: "bt", Source: SourceLLM, Question: "Why does it crash?", Recorded: steps[2].Recorded}, steps[2])
+
+	_, skipped = Steps(session, Options{UserOnly: true, Limit: 2})
+	assert.Equal(t, 2, skipped)
+
+	exec := &fakeExecutor{failOn: "info frame"}
+	report := Run(context.Background(), exec, session, Options{})
+	assert.Equal(t, []string{"break main", "input:hello\n", "bt", "info frame"}, exec.ran)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "output of bt", report.Steps[2].Output)
+
+	var text strings.Builder
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "# Question: Why does it crash?")
+	assert.Contains(t, text.String(), "(gdb) bt    # run by the assistant")
+	assert.Contains(t, text.String(), "error: No frame selected.")
+
+	// A cancelled replay stops before the next step
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report = Run(ctx, &fakeExecutor{}, session, Options{})
+	assert.True(t, report.Cancelled)
+	assert.Empty(t, report.Steps)
+}
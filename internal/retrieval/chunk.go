@@ -0,0 +1,192 @@
+package retrieval
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// LogSource is the Source of chunks of the session log
+const LogSource = "session log"
+
+// maxLineLength caps the bytes of a line that are embedded, as GDB can print very long ones
+const maxLineLength = 400
+
+// binarySniff is how much of a file is checked for NUL bytes to tell binaries from text
+const binarySniff = 8000
+
+// processingLine matches the chat processor's own steps, which it logs as terminal output
+// but which say nothing about the program
+var processingLine = regexp.MustCompile(`^(?:\[\d\d:\d\d:\d\d\.\d{3}\] |=== |Executing command \d+/\d+: |Command output \(\d+ chars\): |Command failed: |\(LLM-Capture\) )`)
+
+// logState is how far the session log has been indexed
+type logState struct {
+	offset int64 // Bytes
+	lines  int   // Entries
+}
+
+// scanSources lists the text files under dir, up to max_files and skipping hidden
+// directories and files over max_file_size, and chunks those that changed since previous
+func scanSources(dir string, previous map[string]fileState, cfg config.RetrievalConfig) (map[string]fileState, []Chunk, error) {
+	files := make(map[string]fileState)
+	if dir == "" {
+		return files, nil, nil
+	}
+	var pending []Chunk
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if cfg.MaxFiles > 0 && len(files) >= cfg.MaxFiles {
+			return filepath.SkipAll
+		}
+		info, err := entry.Info()
+		if err != nil || (cfg.MaxFileSize > 0 && info.Size() > cfg.MaxFileSize) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if old, ok := previous[rel]; ok && old == state {
+			files[rel] = state
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data[:min(len(data), binarySniff)], 0) >= 0 {
+			return nil
+		}
+		files[rel] = state
+		lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		pending = append(pending, chunkLines(rel, lines, nil, cfg.ChunkLines, cfg.ChunkOverlap)...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, pending, nil
+}
+
+// readLog chunks the entries of the session log written after state, returning the new
+// state and whether the log was replaced, making the chunks indexed before stale. Only
+// complete entries are read.
+func readLog(path string, state logState, cfg config.RetrievalConfig) ([]Chunk, logState, bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, state, false, nil
+	}
+	if err != nil {
+		return nil, state, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, state, false, err
+	}
+	reset := info.Size() < state.offset
+	if reset {
+		state = logState{}
+	}
+	if _, err := file.Seek(state.offset, io.SeekStart); err != nil {
+		return nil, state, false, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, state, false, err
+	}
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+
+	var lines []string
+	var numbers []int
+	if len(data) > 0 {
+		for _, entry := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+			state.lines++
+			for _, line := range logEntryText(entry) {
+				lines = append(lines, line)
+				numbers = append(numbers, state.lines)
+			}
+		}
+	}
+	state.offset += int64(len(data))
+	return chunkLines(LogSource, lines, numbers, cfg.ChunkLines, 0), state, reset, nil
+}
+
+// logEntryText returns the lines of a log entry worth retrieving: the user's messages,
+// GDB's commands and GDB's output
+func logEntryText(entry []byte) []string {
+	var fields map[string]interface{}
+	if json.Unmarshal(entry, &fields) != nil {
+		return nil
+	}
+	text := func(key string) string {
+		value, _ := fields[key].(string)
+		return value
+	}
+	switch fields["event.type"] {
+	case "user.input":
+		return []string{"user: " + text("user.message")}
+	case "gdb.command":
+		return []string{"(gdb) " + text("gdb.command")}
+	case "gdb.output":
+		output := text("gdb.output")
+		if output == "" || processingLine.MatchString(output) {
+			return nil
+		}
+		return strings.Split(output, "\n")
+	}
+	return nil
+}
+
+// chunkLines splits lines into chunks of size lines, consecutive chunks sharing overlap
+// lines. numbers gives each line's number, or is nil to number them from 1. Blank chunks
+// are left out.
+func chunkLines(source string, lines []string, numbers []int, size, overlap int) []Chunk {
+	number := func(i int) int {
+		if numbers == nil {
+			return i + 1
+		}
+		return numbers[i]
+	}
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += size - overlap {
+		end := min(start+size, len(lines))
+		span := make([]string, end-start)
+		for i, line := range lines[start:end] {
+			if len(line) > maxLineLength {
+				line = strings.ToValidUTF8(line[:maxLineLength], "")
+			}
+			span[i] = line
+		}
+		if text := strings.Join(span, "\n"); strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{Source: source, StartLine: number(start), EndLine: number(end - 1), Text: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
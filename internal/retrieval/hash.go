@@ -0,0 +1,90 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// hashDimensions is the length of the hash embedder's vectors
+const hashDimensions = 1024
+
+// word matches the words and identifiers of a text
+var word = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*|[0-9]+`)
+
+// HashEmbedder embeds texts locally, without a model: each word, and each part of a
+// snake_case or camelCase identifier, adds to a dimension picked by its hash. Texts are
+// close when they share words, which finds the code and output naming what a message
+// asks about, though not texts that only mean the same.
+type HashEmbedder struct{}
+
+// NewHashEmbedder creates a hash embedder
+func NewHashEmbedder() *HashEmbedder {
+	return &HashEmbedder{}
+}
+
+// Model names the embedder and its vectors' length
+func (*HashEmbedder) Model() string {
+	return fmt.Sprintf("hash/%d", hashDimensions)
+}
+
+// Embed returns an embedding for each text; it needs no API key
+func (*HashEmbedder) Embed(_ context.Context, _ string, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		counts := make(map[string]int)
+		for _, token := range word.FindAllString(text, -1) {
+			for _, term := range terms(token) {
+				counts[term]++
+			}
+		}
+		vector := make([]float32, hashDimensions)
+		for term, count := range counts {
+			h := fnv.New32a()
+			h.Write([]byte(term))
+			sum := h.Sum32()
+			// The hash's top bit picks the sign, so colliding terms tend to cancel out
+			weight := float32(1 + math.Log(float64(count)))
+			if sum&(1<<31) != 0 {
+				weight = -weight
+			}
+			vector[sum%hashDimensions] += weight
+		}
+		embeddings[i] = normalize(vector)
+	}
+	return embeddings, nil
+}
+
+// terms returns a token, lowercased, and the parts of it when it is an identifier made of
+// several words
+func terms(token string) []string {
+	lower := strings.ToLower(token)
+	if len(lower) < 2 {
+		return nil
+	}
+	parts := []string{lower}
+	var part []rune
+	flush := func() {
+		if len(part) > 1 && len(part) < len(token) {
+			parts = append(parts, strings.ToLower(string(part)))
+		}
+		part = part[:0]
+	}
+	runes := []rune(token)
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			flush()
+		}
+		part = append(part, r)
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,272 @@
+// Package retrieval finds the parts of a debugging session most relevant to a chat
+// message, so large codebases need not be attached by hand: it splits the session's
+// uploaded sources and log into chunks of lines, embeds them, and returns the chunks
+// whose embeddings are closest to the message's. Each session's index is kept in memory
+// and brought up to date before it is searched, embedding only changed files and new log
+// entries.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/chat/providers"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// maxIndexes caps the sessions indexed at once; the least recently searched index is
+// dropped to make room
+const maxIndexes = 32
+
+// Embedder turns texts into embeddings, vectors that are close for related texts
+type Embedder interface {
+	// Model names the embeddings, which are only comparable with the same model's
+	Model() string
+	Embed(ctx context.Context, apiKey string, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder creates an embedder of a kind, config.EmbedderOpenAI or config.EmbedderHash,
+// for a model and server; empty ones select the embedder's defaults
+func NewEmbedder(kind, model, baseURL string, timeout time.Duration) Embedder {
+	if kind == config.EmbedderHash {
+		return NewHashEmbedder()
+	}
+	return providers.NewOpenAIEmbedder(&providers.ProviderConfig{
+		BaseURL:      baseURL,
+		DefaultModel: model,
+		Timeout:      timeout,
+	})
+}
+
+// Session is what is indexed for a debugging session
+type Session struct {
+	ID         string
+	SourcesDir string // Uploaded sources; "" when there are none
+	LogFile    string // The session log; "" to leave it out
+}
+
+// Chunk is a span of lines of a source file or of the session log
+type Chunk struct {
+	Source    string // Path of the file under the sources directory, or LogSource
+	StartLine int    // 1-based
+	EndLine   int
+	Text      string
+
+	vector []float32
+}
+
+// Result is a chunk found for a query and its cosine similarity to it
+type Result struct {
+	Chunk
+	Score float64
+}
+
+// Retriever indexes sessions and searches them
+type Retriever struct {
+	cfg      config.RetrievalConfig
+	embedder Embedder
+
+	mutex   sync.Mutex
+	indexes map[string]*index // By session ID
+}
+
+// New creates a retriever embedding with embedder
+func New(cfg config.RetrievalConfig, embedder Embedder) *Retriever {
+	return &Retriever{cfg: cfg, embedder: embedder, indexes: make(map[string]*index)}
+}
+
+// Retrieve brings the session's index up to date and returns the chunks most similar to
+// query, at most top_k and scoring at least min_score, best first. apiKey authenticates
+// with the embedder.
+func (r *Retriever) Retrieve(ctx context.Context, session Session, apiKey, query string) ([]Result, error) {
+	idx := r.index(session.ID)
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if err := idx.refresh(ctx, r.cfg, r.embedder, session, apiKey); err != nil {
+		return nil, fmt.Errorf("failed to index session %s: %w", session.ID, err)
+	}
+	vectors, err := r.embedder.Embed(ctx, apiKey, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("failed to embed the query: got %d embeddings", len(vectors))
+	}
+	return idx.search(normalize(vectors[0]), r.cfg.TopK, r.cfg.MinScore), nil
+}
+
+// Forget drops a session's index, e.g. when the session ends
+func (r *Retriever) Forget(sessionID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.indexes, sessionID)
+}
+
+// index returns a session's index, creating it and evicting the least recently used one
+// when there are too many
+func (r *Retriever) index(sessionID string) *index {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	idx, ok := r.indexes[sessionID]
+	if !ok {
+		if len(r.indexes) >= maxIndexes {
+			var oldest string
+			for id, candidate := range r.indexes {
+				if oldest == "" || candidate.lastUsed.Before(r.indexes[oldest].lastUsed) {
+					oldest = id
+				}
+			}
+			delete(r.indexes, oldest)
+		}
+		idx = newIndex()
+		r.indexes[sessionID] = idx
+	}
+	idx.lastUsed = time.Now()
+	return idx
+}
+
+// index holds the embedded chunks of one session
+type index struct {
+	mutex    sync.Mutex // Held while the index is refreshed and searched
+	lastUsed time.Time  // Guarded by the Retriever's mutex
+	model    string     // Embedder model of the chunks
+
+	files  map[string]fileState // Indexed source files, by path under the sources directory
+	chunks map[string][]Chunk   // By source
+	log    logState             // How much of the session log is indexed
+}
+
+// fileState tells whether a source file changed since it was indexed
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func newIndex() *index {
+	return &index{files: make(map[string]fileState), chunks: make(map[string][]Chunk)}
+}
+
+// refresh embeds the chunks of the source files that changed and of the log entries
+// written since the last refresh. Nothing changes when embedding fails, so the next
+// refresh tries again.
+func (idx *index) refresh(ctx context.Context, cfg config.RetrievalConfig, embedder Embedder, session Session, apiKey string) error {
+	if idx.model != embedder.Model() {
+		idx.model = embedder.Model()
+		idx.files = make(map[string]fileState)
+		idx.chunks = make(map[string][]Chunk)
+		idx.log = logState{}
+	}
+
+	files, pending, err := scanSources(session.SourcesDir, idx.files, cfg)
+	if err != nil {
+		return err
+	}
+	log, reset := idx.log, false
+	if session.LogFile != "" {
+		var chunks []Chunk
+		chunks, log, reset, err = readLog(session.LogFile, idx.log, cfg)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, chunks...)
+	}
+
+	if len(pending) > 0 {
+		texts := make([]string, len(pending))
+		for i, chunk := range pending {
+			texts[i] = chunk.Source + "\n" + chunk.Text
+		}
+		vectors, err := embedder.Embed(ctx, apiKey, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("got %d embeddings for %d chunks", len(vectors), len(texts))
+		}
+		for i := range pending {
+			pending[i].vector = normalize(vectors[i])
+		}
+	}
+
+	// Chunks of changed and removed files are replaced; the log's are added to
+	for path := range idx.files {
+		if state, ok := files[path]; !ok || state != idx.files[path] {
+			delete(idx.chunks, path)
+		}
+	}
+	if reset {
+		delete(idx.chunks, LogSource)
+	}
+	for _, chunk := range pending {
+		idx.chunks[chunk.Source] = append(idx.chunks[chunk.Source], chunk)
+	}
+	idx.files = files
+	idx.log = log
+	return nil
+}
+
+// search returns the topK chunks scoring at least minScore against a normalized query
+func (idx *index) search(query []float32, topK int, minScore float64) []Result {
+	var results []Result
+	for _, chunks := range idx.chunks {
+		for _, chunk := range chunks {
+			if score := dot(query, chunk.vector); score >= minScore {
+				results = append(results, Result{Chunk: chunk, Score: score})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Source != results[j].Source {
+			return results[i].Source < results[j].Source
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// Similarity returns the cosine similarity of two embeddings: 1 for the same direction,
+// 0 for unrelated ones or embeddings of different lengths
+func Similarity(a, b []float32) float64 {
+	return dot(normalize(a), normalize(b))
+}
+
+// normalize scales a vector to unit length, so dot products are cosine similarities
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// dot returns the dot product of two vectors, 0 when their lengths differ
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,112 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// countingEmbedder counts the texts it embeds
+type countingEmbedder struct {
+	HashEmbedder
+	embedded int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, apiKey string, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+	return e.HashEmbedder.Embed(ctx, apiKey, texts)
+}
+
+func appendLog(t *testing.T, path string, entries ...map[string]interface{}) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer file.Close()
+	for _, entry := range entries {
+		require.NoError(t, json.NewEncoder(file).Encode(entry))
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	dir := t.TempDir()
+	sources := filepath.Join(dir, "sources")
+	require.NoError(t, os.MkdirAll(filepath.Join(sources, "src"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sources, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sources, "src", "parse.c"), []byte(
+		"#include <string.h>\n\nint parse_header(char *buf) {\n  char name[8];\n  strcpy(name, buf);\n  return name[0];\n}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sources, "src", "util.c"), []byte(
+		"int add(int a, int b) {\n  return a + b;\n}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sources, "a.out"), []byte("\x7fELF\x00parse_header"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sources, ".git", "parse_header"), []byte("parse_header"), 0644))
+
+	logFile := filepath.Join(dir, "session.log")
+	appendLog(t, logFile,
+		map[string]interface{}{"event.type": "user.input", "user.message": "it crashes on long input"},
+		map[string]interface{}{"event.type": "gdb.command", "gdb.command": "run"},
+		map[string]interface{}{"event.type": "gdb.output", "gdb.output": "Program received signal SIGSEGV, Segmentation fault.\n0x0040 in parse_header (buf=0x7ffc) at src/parse.c:5"},
+		map[string]interface{}{"event.type": "gdb.output", "gdb.output": "[10:00:00.000] Parsed response - Text: 10 chars"},
+		map[string]interface{}{"event.type": "llm.response", "llm.response.body": "SIGSEGV"},
+	)
+
+	embedder := &countingEmbedder{}
+	retriever := New(config.RetrievalConfig{TopK: 2, MinScore: 0.1, ChunkLines: 4, ChunkOverlap: 1}, embedder)
+	session := Session{ID: "s1", SourcesDir: sources, LogFile: logFile}
+
+	results, err := retriever.Retrieve(context.Background(), session, "", "why does strcpy in parse_header overflow name?")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "src/parse.c", results[0].Source)
+	assert.Equal(t, 4, results[0].StartLine)
+	assert.Equal(t, 7, results[0].EndLine)
+	assert.Contains(t, results[0].Text, "strcpy(name, buf);")
+	for _, result := range results {
+		assert.NotEqual(t, "a.out", result.Source, "binaries are not indexed")
+		assert.NotContains(t, result.Source, ".git", "hidden directories are not indexed")
+	}
+	// 3 chunks of parse.c, 1 of util.c, 1 of the log and the query
+	assert.Equal(t, 6, embedder.embedded)
+
+	results, err = retriever.Retrieve(context.Background(), session, "", "Segmentation fault SIGSEGV")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, LogSource, results[0].Source)
+	assert.Equal(t, 1, results[0].StartLine)
+	assert.Equal(t, 3, results[0].EndLine)
+	assert.NotContains(t, results[0].Text, "Parsed response", "the chat processor's steps are not indexed")
+	assert.Equal(t, 7, embedder.embedded, "unchanged files and log entries are not embedded again")
+
+	// Only new log entries and changed files are embedded
+	appendLog(t, logFile, map[string]interface{}{"event.type": "gdb.command", "gdb.command": "bt"})
+	require.NoError(t, os.WriteFile(filepath.Join(sources, "src", "util.c"), []byte("int sub(int a, int b) {\n  return a - b;\n}\n"), 0644))
+	_, err = retriever.Retrieve(context.Background(), session, "", "sub")
+	require.NoError(t, err)
+	assert.Equal(t, 10, embedder.embedded)
+
+	results, err = retriever.Retrieve(context.Background(), session, "", "add")
+	require.NoError(t, err)
+	assert.Empty(t, results, "the chunks of the old util.c were replaced")
+
+	require.NoError(t, os.Remove(filepath.Join(sources, "src", "parse.c")))
+	results, err = retriever.Retrieve(context.Background(), session, "", "strcpy parse_header name buf")
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.NotEqual(t, "src/parse.c", result.Source, "removed files are dropped")
+	}
+}
+
+func TestChunkLines(t *testing.T) {
+	chunks := chunkLines("f.c", []string{"a", "b", "c", "d", "e", "", ""}, nil, 3, 1)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, Chunk{Source: "f.c", StartLine: 1, EndLine: 3, Text: "a\nb\nc"}, chunks[0])
+	assert.Equal(t, Chunk{Source: "f.c", StartLine: 3, EndLine: 5, Text: "c\nd\ne"}, chunks[1])
+	assert.Equal(t, Chunk{Source: "f.c", StartLine: 5, EndLine: 7, Text: "e\n\n"}, chunks[2])
+
+	assert.Equal(t, []string{"parseheader", "parse", "header"}, terms("parseHeader"))
+	assert.Equal(t, []string{"http_server", "http", "server"}, terms("http_server"))
+	assert.Equal(t, []string{"httpserver", "http", "server"}, terms("HTTPServer"))
+}
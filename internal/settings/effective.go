@@ -0,0 +1,176 @@
+package settings
+
+import (
+	"os"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Source identifies the configuration layer an effective value came from
+type Source string
+
+// Configuration layers, from lowest to highest precedence
+const (
+	SourceDefault  Source = "default"
+	SourceFile     Source = "config_file"
+	SourceSettings Source = "settings_api"
+	SourceEnv      Source = "env"
+	SourceFlag     Source = "flag"
+)
+
+// Built-in defaults used when no layer sets a value
+const (
+	DefaultProvider = "anthropic"
+	DefaultModel    = "claude-3-sonnet-20240229"
+)
+
+// Value is an effective setting together with the layer that supplied it
+type Value struct {
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// EffectiveSettings are the resolved LLM settings with the source of each value
+type EffectiveSettings struct {
+	Provider Value `json:"provider"`
+	Model    Value `json:"model"`
+	APIKey   Value `json:"apiKey"`
+	Profile  Value `json:"profile"`
+}
+
+// Settings returns the effective values without their sources
+func (e EffectiveSettings) Settings() Settings {
+	return Settings{
+		Provider: e.Provider.Value,
+		Model:    e.Model.Value,
+		APIKey:   e.APIKey.Value,
+		Profile:  e.Profile.Value,
+	}
+}
+
+// Redacted returns a copy safe to show to clients, with the API key masked
+func (e EffectiveSettings) Redacted() EffectiveSettings {
+	if e.APIKey.Value != "" {
+		e.APIKey.Value = "********"
+	}
+	return e
+}
+
+// layers holds the non-stored configuration layers. Empty fields are unset.
+type layers struct {
+	file    Settings
+	env     Settings
+	flag    Settings
+	secrets []SecretSource
+}
+
+// NewManagerFromConfig creates a settings manager whose saved settings are combined with the
+// config file, environment variables and command-line overrides, in the precedence order
+// flags > env > keychain > settings API > config file > defaults
+func NewManagerFromConfig(filePath string, cfg *config.Config) (*Manager, error) {
+	manager, err := NewManager(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.layers = layers{
+		file: fileLayer(cfg),
+		env: Settings{
+			Provider: os.Getenv(config.EnvVar("llm.default_provider")),
+			Model:    os.Getenv(config.EnvVar("llm.default_model")),
+			APIKey:   os.Getenv(config.EnvVar("llm.api_key")),
+		},
+		flag: Settings{
+			Provider: cfg.Overrides.Provider,
+			Model:    cfg.Overrides.Model,
+		},
+		secrets: secretSourcesFromConfig(cfg),
+	}
+
+	return manager, nil
+}
+
+// fileLayer returns the settings given in the config file
+func fileLayer(cfg *config.Config) Settings {
+	return Settings{
+		Provider: cfg.LLM.DefaultProvider,
+		Model:    cfg.LLM.DefaultModel,
+		APIKey:   cfg.LLM.APIKey,
+		Profile:  cfg.Prompts.DefaultProfile,
+	}
+}
+
+// Reload replaces the settings given in the config file, e.g. llm.default_model, with
+// those of a reloaded configuration. Saved settings, the environment and flags still
+// take precedence.
+func (m *Manager) Reload(cfg *config.Config) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.layers.file = fileLayer(cfg)
+}
+
+// Effective resolves the settings for a user across all layers. A user's own saved
+// settings take precedence over the shared saved settings within the settings API layer.
+// The API key for the effective provider is looked up in the secret sources first, so a
+// provider-specific key beats the generic GOGDBLLM_LLM_API_KEY and the saved settings.
+func (m *Manager) Effective(user string) EffectiveSettings {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var stored Settings
+	if user != "" {
+		stored = m.users[user]
+	}
+
+	pick := func(field func(Settings) string, def string) Value {
+		candidates := []struct {
+			value  string
+			source Source
+		}{
+			{field(m.layers.flag), SourceFlag},
+			{field(m.layers.env), SourceEnv},
+			{field(stored), SourceSettings},
+			{field(m.settings), SourceSettings},
+			{field(m.layers.file), SourceFile},
+		}
+		for _, c := range candidates {
+			if c.value != "" {
+				return Value{Value: c.value, Source: c.source}
+			}
+		}
+		if def == "" {
+			return Value{}
+		}
+		return Value{Value: def, Source: SourceDefault}
+	}
+
+	effective := EffectiveSettings{
+		Provider: pick(func(s Settings) string { return s.Provider }, DefaultProvider),
+		Model:    pick(func(s Settings) string { return s.Model }, DefaultModel),
+		Profile:  pick(func(s Settings) string { return s.Profile }, ""),
+	}
+
+	for _, secrets := range m.layers.secrets {
+		if key := secrets.Lookup(effective.Provider.Value); key != "" {
+			effective.APIKey = Value{Value: key, Source: secrets.Source()}
+			return effective
+		}
+	}
+	effective.APIKey = pick(func(s Settings) string { return s.APIKey }, "")
+	return effective
+}
+
+// APIKeyFor returns the API key a user would use with provider, which need not be their
+// current provider. A key saved through the settings API or configured without a
+// provider is only used for the provider it was saved with.
+func (m *Manager) APIKeyFor(user, provider string) string {
+	for _, secrets := range m.layers.secrets {
+		if key := secrets.Lookup(provider); key != "" {
+			return key
+		}
+	}
+	if effective := m.Effective(user); effective.Provider.Value == provider {
+		return effective.APIKey.Value
+	}
+	return ""
+}
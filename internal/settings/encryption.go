@@ -0,0 +1,244 @@
+package settings
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/utils"
+)
+
+const (
+	// encryptedPrefix marks an API key encrypted with AES-256-GCM in the settings file
+	encryptedPrefix = "enc:v1:"
+
+	// keyFileSuffix is appended to the settings file path to name the machine key file
+	keyFileSuffix = ".key"
+
+	// passphraseKDF names the key derivation recorded for passphrase-encrypted files
+	passphraseKDF = "pbkdf2-sha256"
+
+	// passphraseIterations is the PBKDF2 work factor for passphrase-derived keys
+	passphraseIterations = 600000
+
+	encryptionKeyLength  = 32
+	passphraseSaltLength = 16
+)
+
+// PassphraseEnv is the environment variable holding the passphrase that API keys are
+// encrypted with. Without it a random machine key is kept next to the settings file.
+var PassphraseEnv = config.EnvVar("settings.passphrase")
+
+// encryptionHeader records how the key was derived when the file is passphrase-encrypted
+type encryptionHeader struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+}
+
+// keyCipher encrypts API keys for storage
+type keyCipher struct {
+	aead cipher.AEAD
+}
+
+// newKeyCipher creates an AES-256-GCM cipher from a 32-byte key
+func newKeyCipher(key []byte) (*keyCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &keyCipher{aead: aead}, nil
+}
+
+// seal encrypts an API key; empty keys stay empty
+func (c *keyCipher) seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts an API key produced by seal
+func (c *keyCipher) open(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted API key")
+	}
+
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt API key (wrong machine key or %s?)", PassphraseEnv)
+	}
+	return string(plaintext), nil
+}
+
+// isEncrypted reports whether a stored API key is encrypted
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// keyCipherLocked returns the cipher, deriving it on first use from the passphrase in
+// PassphraseEnv or from the machine key file; the caller must hold the mutex
+func (m *Manager) keyCipherLocked() (*keyCipher, error) {
+	if m.cipher != nil {
+		return m.cipher, nil
+	}
+
+	var key []byte
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		header, err := m.passphraseHeaderLocked()
+		if err != nil {
+			return nil, err
+		}
+		salt, err := base64.StdEncoding.DecodeString(header.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption salt in settings file: %w", err)
+		}
+		key = utils.PBKDF2SHA256([]byte(passphrase), salt, header.Iterations, encryptionKeyLength)
+	} else {
+		if m.header != nil {
+			return nil, fmt.Errorf("settings file %s is encrypted with a passphrase; set %s", m.filePath, PassphraseEnv)
+		}
+		var err error
+		if key, err = loadOrCreateMachineKey(m.filePath + keyFileSuffix); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	m.cipher = c
+	return c, nil
+}
+
+// passphraseHeaderLocked returns the header of a passphrase-encrypted file, creating one
+// with a fresh salt for files not yet encrypted with a passphrase
+func (m *Manager) passphraseHeaderLocked() (*encryptionHeader, error) {
+	if m.header != nil {
+		if m.header.KDF != passphraseKDF || m.header.Iterations <= 0 {
+			return nil, fmt.Errorf("unsupported settings encryption %q", m.header.KDF)
+		}
+		return m.header, nil
+	}
+
+	salt := make([]byte, passphraseSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	m.header = &encryptionHeader{
+		KDF:        passphraseKDF,
+		Iterations: passphraseIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+	}
+	return m.header, nil
+}
+
+// loadOrCreateMachineKey reads the machine key file, generating a random key readable
+// only by the current user if it does not exist
+func loadOrCreateMachineKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != encryptionKeyLength {
+			return nil, fmt.Errorf("invalid machine key file %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read machine key: %w", err)
+	}
+
+	key := make([]byte, encryptionKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate machine key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write machine key: %w", err)
+	}
+	return key, nil
+}
+
+// decryptKeysLocked decrypts the API keys of settings read from disk and reports whether
+// any were stored in plaintext; the caller must hold the mutex
+func (m *Manager) decryptKeysLocked(fileData *settingsFileData) (bool, error) {
+	plaintext := false
+	decrypt := func(s *Settings) error {
+		if s.APIKey == "" {
+			return nil
+		}
+		if !isEncrypted(s.APIKey) {
+			plaintext = true
+			return nil
+		}
+		c, err := m.keyCipherLocked()
+		if err != nil {
+			return err
+		}
+		s.APIKey, err = c.open(s.APIKey)
+		return err
+	}
+
+	if err := decrypt(&fileData.Settings); err != nil {
+		return false, err
+	}
+	for user, s := range fileData.Users {
+		if err := decrypt(&s); err != nil {
+			return false, fmt.Errorf("settings for %s: %w", user, err)
+		}
+		fileData.Users[user] = s
+	}
+	return plaintext, nil
+}
+
+// encryptKeysLocked returns the on-disk form of the settings with every API key
+// encrypted; the caller must hold the mutex
+func (m *Manager) encryptKeysLocked() (settingsFileData, error) {
+	fileData := settingsFileData{Version: settingsVersion, Settings: m.settings, Users: make(map[string]Settings, len(m.users))}
+	for user, s := range m.users {
+		fileData.Users[user] = s
+	}
+
+	encrypt := func(s *Settings) error {
+		if s.APIKey == "" {
+			return nil
+		}
+		c, err := m.keyCipherLocked()
+		if err != nil {
+			return err
+		}
+		s.APIKey, err = c.seal(s.APIKey)
+		return err
+	}
+
+	if err := encrypt(&fileData.Settings); err != nil {
+		return fileData, err
+	}
+	for user, s := range fileData.Users {
+		if err := encrypt(&s); err != nil {
+			return fileData, err
+		}
+		fileData.Users[user] = s
+	}
+	fileData.Encryption = m.header
+	return fileData, nil
+}
@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// SourceKeychain marks an API key read from the operating system keychain
+const SourceKeychain Source = "keychain"
+
+// keychainCacheTTL is how long keychain lookups are reused, so a key rotated in the
+// keychain is picked up without restarting
+const keychainCacheTTL = time.Minute
+
+// keychainTimeout bounds a single keychain lookup
+const keychainTimeout = 5 * time.Second
+
+// SecretSource looks up the API key for an LLM provider outside the settings file
+type SecretSource interface {
+	// Source identifies the layer in EffectiveSettings
+	Source() Source
+	// Lookup returns the key for a provider, or "" if the source has none
+	Lookup(provider string) string
+}
+
+// ProviderKeyEnv returns the environment variable holding the API key for a provider,
+// e.g. anthropic -> GOGDBLLM_ANTHROPIC_KEY
+func ProviderKeyEnv(provider string) string {
+	return config.EnvVar(provider + ".key")
+}
+
+// EnvSecrets reads per-provider API keys from GOGDBLLM_<PROVIDER>_KEY
+type EnvSecrets struct{}
+
+// Source implements SecretSource
+func (EnvSecrets) Source() Source { return SourceEnv }
+
+// Lookup implements SecretSource
+func (EnvSecrets) Lookup(provider string) string {
+	if provider == "" {
+		return ""
+	}
+	return os.Getenv(ProviderKeyEnv(provider))
+}
+
+// commandRunner runs a keychain tool and returns its standard output
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// KeychainSecrets reads API keys from the OS keychain: the macOS login keychain via
+// security(1), or the Secret Service (GNOME Keyring, KWallet) via secret-tool(1) elsewhere.
+// Keys are stored with the service name and the provider as the account.
+type KeychainSecrets struct {
+	service string
+	run     commandRunner
+
+	cache map[string]keychainEntry
+	mutex sync.Mutex
+}
+
+type keychainEntry struct {
+	key     string
+	fetched time.Time
+}
+
+// NewKeychainSecrets creates a keychain source for the given service name
+func NewKeychainSecrets(service string) *KeychainSecrets {
+	return &KeychainSecrets{
+		service: service,
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).Output()
+		},
+		cache: make(map[string]keychainEntry),
+	}
+}
+
+// Source implements SecretSource
+func (k *KeychainSecrets) Source() Source { return SourceKeychain }
+
+// Lookup implements SecretSource. Missing entries and lookup failures both return "".
+func (k *KeychainSecrets) Lookup(provider string) string {
+	if provider == "" {
+		return ""
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if entry, ok := k.cache[provider]; ok && time.Since(entry.fetched) < keychainCacheTTL {
+		return entry.key
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+
+	var out []byte
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		out, err = k.run(ctx, "security", "find-generic-password", "-s", k.service, "-a", provider, "-w")
+	default:
+		out, err = k.run(ctx, "secret-tool", "lookup", "service", k.service, "account", provider)
+	}
+
+	key := ""
+	if err == nil {
+		key = strings.TrimSpace(string(out))
+	}
+	k.cache[provider] = keychainEntry{key: key, fetched: time.Now()}
+	return key
+}
+
+// secretSourcesFromConfig returns the secret sources enabled in the configuration
+func secretSourcesFromConfig(cfg *config.Config) []SecretSource {
+	sources := []SecretSource{EnvSecrets{}}
+	if cfg.Secrets.Keychain {
+		service := cfg.Secrets.KeychainService
+		if service == "" {
+			service = "gogdbllm"
+		}
+		sources = append(sources, NewKeychainSecrets(service))
+	}
+	return sources
+}
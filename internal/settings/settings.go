@@ -3,6 +3,7 @@ package settings
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -10,17 +11,63 @@ import (
 
 const settingsFile = ".gogdbllm_settings.json"
 
+// settingsVersion is the version of the settings file layout this version writes. Files
+// of older versions are migrated when loaded; see migrations.
+const settingsVersion = 1
+
 // Settings represents the application settings
 type Settings struct {
 	Provider string `json:"provider"`
 	Model    string `json:"model"`
 	APIKey   string `json:"apiKey"`
+	Profile  string `json:"profile,omitempty"` // Prompt profile; empty for the default behaviour
+}
+
+// settingsFileData is the on-disk layout: the shared settings at the top level, for
+// compatibility with files written before per-user settings, plus settings per user.
+// API keys are encrypted; see encryption.go.
+type settingsFileData struct {
+	Version int `json:"version"` // 0 for files written before the layout was versioned
+	Settings
+	Users      map[string]Settings `json:"users,omitempty"`
+	Encryption *encryptionHeader   `json:"encryption,omitempty"`
 }
 
-// Manager handles loading and saving settings
+// migrations upgrade a settings file from the version at their index to the next one.
+// They run on decrypted API keys.
+var migrations = []func(*settingsFileData){
+	// Unversioned files hold the values as clients sent them, e.g. "Anthropic " as the
+	// provider, which saving now normalizes and validates
+	func(fileData *settingsFileData) {
+		fileData.Settings = fileData.Settings.Normalize()
+		for user, s := range fileData.Users {
+			fileData.Users[user] = s.Normalize()
+		}
+	},
+}
+
+// migrate upgrades fileData to settingsVersion and reports whether it changed version. It
+// fails for files written by a newer version, which this one must not overwrite.
+func migrate(fileData *settingsFileData) (bool, error) {
+	if fileData.Version > settingsVersion {
+		return false, fmt.Errorf("settings file version %d is newer than this server supports (%d)", fileData.Version, settingsVersion)
+	}
+	migrated := fileData.Version < settingsVersion
+	for ; fileData.Version < settingsVersion; fileData.Version++ {
+		migrations[fileData.Version](fileData)
+	}
+	return migrated, nil
+}
+
+// Manager handles loading and saving settings. Saved settings are one layer of the
+// effective configuration; see Effective for how the layers combine.
 type Manager struct {
 	filePath string
 	settings Settings
+	users    map[string]Settings
+	layers   layers
+	cipher   *keyCipher
+	header   *encryptionHeader
 	mutex    sync.RWMutex
 }
 
@@ -37,11 +84,7 @@ func NewManager(filePath string) (*Manager, error) {
 
 	manager := &Manager{
 		filePath: filePath,
-		settings: Settings{
-			Provider: "anthropic",                // Default provider
-			Model:    "claude-3-sonnet-20240229", // Default model
-			APIKey:   "",
-		},
+		users:    make(map[string]Settings),
 	}
 
 	// Try to load existing settings
@@ -53,7 +96,8 @@ func NewManager(filePath string) (*Manager, error) {
 	return manager, nil
 }
 
-// Load settings from file
+// Load settings from file. Files of older versions are migrated, and API keys still
+// stored in plaintext by older versions encrypted, by rewriting the file.
 func (m *Manager) Load() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -61,22 +105,40 @@ func (m *Manager) Load() error {
 	// Try to read from the file path
 	data, err := os.ReadFile(m.filePath)
 	if err != nil {
-		// If file doesn't exist, use default settings
+		// If file doesn't exist, nothing has been saved and the other layers apply
 		if os.IsNotExist(err) {
-			m.settings = Settings{
-				Provider: "anthropic",
-				Model:    "claude-3-sonnet-20240229",
-				APIKey:   "",
-			}
+			m.settings = Settings{}
 			return os.ErrNotExist
 		}
 		return err
 	}
 
 	// Unmarshal the data
-	if err := json.Unmarshal(data, &m.settings); err != nil {
+	var fileData settingsFileData
+	if err := json.Unmarshal(data, &fileData); err != nil {
 		return err
 	}
+	m.header = fileData.Encryption
+	m.cipher = nil
+	plaintext, err := m.decryptKeysLocked(&fileData)
+	if err != nil {
+		return fmt.Errorf("failed to load settings from %s: %w", m.filePath, err)
+	}
+	migrated, err := migrate(&fileData)
+	if err != nil {
+		return fmt.Errorf("failed to load settings from %s: %w", m.filePath, err)
+	}
+	m.settings = fileData.Settings
+	m.users = fileData.Users
+	if m.users == nil {
+		m.users = make(map[string]Settings)
+	}
+
+	if plaintext || migrated {
+		if err := m.saveLocked(); err != nil {
+			return fmt.Errorf("failed to rewrite settings file: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -85,26 +147,33 @@ func (m *Manager) Load() error {
 func (m *Manager) Save() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	return m.saveLocked()
+}
 
-	data, err := json.MarshalIndent(m.settings, "", "  ")
+// saveLocked writes the settings with encrypted API keys; the caller must hold the mutex
+func (m *Manager) saveLocked() error {
+	// Create directory if it doesn't exist; the machine key is kept alongside
+	dir := filepath.Dir(m.filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fileData, err := m.encryptKeysLocked()
 	if err != nil {
 		return err
 	}
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(m.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	data, err := json.MarshalIndent(fileData, "", "  ")
+	if err != nil {
 		return err
 	}
 
 	return os.WriteFile(m.filePath, data, 0600)
 }
 
-// GetSettings returns the current settings
+// GetSettings returns the effective shared settings
 func (m *Manager) GetSettings() Settings {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	return m.settings
+	return m.GetUserSettings("")
 }
 
 // UpdateSettings updates the current settings
@@ -113,3 +182,44 @@ func (m *Manager) UpdateSettings(newSettings Settings) {
 	defer m.mutex.Unlock()
 	m.settings = newSettings
 }
+
+// GetUserSettings returns the effective settings for a user; an empty user returns the
+// effective shared settings. A user's API key is never visible to other users.
+func (m *Manager) GetUserSettings(user string) Settings {
+	return m.Effective(user).Settings()
+}
+
+// StoredSettings returns the settings saved through the settings API for a user, without
+// the other configuration layers applied
+func (m *Manager) StoredSettings(user string) Settings {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if user == "" {
+		return m.settings
+	}
+	return m.users[user]
+}
+
+// UpdateUserSettings updates the settings for a user; an empty user updates the shared settings
+func (m *Manager) UpdateUserSettings(user string, newSettings Settings) {
+	if user == "" {
+		m.UpdateSettings(newSettings)
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.users[user] = newSettings
+}
+
+// HasUserAPIKey reports whether a user has stored their own API key
+func (m *Manager) HasUserAPIKey(user string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if user == "" {
+		return m.settings.APIKey != ""
+	}
+	return m.users[user].APIKey != ""
+}
@@ -0,0 +1,221 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+func TestUserSettingsIsolation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	manager, err := NewManager(path)
+	require.NoError(t, err)
+
+	manager.UpdateSettings(Settings{Provider: "anthropic", Model: "claude-3-haiku-20240307", APIKey: "shared-key"})
+	manager.UpdateUserSettings("alice", Settings{Provider: "openai", Model: "gpt-4o", APIKey: "alice-key"})
+	manager.UpdateUserSettings("bob", Settings{Model: "claude-3-opus-20240229"})
+
+	alice := manager.GetUserSettings("alice")
+	assert.Equal(t, Settings{Provider: "openai", Model: "gpt-4o", APIKey: "alice-key"}, alice)
+
+	// Unset fields fall back to the shared settings, never to another user's
+	bob := manager.GetUserSettings("bob")
+	assert.Equal(t, Settings{Provider: "anthropic", Model: "claude-3-opus-20240229", APIKey: "shared-key"}, bob)
+	assert.False(t, manager.HasUserAPIKey("bob"))
+
+	assert.Equal(t, "shared-key", manager.GetUserSettings("").APIKey)
+
+	// Per-user settings survive a reload
+	require.NoError(t, manager.Save())
+	reloaded, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, alice, reloaded.GetUserSettings("alice"))
+	assert.Equal(t, "shared-key", reloaded.GetSettings().APIKey)
+}
+
+func TestLoadLegacySettingsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"provider":"openai","model":"gpt-4-turbo","apiKey":"legacy"}`), 0600))
+
+	manager, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, Settings{Provider: "openai", Model: "gpt-4-turbo", APIKey: "legacy"}, manager.GetSettings())
+	assert.Equal(t, "legacy", manager.GetUserSettings("alice").APIKey)
+}
+
+func TestEffectivePrecedence(t *testing.T) {
+	cfg := &config.Config{LLM: config.LLMConfig{DefaultProvider: "openai", DefaultModel: "gpt-4-turbo", APIKey: "file-key"}}
+	t.Setenv(config.EnvVar("llm.default_provider"), "")
+	t.Setenv(config.EnvVar("llm.default_model"), "")
+	t.Setenv(config.EnvVar("llm.api_key"), "")
+
+	manager, err := NewManagerFromConfig(filepath.Join(t.TempDir(), "settings.json"), cfg)
+	require.NoError(t, err)
+
+	// Only the config file is set
+	effective := manager.Effective("")
+	assert.Equal(t, Value{Value: "openai", Source: SourceFile}, effective.Provider)
+	assert.Equal(t, Value{Value: "file-key", Source: SourceFile}, effective.APIKey)
+
+	// Saved settings beat the config file
+	manager.UpdateSettings(Settings{Model: "gpt-4o"})
+	assert.Equal(t, Value{Value: "gpt-4o", Source: SourceSettings}, manager.Effective("").Model)
+
+	// Environment beats saved settings, flags beat the environment
+	t.Setenv(config.EnvVar("llm.default_model"), "gpt-4o-mini")
+	cfg.Overrides = config.Overrides{Provider: "openrouter"}
+	manager, err = NewManagerFromConfig(filepath.Join(t.TempDir(), "settings.json"), cfg)
+	require.NoError(t, err)
+	manager.UpdateSettings(Settings{Provider: "anthropic", Model: "gpt-4o"})
+
+	effective = manager.Effective("")
+	assert.Equal(t, Value{Value: "openrouter", Source: SourceFlag}, effective.Provider)
+	assert.Equal(t, Value{Value: "gpt-4o-mini", Source: SourceEnv}, effective.Model)
+	assert.Equal(t, "********", effective.Redacted().APIKey.Value)
+}
+
+func TestAPIKeysEncryptedAtRest(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	// Plaintext keys written by older versions are migrated on load
+	require.NoError(t, os.WriteFile(path, []byte(`{"provider":"openai","apiKey":"sk-legacy","users":{"alice":{"apiKey":"sk-alice"}}}`), 0600))
+	manager, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", manager.GetSettings().APIKey)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-legacy")
+	assert.NotContains(t, string(data), "sk-alice")
+	assert.Contains(t, string(data), encryptedPrefix)
+
+	info, err := os.Stat(path + keyFileSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reloaded, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-alice", reloaded.GetUserSettings("alice").APIKey)
+
+	// A different machine key cannot decrypt the file
+	require.NoError(t, os.Remove(path+keyFileSuffix))
+	_, err = NewManager(path)
+	assert.Error(t, err)
+}
+
+func TestAPIKeysEncryptedWithPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	manager, err := NewManager(path)
+	require.NoError(t, err)
+	manager.UpdateSettings(Settings{Provider: "anthropic", APIKey: "sk-secret"})
+	require.NoError(t, manager.Save())
+
+	_, err = os.Stat(path + keyFileSuffix)
+	assert.True(t, os.IsNotExist(err), "no machine key is created in passphrase mode")
+
+	reloaded, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", reloaded.GetSettings().APIKey)
+
+	t.Setenv(PassphraseEnv, "")
+	_, err = NewManager(path)
+	assert.ErrorContains(t, err, PassphraseEnv)
+}
+
+func TestSecretSources(t *testing.T) {
+	cfg := &config.Config{LLM: config.LLMConfig{DefaultProvider: "openai", APIKey: "file-key"}}
+	t.Setenv(config.EnvVar("llm.api_key"), "generic-key")
+	t.Setenv(ProviderKeyEnv("openai"), "")
+	t.Setenv(ProviderKeyEnv("anthropic"), "")
+
+	manager, err := NewManagerFromConfig(filepath.Join(t.TempDir(), "settings.json"), cfg)
+	require.NoError(t, err)
+
+	keychain := NewKeychainSecrets("gogdbllm")
+	keychain.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if slices.Contains(args, "anthropic") {
+			return []byte("keychain-key\n"), nil
+		}
+		return nil, errors.New("item not found")
+	}
+	manager.layers.secrets = append(manager.layers.secrets, keychain)
+	manager.UpdateSettings(Settings{APIKey: "stored-key"})
+
+	// The generic environment variable beats saved settings when nothing provider-specific exists
+	assert.Equal(t, Value{Value: "generic-key", Source: SourceEnv}, manager.Effective("").APIKey)
+
+	// A provider-specific variable wins for that provider
+	t.Setenv(ProviderKeyEnv("openai"), "openai-env-key")
+	assert.Equal(t, Value{Value: "openai-env-key", Source: SourceEnv}, manager.Effective("").APIKey)
+
+	// The keychain is consulted for the user's effective provider
+	manager.UpdateUserSettings("alice", Settings{Provider: "anthropic"})
+	assert.Equal(t, Value{Value: "keychain-key", Source: SourceKeychain}, manager.Effective("alice").APIKey)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Settings{Provider: "anthropic", Model: "claude-3-haiku-20240307", APIKey: "sk-ant-REDACTED"}
+	assert.Empty(t, valid.Validate().Fields)
+	assert.Empty(t, Settings{Provider: "openai", Model: "gpt-4o"}.Validate().Fields, "the stored key is kept")
+
+	verr := Settings{Provider: "gemini"}.Validate()
+	assert.ErrorIs(t, verr, appErrors.ErrBadRequest)
+	assert.Equal(t, []string{"provider", "model"}, fieldNames(verr))
+
+	for key, message := range map[string]string{
+		"sk-ant-REDACTED": "looks like a key for anthropic",
+		"sk-proj-0123456789 abcdefgh":   "spaces",
+		"sk-0123":                       "too short",
+	} {
+		verr := Settings{Provider: "openai", Model: "gpt-4o", APIKey: key}.Validate()
+		require.Len(t, verr.Fields, 1, key)
+		assert.Equal(t, "apiKey", verr.Fields[0].Field)
+		assert.Contains(t, verr.Fields[0].Message, message)
+	}
+	assert.Equal(t, []string{"apiKey"}, fieldNames(Settings{Provider: "openrouter", Model: "openai/gpt-4o", APIKey: "sk-proj-0123456789abcdef"}.Validate()))
+
+	assert.Equal(t, Settings{Provider: "openai", Model: "gpt-4o"}, Settings{Provider: " OpenAI", Model: "gpt-4o "}.Normalize())
+}
+
+func fieldNames(verr *ValidationError) []string {
+	var names []string
+	for _, field := range verr.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+func TestMigrate(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"provider":"Anthropic ","model":"claude-3-haiku-20240307","users":{"alice":{"provider":"OPENAI","model":" gpt-4o"}}}`), 0600))
+
+	manager, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", manager.StoredSettings("").Provider)
+	assert.Equal(t, Settings{Provider: "openai", Model: "gpt-4o"}, manager.StoredSettings("alice"))
+
+	// The file is rewritten at the current version
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var fileData settingsFileData
+	require.NoError(t, json.Unmarshal(data, &fileData))
+	assert.Equal(t, settingsVersion, fileData.Version)
+
+	// Files of newer versions are left alone
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":99,"provider":"anthropic"}`), 0600))
+	_, err = NewManager(path)
+	assert.ErrorContains(t, err, "newer")
+}
@@ -0,0 +1,106 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+)
+
+// Providers are the LLM providers settings may name
+var Providers = []string{"anthropic", "openai", "openrouter"}
+
+// keyPrefixes are the prefixes of each provider's API keys. A key with another
+// provider's prefix was most likely pasted for the wrong provider.
+var keyPrefixes = map[string]string{
+	"anthropic":  "sk-ant-",
+	"openrouter": "sk-or-",
+	"openai":     "sk-",
+}
+
+// minKeyLength is shorter than any provider's keys, so truncated pastes are caught
+const minKeyLength = 20
+
+// FieldError is a setting that failed validation
+type FieldError struct {
+	Field   string `json:"field"` // JSON name of the setting, e.g. "apiKey"
+	Message string `json:"message"`
+}
+
+// ValidationError lists the settings that failed validation. It wraps ErrBadRequest.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "invalid settings: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return appErrors.ErrBadRequest
+}
+
+// Add records a failed setting
+func (e *ValidationError) Add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Normalize trims the settings and lowercases the provider, as users type them
+func (s Settings) Normalize() Settings {
+	s.Provider = strings.ToLower(strings.TrimSpace(s.Provider))
+	s.Model = strings.TrimSpace(s.Model)
+	s.APIKey = strings.TrimSpace(s.APIKey)
+	s.Profile = strings.TrimSpace(s.Profile)
+	return s
+}
+
+// Validate checks settings about to be saved: the provider must be known and the model
+// set, and an API key must look like one of the provider's. Keys are checked by their
+// format only; /test-connection tries them. The returned error lists every failed
+// setting and has no fields if all passed.
+func (s Settings) Validate() *ValidationError {
+	verr := &ValidationError{}
+	known := false
+	for _, provider := range Providers {
+		known = known || s.Provider == provider
+	}
+	switch {
+	case s.Provider == "":
+		verr.Add("provider", "is required")
+	case !known:
+		verr.Add("provider", "unknown provider %q (expected one of %s)", s.Provider, strings.Join(Providers, ", "))
+	}
+	if s.Model == "" {
+		verr.Add("model", "is required")
+	}
+	if s.APIKey != "" && known {
+		if message := checkKey(s.Provider, s.APIKey); message != "" {
+			verr.Add("apiKey", "%s", message)
+		}
+	}
+	return verr
+}
+
+// checkKey returns why key does not look like an API key of provider, or "" if it does
+func checkKey(provider, key string) string {
+	if strings.ContainsAny(key, " \t\r\n") {
+		return "must not contain spaces"
+	}
+	if len(key) < minKeyLength {
+		return "is too short to be an API key"
+	}
+	for other, prefix := range keyPrefixes {
+		// "sk-" is a prefix of the others' prefixes
+		if other != provider && len(prefix) > len(keyPrefixes[provider]) && strings.HasPrefix(key, prefix) {
+			return fmt.Sprintf("looks like a key for %s, not %s", other, provider)
+		}
+	}
+	if !strings.HasPrefix(key, keyPrefixes[provider]) {
+		return fmt.Sprintf("%s keys start with %q", provider, keyPrefixes[provider])
+	}
+	return ""
+}
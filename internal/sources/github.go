@@ -0,0 +1,171 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// defaultAPIURL is GitHub's API, used when the configuration names none
+const defaultAPIURL = "https://api.github.com"
+
+var (
+	repositoryPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	commitPattern     = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+)
+
+// ValidRepository reports whether repository is an owner/name
+func ValidRepository(repository string) bool {
+	return repositoryPattern.MatchString(repository) && !strings.Contains(repository, "..")
+}
+
+// ValidCommit reports whether commit is a full or abbreviated commit SHA
+func ValidCommit(commit string) bool {
+	return commitPattern.MatchString(commit)
+}
+
+// Result describes fetched sources
+type Result struct {
+	Files   int   `json:"files"`
+	Size    int64 `json:"size"`
+	Skipped int   `json:"skipped,omitempty"` // Matched but over the size or file limit
+}
+
+// GitHub fetches sources from GitHub repositories
+type GitHub struct {
+	cfg    config.GitHubSourcesConfig
+	apiURL string
+	client *http.Client
+}
+
+// NewGitHub creates a GitHub source fetcher
+func NewGitHub(cfg config.GitHubSourcesConfig) *GitHub {
+	apiURL := strings.TrimRight(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &GitHub{cfg: cfg, apiURL: apiURL, client: &http.Client{}}
+}
+
+// Allowed reports whether sources may be fetched from repository
+func (g *GitHub) Allowed(repository string) bool {
+	if len(g.cfg.Repositories) == 0 {
+		return true
+	}
+	owner, _, _ := strings.Cut(repository, "/")
+	for _, allowed := range g.cfg.Repositories {
+		if strings.EqualFold(allowed, repository) || strings.EqualFold(allowed, owner+"/*") {
+			return true
+		}
+	}
+	return false
+}
+
+// treeEntry is a file or directory in a tree listing
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+	Size int64  `json:"size"`
+}
+
+// Fetch writes the files of repository at commit that the referenced names were built
+// from below dest, at their paths in the repository
+func (g *GitHub) Fetch(ctx context.Context, repository, commit string, referenced []string, dest string) (*Result, error) {
+	if !ValidRepository(repository) || !ValidCommit(commit) {
+		return nil, fmt.Errorf("invalid repository %q or commit %q", repository, commit)
+	}
+	if !g.Allowed(repository) {
+		return nil, fmt.Errorf("fetching sources from %s is not allowed", repository)
+	}
+	if g.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.cfg.Timeout)
+		defer cancel()
+	}
+
+	// A tree too large for one listing is truncated; its files past the cut are not found
+	var tree struct {
+		Tree []treeEntry `json:"tree"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("/repos/%s/git/trees/%s?recursive=1", repository, commit), "application/vnd.github+json", func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&tree)
+	}); err != nil {
+		return nil, fmt.Errorf("listing %s at %s: %w", repository, commit, err)
+	}
+
+	blobs := make(map[string]treeEntry)
+	paths := make([]string, 0, len(tree.Tree))
+	for _, entry := range tree.Tree {
+		if entry.Type == "blob" && filepath.IsLocal(entry.Path) {
+			blobs[entry.Path] = entry
+			paths = append(paths, entry.Path)
+		}
+	}
+
+	result := &Result{}
+	for _, name := range match(referenced, paths) {
+		entry := blobs[name]
+		if (g.cfg.MaxFileSize > 0 && entry.Size > g.cfg.MaxFileSize) || (g.cfg.MaxFiles > 0 && result.Files >= g.cfg.MaxFiles) {
+			result.Skipped++
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return result, err
+		}
+		err := g.get(ctx, fmt.Sprintf("/repos/%s/git/blobs/%s", repository, entry.SHA), "application/vnd.github.raw+json", func(body io.Reader) error {
+			return writeFile(target, body)
+		})
+		if err != nil {
+			return result, fmt.Errorf("fetching %s: %w", name, err)
+		}
+		result.Files++
+		result.Size += entry.Size
+	}
+	return result, nil
+}
+
+// get requests an API path and hands a successful response's body to read
+func (g *GitHub) get(ctx context.Context, apiPath, accept string, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.apiURL+apiPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return read(resp.Body)
+}
+
+// writeFile saves a fetched file
+func writeFile(path string, body io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,115 @@
+// Package sources finds the source files a binary was built from, for sessions whose
+// sources were not uploaded: it reads the file names in the binary's debug information
+// and fetches those files from the GitHub repository at the commit the binary was built
+// from. The files are written where an uploaded source archive would be extracted, so
+// GDB lists them like uploaded sources and the assistant sees them in its output.
+package sources
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrNoDebugInfo is returned for binaries without DWARF debug information
+var ErrNoDebugInfo = errors.New("the binary has no debug information")
+
+// Referenced returns the source files named in a binary's DWARF line tables, as they
+// were named when it was built, with forward slashes
+func Referenced(path string) ([]string, error) {
+	data, err := debugData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagCompileUnit {
+			reader.SkipChildren()
+			continue
+		}
+		lines, err := data.LineReader(entry)
+		reader.SkipChildren()
+		if err != nil || lines == nil {
+			continue
+		}
+		for _, file := range lines.Files() {
+			if file != nil && file.Name != "" {
+				seen[strings.ReplaceAll(file.Name, `\`, "/")] = true
+			}
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// debugData opens the DWARF data of an ELF, Mach-O or PE binary
+func debugData(path string) (*dwarf.Data, error) {
+	if file, err := elf.Open(path); err == nil {
+		defer file.Close()
+		return dwarfOf(file.DWARF())
+	}
+	if file, err := macho.Open(path); err == nil {
+		defer file.Close()
+		return dwarfOf(file.DWARF())
+	}
+	if file, err := pe.Open(path); err == nil {
+		defer file.Close()
+		return dwarfOf(file.DWARF())
+	}
+	return nil, errors.New("not an ELF, Mach-O or PE binary")
+}
+
+// dwarfOf maps a missing DWARF section to ErrNoDebugInfo
+func dwarfOf(data *dwarf.Data, err error) (*dwarf.Data, error) {
+	if err != nil {
+		return nil, ErrNoDebugInfo
+	}
+	return data, nil
+}
+
+// match pairs referenced files with the repository paths they were built from: a path
+// matches when the referenced name ends with it, and the longest match wins, so
+// /home/ci/work/app/src/parse.c finds src/parse.c. Files outside the repository, like
+// system headers, match nothing.
+func match(referenced, tree []string) []string {
+	byBase := make(map[string][]string)
+	for _, path := range tree {
+		base := path[strings.LastIndex(path, "/")+1:]
+		byBase[base] = append(byBase[base], path)
+	}
+
+	seen := make(map[string]bool)
+	var matched []string
+	for _, name := range referenced {
+		best := ""
+		for _, path := range byBase[name[strings.LastIndex(name, "/")+1:]] {
+			if (name == path || strings.HasSuffix(name, "/"+path)) && len(path) > len(best) {
+				best = path
+			}
+		}
+		if best != "" && !seen[best] {
+			seen[best] = true
+			matched = append(matched, best)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
@@ -0,0 +1,113 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestReferencedReadsDebugInfo(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "src", "crash.c")
+	require.NoError(t, os.MkdirAll(filepath.Dir(source), 0755))
+	require.NoError(t, os.WriteFile(source, []byte("int main(void) { return 0; }\n"), 0644))
+	executable := filepath.Join(dir, "crash")
+	output, err := exec.Command("gcc", "-g", "-o", executable, source).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	files, err := Referenced(executable)
+	require.NoError(t, err)
+	assert.Contains(t, files, filepath.ToSlash(source))
+
+	stripped := filepath.Join(dir, "stripped")
+	output, err = exec.Command("gcc", "-o", stripped, source).CombinedOutput()
+	require.NoError(t, err, string(output))
+	_, err = Referenced(stripped)
+	assert.Equal(t, ErrNoDebugInfo, err)
+
+	_, err = Referenced(source)
+	assert.Error(t, err, "not a binary")
+}
+
+func TestMatch(t *testing.T) {
+	tree := []string{"main.c", "src/parse.c", "vendor/src/parse.c", "include/app.h", "README.md"}
+	referenced := []string{
+		"/home/ci/work/app/src/parse.c",
+		"/home/ci/work/app/main.c",
+		"/home/ci/work/app/include/app.h",
+		"/usr/include/stdio.h",
+		"C:/build/app/main.c",
+	}
+	assert.Equal(t, []string{"include/app.h", "main.c", "src/parse.c"}, match(referenced, tree))
+	assert.Equal(t, []string{"vendor/src/parse.c"}, match([]string{"/w/vendor/src/parse.c"}, tree), "the longest path wins")
+	assert.Empty(t, match([]string{"/w/notparse.c"}, tree), "names match whole components")
+}
+
+func TestFetch(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		assert.Equal(t, "Bearer ghs_x", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/repos/acme/app/git/trees/0123abc":
+			json.NewEncoder(w).Encode(map[string]interface{}{"tree": []map[string]interface{}{
+				{"path": "src", "type": "tree", "sha": "t1"},
+				{"path": "src/parse.c", "type": "blob", "sha": "b1", "size": 12},
+				{"path": "src/huge.c", "type": "blob", "sha": "b2", "size": 1 << 30},
+				{"path": "../escape.c", "type": "blob", "sha": "b3", "size": 1},
+			}})
+		case "/repos/acme/app/git/blobs/b1":
+			assert.Equal(t, "application/vnd.github.raw+json", r.Header.Get("Accept"))
+			w.Write([]byte("int parse();"))
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	github := NewGitHub(config.GitHubSourcesConfig{
+		Token:        "ghs_x",
+		APIURL:       server.URL + "/",
+		Repositories: []string{"acme/*"},
+		MaxFileSize:  1 << 20,
+	})
+	dest := t.TempDir()
+	result, err := github.Fetch(context.Background(), "acme/app", "0123abc",
+		[]string{"/ci/app/src/parse.c", "/ci/app/src/huge.c", "/ci/escape.c", "/usr/include/stdio.h"}, dest)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Files: 1, Size: 12, Skipped: 1}, result)
+	data, err := os.ReadFile(filepath.Join(dest, "src", "parse.c"))
+	require.NoError(t, err)
+	assert.Equal(t, "int parse();", string(data))
+	assert.Equal(t, []string{"/repos/acme/app/git/trees/0123abc?recursive=1", "/repos/acme/app/git/blobs/b1"}, requests)
+
+	_, err = github.Fetch(context.Background(), "other/app", "0123abc", nil, dest)
+	assert.EqualError(t, err, "fetching sources from other/app is not allowed")
+	_, err = github.Fetch(context.Background(), "acme/app", "main", nil, dest)
+	assert.Error(t, err, "only commit SHAs are accepted")
+	_, err = github.Fetch(context.Background(), "acme/app", "fedcba9", nil, dest)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "listing acme/app at fedcba9: 404 Not Found"), err.Error())
+}
+
+func TestValidRepository(t *testing.T) {
+	assert.True(t, ValidRepository("acme/my-app.go"))
+	assert.False(t, ValidRepository("acme"))
+	assert.False(t, ValidRepository("acme/app/extra"))
+	assert.False(t, ValidRepository("../app"))
+	assert.True(t, ValidCommit("0123abc"))
+	assert.False(t, ValidCommit("main"))
+}
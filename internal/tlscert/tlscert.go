@@ -0,0 +1,115 @@
+// Package tlscert serves the server's TLS certificate from a certificate and key file,
+// loading them again when they change, so certificates renewed by an ACME client such as
+// certbot or lego take effect without a restart. Obtaining certificates itself, with
+// golang.org/x/crypto/acme/autocert, is a deferred follow-up.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds the certificate loaded from a certificate and key file
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mutex   sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // Latest modification time of the two files when last loaded
+}
+
+// New loads the certificate and key, failing if they cannot be used
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a TLS configuration serving the current certificate
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Reload loads the files again if either changed since they were last loaded, reporting
+// whether it did. A certificate that fails to load leaves the current one in use.
+func (r *Reloader) Reload() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+	r.mutex.RLock()
+	unchanged := r.cert != nil && !modTime.After(r.modTime)
+	r.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS certificate %s: %w", r.certFile, err)
+	}
+	r.mutex.Lock()
+	r.cert, r.modTime = &cert, modTime
+	r.mutex.Unlock()
+	return true, nil
+}
+
+// latestModTime returns when the certificate or key file was last modified
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Start checks the files every interval until ctx is done. It does nothing for a
+// non-positive interval.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloaded, err := r.Reload()
+				if err != nil {
+					log.Printf("Keeping the current TLS certificate: %v", err)
+				} else if reloaded {
+					log.Printf("Loaded the renewed TLS certificate %s", r.certFile)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,388 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/logger"
+)
+
+// queueSize is how many ended spans may wait for export; further spans are dropped
+const queueSize = 4096
+
+// Tracer starts root spans and exports ended spans in batches. A disabled tracer starts
+// no spans.
+type Tracer struct {
+	enabled       bool
+	endpoint      string
+	headers       map[string]string
+	serviceName   string
+	sampleRatio   float64
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+
+	queue    chan *Span
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	dropped  atomic.Uint64
+}
+
+// NewTracer creates a tracer from the tracing configuration and starts its exporter
+func NewTracer(cfg *config.Config) *Tracer {
+	tc := cfg.Tracing
+	t := &Tracer{
+		enabled:       tc.Enabled && tc.Endpoint != "",
+		endpoint:      tc.Endpoint,
+		headers:       tc.Headers,
+		serviceName:   tc.ServiceName,
+		sampleRatio:   tc.SampleRatio,
+		batchSize:     tc.BatchSize,
+		flushInterval: tc.FlushInterval,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		queue:         make(chan *Span, queueSize),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if t.serviceName == "" {
+		t.serviceName = "gogdbllm"
+	}
+	if t.batchSize <= 0 {
+		t.batchSize = 256
+	}
+	if t.flushInterval <= 0 {
+		t.flushInterval = 5 * time.Second
+	}
+
+	if t.enabled {
+		go t.run()
+	} else {
+		close(t.stopped)
+	}
+	return t
+}
+
+// Enabled reports whether the tracer records spans
+func (t *Tracer) Enabled() bool {
+	return t != nil && t.enabled
+}
+
+// StartRoot starts the root span of a trace, continuing the trace of a W3C traceparent
+// header if one is given. It returns a nil span when tracing is disabled or the trace is
+// not sampled.
+func (t *Tracer) StartRoot(ctx context.Context, name string, kind int, traceparent string, attributes ...Attribute) (context.Context, *Span) {
+	if !t.Enabled() {
+		return ctx, nil
+	}
+
+	traceID, parentID, sampled, ok := parseTraceparent(traceparent)
+	if !ok {
+		traceID, parentID = newTraceID(), SpanID{}
+		sampled = sampledRatio(traceID, t.sampleRatio)
+	}
+	if !sampled {
+		return ctx, nil
+	}
+
+	span := t.newSpan(name, kind, traceID, parentID)
+	span.SetAttributes(attributes...)
+	return ContextWithSpan(ctx, span), span
+}
+
+// newSpan creates a started span
+func (t *Tracer) newSpan(name string, kind int, traceID TraceID, parentID SpanID) *Span {
+	return &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		traceID:    traceID,
+		spanID:     newSpanID(),
+		parentID:   parentID,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+}
+
+// enqueue queues an ended span for export, dropping it if the queue is full
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// Middleware traces each HTTP request with a server span named after its route,
+// continuing the caller's trace when the request has a traceparent header. Static files
+// and WebSocket connections are not traced.
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.Enabled() || strings.HasPrefix(r.URL.Path, "/static/") || r.URL.Path == "/ws" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		ctx, span := t.StartRoot(r.Context(), r.Method+" "+route, KindServer, r.Header.Get("traceparent"),
+			Attr("http.request.method", r.Method),
+			Attr("http.route", route),
+			Attr("url.path", r.URL.Path))
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+		if id := logger.RequestID(ctx); id != "" {
+			span.SetAttributes(Attr("http.request_id", id))
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(Attr("http.response.status_code", recorder.status))
+		if recorder.status >= 500 {
+			span.RecordError(fmt.Errorf("HTTP %d", recorder.status))
+		}
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches it
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Shutdown exports the queued spans and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run exports spans in batches until the tracer is shut down
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.batchSize)
+	flush := func() {
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			log.Printf("Dropped %d spans: the export queue was full", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			log.Printf("Exporting %d spans failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= t.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends a batch of spans to the collector
+func (t *Tracer) export(spans []*Span) error {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// OTLP/JSON structures, see opentelemetry-proto's trace.proto. Trace and span IDs are
+// hex strings and 64-bit integers decimal strings.
+type (
+	otlpExport struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// encode converts spans to an OTLP export request
+func (t *Tracer) encode(spans []*Span) otlpExport {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mutex.Lock()
+		s := otlpSpan{
+			TraceID:           span.traceID.String(),
+			SpanID:            span.spanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attributes),
+			Status:            otlpStatus{Code: span.status, Message: span.statusMsg},
+		}
+		if span.parentID != (SpanID{}) {
+			s.ParentSpanID = span.parentID.String()
+		}
+		span.mutex.Unlock()
+		encoded = append(encoded, s)
+	}
+
+	return otlpExport{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes(map[string]interface{}{
+			"service.name": t.serviceName,
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/yourusername/gogdbllm"},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+// encodeAttributes converts attributes to OTLP key-values
+func encodeAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	encoded := make([]otlpKeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		encoded = append(encoded, otlpKeyValue{Key: key, Value: encodeValue(value)})
+	}
+	return encoded
+}
+
+// encodeValue converts an attribute value to an OTLP any-value
+func encodeValue(value interface{}) otlpValue {
+	integer := func(i int64) otlpValue {
+		s := strconv.FormatInt(i, 10)
+		return otlpValue{IntValue: &s}
+	}
+	switch v := value.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		return integer(int64(v))
+	case int64:
+		return integer(v)
+	case uint64:
+		return integer(int64(v))
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case time.Duration:
+		return integer(v.Milliseconds())
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
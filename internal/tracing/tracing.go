@@ -0,0 +1,218 @@
+// Package tracing records OpenTelemetry spans for requests and exports them to an OTLP
+// collector over HTTP in the JSON encoding. Spans travel in context.Context: a root span
+// is started per HTTP request by Tracer.Middleware, and code further down starts child
+// spans with Start, which does nothing when the context carries no span.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as in the OTLP protocol
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Span status codes, as in the OTLP protocol
+const (
+	statusUnset = 0
+	statusOK    = 1
+	statusError = 2
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the ID in hex, as used in traceparent headers and OTLP/JSON
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// String returns the ID in hex, as used in traceparent headers and OTLP/JSON
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// Span is a timed operation within a trace. A nil *Span is valid and records nothing, so
+// callers need not check whether tracing is enabled.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	kind     int
+	traceID  TraceID
+	spanID   SpanID
+	parentID SpanID // Zero for a root span
+	start    time.Time
+
+	mutex      sync.Mutex
+	end        time.Time
+	attributes map[string]interface{}
+	status     int
+	statusMsg  string
+	ended      bool
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying span as the current span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the context's current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts a child of the context's current span and returns a context carrying it.
+// Without a current span it returns ctx and a nil span.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attributes...)
+}
+
+// StartKind is Start with a span kind, e.g. KindClient for calls to other services
+func StartKind(ctx context.Context, name string, kind int, attributes ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := parent.tracer.newSpan(name, kind, parent.traceID, parent.spanID)
+	span.SetAttributes(attributes...)
+	return ContextWithSpan(ctx, span), span
+}
+
+// Attribute is a key and value recorded on a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr returns an attribute. Values may be strings, bools, integers or floats; anything
+// else is recorded as its fmt.Sprint form.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SetAttributes records attributes on the span, replacing earlier values of the same keys
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil || len(attributes) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, attr := range attributes {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = statusError
+	s.statusMsg = err.Error()
+}
+
+// SetOK marks the span as successful
+func (s *Span) SetOK() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.status == statusUnset {
+		s.status = statusOK
+	}
+}
+
+// End ends the span and queues it for export. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+	s.tracer.enqueue(s)
+}
+
+// TraceID returns the span's trace ID, or "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID.String()
+}
+
+// Traceparent returns the W3C traceparent header value that continues the span's trace
+// in another service, or "" for a nil span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.traceID, s.spanID)
+}
+
+// parseTraceparent parses a W3C traceparent header. sampled reports the caller's
+// sampling decision.
+func parseTraceparent(header string) (traceID TraceID, parentID SpanID, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == (TraceID{}) {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == (SpanID{}) {
+		return traceID, parentID, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+// newTraceID returns a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+// newSpanID returns a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// sampledRatio reports whether a new trace falls within the sample ratio, deciding by
+// its ID so the decision is stable for the trace
+func sampledRatio(id TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>11)/float64(1<<53) < ratio
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestTracerExportsSpans(t *testing.T) {
+	var mutex sync.Mutex
+	var exports []otlpExport
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var export otlpExport
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&export))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		mutex.Lock()
+		exports = append(exports, export)
+		mutex.Unlock()
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(&config.Config{Tracing: config.TracingConfig{
+		Enabled:     true,
+		Endpoint:    collector.URL,
+		Headers:     map[string]string{"Authorization": "secret"},
+		SampleRatio: 1,
+	}})
+
+	// The root continues the caller's trace; children nest through the context
+	ctx, root := tracer.StartRoot(context.Background(), "POST /api/chat", KindServer,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NotNil(t, root)
+	childCtx, child := Start(ctx, "llm.call", Attr("gen_ai.usage.input_tokens", 42))
+	_, grandchild := Start(childCtx, "gdb.command")
+	grandchild.RecordError(errors.New("no symbol table"))
+	grandchild.End()
+	child.End()
+	root.End()
+	root.End() // Ending twice exports once
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, tracer.Shutdown(shutdownCtx))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, exports, 1)
+	spans := exports[0].ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 3)
+	byName := make(map[string]otlpSpan)
+	for _, span := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+		byName[span.Name] = span
+	}
+	assert.Equal(t, "00f067aa0ba902b7", byName["POST /api/chat"].ParentSpanID)
+	assert.Equal(t, byName["POST /api/chat"].SpanID, byName["llm.call"].ParentSpanID)
+	assert.Equal(t, byName["llm.call"].SpanID, byName["gdb.command"].ParentSpanID)
+	assert.Equal(t, statusError, byName["gdb.command"].Status.Code)
+	require.Len(t, byName["llm.call"].Attributes, 1)
+	assert.Equal(t, "42", *byName["llm.call"].Attributes[0].Value.IntValue)
+}
+
+func TestTracingWithoutSpan(t *testing.T) {
+	// Without a span in the context nothing is recorded, and nil spans are safe to use
+	ctx, span := Start(context.Background(), "chat.process")
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+	span.SetAttributes(Attr("key", "value"))
+	span.RecordError(errors.New("ignored"))
+	span.End()
+
+	disabled := NewTracer(&config.Config{})
+	_, root := disabled.StartRoot(context.Background(), "GET /", KindServer, "")
+	assert.Nil(t, root)
+	assert.NoError(t, disabled.Shutdown(context.Background()))
+
+	// A caller that did not sample the trace is respected
+	sampled := NewTracer(&config.Config{Tracing: config.TracingConfig{Enabled: true, Endpoint: "http://127.0.0.1:1", SampleRatio: 1}})
+	_, root = sampled.StartRoot(context.Background(), "GET /", KindServer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Nil(t, root)
+	assert.NoError(t, sampled.Shutdown(context.Background()))
+}
@@ -0,0 +1,161 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxCommentLength truncates long explanations so the script stays readable
+const maxCommentLength = 1500
+
+// commentWidth is the line width comments are wrapped to
+const commentWidth = 76
+
+// quitCommands end GDB and are dropped so the script leaves the session open
+var quitCommands = map[string]bool{"q": true, "quit": true, "exit": true}
+
+// GDBScriptExporter transcribes the commands of a session into a GDB script. The user's
+// questions and the assistant's explanations are kept as comments above the commands they led to.
+type GDBScriptExporter struct{}
+
+// NewGDBScriptExporter creates a GDB script exporter
+func NewGDBScriptExporter() *GDBScriptExporter {
+	return &GDBScriptExporter{}
+}
+
+// Name implements Exporter
+func (e *GDBScriptExporter) Name() string { return "gdb" }
+
+// FileExtension implements Exporter
+func (e *GDBScriptExporter) FileExtension() string { return ".gdb" }
+
+// ContentType implements Exporter
+func (e *GDBScriptExporter) ContentType() string { return "text/x-gdb; charset=utf-8" }
+
+// Export implements Exporter
+func (e *GDBScriptExporter) Export(w io.Writer, session *Session) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# GDB script transcribed from session %s\n", session.ID))
+	sb.WriteString(fmt.Sprintf("# Generated %s\n", time.Now().Format(time.RFC3339)))
+	if filename := session.Metadata("session.filename"); filename != "" {
+		sb.WriteString(fmt.Sprintf("#\n# Run with: gdb -x %s%s %s\n", session.ID, e.FileExtension(), filename))
+	} else {
+		sb.WriteString(fmt.Sprintf("#\n# Run with: gdb -x %s%s <executable>\n", session.ID, e.FileExtension()))
+	}
+	sb.WriteString("\nset pagination off\nset confirm off\n")
+
+	var question, explanation, lastExplanation string
+	// flush writes the pending question and explanation as comments
+	flush := func() {
+		if question == "" && explanation == "" {
+			return
+		}
+		sb.WriteString("\n")
+		if question != "" {
+			writeComment(&sb, "Question: "+question)
+			question = ""
+		}
+		if explanation != "" {
+			writeComment(&sb, "Assistant: "+explanation)
+			explanation = ""
+		}
+	}
+
+	commands := 0
+	for _, entry := range session.Entries {
+		switch entry.Type {
+		case EventUserInput:
+			flush()
+			question = strings.TrimSpace(entry.String("user.message"))
+
+		case EventLLMResponse:
+			text := explanationText(entry.String("llm.response.body"))
+			if text != "" && text != lastExplanation {
+				if explanation != "" {
+					flush()
+				}
+				explanation = text
+				lastExplanation = text
+			}
+
+		case EventGDBCommand:
+			command, ok := CleanCommand(entry.String("gdb.command"))
+			if !ok {
+				continue
+			}
+
+			flush()
+			if entry.String("gdb.command.source") == "llm" {
+				sb.WriteString("# (run by the assistant)\n")
+			}
+			sb.WriteString(command + "\n")
+			commands++
+		}
+	}
+	flush()
+
+	if commands == 0 {
+		sb.WriteString("\n# No commands were recorded in this session\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// explanationText extracts the explanation from an LLM response body, which is either
+// the structured JSON response or plain text
+func explanationText(body string) string {
+	body = strings.TrimSpace(body)
+	if strings.HasPrefix(body, "{") {
+		var resp struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal([]byte(body), &resp); err == nil {
+			return strings.TrimSpace(resp.Text)
+		}
+	}
+	return body
+}
+
+// CleanCommand cleans a recorded command, rejecting blank lines, control keys and quit
+func CleanCommand(command string) (string, bool) {
+	command = strings.TrimSpace(command)
+	if command == "" || quitCommands[command] {
+		return "", false
+	}
+	for _, r := range command {
+		if r < 0x20 || r == 0x7f {
+			return "", false
+		}
+	}
+	return command, true
+}
+
+// writeComment writes text as word-wrapped "# " comment lines
+func writeComment(sb *strings.Builder, text string) {
+	if len(text) > maxCommentLength {
+		text = text[:maxCommentLength] + "..."
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			sb.WriteString("#\n")
+			continue
+		}
+
+		line := "#"
+		for _, word := range words {
+			if len(line)+1+len(word) > commentWidth && line != "#" {
+				sb.WriteString(line + "\n")
+				line = "#"
+			}
+			line += " " + word
+		}
+		sb.WriteString(line + "\n")
+	}
+}
@@ -0,0 +1,56 @@
+package transcript
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sessionLog = `{"event.type":"session.metadata","session.filename":"crash"}
+{"event.type":"gdb.command","gdb.command":"break main","gdb.command.source":"user"}
+{"event.type":"gdb.command","gdb.command":"run","gdb.command.source":"user"}
+{"event.type":"user.input","user.message":"Why did it crash?"}
+{"event.type":"llm.response","llm.response.body":"{\"text\": \"p is NULL; check the backtrace.\", \"gdbCommands\": [\"bt\"], \"waitForOutput\": true}"}
+{"event.type":"gdb.command","gdb.command":"bt","gdb.command.source":"llm"}
+{"event.type":"llm.response","llm.response.body":"The crash is in parse() at line 12."}
+not json
+{"event.type":"gdb.command","gdb.command":"\u0003","gdb.command.source":"user"}
+{"event.type":"gdb.command","gdb.command":"quit","gdb.command.source":"user"}
+`
+
+func TestGDBScriptExport(t *testing.T) {
+	session, err := ReadSession("20240101_120000_crash", strings.NewReader(sessionLog))
+	require.NoError(t, err)
+	assert.Equal(t, "crash", session.Metadata("session.filename"))
+
+	var buf bytes.Buffer
+	require.NoError(t, NewGDBScriptExporter().Export(&buf, session))
+	script := buf.String()
+
+	assert.Contains(t, script, "# Run with: gdb -x 20240101_120000_crash.gdb crash\n")
+	assert.Contains(t, script, "break main\nrun\n")
+	assert.Contains(t, script, "# Question: Why did it crash?\n# Assistant: p is NULL; check the backtrace.\n# (run by the assistant)\nbt\n")
+	assert.Contains(t, script, "# Assistant: The crash is in parse() at line 12.\n")
+	assert.NotContains(t, script, "\x03")
+	assert.NotContains(t, script, "quit")
+
+	// Every non-comment line must be a command GDB can read
+	for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		assert.Contains(t, []string{"set pagination off", "set confirm off", "break main", "run", "bt"}, line)
+	}
+}
+
+func TestWriteCommentWraps(t *testing.T) {
+	var sb strings.Builder
+	writeComment(&sb, strings.Repeat("word ", 40))
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n") {
+		assert.True(t, strings.HasPrefix(line, "# "))
+		assert.LessOrEqual(t, len(line), commentWidth)
+	}
+}
@@ -0,0 +1,315 @@
+package transcript
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxReportOutputLines is how many lines of a command's output a report shows
+const maxReportOutputLines = 20
+
+// executorOutputPrefixes mark the progress lines the chat's GDB executor logs as terminal
+// output; they are not GDB's output and are left out of reports
+var executorOutputPrefixes = []string{"=== ", "Executing command ", "Command output (", "Command failed: ", "(LLM-Capture) "}
+
+// report is a session arranged for reading: the commands run before the first question,
+// then each question with the assistant's answers and the commands that followed it
+type report struct {
+	ID         string
+	Executable string
+	Owner      string
+	Lab        string
+	Started    time.Time
+	Ended      time.Time
+	Turns      []reportTurn
+	Commands   int
+	Conclusion string // The assistant's last explanation
+}
+
+// reportTurn is a question and what followed it; the first turn may have no question
+type reportTurn struct {
+	Question string
+	Answers  []string
+	Commands []reportCommand
+}
+
+// reportCommand is a GDB command and the start of its output
+type reportCommand struct {
+	Command   string
+	ByLLM     bool
+	Output    []string
+	Truncated int // Output lines left out
+}
+
+// buildReport arranges a session's log entries into a report
+func buildReport(session *Session) *report {
+	r := &report{
+		ID:         session.ID,
+		Executable: session.Metadata("session.filename"),
+		Owner:      session.Metadata("session.owner"),
+		Lab:        session.Metadata("session.lab"),
+	}
+
+	turn := &reportTurn{}
+	var command *reportCommand
+	lastAnswer := ""
+	for _, entry := range session.Entries {
+		if !entry.Timestamp.IsZero() {
+			if r.Started.IsZero() {
+				r.Started = entry.Timestamp
+			}
+			r.Ended = entry.Timestamp
+		}
+
+		switch entry.Type {
+		case EventUserInput:
+			if turn.Question != "" || len(turn.Answers) > 0 || len(turn.Commands) > 0 {
+				r.Turns = append(r.Turns, *turn)
+			}
+			turn = &reportTurn{Question: strings.TrimSpace(entry.String("user.message"))}
+			command = nil
+
+		case EventLLMResponse:
+			text := explanationText(entry.String("llm.response.body"))
+			if text != "" && text != lastAnswer {
+				turn.Answers = append(turn.Answers, text)
+				lastAnswer = text
+				r.Conclusion = text
+			}
+
+		case EventGDBCommand:
+			text, ok := CleanCommand(entry.String("gdb.command"))
+			if !ok {
+				continue
+			}
+			turn.Commands = append(turn.Commands, reportCommand{Command: text, ByLLM: entry.String("gdb.command.source") == "llm"})
+			command = &turn.Commands[len(turn.Commands)-1]
+			r.Commands++
+
+		case EventGDBOutput:
+			if command == nil {
+				continue
+			}
+			line := strings.TrimRight(entry.String("gdb.output"), " \r\n")
+			if isExecutorOutput(line) || (line == "" && len(command.Output) == 0) {
+				continue
+			}
+			if len(command.Output) < maxReportOutputLines {
+				command.Output = append(command.Output, line)
+			} else {
+				command.Truncated++
+			}
+		}
+	}
+	if turn.Question != "" || len(turn.Answers) > 0 || len(turn.Commands) > 0 {
+		r.Turns = append(r.Turns, *turn)
+	}
+	return r
+}
+
+// isExecutorOutput reports whether a logged output line is the chat executor's progress
+func isExecutorOutput(line string) bool {
+	for _, prefix := range executorOutputPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkdownExporter writes a session as a Markdown report of the questions asked, the
+// assistant's answers, the commands run with the start of their output, and the
+// conclusion, for pasting into bug trackers
+type MarkdownExporter struct{}
+
+// NewMarkdownExporter creates a Markdown report exporter
+func NewMarkdownExporter() *MarkdownExporter {
+	return &MarkdownExporter{}
+}
+
+// Name implements Exporter
+func (e *MarkdownExporter) Name() string { return "markdown" }
+
+// FileExtension implements Exporter
+func (e *MarkdownExporter) FileExtension() string { return ".md" }
+
+// ContentType implements Exporter
+func (e *MarkdownExporter) ContentType() string { return "text/markdown; charset=utf-8" }
+
+// Export implements Exporter
+func (e *MarkdownExporter) Export(w io.Writer, session *Session) error {
+	r := buildReport(session)
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# Debugging session %s\n\n", r.ID)
+	if r.Executable != "" {
+		fmt.Fprintf(&sb, "- **Executable:** `%s`\n", r.Executable)
+	}
+	if r.Lab != "" {
+		fmt.Fprintf(&sb, "- **Lab:** %s\n", r.Lab)
+	}
+	if r.Owner != "" {
+		fmt.Fprintf(&sb, "- **User:** %s\n", r.Owner)
+	}
+	if !r.Started.IsZero() {
+		fmt.Fprintf(&sb, "- **Time:** %s to %s\n", r.Started.Format(time.RFC3339), r.Ended.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&sb, "- **GDB commands:** %d\n", r.Commands)
+
+	if r.Conclusion != "" {
+		sb.WriteString("\n## Conclusion\n\n")
+		sb.WriteString(r.Conclusion + "\n")
+	}
+
+	for i, turn := range r.Turns {
+		switch {
+		case turn.Question != "":
+			fmt.Fprintf(&sb, "\n## %d. %s\n", i+1, markdownHeading(turn.Question))
+			if strings.Contains(turn.Question, "\n") || len(turn.Question) > 80 {
+				sb.WriteString("\n" + markdownQuote(turn.Question) + "\n")
+			}
+		default:
+			sb.WriteString("\n## Commands before the first question\n")
+		}
+		for _, answer := range turn.Answers {
+			sb.WriteString("\n**Assistant:** " + answer + "\n")
+		}
+		for _, command := range turn.Commands {
+			by := ""
+			if command.ByLLM {
+				by = " (run by the assistant)"
+			}
+			fmt.Fprintf(&sb, "\n`(gdb) %s`%s\n", command.Command, by)
+			if len(command.Output) > 0 {
+				fence := markdownFence(command.Output)
+				sb.WriteString("\n" + fence + "\n" + strings.Join(command.Output, "\n") + "\n")
+				if command.Truncated > 0 {
+					fmt.Fprintf(&sb, "... %d more lines\n", command.Truncated)
+				}
+				sb.WriteString(fence + "\n")
+			}
+		}
+	}
+
+	if len(r.Turns) == 0 {
+		sb.WriteString("\nNothing was recorded in this session.\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownHeading shortens text to a single-line heading
+func markdownHeading(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if len(line) > 80 {
+		line = line[:77] + "..."
+	}
+	return line
+}
+
+// markdownQuote formats text as a block quote
+func markdownQuote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+// markdownFence returns a code fence longer than any backtick run in the lines
+func markdownFence(lines []string) string {
+	fence := "```"
+	for _, line := range lines {
+		for strings.Contains(line, fence) {
+			fence += "`"
+		}
+	}
+	return fence
+}
+
+// HTMLExporter writes the report of MarkdownExporter as a standalone HTML page
+type HTMLExporter struct{}
+
+// NewHTMLExporter creates an HTML report exporter
+func NewHTMLExporter() *HTMLExporter {
+	return &HTMLExporter{}
+}
+
+// Name implements Exporter
+func (e *HTMLExporter) Name() string { return "html" }
+
+// FileExtension implements Exporter
+func (e *HTMLExporter) FileExtension() string { return ".html" }
+
+// ContentType implements Exporter
+func (e *HTMLExporter) ContentType() string { return "text/html; charset=utf-8" }
+
+// Export implements Exporter
+func (e *HTMLExporter) Export(w io.Writer, session *Session) error {
+	return htmlReport.Execute(w, buildReport(session))
+}
+
+// htmlReport renders a report; html/template escapes everything taken from the log
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"join": strings.Join,
+	"inc":  func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Debugging session {{.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+pre { background: #f5f5f5; border: 1px solid #ddd; padding: 0.6em; overflow-x: auto; }
+code { font-family: Menlo, Consolas, monospace; }
+.question { white-space: pre-wrap; }
+.answer { white-space: pre-wrap; background: #eef5ff; border-left: 3px solid #4a8ad4; padding: 0.4em 0.8em; }
+.by-llm { color: #666; font-size: 0.9em; }
+.conclusion { white-space: pre-wrap; background: #eefbea; border-left: 3px solid #4aa04a; padding: 0.4em 0.8em; }
+</style>
+</head>
+<body>
+<h1>Debugging session {{.ID}}</h1>
+<ul>
+{{- if .Executable}}
+<li><strong>Executable:</strong> <code>{{.Executable}}</code></li>
+{{- end}}
+{{- if .Lab}}
+<li><strong>Lab:</strong> {{.Lab}}</li>
+{{- end}}
+{{- if .Owner}}
+<li><strong>User:</strong> {{.Owner}}</li>
+{{- end}}
+{{- if not .Started.IsZero}}
+<li><strong>Time:</strong> {{time .Started}} to {{time .Ended}}</li>
+{{- end}}
+<li><strong>GDB commands:</strong> {{.Commands}}</li>
+</ul>
+{{- if .Conclusion}}
+<h2>Conclusion</h2>
+<div class="conclusion">{{.Conclusion}}</div>
+{{- end}}
+{{- range $i, $turn := .Turns}}
+{{- if $turn.Question}}
+<h2>{{inc $i}}. Question</h2>
+<p class="question">{{$turn.Question}}</p>
+{{- else}}
+<h2>Commands before the first question</h2>
+{{- end}}
+{{- range $turn.Answers}}
+<div class="answer">{{.}}</div>
+{{- end}}
+{{- range $turn.Commands}}
+<p><code>(gdb) {{.Command}}</code>{{if .ByLLM}} <span class="by-llm">(run by the assistant)</span>{{end}}</p>
+{{- if .Output}}
+<pre>{{join .Output "\n"}}{{if .Truncated}}
+... {{.Truncated}} more lines{{end}}</pre>
+{{- end}}
+{{- end}}
+{{- else}}
+<p>Nothing was recorded in this session.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,56 @@
+package transcript
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportLog = `{"timestamp":"2024-01-01T12:00:00Z","event.type":"session.metadata","session.filename":"crash","session.owner":"alice"}
+{"timestamp":"2024-01-01T12:00:01Z","event.type":"gdb.command","gdb.command":"run","gdb.command.source":"user"}
+{"timestamp":"2024-01-01T12:00:02Z","event.type":"gdb.output","gdb.output":"Program received signal SIGSEGV, Segmentation fault."}
+{"timestamp":"2024-01-01T12:00:03Z","event.type":"user.input","user.message":"Why did it crash? <script>"}
+{"timestamp":"2024-01-01T12:00:04Z","event.type":"llm.response","llm.response.body":"{\"text\": \"Let's look at the stack.\", \"gdbCommands\": [\"bt\"]}"}
+{"timestamp":"2024-01-01T12:00:05Z","event.type":"gdb.output","gdb.output":"Executing command 1/1: bt"}
+{"timestamp":"2024-01-01T12:00:05Z","event.type":"gdb.command","gdb.command":"bt","gdb.command.source":"llm"}
+{"timestamp":"2024-01-01T12:00:06Z","event.type":"gdb.output","gdb.output":"#0  parse (p=0x0) at crash.c:12"}
+{"timestamp":"2024-01-01T12:00:06Z","event.type":"gdb.output","gdb.output":"Command output (34 chars): #0  parse (p=0x0) at crash.c:12"}
+{"timestamp":"2024-01-01T12:00:07Z","event.type":"llm.response","llm.response.body":"parse() dereferences a NULL p at line 12."}
+`
+
+func TestMarkdownReport(t *testing.T) {
+	session, err := ReadSession("20240101_120000_crash", strings.NewReader(reportLog))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, NewMarkdownExporter().Export(&buf, session))
+	md := buf.String()
+
+	assert.Contains(t, md, "# Debugging session 20240101_120000_crash\n")
+	assert.Contains(t, md, "- **Executable:** `crash`\n")
+	assert.Contains(t, md, "- **GDB commands:** 2\n")
+	assert.Contains(t, md, "## Conclusion\n\nparse() dereferences a NULL p at line 12.\n")
+	assert.Contains(t, md, "## Commands before the first question\n\n`(gdb) run`\n\n```\nProgram received signal SIGSEGV, Segmentation fault.\n```\n")
+	assert.Contains(t, md, "## 2. Why did it crash? <script>\n\n**Assistant:** Let's look at the stack.\n")
+	assert.Contains(t, md, "`(gdb) bt` (run by the assistant)\n\n```\n#0  parse (p=0x0) at crash.c:12\n```\n")
+	assert.NotContains(t, md, "Executing command")
+	assert.NotContains(t, md, "Command output")
+}
+
+func TestHTMLReportEscapes(t *testing.T) {
+	session, err := ReadSession("s1", strings.NewReader(reportLog))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, NewHTMLExporter().Export(&buf, session))
+	page := buf.String()
+
+	assert.Contains(t, page, "<title>Debugging session s1</title>")
+	assert.Contains(t, page, "Why did it crash? &lt;script&gt;")
+	assert.NotContains(t, page, "<script>")
+	assert.Contains(t, page, "<pre>#0  parse (p=0x0) at crash.c:12</pre>")
+	assert.Contains(t, page, `<span class="by-llm">(run by the assistant)</span>`)
+}
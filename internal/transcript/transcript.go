@@ -0,0 +1,145 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Session log event types used by the exporters
+const (
+	EventMetadata    = "session.metadata"
+	EventUserInput   = "user.input"
+	EventLLMResponse = "llm.response"
+	EventGDBCommand  = "gdb.command"
+	EventInput       = "program.input"
+	EventGDBOutput   = "gdb.output"
+)
+
+// Entry is a single event from a session log
+type Entry struct {
+	Timestamp time.Time
+	Type      string
+	Fields    map[string]interface{}
+}
+
+// String returns a string field of the entry, or "" if it is missing
+func (e Entry) String(field string) string {
+	s, _ := e.Fields[field].(string)
+	return s
+}
+
+// Session is a parsed session log
+type Session struct {
+	ID      string
+	Entries []Entry
+}
+
+// Metadata returns a string value recorded in the session's metadata events
+func (s *Session) Metadata(field string) string {
+	for _, entry := range s.Entries {
+		if entry.Type == EventMetadata {
+			if value := entry.String(field); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// LoadSession reads a JSON Lines session log from disk
+func LoadSession(sessionID, path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session log: %w", err)
+	}
+	defer file.Close()
+
+	return ReadSession(sessionID, file)
+}
+
+// ReadSession parses a JSON Lines session log. Lines that are not valid JSON are skipped.
+func ReadSession(sessionID string, r io.Reader) (*Session, error) {
+	session := &Session{ID: sessionID}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // GDB output lines can be large
+	for scanner.Scan() {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			continue
+		}
+
+		entry := Entry{Fields: fields}
+		entry.Type, _ = fields["event.type"].(string)
+		if ts, ok := fields["timestamp"].(string); ok {
+			entry.Timestamp, _ = time.Parse(time.RFC3339Nano, ts)
+		}
+		session.Entries = append(session.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session log: %w", err)
+	}
+
+	return session, nil
+}
+
+// Exporter converts a session into a downloadable document
+type Exporter interface {
+	// Name identifies the exporter in the ?format= query parameter
+	Name() string
+	// FileExtension is appended to the session ID to name the download, e.g. ".gdb"
+	FileExtension() string
+	// ContentType is the MIME type of the exported document
+	ContentType() string
+	// Export writes the document for a session
+	Export(w io.Writer, session *Session) error
+}
+
+// Registry holds the available exporters
+type Registry struct {
+	exporters map[string]Exporter
+	mutex     sync.RWMutex
+}
+
+// NewRegistry creates a registry with the built-in exporters
+func NewRegistry() *Registry {
+	r := &Registry{exporters: make(map[string]Exporter)}
+	r.Register(NewGDBScriptExporter())
+	r.Register(NewMarkdownExporter())
+	r.Register(NewHTMLExporter())
+	return r
+}
+
+// Register adds an exporter, replacing any exporter with the same name
+func (r *Registry) Register(exporter Exporter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.exporters[exporter.Name()] = exporter
+}
+
+// Get returns the exporter with the given name
+func (r *Registry) Get(name string) (Exporter, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	exporter, ok := r.exporters[name]
+	return exporter, ok
+}
+
+// Names returns the registered exporter names in sorted order
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.exporters))
+	for name := range r.exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
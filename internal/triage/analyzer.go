@@ -0,0 +1,51 @@
+package triage
+
+import (
+	"context"
+
+	"github.com/yourusername/gogdbllm/internal/analyze"
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/config"
+	"github.com/yourusername/gogdbllm/internal/features"
+	"github.com/yourusername/gogdbllm/internal/prompts"
+	"github.com/yourusername/gogdbllm/internal/settings"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// NewAnalyzer returns the analyzer the server runs jobs with: the analyze command's agent
+// loop, in a GDB session started on the job's binary and logged for replay
+func NewAnalyzer(
+	cfg *config.Config,
+	hub *websocket.Hub,
+	settingsManager *settings.Manager,
+	featureManager *features.Manager,
+	responseCache *api.ResponseCache,
+	promptEngine *prompts.Engine,
+) Analyzer {
+	return func(ctx context.Context, crash Crash) (*analyze.Report, error) {
+		if _, err := promptEngine.Profile(crash.Profile); err != nil {
+			return nil, err
+		}
+		session, err := analyze.StartSession(cfg, hub, analyze.SessionOptions{
+			Executable: crash.Executable,
+			Core:       crash.Core,
+			Args:       crash.Args,
+			Kind:       "triage",
+			Metadata:   map[string]interface{}{"session.analyze": crash.Question},
+			Features:   featureManager,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer session.Close()
+
+		processor := api.NewChatProcessor(settingsManager, session.Logs, session.GDB, featureManager, cfg.Chat, responseCache, promptEngine)
+		report, err := analyze.Run(ctx, processor, analyze.Options{
+			Question: crash.Question,
+			MaxTurns: cfg.Triage.MaxTurns,
+			Profile:  crash.Profile,
+		})
+		report.Session = session.ID
+		return report, err
+	}
+}
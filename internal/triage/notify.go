@@ -0,0 +1,116 @@
+package triage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// defaultGitHubAPI is used when a GitHub integration names no API URL
+const defaultGitHubAPI = "https://api.github.com"
+
+// Limits of the posted text: GitHub refuses comments over 65536 characters, and Slack
+// truncates long messages
+const (
+	maxCommentSize = 60000
+	maxSlackSize   = 3000
+)
+
+// post sends a finished job to its integration
+func post(ctx context.Context, client *http.Client, integration config.TriageIntegration, job Job) error {
+	switch integration.Kind {
+	case config.TriageGitHub:
+		api := strings.TrimRight(integration.URL, "/")
+		if api == "" {
+			api = defaultGitHubAPI
+		}
+		url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", api, integration.Repository, job.PullRequest)
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+integration.GitHubToken)
+		header.Set("Accept", "application/vnd.github+json")
+		return postJSON(ctx, client, url, header, map[string]string{"body": truncate(markdown(job), maxCommentSize)})
+	case config.TriageSlack:
+		return postJSON(ctx, client, integration.URL, nil, map[string]string{"text": truncate(slackText(job), maxSlackSize)})
+	default:
+		return postJSON(ctx, client, integration.URL, nil, job)
+	}
+}
+
+// postJSON POSTs body as JSON and fails unless the answer is a success
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// markdown is a job's report as a pull request comment
+func markdown(job Job) string {
+	var sb strings.Builder
+	sb.WriteString(heading(job, "**", func(text, url string) string { return "[" + text + "](" + url + ")" }))
+	sb.WriteString("\n\n")
+	if job.Report != nil {
+		job.Report.WriteText(&sb)
+	}
+	if job.Error != "" {
+		fmt.Fprintf(&sb, "\nThe triage failed: %s\n", job.Error)
+	}
+	return sb.String()
+}
+
+// slackText is the answer of a job's report as a Slack message
+func slackText(job Job) string {
+	text := heading(job, "*", func(text, url string) string { return "<" + url + "|" + text + ">" })
+	if job.Report != nil && job.Report.Answer != "" {
+		text += "\n" + strings.TrimSpace(job.Report.Answer)
+	}
+	if job.Error != "" {
+		text += "\nThe triage failed: " + job.Error
+	}
+	return text
+}
+
+// heading names the crash and links the build in the message format's syntax
+func heading(job Job, bold string, link func(text, url string) string) string {
+	text := fmt.Sprintf("%sCrash triage of %s%s", bold, job.Executable, bold)
+	if job.Ref != "" {
+		text += " at " + job.Ref
+	}
+	if job.BuildURL != "" {
+		text += " (" + link("build", job.BuildURL) + ")"
+	}
+	return text
+}
+
+// truncate cuts text to at most size bytes, saying so
+func truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	const note = "\n\n(truncated)"
+	return strings.ToValidUTF8(text[:size-len(note)], "") + note
+}
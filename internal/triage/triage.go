@@ -0,0 +1,443 @@
+// Package triage accepts crashes from CI systems: a binary, optionally its core dump and
+// standard error. Each crash is analyzed by the assistant in a GDB session of its own, one
+// at a time, and the report is posted back to the integration that sent it: as a pull
+// request comment, a Slack message or a JSON webhook.
+package triage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/gogdbllm/internal/analyze"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Job statuses
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// maxStderr is how much of the end of the standard error the assistant is shown
+const maxStderr = 8 * 1024
+
+// maxJobs is how many jobs are remembered for status requests; their files stay on disk
+const maxJobs = 100
+
+// Crash is what an analysis is given
+type Crash struct {
+	Executable string // Absolute path
+	Core       string // Absolute path of the core dump, optional
+	Args       string
+	Question   string
+	Profile    string
+}
+
+// Analyzer analyzes a crash; NewAnalyzer's runs the assistant on it
+type Analyzer func(ctx context.Context, crash Crash) (*analyze.Report, error)
+
+// Job is a crash posted by an integration
+type Job struct {
+	ID          string          `json:"id"`
+	Integration string          `json:"integration"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Executable  string          `json:"executable"` // Name of the binary
+	PullRequest int             `json:"pullRequest,omitempty"`
+	Ref         string          `json:"ref,omitempty"`      // Commit or branch the crash came from
+	BuildURL    string          `json:"buildUrl,omitempty"` // The CI run, linked from the report
+	Created     time.Time       `json:"created"`
+	Finished    *time.Time      `json:"finished,omitempty"`
+	Report      *analyze.Report `json:"report,omitempty"`
+	Posted      bool            `json:"posted"`
+	PostError   string          `json:"postError,omitempty"`
+
+	crash Crash
+	dir   string
+}
+
+// Handler serves the triage endpoint and runs its jobs
+type Handler struct {
+	cfg          config.TriageConfig
+	integrations []config.TriageIntegration
+	analyze      Analyzer
+	client       *http.Client
+	queue        chan *Job
+
+	mutex sync.Mutex
+	jobs  map[string]*Job
+	order []string // Job IDs, oldest first
+}
+
+// NewHandler creates the triage handler and, when the endpoint is enabled, starts running
+// the jobs posted to it
+func NewHandler(cfg config.TriageConfig, analyzer Analyzer) (*Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = 16
+	}
+	h := &Handler{
+		cfg:          cfg,
+		integrations: cfg.Integrations,
+		analyze:      analyzer,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		queue:        make(chan *Job, cfg.MaxQueued),
+		jobs:         make(map[string]*Job),
+	}
+	if cfg.Enabled {
+		if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create triage directory: %w", err)
+		}
+		go h.work()
+	}
+	return h, nil
+}
+
+// HandleSubmit queues a crash, e.g. POST /api/triage with the multipart fields executable,
+// and optionally core, stderr, args, question, pullRequest, ref and buildUrl. It answers
+// 202 with the job; GET /api/triage/{id} follows it.
+func (h *Handler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	integration, ok := h.authenticate(r)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "A triage integration token is required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxUploadSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "The crash artifacts exceed triage.max_upload_size")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "Invalid form: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	job, err := h.newJob(integration, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.mutex.Lock()
+	select {
+	case h.queue <- job:
+		h.remember(job)
+		h.mutex.Unlock()
+	default:
+		h.mutex.Unlock()
+		os.RemoveAll(job.dir)
+		writeJSONError(w, http.StatusServiceUnavailable, "Too many crashes are waiting to be triaged")
+		return
+	}
+
+	log.Printf("Triage job %s queued by %s for %s", job.ID, integration.Name, job.Executable)
+	writeJSON(w, http.StatusAccepted, h.snapshot(job))
+}
+
+// HandleStatus returns one of the integration's jobs, e.g. GET /api/triage/{id}
+func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	integration, ok := h.authenticate(r)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "A triage integration token is required")
+		return
+	}
+
+	h.mutex.Lock()
+	job, found := h.jobs[mux.Vars(r)["id"]]
+	h.mutex.Unlock()
+	if !found || job.Integration != integration.Name {
+		writeJSONError(w, http.StatusNotFound, "No such triage job")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.snapshot(job))
+}
+
+// authenticate returns the integration whose token the request carries
+func (h *Handler) authenticate(r *http.Request) (config.TriageIntegration, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return config.TriageIntegration{}, false
+	}
+	for _, integration := range h.integrations {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(integration.Token)) == 1 {
+			return integration, true
+		}
+	}
+	return config.TriageIntegration{}, false
+}
+
+// newJob saves a request's artifacts to a directory of the job's own
+func (h *Handler) newJob(integration config.TriageIntegration, r *http.Request) (*Job, error) {
+	job := &Job{
+		Integration: integration.Name,
+		Status:      StatusQueued,
+		Ref:         r.FormValue("ref"),
+		BuildURL:    r.FormValue("buildUrl"),
+		Created:     time.Now(),
+	}
+	if value := r.FormValue("pullRequest"); value != "" {
+		number, err := strconv.Atoi(value)
+		if err != nil || number <= 0 {
+			return nil, errors.New("pullRequest must be a pull request number")
+		}
+		job.PullRequest = number
+	}
+	if integration.Kind == config.TriageGitHub && job.PullRequest == 0 {
+		return nil, errors.New("pullRequest is required to comment on GitHub")
+	}
+
+	args := r.FormValue("args")
+	if strings.ContainsAny(args, "\r\n") {
+		return nil, errors.New("args must be a single line")
+	}
+
+	executable, header, err := r.FormFile("executable")
+	if err != nil {
+		return nil, errors.New("the executable is required")
+	}
+	defer executable.Close()
+	job.Executable = filepath.Base(header.Filename)
+	if job.Executable == string(filepath.Separator) || strings.Contains(job.Executable, "..") || job.Executable == "." {
+		return nil, fmt.Errorf("invalid executable name %q", header.Filename)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job.ID = id
+	job.dir, err = filepath.Abs(filepath.Join(h.cfg.Directory, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(job.dir, 0755); err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*Job, error) {
+		os.RemoveAll(job.dir)
+		return nil, err
+	}
+
+	// The binary has a directory of its own, where GDB is started on it
+	job.crash.Executable = filepath.Join(job.dir, "bin", job.Executable)
+	if err := os.Mkdir(filepath.Dir(job.crash.Executable), 0755); err != nil {
+		return fail(err)
+	}
+	if err := saveFile(executable, job.crash.Executable, 0755); err != nil {
+		return fail(err)
+	}
+
+	if core, _, err := r.FormFile("core"); err == nil {
+		defer core.Close()
+		job.crash.Core = filepath.Join(job.dir, "core")
+		if err := saveFile(core, job.crash.Core, 0644); err != nil {
+			return fail(err)
+		}
+	}
+
+	stderr := r.FormValue("stderr")
+	if file, _, err := r.FormFile("stderr"); err == nil {
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return fail(err)
+		}
+		stderr = string(data)
+	}
+	if stderr != "" {
+		if err := os.WriteFile(filepath.Join(job.dir, "stderr.txt"), []byte(stderr), 0644); err != nil {
+			return fail(err)
+		}
+	}
+
+	job.crash.Args = args
+	job.crash.Question = question(r.FormValue("question"), job.crash.Core != "", stderr)
+	job.crash.Profile = h.cfg.Profile
+	if job.crash.Profile == "" && job.crash.Core != "" {
+		// Running the program again would lose the state the core dump holds
+		job.crash.Profile = "triage"
+	}
+	return job, nil
+}
+
+// question adds the end of the program's standard error to the question asked
+func question(asked string, core bool, stderr string) string {
+	if asked == "" {
+		asked = analyze.DefaultQuestion
+		if core {
+			asked = analyze.CoreQuestion
+		}
+	}
+	stderr = strings.TrimRight(stderr, "\n")
+	if stderr == "" {
+		return asked
+	}
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return fmt.Sprintf("%s\n\nThe program's standard error in CI ended with:\n```\n%s\n```", asked, stderr)
+}
+
+// work runs the queued jobs one at a time
+func (h *Handler) work() {
+	for job := range h.queue {
+		h.run(job)
+	}
+}
+
+// run analyzes a job's crash, keeps its report and posts it to the integration
+func (h *Handler) run(job *Job) {
+	h.update(job, func(j *Job) { j.Status = StatusRunning })
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.Timeout)
+	report, err := h.analyze(ctx, job.crash)
+	cancel()
+	if report != nil {
+		report.Executable = job.Executable
+		if err == nil {
+			err = report.Validate()
+		}
+		if saveErr := saveReport(report, filepath.Join(job.dir, "report.json")); saveErr != nil {
+			log.Printf("Failed to save the report of triage job %s: %v", job.ID, saveErr)
+		}
+	}
+	// The report is kept, but binaries and core dumps are too large to keep around
+	os.RemoveAll(filepath.Dir(job.crash.Executable))
+	if job.crash.Core != "" {
+		os.Remove(job.crash.Core)
+	}
+
+	h.update(job, func(j *Job) {
+		finished := time.Now()
+		j.Report, j.Finished = report, &finished
+		j.Status = StatusDone
+		if err != nil {
+			j.Status, j.Error = StatusFailed, err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("Triage job %s failed: %v", job.ID, err)
+	}
+
+	integration, ok := h.integration(job.Integration)
+	if !ok {
+		return
+	}
+	postCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	postErr := post(postCtx, h.client, integration, h.snapshot(job))
+	h.update(job, func(j *Job) {
+		j.Posted = postErr == nil
+		if postErr != nil {
+			j.PostError = postErr.Error()
+		}
+	})
+	if postErr != nil {
+		log.Printf("Failed to post triage job %s to %s: %v", job.ID, integration.Name, postErr)
+	}
+}
+
+// integration looks an integration up by name
+func (h *Handler) integration(name string) (config.TriageIntegration, bool) {
+	for _, integration := range h.integrations {
+		if integration.Name == name {
+			return integration, true
+		}
+	}
+	return config.TriageIntegration{}, false
+}
+
+// remember adds a job for status requests, forgetting the oldest beyond maxJobs. The
+// caller holds the mutex.
+func (h *Handler) remember(job *Job) {
+	h.jobs[job.ID] = job
+	h.order = append(h.order, job.ID)
+	if len(h.order) > maxJobs {
+		delete(h.jobs, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// update changes a job under the mutex
+func (h *Handler) update(job *Job, change func(*Job)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	change(job)
+}
+
+// snapshot copies a job under the mutex
+func (h *Handler) snapshot(job *Job) Job {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return *job
+}
+
+// newJobID returns a sortable, unguessable job ID
+func newJobID() (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102_150405_") + hex.EncodeToString(random), nil
+}
+
+// saveFile copies an uploaded file to path
+func saveFile(src multipart.File, path string, perm os.FileMode) error {
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// saveReport writes a report as JSON
+func saveReport(report *analyze.Report, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = report.WriteJSON(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeJSON writes body as a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeJSONError writes an {"error": message} response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
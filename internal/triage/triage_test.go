@@ -0,0 +1,216 @@
+package triage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/analyze"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// received is a request the fake GitHub or Slack got
+type received struct {
+	path          string
+	authorization string
+	body          map[string]interface{}
+}
+
+// newReceiver records the requests posted to it
+func newReceiver(t *testing.T) (*httptest.Server, chan received) {
+	requests := make(chan received, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests <- received{path: r.URL.Path, authorization: r.Header.Get("Authorization"), body: body}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// newTestHandler serves a handler whose jobs are analyzed by analyzer
+func newTestHandler(t *testing.T, receiverURL string, analyzer Analyzer) http.Handler {
+	handler, err := NewHandler(config.TriageConfig{
+		Enabled:       true,
+		Directory:     t.TempDir(),
+		MaxUploadSize: 1 << 20,
+		Timeout:       time.Minute,
+		Integrations: []config.TriageIntegration{
+			{Name: "ci", Token: "gh-secret", Kind: config.TriageGitHub, URL: receiverURL, Repository: "acme/app", GitHubToken: "ghp_x"},
+			{Name: "chat", Token: "slack-secret", Kind: config.TriageSlack, URL: receiverURL + "/slack"},
+		},
+	}, analyzer)
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/triage", handler.HandleSubmit).Methods("POST")
+	router.HandleFunc("/api/triage/{id}", handler.HandleStatus).Methods("GET")
+	return router
+}
+
+// submit posts a crash with the given fields; "executable" and "core" are sent as files
+func submit(t *testing.T, handler http.Handler, token string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if name == "executable" || name == "core" {
+			part, err := form.CreateFormFile(name, value)
+			require.NoError(t, err)
+			part.Write([]byte("\x7fELF " + value))
+			continue
+		}
+		form.WriteField(name, value)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/triage", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// status fetches a job
+func status(t *testing.T, handler http.Handler, token, id string) (int, Job) {
+	req := httptest.NewRequest(http.MethodGet, "/api/triage/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var job Job
+	json.NewDecoder(rec.Body).Decode(&job)
+	return rec.Code, job
+}
+
+func TestTriagePostsPullRequestComment(t *testing.T) {
+	receiver, requests := newReceiver(t)
+	crashes := make(chan Crash, 1)
+	handler := newTestHandler(t, receiver.URL, func(ctx context.Context, crash Crash) (*analyze.Report, error) {
+		data, err := os.ReadFile(crash.Executable)
+		require.NoError(t, err)
+		assert.Equal(t, "\x7fELF app", string(data))
+		crashes <- crash
+		return &analyze.Report{Question: crash.Question, Answer: "parse dereferences NULL.", Model: "m"}, nil
+	})
+
+	rec := submit(t, handler, "gh-secret", map[string]string{
+		"executable":  "app",
+		"core":        "core.1234",
+		"stderr":      "Segmentation fault (core dumped)\n",
+		"pullRequest": "7",
+		"ref":         "abc123",
+		"buildUrl":    "https://ci.example/42",
+	})
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var job Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	assert.Equal(t, StatusQueued, job.Status)
+	assert.Equal(t, "app", job.Executable)
+
+	crash := <-crashes
+	assert.Equal(t, "triage", crash.Profile, "a core dump is inspected without running the program")
+	assert.True(t, strings.HasPrefix(crash.Question, analyze.CoreQuestion))
+	assert.Contains(t, crash.Question, "```\nSegmentation fault (core dumped)\n```")
+	assert.NotEmpty(t, crash.Core)
+
+	request := <-requests
+	assert.Equal(t, "/repos/acme/app/issues/7/comments", request.path)
+	assert.Equal(t, "Bearer ghp_x", request.authorization)
+	comment, _ := request.body["body"].(string)
+	assert.True(t, strings.HasPrefix(comment, "**Crash triage of app** at abc123 ([build](https://ci.example/42))\n\n# Analysis of app"), comment)
+	assert.Contains(t, comment, "parse dereferences NULL.")
+
+	require.Eventually(t, func() bool {
+		_, job = status(t, handler, "gh-secret", job.ID)
+		return job.Posted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, StatusDone, job.Status)
+	assert.Equal(t, "parse dereferences NULL.", job.Report.Answer)
+	_, err := os.Stat(crash.Executable)
+	assert.True(t, os.IsNotExist(err), "the binary is deleted once triaged")
+	_, err = os.Stat(crash.Core)
+	assert.True(t, os.IsNotExist(err), "so is the core dump")
+
+	code, _ := status(t, handler, "slack-secret", job.ID)
+	assert.Equal(t, http.StatusNotFound, code, "integrations only see their own jobs")
+}
+
+func TestTriagePostsFailures(t *testing.T) {
+	receiver, requests := newReceiver(t)
+	handler := newTestHandler(t, receiver.URL, func(ctx context.Context, crash Crash) (*analyze.Report, error) {
+		assert.Equal(t, "", crash.Profile)
+		assert.Equal(t, "why?", crash.Question)
+		assert.Equal(t, "--fast input.txt", crash.Args)
+		return nil, errors.New("starting GDB: not an executable")
+	})
+
+	rec := submit(t, handler, "slack-secret", map[string]string{"executable": "app", "question": "why?", "args": "--fast input.txt"})
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	request := <-requests
+	assert.Equal(t, "/slack", request.path)
+	assert.Equal(t, "*Crash triage of app*\nThe triage failed: starting GDB: not an executable", request.body["text"])
+}
+
+func TestTriageRejectsRequests(t *testing.T) {
+	handler := newTestHandler(t, "http://127.0.0.1:1", func(ctx context.Context, crash Crash) (*analyze.Report, error) {
+		t.Error("nothing should be analyzed")
+		return nil, nil
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		fields map[string]string
+		code   int
+		error  string
+	}{
+		{"unknown token", "guess", map[string]string{"executable": "app"}, http.StatusUnauthorized, "A triage integration token is required"},
+		{"no pull request", "gh-secret", map[string]string{"executable": "app"}, http.StatusBadRequest, "pullRequest is required to comment on GitHub"},
+		{"no executable", "slack-secret", map[string]string{"core": "core"}, http.StatusBadRequest, "the executable is required"},
+		{"multiline args", "slack-secret", map[string]string{"executable": "app", "args": "a\nshell rm -rf /"}, http.StatusBadRequest, "args must be a single line"},
+		{"too large", "slack-secret", map[string]string{"executable": "app", "stderr": strings.Repeat("x", 2<<20)}, http.StatusRequestEntityTooLarge, "The crash artifacts exceed triage.max_upload_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := submit(t, handler, tt.token, tt.fields)
+			assert.Equal(t, tt.code, rec.Code)
+			body, _ := io.ReadAll(rec.Body)
+			assert.JSONEq(t, `{"error": "`+tt.error+`"}`, string(body))
+		})
+	}
+}
+
+func TestQuestionKeepsEndOfStderr(t *testing.T) {
+	stderr := strings.Repeat("a", maxStderr) + "the end\n"
+	asked := question("", false, stderr)
+	assert.True(t, strings.HasPrefix(asked, analyze.DefaultQuestion))
+	assert.True(t, strings.HasSuffix(asked, "a"+"the end\n```"))
+	assert.Contains(t, asked, "```\n...a")
+	assert.Equal(t, "why?", question("why?", true, ""))
+}
+
+func TestNewHandlerValidatesIntegrations(t *testing.T) {
+	_, err := NewHandler(config.TriageConfig{Enabled: true, Integrations: []config.TriageIntegration{
+		{Name: "a", Token: "same", Kind: config.TriageWebhook, URL: "http://hooks"},
+		{Name: "b", Token: "same", Kind: config.TriageWebhook, URL: "http://hooks"},
+	}}, nil)
+	assert.EqualError(t, err, "triage.integrations: b needs a token of its own")
+
+	_, err = NewHandler(config.TriageConfig{Enabled: true, Integrations: []config.TriageIntegration{
+		{Name: "gh", Token: "t", Kind: config.TriageGitHub},
+	}}, nil)
+	assert.EqualError(t, err, "triage.integrations: gh needs repository and github_token")
+}
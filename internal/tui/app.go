@@ -0,0 +1,419 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// Options configure a client session
+type Options struct {
+	// Executable is uploaded and GDB started on it, and stopped when the client quits.
+	// Without it, SessionToken joins a session started elsewhere.
+	Executable   string
+	SessionToken string
+
+	Model         string // Model the assistant uses instead of the user's
+	TerminalLines int    // Lines of GDB's output sent with each question
+}
+
+// helpText is what /help shows
+const helpText = `Commands:
+  /gdb, /chat     send the input line to GDB or to the assistant (or press Tab)
+  /run N          run the assistant's suggested command N
+  /input TEXT     send a line to the program's standard input
+  /start, /stop   start or stop GDB
+  /quit           stop GDB if the client started it, and quit (or Ctrl-D)
+Ctrl-C interrupts the program, PgUp and PgDn scroll, Up and Down recall earlier lines.
+`
+
+// app is a running client
+type app struct {
+	client *Client
+	conn   *Conn
+	opts   Options
+	in     *os.File
+	out    *os.File
+
+	mutex       sync.Mutex
+	screen      *Screen
+	rows, cols  int
+	filename    string // Executable GDB runs on, when the client uploaded it
+	started     bool   // The client started GDB
+	history     []api.ChatMessage
+	suggestions []string      // The assistant's last suggested commands
+	asking      bool          // A question is waiting for its answer
+	done        chan struct{} // Closed when the client quits
+	quitOnce    sync.Once
+}
+
+// Run uploads the executable and starts GDB on it, or joins a session, and runs the
+// client on a terminal until the user quits. Keys are read from in and the screen is
+// drawn on out.
+func Run(client *Client, opts Options, in, out *os.File) error {
+	a := &app{client: client, opts: opts, in: in, out: out, screen: NewScreen(), done: make(chan struct{})}
+
+	token := opts.SessionToken
+	if opts.Executable != "" {
+		filename, sessionToken, err := client.Upload(opts.Executable)
+		if err != nil {
+			return err
+		}
+		a.filename, token = filename, sessionToken
+	}
+	if token == "" {
+		return errors.New("an executable or a session token is required")
+	}
+
+	// Subscribe before starting GDB so no output is missed
+	conn, err := client.Connect(token)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	defer conn.Close()
+
+	if a.filename != "" {
+		a.screen.SetStatus(a.filename + " (starting)")
+		if err := client.StartGDB(a.filename); err != nil {
+			return fmt.Errorf("starting GDB: %w", err)
+		}
+		a.started = true
+	}
+
+	raw := true
+	restore, err := makeRaw(in)
+	if err != nil {
+		raw, restore = false, func() {}
+	}
+	// Draw on the alternate screen, leaving the shell's screen as it was
+	fmt.Fprint(out, "\x1b[?1049h\x1b[2J")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?1049l")
+		restore()
+	}()
+
+	a.rows, a.cols = terminalSize(out)
+	a.sendSize()
+	a.redraw()
+
+	go a.receive()
+	go a.watchSize()
+	if raw {
+		go a.readKeys()
+	} else {
+		go a.readLines()
+	}
+	<-a.done
+
+	if a.started {
+		client.StopGDB()
+	}
+	return nil
+}
+
+// quit ends the client
+func (a *app) quit() {
+	a.quitOnce.Do(func() { close(a.done) })
+}
+
+// redraw draws the screen; the caller must not hold the mutex
+func (a *app) redraw() {
+	a.mutex.Lock()
+	frame := a.screen.Render(a.rows, a.cols)
+	a.mutex.Unlock()
+	io.WriteString(a.out, frame)
+}
+
+// update changes the screen and draws it
+func (a *app) update(change func(s *Screen)) {
+	a.mutex.Lock()
+	change(a.screen)
+	a.mutex.Unlock()
+	a.redraw()
+}
+
+// sendSize gives the program's terminal the size of the GDB pane
+func (a *app) sendSize() {
+	a.mutex.Lock()
+	rows, cols := GDBSize(a.rows, a.cols)
+	a.mutex.Unlock()
+	a.conn.Send(websocket.TypeResize, websocket.ResizePayload{Rows: rows, Cols: cols})
+}
+
+// watchSize redraws the screen when the terminal is resized
+func (a *app) watchSize() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+		}
+		rows, cols := terminalSize(a.out)
+		a.mutex.Lock()
+		changed := rows != a.rows || cols != a.cols
+		a.rows, a.cols = rows, cols
+		a.mutex.Unlock()
+		if changed {
+			fmt.Fprint(a.out, "\x1b[2J")
+			a.sendSize()
+			a.redraw()
+		}
+	}
+}
+
+// receive shows the session's messages until the connection closes
+func (a *app) receive() {
+	for {
+		envelope, err := a.conn.Receive()
+		if err != nil {
+			select {
+			case <-a.done:
+			default:
+				a.update(func(s *Screen) { s.Notice("disconnected: " + err.Error()) })
+			}
+			return
+		}
+
+		switch envelope.Type {
+		case websocket.TypeGDBOutput:
+			var payload websocket.OutputPayload
+			json.Unmarshal(envelope.Payload, &payload)
+			a.update(func(s *Screen) { s.GDBOutput(payload.Text) })
+		case websocket.TypeStatus:
+			var payload websocket.StatusPayload
+			json.Unmarshal(envelope.Payload, &payload)
+			if payload.GDB == "" {
+				continue
+			}
+			a.update(func(s *Screen) {
+				status := payload.GDB
+				if payload.Reason != "" {
+					status += ", " + payload.Reason
+				}
+				s.SetStatus(fmt.Sprintf("%s (%s)", a.displayName(payload.File), status))
+			})
+		case websocket.TypeChatStream:
+			var payload websocket.ChatStreamPayload
+			json.Unmarshal(envelope.Payload, &payload)
+			a.update(func(s *Screen) {
+				if a.asking {
+					s.streamed += payload.Delta
+				}
+			})
+		case websocket.TypeError:
+			var payload websocket.ErrorPayload
+			json.Unmarshal(envelope.Payload, &payload)
+			a.update(func(s *Screen) { s.Notice("error: " + payload.Error) })
+		}
+	}
+}
+
+// displayName returns the name of the program a status is about
+func (a *app) displayName(file string) string {
+	if file == "" {
+		return a.filename
+	}
+	return file
+}
+
+// readKeys edits the input line key by key
+func (a *app) readKeys() {
+	reader := bufio.NewReader(a.in)
+	for {
+		k, err := readKey(reader)
+		if err != nil {
+			a.quit()
+			return
+		}
+		a.handleKey(k)
+	}
+}
+
+// handleKey acts on a key press
+func (a *app) handleKey(k key) {
+	a.mutex.Lock()
+	s := a.screen
+	rows := a.rows
+	var line string
+	submitted := false
+	switch k.name {
+	case "":
+		s.Type(k.char)
+	case "enter":
+		line, submitted = s.Submit(), true
+	case "backspace":
+		s.Backspace()
+	case "tab":
+		s.Toggle()
+	case "up":
+		s.History(-1)
+	case "down":
+		s.History(1)
+	case "pgup":
+		s.Scroll(1, rows)
+	case "pgdn":
+		s.Scroll(-1, rows)
+	case "ctrl-u":
+		s.ClearInput()
+	case "ctrl-c":
+		if s.focus == focusGDB {
+			// Interrupts the program, as in the web terminal
+			a.conn.Send(websocket.TypeCommand, websocket.CommandPayload{Command: "\x03"})
+		}
+		s.ClearInput()
+	case "ctrl-d":
+		if s.Input() == "" {
+			a.mutex.Unlock()
+			a.quit()
+			return
+		}
+	case "ctrl-l":
+		fmt.Fprint(a.out, "\x1b[2J")
+	}
+	a.mutex.Unlock()
+
+	if submitted {
+		a.submit(line)
+	}
+	a.redraw()
+}
+
+// readLines reads whole lines where the terminal cannot be put in raw mode
+func (a *app) readLines() {
+	scanner := bufio.NewScanner(a.in)
+	for scanner.Scan() {
+		a.submit(scanner.Text())
+		a.redraw()
+	}
+	a.quit()
+}
+
+// submit sends a line from the input line to the focused pane, or runs a slash command
+func (a *app) submit(line string) {
+	if strings.HasPrefix(line, "/") {
+		a.command(line)
+		return
+	}
+
+	a.mutex.Lock()
+	focused := a.screen.focus
+	a.mutex.Unlock()
+	if focused == focusGDB {
+		a.conn.Send(websocket.TypeCommand, websocket.CommandPayload{Command: line})
+		return
+	}
+	if strings.TrimSpace(line) != "" {
+		a.ask(line)
+	}
+}
+
+// command runs a slash command
+func (a *app) command(line string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	s := a.screen
+	switch name {
+	case "/help":
+		s.ChatText(helpText)
+	case "/gdb":
+		s.SetFocus(focusGDB)
+	case "/chat":
+		s.SetFocus(focusChat)
+	case "/run":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(a.suggestions) {
+			s.ChatText(fmt.Sprintf("No suggested command %q\n", arg))
+			return
+		}
+		a.conn.Send(websocket.TypeCommand, websocket.CommandPayload{Command: a.suggestions[n-1]})
+	case "/input":
+		a.conn.Send(websocket.TypeInput, websocket.InputPayload{Data: arg + "\n"})
+	case "/start", "/stop":
+		go a.startStop(name == "/start")
+	case "/quit":
+		a.quit()
+	default:
+		s.ChatText(fmt.Sprintf("Unknown command %s; /help lists them\n", name))
+	}
+}
+
+// startStop starts or stops GDB
+func (a *app) startStop(start bool) {
+	var err error
+	switch {
+	case !start:
+		err = a.client.StopGDB()
+	case a.filename == "":
+		err = errors.New("the client did not upload the program, so start it where it was uploaded")
+	default:
+		err = a.client.StartGDB(a.filename)
+	}
+	a.update(func(s *Screen) {
+		if err != nil {
+			s.Notice(err.Error())
+			return
+		}
+		a.started = start
+	})
+}
+
+// ask sends a question to the assistant and shows the answer
+func (a *app) ask(question string) {
+	a.mutex.Lock()
+	if a.asking {
+		a.screen.ChatText("Wait for the answer to the last question\n")
+		a.mutex.Unlock()
+		return
+	}
+	a.asking = true
+	a.screen.ChatText("> " + question + "\n")
+	req := api.ChatRequest{
+		Message:       question,
+		History:       append([]api.ChatMessage(nil), a.history...),
+		RequestID:     fmt.Sprintf("tui-%d", time.Now().UnixNano()),
+		TerminalLines: a.opts.TerminalLines,
+		Model:         a.opts.Model,
+	}
+	a.mutex.Unlock()
+
+	go func() {
+		resp, err := a.client.Chat(context.Background(), req)
+		a.update(func(s *Screen) {
+			a.asking = false
+			s.streamed = ""
+			if err != nil {
+				s.ChatText("Error: " + err.Error() + "\n\n")
+				return
+			}
+			s.ChatText(strings.TrimRight(resp.Response, "\n") + "\n")
+			a.suggestions = resp.SuggestedCommands
+			for i, command := range resp.SuggestedCommands {
+				s.ChatText(fmt.Sprintf("  [%d] %s\n", i+1, command))
+			}
+			if len(resp.SuggestedCommands) > 0 {
+				s.ChatText("Run one with /run N\n")
+			}
+			s.ChatText("\n")
+			a.history = append(a.history,
+				api.ChatMessage{Role: "user", Content: question},
+				api.ChatMessage{Role: "assistant", Content: resp.Response})
+		})
+	}()
+}
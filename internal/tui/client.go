@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// maxResponseSize limits the size of a response the client reads
+const maxResponseSize = 16 << 20
+
+// Client calls the server's HTTP API and opens its WebSocket
+type Client struct {
+	base  *url.URL
+	token string
+	http  *http.Client // Its cookie jar keeps the session of a password login
+}
+
+// NewClient creates a client of the server at serverURL. A token is sent as a bearer
+// token (auth.mode: token); in password mode, Login first.
+func NewClient(serverURL, token string) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", serverURL)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{base: base, token: token, http: &http.Client{Jar: jar}}, nil
+}
+
+// Login signs in with a username and password
+func (c *Client) Login(username, password string) error {
+	return c.postJSON(context.Background(), "/auth/login", auth.LoginRequest{Username: username, Password: password}, nil)
+}
+
+// Upload uploads an executable and returns its name on the server and the token of its
+// debugging session
+func (c *Client) Upload(path string) (filename, sessionToken string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	// Stream the form rather than holding the executable in memory
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("executable", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint("/upload"), body)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	var resp struct {
+		Data struct {
+			Filename     string `json:"filename"`
+			SessionToken string `json:"sessionToken"`
+		} `json:"data"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", "", fmt.Errorf("uploading %s: %w", path, err)
+	}
+	return resp.Data.Filename, resp.Data.SessionToken, nil
+}
+
+// StartGDB starts GDB on an uploaded executable
+func (c *Client) StartGDB(filename string) error {
+	return c.postJSON(context.Background(), "/start-gdb", map[string]string{"filename": filename}, nil)
+}
+
+// StopGDB stops the user's GDB
+func (c *Client) StopGDB() error {
+	return c.postJSON(context.Background(), "/stop-gdb", struct{}{}, nil)
+}
+
+// Chat asks the assistant about the debugging session
+func (c *Client) Chat(ctx context.Context, req api.ChatRequest) (*api.ChatResponse, error) {
+	var resp api.ChatResponse
+	if err := c.postJSON(ctx, "/api/chat", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Connect opens the WebSocket subscribed to the debugging session of sessionToken
+func (c *Client) Connect(sessionToken string) (*Conn, error) {
+	u := *c.base
+	u.Scheme = "ws"
+	if c.base.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+	u.Path += "/ws"
+	u.RawQuery = url.Values{"session": {sessionToken}}.Encode()
+
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	dialer := gws.Dialer{
+		Subprotocols:     []string{websocket.ProtocolV2Subprotocol},
+		Jar:              c.http.Jar,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	conn, resp, err := dialer.Dial(u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("connecting to the session: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("connecting to the session: %w", err)
+	}
+	return &Conn{conn: conn}, nil
+}
+
+func (c *Client) endpoint(path string) string {
+	return c.base.String() + path
+}
+
+// postJSON POSTs body as JSON and decodes the response into v, unless v is nil
+func (c *Client) postJSON(ctx context.Context, path string, body, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(path), strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, v)
+}
+
+// do sends a request and decodes its JSON response into v, unless v is nil
+func (c *Client) do(req *http.Request, v interface{}) error {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp.Status, body)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+// responseError returns the error of a failed request. Handlers write errors as JSON with
+// an error field or as plain text.
+func responseError(status string, body []byte) error {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		return fmt.Errorf("%s: %s", status, payload.Error)
+	}
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return fmt.Errorf("%s: %s", status, text)
+	}
+	return fmt.Errorf("%s", status)
+}
+
+// Conn is a WebSocket connection to a debugging session, speaking protocol version 2
+type Conn struct {
+	conn  *gws.Conn
+	mutex sync.Mutex // Serializes writes
+}
+
+// Send sends a message of the given type
+func (c *Conn) Send(messageType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn.WriteJSON(websocket.Envelope{V: websocket.ProtocolV2, Type: messageType, Payload: data})
+}
+
+// Receive returns the next message from the server
+func (c *Conn) Receive() (websocket.Envelope, error) {
+	var envelope websocket.Envelope
+	err := c.conn.ReadJSON(&envelope)
+	return envelope, err
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/api"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// newTestServer plays the server's API for the token "secret"
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("executable")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "\x7fELF", string(data))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"filename": header.Filename, "sessionToken": "session-token"},
+		})
+	})
+	mux.HandleFunc("/start-gdb", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["filename"] != "crash" {
+			http.Error(w, "Failed to start GDB: no such file", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	mux.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(api.ChatResponse{Response: "You asked: " + req.Message, SuggestedCommands: []string{"bt"}})
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "session-token" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		upgrader := gws.Upgrader{Subprotocols: []string{websocket.ProtocolV2Subprotocol}}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		// Answer each command with its output
+		for {
+			var envelope websocket.Envelope
+			if conn.ReadJSON(&envelope) != nil {
+				return
+			}
+			var command websocket.CommandPayload
+			json.Unmarshal(envelope.Payload, &command)
+			payload, _ := json.Marshal(websocket.OutputPayload{Text: "ran " + command.Command + "\r\n"})
+			conn.WriteJSON(websocket.Envelope{V: 2, Type: websocket.TypeGDBOutput, Payload: payload})
+		}
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Authentication required"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t)
+	client, err := NewClient(server.URL+"/", "secret")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "crash")
+	require.NoError(t, os.WriteFile(path, []byte("\x7fELF"), 0755))
+	filename, token, err := client.Upload(path)
+	require.NoError(t, err)
+	assert.Equal(t, "crash", filename)
+	assert.Equal(t, "session-token", token)
+
+	require.NoError(t, client.StartGDB("crash"))
+	assert.EqualError(t, client.StartGDB("other"), "500 Internal Server Error: Failed to start GDB: no such file")
+
+	resp, err := client.Chat(context.Background(), api.ChatRequest{Message: "why?"})
+	require.NoError(t, err)
+	assert.Equal(t, "You asked: why?", resp.Response)
+	assert.Equal(t, []string{"bt"}, resp.SuggestedCommands)
+
+	conn, err := client.Connect(token)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Send(websocket.TypeCommand, websocket.CommandPayload{Command: "bt"}))
+	envelope, err := conn.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TypeGDBOutput, envelope.Type)
+	assert.JSONEq(t, `{"text": "ran bt\r\n"}`, string(envelope.Payload))
+
+	_, err = client.Connect("stolen")
+	assert.EqualError(t, err, "connecting to the session: 403 Forbidden")
+}
+
+func TestClientErrors(t *testing.T) {
+	server := newTestServer(t)
+	client, err := NewClient(server.URL, "wrong")
+	require.NoError(t, err)
+	assert.EqualError(t, client.StartGDB("crash"), "401 Unauthorized: Authentication required")
+
+	_, err = NewClient("localhost:8080", "")
+	assert.Error(t, err, "the scheme is required")
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"bufio"
+)
+
+// key is a key press: a character, or a named key
+type key struct {
+	char rune
+	name string // "enter", "backspace", "tab", "escape", "up", "down", "pgup", "pgdn", or "ctrl-<letter>"
+}
+
+// escapeKeys names the control sequences of the keys the client uses
+var escapeKeys = map[string]string{
+	"A":  "up",
+	"B":  "down",
+	"5~": "pgup",
+	"6~": "pgdn",
+}
+
+// readKey reads a key press from a terminal in raw mode
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch {
+	case c == '\r' || c == '\n':
+		return key{name: "enter"}, nil
+	case c == 0x7f || c == '\b':
+		return key{name: "backspace"}, nil
+	case c == '\t':
+		return key{name: "tab"}, nil
+	case c == 0x1b:
+		return readEscape(r)
+	case c < ' ':
+		return key{name: "ctrl-" + string('a'+c-1)}, nil
+	}
+	return key{char: c}, nil
+}
+
+// readEscape reads the rest of the escape sequence a key sent. Terminals write a
+// sequence at once, so an Escape with nothing after it was the Escape key.
+func readEscape(r *bufio.Reader) (key, error) {
+	if r.Buffered() == 0 {
+		return key{name: "escape"}, nil
+	}
+	next, err := r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+	if next != '[' && next != 'O' {
+		// Alt with a key; not used
+		return key{name: "escape"}, nil
+	}
+
+	// Parameters up to a final byte
+	var sequence []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		sequence = append(sequence, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+	return key{name: escapeKeys[string(sequence)]}, nil
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"unicode/utf8"
+)
+
+// maxPaneLines is how many lines a pane keeps to scroll back through
+const maxPaneLines = 2000
+
+// pane is a scrolling region of text
+type pane struct {
+	lines   []string
+	partial []rune // The last line, until its newline arrives
+	scroll  int    // Rows scrolled back from the bottom
+	pending []byte // An incomplete UTF-8 sequence at the end of the last write
+}
+
+// Write appends text free of escape sequences. Carriage returns are dropped, and
+// backspaces and tabs move as a terminal would show them.
+func (p *pane) Write(b []byte) (int, error) {
+	data := append(p.pending, b...)
+	p.pending = nil
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && !utf8.FullRune(data) {
+			p.pending = append([]byte(nil), data...)
+			break
+		}
+		data = data[size:]
+
+		switch {
+		case r == '\n':
+			p.lines = append(p.lines, string(p.partial))
+			p.partial = p.partial[:0]
+		case r == '\b':
+			if len(p.partial) > 0 {
+				p.partial = p.partial[:len(p.partial)-1]
+			}
+		case r == '\t':
+			for {
+				p.partial = append(p.partial, ' ')
+				if len(p.partial)%8 == 0 {
+					break
+				}
+			}
+		case r < ' ' || r == 0x7f:
+			// Other control characters, including carriage returns, are not shown
+		default:
+			p.partial = append(p.partial, r)
+		}
+	}
+	if len(p.lines) > maxPaneLines {
+		p.lines = append([]string(nil), p.lines[len(p.lines)-maxPaneLines:]...)
+	}
+	return len(b), nil
+}
+
+// WriteString appends text like Write
+func (p *pane) WriteString(s string) {
+	p.Write([]byte(s))
+}
+
+// view returns the height rows of the pane in view, wrapped at width and padded with
+// empty rows
+func (p *pane) view(height, width int) []string {
+	var rows []string
+	for _, line := range p.lines {
+		rows = append(rows, wrap([]rune(line), width)...)
+	}
+	if len(p.partial) > 0 {
+		rows = append(rows, wrap(p.partial, width)...)
+	}
+
+	p.scroll = max(min(p.scroll, len(rows)-height), 0)
+	end := len(rows) - p.scroll
+	view := rows[max(end-height, 0):end]
+	for len(view) < height {
+		view = append(view, "")
+	}
+	return view
+}
+
+// scrollBy scrolls the pane back by rows, or forward for negative rows
+func (p *pane) scrollBy(rows int) {
+	p.scroll = max(p.scroll+rows, 0)
+}
+
+// wrap splits a line into rows of at most width characters
+func wrap(line []rune, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	rows := []string{}
+	for len(line) > width {
+		rows = append(rows, string(line[:width]))
+		line = line[width:]
+	}
+	return append(rows, string(line))
+}
@@ -0,0 +1,203 @@
+// Package tui is a terminal client of the server for users who would rather not use a
+// browser: GDB's output and the conversation with the assistant in two panes above an
+// input line, over the same HTTP API and WebSocket as the web UI.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yourusername/gogdbllm/internal/utils"
+)
+
+// focus is the pane the input line goes to
+type focus int
+
+const (
+	focusGDB focus = iota
+	focusChat
+)
+
+// prompts are the input line's prompts by focus
+var prompts = [...]string{focusGDB: "(gdb) ", focusChat: "ask> "}
+
+// Screen holds what the client shows. It is not safe for concurrent use.
+type Screen struct {
+	gdb      pane
+	gdbClean io.Writer // Strips escape sequences from GDB's output on its way to gdb
+	chat     pane
+	focus    focus
+	input    []rune
+	status   string // Shown in the GDB pane's title, e.g. the program and whether GDB runs
+
+	// streamed is the text of the answer being streamed, shown below the conversation
+	// until the complete answer arrives
+	streamed string
+
+	history      [2][]string // Submitted lines by focus
+	historyIndex int         // Position in the focused history while browsing it
+}
+
+// NewScreen creates an empty screen with input going to GDB
+func NewScreen() *Screen {
+	s := &Screen{}
+	s.gdbClean = utils.NewCleanWriter(&s.gdb)
+	return s
+}
+
+// GDBOutput appends output of GDB, which may contain escape sequences
+func (s *Screen) GDBOutput(text string) {
+	io.WriteString(s.gdbClean, text)
+}
+
+// Notice appends a line of the client's own to the GDB pane, like a failed upload
+func (s *Screen) Notice(text string) {
+	s.gdb.WriteString("[" + text + "]\n")
+}
+
+// ChatText appends text to the conversation
+func (s *Screen) ChatText(text string) {
+	s.chat.WriteString(text)
+}
+
+// SetStatus sets the text of the GDB pane's title
+func (s *Screen) SetStatus(status string) {
+	s.status = status
+}
+
+// Toggle moves the input line to the other pane
+func (s *Screen) Toggle() {
+	s.SetFocus(1 - s.focus)
+}
+
+// SetFocus moves the input line to a pane, keeping what was typed
+func (s *Screen) SetFocus(f focus) {
+	s.focus = f
+	s.historyIndex = len(s.history[f])
+}
+
+// Type adds a character to the input line
+func (s *Screen) Type(r rune) {
+	s.input = append(s.input, r)
+}
+
+// Backspace removes the last character of the input line
+func (s *Screen) Backspace() {
+	if len(s.input) > 0 {
+		s.input = s.input[:len(s.input)-1]
+	}
+}
+
+// ClearInput empties the input line
+func (s *Screen) ClearInput() {
+	s.input = s.input[:0]
+}
+
+// Input returns the input line
+func (s *Screen) Input() string {
+	return string(s.input)
+}
+
+// Submit empties the input line and returns it, remembering it in the focused pane's
+// history
+func (s *Screen) Submit() string {
+	line := string(s.input)
+	s.input = s.input[:0]
+	if strings.TrimSpace(line) != "" {
+		s.history[s.focus] = append(s.history[s.focus], line)
+	}
+	s.historyIndex = len(s.history[s.focus])
+	return line
+}
+
+// History replaces the input line with an earlier (-1) or later (1) line of the focused
+// pane's history
+func (s *Screen) History(delta int) {
+	history := s.history[s.focus]
+	index := s.historyIndex + delta
+	if index < 0 || index > len(history) {
+		return
+	}
+	s.historyIndex = index
+	s.input = s.input[:0]
+	if index < len(history) {
+		s.input = append(s.input, []rune(history[index])...)
+	}
+}
+
+// Scroll scrolls the focused pane back by pages, or forward for negative pages, on a
+// screen of the given rows
+func (s *Screen) Scroll(pages, rows int) {
+	gdbRows, chatRows := layout(rows)
+	height := gdbRows
+	target := &s.gdb
+	if s.focus == focusChat {
+		height, target = chatRows, &s.chat
+	}
+	target.scrollBy(pages * max(height/2, 1))
+}
+
+// layout splits a screen's rows between the panes, after the two titles and the input line
+func layout(rows int) (gdbRows, chatRows int) {
+	available := max(rows-3, 2)
+	gdbRows = max(available*3/5, 1)
+	return gdbRows, max(available-gdbRows, 1)
+}
+
+// GDBSize returns the size of the GDB pane on a screen of the given size, which the
+// program's terminal is given
+func GDBSize(rows, cols int) (int, int) {
+	gdbRows, _ := layout(rows)
+	return gdbRows, cols
+}
+
+// Render returns the escape sequences and text that draw the screen over the previous
+// frame, leaving the cursor at the end of the input line
+func (s *Screen) Render(rows, cols int) string {
+	gdbRows, chatRows := layout(rows)
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+
+	title := " GDB"
+	if s.status != "" {
+		title += ": " + s.status
+	}
+	s.title(&b, title, cols, s.focus == focusGDB)
+	for _, row := range s.gdb.view(gdbRows, cols) {
+		b.WriteString(row + "\x1b[K\r\n")
+	}
+
+	s.title(&b, " Assistant (Tab switches panes, /help lists commands)", cols, s.focus == focusChat)
+	chat := s.chat
+	if s.streamed != "" {
+		// Show the streamed answer after the conversation without keeping it there
+		chat.lines = append(chat.lines[:len(chat.lines):len(chat.lines)], strings.Split(strings.TrimRight(s.streamed, "\n"), "\n")...)
+	}
+	for _, row := range chat.view(chatRows, cols) {
+		b.WriteString(row + "\x1b[K\r\n")
+	}
+	s.chat.scroll = chat.scroll
+
+	// Show the end of an input line longer than the screen
+	prompt := prompts[s.focus]
+	input := s.input
+	if room := max(cols-len(prompt)-1, 1); len(input) > room {
+		input = input[len(input)-room:]
+	}
+	fmt.Fprintf(&b, "\x1b[1m%s\x1b[m%s\x1b[K", prompt, string(input))
+	return b.String()
+}
+
+// title writes a pane's title row, in reverse video for the focused pane
+func (s *Screen) title(b *strings.Builder, text string, cols int, focused bool) {
+	runes := []rune(text)
+	if len(runes) > cols {
+		runes = runes[:cols]
+	}
+	style := "\x1b[2;7m"
+	if focused {
+		style = "\x1b[1;7m"
+	}
+	fmt.Fprintf(b, "%s%s%s\x1b[m\r\n", style, string(runes), strings.Repeat(" ", cols-len(runes)))
+}
@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaneWrapsAndScrolls(t *testing.T) {
+	var p pane
+	p.WriteString("one\r\ntwo\tx\nabcdefghij\n(gdb) ")
+	assert.Equal(t, []string{"two     x", "abcdefghij", "(gdb) "}, p.view(3, 10))
+	assert.Equal(t, []string{"fghij", "(gdb)", " "}, p.view(3, 5))
+	assert.Equal(t, []string{"one", "two     x", "abcdefghij", "(gdb) ", ""}, p.view(5, 10), "short panes are padded")
+
+	p.scrollBy(1)
+	assert.Equal(t, []string{"one", "two     x", "abcdefghij"}, p.view(3, 10))
+	p.scrollBy(100)
+	assert.Equal(t, []string{"one", "two     x", "abcdefghij"}, p.view(3, 10), "scrolling stops at the top")
+	p.scrollBy(-100)
+	assert.Equal(t, []string{"two     x", "abcdefghij", "(gdb) "}, p.view(3, 10))
+
+	// A character split between writes, and a backspace
+	p.Write([]byte("\xc3"))
+	p.Write([]byte("\xa9\b!\n"))
+	assert.Equal(t, "(gdb) !", p.lines[len(p.lines)-1])
+}
+
+func TestScreen(t *testing.T) {
+	s := NewScreen()
+	s.GDBOutput("\x1b[1mBreakpoint 1, \x1b[mmain () at crash.c:5\r\n(gdb) ")
+	s.SetStatus("crash (running)")
+	for _, r := range "bt" {
+		s.Type(r)
+	}
+
+	frame := s.Render(8, 40)
+	assert.Contains(t, frame, "\x1b[1;7m GDB: crash (running)")
+	assert.Contains(t, frame, "Breakpoint 1, main () at crash.c:5\x1b[K\r\n(gdb) \x1b[K")
+	assert.True(t, strings.HasSuffix(frame, "\x1b[1m(gdb) \x1b[mbt\x1b[K"), frame)
+
+	assert.Equal(t, "bt", s.Submit())
+	s.Toggle()
+	s.Type('?')
+	s.ChatText("> why?\n")
+	s.streamed = "Because"
+	frame = s.Render(8, 40)
+	assert.Contains(t, frame, "> why?\x1b[K\r\nBecause\x1b[K")
+	assert.True(t, strings.HasSuffix(frame, "ask> \x1b[m?\x1b[K"), frame)
+
+	// Each pane has its own history
+	s.ClearInput()
+	s.History(-1)
+	assert.Equal(t, "", s.Input())
+	s.Toggle()
+	s.History(-1)
+	assert.Equal(t, "bt", s.Input())
+	s.History(1)
+	assert.Equal(t, "", s.Input())
+}
+
+func TestReadKey(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("a\x7f\t\x1b[A\x1b[5~\x03\r"))
+	var keys []key
+	for {
+		k, err := readKey(reader)
+		if err != nil {
+			break
+		}
+		keys = append(keys, k)
+	}
+	require.Len(t, keys, 7)
+	assert.Equal(t, []key{{char: 'a'}, {name: "backspace"}, {name: "tab"}, {name: "up"}, {name: "pgup"},
+		{name: "ctrl-c"}, {name: "enter"}}, keys)
+}
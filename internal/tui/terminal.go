@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/creack/pty"
+)
+
+// makeRaw switches a terminal to reading key by key without echo, using stty, and returns
+// a function restoring its settings. Ctrl-C and Ctrl-D arrive as keys. It fails where
+// stty is missing, e.g. on Windows, and the client then reads whole lines.
+func makeRaw(tty *os.File) (restore func(), err error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(tty, "-icanon", "-echo", "-isig", "-ixon", "min", "1", "time", "0"); err != nil {
+		return nil, err
+	}
+	return func() { stty(tty, strings.TrimSpace(saved)) }, nil
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// terminalSize returns the size of a terminal, or the classic 24x80 where it cannot be
+// found
+func terminalSize(f *os.File) (rows, cols int) {
+	rows, cols, err := pty.Getsize(f)
+	if err != nil || rows < 1 || cols < 1 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// ReadPassword asks for a password on a terminal without echoing it. Where echo cannot
+// be turned off, the password is read as typed.
+func ReadPassword(tty *os.File, prompt io.Writer, text string) (string, error) {
+	fmt.Fprint(prompt, text)
+	if saved, err := stty(tty, "-g"); err == nil {
+		if _, err := stty(tty, "-echo"); err == nil {
+			defer func() {
+				stty(tty, strings.TrimSpace(saved))
+				fmt.Fprintln(prompt)
+			}()
+		}
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -1,45 +1,109 @@
 package utils
 
 import (
-	"regexp"
+	"io"
 	"strings"
 )
 
-// ansiRegex defines the pattern for ANSI escape codes.
-var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
-
 // gdbControlChars defines specific GDB control characters (SOH, STX) to remove.
 // Using direct byte representation for clarity.
 var gdbControlChars = []byte{0x01, 0x02} // Corresponds to \u0001 and \u0002
 
 // StripAnsiAndControlChars removes ANSI escape codes and specific GDB control characters from a string.
 func StripAnsiAndControlChars(str string) string {
-	// Remove ANSI escape codes
-	sanitized := ansiRegex.ReplaceAllString(str, "")
-
-	// Remove specific GDB control characters (\u0001, \u0002)
-	// We convert the string to bytes, filter, and convert back.
-	// This is generally safer for handling potential multi-byte characters
-	// if they were present, though less critical for control chars.
 	var result strings.Builder
-	result.Grow(len(sanitized)) // Pre-allocate roughly the needed size
-
-	for _, r := range sanitized {
-		isControlChar := false
-		for _, controlByte := range gdbControlChars {
-			// Check if the rune's byte representation matches a control character
-			// This check is simplistic and assumes single-byte representation for control chars.
-			// A more robust method might be needed if dealing with complex encodings,
-			// but for ASCII/UTF-8 control chars 0x01/0x02, this works.
-			if byte(r) == controlByte {
-				isControlChar = true
-				break
+	result.Grow(len(str)) // Pre-allocate roughly the needed size
+	io.WriteString(NewCleanWriter(&result), str)
+	return result.String()
+}
+
+// cleanState is where a CleanWriter is in an escape sequence
+type cleanState int
+
+const (
+	cleanText       cleanState = iota
+	cleanEscape                // After ESC
+	cleanEscapeNext            // In a two-character escape with intermediate bytes, like ESC ( B
+	cleanCSI                   // In a control sequence: ESC [ parameters final
+	cleanOSC                   // In an operating system command: ESC ] ... BEL or ST
+	cleanOSCEscape             // After ESC in an operating system command
+)
+
+// CleanWriter removes ANSI escape sequences and GDB's control characters from what is
+// written to it before passing it on. It keeps its place in an escape sequence between
+// writes, so a stream may be split anywhere.
+type CleanWriter struct {
+	w     io.Writer
+	state cleanState
+	buf   []byte
+}
+
+// NewCleanWriter creates a CleanWriter writing to w
+func NewCleanWriter(w io.Writer) *CleanWriter {
+	return &CleanWriter{w: w}
+}
+
+// Write writes p without its escape sequences and control characters. It reports all of
+// p as written unless w fails.
+func (cw *CleanWriter) Write(p []byte) (int, error) {
+	cw.buf = cw.buf[:0]
+	for _, b := range p {
+		switch cw.state {
+		case cleanText:
+			if b == 0x1b {
+				cw.state = cleanEscape
+			} else if !isGDBControlChar(b) {
+				cw.buf = append(cw.buf, b)
+			}
+		case cleanEscape:
+			switch {
+			case b == '[':
+				cw.state = cleanCSI
+			case b == ']':
+				cw.state = cleanOSC
+			case b >= 0x20 && b <= 0x2f:
+				cw.state = cleanEscapeNext
+			default:
+				cw.state = cleanText
+			}
+		case cleanEscapeNext:
+			if b < 0x20 || b > 0x2f {
+				cw.state = cleanText
+			}
+		case cleanCSI:
+			if b >= 0x40 && b <= 0x7e {
+				cw.state = cleanText
+			}
+		case cleanOSC:
+			if b == 0x07 {
+				cw.state = cleanText
+			} else if b == 0x1b {
+				cw.state = cleanOSCEscape
+			}
+		case cleanOSCEscape:
+			if b == '\\' {
+				cw.state = cleanText
+			} else {
+				cw.state = cleanOSC
 			}
 		}
-		if !isControlChar {
-			result.WriteRune(r)
+	}
+
+	if len(cw.buf) > 0 {
+		if _, err := cw.w.Write(cw.buf); err != nil {
+			return 0, err
 		}
 	}
+	return len(p), nil
+}
 
-	return result.String()
+// isGDBControlChar reports whether b is one of the control characters GDB wraps its
+// prompt's escape codes in
+func isGDBControlChar(b byte) bool {
+	for _, controlByte := range gdbControlChars {
+		if b == controlByte {
+			return true
+		}
+	}
+	return false
 }
@@ -1,12 +1,14 @@
 package websocket
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/yourusername/gogdbllm/internal/auth"
+	appErrors "github.com/yourusername/gogdbllm/internal/errors"
+	"github.com/yourusername/gogdbllm/internal/middleware"
 )
 
 const (
@@ -19,30 +21,93 @@ const (
 	// Send pings to peer with this period
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Messages up to this multiple of Limits.MaxMessageSize get an error reply; larger
+	// ones close the connection without being read
+	readLimitFactor = 4
 )
 
+// upgrader is copied by ServeWs, which sets its origin check
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true },
+	Subprotocols:    []string{ProtocolV2Subprotocol},
 }
 
 // GDBHandler defines the interface for handling GDB commands
 type GDBHandler interface {
-	HandleCommand(cmd string) error
+	// HandleUserCommand runs a command for a user, failing with errors.ErrForbidden if the
+	// user does not own the debugging session
+	HandleUserCommand(user, cmd string) error
+
+	// SubscribeSession returns the ID of the debugging session whose subscription token is
+	// token, failing with errors.ErrForbidden if the token is invalid or user neither owns
+	// nor joined it
+	SubscribeSession(user, token string) (string, error)
+
+	// WatchSession returns the ID of the debugging session a read-only share token grants
+	// access to and when the token expires, failing with errors.ErrForbidden if it is
+	// unknown or expired
+	WatchSession(token string) (string, time.Time, error)
+
+	// HandleProgramInput sends input to the debugged program's terminal for a user, failing
+	// with errors.ErrForbidden if the user does not own the debugging session
+	HandleProgramInput(user, input string) error
+
+	// ResizeTerminal sets the size of the debugged program's terminal for a user
+	ResizeTerminal(user string, rows, cols int) error
+
+	// CompleteCommand returns GDB's completions of a partial command line for a user
+	CompleteCommand(user, text string) ([]string, error)
 }
 
-// WebSocketMessage defines the structure of messages from the client
+// WebSocketMessage defines the structure of protocol version 1 messages from the client
 type WebSocketMessage struct {
 	Type    string `json:"type"`
 	Command string `json:"command"`
 }
 
-// ServeWs handles websocket requests from clients
-func ServeWs(hub *Hub, gdbHandler GDBHandler) http.HandlerFunc {
+// ServeWs handles websocket requests from clients. The protocol version is negotiated with
+// the Sec-WebSocket-Protocol header, and each client's messages are validated and
+// rate-limited according to limits. A client receives a debugging session's output only if
+// it presents the session's token in the session query parameter, or a share token in the
+// share parameter, which lets it watch as a viewer until the token expires. Browsers may
+// connect only from allowedOrigins (see middleware.OriginAllowed; the server's own origin
+// if empty), so another site cannot drive the debugger with a signed-in user's cookie.
+func ServeWs(hub *Hub, gdbHandler GDBHandler, limits Limits, allowedOrigins []string) http.HandlerFunc {
+	upgrader := upgrader
+	upgrader.CheckOrigin = func(r *http.Request) bool {
+		return middleware.OriginAllowed(r, allowedOrigins)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !upgrader.CheckOrigin(r) {
+			log.Printf("Refusing websocket connection from origin %q", r.Header.Get("Origin"))
+			http.Error(w, "Origin not allowed (server.allowed_origins)", http.StatusForbidden)
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		role := auth.RoleFromContext(r.Context())
+
+		// Check the session or share token before upgrading so a rejected client gets an
+		// HTTP error
+		var sessionID, share string
+		var shareExpires time.Time
+		if token := r.URL.Query().Get("session"); token != "" {
+			var err error
+			if sessionID, err = gdbHandler.SubscribeSession(user, token); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		} else if token := r.URL.Query().Get("share"); token != "" {
+			var err error
+			if sessionID, shareExpires, err = gdbHandler.WatchSession(token); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			share, role = token, auth.RoleViewer
+		}
+
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println("Error upgrading connection:", err)
@@ -50,25 +115,38 @@ func ServeWs(hub *Hub, gdbHandler GDBHandler) http.HandlerFunc {
 		}
 
 		client := &Client{
-			Hub:  hub,
-			Send: make(chan Message, 256),
+			Hub:      hub,
+			Send:     make(chan Message, 256),
+			User:     user,
+			Role:     role,
+			Session:  sessionID,
+			Protocol: negotiateProtocol(conn.Subprotocol()),
+
+			share:        share,
+			shareExpires: shareExpires,
 		}
 		client.Hub.register <- client
+		client.Hub.sendTo(client, Message{Type: TypeStatus, Payload: StatusPayload{Protocol: client.Protocol, User: user, Session: sessionID}})
 
 		// Start the client's goroutines
 		go handleWrite(client, conn)
-		go handleRead(client, conn, gdbHandler)
+		go handleRead(client, conn, gdbHandler, limits)
 	}
 }
 
-// handleRead handles incoming messages from clients
-func handleRead(client *Client, conn *websocket.Conn, gdbHandler GDBHandler) {
+// handleRead handles incoming messages from clients. Rejected messages get an ErrorReply;
+// a client that keeps sending rejected messages is disconnected.
+func handleRead(client *Client, conn *websocket.Conn, gdbHandler GDBHandler, limits Limits) {
 	defer func() {
 		client.Hub.unregister <- client
 		conn.Close()
 	}()
 
-	conn.SetReadLimit(maxMessageSize)
+	bucket := newTokenBucket(limits.CommandsPerSecond, limits.CommandBurst)
+	inputBucket := newTokenBucket(limits.CommandsPerSecond*inputRateFactor, limits.CommandBurst*inputRateFactor)
+	violations := 0
+
+	conn.SetReadLimit(int64(limits.MaxMessageSize) * readLimitFactor)
 	conn.SetReadDeadline(time.Now().Add(pongWait))
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -84,23 +162,86 @@ func handleRead(client *Client, conn *websocket.Conn, gdbHandler GDBHandler) {
 			break
 		}
 
-		var msg WebSocketMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("error unmarshaling message: %v", err)
+		msg, reply := validateMessage(message, client.Protocol, limits)
+		switch {
+		case reply != nil:
+		case (msg.Type == TypeInput || msg.Type == TypeResize) && !inputBucket.allow(time.Now()):
+			rejected := newErrorReply(ErrCodeRateLimited, "too much input; the limit is %g messages per second", limits.CommandsPerSecond*inputRateFactor)
+			reply = &rejected
+		case (msg.Type == TypeCommand || msg.Type == TypeComplete) && !controlKeys[msg.Command] && !bucket.allow(time.Now()):
+			rejected := newErrorReply(ErrCodeRateLimited, "too many commands; the limit is %g per second", limits.CommandsPerSecond)
+			reply = &rejected
+		}
+		if reply != nil {
+			violations++
+			client.Hub.sendTo(client, *reply)
+			if violations >= limits.MaxViolations {
+				log.Printf("disconnecting websocket client after %d rejected messages", violations)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many rejected messages"),
+					time.Now().Add(writeWait))
+				break
+			}
 			continue
 		}
+		violations = 0
 
-		if msg.Type == "command" {
-			if err := gdbHandler.HandleCommand(msg.Command); err != nil {
-				log.Printf("error handling command: %v", err)
+		if msg.Type == TypeHeartbeat {
+			client.Hub.sendTo(client, Message{Type: TypeHeartbeat, ID: msg.ID, Payload: HeartbeatPayload{Time: time.Now()}})
+			continue
+		}
+		if !auth.Allows(client.Role, auth.RoleDebugger) {
+			reply := newErrorReply(ErrCodeForbidden, "the %s role may only watch; running commands and typing input require the debugger role", client.Role)
+			reply.ID = msg.ID
+			client.Hub.sendTo(client, reply)
+			continue
+		}
+
+		switch msg.Type {
+		case TypeInput:
+			err = gdbHandler.HandleProgramInput(client.User, msg.Input)
+		case TypeResize:
+			err = gdbHandler.ResizeTerminal(client.User, msg.Rows, msg.Cols)
+		case TypeComplete:
+			var completions []string
+			if completions, err = gdbHandler.CompleteCommand(client.User, msg.Command); err == nil {
+				client.Hub.sendTo(client, Message{Type: TypeCompletions, ID: msg.ID, Payload: CompletionsPayload{Text: msg.Command, Completions: completions}})
+			}
+		default:
+			err = gdbHandler.HandleUserCommand(client.User, msg.Command)
+		}
+		if err != nil {
+			if appErrors.Is(err, appErrors.ErrForbidden) {
+				reply := newErrorReply(ErrCodeForbidden, "%v", err)
+				reply.ID = msg.ID
+				client.Hub.sendTo(client, reply)
+				continue
+			}
+			if msg.Type != TypeCommand {
+				// Say why input, a resize or a completion had no effect
+				reply := newErrorReply(ErrCodeInvalidMessage, "%v", err)
+				reply.ID = msg.ID
+				client.Hub.sendTo(client, reply)
+				continue
 			}
+			log.Printf("error handling command: %v", err)
 		}
 	}
 }
 
-// handleWrite pumps messages from the hub to the websocket connection
+// handleWrite pumps messages from the hub to the websocket connection, encoded for the
+// client's protocol version. Version 2 clients also get heartbeat messages, since browsers
+// do not expose ping frames. A client watching through a share link is disconnected when
+// the link expires.
 func handleWrite(client *Client, conn *websocket.Conn) {
+	var seq uint64
 	ticker := time.NewTicker(pingPeriod)
+	var expired <-chan time.Time
+	if !client.shareExpires.IsZero() {
+		timer := time.NewTimer(time.Until(client.shareExpires))
+		defer timer.Stop()
+		expired = timer.C
+	}
 	defer func() {
 		ticker.Stop()
 		conn.Close()
@@ -111,25 +252,44 @@ func handleWrite(client *Client, conn *websocket.Conn) {
 		case message, ok := <-client.Send:
 			conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				// The hub closed the channel
-				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				// The hub closed the channel, saying why if it disconnected the client
+				closeMessage := []byte{}
+				switch client.closeReason {
+				case CloseReasonSlowClient:
+					closeMessage = websocket.FormatCloseMessage(CloseCodeSlowClient, CloseReasonSlowClient)
+				case CloseReasonShareEnded:
+					closeMessage = websocket.FormatCloseMessage(CloseCodeShareEnded, CloseReasonShareEnded)
+				}
+				conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
 
-			w, err := conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+			seq++
+			data, ok := encodeMessage(message, client.Protocol, seq)
+			if !ok {
+				continue
 			}
-			w.Write([]byte(message.Content))
-
-			if err := w.Close(); err != nil {
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 				return
 			}
+			client.recordSent(time.Since(message.queuedAt), len(client.Send))
+		case <-expired:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(CloseCodeShareEnded, CloseReasonShareEnded),
+				time.Now().Add(writeWait))
+			return
 		case <-ticker.C:
 			conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			if client.Protocol == ProtocolV2 {
+				seq++
+				data, _ := encodeMessage(Message{Type: TypeHeartbeat, Payload: HeartbeatPayload{Time: time.Now()}}, ProtocolV2, seq)
+				if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+					return
+				}
+			}
 		}
 	}
 }
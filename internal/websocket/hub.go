@@ -1,18 +1,45 @@
 package websocket
 
 import (
+	"log"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/yourusername/gogdbllm/internal/config"
 )
 
-// Message represents a message to be broadcasted to clients
+// defaultSlowClientTimeout is used when websocket.slow_client_timeout is unset
+const defaultSlowClientTimeout = 10 * time.Second
+
+// Message represents a message to be broadcasted to clients. Each client encodes it for
+// the protocol version it negotiated.
 type Message struct {
-	Content string
+	Type    string      // Protocol version 2 message type, e.g. TypeGDBOutput
+	ID      string      // ID of the client message this replies to; "" for server-initiated messages
+	Payload interface{} // One of the payload types, e.g. OutputPayload
+	User    string      // Only clients of this user receive the message; "" means all clients
+	Session string      // Only clients subscribed to this debugging session receive the message
+
+	queuedAt time.Time // When the message entered a client's send queue
 }
 
 // Client represents a connected client
 type Client struct {
-	Hub  *Hub
-	Send chan Message
+	Hub      *Hub
+	Send     chan Message
+	User     string // Authenticated user, "" when authentication is disabled
+	Role     string // The user's role; viewers only receive messages
+	Session  string // Debugging session subscribed to during the handshake, if any
+	Protocol int    // Negotiated protocol version
+
+	// Set for clients watching a session through a read-only share link: its token and
+	// when it expires
+	share        string
+	shareExpires time.Time
+
+	stats       clientStats
+	closeReason string // Why the hub disconnected the client; set before Send is closed
 }
 
 // Hub maintains active clients and broadcasts messages
@@ -20,6 +47,9 @@ type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
+	// Clients subscribed to each debugging session
+	sessions map[string]map[*Client]bool
+
 	// Register requests from clients
 	register chan *Client
 
@@ -29,17 +59,29 @@ type Hub struct {
 	// Broadcast messages to all clients
 	broadcast chan Message
 
+	// Clients whose send queue stays full this long are disconnected
+	slowClientTimeout time.Duration
+
+	// Totals kept across disconnects
+	totals hubTotals
+
 	// Mutex for thread-safe operations
 	mutex sync.Mutex
 }
 
 // NewHub creates a new hub instance
-func NewHub() *Hub {
+func NewHub(cfg *config.Config) *Hub {
+	slowClientTimeout := cfg.WebSocket.SlowClientTimeout
+	if slowClientTimeout <= 0 {
+		slowClientTimeout = defaultSlowClientTimeout
+	}
 	return &Hub{
-		clients:    make(map[*Client]bool),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan Message),
+		clients:           make(map[*Client]bool),
+		sessions:          make(map[string]map[*Client]bool),
+		register:          make(chan *Client),
+		unregister:        make(chan *Client),
+		broadcast:         make(chan Message),
+		slowClientTimeout: slowClientTimeout,
 	}
 }
 
@@ -49,35 +91,244 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mutex.Lock()
+			client.stats.connectedAt = time.Now()
 			h.clients[client] = true
+			if client.Session != "" {
+				if h.sessions[client.Session] == nil {
+					h.sessions[client.Session] = make(map[*Client]bool)
+				}
+				h.sessions[client.Session][client] = true
+				h.presenceLocked(client.Session)
+			}
 			h.mutex.Unlock()
 		case client := <-h.unregister:
 			h.mutex.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
+				h.removeLocked(client)
 			}
 			h.mutex.Unlock()
 		case message := <-h.broadcast:
 			h.mutex.Lock()
-			for client := range h.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.clients, client)
+			recipients := h.clients
+			if message.Session != "" {
+				recipients = h.sessions[message.Session]
+			}
+			for client := range recipients {
+				if message.User != "" && client.User != message.User {
+					continue
 				}
+				h.enqueueLocked(client, message)
 			}
 			h.mutex.Unlock()
 		}
 	}
 }
 
-// Broadcast sends a message to all connected clients
+// enqueueLocked queues a message for a client. When the client's queue is full the message
+// is dropped, and a client whose queue has stayed full for longer than the slow client
+// timeout is disconnected. The caller must hold the mutex.
+func (h *Hub) enqueueLocked(client *Client, message Message) bool {
+	now := time.Now()
+	message.queuedAt = now
+	select {
+	case client.Send <- message:
+		return true
+	default:
+	}
+
+	client.stats.dropped.Add(1)
+	h.totals.dropped.Add(1)
+	since := client.stats.backloggedSince.Load()
+	if since == 0 {
+		client.stats.backloggedSince.CompareAndSwap(0, now.UnixNano())
+		return false
+	}
+	if backlogged := now.Sub(time.Unix(0, since)); backlogged > h.slowClientTimeout {
+		log.Printf("disconnecting slow websocket client (user %q): send queue full for %s, %d messages dropped",
+			client.User, backlogged.Round(time.Second), client.stats.dropped.Load())
+		client.closeReason = CloseReasonSlowClient
+		h.totals.slowDisconnects.Add(1)
+		h.removeLocked(client)
+	}
+	return false
+}
+
+// removeLocked unregisters a client and closes its send channel. The caller must hold the mutex.
+func (h *Hub) removeLocked(client *Client) {
+	delete(h.clients, client)
+	if subscribers := h.sessions[client.Session]; subscribers != nil {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.sessions, client.Session)
+		} else {
+			h.presenceLocked(client.Session)
+		}
+	}
+	close(client.Send)
+}
+
+// presenceLocked sends who is connected to a debugging session to its clients. The caller
+// must hold the mutex.
+func (h *Hub) presenceLocked(sessionID string) {
+	subscribers := h.sessions[sessionID]
+	message := Message{
+		Type:    TypePresence,
+		Payload: PresencePayload{Session: sessionID, Users: sessionUsers(subscribers), Clients: len(subscribers)},
+		Session: sessionID,
+	}
+	for client := range subscribers {
+		h.enqueueLocked(client, message)
+	}
+}
+
+// sessionUsers returns the distinct users of clients, sorted
+func sessionUsers(clients map[*Client]bool) []string {
+	seen := make(map[string]bool)
+	users := []string{}
+	for client := range clients {
+		if client.User != "" && !seen[client.User] {
+			seen[client.User] = true
+			users = append(users, client.User)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// SessionUsers returns the users with a client connected to a debugging session, sorted
+func (h *Hub) SessionUsers(sessionID string) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return sessionUsers(h.sessions[sessionID])
+}
+
+// EndShare disconnects the clients watching through a share link, e.g. once it is revoked
+func (h *Hub) EndShare(token string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for client := range h.clients {
+		if client.share == token {
+			client.closeReason = CloseReasonShareEnded
+			h.removeLocked(client)
+		}
+	}
+}
+
+// Subscribe registers a client without a WebSocket connection, e.g. a gRPC stream, which
+// reads its messages from Send. The client receives the messages sent to user, or with a
+// sessionID those of that debugging session. Send is closed once the client is
+// unsubscribed, or disconnected for being too slow.
+func (h *Hub) Subscribe(user, sessionID string) *Client {
+	client := &Client{
+		Hub:      h,
+		Send:     make(chan Message, 256),
+		User:     user,
+		Session:  sessionID,
+		Protocol: ProtocolV2,
+	}
+	h.register <- client
+	return client
+}
+
+// Unsubscribe unregisters a client registered with Subscribe
+func (h *Hub) Unsubscribe(client *Client) {
+	h.unregister <- client
+}
+
+// BroadcastToSession sends GDB output to the clients subscribed to a debugging session
+func (h *Hub) BroadcastToSession(sessionID, content string) {
+	h.broadcast <- Message{
+		Type:    TypeGDBOutput,
+		Payload: OutputPayload{Text: content},
+		Session: sessionID,
+	}
+}
+
+// BroadcastSessionStatus sends a status change to the clients subscribed to a debugging
+// session. Only protocol version 2 clients receive it.
+func (h *Hub) BroadcastSessionStatus(sessionID string, status StatusPayload) {
+	h.broadcast <- Message{
+		Type:    TypeStatus,
+		Payload: status,
+		Session: sessionID,
+	}
+}
+
+// BroadcastSessionStop sends a stop of the program to the clients subscribed to a
+// debugging session. Only protocol version 2 clients receive it.
+func (h *Hub) BroadcastSessionStop(sessionID string, stop StopPayload) {
+	h.broadcast <- Message{
+		Type:    TypeStop,
+		Payload: stop,
+		Session: sessionID,
+	}
+}
+
+// BroadcastSessionChat sends a chat turn of one of a debugging session's members to the
+// clients subscribed to the session. Only protocol version 2 clients receive it.
+func (h *Hub) BroadcastSessionChat(sessionID string, chat ChatPayload) {
+	h.broadcast <- Message{
+		Type:    TypeChat,
+		Payload: chat,
+		Session: sessionID,
+	}
+}
+
+// Broadcast sends GDB output to all connected clients
 func (h *Hub) Broadcast(content string) {
+	h.BroadcastToUser("", content)
+}
+
+// BroadcastToUser sends GDB output to the clients of one user. With authentication
+// disabled the user is "" and every client receives it.
+func (h *Hub) BroadcastToUser(user, content string) {
+	h.broadcast <- Message{
+		Type:    TypeGDBOutput,
+		Payload: OutputPayload{Text: content},
+		User:    user,
+	}
+}
+
+// BroadcastStatus sends a status change to the clients of one user. Only protocol
+// version 2 clients receive it.
+func (h *Hub) BroadcastStatus(user string, status StatusPayload) {
+	h.broadcast <- Message{
+		Type:    TypeStatus,
+		Payload: status,
+		User:    user,
+	}
+}
+
+// BroadcastStop sends a stop of the program to the clients of one user. Only protocol
+// version 2 clients receive it.
+func (h *Hub) BroadcastStop(user string, stop StopPayload) {
 	h.broadcast <- Message{
-		Content: content,
+		Type:    TypeStop,
+		Payload: stop,
+		User:    user,
+	}
+}
+
+// SendChatStream sends part of a streamed chat response to the clients of one user. Only
+// protocol version 2 clients receive it.
+func (h *Hub) SendChatStream(user string, chunk ChatStreamPayload) {
+	h.broadcast <- Message{
+		Type:    TypeChatStream,
+		Payload: chunk,
+		User:    user,
+	}
+}
+
+// sendTo delivers a message to a single client. The message is dropped if the client has
+// been unregistered or its send buffer is full.
+func (h *Hub) sendTo(client *Client, message Message) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.clients[client] {
+		return false
 	}
+	return h.enqueueLocked(client, message)
 }
 
 // ClientCount returns the number of connected clients
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestHubSessionDelivery(t *testing.T) {
+	hub := NewHub(&config.Config{})
+	go hub.Run()
+
+	subscribed := &Client{Hub: hub, Send: make(chan Message, 4), Session: "s1"}
+	other := &Client{Hub: hub, Send: make(chan Message, 4), Session: "s2"}
+	unsubscribed := &Client{Hub: hub, Send: make(chan Message, 4)}
+	for _, client := range []*Client{subscribed, other, unsubscribed} {
+		hub.register <- client
+	}
+
+	hub.BroadcastToSession("s1", "Breakpoint 1, main ()")
+	hub.Broadcast("announcement")
+
+	received := func(client *Client) []string {
+		var texts []string
+		timeout := time.After(100 * time.Millisecond)
+		for {
+			select {
+			case message := <-client.Send:
+				if output, ok := message.Payload.(OutputPayload); ok {
+					texts = append(texts, output.Text)
+				}
+			case <-timeout:
+				return texts
+			}
+		}
+	}
+	assert.Equal(t, []string{"Breakpoint 1, main ()", "announcement"}, received(subscribed))
+	assert.Equal(t, []string{"announcement"}, received(other))
+	assert.Equal(t, []string{"announcement"}, received(unsubscribed))
+
+	// Unregistering removes the client from its session
+	hub.unregister <- subscribed
+	assert.Eventually(t, func() bool {
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+		return len(hub.clients) == 2 && hub.sessions["s1"] == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHubPresence(t *testing.T) {
+	hub := NewHub(&config.Config{})
+	go hub.Run()
+
+	alice := &Client{Hub: hub, Send: make(chan Message, 4), User: "alice", Session: "s1"}
+	bob := &Client{Hub: hub, Send: make(chan Message, 4), User: "bob", Session: "s1"}
+	hub.register <- alice
+	hub.register <- bob
+
+	presence := func(client *Client) PresencePayload {
+		select {
+		case message := <-client.Send:
+			assert.Equal(t, TypePresence, message.Type)
+			return message.Payload.(PresencePayload)
+		case <-time.After(time.Second):
+			t.Fatal("no presence message")
+			return PresencePayload{}
+		}
+	}
+
+	// Each client is told who is connected when a client joins
+	assert.Equal(t, []string{"alice"}, presence(alice).Users)
+	assert.Equal(t, PresencePayload{Session: "s1", Users: []string{"alice", "bob"}, Clients: 2}, presence(alice))
+	assert.Equal(t, []string{"alice", "bob"}, presence(bob).Users)
+	assert.Equal(t, []string{"alice", "bob"}, hub.SessionUsers("s1"))
+
+	// and when one leaves
+	hub.unregister <- bob
+	assert.Equal(t, PresencePayload{Session: "s1", Users: []string{"alice"}, Clients: 1}, presence(alice))
+	assert.Equal(t, []string{"alice"}, hub.SessionUsers("s1"))
+}
+
+func TestHubEndShare(t *testing.T) {
+	hub := NewHub(&config.Config{})
+	go hub.Run()
+
+	watcher := &Client{Hub: hub, Send: make(chan Message, 4), Session: "s1", share: "t1"}
+	member := &Client{Hub: hub, Send: make(chan Message, 4), Session: "s1"}
+	hub.register <- watcher
+	hub.register <- member
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 2 }, time.Second, 10*time.Millisecond)
+
+	// Ending a share disconnects only those watching through it, saying why
+	hub.EndShare("t1")
+	assert.Equal(t, 1, hub.ClientCount())
+	assert.Equal(t, CloseReasonShareEnded, watcher.closeReason)
+	assert.Empty(t, member.closeReason)
+}
+
+func TestHubSlowClient(t *testing.T) {
+	hub := NewHub(&config.Config{WebSocket: config.WebSocketConfig{SlowClientTimeout: 20 * time.Millisecond}})
+	go hub.Run()
+
+	slow := &Client{Hub: hub, Send: make(chan Message, 1)}
+	hub.register <- slow
+
+	// A full queue drops messages but keeps the client for a while
+	hub.Broadcast("first")
+	hub.Broadcast("dropped")
+	metrics := hub.Metrics()
+	assert.Equal(t, 1, metrics.Clients)
+	assert.Equal(t, uint64(1), metrics.Dropped)
+	assert.Equal(t, 1, metrics.ClientMetrics[0].QueueDepth)
+
+	// A queue that stays full past the timeout disconnects the client with a reason
+	time.Sleep(30 * time.Millisecond)
+	hub.Broadcast("too late")
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, CloseReasonSlowClient, slow.closeReason)
+	assert.Equal(t, uint64(1), hub.Metrics().SlowDisconnects)
+}
@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+// Error codes sent to clients whose messages are rejected
+const (
+	ErrCodeMessageTooLarge = "message_too_large"
+	ErrCodeInvalidMessage  = "invalid_message"
+	ErrCodeUnknownType     = "unknown_type"
+	ErrCodeInvalidCommand  = "invalid_command"
+	ErrCodeRateLimited     = "rate_limited"
+	ErrCodeForbidden       = "forbidden"
+)
+
+// controlKeys are single-character commands forwarded to GDB as keystrokes. They are
+// exempt from rate limiting so a runaway inferior can always be interrupted.
+var controlKeys = map[string]bool{
+	"\x03": true, // Ctrl-C
+	"\x04": true, // Ctrl-D
+}
+
+// Limits bounds what a single client may send
+type Limits struct {
+	MaxMessageSize    int
+	MaxCommandLength  int
+	CommandsPerSecond float64
+	CommandBurst      int
+	MaxViolations     int
+}
+
+// LimitsFromConfig builds client limits from the websocket configuration, falling back
+// to the defaults for unset values
+func LimitsFromConfig(cfg config.WebSocketConfig) Limits {
+	limits := Limits{
+		MaxMessageSize:    cfg.MaxMessageSize,
+		MaxCommandLength:  cfg.MaxCommandLength,
+		CommandsPerSecond: cfg.CommandsPerSecond,
+		CommandBurst:      cfg.CommandBurst,
+		MaxViolations:     cfg.MaxViolations,
+	}
+	if limits.MaxMessageSize <= 0 {
+		limits.MaxMessageSize = 4096
+	}
+	if limits.MaxCommandLength <= 0 {
+		limits.MaxCommandLength = 1024
+	}
+	if limits.CommandsPerSecond <= 0 {
+		limits.CommandsPerSecond = 5
+	}
+	if limits.CommandBurst <= 0 {
+		limits.CommandBurst = 10
+	}
+	if limits.MaxViolations <= 0 {
+		limits.MaxViolations = 20
+	}
+	return limits
+}
+
+// ErrorReply is sent to a protocol version 1 client when one of its messages is rejected.
+// Version 2 clients receive an error envelope with an ErrorPayload instead.
+type ErrorReply struct {
+	Type  string `json:"type"` // Always "error"
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// newErrorReply creates the hub message rejecting a client message
+func newErrorReply(code, format string, args ...interface{}) Message {
+	return Message{Type: TypeError, Payload: ErrorPayload{Code: code, Error: fmt.Sprintf(format, args...)}}
+}
+
+// clientMessage is a validated message from a client
+type clientMessage struct {
+	Type    string
+	ID      string
+	Command string // Set for command and complete messages
+	Input   string // Set for input messages
+	Rows    int    // Set for resize messages
+	Cols    int
+}
+
+// Bounds of a terminal size in a resize message
+const (
+	maxTerminalRows = 500
+	maxTerminalCols = 1000
+)
+
+// validateMessage decodes a client message of the given protocol version and checks it
+// against the message schema. It returns the error reply to send when the message is
+// rejected; replies to version 2 messages carry the message's ID.
+func validateMessage(data []byte, protocol int, limits Limits) (clientMessage, *Message) {
+	var msg clientMessage
+	reject := func(code, format string, args ...interface{}) (clientMessage, *Message) {
+		reply := newErrorReply(code, format, args...)
+		reply.ID = msg.ID
+		return msg, &reply
+	}
+
+	if len(data) > limits.MaxMessageSize {
+		return reject(ErrCodeMessageTooLarge, "message exceeds %d bytes", limits.MaxMessageSize)
+	}
+
+	envelope, err := decodeMessage(data, protocol)
+	msg.Type, msg.ID = envelope.Type, envelope.ID
+	if err != nil {
+		if protocol == ProtocolV2 {
+			return reject(ErrCodeInvalidMessage, "message must be a JSON envelope with v, type, id and payload fields")
+		}
+		return reject(ErrCodeInvalidMessage, "message must be a JSON object with type and command fields")
+	}
+
+	switch {
+	case envelope.Type == TypeCommand:
+		var payload CommandPayload
+		if err := decodeStrict(envelope.Payload, &payload); err != nil {
+			return reject(ErrCodeInvalidMessage, "command payload must have a command field")
+		}
+		if err := validateCommand(payload.Command, limits.MaxCommandLength); err != nil {
+			return reject(ErrCodeInvalidCommand, "%v", err)
+		}
+		msg.Command = payload.Command
+	case envelope.Type == TypeInput && protocol == ProtocolV2:
+		var payload InputPayload
+		if err := decodeStrict(envelope.Payload, &payload); err != nil {
+			return reject(ErrCodeInvalidMessage, "input payload must have a data field")
+		}
+		if payload.Data == "" || !utf8.ValidString(payload.Data) {
+			return reject(ErrCodeInvalidMessage, "input must be non-empty UTF-8 text")
+		}
+		msg.Input = payload.Data
+	case envelope.Type == TypeResize && protocol == ProtocolV2:
+		var payload ResizePayload
+		if err := decodeStrict(envelope.Payload, &payload); err != nil {
+			return reject(ErrCodeInvalidMessage, "resize payload must have rows and cols fields")
+		}
+		if payload.Rows < 1 || payload.Rows > maxTerminalRows || payload.Cols < 1 || payload.Cols > maxTerminalCols {
+			return reject(ErrCodeInvalidMessage, "terminal size must be between 1x1 and %dx%d", maxTerminalCols, maxTerminalRows)
+		}
+		msg.Rows, msg.Cols = payload.Rows, payload.Cols
+	case envelope.Type == TypeComplete && protocol == ProtocolV2:
+		var payload CompletePayload
+		if err := decodeStrict(envelope.Payload, &payload); err != nil {
+			return reject(ErrCodeInvalidMessage, "complete payload must have a text field")
+		}
+		if err := validateCommand(payload.Text, limits.MaxCommandLength); err != nil || controlKeys[payload.Text] {
+			return reject(ErrCodeInvalidCommand, "text to complete must be a single line of GDB command text")
+		}
+		msg.Command = payload.Text
+	case envelope.Type == TypeHeartbeat && protocol == ProtocolV2:
+	default:
+		return reject(ErrCodeUnknownType, "unknown message type %q", envelope.Type)
+	}
+
+	return msg, nil
+}
+
+// validateCommand rejects commands that are too long or that contain control
+// characters, which could smuggle several GDB commands into one message
+func validateCommand(command string, maxLength int) error {
+	if controlKeys[command] {
+		return nil
+	}
+	if !utf8.ValidString(command) {
+		return fmt.Errorf("command is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(command); n > maxLength {
+		return fmt.Errorf("command is %d characters; the limit is %d", n, maxLength)
+	}
+	for _, r := range command {
+		if (r < 0x20 && r != '\t') || r == 0x7f {
+			return fmt.Errorf("command contains control character %U", r)
+		}
+	}
+	return nil
+}
+
+// inputRateFactor is how many more input messages than commands a client may send, since
+// interactive programs receive input a keystroke at a time
+const inputRateFactor = 10
+
+// tokenBucket limits the rate of commands from one client
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// newTokenBucket creates a full bucket refilled at rate tokens per second
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token if one is available
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
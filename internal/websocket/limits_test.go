@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestValidateMessage(t *testing.T) {
+	limits := LimitsFromConfig(config.WebSocketConfig{MaxMessageSize: 128, MaxCommandLength: 16})
+
+	tests := []struct {
+		name    string
+		message string
+		code    string
+	}{
+		{"valid command", `{"type":"command","command":"info registers"}`, ""},
+		{"ctrl-c", `{"type":"command","command":"\u0003"}`, ""},
+		{"too large", `{"type":"command","command":"` + strings.Repeat("x", 200) + `"}`, ErrCodeMessageTooLarge},
+		{"not json", `break main`, ErrCodeInvalidMessage},
+		{"unknown field", `{"type":"command","command":"run","shell":"rm -rf /"}`, ErrCodeInvalidMessage},
+		{"unknown type", `{"type":"exec","command":"run"}`, ErrCodeUnknownType},
+		{"command too long", `{"type":"command","command":"print 12345678901234567890"}`, ErrCodeInvalidCommand},
+		{"embedded newline", `{"type":"command","command":"run\nshell id"}`, ErrCodeInvalidCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, reply := validateMessage([]byte(tt.message), ProtocolV1, limits)
+			if tt.code == "" {
+				assert.Nil(t, reply)
+				return
+			}
+
+			require.NotNil(t, reply)
+			data, ok := encodeMessage(*reply, ProtocolV1, 1)
+			require.True(t, ok)
+			var decoded ErrorReply
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, "error", decoded.Type)
+			assert.Equal(t, tt.code, decoded.Code)
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.allow(now), "burst command %d", i)
+	}
+	assert.False(t, bucket.allow(now))
+
+	// Two tokens per second refill one token every 500ms
+	assert.True(t, bucket.allow(now.Add(500*time.Millisecond)))
+	assert.False(t, bucket.allow(now.Add(500*time.Millisecond)))
+}
@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Close codes and reasons sent when the hub disconnects a client. Codes 4000-4999 are
+// reserved for applications.
+const (
+	CloseCodeSlowClient   = 4008
+	CloseReasonSlowClient = "slow_client"
+
+	// Sent to a client watching through a share link once the link expires or is revoked
+	CloseCodeShareEnded   = 4010
+	CloseReasonShareEnded = "share_ended"
+)
+
+// clientStats tracks how well a client keeps up with its messages. The counters are
+// updated by the hub and the client's writer without holding the hub's mutex.
+type clientStats struct {
+	connectedAt     time.Time // Set on registration
+	sent            atomic.Uint64
+	dropped         atomic.Uint64
+	latencyTotal    atomic.Int64 // Nanoseconds from queueing to writing, summed over sent messages
+	latencyMax      atomic.Int64
+	backloggedSince atomic.Int64 // Unix nanoseconds of the first drop since the queue last drained; 0 if not backlogged
+}
+
+// recordSent records a message written to the client after waiting latency in its queue.
+// Once the queue has drained the client is no longer backlogged.
+func (c *Client) recordSent(latency time.Duration, queued int) {
+	c.stats.sent.Add(1)
+	c.stats.latencyTotal.Add(int64(latency))
+	for {
+		max := c.stats.latencyMax.Load()
+		if int64(latency) <= max || c.stats.latencyMax.CompareAndSwap(max, int64(latency)) {
+			break
+		}
+	}
+	if queued == 0 {
+		c.stats.backloggedSince.Store(0)
+	}
+}
+
+// hubTotals are counters kept across client disconnects
+type hubTotals struct {
+	dropped         atomic.Uint64
+	slowDisconnects atomic.Uint64
+}
+
+// ClientMetrics describes one connected client
+type ClientMetrics struct {
+	User             string    `json:"user,omitempty"`
+	Session          string    `json:"session,omitempty"`
+	Protocol         int       `json:"protocol"`
+	ConnectedAt      time.Time `json:"connectedAt"`
+	QueueDepth       int       `json:"queueDepth"`
+	QueueCapacity    int       `json:"queueCapacity"`
+	Sent             uint64    `json:"sent"`
+	Dropped          uint64    `json:"dropped"`
+	AvgSendLatencyMs float64   `json:"avgSendLatencyMs"`
+	MaxSendLatencyMs float64   `json:"maxSendLatencyMs"`
+	BackloggedFor    string    `json:"backloggedFor,omitempty"` // How long the queue has been full
+}
+
+// HubMetrics describes the hub and its connected clients
+type HubMetrics struct {
+	Timestamp         time.Time       `json:"timestamp"`
+	Clients           int             `json:"clients"`
+	Dropped           uint64          `json:"dropped"`         // Messages dropped since the server started
+	SlowDisconnects   uint64          `json:"slowDisconnects"` // Clients disconnected for being too slow
+	SlowClientTimeout string          `json:"slowClientTimeout"`
+	ClientMetrics     []ClientMetrics `json:"clientMetrics"`
+}
+
+// Metrics returns a snapshot of the hub's counters and each client's queue
+func (h *Hub) Metrics() HubMetrics {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	metrics := HubMetrics{
+		Timestamp:         now,
+		Clients:           len(h.clients),
+		Dropped:           h.totals.dropped.Load(),
+		SlowDisconnects:   h.totals.slowDisconnects.Load(),
+		SlowClientTimeout: h.slowClientTimeout.String(),
+		ClientMetrics:     make([]ClientMetrics, 0, len(h.clients)),
+	}
+	for client := range h.clients {
+		m := ClientMetrics{
+			User:          client.User,
+			Session:       client.Session,
+			Protocol:      client.Protocol,
+			ConnectedAt:   client.stats.connectedAt,
+			QueueDepth:    len(client.Send),
+			QueueCapacity: cap(client.Send),
+			Sent:          client.stats.sent.Load(),
+			Dropped:       client.stats.dropped.Load(),
+		}
+		if m.Sent > 0 {
+			m.AvgSendLatencyMs = float64(client.stats.latencyTotal.Load()) / float64(m.Sent) / float64(time.Millisecond)
+			m.MaxSendLatencyMs = float64(client.stats.latencyMax.Load()) / float64(time.Millisecond)
+		}
+		if since := client.stats.backloggedSince.Load(); since != 0 {
+			m.BackloggedFor = now.Sub(time.Unix(0, since)).Round(time.Millisecond).String()
+		}
+		metrics.ClientMetrics = append(metrics.ClientMetrics, m)
+	}
+	sort.Slice(metrics.ClientMetrics, func(i, j int) bool {
+		return metrics.ClientMetrics[i].ConnectedAt.Before(metrics.ClientMetrics[j].ConnectedAt)
+	})
+	return metrics
+}
+
+// HandleMetrics returns the hub's metrics, e.g. GET /api/ws/metrics
+func (h *Hub) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Metrics())
+}
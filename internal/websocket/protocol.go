@@ -0,0 +1,218 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Protocol versions. Version 1 sends GDB output as raw text and errors as ErrorReply
+// objects; version 2 wraps every message in an Envelope. Clients opt in to version 2 by
+// requesting the ProtocolV2Subprotocol WebSocket subprotocol during the handshake.
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = 2
+
+	ProtocolV2Subprotocol = "gogdbllm.v2"
+)
+
+// Message types of protocol version 2
+const (
+	TypeCommand     = "command"     // Client: run a GDB command
+	TypeInput       = "input"       // Client: send input to the debugged program's terminal
+	TypeResize      = "resize"      // Client: the terminal's size changed
+	TypeComplete    = "complete"    // Client: complete a partial GDB command
+	TypeCompletions = "completions" // Server: reply to complete
+	TypeGDBOutput   = "gdb_output"  // Server: output from GDB
+	TypeChatStream  = "chat_stream" // Server: part of a streamed chat response
+	TypeChat        = "chat"        // Server: a chat turn of a member of the debugging session
+	TypePresence    = "presence"    // Server: who is connected to the debugging session
+	TypeStatus      = "status"      // Server: connection or debugging session state changed
+	TypeStop        = "stop"        // Server: the program stopped, with where and the source around it
+	TypeError       = "error"       // Server: a client message was rejected
+	TypeHeartbeat   = "heartbeat"   // Both: keep-alive; the server echoes a client heartbeat's ID
+)
+
+// Envelope wraps every protocol version 2 message
+type Envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"` // Client-chosen for requests; replies carry the request's ID
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// CommandPayload is the payload of a command message
+type CommandPayload struct {
+	Command string `json:"command"`
+}
+
+// InputPayload is the payload of an input message. Data is sent to the program as typed,
+// so it may hold control characters and escape sequences.
+type InputPayload struct {
+	Data string `json:"data"`
+}
+
+// ResizePayload is the payload of a resize message
+type ResizePayload struct {
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+}
+
+// CompletePayload is the payload of a complete message
+type CompletePayload struct {
+	Text string `json:"text"`
+}
+
+// CompletionsPayload is the payload of a completions message. Completions are whole
+// command lines that start with Text.
+type CompletionsPayload struct {
+	Text        string   `json:"text"`
+	Completions []string `json:"completions"`
+}
+
+// OutputPayload is the payload of a gdb_output message
+type OutputPayload struct {
+	Text string `json:"text"`
+}
+
+// ChatStreamPayload is the payload of a chat_stream message
+type ChatStreamPayload struct {
+	RequestID string `json:"requestId"`
+	Delta     string `json:"delta"`
+	Done      bool   `json:"done,omitempty"`
+}
+
+// ChatPayload is the payload of a chat message, sent to every client of a shared
+// debugging session so its members follow each other's conversations with the assistant
+type ChatPayload struct {
+	RequestID string `json:"requestId,omitempty"` // As chosen by the client that sent Message
+	User      string `json:"user,omitempty"`
+	Message   string `json:"message"`
+	Response  string `json:"response"`
+}
+
+// PresencePayload is the payload of a presence message, sent to the clients of a
+// debugging session whenever one connects or disconnects
+type PresencePayload struct {
+	Session string   `json:"session"`
+	Users   []string `json:"users"`   // Users with a client connected, sorted; none without authentication
+	Clients int      `json:"clients"` // Connected clients, counting each of a user's tabs
+}
+
+// StatusPayload is the payload of a status message
+type StatusPayload struct {
+	Protocol int    `json:"protocol,omitempty"` // Set in the status sent when a client connects
+	User     string `json:"user,omitempty"`
+	Session  string `json:"session,omitempty"` // Session subscribed to during the handshake
+	GDB      string `json:"gdb,omitempty"`     // "running", "exited" or "restarted"
+	File     string `json:"file,omitempty"`
+	// Set when GDB exited on its own: why, and for a restart how many breakpoints were set
+	// again
+	Reason      string `json:"reason,omitempty"`
+	Breakpoints int    `json:"breakpoints,omitempty"`
+}
+
+// StopPayload is the payload of a stop message, sent after the gdb_output reporting the
+// stop, so a client can show the line the program stopped at without asking GDB for it
+type StopPayload struct {
+	Reason      string       `json:"reason"` // "breakpoint", "watchpoint", "signal", "exited", "stepped", ...
+	Breakpoint  int          `json:"breakpoint,omitempty"`
+	Signal      string       `json:"signal,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Function    string       `json:"function,omitempty"`
+	Address     string       `json:"address,omitempty"`
+	File        string       `json:"file,omitempty"` // As GDB names it
+	Line        int          `json:"line,omitempty"`
+	Source      []SourceLine `json:"source,omitempty"` // Lines around Line, when the server has the file
+}
+
+// SourceLine is a line of source in a stop message
+type SourceLine struct {
+	Number  int    `json:"number"`
+	Text    string `json:"text"`
+	Current bool   `json:"current,omitempty"`
+}
+
+// ErrorPayload is the payload of an error message
+type ErrorPayload struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// HeartbeatPayload is the payload of a heartbeat message
+type HeartbeatPayload struct {
+	Time time.Time `json:"time"`
+}
+
+// negotiateProtocol returns the protocol version for the subprotocol agreed in the handshake
+func negotiateProtocol(subprotocol string) int {
+	if subprotocol == ProtocolV2Subprotocol {
+		return ProtocolV2
+	}
+	return ProtocolV1
+}
+
+// encodeMessage renders a hub message for a client speaking the given protocol version.
+// seq numbers server-initiated version 2 messages. It returns false when the message has
+// no version 1 representation and should not be sent to the client.
+func encodeMessage(message Message, protocol int, seq uint64) ([]byte, bool) {
+	if protocol == ProtocolV2 {
+		payload, err := json.Marshal(message.Payload)
+		if err != nil {
+			return nil, false
+		}
+		id := message.ID
+		if id == "" {
+			id = "s" + strconv.FormatUint(seq, 10)
+		}
+		data, err := json.Marshal(Envelope{V: ProtocolV2, Type: message.Type, ID: id, Payload: payload})
+		return data, err == nil
+	}
+
+	switch payload := message.Payload.(type) {
+	case OutputPayload:
+		return []byte(payload.Text), true
+	case ErrorPayload:
+		data, err := json.Marshal(ErrorReply{Type: TypeError, Code: payload.Code, Error: payload.Error})
+		return data, err == nil
+	default:
+		return nil, false
+	}
+}
+
+// decodeMessage decodes a client message of the given protocol version into a
+// version 2 envelope; version 1 messages are {"type": "command", "command": "..."}
+func decodeMessage(data []byte, protocol int) (Envelope, error) {
+	if protocol == ProtocolV2 {
+		var envelope Envelope
+		if err := decodeStrict(data, &envelope); err != nil {
+			return envelope, err
+		}
+		if envelope.V != ProtocolV2 {
+			return envelope, fmt.Errorf("unsupported protocol version %d", envelope.V)
+		}
+		return envelope, nil
+	}
+
+	var msg WebSocketMessage
+	if err := decodeStrict(data, &msg); err != nil {
+		return Envelope{}, err
+	}
+	payload, _ := json.Marshal(CommandPayload{Command: msg.Command})
+	return Envelope{V: ProtocolV1, Type: msg.Type, Payload: payload}, nil
+}
+
+// decodeStrict decodes a single JSON value, rejecting unknown fields and trailing data
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after message")
+	}
+	return nil
+}
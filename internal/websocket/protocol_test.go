@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/config"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	assert.Equal(t, ProtocolV2, negotiateProtocol(ProtocolV2Subprotocol))
+	assert.Equal(t, ProtocolV1, negotiateProtocol(""))
+}
+
+func TestValidateMessageV2(t *testing.T) {
+	limits := LimitsFromConfig(config.WebSocketConfig{MaxMessageSize: 256, MaxCommandLength: 16})
+
+	msg, reply := validateMessage([]byte(`{"v":2,"type":"command","id":"c1","payload":{"command":"bt"}}`), ProtocolV2, limits)
+	assert.Nil(t, reply)
+	assert.Equal(t, clientMessage{Type: TypeCommand, ID: "c1", Command: "bt"}, msg)
+
+	// Program input may hold control characters and escape sequences
+	msg, reply = validateMessage([]byte(`{"v":2,"type":"input","id":"i1","payload":{"data":"q\u001b[A\n"}}`), ProtocolV2, limits)
+	assert.Nil(t, reply)
+	assert.Equal(t, clientMessage{Type: TypeInput, ID: "i1", Input: "q\x1b[A\n"}, msg)
+
+	msg, reply = validateMessage([]byte(`{"v":2,"type":"resize","id":"r1","payload":{"rows":40,"cols":132}}`), ProtocolV2, limits)
+	assert.Nil(t, reply)
+	assert.Equal(t, clientMessage{Type: TypeResize, ID: "r1", Rows: 40, Cols: 132}, msg)
+
+	msg, reply = validateMessage([]byte(`{"v":2,"type":"complete","id":"c6","payload":{"text":"info reg"}}`), ProtocolV2, limits)
+	assert.Nil(t, reply)
+	assert.Equal(t, clientMessage{Type: TypeComplete, ID: "c6", Command: "info reg"}, msg)
+
+	msg, reply = validateMessage([]byte(`{"v":2,"type":"heartbeat","id":"h1"}`), ProtocolV2, limits)
+	assert.Nil(t, reply)
+	assert.Equal(t, TypeHeartbeat, msg.Type)
+
+	tests := []struct {
+		name    string
+		message string
+		code    string
+	}{
+		{"version 1 message", `{"type":"command","command":"bt"}`, ErrCodeInvalidMessage},
+		{"wrong version", `{"v":3,"type":"command","id":"c2","payload":{"command":"bt"}}`, ErrCodeInvalidMessage},
+		{"missing payload", `{"v":2,"type":"command","id":"c3"}`, ErrCodeInvalidMessage},
+		{"server type", `{"v":2,"type":"gdb_output","id":"c4","payload":{"text":"x"}}`, ErrCodeUnknownType},
+		{"embedded newline", `{"v":2,"type":"command","id":"c5","payload":{"command":"run\nshell id"}}`, ErrCodeInvalidCommand},
+		{"empty input", `{"v":2,"type":"input","id":"i2","payload":{"data":""}}`, ErrCodeInvalidMessage},
+		{"zero size", `{"v":2,"type":"resize","id":"r2","payload":{"rows":0,"cols":80}}`, ErrCodeInvalidMessage},
+		{"multi-line completion", `{"v":2,"type":"complete","id":"c7","payload":{"text":"b\nrun"}}`, ErrCodeInvalidCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, reply := validateMessage([]byte(tt.message), ProtocolV2, limits)
+			require.NotNil(t, reply)
+			assert.Equal(t, tt.code, reply.Payload.(ErrorPayload).Code)
+		})
+	}
+
+	// Heartbeats are a version 2 message type
+	_, reply = validateMessage([]byte(`{"type":"heartbeat","command":""}`), ProtocolV1, limits)
+	require.NotNil(t, reply)
+	assert.Equal(t, ErrCodeUnknownType, reply.Payload.(ErrorPayload).Code)
+}
+
+func TestEncodeMessage(t *testing.T) {
+	output := Message{Type: TypeGDBOutput, Payload: OutputPayload{Text: "(gdb) "}}
+
+	data, ok := encodeMessage(output, ProtocolV1, 1)
+	require.True(t, ok)
+	assert.Equal(t, "(gdb) ", string(data))
+
+	data, ok = encodeMessage(output, ProtocolV2, 7)
+	require.True(t, ok)
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, Envelope{V: 2, Type: TypeGDBOutput, ID: "s7", Payload: json.RawMessage(`{"text":"(gdb) "}`)}, envelope)
+
+	// Replies keep the request's ID
+	reply := newErrorReply(ErrCodeRateLimited, "slow down")
+	reply.ID = "c9"
+	data, _ = encodeMessage(reply, ProtocolV2, 8)
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, "c9", envelope.ID)
+
+	// Version 1 clients have no representation for status, stream or heartbeat messages
+	_, ok = encodeMessage(Message{Type: TypeStatus, Payload: StatusPayload{GDB: "running"}}, ProtocolV1, 2)
+	assert.False(t, ok)
+}
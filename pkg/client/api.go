@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Upload is an executable uploaded to the server, with the debugging session it started
+type Upload struct {
+	Filename     string `json:"filename"`     // Name of the executable on the server, for StartSession
+	SessionToken string `json:"sessionToken"` // Token of the session, for Stream
+	Format       string `json:"format,omitempty"`
+	BinaryID     string `json:"binaryId,omitempty"`
+}
+
+// StartOptions say how GDB runs the program
+type StartOptions struct {
+	Args       []string          `json:"args,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	StdinFile  string            `json:"stdinFile,omitempty"`  // File of the uploaded sources read as the program's input
+	WorkingDir string            `json:"workingDir,omitempty"` // As the program sees it
+}
+
+// ChatMessage is a turn of a conversation with the assistant
+type ChatMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// ChatRequest is a question for the assistant
+type ChatRequest struct {
+	Message       string        `json:"message"`
+	History       []ChatMessage `json:"history"`
+	RequestID     string        `json:"requestId,omitempty"`     // Chosen by the client to cancel the request with
+	Profile       string        `json:"profile,omitempty"`       // Prompt profile instead of the user's
+	TerminalLines int           `json:"terminalLines,omitempty"` // Attach the last lines of the session's output
+	BinaryContext bool          `json:"binaryContext,omitempty"` // Attach a summary of the executable
+	ScriptResults bool          `json:"scriptResults,omitempty"` // Attach the session's recent GDB Python script runs
+	Variables     bool          `json:"variables,omitempty"`     // Attach the innermost frame's arguments and locals
+	Function      string        `json:"function,omitempty"`      // Attach the decompiled code of a function
+	Attachments   []string      `json:"attachments,omitempty"`   // IDs of uploaded attachments
+	Model         string        `json:"model,omitempty"`         // Model instead of the user's
+	Temperature   *float64      `json:"temperature,omitempty"`
+	MaxTokens     int           `json:"maxTokens,omitempty"`
+}
+
+// ChatResponse is the assistant's answer
+type ChatResponse struct {
+	Response          string   `json:"response"`
+	Model             string   `json:"model,omitempty"`
+	Refused           bool     `json:"refused,omitempty"`
+	SuggestedCommands []string `json:"suggestedCommands,omitempty"` // Commands to consider running; never run by the server
+	NextPage          string   `json:"nextPage,omitempty"`          // Set when Response is the first page of a longer one
+	Cancelled         bool     `json:"cancelled,omitempty"`
+	Cost              float64  `json:"cost,omitempty"` // US dollars
+}
+
+// response is the envelope of the API's JSON responses
+type response struct {
+	Data interface{} `json:"data"`
+}
+
+// Login signs in with a username and password (auth.mode: password). The session cookie
+// is kept in the HTTP client's cookie jar.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/auth/login", jsonBody(map[string]string{
+		"username": username,
+		"password": password,
+	}), nil)
+}
+
+// UploadFile uploads the executable at path, which replaces the user's debugging session
+// with a new one
+func (c *Client) UploadFile(ctx context.Context, path string) (*Upload, error) {
+	return c.upload(ctx, filepath.Base(path), func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+}
+
+// Upload uploads an executable read from r under the given name. Unlike UploadFile, it is
+// not retried, since r cannot be read again.
+func (c *Client) Upload(ctx context.Context, name string, r io.Reader) (*Upload, error) {
+	sent := false
+	noRetry := *c
+	noRetry.attempts = 1
+	return noRetry.upload(ctx, name, func() (io.ReadCloser, error) {
+		if sent {
+			return nil, io.ErrUnexpectedEOF
+		}
+		sent = true
+		return io.NopCloser(r), nil
+	})
+}
+
+// upload streams a multipart form holding the executable opened by open
+func (c *Client) upload(ctx context.Context, name string, open func() (io.ReadCloser, error)) (*Upload, error) {
+	var upload Upload
+	err := c.do(ctx, http.MethodPost, "/api/v1/upload", func() (io.Reader, string, error) {
+		file, err := open()
+		if err != nil {
+			return nil, "", err
+		}
+		// Stream the form rather than holding the executable in memory
+		reader, writer := io.Pipe()
+		form := multipart.NewWriter(writer)
+		go func() {
+			defer file.Close()
+			part, err := form.CreateFormFile("executable", name)
+			if err == nil {
+				_, err = io.Copy(part, file)
+			}
+			if err == nil {
+				err = form.Close()
+			}
+			writer.CloseWithError(err)
+		}()
+		return reader, form.FormDataContentType(), nil
+	}, &response{Data: &upload})
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// StartSession starts GDB on an uploaded executable, running the program as opts say, if
+// given. Its output goes to the streams of the session.
+func (c *Client) StartSession(ctx context.Context, filename string, opts *StartOptions) error {
+	req := struct {
+		Filename string `json:"filename"`
+		StartOptions
+	}{Filename: filename}
+	if opts != nil {
+		req.StartOptions = *opts
+	}
+	return c.do(ctx, http.MethodPost, "/api/v1/start-gdb", jsonBody(req), nil)
+}
+
+// StopSession stops the user's GDB
+func (c *Client) StopSession(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/stop-gdb", jsonBody(struct{}{}), nil)
+}
+
+// Chat asks the assistant about the debugging session. Cancelling ctx abandons the
+// request; CancelChat with its RequestID also stops the server working on it.
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if req.History == nil {
+		req.History = []ChatMessage{}
+	}
+	var resp ChatResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/chat", jsonBody(req), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelChat cancels the chat request with the given RequestID
+func (c *Client) CancelChat(ctx context.Context, requestID string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/chat/cancel", jsonBody(map[string]string{"requestId": requestID}), nil)
+}
@@ -0,0 +1,219 @@
+// Package client is a Go client of a gogdbllm server, for tools and tests that drive it
+// programmatically: upload an executable, start GDB on it, stream the session's output,
+// run commands and ask the assistant. It calls the versioned API under /api/v1 and the
+// WebSocket with protocol version 2.
+//
+//	c, err := client.New("http://localhost:8080", client.WithToken(token))
+//	upload, err := c.UploadFile(ctx, "./crash")
+//	stream, err := c.Stream(ctx, upload.SessionToken)
+//	err = c.StartSession(ctx, upload.Filename, nil)
+//	err = stream.Command("run")
+//	answer, err := c.Chat(ctx, client.ChatRequest{Message: "Why did it crash?", TerminalLines: 50})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseSize limits the size of a response the client reads
+const maxResponseSize = 16 << 20
+
+// Client calls a gogdbllm server. It is safe for concurrent use.
+type Client struct {
+	base     *url.URL
+	token    string
+	http     *http.Client
+	attempts int           // Tries of a request, including the first
+	backoff  time.Duration // Wait before the first retry, doubled for each further one
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates with a bearer token (auth.mode: token). In password mode, call
+// Login instead.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient sends requests with hc instead of a client of the default transport. Its
+// cookie jar, if any, keeps the session of Login.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetry tries requests up to attempts times, waiting backoff before the first retry
+// and twice as long before each further one; 1 disables retries. By default a request is
+// tried 3 times, from 500ms apart.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *Client) { c.attempts, c.backoff = attempts, backoff }
+}
+
+// New creates a client of the server at serverURL, e.g. "https://gdb.example.com/gdb"
+func New(serverURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", serverURL)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{base: base, http: &http.Client{Jar: jar}, attempts: 3, backoff: 500 * time.Millisecond}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.attempts < 1 {
+		c.attempts = 1
+	}
+	return c, nil
+}
+
+// Error is a request the server refused or failed
+type Error struct {
+	StatusCode int
+	Code       string // Machine-readable error code, for the endpoints that give one
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsStatus reports whether err is an Error with the given HTTP status
+func IsStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// body returns a request body and its content type, afresh for each attempt
+type body func() (io.Reader, string, error)
+
+// jsonBody returns the body of v as JSON
+func jsonBody(v interface{}) body {
+	return func() (io.Reader, string, error) {
+		data, err := json.Marshal(v)
+		return bytes.NewReader(data), "application/json", err
+	}
+}
+
+// do sends a request to path under the server's URL, retrying it as the client is
+// configured, and decodes the data of the JSON response into v, unless v is nil
+func (c *Client) do(ctx context.Context, method, path string, newBody body, v interface{}) error {
+	wait := c.backoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := c.try(ctx, method, path, newBody, v)
+		if err == nil || attempt >= c.attempts || !retryable(method, err) {
+			return err
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// try sends a request once, returning how long the server asked to wait before retrying
+// it, if it did
+func (c *Client) try(ctx context.Context, method, path string, newBody body, v interface{}) (time.Duration, error) {
+	var reader io.Reader
+	var contentType string
+	if newBody != nil {
+		var err error
+		if reader, contentType, err = newBody(); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base.String()+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, responseError(resp.StatusCode, data)
+	}
+	if v == nil {
+		return 0, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return 0, fmt.Errorf("decoding the response of %s: %w", path, err)
+	}
+	return 0, nil
+}
+
+// retryable reports whether a failed request may be sent again: when the server was busy
+// or unreachable behind a proxy, or could not be connected to. Other failures of requests
+// that change something are not retried, since the server may have acted on them.
+func retryable(method string, err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// responseError returns the error of a failed request. Handlers write errors as JSON with
+// an error field, and sometimes a code, or as plain text.
+func responseError(status int, data []byte) error {
+	var payload struct {
+		Error string          `json:"error"`
+		Code  json.RawMessage `json:"code"`
+	}
+	if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+		var code string
+		json.Unmarshal(payload.Code, &code) // Only string codes are the handlers' own
+		return &Error{StatusCode: status, Code: code, Message: payload.Error}
+	}
+	return &Error{StatusCode: status, Message: strings.TrimSpace(string(data))}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/gogdbllm/internal/websocket"
+)
+
+// newTestServer plays the server's API for the token "secret". Chat answers 503 the
+// first time.
+func newTestServer(t *testing.T) (*httptest.Server, *int32) {
+	var chats int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("executable")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "\x7fELF", string(data))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"filename": header.Filename, "sessionToken": "session-token"},
+		})
+	})
+	mux.HandleFunc("/api/v1/start-gdb", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["filename"] != "crash" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "no such file", "code": "FILE_NOT_FOUND"})
+			return
+		}
+		assert.Equal(t, []interface{}{"-v"}, req["args"])
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	mux.HandleFunc("/api/v1/chat", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&chats, 1) == 1 {
+			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(ChatResponse{Response: "You asked: " + req.Message, SuggestedCommands: []string{"bt"}})
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "session-token" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		upgrader := gws.Upgrader{Subprotocols: []string{subprotocol}}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		// Answer each command with its output
+		for {
+			var msg envelope
+			if conn.ReadJSON(&msg) != nil {
+				return
+			}
+			var command struct {
+				Command string `json:"command"`
+			}
+			json.Unmarshal(msg.Payload, &command)
+			payload, _ := json.Marshal(map[string]string{"text": "ran " + command.Command + "\r\n"})
+			conn.WriteJSON(envelope{V: 2, Type: EventOutput, Payload: payload})
+		}
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Authentication required", "code": 401})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &chats
+}
+
+func TestClient(t *testing.T) {
+	server, chats := newTestServer(t)
+	ctx := context.Background()
+	c, err := New(server.URL+"/", WithToken("secret"), WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "crash")
+	require.NoError(t, os.WriteFile(path, []byte("\x7fELF"), 0755))
+	upload, err := c.UploadFile(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, &Upload{Filename: "crash", SessionToken: "session-token"}, upload)
+
+	stream, err := c.Stream(ctx, upload.SessionToken)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	require.NoError(t, c.StartSession(ctx, upload.Filename, &StartOptions{Args: []string{"-v"}}))
+	err = c.StartSession(ctx, "missing", nil)
+	assert.True(t, IsStatus(err, http.StatusNotFound))
+	assert.Equal(t, "FILE_NOT_FOUND", err.(*Error).Code)
+
+	// Output streams back
+	require.NoError(t, stream.Command("bt"))
+	event, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Event{Type: EventOutput, Payload: event.Payload, Text: "ran bt\r\n"}, event)
+
+	// The busy server's 503 is retried
+	answer, err := c.Chat(ctx, ChatRequest{Message: "why?"})
+	require.NoError(t, err)
+	assert.Equal(t, "You asked: why?", answer.Response)
+	assert.Equal(t, int32(2), atomic.LoadInt32(chats))
+}
+
+func TestClientErrors(t *testing.T) {
+	server, chats := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := New("localhost:8080")
+	assert.Error(t, err)
+
+	// Refusals are not retried
+	c, err := New(server.URL, WithToken("wrong"), WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+	err = c.StopSession(ctx)
+	assert.True(t, IsStatus(err, http.StatusUnauthorized))
+	assert.Equal(t, "401 Unauthorized: Authentication required", err.Error())
+	_, err = c.Stream(ctx, "session-token")
+	assert.True(t, IsStatus(err, http.StatusUnauthorized))
+
+	// Without retries the busy server's 503 is returned
+	c, err = New(server.URL, WithToken("secret"), WithRetry(1, time.Millisecond))
+	require.NoError(t, err)
+	_, err = c.Chat(ctx, ChatRequest{Message: "why?"})
+	assert.True(t, IsStatus(err, http.StatusServiceUnavailable))
+	assert.Equal(t, int32(1), atomic.LoadInt32(chats))
+
+	// The stream ends with its context
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := c.Stream(streamCtx, "session-token")
+	require.NoError(t, err)
+	cancel()
+	_, err = stream.Next()
+	assert.Error(t, err)
+}
+
+func TestProtocol(t *testing.T) {
+	// The client speaks the server's protocol
+	assert.Equal(t, websocket.ProtocolV2Subprotocol, subprotocol)
+	assert.Equal(t, websocket.ProtocolV2, protocolVersion)
+	assert.Equal(t, websocket.TypeGDBOutput, EventOutput)
+	assert.Equal(t, websocket.TypeStop, EventStop)
+}
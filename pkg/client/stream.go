@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	gws "github.com/gorilla/websocket"
+)
+
+// Types of the events a Stream receives
+const (
+	EventOutput   = "gdb_output" // GDB's output, in Text
+	EventStatus   = "status"     // The connection or GDB's state changed
+	EventStop     = "stop"       // The program stopped, with where and the source around it
+	EventChat     = "chat"       // A chat turn of another member of the session
+	EventPresence = "presence"   // Who is connected to the session
+	EventError    = "error"      // A message of the client was rejected
+)
+
+// The version of the WebSocket protocol the client speaks, and its subprotocol name
+const (
+	protocolVersion = 2
+	subprotocol     = "gogdbllm.v2"
+)
+
+// Event is a message from the server
+type Event struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"` // Decode it by Type, e.g. a stop's location
+	Text    string          `json:"-"`                 // The output of EventOutput, or the error of EventError
+}
+
+// envelope frames the WebSocket's messages
+type envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Stream is a WebSocket connection to a debugging session: it receives the session's
+// output and events, and runs commands in it
+type Stream struct {
+	conn      *gws.Conn
+	mutex     sync.Mutex // Serializes writes
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Stream connects to the debugging session of sessionToken, from Upload. The stream is
+// closed when ctx is done.
+func (c *Client) Stream(ctx context.Context, sessionToken string) (*Stream, error) {
+	u := *c.base
+	u.Scheme = "ws"
+	if c.base.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+	u.Path += "/ws"
+	u.RawQuery = url.Values{"session": {sessionToken}}.Encode()
+
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	dialer := gws.Dialer{
+		Subprotocols: []string{subprotocol},
+		Jar:          c.http.Jar,
+		Proxy:        http.ProxyFromEnvironment,
+	}
+	if transport, ok := c.http.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("connecting to the session: %w", &Error{StatusCode: resp.StatusCode})
+		}
+		return nil, fmt.Errorf("connecting to the session: %w", err)
+	}
+
+	s := &Stream{conn: conn, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-s.done:
+		}
+	}()
+	return s, nil
+}
+
+// Next waits for the next event. It fails once the stream is closed.
+func (s *Stream) Next() (Event, error) {
+	var msg envelope
+	if err := s.conn.ReadJSON(&msg); err != nil {
+		return Event{}, err
+	}
+	event := Event{Type: msg.Type, Payload: msg.Payload}
+	switch msg.Type {
+	case EventOutput:
+		var output struct {
+			Text string `json:"text"`
+		}
+		json.Unmarshal(msg.Payload, &output)
+		event.Text = output.Text
+	case EventError:
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(msg.Payload, &failure)
+		event.Text = failure.Error
+	}
+	return event, nil
+}
+
+// Command runs a GDB command in the session
+func (s *Stream) Command(command string) error {
+	return s.send("command", map[string]string{"command": command})
+}
+
+// Input sends data to the debugged program's terminal
+func (s *Stream) Input(data string) error {
+	return s.send("input", map[string]string{"data": data})
+}
+
+// send sends a message of the given type
+func (s *Stream) send(messageType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.conn.WriteJSON(envelope{V: protocolVersion, Type: messageType, Payload: data})
+}
+
+// Close closes the stream
+func (s *Stream) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.conn.Close()
+}
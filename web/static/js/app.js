@@ -28,6 +28,11 @@ document.addEventListener('DOMContentLoaded', () => {
         initBasicSettings();
     }
     
+    // Initialize memory viewer
+    if (window.AppMemory && typeof window.AppMemory.initMemorySection === 'function') {
+        window.AppMemory.initMemorySection();
+    }
+    
     // Initialize chat panel
     if (window.AppChat && typeof window.AppChat.initChatPanel === 'function') {
         window.AppChat.initChatPanel();
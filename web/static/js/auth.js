@@ -0,0 +1,89 @@
+/**
+ * auth.js - Login overlay for servers with authentication enabled
+ */
+
+(function() {
+    // Show the login form if the server requires authentication and we have no session
+    async function initAuth() {
+        let status;
+        try {
+            const response = await fetch('/auth/status');
+            status = await response.json();
+        } catch (error) {
+            console.error('Failed to fetch auth status:', error);
+            return;
+        }
+
+        if (!status.enabled) {
+            return;
+        }
+
+        if (status.authenticated) {
+            showLogoutButton(status.user, status.role);
+            return;
+        }
+
+        showLoginOverlay(status.mode);
+    }
+
+    function showLoginOverlay(mode) {
+        const overlay = document.getElementById('loginOverlay');
+        const tokenGroup = document.getElementById('loginTokenGroup');
+        const passwordGroup = document.getElementById('loginPasswordGroup');
+        const form = document.getElementById('loginForm');
+        const status = document.getElementById('loginStatus');
+
+        tokenGroup.style.display = mode === 'token' ? 'block' : 'none';
+        passwordGroup.style.display = mode === 'password' ? 'block' : 'none';
+        overlay.classList.add('show');
+
+        form.addEventListener('submit', async (event) => {
+            event.preventDefault();
+
+            const body = mode === 'token'
+                ? { token: document.getElementById('loginToken').value }
+                : {
+                    username: document.getElementById('loginUsername').value,
+                    password: document.getElementById('loginPassword').value
+                };
+
+            try {
+                const response = await fetch('/auth/login', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!response.ok || !result.success) {
+                    status.textContent = result.error || 'Login failed';
+                    status.className = 'status-message error';
+                    return;
+                }
+                // Reload so the WebSocket connects with the new session cookie
+                window.location.reload();
+            } catch (error) {
+                status.textContent = 'Login failed: ' + error.message;
+                status.className = 'status-message error';
+            }
+        });
+    }
+
+    function showLogoutButton(user, role) {
+        const button = document.getElementById('logoutBtn');
+        const who = [user, role].filter(Boolean).join(', ');
+        button.textContent = who ? `Log out (${who})` : 'Log out';
+        // Viewers only watch; the server rejects their commands and chat messages
+        document.body.dataset.role = role || '';
+        button.style.display = 'inline-block';
+        button.addEventListener('click', async () => {
+            await fetch('/auth/logout', { method: 'POST' });
+            window.location.reload();
+        });
+    }
+
+    document.addEventListener('DOMContentLoaded', initAuth);
+
+    window.AppAuth = {
+        initAuth
+    };
+})();
@@ -0,0 +1,20 @@
+/**
+ * base_path.js - Moves the UI's requests under the server's base path (server.base_path)
+ * when it is reached through a proxy under one. Load it before the other scripts.
+ */
+
+// The base path, e.g. "/gdb", given by the server on the html element; "" without one
+const APP_BASE_PATH = document.documentElement.dataset.basePath || '';
+
+// Return a root-relative path under the base path; other URLs are left as they are
+function appPath(path) {
+    if (typeof path !== 'string' || !path.startsWith('/') || path.startsWith('//')) {
+        return path;
+    }
+    return APP_BASE_PATH + path;
+}
+
+if (APP_BASE_PATH) {
+    const fetchWithoutBasePath = window.fetch.bind(window);
+    window.fetch = (resource, options) => fetchWithoutBasePath(appPath(resource), options);
+}
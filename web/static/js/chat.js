@@ -28,6 +28,7 @@ function initChatPanel() {
     let chatHistory = [];
     let savedPanelWidth = localStorage.getItem('chatPanelWidth') || '400px';
     let stagedContext = null; // Variable to hold context from right-click selection
+    const sentRequestIds = new Set(); // Requests sent from this tab, whose shared turns it already shows
     
     // Set initial width from saved value
     chatPanel.style.width = savedPanelWidth;
@@ -267,8 +268,9 @@ function initChatPanel() {
         }
     }
     
-    // Process LLM response - attempt to parse JSON if present
-    function processLLMResponse(responseText) {
+    // Process LLM response - attempt to parse JSON if present. Commands are only
+    // auto-executed when autoExecute is set, never for plain envelope responses.
+    function processLLMResponse(responseText, autoExecute = true) {
         // Try to parse response as JSON
         try {
             // Ensure we're working with a string
@@ -287,7 +289,7 @@ function initChatPanel() {
                     console.log('Successfully parsed LLM response as JSON:', jsonData);
                     
                     // Execute GDB commands if present
-                    if (jsonData.gdbCommands && Array.isArray(jsonData.gdbCommands) && jsonData.gdbCommands.length > 0) {
+                    if (autoExecute && jsonData.gdbCommands && Array.isArray(jsonData.gdbCommands) && jsonData.gdbCommands.length > 0) {
                         console.log('JSON contains GDB commands:', jsonData.gdbCommands);
                         // Process commands if possible here or pass them to terminal
                         if (window.AppTerminal && typeof window.AppTerminal.sendCommand === 'function') {
@@ -317,6 +319,29 @@ function initChatPanel() {
         };
     }
 
+    // The model, temperature and response size chosen in the chat panel for this request,
+    // where unset fields fall back to the settings, and the terminal output to attach
+    function requestOptions() {
+        const overrides = {};
+        const model = document.getElementById('chatModelSelect').value;
+        const temperature = parseFloat(document.getElementById('chatTemperatureInput').value);
+        const maxTokens = parseInt(document.getElementById('chatMaxTokensInput').value, 10);
+        const terminalLines = parseInt(document.getElementById('chatTerminalLinesInput').value, 10);
+        if (model) {
+            overrides.model = model;
+        }
+        if (!isNaN(temperature)) {
+            overrides.temperature = temperature;
+        }
+        if (maxTokens > 0) {
+            overrides.maxTokens = maxTokens;
+        }
+        if (terminalLines > 0) {
+            overrides.terminalLines = terminalLines;
+        }
+        return overrides;
+    }
+
     // Send chat message
     async function sendMessage() {
         const userQuery = chatInput.value.trim();
@@ -338,7 +363,10 @@ function initChatPanel() {
         addMessageToUI(userMessage.role, userMessage.content, userMessage.sentContext);
         clearStagedContext(); // Clear context after sending
 
-        addThinkingMessage();
+        // The request ID lets the Cancel button stop this request on the server
+        const requestId = `chat-${Date.now()}-${Math.random().toString(36).slice(2, 10)}`;
+        sentRequestIds.add(requestId);
+        addThinkingMessage(requestId);
 
         // Prepare history, excluding the just-added user message's context for the API call
         const historyForAPI = chatHistory.map(msg => ({
@@ -357,12 +385,21 @@ function initChatPanel() {
                     history: historyForAPI,
                     // Include the sentContext for the current message if it exists
                     // Backend needs to be updated to handle this field.
-                    sentContext: userMessage.sentContext && userMessage.sentContext.length > 0 ? userMessage.sentContext : undefined
+                    sentContext: userMessage.sentContext && userMessage.sentContext.length > 0 ? userMessage.sentContext : undefined,
+                    requestId: requestId,
+                    ...requestOptions()
                 }),
             });
 
             document.getElementById('thinkingMessage')?.remove();
 
+            if (response.status === 429) {
+                // Too many requests are already waiting for this session
+                const retryAfter = response.headers.get('Retry-After') || 'a few';
+                addMessageToUI('error', `The assistant is busy with earlier requests for this session. Try again in ${retryAfter} seconds.`);
+                return;
+            }
+
             if (!response.ok) {
                 const errorText = await response.text();
                 throw new Error(`HTTP error! status: ${response.status}, ${errorText}`);
@@ -371,13 +408,19 @@ function initChatPanel() {
             const data = await response.json();
             console.log('Raw LLM response:', data.response);
 
+            if (data.cancelled && !data.response) {
+                addMessageToUI('error', 'Request cancelled');
+                return;
+            }
+
             // Process the response to extract text from JSON if needed
-            const processedResult = processLLMResponse(data.response);
+            const processedResult = processLLMResponse(data.response, data.envelope !== 'plain');
             console.log('Processed LLM response:', processedResult.processedContent);
 
             // Check if the response was cut off (ends without proper punctuation or sentence completion)
             let responseContent = processedResult.processedContent;
             if (typeof responseContent === 'string' && 
+                !data.nextPage &&
                 responseContent.length > 0 && 
                 !responseContent.endsWith('.') && 
                 !responseContent.endsWith('!') && 
@@ -394,7 +437,10 @@ function initChatPanel() {
                 role: 'assistant',
                 content: data.response, // Store original response (with JSON) in history
                 processedContent: responseContent, // Store the processed content (with cut-off indicator if needed)
-                originalJson: processedResult.originalJson // Store the parsed JSON if available
+                originalJson: processedResult.originalJson, // Store the parsed JSON if available
+                suggestedCommands: data.suggestedCommands || [], // Plain envelope mode: run only when clicked
+                nextPage: data.nextPage || null, // Long responses arrive a page at a time
+                totalSize: data.totalSize || 0
             };
             
             // Display the processed text to the user
@@ -444,6 +490,14 @@ function initChatPanel() {
         textElement.innerHTML = escapedContent;
         messageElement.appendChild(textElement);
 
+        if (typeof content === 'object' && content !== null && content.suggestedCommands && content.suggestedCommands.length > 0) {
+            messageElement.appendChild(createSuggestedCommands(content.suggestedCommands));
+        }
+
+        if (typeof content === 'object' && content !== null && content.nextPage) {
+            messageElement.appendChild(createShowMoreButton(textElement, content.nextPage, content.totalSize));
+        }
+
         // --- Context Display Logic ---
         if (role === 'user' && sentContext && sentContext.length > 0) {
             const toggle = document.createElement('span');
@@ -484,11 +538,99 @@ function initChatPanel() {
         chatMessages.scrollTop = chatMessages.scrollHeight;
     }
     
-    // Add thinking message
-    function addThinkingMessage() {
+    // Render suggested GDB commands with a button to run each one in the terminal
+    function createSuggestedCommands(commands) {
+        const container = document.createElement('div');
+        container.classList.add('suggested-commands');
+
+        const title = document.createElement('div');
+        title.classList.add('suggested-commands-title');
+        title.textContent = 'Suggested commands (not run automatically):';
+        container.appendChild(title);
+
+        commands.forEach(cmd => {
+            const row = document.createElement('div');
+            row.classList.add('suggested-command');
+
+            const code = document.createElement('code');
+            code.textContent = cmd;
+            row.appendChild(code);
+
+            const runButton = document.createElement('button');
+            runButton.textContent = 'Run';
+            runButton.title = 'Send this command to GDB';
+            runButton.addEventListener('click', () => {
+                if (window.AppTerminal && typeof window.AppTerminal.sendCommand === 'function') {
+                    window.AppTerminal.sendCommand(cmd);
+                    runButton.disabled = true;
+                }
+            });
+            row.appendChild(runButton);
+
+            container.appendChild(row);
+        });
+
+        return container;
+    }
+
+    // Render a button that appends the next page of a long response to textElement
+    function createShowMoreButton(textElement, nextPage, totalSize) {
+        const button = document.createElement('button');
+        button.classList.add('show-more');
+        let shown = textElement.textContent.length;
+
+        const updateLabel = () => {
+            button.textContent = totalSize > 0
+                ? `Show more (${Math.round(shown / 1024)} of ${Math.round(totalSize / 1024)} KB shown)`
+                : 'Show more';
+        };
+        updateLabel();
+
+        button.addEventListener('click', async () => {
+            button.disabled = true;
+            try {
+                const response = await fetch(`/api/chat/pages/${encodeURIComponent(nextPage)}`);
+                if (!response.ok) {
+                    throw new Error(await response.text());
+                }
+                const page = await response.json();
+                textElement.appendChild(document.createTextNode(page.text));
+                shown = page.offset + page.text.length;
+                if (page.nextPage) {
+                    nextPage = page.nextPage;
+                    updateLabel();
+                    button.disabled = false;
+                } else {
+                    button.remove();
+                }
+            } catch (error) {
+                console.error('Error loading response page:', error);
+                button.textContent = 'Could not load more; click to retry';
+                button.disabled = false;
+            }
+        });
+
+        return button;
+    }
+
+    // Add thinking message, with a button cancelling the request with the given ID
+    function addThinkingMessage(requestId) {
         const messageDiv = document.createElement('div');
+        messageDiv.id = 'thinkingMessage';
         messageDiv.className = 'message assistant thinking';
         messageDiv.textContent = 'Thinking...';
+
+        if (requestId) {
+            const cancelButton = document.createElement('button');
+            cancelButton.className = 'cancel-request';
+            cancelButton.textContent = 'Cancel';
+            cancelButton.addEventListener('click', () => {
+                cancelButton.disabled = true;
+                cancelButton.textContent = 'Cancelling...';
+                cancelRequest(requestId);
+            });
+            messageDiv.appendChild(cancelButton);
+        }
         
         // Add to messages
         chatMessages.appendChild(messageDiv);
@@ -499,6 +641,21 @@ function initChatPanel() {
         return messageDiv;
     }
     
+    // Cancel a running chat request; its pending fetch then returns a cancelled response
+    async function cancelRequest(requestId) {
+        try {
+            await fetch('/api/chat/cancel', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ requestId: requestId }),
+            });
+        } catch (error) {
+            console.error('Error cancelling request:', error);
+        }
+    }
+
     // Handle send button click
     sendChatBtn.addEventListener('click', sendMessage);
     
@@ -510,6 +667,31 @@ function initChatPanel() {
         }
     });
     
+    // Show the chat turns of the session's other members, and of this user in other tabs,
+    // and keep them in the history so the assistant sees the whole conversation
+    document.addEventListener('chat-shared', (event) => {
+        const turn = event.detail;
+        if (sentRequestIds.delete(turn.requestId)) {
+            return;
+        }
+        const question = turn.user ? `${turn.user}: ${turn.message}` : turn.message;
+        addMessageToUI('user', question);
+        addMessageToUI('assistant', turn.response);
+        chatHistory.push({ role: 'user', content: question });
+        chatHistory.push({ role: 'assistant', content: turn.response });
+    });
+
+    // Note in the chat when GDB exits on its own, since earlier answers about the program's
+    // state no longer hold
+    document.addEventListener('gdb-status', (event) => {
+        const status = event.detail;
+        if (status.gdb === 'restarted') {
+            addMessageToUI('error', `GDB exited (${status.reason}) and was restarted with ${status.breakpoints || 0} breakpoints. Run the program again to continue.`);
+        } else {
+            addMessageToUI('error', `GDB exited (${status.reason}) and was not restarted. Start a new session to continue.`);
+        }
+    });
+
     // Initial welcome message
     setTimeout(() => {
         addMessageToUI(
@@ -0,0 +1,28 @@
+/**
+ * csrf.js - Sends the CSRF token the server gives the UI in a cookie (server.csrf) with
+ * the UI's state-changing requests, which the server refuses without it. Load it after
+ * base_path.js and before the other scripts.
+ */
+
+const CSRF_COOKIE_NAME = 'gogdbllm_csrf';
+const CSRF_HEADER = 'X-CSRF-Token';
+
+// Return the token of the CSRF cookie, or '' before the server has set one
+function csrfToken() {
+    const match = document.cookie.match(new RegExp('(?:^|;\\s*)' + CSRF_COOKIE_NAME + '=([^;]*)'));
+    return match ? decodeURIComponent(match[1]) : '';
+}
+
+{
+    const fetchWithoutCSRF = window.fetch.bind(window);
+    window.fetch = (resource, options = {}) => {
+        const method = (options.method || (resource instanceof Request ? resource.method : 'GET')).toUpperCase();
+        const token = csrfToken();
+        if (['GET', 'HEAD', 'OPTIONS'].includes(method) || !token) {
+            return fetchWithoutCSRF(resource, options);
+        }
+        const headers = new Headers(options.headers || (resource instanceof Request ? resource.headers : undefined));
+        headers.set(CSRF_HEADER, token);
+        return fetchWithoutCSRF(resource, { ...options, headers });
+    };
+}
@@ -0,0 +1,118 @@
+/**
+ * memory.js - Register view and hex viewer for the debugged program's memory
+ */
+
+// Initialize the memory section
+function initMemorySection() {
+    const readBtn = document.getElementById('memoryReadBtn');
+    const writeBtn = document.getElementById('memoryWriteBtn');
+    const addressInput = document.getElementById('memoryAddressInput');
+    if (!readBtn || !writeBtn || !addressInput) {
+        return;
+    }
+    
+    const registersBtn = document.getElementById('registersReadBtn');
+    if (registersBtn) {
+        registersBtn.addEventListener('click', readRegisters);
+    }
+    readBtn.addEventListener('click', readMemory);
+    writeBtn.addEventListener('click', writeMemory);
+    addressInput.addEventListener('keydown', event => {
+        if (event.key === 'Enter') {
+            readMemory();
+        }
+    });
+    
+    console.log('Memory section initialized');
+}
+
+// Read the registers and highlight those that changed since the last stop
+async function readRegisters() {
+    const all = document.getElementById('registersAllInput').checked;
+    const table = document.getElementById('registersTable');
+    
+    try {
+        const response = await fetch(`/api/v1/debugger/registers?all=${all}`);
+        const result = await response.json();
+        if (!result.success) {
+            throw new Error(result.error || 'Failed to read registers');
+        }
+        
+        table.innerHTML = '';
+        result.data.registers.forEach(register => {
+            const row = table.insertRow();
+            if (register.changed) {
+                row.classList.add('changed');
+                row.title = `Was ${register.previous} at the last stop`;
+            }
+            row.insertCell().textContent = register.name;
+            row.insertCell().textContent = register.value;
+            row.insertCell().textContent = register.natural || '';
+        });
+        const changed = result.data.registers.filter(register => register.changed).length;
+        setMemoryStatus(`${changed} registers changed since the last stop`, 'success');
+    } catch (error) {
+        console.error('Error reading registers:', error);
+        setMemoryStatus(error.message, 'error');
+    }
+}
+
+// Read memory at the address and show it as a hexdump
+async function readMemory() {
+    const address = document.getElementById('memoryAddressInput').value.trim();
+    const length = document.getElementById('memoryLengthInput').value;
+    const dump = document.getElementById('memoryDump');
+    
+    try {
+        const params = new URLSearchParams({ addr: address, len: length });
+        const response = await fetch(`/api/v1/debugger/memory?${params}`);
+        const result = await response.json();
+        if (!result.success) {
+            throw new Error(result.error || 'Failed to read memory');
+        }
+        
+        dump.textContent = result.data.hexdump.join('\n');
+        setMemoryStatus(`Read ${result.data.length} bytes at ${result.data.address}`, 'success');
+    } catch (error) {
+        console.error('Error reading memory:', error);
+        setMemoryStatus(error.message, 'error');
+    }
+}
+
+// Write the hex bytes at the address, then show the memory again
+async function writeMemory() {
+    const address = document.getElementById('memoryAddressInput').value.trim();
+    const hex = document.getElementById('memoryWriteInput').value.trim();
+    
+    try {
+        const response = await fetch('/api/v1/debugger/memory', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ addr: address, hex })
+        });
+        const result = await response.json();
+        if (!result.success) {
+            throw new Error(result.error || 'Failed to write memory');
+        }
+        
+        await readMemory();
+        setMemoryStatus(`Wrote ${result.data.length} bytes at ${result.data.address}`, 'success');
+    } catch (error) {
+        console.error('Error writing memory:', error);
+        setMemoryStatus(error.message, 'error');
+    }
+}
+
+// Show the outcome of the last read or write
+function setMemoryStatus(message, type) {
+    const status = document.getElementById('memoryStatus');
+    status.textContent = message;
+    status.className = `status-message ${type}`;
+}
+
+// Make available globally
+window.AppMemory = {
+    initMemorySection
+};
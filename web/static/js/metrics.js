@@ -0,0 +1,131 @@
+/**
+ * metrics.js - Dashboard charting the chat metrics history per provider
+ */
+
+// Colors of the providers' lines, in the order they are first seen
+const METRICS_COLORS = ['#1a73e8', '#34a853', '#fbbc04', '#ea4335', '#a142f4', '#24c1e0'];
+
+// The charts and the bucket value each plots
+const METRICS_CHARTS = [
+    { id: 'latencyChart', value: bucket => bucket.avg_response_time },
+    { id: 'errorChart', value: bucket => bucket.error_rate * 100 },
+    { id: 'costChart', value: bucket => bucket.cost },
+    { id: 'requestChart', value: bucket => bucket.requests },
+];
+
+// Initialize the dashboard
+function initMetricsDashboard() {
+    const range = document.getElementById('metricsRange');
+    const refreshBtn = document.getElementById('metricsRefreshBtn');
+    if (!range || !refreshBtn) {
+        return;
+    }
+
+    range.addEventListener('change', loadMetricsHistory);
+    refreshBtn.addEventListener('click', loadMetricsHistory);
+    loadMetricsHistory();
+}
+
+// Fetch the history of the selected range and redraw the charts
+async function loadMetricsHistory() {
+    const range = document.getElementById('metricsRange');
+    const option = range.options[range.selectedIndex];
+    const status = document.getElementById('metricsStatus');
+    const to = new Date();
+    const from = new Date(to.getTime() - parseHours(option.value) * 3600 * 1000);
+
+    const params = new URLSearchParams({
+        from: from.toISOString().replace(/\.\d+Z$/, 'Z'),
+        to: to.toISOString().replace(/\.\d+Z$/, 'Z'),
+        step: option.dataset.step,
+    });
+
+    try {
+        const response = await fetch(`/api/chat/metrics/history?${params}`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const result = await response.json();
+        const series = result.series || {};
+        drawMetricsLegend(series);
+        METRICS_CHARTS.forEach(chart => drawMetricsChart(chart, series, from, to));
+        status.textContent = Object.keys(series).length === 0 ? 'No requests in this range' : '';
+        status.className = 'status-message';
+    } catch (error) {
+        status.textContent = error.message;
+        status.className = 'status-message error';
+    }
+}
+
+// Parse a range like "24h" into hours
+function parseHours(value) {
+    return parseInt(value, 10);
+}
+
+// List the providers with the color of their lines
+function drawMetricsLegend(series) {
+    const legend = document.getElementById('metricsLegend');
+    legend.innerHTML = '';
+    Object.keys(series).sort().forEach((provider, i) => {
+        const item = document.createElement('span');
+        item.className = 'metrics-legend-item';
+        item.style.color = METRICS_COLORS[i % METRICS_COLORS.length];
+        item.textContent = provider;
+        legend.appendChild(item);
+    });
+}
+
+// Draw a line per provider of one value of the buckets over the range
+function drawMetricsChart(chart, series, from, to) {
+    const svg = document.getElementById(chart.id);
+    const width = 600, height = 200, padding = 20;
+    svg.innerHTML = '';
+
+    let max = 0;
+    Object.values(series).forEach(buckets => {
+        buckets.forEach(bucket => { max = Math.max(max, chart.value(bucket)); });
+    });
+    if (max === 0) {
+        max = 1;
+    }
+
+    const x = time => padding + (width - 2 * padding) * (new Date(time) - from) / (to - from);
+    const y = value => height - padding - (height - 2 * padding) * value / max;
+
+    const axis = document.createElementNS('http://www.w3.org/2000/svg', 'line');
+    axis.setAttribute('x1', padding);
+    axis.setAttribute('x2', width - padding);
+    axis.setAttribute('y1', height - padding);
+    axis.setAttribute('y2', height - padding);
+    axis.setAttribute('class', 'metrics-axis');
+    svg.appendChild(axis);
+
+    const label = document.createElementNS('http://www.w3.org/2000/svg', 'text');
+    label.setAttribute('x', padding);
+    label.setAttribute('y', padding - 6);
+    label.setAttribute('class', 'metrics-label');
+    label.textContent = `max ${formatMetric(max)}`;
+    svg.appendChild(label);
+
+    Object.keys(series).sort().forEach((provider, i) => {
+        const points = series[provider]
+            .map(bucket => `${x(bucket.time).toFixed(1)},${y(chart.value(bucket)).toFixed(1)}`)
+            .join(' ');
+        const line = document.createElementNS('http://www.w3.org/2000/svg', 'polyline');
+        line.setAttribute('points', points);
+        line.setAttribute('fill', 'none');
+        line.setAttribute('stroke', METRICS_COLORS[i % METRICS_COLORS.length]);
+        line.setAttribute('stroke-width', '2');
+        svg.appendChild(line);
+    });
+}
+
+// Format a chart's maximum compactly
+function formatMetric(value) {
+    if (value >= 100) {
+        return value.toFixed(0);
+    }
+    return value.toPrecision(3);
+}
+
+document.addEventListener('DOMContentLoaded', initMetricsDashboard);
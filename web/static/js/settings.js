@@ -6,6 +6,7 @@
 function initSettingsSection() {
     const providerSelect = document.getElementById('providerSelect');
     const modelSelect = document.getElementById('modelSelect');
+    const profileSelect = document.getElementById('profileSelect');
     const apiKeyInput = document.getElementById('apiKeyInput');
     const testConnectionBtn = document.getElementById('testConnectionBtn');
     const saveSettingsBtn = document.getElementById('saveSettingsBtn');
@@ -52,12 +53,12 @@ function initSettingsSection() {
     };
     
     // Update model select options based on provider
-    function updateModelOptions(provider) {
+    function updateModelOptions(provider, models = MODEL_OPTIONS[provider] || []) {
         // Clear existing options
         modelSelect.innerHTML = '';
         
         // Add new options
-        MODEL_OPTIONS[provider].forEach(model => {
+        models.forEach(model => {
             const option = document.createElement('option');
             option.value = model.id;
             option.textContent = model.name;
@@ -65,6 +66,63 @@ function initSettingsSection() {
         });
     }
     
+    // Replace the model options with the provider's current models, fetched by the server
+    // from the provider's API; the built-in list stays when the provider cannot be asked
+    async function loadProviderModels(provider) {
+        try {
+            const response = await fetch(`/api/v1/providers/${encodeURIComponent(provider)}/models`);
+            if (!response.ok) {
+                return;
+            }
+            
+            const list = await response.json();
+            if (providerSelect.value !== provider || !list.models || list.models.length === 0) {
+                return;
+            }
+            
+            const selected = modelSelect.value;
+            updateModelOptions(provider, list.models.map(model => ({ id: model.id, name: model.name || model.id })));
+            if (Array.from(modelSelect.options).some(option => option.value === selected)) {
+                modelSelect.value = selected;
+            }
+        } catch (error) {
+            console.warn(`Using the built-in model list for ${provider}:`, error);
+        }
+    }
+    
+    // Fill the profile select with the profiles the server offers
+    function updateProfileOptions(profiles) {
+        profileSelect.innerHTML = '';
+        const defaultOption = document.createElement('option');
+        defaultOption.value = '';
+        defaultOption.textContent = 'Default';
+        profileSelect.appendChild(defaultOption);
+        
+        (profiles || []).forEach(profile => {
+            const option = document.createElement('option');
+            option.value = profile.name;
+            option.textContent = profile.name;
+            option.title = profile.description;
+            profileSelect.appendChild(option);
+        });
+    }
+    
+    // Describe where the server's API key comes from
+    function apiKeyPlaceholder(settings) {
+        switch (settings.hasApiKey && settings.apiKeySource) {
+            case 'env':
+                return 'API key configured via environment variable';
+            case 'keychain':
+                return 'API key configured via OS keychain';
+            case 'config_file':
+                return 'API key configured in config file';
+            case 'settings_api':
+                return 'API key saved (leave blank to keep it)';
+            default:
+                return 'Enter your API key';
+        }
+    }
+    
     // Load settings from server
     async function loadSettings() {
         try {
@@ -79,12 +137,19 @@ function initSettingsSection() {
             currentSettings = {
                 provider: settings.provider || 'anthropic',
                 model: settings.model || '',
-                apiKey: settings.apiKey || ''
+                profile: settings.profile || '',
+                apiKey: ''
             };
             
-            // Update UI
+            updateProfileOptions(settings.profiles);
+            profileSelect.value = currentSettings.profile;
+            
+            // Update UI; the server never returns the stored key
+            apiKeyInput.value = '';
+            apiKeyInput.placeholder = apiKeyPlaceholder(settings);
             providerSelect.value = currentSettings.provider;
             updateModelOptions(currentSettings.provider);
+            await loadProviderModels(currentSettings.provider);
             
             // Set model value if it exists in options
             if (currentSettings.model) {
@@ -118,6 +183,7 @@ function initSettingsSection() {
             const settings = {
                 provider: providerSelect.value,
                 model: modelSelect.value,
+                profile: profileSelect.value,
                 apiKey: apiKeyInput.value.trim()
             };
             
@@ -125,6 +191,7 @@ function initSettingsSection() {
             const dataToSend = {
                 provider: settings.provider,
                 model: settings.model,
+                profile: settings.profile,
                 apiKey: settings.apiKey === '' ? undefined : settings.apiKey
             };
             
@@ -136,17 +203,23 @@ function initSettingsSection() {
                 body: JSON.stringify(dataToSend)
             });
             
+            const result = await response.json().catch(() => ({}));
+            
             if (!response.ok) {
-                throw new Error(`Failed to save settings: ${response.statusText}`);
+                // Invalid settings come back with the fields that failed
+                const fields = (result.data && result.data.fields) || [];
+                if (fields.length > 0) {
+                    throw new Error(fields.map(f => `${f.field} ${f.message}`).join('; '));
+                }
+                throw new Error(result.error || `Failed to save settings: ${response.statusText}`);
             }
             
-            const result = await response.json();
-            
             if (result.success) {
                 // Update current settings
                 currentSettings = {
                     provider: settings.provider,
                     model: settings.model,
+                    profile: settings.profile,
                     apiKey: settings.apiKey
                 };
                 
@@ -220,6 +293,20 @@ function initSettingsSection() {
         // Update UI
         const providerName = currentSettings.provider.charAt(0).toUpperCase() + currentSettings.provider.slice(1);
         currentModelElement.textContent = `${providerName}: ${model.name}`;
+        
+        // The chat panel may pick another of the provider's models for its requests
+        const chatModelSelect = document.getElementById('chatModelSelect');
+        const selected = chatModelSelect.value;
+        chatModelSelect.length = 1;
+        modelOptions.filter(m => m.id !== currentSettings.model).forEach(m => {
+            const option = document.createElement('option');
+            option.value = m.id;
+            option.textContent = m.name;
+            chatModelSelect.appendChild(option);
+        });
+        if (Array.from(chatModelSelect.options).some(option => option.value === selected)) {
+            chatModelSelect.value = selected;
+        }
     }
     
     // Set up event listeners
@@ -229,6 +316,7 @@ function initSettingsSection() {
         
         // Use the first model as the default
         modelSelect.selectedIndex = 0;
+        loadProviderModels(provider);
     });
     
     testConnectionBtn.addEventListener('click', testConnection);
@@ -237,6 +325,22 @@ function initSettingsSection() {
     // Initialize UI
     updateModelOptions(currentSettings.provider);
     
+    // Describe where the server's API key comes from
+    function apiKeyPlaceholder(settings) {
+        switch (settings.hasApiKey && settings.apiKeySource) {
+            case 'env':
+                return 'API key configured via environment variable';
+            case 'keychain':
+                return 'API key configured via OS keychain';
+            case 'config_file':
+                return 'API key configured in config file';
+            case 'settings_api':
+                return 'API key saved (leave blank to keep it)';
+            default:
+                return 'Enter your API key';
+        }
+    }
+    
     // Load settings from server
     loadSettings();
     
@@ -7,6 +7,8 @@ function initTerminalSection() {
     const terminal = document.getElementById('terminal');
     const commandInput = document.getElementById('commandInput');
     const commandPrompt = document.getElementById('commandPrompt');
+    const programInputBtn = document.getElementById('programInputBtn');
+    const rawKeysBtn = document.getElementById('rawKeysBtn');
     const terminalOutput = document.getElementById('terminalOutput');
     
     let socket = null;
@@ -14,6 +16,10 @@ function initTerminalSection() {
     let historyIndex = -1;
     let terminalConnected = false;
     
+    // Where typed input goes: 'gdb' sends commands to GDB; 'line' sends typed lines and
+    // control keys to the debugged program's terminal; 'raw' sends every key as it is pressed
+    let inputMode = 'gdb';
+    
     // Define a maximum buffer size to prevent memory issues (roughly 50KB)
     const MAX_BUFFER_SIZE = 50000;
     
@@ -43,6 +49,24 @@ function initTerminalSection() {
         }
     };
     
+    // WebSocket protocol version 2 wraps every message in {v, type, id, payload}. The
+    // server falls back to version 1 (raw output text) if it does not support it.
+    const PROTOCOL_V2 = 'gogdbllm.v2';
+    let nextMessageId = 0;
+    let lastHeartbeat = 0;
+    
+    // Close code the server uses when it disconnects a client that cannot keep up
+    const CLOSE_SLOW_CLIENT = 4008;
+    // Close code for a client watching through a share link that expired or was revoked
+    const CLOSE_SHARE_ENDED = 4010;
+    
+    // Token of the debugging session whose output this terminal receives
+    let sessionToken = sessionStorage.getItem('gdbSessionToken');
+    
+    // Token of a read-only share link this page was opened from (/?watch=<token>); the
+    // terminal then only shows the shared session
+    let shareToken = new URLSearchParams(window.location.search).get('watch');
+    
     // Special control characters
     const CTRL_C = '\x03';  // Control-C character
     const CTRL_D = '\x04';  // Control-D character
@@ -59,15 +83,25 @@ function initTerminalSection() {
         
         // Create WebSocket connection
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/ws`;
+        let wsUrl = `${protocol}//${window.location.host}${appPath('/ws')}`;
+        if (shareToken) {
+            wsUrl += `?share=${encodeURIComponent(shareToken)}`;
+        } else if (sessionToken) {
+            wsUrl += `?session=${encodeURIComponent(sessionToken)}`;
+        }
         
-        socket = new WebSocket(wsUrl);
+        const ws = new WebSocket(wsUrl, [PROTOCOL_V2]);
+        socket = ws;
+        let opened = false;
         
         // Connection opened
         socket.addEventListener('open', (event) => {
             terminalConnected = true;
+            opened = true;
+            lastHeartbeat = Date.now();
             appendToTerminal('Terminal connected');
             console.log('WebSocket connection established');
+            reportTerminalSize();
         });
         
         // Listen for messages from server
@@ -83,6 +117,11 @@ function initTerminalSection() {
                     appendToTerminal("[Error reading binary data]");
                 };
                 reader.readAsText(event.data);
+            } else if (socket.protocol === PROTOCOL_V2) {
+                handleEnvelope(event.data);
+            } else if (isErrorReply(event.data)) {
+                const reply = JSON.parse(event.data);
+                appendToTerminal(`\x1b[31m[${reply.code}] ${reply.error}\x1b[0m`);
             } else {
                 appendToTerminal(event.data); // Process string data directly
             }
@@ -90,9 +129,31 @@ function initTerminalSection() {
         
         // Connection closed
         socket.addEventListener('close', (event) => {
+            // Ignore a connection that was replaced by a newer one
+            if (ws !== socket) {
+                return;
+            }
             terminalConnected = false;
-            appendToTerminal('\nTerminal disconnected');
-            console.log('WebSocket connection closed');
+            
+            // A rejected handshake means the session token is no longer valid (e.g. the
+            // session was replaced); reconnect without it
+            if (!opened && sessionToken) {
+                console.warn('Session token rejected, reconnecting without it');
+                sessionToken = null;
+                sessionStorage.removeItem('gdbSessionToken');
+            }
+            if (shareToken && (event.code === CLOSE_SHARE_ENDED || !opened)) {
+                // Watching ends with the share link; there is nothing to reconnect to
+                appendToTerminal('\n\x1b[33mThe share link expired or was revoked\x1b[0m');
+                return;
+            }
+            if (event.code === CLOSE_SLOW_CLIENT) {
+                // The server gave up waiting for this tab to read its output
+                appendToTerminal('\n\x1b[33mTerminal disconnected: output arrived faster than this page could display it; some output was lost\x1b[0m');
+            } else {
+                appendToTerminal('\nTerminal disconnected');
+            }
+            console.log('WebSocket connection closed', event.code, event.reason);
             
             // Try to reconnect after 3 seconds
             setTimeout(() => {
@@ -109,6 +170,79 @@ function initTerminalSection() {
         });
     }
     
+    // Dispatch a protocol version 2 message by type
+    function handleEnvelope(data) {
+        let envelope;
+        try {
+            envelope = JSON.parse(data);
+        } catch (e) {
+            console.error('Invalid message from server:', data);
+            return;
+        }
+        const payload = envelope.payload || {};
+        switch (envelope.type) {
+            case 'gdb_output':
+                appendToTerminal(payload.text);
+                break;
+            case 'error':
+                appendToTerminal(`\x1b[31m[${payload.code}] ${payload.error}\x1b[0m`);
+                break;
+            case 'status':
+                if (payload.gdb === 'exited') {
+                    appendToTerminal(`\nGDB exited (${payload.file})`);
+                }
+                if (payload.gdb === 'restarted' || (payload.gdb === 'exited' && payload.reason)) {
+                    document.dispatchEvent(new CustomEvent('gdb-status', { detail: payload }));
+                }
+                break;
+            case 'heartbeat':
+                lastHeartbeat = Date.now();
+                break;
+            case 'completions':
+                showCompletions(payload);
+                break;
+            case 'chat_stream':
+                document.dispatchEvent(new CustomEvent('chat-stream', { detail: payload }));
+                break;
+            case 'chat':
+                // A chat turn in the session, maybe of another member
+                document.dispatchEvent(new CustomEvent('chat-shared', { detail: payload }));
+                break;
+            case 'presence':
+                showPresence(payload);
+                break;
+            default:
+                console.warn('Unknown message type from server:', envelope.type);
+        }
+    }
+    
+    // Show who is connected to the session
+    function showPresence(presence) {
+        const element = document.getElementById('sessionPresence');
+        if (!element) {
+            return;
+        }
+        const users = presence.users || [];
+        if (users.length > 0) {
+            element.textContent = `Connected: ${users.join(', ')}`;
+        } else {
+            element.textContent = `${presence.clients} ${presence.clients === 1 ? 'tab' : 'tabs'} connected`;
+        }
+    }
+    
+    // Check whether a message is a server error reply rather than GDB output
+    function isErrorReply(data) {
+        if (!data.startsWith('{"type":"error"')) {
+            return false;
+        }
+        try {
+            const reply = JSON.parse(data);
+            return reply.type === 'error' && typeof reply.error === 'string';
+        } catch (e) {
+            return false;
+        }
+    }
+    
     // Append text to terminal and terminal output in chat panel
     function appendToTerminal(text) {
         // Convert the entire chunk's ANSI codes to HTML (includes <br> for newlines)
@@ -136,6 +270,81 @@ function initTerminalSection() {
         }
     }
     
+    // Subscribe to a debugging session's output by reconnecting with its token. Resolves
+    // once the new connection is open.
+    function connectToSession(token) {
+        sessionToken = token;
+        sessionStorage.setItem('gdbSessionToken', token);
+        connectWebSocket();
+        return new Promise((resolve) => {
+            const current = socket;
+            current.addEventListener('open', () => resolve(true), { once: true });
+            current.addEventListener('close', () => resolve(false), { once: true });
+        });
+    }
+    
+    // Join another user's debugging session with the token from their invite link, sharing
+    // its terminal and chat
+    async function joinSession(token) {
+        try {
+            const response = await fetch('/api/sessions/join', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ token: token }),
+            });
+            const result = await response.json();
+            if (!response.ok || !result.success) {
+                throw new Error(result.error || `HTTP ${response.status}`);
+            }
+            appendToTerminal(`Joined session ${result.data.session}`);
+            return connectToSession(token);
+        } catch (error) {
+            appendToTerminal(`\x1b[31mCould not join the session: ${error.message}\x1b[0m`);
+            connectWebSocket();
+            return false;
+        }
+    }
+    
+    // Copy a link inviting others to join the session
+    async function copyInviteLink() {
+        if (!sessionToken) {
+            appendToTerminal('Start a session before inviting others to it');
+            return;
+        }
+        const link = `${window.location.origin}${appPath('/')}?join=${encodeURIComponent(sessionToken)}`;
+        try {
+            await navigator.clipboard.writeText(link);
+            appendToTerminal('Invite link copied; anyone signed in who opens it joins this session');
+        } catch (error) {
+            appendToTerminal(`Invite link: ${link}`);
+        }
+    }
+    
+    // Copy a link letting others watch the session read-only until it expires
+    async function copyShareLink() {
+        try {
+            const response = await fetch('/api/sessions/share', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({}),
+            });
+            const result = await response.json();
+            if (!response.ok || !result.success) {
+                throw new Error(result.error || `HTTP ${response.status}`);
+            }
+            const link = `${window.location.origin}${appPath(result.data.url)}`;
+            const expires = new Date(result.data.expires).toLocaleString();
+            try {
+                await navigator.clipboard.writeText(link);
+                appendToTerminal(`Read-only link copied; it works until ${expires}`);
+            } catch (error) {
+                appendToTerminal(`Read-only link, until ${expires}: ${link}`);
+            }
+        } catch (error) {
+            appendToTerminal(`\x1b[31mCould not share the session: ${error.message}\x1b[0m`);
+        }
+    }
+    
     // Send command to server
     function sendCommand(command) {
         if (!terminalConnected) {
@@ -157,18 +366,174 @@ function initTerminalSection() {
         
         // Send command to server
         try {
-            socket.send(JSON.stringify({
-                type: 'command',
-                command: command
-            }));
+            if (socket.protocol === PROTOCOL_V2) {
+                socket.send(JSON.stringify({
+                    v: 2,
+                    type: 'command',
+                    id: `c${++nextMessageId}`,
+                    payload: { command: command }
+                }));
+            } else {
+                socket.send(JSON.stringify({
+                    type: 'command',
+                    command: command
+                }));
+            }
         } catch (error) {
             console.error('Error sending command:', error);
             appendToTerminal(`Error sending command: ${error.message}`);
         }
     }
     
+    // Send a protocol version 2 message; returns false on a version 1 connection
+    function sendEnvelope(type, payload) {
+        if (!terminalConnected || socket.protocol !== PROTOCOL_V2) {
+            return false;
+        }
+        socket.send(JSON.stringify({
+            v: 2,
+            type: type,
+            id: `${type[0]}${++nextMessageId}`,
+            payload: payload
+        }));
+        return true;
+    }
+    
+    // Send input to the debugged program's terminal (protocol version 2 only)
+    function sendProgramInput(data) {
+        if (!sendEnvelope('input', { data: data })) {
+            appendToTerminal('\x1b[31mProgram input needs a protocol version 2 connection\x1b[0m');
+        }
+    }
+    
+    // Report the terminal's size in characters, so the program's terminal matches it
+    function reportTerminalSize() {
+        const probe = document.createElement('span');
+        probe.textContent = 'M'.repeat(10);
+        probe.style.visibility = 'hidden';
+        probe.style.position = 'absolute';
+        terminal.appendChild(probe);
+        const charWidth = probe.getBoundingClientRect().width / 10;
+        const lineHeight = probe.getBoundingClientRect().height;
+        probe.remove();
+        if (charWidth <= 0 || lineHeight <= 0) {
+            return;
+        }
+        sendEnvelope('resize', {
+            rows: Math.max(1, Math.floor(terminal.clientHeight / lineHeight)),
+            cols: Math.max(1, Math.floor(terminal.clientWidth / charWidth))
+        });
+    }
+    
+    let resizeTimer = null;
+    window.addEventListener('resize', () => {
+        clearTimeout(resizeTimer);
+        resizeTimer = setTimeout(reportTerminalSize, 200);
+    });
+    
+    // Apply GDB's completions of the command being typed: a single completion replaces
+    // the input; several are listed and the input is extended to their common prefix
+    function showCompletions(payload) {
+        const completions = payload.completions || [];
+        if (inputMode !== 'gdb' || commandInput.value !== payload.text || completions.length === 0) {
+            return;
+        }
+        if (completions.length === 1) {
+            commandInput.value = completions[0] + ' ';
+            return;
+        }
+        let prefix = completions[0];
+        for (const completion of completions) {
+            while (!completion.startsWith(prefix)) {
+                prefix = prefix.slice(0, -1);
+            }
+        }
+        appendToTerminal(completions.join('\n'));
+        commandInput.value = prefix;
+    }
+    
+    // Escape sequences sent for special keys in raw mode, as a VT100-style terminal would
+    const RAW_KEYS = {
+        Enter: '\r',
+        Backspace: '\x7f',
+        Tab: '\t',
+        Escape: '\x1b',
+        ArrowUp: '\x1b[A',
+        ArrowDown: '\x1b[B',
+        ArrowRight: '\x1b[C',
+        ArrowLeft: '\x1b[D',
+        Home: '\x1b[H',
+        End: '\x1b[F',
+        Insert: '\x1b[2~',
+        Delete: '\x1b[3~',
+        PageUp: '\x1b[5~',
+        PageDown: '\x1b[6~'
+    };
+    
+    // Translate a key press to the bytes a terminal would send, or null for keys that
+    // send nothing (e.g. Shift on its own)
+    function rawKeyData(e) {
+        if (RAW_KEYS[e.key]) {
+            return RAW_KEYS[e.key];
+        }
+        if (e.key.length !== 1) {
+            return null;
+        }
+        if (e.ctrlKey && /^[a-z@\[\\\]^_]$/i.test(e.key)) {
+            return String.fromCharCode(e.key.toUpperCase().charCodeAt(0) & 0x1f);
+        }
+        return e.altKey ? '\x1b' + e.key : e.key;
+    }
+    
+    // Switch where typed input goes
+    function setInputMode(mode) {
+        inputMode = mode;
+        commandPrompt.textContent = { gdb: '(gdb)', line: 'stdin>', raw: 'raw>' }[mode];
+        programInputBtn.classList.toggle('active', mode === 'line');
+        rawKeysBtn.classList.toggle('active', mode === 'raw');
+        commandInput.value = '';
+        commandInput.focus();
+    }
+    
+    programInputBtn.addEventListener('click', () => setInputMode(inputMode === 'line' ? 'gdb' : 'line'));
+    rawKeysBtn.addEventListener('click', () => setInputMode(inputMode === 'raw' ? 'gdb' : 'raw'));
+    
     // Handle command input
     commandInput.addEventListener('keydown', (e) => {
+        // Raw mode: every key goes to the program as it is pressed; the Raw keys button
+        // switches back, since Escape is sent to the program too
+        if (inputMode === 'raw') {
+            const data = rawKeyData(e);
+            if (data !== null && !e.metaKey) {
+                e.preventDefault();
+                sendProgramInput(data);
+            }
+            return;
+        }
+        
+        // Line mode: send the line, or control keys, to the program as typed
+        if (inputMode === 'line') {
+            if (e.key === 'Enter') {
+                e.preventDefault();
+                sendProgramInput(commandInput.value + '\n');
+                commandInput.value = '';
+            } else if (e.ctrlKey && (e.key === 'c' || e.key === 'd')) {
+                e.preventDefault();
+                sendProgramInput(e.key === 'c' ? CTRL_C : CTRL_D);
+            } else if (e.key === 'Escape') {
+                e.preventDefault();
+                setInputMode('gdb');
+            }
+            return;
+        }
+        
+        // Tab to complete the command with GDB's completions
+        if (e.key === 'Tab') {
+            e.preventDefault();
+            sendEnvelope('complete', { text: commandInput.value });
+            return;
+        }
+        
         // Enter key to send command
         if (e.key === 'Enter') {
             e.preventDefault();
@@ -233,8 +598,32 @@ function initTerminalSection() {
         attributes: true
     });
     
-    // Connect WebSocket
-    connectWebSocket();
+    // The server sends a heartbeat about once a minute; reconnect if they stop arriving
+    const HEARTBEAT_TIMEOUT = 2 * 60 * 1000;
+    setInterval(() => {
+        if (terminalConnected && socket.protocol === PROTOCOL_V2 && Date.now() - lastHeartbeat > HEARTBEAT_TIMEOUT) {
+            console.warn('No heartbeat from server, reconnecting');
+            socket.close();
+        }
+    }, 30000);
+    
+    document.getElementById('inviteBtn')?.addEventListener('click', copyInviteLink);
+    document.getElementById('shareBtn')?.addEventListener('click', copyShareLink);
+    
+    // Connect WebSocket, joining the session of an invite link when the page was opened
+    // from one
+    const joinToken = new URLSearchParams(window.location.search).get('join');
+    if (shareToken) {
+        // Watchers see the output and the chat but cannot type
+        document.body.dataset.watching = 'true';
+        appendToTerminal('Watching a shared session (read-only)');
+        connectWebSocket();
+    } else if (joinToken) {
+        window.history.replaceState(null, '', window.location.pathname);
+        joinSession(joinToken);
+    } else {
+        connectWebSocket();
+    }
     
     // Initial terminal message
     appendToTerminal('GDB Terminal\nUse the terminal to debug your program.');
@@ -245,6 +634,9 @@ function initTerminalSection() {
     return {
         appendToTerminal,
         sendCommand,
+        sendProgramInput,
+        connectToSession,
+        joinSession,
         getLastCommandOutput: () => {
             // Get all terminal text from the saved history
             return outputHistory.getAll();
@@ -56,6 +56,20 @@ function initUploadSection() {
         // Create form data
         const formData = new FormData();
         formData.append('executable', selectedFile);
+
+        // Attach the optional source archive so GDB can list sources
+        const sourceInput = document.getElementById('sourceInput');
+        if (sourceInput && sourceInput.files.length > 0) {
+            formData.append('source', sourceInput.files[0]);
+        }
+
+        // Or name the commit the binary was built from, to fetch its sources from GitHub
+        const repository = document.getElementById('repositoryInput');
+        const commit = document.getElementById('commitInput');
+        if (repository && commit && repository.value.trim() !== '') {
+            formData.append('repository', repository.value.trim());
+            formData.append('commit', commit.value.trim());
+        }
         
         // Update UI during upload
         uploadBtn.disabled = true;
@@ -75,6 +89,18 @@ function initUploadSection() {
                 // Show success message
                 uploadStatus.textContent = `Upload successful: ${selectedFile.name}`;
                 uploadStatus.classList.add('success');
+                if (result.data.warning) {
+                    // e.g. no debug information: the session starts, but without source lines
+                    uploadStatus.textContent += ` (${result.data.warning})`;
+                }
+                if (result.data.sourceError) {
+                    AppUtils.showNotification(result.data.sourceError, 'error');
+                }
+                
+                // Subscribe the terminal to the new session before GDB starts producing output
+                if (result.data.sessionToken && window.AppTerminal) {
+                    await window.AppTerminal.connectToSession(result.data.sessionToken);
+                }
                 
                 // Start GDB with the uploaded file
                 await startGDB(result.data.filename);
@@ -100,9 +126,101 @@ function initUploadSection() {
         }
     });
     
+    loadLabs();
+    
     console.log('Upload section initialized');
 }
 
+// Show the lab catalog, if the server has any labs
+async function loadLabs() {
+    const labsPanel = document.getElementById('labsPanel');
+    const labList = document.getElementById('labList');
+    if (!labsPanel || !labList) {
+        return;
+    }
+    
+    try {
+        const response = await fetch('/api/labs');
+        const result = await response.json();
+        const targets = (result.success && result.data) || [];
+        labList.innerHTML = '';
+        targets.forEach(target => labList.appendChild(createLabCard(target)));
+        labsPanel.style.display = targets.length > 0 ? 'block' : 'none';
+    } catch (error) {
+        console.error('Error loading labs:', error);
+    }
+}
+
+// Render one lab target with its hints and a button to start it
+function createLabCard(target) {
+    const card = document.createElement('div');
+    card.classList.add('lab-card');
+    
+    const title = document.createElement('h3');
+    title.textContent = target.name;
+    card.appendChild(title);
+    
+    if (target.tags && target.tags.length > 0) {
+        const tags = document.createElement('div');
+        tags.classList.add('lab-tags');
+        tags.textContent = target.tags.join(' · ');
+        card.appendChild(tags);
+    }
+    
+    if (target.description) {
+        const description = document.createElement('p');
+        description.textContent = target.description;
+        card.appendChild(description);
+    }
+    
+    // Hints stay folded so students can try without them first
+    (target.hints || []).forEach((hint, i) => {
+        const details = document.createElement('details');
+        const summary = document.createElement('summary');
+        summary.textContent = `Hint ${i + 1}`;
+        details.appendChild(summary);
+        details.appendChild(document.createTextNode(hint));
+        card.appendChild(details);
+    });
+    
+    const startButton = document.createElement('button');
+    startButton.classList.add('btn', 'primary-btn');
+    startButton.textContent = 'Start';
+    startButton.addEventListener('click', async () => {
+        startButton.disabled = true;
+        try {
+            await startLab(target);
+        } finally {
+            startButton.disabled = false;
+        }
+    });
+    card.appendChild(startButton);
+    
+    return card;
+}
+
+// Start a fresh session on a lab target and switch to the terminal
+async function startLab(target) {
+    try {
+        const response = await fetch(`/api/labs/${encodeURIComponent(target.id)}/start`, { method: 'POST' });
+        const result = await response.json();
+        if (!result.success) {
+            throw new Error(result.error || 'Failed to start lab');
+        }
+        
+        if (result.data.sessionToken && window.AppTerminal) {
+            await window.AppTerminal.connectToSession(result.data.sessionToken);
+        }
+        if (await startGDB(result.data.filename)) {
+            document.getElementById('terminalTabBtn').click();
+            AppUtils.showNotification(`Lab ${target.name} started`, 'success');
+        }
+    } catch (error) {
+        console.error('Error starting lab:', error);
+        AppUtils.showNotification(`Failed to start lab: ${error.message}`, 'error');
+    }
+}
+
 // Start GDB with the uploaded file
 async function startGDB(filename) {
     try {
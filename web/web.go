@@ -0,0 +1,21 @@
+// Package web holds the browser UI: the pages in templates and the scripts and styles in
+// static. They are embedded in the binary, so a released server needs no files beside it.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed static templates
+var embedded embed.FS
+
+// Files returns the UI's files: those embedded in the binary, or with dir set (the
+// -dev flag of serve) the ones in that directory, e.g. "web", so edits show on reload
+func Files(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
@@ -0,0 +1,24 @@
+package web
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	// The pages and the scripts they load are in the binary
+	for _, name := range []string{"templates/index.html", "templates/metrics.html", "static/js/base_path.js", "static/css/styles.css"} {
+		_, err := fs.Stat(Files(""), name)
+		assert.NoError(t, err, name)
+	}
+
+	// A directory serves the files on disk instead
+	page, err := fs.ReadFile(Files("."), "templates/index.html")
+	require.NoError(t, err)
+	embedded, err := fs.ReadFile(Files(""), "templates/index.html")
+	require.NoError(t, err)
+	assert.Equal(t, embedded, page)
+}